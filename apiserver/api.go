@@ -12,6 +12,7 @@ package apiserver
 import (
 	"context"
 	"net/http"
+	"time"
 )
 
 // ConfigurationAPIRouter defines the required methods for binding the api requests to a responses for the ConfigurationAPI
@@ -21,10 +22,24 @@ type ConfigurationAPIRouter interface {
 	DeleteConfigurationById(http.ResponseWriter, *http.Request)
 	GetConfigurationById(http.ResponseWriter, *http.Request)
 	GetConfigurations(http.ResponseWriter, *http.Request)
+	GetRoomLists(http.ResponseWriter, *http.Request)
 	PostConfiguration(http.ResponseWriter, *http.Request)
 	PutConfigurationById(http.ResponseWriter, *http.Request)
 }
 
+// MaintenanceAPIRouter defines the required methods for binding the api requests to a responses for the MaintenanceAPI
+// The MaintenanceAPIRouter implementation should parse necessary information from the http request,
+// pass the data to a MaintenanceAPIServicer to perform the required actions, then write the service results to the http response.
+type MaintenanceAPIRouter interface {
+	CancelOrphanedEvents(http.ResponseWriter, *http.Request)
+	GetAuditLog(http.ResponseWriter, *http.Request)
+	GetOrphanedEvents(http.ResponseWriter, *http.Request)
+	GetStatus(http.ResponseWriter, *http.Request)
+	GetSupportBundle(http.ResponseWriter, *http.Request)
+	GetUtilization(http.ResponseWriter, *http.Request)
+	SyncConfiguration(http.ResponseWriter, *http.Request)
+}
+
 // VersionAPIRouter defines the required methods for binding the api requests to a responses for the VersionAPI
 // The VersionAPIRouter implementation should parse necessary information from the http request,
 // pass the data to a VersionAPIServicer to perform the required actions, then write the service results to the http response.
@@ -41,8 +56,23 @@ type ConfigurationAPIServicer interface {
 	DeleteConfigurationById(context.Context, int64) (ImplResponse, error)
 	GetConfigurationById(context.Context, int64) (ImplResponse, error)
 	GetConfigurations(context.Context) (ImplResponse, error)
-	PostConfiguration(context.Context, Configuration) (ImplResponse, error)
-	PutConfigurationById(context.Context, int64, Configuration) (ImplResponse, error)
+	GetRoomLists(context.Context, int64) (ImplResponse, error)
+	PostConfiguration(context.Context, Configuration, bool) (ImplResponse, error)
+	PutConfigurationById(context.Context, int64, Configuration, bool) (ImplResponse, error)
+}
+
+// MaintenanceAPIServicer defines the api actions for the MaintenanceAPI service
+// This interface intended to stay up to date with the openapi yaml used to generate it,
+// while the service implementation can be ignored with the .openapi-generator-ignore file
+// and updated with the logic required for the API.
+type MaintenanceAPIServicer interface {
+	CancelOrphanedEvents(context.Context, int64, bool) (ImplResponse, error)
+	GetAuditLog(context.Context, int64, string, time.Time, time.Time) (ImplResponse, error)
+	GetOrphanedEvents(context.Context, int64) (ImplResponse, error)
+	GetStatus(context.Context) (ImplResponse, error)
+	GetSupportBundle(context.Context, int64) (ImplResponse, error)
+	GetUtilization(context.Context, int64, time.Time, time.Time) (ImplResponse, error)
+	SyncConfiguration(context.Context, int64) (ImplResponse, error)
 }
 
 // VersionAPIServicer defines the api actions for the VersionAPI service
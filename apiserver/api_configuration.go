@@ -67,6 +67,11 @@ func (c *ConfigurationAPIController) Routes() Routes {
 			"/v1/configs",
 			c.GetConfigurations,
 		},
+		"GetRoomLists": Route{
+			strings.ToUpper("Get"),
+			"/v1/configs/{config-id}/room-lists",
+			c.GetRoomLists,
+		},
 		"PostConfiguration": Route{
 			strings.ToUpper("Post"),
 			"/v1/configs",
@@ -134,8 +139,46 @@ func (c *ConfigurationAPIController) GetConfigurations(w http.ResponseWriter, r
 	EncodeJSONResponse(result.Body, &result.Code, w)
 }
 
+// GetRoomLists - Lists room lists
+func (c *ConfigurationAPIController) GetRoomLists(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.GetRoomLists(r.Context(), configIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
 // PostConfiguration - Creates a configuration
 func (c *ConfigurationAPIController) PostConfiguration(w http.ResponseWriter, r *http.Request) {
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	var skipConnectionTestParam bool
+	if query.Has("skipConnectionTest") {
+		param, err := parseBoolParameter(
+			query.Get("skipConnectionTest"),
+			WithParse[bool](parseBool),
+		)
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+		skipConnectionTestParam = param
+	}
 	configurationParam := Configuration{}
 	d := json.NewDecoder(r.Body)
 	d.DisallowUnknownFields()
@@ -151,7 +194,7 @@ func (c *ConfigurationAPIController) PostConfiguration(w http.ResponseWriter, r
 		c.errorHandler(w, r, err, nil)
 		return
 	}
-	result, err := c.service.PostConfiguration(r.Context(), configurationParam)
+	result, err := c.service.PostConfiguration(r.Context(), configurationParam, skipConnectionTestParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
@@ -164,6 +207,11 @@ func (c *ConfigurationAPIController) PostConfiguration(w http.ResponseWriter, r
 // PutConfigurationById - Updates a configuration
 func (c *ConfigurationAPIController) PutConfigurationById(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
 	configIdParam, err := parseNumericParameter[int64](
 		params["config-id"],
 		WithRequire[int64](parseInt64),
@@ -172,6 +220,18 @@ func (c *ConfigurationAPIController) PutConfigurationById(w http.ResponseWriter,
 		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
 		return
 	}
+	var skipConnectionTestParam bool
+	if query.Has("skipConnectionTest") {
+		param, err := parseBoolParameter(
+			query.Get("skipConnectionTest"),
+			WithParse[bool](parseBool),
+		)
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+		skipConnectionTestParam = param
+	}
 	configurationParam := Configuration{}
 	d := json.NewDecoder(r.Body)
 	d.DisallowUnknownFields()
@@ -187,7 +247,7 @@ func (c *ConfigurationAPIController) PutConfigurationById(w http.ResponseWriter,
 		c.errorHandler(w, r, err, nil)
 		return
 	}
-	result, err := c.service.PutConfigurationById(r.Context(), configIdParam, configurationParam)
+	result, err := c.service.PutConfigurationById(r.Context(), configIdParam, configurationParam, skipConnectionTestParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
@@ -0,0 +1,297 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// MaintenanceAPIController binds http requests to an api service and writes the service results to the http response
+type MaintenanceAPIController struct {
+	service      MaintenanceAPIServicer
+	errorHandler ErrorHandler
+}
+
+// MaintenanceAPIOption for how the controller is set up.
+type MaintenanceAPIOption func(*MaintenanceAPIController)
+
+// WithMaintenanceAPIErrorHandler inject ErrorHandler into controller
+func WithMaintenanceAPIErrorHandler(h ErrorHandler) MaintenanceAPIOption {
+	return func(c *MaintenanceAPIController) {
+		c.errorHandler = h
+	}
+}
+
+// NewMaintenanceAPIController creates a default api controller
+func NewMaintenanceAPIController(s MaintenanceAPIServicer, opts ...MaintenanceAPIOption) Router {
+	controller := &MaintenanceAPIController{
+		service:      s,
+		errorHandler: DefaultErrorHandler,
+	}
+
+	for _, opt := range opts {
+		opt(controller)
+	}
+
+	return controller
+}
+
+// Routes returns all the api routes for the MaintenanceAPIController
+func (c *MaintenanceAPIController) Routes() Routes {
+	return Routes{
+		"CancelOrphanedEvents": Route{
+			strings.ToUpper("Delete"),
+			"/v1/configs/{config-id}/orphaned-events",
+			c.CancelOrphanedEvents,
+		},
+		"GetAuditLog": Route{
+			strings.ToUpper("Get"),
+			"/v1/audit-log",
+			c.GetAuditLog,
+		},
+		"GetOrphanedEvents": Route{
+			strings.ToUpper("Get"),
+			"/v1/configs/{config-id}/orphaned-events",
+			c.GetOrphanedEvents,
+		},
+		"GetStatus": Route{
+			strings.ToUpper("Get"),
+			"/v1/status",
+			c.GetStatus,
+		},
+		"GetSupportBundle": Route{
+			strings.ToUpper("Get"),
+			"/v1/configs/{config-id}/support-bundle",
+			c.GetSupportBundle,
+		},
+		"GetUtilization": Route{
+			strings.ToUpper("Get"),
+			"/v1/configs/{config-id}/utilization",
+			c.GetUtilization,
+		},
+		"SyncConfiguration": Route{
+			strings.ToUpper("Post"),
+			"/v1/configs/{config-id}/sync",
+			c.SyncConfiguration,
+		},
+	}
+}
+
+// CancelOrphanedEvents - Cancels orphaned events
+func (c *MaintenanceAPIController) CancelOrphanedEvents(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	var dryRunParam bool
+	if query.Has("dryRun") {
+		param, err := parseBoolParameter(
+			query.Get("dryRun"),
+			WithParse[bool](parseBool),
+		)
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+		dryRunParam = param
+	}
+	result, err := c.service.CancelOrphanedEvents(r.Context(), configIdParam, dryRunParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetAuditLog - Audit log of Exchange mutations
+func (c *MaintenanceAPIController) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	var configIdParam int64
+	if query.Has("configId") {
+		param, err := parseNumericParameter[int64](
+			query.Get("configId"),
+			WithParse[int64](parseInt64),
+		)
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+		configIdParam = param
+	}
+	var roomParam string
+	if query.Has("room") {
+		roomParam = query.Get("room")
+	}
+	var fromParam time.Time
+	if query.Has("from") {
+		param, err := parseTime(query.Get("from"))
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+		fromParam = param
+	}
+	var toParam time.Time
+	if query.Has("to") {
+		param, err := parseTime(query.Get("to"))
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+		toParam = param
+	}
+	result, err := c.service.GetAuditLog(r.Context(), configIdParam, roomParam, fromParam, toParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetOrphanedEvents - Lists orphaned events
+func (c *MaintenanceAPIController) GetOrphanedEvents(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.GetOrphanedEvents(r.Context(), configIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetStatus - Runtime status of the app
+func (c *MaintenanceAPIController) GetStatus(w http.ResponseWriter, r *http.Request) {
+	result, err := c.service.GetStatus(r.Context())
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetSupportBundle - Redacted state of a configuration for troubleshooting
+func (c *MaintenanceAPIController) GetSupportBundle(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.GetSupportBundle(r.Context(), configIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetUtilization - Utilization of the configuration's rooms
+func (c *MaintenanceAPIController) GetUtilization(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	var fromParam time.Time
+	if query.Has("from") {
+		param, err := parseTime(query.Get("from"))
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+		fromParam = param
+	}
+	var toParam time.Time
+	if query.Has("to") {
+		param, err := parseTime(query.Get("to"))
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+		toParam = param
+	}
+	result, err := c.service.GetUtilization(r.Context(), configIdParam, fromParam, toParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// SyncConfiguration - Syncs the configuration immediately
+func (c *MaintenanceAPIController) SyncConfiguration(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	configIdParam, err := parseNumericParameter[int64](
+		params["config-id"],
+		WithRequire[int64](parseInt64),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	result, err := c.service.SyncConfiguration(r.Context(), configIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, w)
+}
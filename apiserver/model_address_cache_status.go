@@ -0,0 +1,36 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// AddressCacheStatus - Effectiveness of the caches of addresses resolved in Exchange
+type AddressCacheStatus struct {
+
+	// Number of addresses found in the cache since the app started
+	Hits int64 `json:"hits"`
+
+	// Number of addresses resolved in Exchange since the app started
+	Misses int64 `json:"misses"`
+
+	// Number of addresses evicted from full caches since the app started
+	Evictions int64 `json:"evictions"`
+
+	// Number of addresses currently cached for all configurations
+	Size int32 `json:"size"`
+}
+
+// AssertAddressCacheStatusRequired checks if the required fields are not zero-ed
+func AssertAddressCacheStatusRequired(obj AddressCacheStatus) error {
+	return nil
+}
+
+// AssertAddressCacheStatusConstraints checks if the values respects the defined constraints
+func AssertAddressCacheStatusConstraints(obj AddressCacheStatus) error {
+	return nil
+}
@@ -0,0 +1,58 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+import (
+	"time"
+)
+
+// AuditEntry - Mutation performed by the app in Exchange.
+type AuditEntry struct {
+
+	// Configuration the mutation was performed for
+	ConfigId int64 `json:"configId,omitempty"`
+
+	// When the mutation was performed
+	Timestamp time.Time `json:"timestamp,omitempty"`
+
+	// What was done in Exchange
+	Action string `json:"action,omitempty"`
+
+	// Organizer of the event
+	Organizer string `json:"organizer,omitempty"`
+
+	// Rooms the mutation concerned, if known
+	Rooms []string `json:"rooms,omitempty"`
+
+	// UID of the event in Exchange
+	ExchangeUID string `json:"exchangeUID,omitempty"`
+
+	// Start of the event, if known
+	Start *time.Time `json:"start,omitempty"`
+
+	// End of the event, if known
+	End *time.Time `json:"end,omitempty"`
+
+	// Identifies the booking event the mutation was performed for, also sent to Exchange as client-request-id
+	CorrelationId string `json:"correlationId,omitempty"`
+
+	// Why the mutation failed. Empty if it succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// AssertAuditEntryRequired checks if the required fields are not zero-ed
+func AssertAuditEntryRequired(obj AuditEntry) error {
+	return nil
+}
+
+// AssertAuditEntryConstraints checks if the values respects the defined constraints
+func AssertAuditEntryConstraints(obj AuditEntry) error {
+	return nil
+}
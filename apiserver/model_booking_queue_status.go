@@ -0,0 +1,36 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// BookingQueueStatus - Booking events received from Eliona waiting for processing in Exchange
+type BookingQueueStatus struct {
+
+	// Number of booking events waiting for processing
+	Depth int32 `json:"depth"`
+
+	// Age of the oldest booking event waiting for processing in seconds
+	OldestPendingAge float64 `json:"oldestPendingAge"`
+
+	// Number of booking events processed since the app started
+	Processed int64 `json:"processed"`
+
+	// Average time between receiving and processing a booking event in seconds
+	AverageLag float64 `json:"averageLag"`
+}
+
+// AssertBookingQueueStatusRequired checks if the required fields are not zero-ed
+func AssertBookingQueueStatusRequired(obj BookingQueueStatus) error {
+	return nil
+}
+
+// AssertBookingQueueStatusConstraints checks if the values respects the defined constraints
+func AssertBookingQueueStatusConstraints(obj BookingQueueStatus) error {
+	return nil
+}
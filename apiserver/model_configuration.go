@@ -9,6 +9,10 @@
 
 package apiserver
 
+import (
+	"time"
+)
+
 // Configuration - Each configuration defines access to provider's API.
 type Configuration struct {
 
@@ -21,10 +25,16 @@ type Configuration struct {
 	// Client Secret (for Exchange Online)
 	ClientSecret *string `json:"clientSecret,omitempty"`
 
+	// When the client secret expires (for Exchange Online). Users are warned ahead of it.
+	ClientSecretExpiresAt *time.Time `json:"clientSecretExpiresAt,omitempty"`
+
 	// Tenant ID (for Exchange Online)
 	TenantId *string `json:"tenantId,omitempty"`
 
-	// URL of EWS API (for Exchange Server NTLM auth)
+	// Microsoft 365 cloud hosting the tenant (for Exchange Online)
+	Cloud *string `json:"cloud,omitempty"`
+
+	// URL of EWS API (for Exchange Server NTLM auth). Autodiscovered from the service user's domain if empty.
 	EwsURL *string `json:"ewsURL,omitempty"`
 
 	// Username (for Exchange Server NTLM auth)
@@ -33,24 +43,161 @@ type Configuration struct {
 	// Password (for Exchange Server NTLM auth)
 	Password *string `json:"password,omitempty"`
 
+	// PEM encoded CA certificates trusted besides the system's (for Exchange Server NTLM auth)
+	TLSCACertPEM *string `json:"tlsCACertPEM,omitempty"`
+
+	// Whether to accept any TLS certificate of Exchange Server, insecure (for Exchange Server NTLM auth)
+	InsecureSkipVerify *bool `json:"insecureSkipVerify,omitempty"`
+
 	// Service user email address.
 	ServiceUserUPN *string `json:"serviceUserUPN,omitempty"`
 
-	// Email address of the room list that will be imported to Eliona.
+	// Service user email address used for reading calendars and rooms. Defaults to serviceUserUPN.
+	ReadServiceUserUPN *string `json:"readServiceUserUPN,omitempty"`
+
+	// Service user email address used for creating and cancelling bookings. Defaults to serviceUserUPN.
+	WriteServiceUserUPN *string `json:"writeServiceUserUPN,omitempty"`
+
+	// Deprecated: use roomListUPNs. Email address of a room list that will be imported to Eliona; added to roomListUPNs.
 	RoomListUPN *string `json:"roomListUPN,omitempty"`
 
+	// Email addresses of the room lists that will be imported to Eliona.
+	RoomListUPNs *[]string `json:"roomListUPNs,omitempty"`
+
+	// Where the rooms are discovered: the room list, an address list or the list of room email addresses.
+	RoomDiscovery *string `json:"roomDiscovery,omitempty"`
+
+	// ID of the address list containing the rooms, for addressList discovery.
+	RoomAddressListID *string `json:"roomAddressListID,omitempty"`
+
+	// Email addresses of the rooms, for static discovery.
+	RoomEmails *[]string `json:"roomEmails,omitempty"`
+
+	// Email addresses of equipment mailboxes, e.g. projectors or cars, booked like rooms.
+	EquipmentEmails *[]string `json:"equipmentEmails,omitempty"`
+
+	// Archive assets of rooms which are no longer discovered, e.g. removed from the room list. Archived rooms are no longer synchronized and not bookable.
+	ArchiveRemovedRooms *bool `json:"archiveRemovedRooms,omitempty"`
+
 	// URL where the Eliona Booking app is reachable.
 	BookingAppURL *string `json:"bookingAppURL,omitempty"`
 
+	// Import only and never write to Exchange, e.g. to validate new credentials alongside the current configuration
+	ReadOnly *bool `json:"readOnly,omitempty"`
+
+	// Handle booking events caused by the app's own imports from Exchange like bookings made in Eliona, exporting them back to Exchange
+	ExportImports *bool `json:"exportImports,omitempty"`
+
+	// Mirror the subject, location and attendees of events marked private or confidential instead of only blocking the room
+	MirrorPrivateDetails *bool `json:"mirrorPrivateDetails,omitempty"`
+
 	// Flag to enable or disable fetching from this API
 	Enable *bool `json:"enable,omitempty"`
 
 	// Interval in seconds for collecting data from API
 	RefreshInterval int32 `json:"refreshInterval,omitempty"`
 
-	// Timeout in seconds
+	// Timeout of requests to Exchange in seconds, including reading the response. Zero uses 30 seconds.
 	RequestTimeout *int32 `json:"requestTimeout,omitempty"`
 
+	// URL of the HTTP proxy requests to Exchange go through, optionally with credentials for basic authentication. Empty uses the proxy of the environment (HTTPS_PROXY).
+	ProxyURL *string `json:"proxyURL,omitempty"`
+
+	// Maximum number of requests per minute sent to EWS for this configuration
+	RequestsPerMinute *int32 `json:"requestsPerMinute,omitempty"`
+
+	// Maximum number of attendees sent in one request when creating an event. The rest is added in batches.
+	MaxAttendeesPerRequest *int32 `json:"maxAttendeesPerRequest,omitempty"`
+
+	// Days ahead up to which occurrences of recurring events are synchronized. Later occurrences are synchronized as time passes.
+	RecurrenceHorizonDays *int32 `json:"recurrenceHorizonDays,omitempty"`
+
+	// Minutes before the start of bookings created from Eliona at which their organizers are reminded; 0 disables the reminder. Empty keeps the default of the organizer's mailbox. Events organized by the service user never remind.
+	ReminderMinutes *int32 `json:"reminderMinutes,omitempty"`
+
+	// Time in seconds to wait for a room to process an invitation before the booking is considered declined
+	DeclineGracePeriod *int32 `json:"declineGracePeriod,omitempty"`
+
+	// Time in seconds during which the rooms are checked every second for having processed an invitation. The decline grace period starts afterwards.
+	InvitationProcessingTimeout *int32 `json:"invitationProcessingTimeout,omitempty"`
+
+	// Time in seconds between checks of the rooms' responses to bookings waiting for them. Zero waits for the responses when creating the booking.
+	ResponsePollInterval *int32 `json:"responsePollInterval,omitempty"`
+
+	// Number of consecutive failures to deliver meeting invitations after which events are created without sending invitations. Zero never stops sending them.
+	InvitationFailureLimit *int32 `json:"invitationFailureLimit,omitempty"`
+
+	// Whether bookings touching at their boundaries (one ending when the other starts) are considered overlapping
+	OverlapPolicy *string `json:"overlapPolicy,omitempty"`
+
+	// Free/busy status shown in attendees' calendars for bookings created from Eliona, unless the booking specifies one
+	FreeBusyStatus *string `json:"freeBusyStatus,omitempty"`
+
+	// What to do with a multi-room booking when some of the rooms decline it.
+	DeclinePolicy *string `json:"declinePolicy,omitempty"`
+
+	// How a booking cancelled in Eliona frees its rooms in Exchange.
+	CancelPolicy *string `json:"cancelPolicy,omitempty"`
+
+	// What to do with an Eliona booking when its last room is removed in Exchange.
+	EmptyBookingPolicy *string `json:"emptyBookingPolicy,omitempty"`
+
+	// What to do with a room whose asset has no email address stored.
+	MissingRoomEmailPolicy *string `json:"missingRoomEmailPolicy,omitempty"`
+
+	// What to do with an event organized by the room it is found in.
+	SelfOrganizedPolicy *string `json:"selfOrganizedPolicy,omitempty"`
+
+	// What to do with an event nobody is invited to, e.g. a block put directly in the room's calendar.
+	BlockPolicy *string `json:"blockPolicy,omitempty"`
+
+	// What to do when Exchange reports the deletion of an item in a room's calendar the app doesn't know.
+	UnknownDeletePolicy *string `json:"unknownDeletePolicy,omitempty"`
+
+	// How changes in rooms' calendars are detected: polling every calendar or asking pull subscriptions first.
+	ChangeDetection *string `json:"changeDetection,omitempty"`
+
+	// Time zone of the project, if the Booking app sends wall-clock times in it labelled as UTC.
+	BookingTimeZone *string `json:"bookingTimeZone,omitempty"`
+
+	// Template composing subjects of bookings without one. Placeholders: {room}, {organizer}, {start}, {end}.
+	SubjectFallback *string `json:"subjectFallback,omitempty"`
+
+	// Template composing the subject shown in the rooms' calendars for bookings from Eliona, instead of the organizer's. Placeholders: {room}, {organizer}, {start}, {end}.
+	ResourceSubject *string `json:"resourceSubject,omitempty"`
+
+	// Template composing the message sent to the attendees of events cancelled from Eliona. Placeholders: {room}, {organizer}, {start}, {end}.
+	CancellationBody *string `json:"cancellationBody,omitempty"`
+
+	// Outlook category assigned to events created for bookings from Eliona. Empty assigns none.
+	AppointmentCategory *string `json:"appointmentCategory,omitempty"`
+
+	// Whether events created for bookings from Eliona are marked as online meetings. EWS cannot create a Teams meeting or its join link.
+	OnlineMeetings *bool `json:"onlineMeetings,omitempty"`
+
+	// What to do with a booking imported from Exchange which overlaps a booking created from Eliona in the same room.
+	ImportOverlapPolicy *string `json:"importOverlapPolicy,omitempty"`
+
+	WorkingHours *WorkingHours `json:"workingHours,omitempty"`
+
+	// Working hours of specific rooms, keyed by the room's email address. Override workingHours.
+	RoomWorkingHours map[string]WorkingHours `json:"roomWorkingHours,omitempty"`
+
+	// Working hours of the rooms in specific buildings, keyed by the building's name as located by roomLocationPattern. Override workingHours, and are overridden by roomWorkingHours.
+	BuildingWorkingHours map[string]WorkingHours `json:"buildingWorkingHours,omitempty"`
+
+	// Names of rooms in Eliona keyed by the room's email address. Take precedence over roomNameRules.
+	RoomNames map[string]string `json:"roomNames,omitempty"`
+
+	// Number of people rooms seat, keyed by the room's email address. Rooms not listed have no capacity in Eliona.
+	RoomCapacities map[string]int32 `json:"roomCapacities,omitempty"`
+
+	// Regular expression with the named groups building and floor, parsing the office location of rooms to group them under building and floor assets. Empty keeps rooms directly under the root asset.
+	RoomLocationPattern *string `json:"roomLocationPattern,omitempty"`
+
+	// Replacements applied in order to Exchange room names to name the rooms in Eliona
+	RoomNameRules []RoomNameRule `json:"roomNameRules,omitempty"`
+
 	// Array of rules combined by logical OR
 	AssetFilter [][]FilterRule `json:"assetFilter,omitempty"`
 
@@ -62,6 +209,9 @@ type Configuration struct {
 
 	// ID of the last Eliona user who created or updated the configuration
 	UserId *string `json:"userId,omitempty"`
+
+	// Email addresses of rooms requiring delegate approval. Bookings of these rooms stay pending until the delegate accepts or declines them.
+	ApprovalRoomUPNs *[]string `json:"approvalRoomUPNs,omitempty"`
 }
 
 // AssertConfigurationRequired checks if the required fields are not zero-ed
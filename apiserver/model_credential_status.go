@@ -0,0 +1,43 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+import (
+	"time"
+)
+
+// CredentialStatus - Whether Exchange accepts the credentials of a configuration
+type CredentialStatus struct {
+
+	// ID of the configuration
+	ConfigId int64 `json:"configId"`
+
+	// False if acquiring tokens fails or Exchange keeps rejecting the credentials
+	Healthy bool `json:"healthy"`
+
+	// Why the credentials are unhealthy
+	Reason string `json:"reason,omitempty"`
+
+	// When the credentials last became healthy or unhealthy
+	Since time.Time `json:"since,omitempty"`
+
+	// When the client secret expires, if configured
+	SecretExpiresAt *time.Time `json:"secretExpiresAt,omitempty"`
+}
+
+// AssertCredentialStatusRequired checks if the required fields are not zero-ed
+func AssertCredentialStatusRequired(obj CredentialStatus) error {
+	return nil
+}
+
+// AssertCredentialStatusConstraints checks if the values respects the defined constraints
+func AssertCredentialStatusConstraints(obj CredentialStatus) error {
+	return nil
+}
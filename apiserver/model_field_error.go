@@ -0,0 +1,30 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// FieldError - Configuration rejected because of one of its fields
+type FieldError struct {
+
+	// Name of the offending field, connection if Exchange rejected the test connection
+	Field string `json:"field"`
+
+	// Why the field was rejected
+	Message string `json:"message"`
+}
+
+// AssertFieldErrorRequired checks if the required fields are not zero-ed
+func AssertFieldErrorRequired(obj FieldError) error {
+	return nil
+}
+
+// AssertFieldErrorConstraints checks if the values respects the defined constraints
+func AssertFieldErrorConstraints(obj FieldError) error {
+	return nil
+}
@@ -0,0 +1,47 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+import (
+	"time"
+)
+
+// OrphanedEvent - Exchange event created by the app which is no longer known to the app's database.
+type OrphanedEvent struct {
+
+	// Email address of the room the event was found in
+	RoomEmail string `json:"roomEmail,omitempty"`
+
+	// UID of the event in Exchange
+	ExchangeUID string `json:"exchangeUID,omitempty"`
+
+	// Email address of the event organizer
+	Organizer string `json:"organizer,omitempty"`
+
+	// ID of the Eliona booking the event was tagged with
+	ElionaId int32 `json:"elionaId,omitempty"`
+
+	Start time.Time `json:"start,omitempty"`
+
+	End time.Time `json:"end,omitempty"`
+
+	// Whether the event was cancelled in Exchange
+	Cancelled bool `json:"cancelled,omitempty"`
+}
+
+// AssertOrphanedEventRequired checks if the required fields are not zero-ed
+func AssertOrphanedEventRequired(obj OrphanedEvent) error {
+	return nil
+}
+
+// AssertOrphanedEventConstraints checks if the values respects the defined constraints
+func AssertOrphanedEventConstraints(obj OrphanedEvent) error {
+	return nil
+}
@@ -0,0 +1,30 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// RoomList - Room list defined in Exchange.
+type RoomList struct {
+
+	// Display name of the room list
+	Name string `json:"name,omitempty"`
+
+	// Email address of the room list, to be used as roomListUPN
+	Email string `json:"email,omitempty"`
+}
+
+// AssertRoomListRequired checks if the required fields are not zero-ed
+func AssertRoomListRequired(obj RoomList) error {
+	return nil
+}
+
+// AssertRoomListConstraints checks if the values respects the defined constraints
+func AssertRoomListConstraints(obj RoomList) error {
+	return nil
+}
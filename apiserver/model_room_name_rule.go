@@ -0,0 +1,30 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// RoomNameRule - Renames rooms whose Exchange name matches the pattern.
+type RoomNameRule struct {
+
+	// Regular expression matched against the room name
+	Pattern string `json:"pattern,omitempty"`
+
+	// Replacement of the matched text, may refer to submatches like $1
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// AssertRoomNameRuleRequired checks if the required fields are not zero-ed
+func AssertRoomNameRuleRequired(obj RoomNameRule) error {
+	return nil
+}
+
+// AssertRoomNameRuleConstraints checks if the values respects the defined constraints
+func AssertRoomNameRuleConstraints(obj RoomNameRule) error {
+	return nil
+}
@@ -0,0 +1,39 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// RoomUtilization - Share of the room's working hours covered by bookings.
+type RoomUtilization struct {
+
+	// Email address of the room
+	RoomEmail string `json:"roomEmail,omitempty"`
+
+	// Time zone the room's working hours were evaluated in
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Minutes within the working hours covered by bookings
+	BookedMinutes int64 `json:"bookedMinutes"`
+
+	// Minutes of working hours in the period
+	AvailableMinutes int64 `json:"availableMinutes"`
+
+	// Booked minutes divided by available minutes
+	Utilization float64 `json:"utilization"`
+}
+
+// AssertRoomUtilizationRequired checks if the required fields are not zero-ed
+func AssertRoomUtilizationRequired(obj RoomUtilization) error {
+	return nil
+}
+
+// AssertRoomUtilizationConstraints checks if the values respects the defined constraints
+func AssertRoomUtilizationConstraints(obj RoomUtilization) error {
+	return nil
+}
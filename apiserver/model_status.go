@@ -0,0 +1,47 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// Status - Runtime status of the app
+type Status struct {
+	BookingQueue BookingQueueStatus `json:"bookingQueue,omitempty"`
+
+	AddressCache AddressCacheStatus `json:"addressCache,omitempty"`
+
+	Credentials []CredentialStatus `json:"credentials,omitempty"`
+
+	Syncs []SyncScheduleStatus `json:"syncs,omitempty"`
+}
+
+// AssertStatusRequired checks if the required fields are not zero-ed
+func AssertStatusRequired(obj Status) error {
+	if err := AssertBookingQueueStatusRequired(obj.BookingQueue); err != nil {
+		return err
+	}
+	if err := AssertAddressCacheStatusRequired(obj.AddressCache); err != nil {
+		return err
+	}
+	for _, el := range obj.Credentials {
+		if err := AssertCredentialStatusRequired(el); err != nil {
+			return err
+		}
+	}
+	for _, el := range obj.Syncs {
+		if err := AssertSyncScheduleStatusRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertStatusConstraints checks if the values respects the defined constraints
+func AssertStatusConstraints(obj Status) error {
+	return nil
+}
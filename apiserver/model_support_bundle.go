@@ -0,0 +1,59 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+import (
+	"time"
+)
+
+// SupportBundle - Redacted state of a configuration for troubleshooting
+type SupportBundle struct {
+
+	// When the bundle was generated
+	GeneratedAt time.Time `json:"generatedAt"`
+
+	Configuration Configuration `json:"configuration"`
+
+	Credentials CredentialStatus `json:"credentials,omitempty"`
+
+	Rooms []SupportBundleRoom `json:"rooms"`
+
+	// Number of booking groups created by the configuration per state
+	BookingCounts map[string]int64 `json:"bookingCounts"`
+
+	// Audit log entries of the last days, newest first
+	AuditLog []AuditEntry `json:"auditLog"`
+}
+
+// AssertSupportBundleRequired checks if the required fields are not zero-ed
+func AssertSupportBundleRequired(obj SupportBundle) error {
+	if err := AssertConfigurationRequired(obj.Configuration); err != nil {
+		return err
+	}
+	if err := AssertCredentialStatusRequired(obj.Credentials); err != nil {
+		return err
+	}
+	for _, el := range obj.Rooms {
+		if err := AssertSupportBundleRoomRequired(el); err != nil {
+			return err
+		}
+	}
+	for _, el := range obj.AuditLog {
+		if err := AssertAuditEntryRequired(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertSupportBundleConstraints checks if the values respects the defined constraints
+func AssertSupportBundleConstraints(obj SupportBundle) error {
+	return nil
+}
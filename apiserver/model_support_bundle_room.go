@@ -0,0 +1,43 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+import (
+	"time"
+)
+
+// SupportBundleRoom - Room of a configuration and how fresh its sync state is
+type SupportBundleRoom struct {
+
+	// Email address of the room
+	RoomEmail string `json:"roomEmail,omitempty"`
+
+	// Eliona project the room's asset belongs to
+	ProjectId string `json:"projectId,omitempty"`
+
+	// ID of the room's asset in Eliona, if created
+	AssetId *int32 `json:"assetId,omitempty"`
+
+	// False if the room was never synchronized
+	HasSyncState bool `json:"hasSyncState"`
+
+	// When the room's sync state was last persisted
+	SyncedAt *time.Time `json:"syncedAt,omitempty"`
+}
+
+// AssertSupportBundleRoomRequired checks if the required fields are not zero-ed
+func AssertSupportBundleRoomRequired(obj SupportBundleRoom) error {
+	return nil
+}
+
+// AssertSupportBundleRoomConstraints checks if the values respects the defined constraints
+func AssertSupportBundleRoomConstraints(obj SupportBundleRoom) error {
+	return nil
+}
@@ -0,0 +1,39 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// SyncScheduleStatus - How long the syncs of a configuration take compared to its refresh interval
+type SyncScheduleStatus struct {
+
+	// ID of the configuration
+	ConfigId int64 `json:"configId"`
+
+	// Refresh interval in effect in seconds, raised to the minimum refresh interval
+	RefreshInterval float64 `json:"refreshInterval"`
+
+	// Duration of the last sync in seconds
+	LastDuration float64 `json:"lastDuration"`
+
+	// True if the last sync took longer than the refresh interval
+	Overrunning bool `json:"overrunning"`
+
+	// Number of syncs which took longer than the refresh interval since the app started
+	Overruns int64 `json:"overruns"`
+}
+
+// AssertSyncScheduleStatusRequired checks if the required fields are not zero-ed
+func AssertSyncScheduleStatusRequired(obj SyncScheduleStatus) error {
+	return nil
+}
+
+// AssertSyncScheduleStatusConstraints checks if the values respects the defined constraints
+func AssertSyncScheduleStatusConstraints(obj SyncScheduleStatus) error {
+	return nil
+}
@@ -0,0 +1,42 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// SyncSummary - Result of an immediate sync of a configuration
+type SyncSummary struct {
+
+	// ID of the synced configuration
+	ConfigId int64 `json:"configId"`
+
+	// Number of rooms synced
+	Rooms int32 `json:"rooms"`
+
+	// Number of bookings created in Exchange since the last sync
+	New int32 `json:"new"`
+
+	// Number of bookings updated in Exchange since the last sync
+	Updated int32 `json:"updated"`
+
+	// Number of room bookings cancelled in Exchange since the last sync
+	Cancelled int32 `json:"cancelled"`
+
+	// Duration of the sync in seconds
+	Duration float64 `json:"duration"`
+}
+
+// AssertSyncSummaryRequired checks if the required fields are not zero-ed
+func AssertSyncSummaryRequired(obj SyncSummary) error {
+	return nil
+}
+
+// AssertSyncSummaryConstraints checks if the values respects the defined constraints
+func AssertSyncSummaryConstraints(obj SyncSummary) error {
+	return nil
+}
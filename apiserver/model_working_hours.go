@@ -0,0 +1,36 @@
+/*
+ * EWS app API
+ *
+ * API to access and configure the Exchange app
+ *
+ * API version: 1.0.0
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package apiserver
+
+// WorkingHours - Daily window in which rooms are expected to be used, in the rooms' local time zone.
+type WorkingHours struct {
+
+	// Start of the working hours in the 24-hour HH:MM format
+	Start string `json:"start,omitempty"`
+
+	// End of the working hours in the 24-hour HH:MM format
+	End string `json:"end,omitempty"`
+
+	// IANA time zone the working hours are evaluated in
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Working days numbered from Sunday (0) to Saturday (6)
+	Days []int32 `json:"days,omitempty"`
+}
+
+// AssertWorkingHoursRequired checks if the required fields are not zero-ed
+func AssertWorkingHoursRequired(obj WorkingHours) error {
+	return nil
+}
+
+// AssertWorkingHoursConstraints checks if the values respects the defined constraints
+func AssertWorkingHoursConstraints(obj WorkingHours) error {
+	return nil
+}
@@ -20,7 +20,10 @@ import (
 	"errors"
 	"ews/apiserver"
 	"ews/conf"
+	"ews/ews"
 	"net/http"
+
+	"github.com/eliona-smart-building-assistant/go-utils/log"
 )
 
 // ConfigurationAPIService is a service that implements the logic for the ConfigurationAPIServicer
@@ -42,8 +45,14 @@ func (s *ConfigurationAPIService) GetConfigurations(ctx context.Context) (apiser
 	return apiserver.Response(http.StatusOK, configs), nil
 }
 
-func (s *ConfigurationAPIService) PostConfiguration(ctx context.Context, config apiserver.Configuration) (apiserver.ImplResponse, error) {
+func (s *ConfigurationAPIService) PostConfiguration(ctx context.Context, config apiserver.Configuration, skipConnectionTest bool) (apiserver.ImplResponse, error) {
+	if resp, ok := failedConnectionTest(config, skipConnectionTest); ok {
+		return resp, nil
+	}
 	insertedConfig, err := conf.InsertConfig(ctx, config)
+	if resp, ok := invalidConfig(err); ok {
+		return resp, nil
+	}
 	if err != nil {
 		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
 	}
@@ -61,12 +70,38 @@ func (s *ConfigurationAPIService) GetConfigurationById(ctx context.Context, conf
 	return apiserver.Response(http.StatusOK, config), nil
 }
 
-func (s *ConfigurationAPIService) PutConfigurationById(ctx context.Context, configId int64, config apiserver.Configuration) (apiserver.ImplResponse, error) {
+func (s *ConfigurationAPIService) GetRoomLists(ctx context.Context, configId int64) (apiserver.ImplResponse, error) {
+	config, err := conf.GetConfig(ctx, configId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	roomLists, err := ews.NewEWSHelper(*config, conf.ReadServiceUserUPN(*config)).GetRoomLists()
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	body := make([]apiserver.RoomList, 0, len(roomLists))
+	for _, roomList := range roomLists {
+		body = append(body, apiserver.RoomList{Name: roomList.Name, Email: roomList.Email})
+	}
+	return apiserver.Response(http.StatusOK, body), nil
+}
+
+func (s *ConfigurationAPIService) PutConfigurationById(ctx context.Context, configId int64, config apiserver.Configuration, skipConnectionTest bool) (apiserver.ImplResponse, error) {
 	config.Id = &configId
+	if resp, ok := failedConnectionTest(config, skipConnectionTest); ok {
+		return resp, nil
+	}
 	upsertedConfig, err := conf.UpsertConfig(ctx, config)
+	if resp, ok := invalidConfig(err); ok {
+		return resp, nil
+	}
 	if err != nil {
 		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
 	}
+	ews.ForgetHelper(configId)
 	return apiserver.Response(http.StatusCreated, upsertedConfig), nil
 }
 
@@ -78,5 +113,36 @@ func (s *ConfigurationAPIService) DeleteConfigurationById(ctx context.Context, c
 	if err != nil {
 		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
 	}
+	ews.ForgetHelper(configId)
 	return apiserver.ImplResponse{Code: http.StatusNoContent}, nil
 }
+
+// failedConnectionTest answers with the reason Exchange gave if it rejects the
+// configuration, so that it isn't stored only to fail with the next sync.
+// Invalid configurations are left to be rejected when storing them.
+func failedConnectionTest(config apiserver.Configuration, skip bool) (apiserver.ImplResponse, bool) {
+	if skip || conf.ValidateConnection(config) != nil {
+		return apiserver.ImplResponse{}, false
+	}
+	if err := ews.TestConnection(config); err != nil {
+		log.Info("configuration", "test connection failed: %v", err)
+		return apiserver.Response(http.StatusBadRequest, apiserver.FieldError{
+			Field:   "connection",
+			Message: err.Error(),
+		}), true
+	}
+	return apiserver.ImplResponse{}, false
+}
+
+// invalidConfig answers with the offending field if the configuration was
+// rejected because of one, so that it can be highlighted.
+func invalidConfig(err error) (apiserver.ImplResponse, bool) {
+	var fieldErr *conf.FieldError
+	if !errors.As(err, &fieldErr) {
+		return apiserver.ImplResponse{}, false
+	}
+	return apiserver.Response(http.StatusBadRequest, apiserver.FieldError{
+		Field:   fieldErr.Field,
+		Message: fieldErr.Message,
+	}), true
+}
@@ -0,0 +1,375 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package apiservices
+
+import (
+	"context"
+	"errors"
+	"ews/apiserver"
+	"ews/booking"
+	"ews/conf"
+	"ews/ews"
+	syncmodel "ews/model/sync"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+// MaintenanceAPIService is a service that implements the logic for the MaintenanceAPIServicer
+// This service should implement the business logic for every endpoint for the MaintenanceAPI API.
+// Include any external packages or services that will be required by this service.
+type MaintenanceAPIService struct {
+	syncNow      func(ctx context.Context, configID int64) (apiserver.SyncSummary, error)
+	syncSchedule func(configID int64) apiserver.SyncScheduleStatus
+}
+
+// NewMaintenanceAPIService creates a default api service. syncNow runs an
+// immediate sync of a configuration, syncSchedule reports how its regular
+// syncs keep up with the refresh interval.
+func NewMaintenanceAPIService(syncNow func(ctx context.Context, configID int64) (apiserver.SyncSummary, error), syncSchedule func(configID int64) apiserver.SyncScheduleStatus) apiserver.MaintenanceAPIServicer {
+	return &MaintenanceAPIService{
+		syncNow:      syncNow,
+		syncSchedule: syncSchedule,
+	}
+}
+
+// ErrSyncUnavailable is returned by syncNow when the configuration is not
+// being synchronized, e.g. because it is disabled.
+var ErrSyncUnavailable = errors.New("configuration is not being synchronized")
+
+// SyncConfiguration syncs the configuration immediately instead of waiting
+// for its refresh interval.
+func (s *MaintenanceAPIService) SyncConfiguration(ctx context.Context, configId int64) (apiserver.ImplResponse, error) {
+	summary, err := s.syncNow(ctx, configId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if errors.Is(err, ErrSyncUnavailable) {
+		return apiserver.ImplResponse{Code: http.StatusConflict}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	return apiserver.Response(http.StatusOK, summary), nil
+}
+
+func (s *MaintenanceAPIService) GetStatus(ctx context.Context) (apiserver.ImplResponse, error) {
+	stats := booking.Lag.Stats(time.Now())
+	queue := apiserver.BookingQueueStatus{
+		Depth:            int32(stats.QueueDepth),
+		OldestPendingAge: stats.OldestPendingAge.Seconds(),
+		Processed:        int64(stats.Count),
+	}
+	if stats.Count > 0 {
+		queue.AverageLag = stats.Sum.Seconds() / float64(stats.Count)
+	}
+	addresses := ews.AddressCache()
+	cache := apiserver.AddressCacheStatus{
+		Hits:      int64(addresses.Hits),
+		Misses:    int64(addresses.Misses),
+		Evictions: int64(addresses.Evictions),
+		Size:      int32(addresses.Size),
+	}
+	configs, err := conf.GetConfigs(ctx)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	credentials := []apiserver.CredentialStatus{}
+	syncs := []apiserver.SyncScheduleStatus{}
+	for _, config := range configs {
+		syncs = append(syncs, s.syncSchedule(*config.Id))
+		state := ews.CredentialsOf(*config.Id)
+		credentials = append(credentials, apiserver.CredentialStatus{
+			ConfigId:        *config.Id,
+			Healthy:         state.Healthy,
+			Reason:          state.Reason,
+			Since:           state.Since,
+			SecretExpiresAt: config.ClientSecretExpiresAt,
+		})
+	}
+	return apiserver.Response(http.StatusOK, apiserver.Status{BookingQueue: queue, AddressCache: cache, Credentials: credentials, Syncs: syncs}), nil
+}
+
+// utilizationPeriod is the default period utilization is computed for.
+const utilizationPeriod = 7 * 24 * time.Hour
+
+// GetUtilization computes how much of each room's working hours is covered by
+// bookings. Working hours are evaluated in the room's local time zone.
+func (s *MaintenanceAPIService) GetUtilization(ctx context.Context, configId int64, from time.Time, to time.Time) (apiserver.ImplResponse, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-utilizationPeriod)
+	}
+	if !from.Before(to) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	config, err := conf.GetConfig(ctx, configId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	assets, err := conf.GetAssetsByConfig(configId)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+
+	ewsHelper := ews.NewEWSHelper(*config, conf.ReadServiceUserUPN(*config))
+	utilization := []apiserver.RoomUtilization{}
+	for _, ast := range assets {
+		if ast.ProviderID == "" {
+			continue
+		}
+		hours, err := conf.RoomWorkingHours(*config, ast.ProviderID, roomBuilding(ewsHelper, *config, ast.ProviderID))
+		if err != nil {
+			return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+		}
+		events, err := ewsHelper.FindEvents(ast.ProviderID, from, to)
+		if err != nil {
+			log.Error("ews", "finding events in %s: %v", ast.ProviderID, err)
+			return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+		}
+		booked := make([]syncmodel.Interval, 0, len(events))
+		for _, event := range events {
+			booked = append(booked, syncmodel.Interval{Start: event.Start, End: event.End})
+		}
+		bookedTime, availableTime := syncmodel.Utilization(booked, hours, from, to)
+		room := apiserver.RoomUtilization{
+			RoomEmail:        ast.ProviderID,
+			TimeZone:         hours.Location.String(),
+			BookedMinutes:    int64(bookedTime / time.Minute),
+			AvailableMinutes: int64(availableTime / time.Minute),
+		}
+		if availableTime > 0 {
+			room.Utilization = float64(bookedTime) / float64(availableTime)
+		}
+		utilization = append(utilization, room)
+	}
+	return apiserver.Response(http.StatusOK, utilization), nil
+}
+
+// roomBuilding locates the room in its building as the room location pattern
+// does, if working hours of buildings are configured.
+func roomBuilding(ewsHelper *ews.EWSHelper, config apiserver.Configuration, roomEmail string) string {
+	if len(config.BuildingWorkingHours) == 0 {
+		return ""
+	}
+	pattern, err := conf.RoomLocationPattern(config)
+	if err != nil || !pattern.Enabled() {
+		return ""
+	}
+	contact, err := ewsHelper.ResolveContact(roomEmail)
+	if err != nil {
+		log.Warn("ews", "locating room %s: %v", roomEmail, err)
+		return ""
+	}
+	building, _ := pattern.Locate(contact.Office)
+	return building
+}
+
+// GetAuditLog lists the mutations the app performed in Exchange, newest first.
+func (s *MaintenanceAPIService) GetAuditLog(ctx context.Context, configId int64, room string, from time.Time, to time.Time) (apiserver.ImplResponse, error) {
+	entries, err := conf.GetAuditLog(configId, room, from, to)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	auditLog := []apiserver.AuditEntry{}
+	for _, entry := range entries {
+		auditLog = append(auditLog, apiserver.AuditEntry{
+			ConfigId:      entry.ConfigurationID,
+			Timestamp:     entry.CreatedAt,
+			Action:        entry.Action,
+			Organizer:     entry.Organizer,
+			Rooms:         entry.Rooms,
+			ExchangeUID:   entry.ExchangeUID,
+			Start:         entry.StartTime.Ptr(),
+			End:           entry.EndTime.Ptr(),
+			CorrelationId: entry.CorrelationID,
+			Error:         entry.Error.String,
+		})
+	}
+	return apiserver.Response(http.StatusOK, auditLog), nil
+}
+
+// supportBundlePeriod is how far back the support bundle's audit log reaches.
+const supportBundlePeriod = 7 * 24 * time.Hour
+
+// GetSupportBundle collects the state of a configuration for troubleshooting.
+// Secrets are stripped from the configuration and subjects are never part of
+// the audit log, so the bundle can be handed to support as is.
+func (s *MaintenanceAPIService) GetSupportBundle(ctx context.Context, configId int64) (apiserver.ImplResponse, error) {
+	config, err := conf.GetConfig(ctx, configId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	assets, err := conf.GetAssetsByConfig(configId)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	counts, err := conf.CountBookingGroupsByState(configId)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	now := time.Now()
+	entries, err := conf.GetAuditLog(configId, "", now.Add(-supportBundlePeriod), time.Time{})
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+
+	state := ews.CredentialsOf(configId)
+	bundle := apiserver.SupportBundle{
+		GeneratedAt:   now,
+		Configuration: redactedConfig(*config),
+		Credentials: apiserver.CredentialStatus{
+			ConfigId:        configId,
+			Healthy:         state.Healthy,
+			Reason:          state.Reason,
+			Since:           state.Since,
+			SecretExpiresAt: config.ClientSecretExpiresAt,
+		},
+		Rooms:         []apiserver.SupportBundleRoom{},
+		BookingCounts: counts,
+		AuditLog:      []apiserver.AuditEntry{},
+	}
+	for _, ast := range assets {
+		bundle.Rooms = append(bundle.Rooms, apiserver.SupportBundleRoom{
+			RoomEmail:    ast.ProviderID,
+			ProjectId:    ast.ProjectID,
+			AssetId:      ast.AssetID.Ptr(),
+			HasSyncState: ast.SyncState != "",
+			SyncedAt:     ast.SyncedAt.Ptr(),
+		})
+	}
+	for _, entry := range entries {
+		bundle.AuditLog = append(bundle.AuditLog, apiserver.AuditEntry{
+			ConfigId:      entry.ConfigurationID,
+			Timestamp:     entry.CreatedAt,
+			Action:        entry.Action,
+			Organizer:     entry.Organizer,
+			Rooms:         entry.Rooms,
+			ExchangeUID:   entry.ExchangeUID,
+			Start:         entry.StartTime.Ptr(),
+			End:           entry.EndTime.Ptr(),
+			CorrelationId: entry.CorrelationID,
+			// Errors may quote the SOAP response.
+			Error: ews.Redact(entry.Error.String),
+		})
+	}
+	return apiserver.Response(http.StatusOK, bundle), nil
+}
+
+// redactedConfig is the configuration without its credentials.
+func redactedConfig(config apiserver.Configuration) apiserver.Configuration {
+	config.ClientSecret = nil
+	config.Password = nil
+	if config.ProxyURL != nil {
+		if proxyURL, err := url.Parse(*config.ProxyURL); err == nil {
+			config.ProxyURL = common.Ptr(proxyURL.Redacted())
+		} else {
+			config.ProxyURL = nil
+		}
+	}
+	return config
+}
+
+func (s *MaintenanceAPIService) GetOrphanedEvents(ctx context.Context, configId int64) (apiserver.ImplResponse, error) {
+	return orphanedEvents(ctx, configId, false)
+}
+
+func (s *MaintenanceAPIService) CancelOrphanedEvents(ctx context.Context, configId int64, dryRun bool) (apiserver.ImplResponse, error) {
+	return orphanedEvents(ctx, configId, !dryRun)
+}
+
+// orphanedEvents scans calendars of the config's rooms for events created by
+// the app that are unknown to the database, e.g. after restoring an older
+// backup. Such events would never be cancelled otherwise.
+func orphanedEvents(ctx context.Context, configId int64, cancel bool) (apiserver.ImplResponse, error) {
+	config, err := conf.GetConfig(ctx, configId)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.ImplResponse{Code: http.StatusBadRequest}, nil
+	}
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+	assets, err := conf.GetAssetsByConfig(configId)
+	if err != nil {
+		return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+	}
+
+	if conf.IsReadOnly(*config) {
+		// Report what would be cancelled.
+		cancel = false
+	}
+	ewsHelper := ews.NewEWSHelper(*config, conf.ReadServiceUserUPN(*config))
+	writeHelper := ews.NewEWSHelper(*config, conf.WriteServiceUserUPN(*config))
+	// Cancellations of one cleanup are correlated in the audit log.
+	writeHelper.SetCorrelationID(ews.NewCorrelationID())
+	orphaned := []apiserver.OrphanedEvent{}
+	// Multi-room events are present in each of the rooms' calendars.
+	seen := make(map[string]bool)
+	for _, ast := range assets {
+		if ast.ProviderID == "" {
+			continue
+		}
+		events, err := ewsHelper.FindTaggedEvents(ast.ProviderID)
+		if err != nil {
+			log.Error("ews", "finding events created by the app in %s: %v", ast.ProviderID, err)
+			return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+		}
+		for _, event := range events {
+			if seen[event.ExchangeUID] {
+				continue
+			}
+			seen[event.ExchangeUID] = true
+			if _, err := conf.GetBookingGroupByElionaID(event.ElionaID); err == nil {
+				continue
+			} else if !errors.Is(err, conf.ErrNotFound) {
+				return apiserver.ImplResponse{Code: http.StatusInternalServerError}, err
+			}
+
+			orphan := apiserver.OrphanedEvent{
+				RoomEmail:   ast.ProviderID,
+				ExchangeUID: event.ExchangeUID,
+				Organizer:   event.OrganizerEmail,
+				ElionaId:    event.ElionaID,
+				Start:       event.Start,
+				End:         event.End,
+			}
+			if cancel {
+				if err := writeHelper.CancelEvent(syncmodel.BookingGroup{
+					ExchangeUID:    event.ExchangeUID,
+					OrganizerEmail: event.OrganizerEmail,
+				}); err != nil {
+					log.Error("ews", "cancelling orphaned event %s: %v", event.ExchangeUID, err)
+				} else {
+					orphan.Cancelled = true
+					log.Info("ews", "cancelled orphaned event %s of Eliona booking %d", event.ExchangeUID, event.ElionaID)
+				}
+			}
+			orphaned = append(orphaned, orphan)
+		}
+	}
+	return apiserver.Response(http.StatusOK, orphaned), nil
+}
@@ -20,14 +20,21 @@ import (
 	"errors"
 	"ews/apiserver"
 	"ews/apiservices"
+	"ews/appdb"
 	"ews/booking"
 	"ews/conf"
 	"ews/eliona"
 	"ews/ews"
+	"ews/model"
 	syncmodel "ews/model/sync"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/eliona-smart-building-assistant/go-eliona/app"
@@ -62,12 +69,147 @@ func initialization() {
 	app.Patch(conn, app.AppName(), "000400",
 		app.ExecSqlFile("conf/000300.sql"),
 	)
+
+	app.Patch(conn, app.AppName(), "000500",
+		app.ExecSqlFile("conf/000500.sql"),
+		asset.InitAssetTypeFiles("resources/asset-types/*.json"),
+	)
+
+	// Keep resolved addresses across restarts, now that their table exists.
+	ews.PersistAddresses(conf.GetResolvedAddresses, conf.UpsertResolvedAddress)
 }
 
 var once sync.Once
 var mu sync.Mutex
 var resubscribeTrigger = make(chan struct{}, 1)
 
+// defaultMaxConcurrentSyncs is how many configurations are processed at once
+// unless MAX_CONCURRENT_SYNCS says otherwise.
+const defaultMaxConcurrentSyncs = 4
+
+// processing bounds the configurations synchronized and booking events
+// handled at once, so that many configurations starting together don't
+// exhaust the database connection pool or get throttled by Exchange.
+var processing = newWorkerPool(maxConcurrentSyncs())
+
+func maxConcurrentSyncs() int {
+	max, err := strconv.Atoi(common.Getenv("MAX_CONCURRENT_SYNCS", strconv.Itoa(defaultMaxConcurrentSyncs)))
+	if err != nil || max < 1 {
+		log.Warn("main", "invalid MAX_CONCURRENT_SYNCS; using %d", defaultMaxConcurrentSyncs)
+		return defaultMaxConcurrentSyncs
+	}
+	return max
+}
+
+// defaultMinRefreshInterval is the shortest refresh interval unless
+// MIN_REFRESH_INTERVAL says otherwise.
+const defaultMinRefreshInterval = 10 * time.Second
+
+// minRefreshInterval raises shorter refresh intervals, so that a configuration
+// with a very low (or no) interval doesn't sync back to back.
+var minRefreshInterval = loadMinRefreshInterval()
+
+func loadMinRefreshInterval() time.Duration {
+	min, err := time.ParseDuration(common.Getenv("MIN_REFRESH_INTERVAL", defaultMinRefreshInterval.String()))
+	if err != nil || min < 0 {
+		log.Warn("main", "invalid MIN_REFRESH_INTERVAL, using %v: %v", defaultMinRefreshInterval, err)
+		return defaultMinRefreshInterval
+	}
+	return min
+}
+
+// refreshInterval is the time between the starts of the configuration's
+// regular syncs, at least min.
+func refreshInterval(config apiserver.Configuration, min time.Duration) time.Duration {
+	interval := time.Duration(config.RefreshInterval) * time.Second
+	if interval < min {
+		return min
+	}
+	return interval
+}
+
+// syncSchedules tracks how the regular syncs of the configurations keep up
+// with their refresh intervals.
+type syncSchedules struct {
+	mu      sync.Mutex
+	configs map[int64]syncSchedule
+}
+
+type syncSchedule struct {
+	interval     time.Duration
+	lastDuration time.Duration
+	overrunning  bool
+	overruns     int64
+}
+
+var schedules = &syncSchedules{configs: make(map[int64]syncSchedule)}
+
+// overrunPause is the wait after a sync that took longer than its interval,
+// so that Exchange and the database get a break between back to back syncs.
+const overrunPause = 5 * time.Second
+
+// run runs a regular sync and returns how long to wait for the next one, so
+// that syncs start every interval. A sync taking longer than the interval is
+// followed by the next one after overrunPause instead of waiting another
+// interval, as the collection never runs syncs of a configuration
+// concurrently.
+func (s *syncSchedules) run(configID int64, interval time.Duration, collect func() error) (time.Duration, error) {
+	startedAt := time.Now()
+	if err := collect(); err != nil {
+		return 0, err
+	}
+	took := time.Since(startedAt)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	schedule := s.configs[configID]
+	wasOverrunning := schedule.overrunning
+	schedule.interval = interval
+	schedule.lastDuration = took
+	schedule.overrunning = took > interval
+	if schedule.overrunning {
+		schedule.overruns++
+	}
+	s.configs[configID] = schedule
+	switch {
+	case schedule.overrunning && !wasOverrunning:
+		log.Warn("main", "sync of %d took %v, longer than its refresh interval %v; raise the refresh interval", configID, took.Round(time.Millisecond), interval)
+	case !schedule.overrunning && wasOverrunning:
+		log.Info("main", "sync of %d keeps up with its refresh interval %v again", configID, interval)
+	}
+	if schedule.overrunning {
+		return overrunPause, nil
+	}
+	return interval - took, nil
+}
+
+// status reports the schedule of the configuration's regular syncs.
+func (s *syncSchedules) status(configID int64) apiserver.SyncScheduleStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	schedule := s.configs[configID]
+	return apiserver.SyncScheduleStatus{
+		ConfigId:        configID,
+		RefreshInterval: schedule.interval.Seconds(),
+		LastDuration:    schedule.lastDuration.Seconds(),
+		Overrunning:     schedule.overrunning,
+		Overruns:        schedule.overruns,
+	}
+}
+
+// workerPool runs at most its capacity of functions at once, queuing the rest.
+type workerPool chan struct{}
+
+func newWorkerPool(size int) workerPool {
+	return make(workerPool, size)
+}
+
+func (p workerPool) run(f func()) {
+	p <- struct{}{}
+	defer func() { <-p }()
+	f()
+}
+
 func collectData() {
 	configs, err := conf.GetConfigs(context.Background())
 	if err != nil {
@@ -113,13 +255,24 @@ func collectData() {
 
 		common.RunOnceWithParam(func(config apiserver.Configuration) {
 			log.Info("main", "Collecting %d started.", *config.Id)
-			if err := collectResources(config); err != nil {
+			wait, err := schedules.run(*config.Id, refreshInterval(config, minRefreshInterval), func() error {
+				_, err := collectResources(config)
+				return err
+			})
+			if err != nil {
 				return // Error is handled in the method itself.
 			}
 			log.Info("main", "Collecting %d finished.", *config.Id)
 
-			time.Sleep(time.Second * time.Duration(config.RefreshInterval))
+			waitForNextSync(config, wait)
 		}, config, fmt.Sprintf("collection_%v", *config.Id))
+
+		if interval := common.Val(config.ResponsePollInterval); interval > 0 {
+			common.RunOnceWithParam(func(config apiserver.Configuration) {
+				reconcilePendingResponses(config)
+				time.Sleep(time.Second * time.Duration(interval))
+			}, config, fmt.Sprintf("responses_%v", *config.Id))
+		}
 	}
 }
 
@@ -131,147 +284,1440 @@ func triggerResubscribe() {
 	}
 }
 
-func collectResources(config apiserver.Configuration) error {
-	// Note: EWSHelper has an address cache and this resets it in each sync.
-	// If there is a need for optimization, create EWS helper only once per config.
-	ewsHelper := ews.NewEWSHelper(config, *config.ServiceUserUPN)
-	if config.RoomListUPN != nil && *config.RoomListUPN != "" {
+// syncRequest asks the collection of a configuration to sync immediately.
+type syncRequest struct {
+	result chan syncResult
+}
+
+type syncResult struct {
+	summary apiserver.SyncSummary
+	err     error
+}
+
+var (
+	syncTriggersMu sync.Mutex
+	syncTriggers   = make(map[int64]chan syncRequest)
+)
+
+// syncTrigger returns the channel the configuration's collection listens on
+// between regular syncs.
+func syncTrigger(configID int64) chan syncRequest {
+	syncTriggersMu.Lock()
+	defer syncTriggersMu.Unlock()
+	trigger, ok := syncTriggers[configID]
+	if !ok {
+		trigger = make(chan syncRequest)
+		syncTriggers[configID] = trigger
+	}
+	return trigger
+}
+
+// waitForNextSync waits until the next regular sync, running the syncs
+// requested in the meantime. Syncs are requested only while the collection is
+// idle, so they never run concurrently with the regular ones.
+func waitForNextSync(config apiserver.Configuration, wait time.Duration) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	trigger := syncTrigger(*config.Id)
+	for {
+		select {
+		case <-timer.C:
+			return
+		case request := <-trigger:
+			log.Info("main", "Sync of %d requested.", *config.Id)
+			summary, err := collectResources(config)
+			request.result <- syncResult{summary: summary, err: err}
+		}
+	}
+}
+
+// syncNow runs a sync of the configuration out of the regular schedule,
+// waiting for its collection to become idle.
+func syncNow(ctx context.Context, configID int64) (apiserver.SyncSummary, error) {
+	config, err := conf.GetConfig(ctx, configID)
+	if err != nil {
+		return apiserver.SyncSummary{}, err
+	}
+	if !conf.IsConfigEnabled(*config) {
+		return apiserver.SyncSummary{}, apiservices.ErrSyncUnavailable
+	}
+	request := syncRequest{result: make(chan syncResult, 1)}
+	select {
+	case syncTrigger(configID) <- request:
+	case <-ctx.Done():
+		return apiserver.SyncSummary{}, ctx.Err()
+	}
+	select {
+	case result := <-request.result:
+		return result.summary, result.err
+	case <-ctx.Done():
+		return apiserver.SyncSummary{}, ctx.Err()
+	}
+}
+
+func collectResources(config apiserver.Configuration) (summary apiserver.SyncSummary, err error) {
+	processing.run(func() {
+		summary, err = syncConfig(config)
+	})
+	return summary, err
+}
+
+func syncConfig(config apiserver.Configuration) (apiserver.SyncSummary, error) {
+	startedAt := time.Now()
+	summary := apiserver.SyncSummary{ConfigId: *config.Id}
+	// Checked afterwards, so that failures of this sync count.
+	defer func() { checkCredentials(config, time.Now()) }()
+	ewsHelper := ews.NewEWSHelper(config, conf.ReadServiceUserUPN(config))
+	if conf.RoomDiscovery(config) != syncmodel.RoomDiscoveryRoomList || len(conf.RoomListUPNs(config)) > 0 {
 		if err := discoverNewAssets(ewsHelper, config); err != nil {
-			return err
+			return summary, err
 		}
 	}
+	reconcilePendingApprovals(config)
+	if common.Val(config.ResponsePollInterval) == 0 {
+		// Finish bookings left pending before polling was turned off.
+		reconcilePendingResponses(config)
+	}
 
-	assets, err := conf.GetAssets()
+	configAssets, err := conf.GetAssetsByConfig(*config.Id)
 	if err != nil {
 		log.Error("conf", "getting assets from DB: %v", err)
-		return err
+		return summary, err
 	}
-	toBook := make(map[string]syncmodel.BookingGroup)
-	var cancelledBookings []syncmodel.RoomBooking
 
+	rooms := roomsToSync(configAssets, *config.Id, conf.MissingRoomEmailPolicy(config), conf.RepairAssetProviderID)
+	summary.Rooms = int32(len(rooms))
+	// Also rooms without changes move their recurrence horizon.
+	extendRecurrences(ewsHelper, rooms, config, time.Now())
+	var subscriptions map[int64]pullSubscription
+	if conf.ChangeDetection(config) == syncmodel.ChangeDetectionPullSubscription {
+		rooms, subscriptions = roomsWithChanges(ewsHelper, rooms, func(assetID int64, subscription pullSubscription) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return conf.PersistSubscription(assetID, subscription.id, subscription.watermark)
+		})
+	}
+	err = syncInPages(rooms,
+		func(ast appdb.Asset) (roomPage, error) {
+			return fetchRoomPage(ewsHelper, ast, config)
+		},
+		func(pages []roomPage) error {
+			if err := processRoomPages(pages, config); err != nil {
+				return err
+			}
+			for _, page := range pages {
+				summary.New += int32(len(page.new))
+				summary.Updated += int32(len(page.updated))
+				summary.Cancelled += int32(len(page.cancelled))
+			}
+			return nil
+		},
+		func(page roomPage) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if err := conf.PersistSyncState(page.asset.ID, page.syncState); err != nil {
+				log.Error("conf", "persisting sync state for %v: %v", page.asset.ID, err)
+				return err
+			}
+			// The events are synchronized once the room's last page is.
+			if subscription, ok := subscriptions[page.asset.ID]; ok && page.last {
+				if err := conf.PersistSubscription(page.asset.ID, subscription.id, subscription.watermark); err != nil {
+					log.Error("conf", "persisting subscription for %v: %v", page.asset.ID, err)
+					return err
+				}
+			}
+			return nil
+		},
+	)
+	// Also rooms whose meetings merely started or ended since the last sync.
+	updateOnlineMeetings(rooms, config, time.Now())
+	summary.Duration = time.Since(startedAt).Seconds()
+	return summary, err
+}
+
+// secretExpiryWarning is how long ahead of the expiry of the client secret its
+// user is warned.
+const secretExpiryWarning = 14 * 24 * time.Hour
+
+type credentialWarning int
+
+const (
+	noCredentialWarning credentialWarning = iota
+	secretExpiring
+	credentialsRejected
+)
+
+var (
+	credentialWarningsMu sync.Mutex
+	credentialWarnings   = make(map[int64]credentialWarning)
+)
+
+// checkCredentials warns the configuration's user ahead of the expiry of the
+// client secret, and once Exchange no longer accepts the credentials. Each
+// warning is sent once until the problem is solved.
+func checkCredentials(config apiserver.Configuration, now time.Time) {
+	state := ews.CredentialsOf(*config.Id)
+	warning := credentialWarningFor(config, state, now)
+	credentialWarningsMu.Lock()
+	sent := credentialWarnings[*config.Id]
+	credentialWarnings[*config.Id] = warning
+	credentialWarningsMu.Unlock()
+	if warning == sent {
+		return
+	}
+	var err error
+	switch warning {
+	case secretExpiring:
+		log.Warn("main", "client secret of configuration %d expires at %v", *config.Id, *config.ClientSecretExpiresAt)
+		err = eliona.NotifySecretExpiry(config, *config.ClientSecretExpiresAt)
+	case credentialsRejected:
+		err = eliona.NotifyCredentialsUnhealthy(config, state.Reason)
+	}
+	if err != nil {
+		log.Error("eliona", "notifying about credentials of configuration %d: %v", *config.Id, err)
+	}
+}
+
+// credentialWarningFor decides what the configuration's user is warned about.
+// Rejected credentials take precedence over the upcoming expiry.
+func credentialWarningFor(config apiserver.Configuration, state ews.CredentialState, now time.Time) credentialWarning {
+	if !state.Healthy {
+		return credentialsRejected
+	}
+	if config.ClientSecretExpiresAt != nil && config.ClientSecretExpiresAt.Sub(now) < secretExpiryWarning {
+		return secretExpiring
+	}
+	return noCredentialWarning
+}
+
+// roomsToSync filters the rooms of the configuration which can be synchronized.
+// Rooms of other configurations must never be synchronized, as they are not
+// accessible with this configuration's credentials. Rooms without an email
+// address indicate broken asset data: they are logged, and repaired if the
+// policy allows it.
+func roomsToSync(assets []appdb.Asset, configID int64, policy syncmodel.MissingRoomEmailPolicy, repair func(appdb.Asset) (appdb.Asset, error)) []appdb.Asset {
+	var rooms []appdb.Asset
 	for _, ast := range assets {
+		if ast.ConfigurationID != configID {
+			continue
+		}
 		if !ast.AssetID.Valid {
 			continue
 		}
 		if ast.ProviderID == "" {
-			continue
+			if !conf.IsResourceAsset(ast) {
+				// The root, building and floor assets have no calendar to
+				// synchronize.
+				continue
+			}
+			if policy != syncmodel.MissingRoomEmailRepair {
+				log.Warn("conf", "skipping room asset %d (%s): no email address stored", ast.AssetID.Int32, ast.GlobalAssetID)
+				continue
+			}
+			repaired, err := repair(ast)
+			if err != nil {
+				log.Warn("conf", "skipping room asset %d: repairing missing email address: %v", ast.AssetID.Int32, err)
+				continue
+			}
+			log.Info("conf", "restored email address %s of room asset %d", repaired.ProviderID, ast.AssetID.Int32)
+			ast = repaired
 		}
+		rooms = append(rooms, ast)
+	}
+	return rooms
+}
 
-		mu.Lock()
-		syncState, err := conf.GetSyncState(ast.ID)
-		if err != nil {
-			log.Error("conf", "getting sync state: %v", err)
+// roomPage is a page of changes in a room's calendar.
+type roomPage struct {
+	asset     appdb.Asset
+	new       []syncmodel.BookingGroup
+	updated   []syncmodel.BookingGroup
+	cancelled []syncmodel.RoomBooking
+	// syncState to continue with after the page is processed.
+	syncState string
+	last      bool
+}
+
+// syncInPages synchronizes the rooms page by page. Each round fetches a page
+// of every room not synchronized yet and processes the pages together, so
+// that events booking multiple rooms are booked at once. Sync states are
+// checkpointed only after the round is processed, so an interrupted sync
+// resumes from the last processed round instead of from the beginning.
+func syncInPages(assets []appdb.Asset, fetch func(appdb.Asset) (roomPage, error), process func([]roomPage) error, checkpoint func(roomPage) error) error {
+	pending := assets
+	for len(pending) > 0 {
+		var pages []roomPage
+		var next []appdb.Asset
+		for _, ast := range pending {
+			page, err := fetch(ast)
+			if err != nil {
+				return err
+			}
+			pages = append(pages, page)
+			if !page.last {
+				next = append(next, ast)
+			}
+		}
+		if err := process(pages); err != nil {
 			return err
 		}
+		for _, page := range pages {
+			if err := checkpoint(page); err != nil {
+				return err
+			}
+		}
+		pending = next
+	}
+	return nil
+}
+
+// changeFeed reports changes in rooms' calendars through pull subscriptions.
+type changeFeed interface {
+	Subscribe(roomEmail string) (subscriptionID string, watermark string, err error)
+	GetEvents(roomEmail, subscriptionID, watermark string) (changed bool, newWatermark string, err error)
+}
+
+// pullSubscription is a room's pull subscription with the watermark its
+// events are synchronized up to.
+type pullSubscription struct {
+	id        string
+	watermark string
+}
+
+// roomsWithChanges returns the rooms whose calendars changed since they were
+// last synchronized according to their pull subscriptions, with the
+// subscriptions to persist once the rooms are synchronized. Rooms without a
+// usable subscription get a new one and are synchronized as well, as are
+// rooms whose events can't be got, falling back to polling. The watermarks of
+// unchanged rooms are persisted right away.
+func roomsWithChanges(feed changeFeed, rooms []appdb.Asset, persist func(assetID int64, subscription pullSubscription) error) ([]appdb.Asset, map[int64]pullSubscription) {
+	var changed []appdb.Asset
+	subscriptions := make(map[int64]pullSubscription)
+	for _, ast := range rooms {
+		if ast.SubscriptionID != "" && ast.SyncState != "" {
+			roomChanged, watermark, err := feed.GetEvents(ast.ProviderID, ast.SubscriptionID, ast.Watermark)
+			switch {
+			case err == nil && !roomChanged:
+				if err := persist(ast.ID, pullSubscription{id: ast.SubscriptionID, watermark: watermark}); err != nil {
+					log.Error("conf", "persisting watermark for %v: %v", ast.ID, err)
+				}
+				continue
+			case err == nil:
+				subscriptions[ast.ID] = pullSubscription{id: ast.SubscriptionID, watermark: watermark}
+				changed = append(changed, ast)
+				continue
+			case errors.Is(err, ews.ErrSubscriptionLost):
+				log.Info("EWS", "subscribing to %s again: %v", ast.ProviderID, err)
+			default:
+				log.Error("EWS", "getting events of %s, synchronizing it anyway: %v", ast.ProviderID, err)
+				changed = append(changed, ast)
+				continue
+			}
+		}
+		// Subscribing before synchronizing catches the changes made meanwhile.
+		id, watermark, err := feed.Subscribe(ast.ProviderID)
+		if err != nil {
+			log.Error("EWS", "subscribing to %s: %v", ast.ProviderID, err)
+		} else {
+			subscriptions[ast.ID] = pullSubscription{id: id, watermark: watermark}
+		}
+		changed = append(changed, ast)
+	}
+	return changed, subscriptions
+}
 
-		// See git blame here for filtering these events based on changeKey.
-		// Now that Exchange provides the distinction, let's trust it and simplify
-		// our logic.
-		new, updated, cancelled, newSyncState, err := ewsHelper.GetRoomAppointments(ast.AssetID.Int32, ast.ProviderID, syncState)
+// recurrenceExtensionInterval is how far the recurrence horizon of a room moves
+// before the occurrences entering it are synchronized.
+const recurrenceExtensionInterval = 24 * time.Hour
+
+// extendRecurrences books the occurrences of recurring events that entered the
+// rooms' recurrence horizon since it last moved. Occurrences are fetched
+// following the last one known of each series, so those already booked are not
+// fetched again. Rooms synchronized for the first time are expanded up to the
+// horizon by their sync already.
+func extendRecurrences(ewsHelper *ews.EWSHelper, rooms []appdb.Asset, config apiserver.Configuration, now time.Time) {
+	until := now.Add(conf.RecurrenceHorizon(config))
+	var pages []roomPage
+	var extended []appdb.Asset
+	for _, room := range rooms {
+		if !room.ExpandedUntil.Valid {
+			extended = append(extended, room)
+			continue
+		}
+		if until.Sub(room.ExpandedUntil.Time) < recurrenceExtensionInterval {
+			continue
+		}
+		groups, err := extendedSeries(ewsHelper, room, until, config)
 		if err != nil {
-			log.Error("EWS", "getting appointments for %s: %v", ast.ProviderID, err)
-			return err
+			log.Error("EWS", "extending recurring events of %s: %v", room.ProviderID, err)
+			continue
+		}
+		pages = append(pages, roomPage{asset: room, updated: groups})
+		extended = append(extended, room)
+	}
+	if len(pages) != 0 {
+		// Pages of all rooms are booked together, so that the occurrences
+		// of a series in several rooms are merged.
+		if err := processRoomPages(pages, config); err != nil {
+			// The horizon stays, the occurrences are fetched again.
+			return
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, room := range extended {
+		if err := conf.PersistExpandedUntil(room.ID, until); err != nil {
+			log.Error("conf", "persisting recurrence horizon for %v: %v", room.ID, err)
 		}
+	}
+}
 
-		for i := range updated {
-			a := updated[i]
-			a, err := assignElionaIDs(a)
-			if err != nil {
-				return err
+// extendedSeries returns the groups booking the occurrences of the room's
+// recurring events up to until that follow the known ones. Series unknown to
+// the app are left to the room's sync.
+func extendedSeries(ewsHelper *ews.EWSHelper, room appdb.Asset, until time.Time, config apiserver.Configuration) ([]syncmodel.BookingGroup, error) {
+	series, err := ewsHelper.FindSeriesOccurrences(room.ProviderID, room.ExpandedUntil.Time, until)
+	if err != nil {
+		return nil, err
+	}
+	var groups []syncmodel.BookingGroup
+	for _, occurrence := range series {
+		dbGroup, err := conf.GetBookingGroupByExchangeUID(occurrence.ExchangeUID)
+		if errors.Is(err, conf.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("getting booking for exchange UID %s: %v", occurrence.ExchangeUID, err)
+		}
+		if !dbGroup.ElionaGroupID.Valid {
+			continue
+		}
+		known, err := conf.GetBookingOccurrencesByGroupID(dbGroup.ID)
+		if err != nil {
+			return nil, fmt.Errorf("getting occurrences for exchange UID %s: %v", occurrence.ExchangeUID, err)
+		}
+		lastIndex := 0
+		for _, knownOccurrence := range known {
+			if int(knownOccurrence.ExchangeInstanceIndex) > lastIndex {
+				lastIndex = int(knownOccurrence.ExchangeInstanceIndex)
 			}
-			if existing, ok := toBook[a.ExchangeUID]; !ok {
-				toBook[a.ExchangeUID] = a
-			} else {
-				for i, existingOccurrence := range existing.Occurrences {
-					existing.Occurrences[i].RoomBookings = append(existingOccurrence.RoomBookings, a.Occurrences[i].RoomBookings...)
-					toBook[a.ExchangeUID] = existing
-				}
+		}
+		group, err := ewsHelper.ExtendSeries(room.AssetID.Int32, room.ProviderID, occurrence.ItemID, lastIndex, until)
+		if err != nil {
+			return nil, err
+		}
+		if len(group.Occurrences) == 0 {
+			continue
+		}
+		// Booked within the existing group, leaving its known occurrences be.
+		group.ElionaID = dbGroup.ElionaGroupID.Int32
+		groups = append(groups, group)
+	}
+	policy := conf.SelfOrganizedPolicy(config)
+	groups = dropSelfOrganized(groups, room.ProviderID, policy)
+	return applyBlockPolicy(groups, room.ProviderID, conf.BlockPolicy(config)), nil
+}
+
+// fetchRoomPage gets the next page of changes in the room's calendar and
+// matches them with bookings already known to the app.
+func fetchRoomPage(ewsHelper *ews.EWSHelper, ast appdb.Asset, config apiserver.Configuration) (roomPage, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	syncState, err := conf.GetSyncState(ast.ID)
+	if err != nil {
+		log.Error("conf", "getting sync state: %v", err)
+		return roomPage{}, err
+	}
+
+	// See git blame here for filtering these events based on changeKey.
+	// Now that Exchange provides the distinction, let's trust it and simplify
+	// our logic.
+	new, updated, cancelled, newSyncState, last, err := ewsHelper.GetRoomAppointments(ast.AssetID.Int32, ast.ProviderID, syncState)
+	if err != nil {
+		log.Error("EWS", "getting appointments for %s: %v", ast.ProviderID, err)
+		return roomPage{}, err
+	}
+	found := append(append([]syncmodel.BookingGroup{}, new...), updated...)
+	policy := conf.SelfOrganizedPolicy(config)
+	new = dropSelfOrganized(new, ast.ProviderID, policy)
+	updated = dropSelfOrganized(updated, ast.ProviderID, policy)
+	new = applyBlockPolicy(new, ast.ProviderID, conf.BlockPolicy(config))
+	updated = applyBlockPolicy(updated, ast.ProviderID, conf.BlockPolicy(config))
+	for _, item := range roomItems(ast.ID, found, append(append([]syncmodel.BookingGroup{}, new...), updated...)) {
+		if err := conf.AddRoomItem(item); err != nil {
+			log.Error("conf", "remembering item %s of %s: %v", item.ExchangeID, ast.ProviderID, err)
+			return roomPage{}, err
+		}
+	}
+
+	page := roomPage{
+		asset:     ast,
+		syncState: newSyncState,
+		last:      last,
+	}
+	// Bookings of a resumed page might have been already booked, these get
+	// their Eliona IDs assigned and are updated instead of duplicated.
+	for _, a := range updated {
+		a, err := assignElionaIDs(a, storedBookings)
+		if err != nil {
+			return roomPage{}, err
+		}
+		page.updated = append(page.updated, a)
+	}
+	for _, a := range new {
+		a, err := assignElionaIDs(a, storedBookings)
+		if err != nil {
+			return roomPage{}, err
+		}
+		page.new = append(page.new, a)
+	}
+	if err := mergeDoubleBookings(ewsHelper, &page, config); err != nil {
+		return roomPage{}, err
+	}
+	if err := flagImportConflicts(ewsHelper, &page, config); err != nil {
+		return roomPage{}, err
+	}
+	var unknown []string
+	page.cancelled, unknown, err = cancelledBookings(ast.AssetID.Int32, cancelled, storedBookings)
+	if err != nil {
+		log.Error("conf", "matching deleted items of %s: %v", ast.ProviderID, err)
+		return roomPage{}, err
+	}
+	for _, exchangeID := range cancelled {
+		if err := conf.DeleteRoomItem(exchangeID); err != nil {
+			log.Error("conf", "forgetting deleted item of %s: %v", ast.ProviderID, err)
+			return roomPage{}, err
+		}
+	}
+	handleUnknownDeletes(&page, unknown, conf.UnknownDeletePolicy(config))
+	return page, nil
+}
+
+// roomItems returns the items of a room's calendar found without room booking
+// of their own: the recurring masters of series, known by their UID, and the
+// items of groups dropped by policy.
+func roomItems(assetID int64, found, kept []syncmodel.BookingGroup) []appdb.RoomItem {
+	var items []appdb.RoomItem
+	for _, group := range found {
+		dropped := true
+		for _, k := range kept {
+			if k.ExchangeUID == group.ExchangeUID {
+				dropped = false
 			}
 		}
-		for i := range new {
-			a := new[i]
-			a, err := assignElionaIDs(a)
-			if err != nil {
-				return err
+		if !dropped {
+			if group.MasterExchangeID != "" {
+				items = append(items, appdb.RoomItem{AssetID: assetID, ExchangeID: group.MasterExchangeID, ExchangeUID: group.ExchangeUID})
 			}
-			if existing, ok := toBook[a.ExchangeUID]; !ok {
-				toBook[a.ExchangeUID] = a
-			} else {
-				for i, existingOccurrence := range existing.Occurrences {
-					existing.Occurrences[i].RoomBookings = append(existingOccurrence.RoomBookings, a.Occurrences[i].RoomBookings...)
-					toBook[a.ExchangeUID] = existing
-				}
+			continue
+		}
+		if group.MasterExchangeID != "" {
+			items = append(items, appdb.RoomItem{AssetID: assetID, ExchangeID: group.MasterExchangeID})
+		}
+		for _, occurrence := range group.Occurrences {
+			for _, roomBooking := range occurrence.RoomBookings {
+				items = append(items, appdb.RoomItem{AssetID: assetID, ExchangeID: roomBooking.ExchangeIDInResourceMailbox})
 			}
 		}
-		for _, cancelledExchangeID := range cancelled {
-			dbBookingGroup, err := conf.GetBookingGroupByExchangeID(cancelledExchangeID)
-			if err != nil && !errors.Is(err, conf.ErrNotFound) {
-				log.Error("conf", "getting booking group for exchange ID %s: %v", cancelledExchangeID, err)
-				return err
-			} else if errors.Is(err, conf.ErrNotFound) || !dbBookingGroup.ElionaGroupID.Valid {
-				// Does not matter, cancelled anyways
+	}
+	return items
+}
+
+// bookingLookup finds what the items deleted from a room's calendar were
+// booked as.
+type bookingLookup struct {
+	groupByExchangeID      func(exchangeID string) (appdb.BookingGroup, error)
+	groupByExchangeUID     func(exchangeUID string) (appdb.BookingGroup, error)
+	roomItem               func(exchangeID string) (appdb.RoomItem, error)
+	occurrencesByGroupID   func(groupID int64) ([]appdb.BookingOccurrence, error)
+	occurrenceByExchangeID func(exchangeID string) (appdb.BookingOccurrence, error)
+}
+
+var storedBookings = bookingLookup{
+	groupByExchangeID:      conf.GetBookingGroupByExchangeID,
+	groupByExchangeUID:     conf.GetBookingGroupByExchangeUID,
+	roomItem:               conf.GetRoomItem,
+	occurrencesByGroupID:   conf.GetBookingOccurrencesByGroupID,
+	occurrenceByExchangeID: conf.GetBookingOccurrenceByExchangeID,
+}
+
+// cancelledBookings returns the room bookings cancelled by the deletion of
+// the items from the room's calendar, and the deleted items unknown to the
+// app. Series are deleted by their recurring master, which is resolved
+// through the UID of its group.
+func cancelledBookings(assetID int32, deleted []string, lookup bookingLookup) (cancelled []syncmodel.RoomBooking, unknown []string, err error) {
+	for _, exchangeID := range deleted {
+		series := false
+		dbBookingGroup, err := lookup.groupByExchangeID(exchangeID)
+		if errors.Is(err, conf.ErrNotFound) {
+			item, err := lookup.roomItem(exchangeID)
+			if errors.Is(err, conf.ErrNotFound) {
+				unknown = append(unknown, exchangeID)
+				continue
+			} else if err != nil {
+				return nil, nil, err
+			}
+			if item.ExchangeUID == "" {
+				// Skipped by policy, so never booked.
+				continue
+			}
+			dbBookingGroup, err = lookup.groupByExchangeUID(item.ExchangeUID)
+			if errors.Is(err, conf.ErrNotFound) {
+				// Cancelled before.
 				continue
+			} else if err != nil {
+				return nil, nil, fmt.Errorf("getting booking group of series %s: %v", item.ExchangeUID, err)
 			}
+			series = true
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("getting booking group for exchange ID %s: %v", exchangeID, err)
+		}
+		if !dbBookingGroup.ElionaGroupID.Valid {
+			// Does not matter, cancelled anyways
+			continue
+		}
 
-			dbOccurrences, err := conf.GetBookingOccurrencesByGroupID(dbBookingGroup.ID)
+		dbOccurrences, err := lookup.occurrencesByGroupID(dbBookingGroup.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting booking occurrences for exchange ID %s groupID %d: %v", exchangeID, dbBookingGroup.ID, err)
+		}
+		if len(dbOccurrences) > 1 && !series {
+			// A deleted item of a series is a single occurrence, e.g. a
+			// modified one dropped when the series was split. The rest of
+			// the series is kept.
+			dbOccurrence, err := lookup.occurrenceByExchangeID(exchangeID)
 			if err != nil {
-				log.Error("conf", "getting booking occurrences for exchange ID %s groupID %d: %v", cancelledExchangeID, dbBookingGroup.ID, err)
-				return err
-			}
-			for _, dbOcc := range dbOccurrences {
-				occ := syncmodel.BookingOccurrence{
-					ElionaID: dbOcc.ElionaBookingID.Int32,
-				}
-				cancelledBookings = append(cancelledBookings, syncmodel.RoomBooking{
-					AssetID:           ast.AssetID.Int32,
-					BookingOccurrence: &occ,
-				})
+				return nil, nil, fmt.Errorf("getting booking occurrence for exchange ID %s: %v", exchangeID, err)
 			}
+			dbOccurrences = []appdb.BookingOccurrence{dbOccurrence}
 		}
-		if err := conf.PersistSyncState(ast.ID, newSyncState); err != nil {
-			log.Error("conf", "persisting sync state for %v: %v", ast.ID, err)
-			return err
+		for _, dbOcc := range dbOccurrences {
+			occ := syncmodel.BookingOccurrence{
+				ElionaID: dbOcc.ElionaBookingID.Int32,
+			}
+			cancelled = append(cancelled, syncmodel.RoomBooking{
+				AssetID:           assetID,
+				BookingOccurrence: &occ,
+			})
 		}
-		mu.Unlock()
 	}
+	return cancelled, unknown, nil
+}
 
-	bc := booking.NewClient(*config.BookingAppURL)
-	if err := bc.Book(toBook); err != nil {
-		log.Error("Booking", "booking: %v", err)
+// unknownDeletes counts the deletions of items unknown to the app.
+var unknownDeletes atomic.Uint64
+
+// handleUnknownDeletes reports deletions of items the app doesn't know. Their
+// creation was likely missed, e.g. because of a page dropped earlier, so if
+// the policy says so, the room is resynchronized from scratch. Events already
+// booked get their Eliona IDs assigned on the way instead of being duplicated.
+func handleUnknownDeletes(page *roomPage, unknown []string, policy syncmodel.UnknownDeletePolicy) {
+	if len(unknown) == 0 {
+		return
+	}
+	unknownDeletes.Add(uint64(len(unknown)))
+	for _, exchangeID := range unknown {
+		log.Warn("sync", "deletion of unknown item %s reported in %s; its creation was likely missed", exchangeID, page.asset.ProviderID)
 	}
+	if policy == syncmodel.UnknownDeleteResync {
+		log.Info("sync", "resynchronizing %s from scratch", page.asset.ProviderID)
+		page.syncState = ""
+	}
+}
 
-	if err := bc.CancelSlice(cancelledBookings); err != nil {
-		log.Error("Booking", "cancelling bookings: %v", err)
+// writeUnknownDeleteMetrics writes the number of unknown deletes in the
+// Prometheus text format.
+func writeUnknownDeleteMetrics(w io.Writer) {
+	fmt.Fprintln(w, "# HELP ews_unknown_deletes_total Deletions of items unknown to the app reported by Exchange.")
+	fmt.Fprintln(w, "# TYPE ews_unknown_deletes_total counter")
+	fmt.Fprintf(w, "ews_unknown_deletes_total %d\n", unknownDeletes.Load())
+}
+
+// dropSelfOrganized drops the events the room organized itself if the policy
+// skips them. Kept ones are booked without an organizer.
+func dropSelfOrganized(groups []syncmodel.BookingGroup, room string, policy syncmodel.SelfOrganizedPolicy) []syncmodel.BookingGroup {
+	var kept []syncmodel.BookingGroup
+	for _, group := range groups {
+		if !group.SelfOrganized {
+			kept = append(kept, group)
+			continue
+		}
+		if policy == syncmodel.SelfOrganizedSkip {
+			log.Info("sync", "skipping event %s organized by room %s itself", group.ExchangeUID, room)
+			continue
+		}
+		log.Info("sync", "importing event %s organized by room %s itself without organizer", group.ExchangeUID, room)
+		kept = append(kept, group)
 	}
+	return kept
+}
 
-	return nil
+// applyBlockPolicy drops the events nobody is invited to if the policy skips
+// them, or marks them occupancy only.
+func applyBlockPolicy(groups []syncmodel.BookingGroup, room string, policy syncmodel.BlockPolicy) []syncmodel.BookingGroup {
+	var kept []syncmodel.BookingGroup
+	for _, group := range groups {
+		if group.Block {
+			switch policy {
+			case syncmodel.BlockSkip:
+				log.Debug("sync", "skipping block %s in %s", group.ExchangeUID, room)
+				continue
+			case syncmodel.BlockOccupancyOnly:
+				group.OccupancyOnly = true
+			}
+		}
+		kept = append(kept, group)
+	}
+	return kept
+}
+
+// mergeDoubleBookings merges events booked in Exchange into the Eliona
+// bookings they duplicate, as when the organizer books the room in both
+// systems before the sync catches up. The event booked in Exchange is adopted
+// for the Eliona booking and the event the app created for it is cancelled,
+// instead of booking the room twice in Eliona.
+func mergeDoubleBookings(ewsHelper *ews.EWSHelper, page *roomPage, config apiserver.Configuration) error {
+	if conf.IsReadOnly(config) || !hasUnknownExternal(page.new) {
+		return nil
+	}
+	tagged, err := ewsHelper.FindTaggedEvents(page.asset.ProviderID)
+	if err != nil {
+		log.Error("EWS", "getting Eliona bookings in %s: %v", page.asset.ProviderID, err)
+		return err
+	}
+	for i, group := range page.new {
+		event, found := findDoubleBooking(group, tagged, conf.OverlapPolicy(config))
+		if !found {
+			continue
+		}
+		merged, err := adoptEvent(group, event, config)
+		if err != nil {
+			log.Error("sync", "merging event %s into the Eliona booking of %s: %v", group.ExchangeUID, event.ExchangeUID, err)
+			continue
+		}
+		page.new[i] = merged
+	}
+	return nil
+}
+
+// findDoubleBooking finds the event the app created for an Eliona booking
+// which the group, booked directly in Exchange by the same organizer,
+// overlaps. Only single events not known to the app are merged.
+func findDoubleBooking(group syncmodel.BookingGroup, tagged []ews.TaggedEvent, policy syncmodel.OverlapPolicy) (ews.TaggedEvent, bool) {
+	if group.ElionaID != 0 || group.CreatedByApp || len(group.Occurrences) != 1 || group.Occurrences[0].InstanceIndex != 0 {
+		return ews.TaggedEvent{}, false
+	}
+	for _, event := range tagged {
+		if event.ExchangeUID == group.ExchangeUID || !strings.EqualFold(event.OrganizerEmail, group.OrganizerEmail) {
+			continue
+		}
+		if _, found := group.FirstOverlap([]syncmodel.Interval{{Start: event.Start, End: event.End}}, policy); found {
+			return event, true
+		}
+	}
+	return ews.TaggedEvent{}, false
+}
+
+// adoptEvent makes the group stand for the Eliona booking of the event and
+// cancels the event.
+func adoptEvent(group syncmodel.BookingGroup, event ews.TaggedEvent, config apiserver.Configuration) (syncmodel.BookingGroup, error) {
+	dbGroup, err := conf.GetBookingGroupByExchangeUID(event.ExchangeUID)
+	if err != nil {
+		return group, fmt.Errorf("getting booking: %v", err)
+	} else if !dbGroup.ElionaGroupID.Valid {
+		return group, errors.New("event is not booked in Eliona")
+	}
+	// Bookings from Eliona have just a single occurrence.
+	occurrences, err := conf.GetBookingOccurrencesByGroupID(dbGroup.ID)
+	if err != nil {
+		return group, err
+	} else if len(occurrences) != 1 {
+		return group, fmt.Errorf("booking group %d has %d != 1 occurrences", dbGroup.ID, len(occurrences))
+	}
+	if err := conf.AdoptExchangeEvent(dbGroup.ID, group.ExchangeUID, group.OrganizerEmail); err != nil {
+		return group, err
+	}
+	group.ElionaID = dbGroup.ElionaGroupID.Int32
+	group.Occurrences[0].ElionaID = occurrences[0].ElionaBookingID.Int32
+	log.Info("sync", "event %s booked in Exchange duplicates Eliona booking %d; adopted it and cancelling %s", group.ExchangeUID, group.ElionaID, event.ExchangeUID)
+
+	organizerHelper := ews.NewEWSHelper(config, event.OrganizerEmail)
+	if err := organizerHelper.CancelEvent(syncmodel.BookingGroup{ExchangeUID: event.ExchangeUID, OrganizerEmail: event.OrganizerEmail}); err != nil {
+		// The event is left as an orphan, cancelled with the orphaned events.
+		log.Error("ews", "cancelling duplicate event %s: %v", event.ExchangeUID, err)
+	}
+	return group, nil
+}
+
+// flagImportConflicts drops bookings new to the app which it shouldn't import.
+// Events created by the app for Eliona bookings are never imported back.
+// Bookings made in Exchange overlapping an Eliona booking are flagged as a
+// conflict instead of imported, if the config says so.
+func flagImportConflicts(ewsHelper *ews.EWSHelper, page *roomPage, config apiserver.Configuration) error {
+	policy := conf.ImportOverlapPolicy(config)
+	var elionaBookings []syncmodel.Interval
+	if policy == syncmodel.ImportOverlapFlag && hasUnknownExternal(page.new, page.updated) {
+		tagged, err := ewsHelper.FindTaggedEvents(page.asset.ProviderID)
+		if err != nil {
+			log.Error("EWS", "getting Eliona bookings in %s: %v", page.asset.ProviderID, err)
+			return err
+		}
+		for _, event := range tagged {
+			elionaBookings = append(elionaBookings, syncmodel.Interval{Start: event.Start, End: event.End})
+		}
+	}
+	var conflicting []syncmodel.BookingGroup
+	page.new, conflicting = splitImports(page.new, elionaBookings, policy, conf.OverlapPolicy(config))
+	var conflictingUpdates []syncmodel.BookingGroup
+	page.updated, conflictingUpdates = splitImports(page.updated, elionaBookings, policy, conf.OverlapPolicy(config))
+	conflicting = append(conflicting, conflictingUpdates...)
+
+	conflict, update := conflictFlag(*page, conflicting, policy)
+	if !update {
+		return nil
+	}
+	if err := eliona.UpsertBookingConflict(page.asset.AssetID.Int32, conflict); err != nil {
+		log.Error("eliona", "upserting booking conflict flag for %s: %v", page.asset.ProviderID, err)
+	}
+	for _, group := range conflicting {
+		log.Info("sync", "booking %s by %s in %s overlaps an Eliona booking; not imported", group.ExchangeUID, group.OrganizerEmail, page.asset.ProviderID)
+		occurrence, _ := group.FirstOverlap(elionaBookings, conf.OverlapPolicy(config))
+		if err := eliona.NotifyBookingConflict(config, page.asset.ProviderID, group.OrganizerEmail, occurrence.Start); err != nil {
+			log.Error("eliona", "notifying about booking conflict: %v", err)
+		}
+	}
+	return nil
+}
+
+// conflictFlag returns the value of the room's booking_conflict attribute
+// after the page was split, and whether to update it. The flag reflects the
+// last change in the room, so it is left as it is without changes.
+func conflictFlag(page roomPage, conflicting []syncmodel.BookingGroup, policy syncmodel.ImportOverlapPolicy) (conflict, update bool) {
+	if policy != syncmodel.ImportOverlapFlag || len(page.new)+len(page.updated)+len(conflicting) == 0 {
+		return false, false
+	}
+	return len(conflicting) > 0, true
+}
+
+func hasUnknownExternal(groupLists ...[]syncmodel.BookingGroup) bool {
+	for _, groups := range groupLists {
+		for _, group := range groups {
+			if group.ElionaID == 0 && !group.CreatedByApp {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitImports separates bookings not known to the app yet which overlap the
+// Eliona bookings, if the policy flags them. Unknown events created by the app
+// are dropped, as their Eliona booking exists already.
+func splitImports(groups []syncmodel.BookingGroup, elionaBookings []syncmodel.Interval, policy syncmodel.ImportOverlapPolicy, overlapPolicy syncmodel.OverlapPolicy) (imported, conflicting []syncmodel.BookingGroup) {
+	for _, group := range groups {
+		if group.ElionaID != 0 {
+			imported = append(imported, group)
+			continue
+		}
+		if group.CreatedByApp {
+			log.Debug("sync", "skipping import of %s created by the app", group.ExchangeUID)
+			continue
+		}
+		if _, found := group.FirstOverlap(elionaBookings, overlapPolicy); found && policy == syncmodel.ImportOverlapFlag {
+			conflicting = append(conflicting, group)
+			continue
+		}
+		imported = append(imported, group)
+	}
+	return imported, conflicting
+}
+
+// processRoomPages books the changes of a round of pages in Eliona.
+func processRoomPages(pages []roomPage, config apiserver.Configuration) error {
+	toBook := make(map[string]syncmodel.BookingGroup)
+	var cancelledBookings []syncmodel.RoomBooking
+	for _, page := range pages {
+		logSeriesSplits(page)
+		for _, groups := range [][]syncmodel.BookingGroup{page.updated, page.new} {
+			for _, group := range groups {
+				mergeRoomGroup(toBook, group)
+			}
+		}
+		cancelledBookings = append(cancelledBookings, page.cancelled...)
+	}
+
+	bc := booking.NewClient(*config.BookingAppURL, conf.BookingClock(config))
+	if err := bc.Book(toBook); err != nil {
+		// Sync states are not checkpointed, the pages will be fetched again.
+		log.Error("Booking", "booking: %v", err)
+		return err
+	}
+
+	if err := bc.CancelSlice(cancelledBookings, conf.EmptyBookingPolicy(config)); err != nil {
+		log.Error("Booking", "cancelling bookings: %v", err)
+	}
+	return nil
+}
+
+// mergeRoomGroup adds the group found in a room mailbox to the groups to book,
+// merging its room bookings into the copy of the same meeting found in other
+// rooms. Groups sharing the UID but not the times are different meetings, e.g.
+// copied by a misbehaving client, and are kept apart under a distinct UID. The
+// Eliona IDs found under the UID are the meeting's, so a copy kept apart for
+// the first time is booked anew.
+func mergeRoomGroup(toBook map[string]syncmodel.BookingGroup, group syncmodel.BookingGroup) {
+	existing, ok := toBook[group.ExchangeUID]
+	if ok && !syncmodel.SameTimes(existing, group) && len(group.Occurrences) > 0 {
+		log.Warn("sync", "event %s has different times in different rooms, booking them separately", group.ExchangeUID)
+		group = withoutElionaIDs(group)
+		group.ExchangeUID = syncmodel.DistinctUID(group.ExchangeUID, group.Occurrences[0].Start)
+		existing, ok = toBook[group.ExchangeUID]
+		if ok && !syncmodel.SameTimes(existing, group) {
+			log.Warn("sync", "event %s has yet other times starting at the same time, not booked", group.ExchangeUID)
+			return
+		}
+	}
+	if !ok {
+		toBook[group.ExchangeUID] = group
+		return
+	}
+	for i := range existing.Occurrences {
+		existing.Occurrences[i].RoomBookings = append(existing.Occurrences[i].RoomBookings, group.Occurrences[i].RoomBookings...)
+	}
+	toBook[group.ExchangeUID] = existing
+}
+
+// logSeriesSplits notes series split by a "this and following occurrences"
+// edit. The truncated series keeps its past occurrences and its remaining ones
+// are cancelled, while the continuation is booked as a new group.
+func logSeriesSplits(page roomPage) {
+	for _, truncated := range page.updated {
+		for _, continuation := range page.new {
+			if syncmodel.IsSeriesSplit(truncated, continuation) {
+				log.Info("sync", "series %s in %s was split, continuing as %s from %v", truncated.ExchangeUID, page.asset.ProviderID, continuation.ExchangeUID, continuation.Occurrences[0].Start)
+			}
+		}
+	}
+}
+
+// updateOnlineMeetings flags the rooms in which a hybrid meeting is taking
+// place, as booked in the Booking app, and clears the flag of the others.
+func updateOnlineMeetings(rooms []appdb.Asset, config apiserver.Configuration, now time.Time) {
+	if len(rooms) == 0 {
+		return
+	}
+	assetIDs := make([]int32, len(rooms))
+	for i, room := range rooms {
+		assetIDs[i] = room.AssetID.Int32
+	}
+	bc := booking.NewClient(*config.BookingAppURL, conf.BookingClock(config))
+	listed, err := bc.ListBookings(assetIDs, now, now)
+	if err != nil {
+		log.Error("booking", "listing ongoing bookings: %v", err)
+		return
+	}
+	online := roomsInOnlineMeetings(listed, now, conf.OverlapPolicy(config))
+	for _, assetID := range assetIDs {
+		if err := eliona.UpsertOnlineMeeting(assetID, online[assetID]); err != nil {
+			log.Error("eliona", "upserting online meeting flag for asset %d: %v", assetID, err)
+		}
+	}
+}
+
+// roomsInOnlineMeetings returns the rooms booked by a hybrid meeting taking
+// place at the given time.
+func roomsInOnlineMeetings(occurrences []syncmodel.BookingOccurrence, t time.Time, policy syncmodel.OverlapPolicy) map[int32]bool {
+	online := make(map[int32]bool)
+	for _, occurrence := range occurrences {
+		if !occurrence.IsOnline || !occurrence.IsOngoing(t, policy) {
+			continue
+		}
+		for _, assetID := range occurrence.GetAssetIDs() {
+			online[assetID] = true
+		}
+	}
+	return online
+}
+
+func discoverNewAssets(ewsHelper *ews.EWSHelper, config apiserver.Configuration) error {
+	root, err := ewsHelper.GetAssets(config)
+	if err != nil {
+		log.Error("EWS", "getting EWS assets: %v", err)
+		return err
+	}
+
+	if cnt, err := eliona.CreateAssets(config, &root); err != nil {
+		log.Error("eliona", "creating assets in Eliona: %v", err)
+		return err
+	} else if cnt > 0 {
+		// New assets are present, need to subscribe again to include these.
+		triggerResubscribe()
+
+		// Set all assets as bookable.
+		if err := eliona.UpsertAssetData(config, withContact(root.Rooms)); err != nil {
+			log.Error("eliona", "upserting asset data: %v", err)
+			return err
+		}
+	}
+	if err := renameRooms(config, root.Rooms); err != nil {
+		log.Error("eliona", "renaming rooms: %v", err)
+		return err
+	}
+	if err := updateRoomData(config, root.Rooms); err != nil {
+		log.Error("eliona", "updating room data: %v", err)
+		return err
+	}
+	if conf.ArchivesRemovedRooms(config) {
+		if err := archiveRemovedRooms(config, root.Rooms); err != nil {
+			log.Error("conf", "archiving removed rooms: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// renameRooms updates the names of assets whose room was renamed in Exchange,
+// or whose name in Eliona changed by the configured room names.
+func renameRooms(config apiserver.Configuration, rooms []model.Room) error {
+	assets, err := conf.GetAssetsByConfig(*config.Id)
+	if err != nil {
+		return err
+	}
+	var renamed []model.Room
+	for _, rename := range renamedRooms(assets, rooms) {
+		name := rename.room.GetName()
+		// Assets created before their names were stored are assumed to be
+		// named correctly.
+		if rename.asset.Name != "" {
+			if err := eliona.RenameAsset(rename.asset.AssetID.Int32, name); err != nil {
+				return err
+			}
+			log.Info("eliona", "renamed asset %d of room %s from '%s' to '%s'", rename.asset.AssetID.Int32, rename.room.Email, rename.asset.Name, name)
+			if !containsRoom(renamed, rename.room.Email) {
+				renamed = append(renamed, rename.room)
+			}
+		}
+		if err := conf.SetAssetName(rename.asset, name); err != nil {
+			return err
+		}
+	}
+	renamed = withContact(renamed)
+	if len(renamed) == 0 {
+		return nil
+	}
+	return eliona.UpsertAssetData(config, renamed)
+}
+
+// withContact returns the rooms whose contact data is known. The data of the
+// others is left as is instead of being blanked until their lookup succeeds.
+func withContact(rooms []model.Room) []model.Room {
+	var known []model.Room
+	for _, room := range rooms {
+		if !room.ContactUnknown {
+			known = append(known, room)
+		}
+	}
+	return known
+}
+
+type roomChange struct {
+	asset appdb.Asset
+	room  model.Room
+}
+
+// renamedRooms returns the room assets whose stored name differs from the
+// discovered room's. Rooms are matched by their email address, as names
+// change.
+func renamedRooms(assets []appdb.Asset, rooms []model.Room) []roomChange {
+	var renames []roomChange
+	for _, ast := range assets {
+		if ast.ProviderID == "" || !ast.AssetID.Valid {
+			continue
+		}
+		for _, room := range rooms {
+			if strings.EqualFold(room.Email, ast.ProviderID) && room.GetName() != ast.Name {
+				renames = append(renames, roomChange{asset: ast, room: room})
+			}
+		}
+	}
+	return renames
+}
+
+// updateRoomData updates the asset data of rooms whose attributes, like
+// capacity or contact data, changed.
+func updateRoomData(config apiserver.Configuration, rooms []model.Room) error {
+	assets, err := conf.GetAssetsByConfig(*config.Id)
+	if err != nil {
+		return err
+	}
+	changes, err := changedData(assets, rooms)
+	if err != nil {
+		return err
+	}
+	var changed []model.Room
+	for _, change := range changes {
+		if !containsRoom(changed, change.room.Email) {
+			changed = append(changed, change.room)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	if err := eliona.UpsertAssetData(config, changed); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		digest, err := change.room.DataDigest()
+		if err != nil {
+			return err
+		}
+		if err := conf.SetAssetDataDigest(change.asset, digest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changedData returns the room assets whose stored data digest differs from
+// the discovered room's.
+func changedData(assets []appdb.Asset, rooms []model.Room) ([]roomChange, error) {
+	var changes []roomChange
+	for _, ast := range assets {
+		if ast.ProviderID == "" || !ast.AssetID.Valid {
+			continue
+		}
+		for _, room := range rooms {
+			if !strings.EqualFold(room.Email, ast.ProviderID) || room.ContactUnknown {
+				continue
+			}
+			digest, err := room.DataDigest()
+			if err != nil {
+				return nil, fmt.Errorf("room %s: %v", room.Email, err)
+			}
+			if digest != ast.DataDigest {
+				changes = append(changes, roomChange{asset: ast, room: room})
+			}
+		}
+	}
+	return changes, nil
+}
+
+func containsRoom(rooms []model.Room, email string) bool {
+	for _, room := range rooms {
+		if strings.EqualFold(room.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveRemovedRooms archives the assets of rooms no longer discovered, so
+// that they are neither synchronized nor bookable anymore, and restores
+// archived assets of rooms discovered again.
+func archiveRemovedRooms(config apiserver.Configuration, rooms []model.Room) error {
+	if len(rooms) == 0 {
+		// More likely a misconfigured discovery than all rooms removed.
+		log.Warn("conf", "not archiving rooms of config %d: no rooms discovered", *config.Id)
+		return nil
+	}
+	assets, err := conf.GetAssetsByConfig(*config.Id)
+	if err != nil {
+		return err
+	}
+	archived, err := conf.GetArchivedAssetsByConfig(*config.Id)
+	if err != nil {
+		return err
+	}
+	_, removed := partitionDiscovered(assets, rooms)
+	restored, _ := partitionDiscovered(archived, rooms)
+
+	var removedRooms []string
+	for _, ast := range removed {
+		if err := conf.SetAssetArchived(ast, true); err != nil {
+			return err
+		}
+		if err := eliona.UpsertBookable(ast.AssetID.Int32, false); err != nil {
+			log.Error("eliona", "setting archived asset %d not bookable: %v", ast.AssetID.Int32, err)
+		}
+		room := ast.ProviderID
+		if room == "" {
+			room = ast.GlobalAssetID
+		}
+		log.Info("conf", "archived asset %d of room %s no longer discovered", ast.AssetID.Int32, room)
+		// Rooms have an asset in each of the projects.
+		if !containsFold(removedRooms, room) {
+			removedRooms = append(removedRooms, room)
+		}
+	}
+	for _, ast := range restored {
+		if err := conf.SetAssetArchived(ast, false); err != nil {
+			return err
+		}
+		if err := eliona.UpsertBookable(ast.AssetID.Int32, true); err != nil {
+			log.Error("eliona", "setting restored asset %d bookable: %v", ast.AssetID.Int32, err)
+		}
+		log.Info("conf", "restored asset %d of %s discovered again", ast.AssetID.Int32, ast.GlobalAssetID)
+	}
+	if len(removed) == 0 && len(restored) == 0 {
+		return nil
+	}
+	// Bookings of archived rooms are no longer watched, those of restored
+	// ones again.
+	triggerResubscribe()
+	if len(removedRooms) > 0 {
+		if err := eliona.NotifyRoomsArchived(config, removedRooms); err != nil {
+			log.Error("eliona", "notifying user about archived rooms: %v", err)
+		}
+	}
+	return nil
+}
+
+// partitionDiscovered splits the room assets into those of the discovered
+// rooms and the others. Assets other than rooms are in neither.
+func partitionDiscovered(assets []appdb.Asset, rooms []model.Room) (discovered, missing []appdb.Asset) {
+	var gais []string
+	for i := range rooms {
+		gais = append(gais, rooms[i].GetGAI())
+	}
+	for _, ast := range assets {
+		if !conf.IsResourceAsset(ast) || !ast.AssetID.Valid {
+			continue
+		}
+		if containsFold(gais, ast.GlobalAssetID) {
+			discovered = append(discovered, ast)
+		} else {
+			missing = append(missing, ast)
+		}
+	}
+	return discovered, missing
+}
+
+// reconcilePendingApprovals resolves bookings of rooms requiring approval once
+// the delegates respond.
+func reconcilePendingApprovals(config apiserver.Configuration) {
+	mu.Lock()
+	defer mu.Unlock()
+	groups, err := conf.GetBookingGroupsByState(*config.Id, conf.BookingStatePendingApproval)
+	if err != nil {
+		log.Error("conf", "getting bookings pending approval: %v", err)
+		return
+	}
+	for _, group := range groups {
+		if err := resolvePendingApproval(config, group); err != nil {
+			log.Error("ews", "resolving approval of booking %v: %v", group.ElionaGroupID.Int32, err)
+		}
+	}
+}
+
+func resolvePendingApproval(config apiserver.Configuration, dbGroup appdb.BookingGroup) error {
+	group := syncmodel.BookingGroup{
+		ElionaID:       dbGroup.ElionaGroupID.Int32,
+		ExchangeUID:    dbGroup.ExchangeUID.String,
+		OrganizerEmail: dbGroup.ExchangeOrganizerMailbox.String,
+	}
+	ewsHelper := ews.NewEWSHelper(config, group.OrganizerEmail)
+	responses, err := ewsHelper.GetAttendeeResponses(group.OrganizerEmail, group.ExchangeUID)
+	if err != nil {
+		return fmt.Errorf("getting attendee responses: %v", err)
+	}
+
+	var approvedRooms []string
+	for _, room := range conf.ApprovalRoomUPNs(config) {
+		response, invited := responses[strings.ToLower(room)]
+		if !invited {
+			continue
+		}
+		switch response {
+		case "Accept":
+			approvedRooms = append(approvedRooms, room)
+		case "Decline":
+			if err := ewsHelper.CancelEvent(group); err != nil {
+				return fmt.Errorf("cancelling declined event: %v", err)
+			}
+			bc := booking.NewClient(*config.BookingAppURL, conf.BookingClock(config))
+			if err := bc.Cancel(group.ElionaID, "declined"); err != nil {
+				return fmt.Errorf("cancelling declined booking: %v", err)
+			}
+			log.Debug("ews", "booking %v was declined by delegate of %s; cancelled", group.ElionaID, room)
+			return conf.SetBookingGroupState(group.ExchangeUID, *config.Id, conf.BookingStateDeclined)
+		default:
+			// Delegate has not responded yet.
+			return nil
+		}
+	}
+
+	// Bookings from Eliona have just a single occurrence.
+	occurrences, err := conf.GetBookingOccurrencesByGroupID(dbGroup.ID)
+	if err != nil {
+		return err
+	} else if len(occurrences) != 1 {
+		return fmt.Errorf("booking group %d has %d != 1 occurrences", dbGroup.ID, len(occurrences))
+	}
+	for _, room := range approvedRooms {
+		resourceEventID, err := ewsHelper.FindEventID(room, group.ExchangeUID)
+		if err != nil {
+			return fmt.Errorf("finding event in approved room %s: %v", room, err)
+		}
+		if err := conf.AddRoomBooking(occurrences[0].ID, resourceEventID); err != nil {
+			return err
+		}
+	}
+	log.Debug("ews", "booking %v was approved", group.ElionaID)
+	return conf.SetBookingGroupState(group.ExchangeUID, *config.Id, conf.BookingStateConfirmed)
+}
+
+// reconcilePendingResponses finalizes bookings created without waiting for the
+// rooms to process the invitation once the rooms respond.
+func reconcilePendingResponses(config apiserver.Configuration) {
+	mu.Lock()
+	defer mu.Unlock()
+	groups, err := conf.GetBookingGroupsByState(*config.Id, conf.BookingStatePendingResponse)
+	if err != nil {
+		log.Error("conf", "getting bookings pending response: %v", err)
+		return
+	}
+	for _, group := range groups {
+		if err := resolvePendingResponse(config, group, time.Now()); err != nil {
+			log.Error("ews", "resolving responses to booking %v: %v", group.ElionaGroupID.Int32, err)
+		}
+	}
+}
+
+func resolvePendingResponse(config apiserver.Configuration, dbGroup appdb.BookingGroup, now time.Time) error {
+	group := syncmodel.BookingGroup{
+		ElionaID:       dbGroup.ElionaGroupID.Int32,
+		ExchangeUID:    dbGroup.ExchangeUID.String,
+		OrganizerEmail: dbGroup.ExchangeOrganizerMailbox.String,
+	}
+	ewsHelper := ews.NewEWSHelper(config, group.OrganizerEmail)
+	ewsHelper.SetCorrelationID(ews.NewCorrelationID())
+	responses, err := ewsHelper.GetAttendeeResponses(group.OrganizerEmail, group.ExchangeUID)
+	if err != nil {
+		return fmt.Errorf("getting attendee responses: %v", err)
+	}
+	gracePeriod := time.Duration(common.Val(config.DeclineGracePeriod)) * time.Second
+	accepted, declined, pending := sortResponses(responses, conf.ApprovalRoomUPNs(config), dbGroup.PendingSince.Time, now, gracePeriod)
+	switch responseState(accepted, declined, pending, conf.DeclinePolicy(config)) {
+	case conf.BookingStateDeclined:
+		if err := ewsHelper.CancelEvent(group); err != nil {
+			return fmt.Errorf("cancelling declined event: %v", err)
+		}
+		bc := booking.NewClient(*config.BookingAppURL, conf.BookingClock(config))
+		if err := bc.Cancel(group.ElionaID, "declined"); err != nil {
+			return fmt.Errorf("cancelling declined booking: %v", err)
+		}
+		log.Debug("ews", "booking %v was declined by %v; cancelled", group.ElionaID, declined)
+		return conf.SetBookingGroupState(group.ExchangeUID, *config.Id, conf.BookingStateDeclined)
+	case conf.BookingStatePendingResponse:
+		return nil
+	}
+
+	// Bookings from Eliona have just a single occurrence.
+	occurrences, err := conf.GetBookingOccurrencesByGroupID(dbGroup.ID)
+	if err != nil {
+		return err
+	} else if len(occurrences) != 1 {
+		return fmt.Errorf("booking group %d has %d != 1 occurrences", dbGroup.ID, len(occurrences))
+	}
+	if len(declined) > 0 {
+		occurrence, err := occurrenceOfRooms(config, occurrences[0], append(accepted, declined...))
+		if err != nil {
+			return err
+		}
+		group.Occurrences = []syncmodel.BookingOccurrence{occurrence}
+		if err := dropDeclinedRooms(ewsHelper, group, declined, config); err != nil {
+			return fmt.Errorf("dropping declined rooms %v: %v", declined, err)
+		}
+		log.Debug("ews", "rooms %v declined booking %v; kept in the other rooms", declined, group.ElionaID)
+	}
+	for _, room := range accepted {
+		resourceEventID, err := ewsHelper.FindEventID(room, group.ExchangeUID)
+		if err != nil {
+			return fmt.Errorf("finding event in accepted room %s: %v", room, err)
+		}
+		if err := conf.AddRoomBooking(occurrences[0].ID, resourceEventID); err != nil {
+			return err
+		}
+	}
+	log.Debug("ews", "booking %v was accepted by %v", group.ElionaID, accepted)
+	return conf.SetBookingGroupState(group.ExchangeUID, *config.Id, conf.BookingStateConfirmed)
+}
+
+// occurrenceOfRooms returns the stored occurrence booking the given rooms.
+func occurrenceOfRooms(config apiserver.Configuration, dbOccurrence appdb.BookingOccurrence, rooms []string) (syncmodel.BookingOccurrence, error) {
+	assets, err := conf.GetAssetsByConfig(*config.Id)
+	if err != nil {
+		return syncmodel.BookingOccurrence{}, fmt.Errorf("getting assets: %v", err)
+	}
+	occurrence := syncmodel.BookingOccurrence{ElionaID: dbOccurrence.ElionaBookingID.Int32}
+	for _, ast := range assets {
+		if ast.AssetID.Valid && containsFold(rooms, ast.ProviderID) {
+			occurrence.RoomBookings = append(occurrence.RoomBookings, syncmodel.RoomBooking{AssetID: ast.AssetID.Int32})
+		}
+	}
+	return occurrence, nil
+}
+
+// responseState is the state a booking waiting for the rooms to respond moves
+// to after their responses were sorted.
+func responseState(accepted, declined, pending []string, policy syncmodel.DeclinePolicy) string {
+	keepAccepted := policy == syncmodel.DeclineKeepAccepted
+	if len(declined) > 0 && (!keepAccepted || len(accepted)+len(pending) == 0) {
+		return conf.BookingStateDeclined
+	}
+	if len(pending) > 0 {
+		return conf.BookingStatePendingResponse
+	}
+	return conf.BookingStateConfirmed
 }
 
-func discoverNewAssets(ewsHelper *ews.EWSHelper, config apiserver.Configuration) error {
-	root, err := ewsHelper.GetAssets(config)
-	if err != nil {
-		log.Error("EWS", "getting EWS assets: %v", err)
-		return err
+// sortResponses sorts the invited rooms by their response. Rooms which
+// haven't processed the invitation within the grace period since the booking
+// started waiting are considered declined, except rooms requiring approval,
+// which wait for their delegate.
+func sortResponses(responses map[string]string, approvalRooms []string, pendingSince, now time.Time, gracePeriod time.Duration) (accepted, declined, pending []string) {
+	rooms := make([]string, 0, len(responses))
+	for room := range responses {
+		rooms = append(rooms, room)
 	}
+	sort.Strings(rooms)
+	expired := now.Sub(pendingSince) > gracePeriod
+	for _, room := range rooms {
+		switch responses[room] {
+		case "Accept":
+			accepted = append(accepted, room)
+		case "Decline":
+			declined = append(declined, room)
+		default:
+			if expired && !containsFold(approvalRooms, room) {
+				declined = append(declined, room)
+			} else {
+				pending = append(pending, room)
+			}
+		}
+	}
+	return accepted, declined, pending
+}
 
-	if cnt, err := eliona.CreateAssets(config, &root); err != nil {
-		log.Error("eliona", "creating assets in Eliona: %v", err)
-		return err
-	} else if cnt > 0 {
-		// New assets are present, need to subscribe again to include these.
-		triggerResubscribe()
-
-		// Set all assets as bookable.
-		if err := eliona.UpsertAssetData(config, root.Rooms); err != nil {
-			log.Error("eliona", "upserting asset data: %v", err)
-			return err
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
 		}
 	}
-	return nil
+	return false
 }
 
-func assignElionaIDs(a syncmodel.BookingGroup) (syncmodel.BookingGroup, error) {
-	booking, err := conf.GetBookingGroupByExchangeUID(a.ExchangeUID)
+// assignElionaIDs assigns the IDs of the Eliona booking stored for the group.
+// Copies of a meeting at other times are stored under a distinct UID, which
+// the group takes over if its room items are booked as such a copy.
+func assignElionaIDs(a syncmodel.BookingGroup, lookup bookingLookup) (syncmodel.BookingGroup, error) {
+	uid, err := storedUID(a, lookup)
+	if err != nil {
+		log.Error("conf", "getting booking of the room items of %s: %v", a.ExchangeUID, err)
+		return syncmodel.BookingGroup{}, err
+	}
+	a.ExchangeUID = uid
+	booking, err := lookup.groupByExchangeUID(a.ExchangeUID)
 	if err != nil && !errors.Is(err, conf.ErrNotFound) {
 		log.Error("conf", "getting booking for exchange UID %s: %v", a.ExchangeUID, err)
 		return syncmodel.BookingGroup{}, err
@@ -285,42 +1731,91 @@ func assignElionaIDs(a syncmodel.BookingGroup) (syncmodel.BookingGroup, error) {
 		return a, nil
 	}
 
-	var occurrencesIncluded []int64
-	for i, occurrence := range a.Occurrences {
-		occurrence, err := conf.GetBookingOccurrenceByGroupAndIndex(booking.ID, int32(occurrence.InstanceIndex))
-		if err != nil && !errors.Is(err, conf.ErrNotFound) {
-			log.Error("conf", "getting booking for exchange UID %s: %v", a.ExchangeUID, err)
-			return syncmodel.BookingGroup{}, err
-		} else if errors.Is(err, conf.ErrNotFound) {
-			// Booking is new
-			return a, nil
+	known, err := lookup.occurrencesByGroupID(booking.ID)
+	if err != nil {
+		log.Error("conf", "getting occurrences for exchange UID %s: %v", a.ExchangeUID, err)
+		return syncmodel.BookingGroup{}, err
+	}
+	a = reconcileOccurrences(a, known)
+	a.ElionaID = booking.ElionaGroupID.Int32
+	return a, nil
+}
+
+// storedUID returns the UID the group is stored under, found by its first room
+// item. The items of a copy are all booked together, so one item tells.
+func storedUID(a syncmodel.BookingGroup, lookup bookingLookup) (string, error) {
+	for _, occurrence := range a.Occurrences {
+		for _, roomBooking := range occurrence.RoomBookings {
+			if roomBooking.ExchangeIDInResourceMailbox == "" {
+				continue
+			}
+			stored, err := lookup.groupByExchangeID(roomBooking.ExchangeIDInResourceMailbox)
+			if errors.Is(err, conf.ErrNotFound) {
+				return a.ExchangeUID, nil
+			} else if err != nil {
+				return "", err
+			}
+			if stored.ExchangeUID.Valid && syncmodel.BaseUID(stored.ExchangeUID.String) == a.ExchangeUID {
+				return stored.ExchangeUID.String, nil
+			}
+			return a.ExchangeUID, nil
 		}
-		if occurrence.ElionaBookingID.Valid {
-			a.Occurrences[i].ElionaID = occurrence.ElionaBookingID.Int32
-			occurrencesIncluded = append(occurrencesIncluded, occurrence.ID)
+	}
+	return a.ExchangeUID, nil
+}
+
+// withoutElionaIDs returns the group as not booked in Eliona yet. The
+// occurrences only known from the stored booking, having no room bookings,
+// are dropped along with the IDs.
+func withoutElionaIDs(group syncmodel.BookingGroup) syncmodel.BookingGroup {
+	group.ElionaID = 0
+	var occurrences []syncmodel.BookingOccurrence
+	for _, occurrence := range group.Occurrences {
+		if occurrence.Cancelled && len(occurrence.RoomBookings) == 0 {
+			continue
 		}
+		occurrence.ElionaID = 0
+		occurrences = append(occurrences, occurrence)
 	}
+	group.Occurrences = occurrences
+	return group
+}
 
-	cancelledOccurrences, err := conf.GetBookingOccurrencesByGroupIDWithoutExceptions(booking.ID, occurrencesIncluded)
-	if err != nil {
-		return a, fmt.Errorf("inferring cancelled occurrences: %v", err)
+// reconcileOccurrences assigns Eliona IDs of the known occurrences to the
+// group's occurrences by their instance index. Occurrences unknown so far are
+// booked within the existing group. Known occurrences missing from the group,
+// e.g. after a "this and following occurrences" edit truncated the series, are
+// marked cancelled.
+func reconcileOccurrences(a syncmodel.BookingGroup, known []appdb.BookingOccurrence) syncmodel.BookingGroup {
+	byIndex := make(map[int]appdb.BookingOccurrence)
+	for _, occurrence := range known {
+		byIndex[int(occurrence.ExchangeInstanceIndex)] = occurrence
 	}
-	for _, cancelledOccurrence := range cancelledOccurrences {
-		if cancelledOccurrence.ElionaBookingID.Valid {
-			a.Occurrences = append(a.Occurrences, syncmodel.BookingOccurrence{
-				ElionaID:  cancelledOccurrence.ElionaBookingID.Int32,
-				Cancelled: true,
-			})
+	included := make(map[int64]bool)
+	for i, occurrence := range a.Occurrences {
+		knownOccurrence, ok := byIndex[occurrence.InstanceIndex]
+		if !ok || !knownOccurrence.ElionaBookingID.Valid {
+			continue
 		}
+		a.Occurrences[i].ElionaID = knownOccurrence.ElionaBookingID.Int32
+		included[knownOccurrence.ID] = true
 	}
-
-	a.ElionaID = booking.ElionaGroupID.Int32
-	return a, nil
+	for _, occurrence := range known {
+		if included[occurrence.ID] || !occurrence.ElionaBookingID.Valid {
+			continue
+		}
+		a.Occurrences = append(a.Occurrences, syncmodel.BookingOccurrence{
+			ElionaID:      occurrence.ElionaBookingID.Int32,
+			InstanceIndex: int(occurrence.ExchangeInstanceIndex),
+			Cancelled:     true,
+		})
+	}
+	return a
 }
 
 func listenForBookings(config apiserver.Configuration) {
 	baseURL := *config.BookingAppURL
-	assetIDs, err := conf.GetWatchedAssetIDs()
+	assetIDs, err := conf.GetWatchedAssetIDs(*config.Id)
 	if err != nil {
 		log.Error("conf", "getting list of assetIDs to watch: %v", err)
 		return
@@ -335,29 +1830,52 @@ func listenForBookings(config apiserver.Configuration) {
 		}
 	}()
 
-	bookingsClient := booking.NewClient(baseURL)
+	bookingsClient := booking.NewClient(baseURL, conf.BookingClock(config))
+	bookingsClient.ExportImports = conf.ExportsImports(config)
 	bookingsChan, err := bookingsClient.ListenForBookings(ctx, assetIDs)
 	if err != nil {
 		log.Error("eliona-bookings", "listening for booking changes: %v", err)
 		return
 	}
-outer:
 	for group := range bookingsChan {
-		if len(group.Occurrences) == 1 && group.Occurrences[0].Cancelled {
-			// Typical case, just a single booking. Cancel the RecurringMaster/group.
-			cancelInEWS(group, config)
-			continue
-		}
-		for _, occurrence := range group.Occurrences {
-			if occurrence.Cancelled {
-				// We must handle cancellation differently to cancel just single occurrences.
-				cancelOccurrenceInEWS(group, occurrence, config)
-				continue outer
-			}
+		processing.run(func() {
+			handleBookingEvent(group, config)
+		})
+		booking.Lag.Processed(group.ReceivedAt, time.Now())
+	}
+	if err := bookingsClient.StopErr(); err != nil {
+		// Resubscribing would be rejected again until the configuration changes.
+		log.Error("eliona-bookings", "subscription %d stopped: %v", *config.Id, err)
+		<-ctx.Done()
+	}
+}
+
+func handleBookingEvent(group syncmodel.BookingGroup, config apiserver.Configuration) {
+	group.CorrelationID = ews.NewCorrelationID()
+	log.Debug("main", "handling booking %v as %s", group.ElionaID, group.CorrelationID)
+	if conf.IsReadOnly(config) {
+		// Writes are refused by the EWS helper too, but this way the Eliona
+		// booking is not cancelled as failed either.
+		log.Info("main", "read-only configuration %d; would have synchronized booking %v to Exchange", *config.Id, group.ElionaID)
+		return
+	}
+	if existing, err := conf.GetBookingGroupByElionaID(group.ElionaID); err == nil && existing.State == conf.BookingStateOccupancyOnly {
+		log.Info("main", "booking %v is occupancy only; not synchronizing it to Exchange", group.ElionaID)
+		return
+	}
+	if len(group.Occurrences) == 1 && group.Occurrences[0].Cancelled {
+		// Typical case, just a single booking. Cancel the RecurringMaster/group.
+		cancelInEWS(group, config)
+		return
+	}
+	for _, occurrence := range group.Occurrences {
+		if occurrence.Cancelled {
+			// We must handle cancellation differently to cancel just single occurrences.
+			cancelOccurrenceInEWS(group, occurrence, config)
+			return
 		}
-		bookInEWS(group, config)
-		continue
 	}
+	bookInEWS(group, config)
 }
 
 // cancelInEWS requests cancellation in Exchange, but first enhances the structs
@@ -366,8 +1884,13 @@ func cancelInEWS(group syncmodel.BookingGroup, config apiserver.Configuration) {
 	mu.Lock()
 	defer mu.Unlock()
 	ewsHelper := ews.NewEWSHelper(config, group.OrganizerEmail)
+	ewsHelper.SetCorrelationID(group.CorrelationID)
 	booking, err := conf.GetBookingGroupByElionaID(group.ElionaID)
-	if err != nil {
+	if errors.Is(err, conf.ErrNotFound) {
+		// E.g. created and cancelled within the coalescing window.
+		log.Debug("conf", "booking %v was never synchronized; nothing to cancel", group.ElionaID)
+		return
+	} else if err != nil {
 		log.Error("conf", "getting booking for Eliona ID %v: %v", group.ElionaID, err)
 		return
 	} else if !booking.ExchangeUID.Valid || !booking.ExchangeOrganizerMailbox.Valid {
@@ -376,18 +1899,71 @@ func cancelInEWS(group syncmodel.BookingGroup, config apiserver.Configuration) {
 	}
 	group.ExchangeUID = booking.ExchangeUID.String
 	group.OrganizerEmail = booking.ExchangeOrganizerMailbox.String
+	if policy := conf.CancelPolicy(config); policy != syncmodel.CancelEvent {
+		if freeRoomsInEWS(ewsHelper, group, 0, policy) {
+			return
+		}
+	}
 	if err := ewsHelper.CancelEvent(group); err != nil {
 		log.Error("ews", "cancelling event: %v", err)
 		return
 	}
 }
 
+// freeRoomsInEWS frees the rooms of the cancelled booking while keeping the
+// event for the organizer. A positive instance index frees them in that
+// occurrence only. Returns false if the rooms are unknown and the event
+// should be cancelled instead.
+func freeRoomsInEWS(ewsHelper *ews.EWSHelper, group syncmodel.BookingGroup, instanceIndex int, policy syncmodel.CancelPolicy) bool {
+	var assetIDs []int32
+	for _, occurrence := range group.Occurrences {
+		assetIDs = append(assetIDs, occurrence.GetAssetIDs()...)
+	}
+	rooms, err := conf.GetAssetEmailsByIds(assetIDs)
+	if err != nil {
+		log.Error("conf", "getting asset IDs %v: %v", assetIDs, err)
+		return false
+	}
+	if len(rooms) == 0 {
+		log.Warn("ews", "booking %v has no rooms to free; cancelling the event", group.ElionaID)
+		return false
+	}
+	switch policy {
+	case syncmodel.CancelRemoveRooms:
+		if instanceIndex > 0 {
+			err = ewsHelper.RemoveOccurrenceAttendees(group.ExchangeUID, group.OrganizerEmail, instanceIndex, rooms)
+		} else {
+			err = ewsHelper.UpdateAppointmentAttendees(group.ExchangeUID, group.OrganizerEmail, nil, rooms)
+		}
+		if err != nil {
+			log.Error("ews", "removing rooms %v from event: %v", rooms, err)
+			return true
+		}
+		log.Debug("ews", "removed rooms %v from cancelled booking %v", rooms, group.ElionaID)
+	case syncmodel.CancelDeclineAsRoom:
+		for _, room := range rooms {
+			if instanceIndex > 0 {
+				err = ewsHelper.DeclineOccurrenceAsResource(room, group.ExchangeUID, instanceIndex)
+			} else {
+				err = ewsHelper.DeclineAsResource(room, group.ExchangeUID)
+			}
+			if err != nil {
+				log.Error("ews", "declining event as %v: %v", room, err)
+				continue
+			}
+			log.Debug("ews", "declined cancelled booking %v as %v", group.ElionaID, room)
+		}
+	}
+	return true
+}
+
 // cancelOccurrenceInEWS requests cancellation of whole occurrence in Exchange,
 // but first enhances the structs with Exchange IDs stored in the DB.
 func cancelOccurrenceInEWS(group syncmodel.BookingGroup, occurrence syncmodel.BookingOccurrence, config apiserver.Configuration) {
 	mu.Lock()
 	defer mu.Unlock()
 	ewsHelper := ews.NewEWSHelper(config, group.OrganizerEmail)
+	ewsHelper.SetCorrelationID(group.CorrelationID)
 	booking, err := conf.GetBookingGroupByElionaID(group.ElionaID)
 	if err != nil {
 		log.Error("conf", "getting booking for Eliona ID %v: %v", group.ElionaID, err)
@@ -407,6 +1983,14 @@ func cancelOccurrenceInEWS(group syncmodel.BookingGroup, occurrence syncmodel.Bo
 		log.Error("db", "cancelling occurrence: dbOccurrence %v does not have ExchangeInstanceIndex", booking.ID)
 		return
 	}
+	occurrence.InstanceIndex = int(dbOccurrence.ExchangeInstanceIndex)
+	if policy := conf.CancelPolicy(config); policy != syncmodel.CancelEvent {
+		freed := group
+		freed.Occurrences = []syncmodel.BookingOccurrence{occurrence}
+		if freeRoomsInEWS(ewsHelper, freed, occurrence.InstanceIndex, policy) {
+			return
+		}
+	}
 
 	if err := ewsHelper.CancelOccurrence(group, occurrence); err != nil {
 		log.Error("ews", "cancelling event: %v", err)
@@ -417,39 +2001,386 @@ func cancelOccurrenceInEWS(group syncmodel.BookingGroup, occurrence syncmodel.Bo
 func bookInEWS(group syncmodel.BookingGroup, config apiserver.Configuration) {
 	mu.Lock()
 	defer mu.Unlock()
-	if len(group.Occurrences) != 1 {
-		log.Error("booking", "booking %d != 1 occurences of a group ElionaID %d is not supported", len(group.Occurrences), group.ElionaID)
+	if len(group.Occurrences) == 0 {
+		log.Error("booking", "booking group ElionaID %d has no occurrences", group.ElionaID)
 		return
 	}
 	book := group.Occurrences[0]
+	if !sameRooms(group.Occurrences) {
+		log.Error("booking", "booking group ElionaID %d books different rooms in its occurrences, which is not supported", group.ElionaID)
+		return
+	}
 	assets, err := conf.GetAssetEmailsByIds(book.GetAssetIDs())
 	if err != nil {
 		log.Error("conf", "getting asset IDs %v: %v", book.GetAssetIDs(), err)
 		return
 	}
+	existing, err := conf.GetBookingGroupByElionaID(group.ElionaID)
+	if err != nil && !errors.Is(err, conf.ErrNotFound) {
+		log.Error("conf", "getting booking for Eliona ID %v: %v", group.ElionaID, err)
+		return
+	} else if err == nil && existing.ExchangeUID.Valid && existing.ExchangeOrganizerMailbox.Valid {
+		// The booking is already in Exchange, just the rooms or times might
+		// have changed.
+		updateAttendeesInEWS(existing, assets, group.CorrelationID, config)
+		if len(group.Occurrences) == 1 {
+			rescheduleInEWS(existing, book, group.CorrelationID, config)
+		} else {
+			rescheduleOccurrencesInEWS(existing, group.Occurrences, group.CorrelationID, config)
+		}
+		return
+	}
+	if len(group.Occurrences) > 1 {
+		sort.SliceStable(group.Occurrences, func(i, j int) bool {
+			return group.Occurrences[i].Start.Before(group.Occurrences[j].Start)
+		})
+		if _, ok := recurrenceOf(group.Occurrences, conf.RoomTimeZone(config, assets[0])); !ok {
+			log.Error("booking", "occurrences of booking group ElionaID %d follow no daily, weekly or monthly pattern, which is not supported", group.ElionaID)
+			return
+		}
+	}
 	createAppointment(assets, group, config)
 }
 
+// sameRooms reports whether all occurrences book the same rooms.
+func sameRooms(occurrences []syncmodel.BookingOccurrence) bool {
+	rooms := make(map[int32]bool)
+	for _, assetID := range occurrences[0].GetAssetIDs() {
+		rooms[assetID] = true
+	}
+	for _, occurrence := range occurrences[1:] {
+		assetIDs := occurrence.GetAssetIDs()
+		if len(assetIDs) != len(rooms) {
+			return false
+		}
+		for _, assetID := range assetIDs {
+			if !rooms[assetID] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// recurrenceOf returns the recurrence of the occurrences sorted by their
+// start. They must take equally long and repeat every few days, weeks or
+// months at the same time in UTC.
+func recurrenceOf(occurrences []syncmodel.BookingOccurrence, zone *time.Location) (ews.Recurrence, bool) {
+	if len(occurrences) < 2 {
+		return ews.Recurrence{}, false
+	}
+	first := occurrences[0]
+	for _, occurrence := range occurrences[1:] {
+		if occurrence.End.Sub(occurrence.Start) != first.End.Sub(first.Start) || occurrence.AllDay != first.AllDay {
+			return ews.Recurrence{}, false
+		}
+	}
+	// Occurrences repeat at the same wall-clock time, which shifts in UTC
+	// when daylight saving time begins or ends.
+	start := first.Start.In(zone)
+	repeats := func(next func(i int) time.Time) bool {
+		for i, occurrence := range occurrences {
+			if !occurrence.Start.Equal(next(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	const day = 24 * time.Hour
+	second := occurrences[1].Start.In(zone)
+	wallClock := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	}
+	if gap := wallClock(second).Sub(wallClock(start)); gap > 0 && gap%day == 0 {
+		days := int(gap / day)
+		if repeats(func(i int) time.Time { return start.AddDate(0, 0, i*days) }) {
+			if days%7 == 0 {
+				return ews.Recurrence{Pattern: ews.RecurWeekly, Interval: days / 7, Count: len(occurrences)}, true
+			}
+			return ews.Recurrence{Pattern: ews.RecurDaily, Interval: days, Count: len(occurrences)}, true
+		}
+	}
+	months := (second.Year()-start.Year())*12 + int(second.Month()-start.Month())
+	if months > 0 && start.Day() <= 28 && repeats(func(i int) time.Time { return start.AddDate(0, i*months, 0) }) {
+		return ews.Recurrence{Pattern: ews.RecurMonthly, Interval: months, Count: len(occurrences)}, true
+	}
+	return ews.Recurrence{}, false
+}
+
+// updateAttendeesInEWS adds and removes rooms of an existing event so that they
+// match the rooms booked in Eliona.
+func updateAttendeesInEWS(dbGroup appdb.BookingGroup, assetsEmails []string, correlationID string, config apiserver.Configuration) {
+	organizer := dbGroup.ExchangeOrganizerMailbox.String
+	ewsHelper := ews.NewEWSHelper(config, organizer)
+	ewsHelper.SetCorrelationID(correlationID)
+	responses, err := ewsHelper.GetAttendeeResponses(organizer, dbGroup.ExchangeUID.String)
+	if err != nil {
+		log.Error("ews", "getting attendees of booking %v: %v", dbGroup.ElionaGroupID.Int32, err)
+		return
+	}
+	// Only the rooms of this configuration are managed by it.
+	rooms, err := conf.GetAssetsByConfig(*config.Id)
+	if err != nil {
+		log.Error("conf", "getting assets: %v", err)
+		return
+	}
+	var roomEmails []string
+	for _, room := range rooms {
+		roomEmails = append(roomEmails, room.ProviderID)
+	}
+	add, remove := roomChanges(responses, roomEmails, assetsEmails)
+	if len(add) == 0 && len(remove) == 0 {
+		log.Debug("ews", "rooms of booking %v did not change", dbGroup.ElionaGroupID.Int32)
+		return
+	}
+	// The items of the removed rooms are gone once they process the
+	// cancellation, so they are looked up beforehand.
+	removedItems := make(map[string]string)
+	for _, room := range remove {
+		resourceEventID, err := ewsHelper.FindEventID(room, dbGroup.ExchangeUID.String)
+		if err != nil {
+			log.Warn("ews", "finding event of booking %v in removed room %s: %v", dbGroup.ElionaGroupID.Int32, room, err)
+			continue
+		}
+		removedItems[room] = resourceEventID
+	}
+	if err := ewsHelper.UpdateAppointmentAttendees(dbGroup.ExchangeUID.String, organizer, add, remove); err != nil {
+		log.Error("ews", "updating rooms of booking %v: %v", dbGroup.ElionaGroupID.Int32, err)
+		return
+	}
+	if err := storeRoomChanges(ewsHelper, dbGroup, rooms, add, removedItems); err != nil {
+		log.Error("conf", "storing rooms of booking %v: %v", dbGroup.ElionaGroupID.Int32, err)
+		return
+	}
+	log.Debug("ews", "updated rooms of booking %v: added %v, removed %v", dbGroup.ElionaGroupID.Int32, add, remove)
+}
+
+// storeRoomChanges stores the room bookings of the rooms added to the event and
+// forgets those of the removed rooms, given by their items. Added rooms which
+// haven't processed the invitation yet are stored by the sync of the room,
+// as are the rooms of recurring events, booked per occurrence.
+func storeRoomChanges(ewsHelper *ews.EWSHelper, dbGroup appdb.BookingGroup, rooms []appdb.Asset, added []string, removedItems map[string]string) error {
+	for _, room := range rooms {
+		if exchangeID, ok := removedItems[strings.ToLower(room.ProviderID)]; ok {
+			if err := conf.RemoveRoomBooking(room.ID, exchangeID); err != nil {
+				return err
+			}
+		}
+	}
+	occurrences, err := conf.GetBookingOccurrencesByGroupID(dbGroup.ID)
+	if err != nil {
+		return err
+	} else if len(occurrences) != 1 {
+		log.Debug("ews", "rooms added to recurring booking %v are stored by their sync", dbGroup.ElionaGroupID.Int32)
+		return nil
+	}
+	for _, room := range added {
+		resourceEventID, err := ewsHelper.FindEventID(room, dbGroup.ExchangeUID.String)
+		if err != nil {
+			log.Debug("ews", "event of booking %v not found in added room %s yet, stored by its sync: %v", dbGroup.ElionaGroupID.Int32, room, err)
+			continue
+		}
+		if err := conf.AddRoomBooking(occurrences[0].ID, resourceEventID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roomChanges returns the rooms to invite to and to remove from an existing
+// event so that it books the wanted rooms. Other attendees than rooms are not
+// managed by Eliona.
+func roomChanges(responses map[string]string, roomEmails, wanted []string) (add, remove []string) {
+	isRoom := make(map[string]bool)
+	for _, room := range roomEmails {
+		isRoom[strings.ToLower(room)] = true
+	}
+	isWanted := make(map[string]bool)
+	for _, email := range wanted {
+		isWanted[strings.ToLower(email)] = true
+		if _, invited := responses[strings.ToLower(email)]; !invited {
+			add = append(add, email)
+		}
+	}
+	for attendee := range responses {
+		if isRoom[attendee] && !isWanted[attendee] {
+			remove = append(remove, attendee)
+		}
+	}
+	sort.Strings(remove)
+	return add, remove
+}
+
+// rescheduleInEWS moves an existing event to the times booked in Eliona.
+func rescheduleInEWS(dbGroup appdb.BookingGroup, book syncmodel.BookingOccurrence, correlationID string, config apiserver.Configuration) {
+	organizer := dbGroup.ExchangeOrganizerMailbox.String
+	ewsHelper := ews.NewEWSHelper(config, organizer)
+	ewsHelper.SetCorrelationID(correlationID)
+	if err := ewsHelper.UpdateAppointment(dbGroup.ExchangeUID.String, organizer, book.Start, book.End); err != nil {
+		log.Error("ews", "moving booking %v to %v - %v: %v", dbGroup.ElionaGroupID.Int32, book.Start, book.End, err)
+		return
+	}
+	log.Debug("ews", "booking %v takes place %v - %v", dbGroup.ElionaGroupID.Int32, book.Start, book.End)
+}
+
+// rescheduleOccurrencesInEWS moves the occurrences of an existing recurring
+// event to the times booked in Eliona, each by its instance index. Occurrences
+// unknown in Exchange, e.g. added to the series in Eliona, are not created.
+func rescheduleOccurrencesInEWS(dbGroup appdb.BookingGroup, occurrences []syncmodel.BookingOccurrence, correlationID string, config apiserver.Configuration) {
+	organizer := dbGroup.ExchangeOrganizerMailbox.String
+	ewsHelper := ews.NewEWSHelper(config, organizer)
+	ewsHelper.SetCorrelationID(correlationID)
+	for _, occurrence := range occurrences {
+		if occurrence.Cancelled {
+			continue
+		}
+		dbOccurrence, err := conf.GetBookingOccurrenceByElionaID(occurrence.ElionaID)
+		if errors.Is(err, conf.ErrNotFound) || (err == nil && dbOccurrence.ExchangeInstanceIndex == 0) {
+			log.Warn("booking", "occurrence %d of booking %v is not in Exchange; adding occurrences to a series is not supported", occurrence.ElionaID, dbGroup.ElionaGroupID.Int32)
+			continue
+		} else if err != nil {
+			log.Error("conf", "getting occurrence %d of booking %v: %v", occurrence.ElionaID, dbGroup.ElionaGroupID.Int32, err)
+			return
+		}
+		if err := ewsHelper.UpdateOccurrence(dbGroup.ExchangeUID.String, organizer, int(dbOccurrence.ExchangeInstanceIndex), occurrence.Start, occurrence.End); err != nil {
+			log.Error("ews", "moving occurrence %d of booking %v to %v - %v: %v", dbOccurrence.ExchangeInstanceIndex, dbGroup.ElionaGroupID.Int32, occurrence.Start, occurrence.End, err)
+			continue
+		}
+		log.Debug("ews", "occurrence %d of booking %v takes place %v - %v", dbOccurrence.ExchangeInstanceIndex, dbGroup.ElionaGroupID.Int32, occurrence.Start, occurrence.End)
+	}
+}
+
+// reminderMinutes returns the reminder of events organized by the organizer.
+// The service user is nobody to remind.
+func reminderMinutes(organizer string, config apiserver.Configuration) *int {
+	if strings.EqualFold(organizer, conf.WriteServiceUserUPN(config)) {
+		noReminder := 0
+		return &noReminder
+	}
+	return conf.ReminderMinutes(config)
+}
+
+// roomsConflict reports whether any of the rooms is already booked during the
+// occurrence. Rooms that cannot be checked are left for Exchange to decide.
+func roomsConflict(ewsHelper *ews.EWSHelper, roomEmails []string, occurrences []syncmodel.BookingOccurrence, policy syncmodel.OverlapPolicy) bool {
+	for _, occurrence := range occurrences {
+		for _, room := range roomEmails {
+			conflicting, err := ewsHelper.FindConflictingEvents(room, occurrence.Start, occurrence.End, policy)
+			if err != nil {
+				log.Warn("ews", "checking conflicts in room %v: %v", room, err)
+				continue
+			}
+			if len(conflicting) > 0 {
+				log.Debug("ews", "room %v has %d conflicting events", room, len(conflicting))
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// freeBusyStatus returns the status requested by the booking, falling back to
+// the configured default if it is missing or invalid.
+func freeBusyStatus(group syncmodel.BookingGroup, config apiserver.Configuration) string {
+	if group.FreeBusyStatus == "" {
+		return common.Val(config.FreeBusyStatus)
+	}
+	if _, err := syncmodel.ParseFreeBusyStatus(group.FreeBusyStatus); err != nil {
+		log.Warn("booking", "booking %v: %v; using default", group.ElionaID, err)
+		return common.Val(config.FreeBusyStatus)
+	}
+	return group.FreeBusyStatus
+}
+
+// appointmentLocation names the rooms by their roomNames, falling back to
+// their emails. The rooms' mailboxes are invited as attendees regardless.
+func appointmentLocation(roomEmails []string, config apiserver.Configuration) string {
+	names := make([]string, len(roomEmails))
+	for i, email := range roomEmails {
+		names[i] = syncmodel.RoomDisplayName(email, email, config.RoomNames, nil)
+	}
+	return strings.Join(names, "; ")
+}
+
+// organizerAndSubject returns who books the group's appointment and its
+// subject. Bookings without organizer are booked by the write service user,
+// as Exchange rejects appointments without one (422), and their fallback
+// subject names that user.
+func organizerAndSubject(assetsEmails []string, group syncmodel.BookingGroup, config apiserver.Configuration) (organizer, subject string) {
+	organizer = group.OrganizerEmail
+	if organizer == "" {
+		organizer = conf.WriteServiceUserUPN(config)
+	}
+	book := group.Occurrences[0]
+	subject = conf.SubjectFallback(config).Subject(group.Subject, assetsEmails[0], organizer, book.Start, book.End)
+	return organizer, subject
+}
+
 func createAppointment(assetsEmails []string, group syncmodel.BookingGroup, config apiserver.Configuration) {
 	book := group.Occurrences[0]
-	if group.OrganizerEmail == "" {
-		// Otherwise we get a 422 error
-		group.OrganizerEmail = *config.ServiceUserUPN
-	}
+	var subject string
+	group.OrganizerEmail, subject = organizerAndSubject(assetsEmails, group, config)
 	// We want to book on behalf of the organizer, thus we need to create a helper for each booking.
 	ewsHelper := ews.NewEWSHelper(config, group.OrganizerEmail)
+	ewsHelper.SetCorrelationID(group.CorrelationID)
+	if roomsConflict(ewsHelper, assetsEmails, group.Occurrences, conf.OverlapPolicy(config)) {
+		bc := booking.NewClient(*config.BookingAppURL, conf.BookingClock(config))
+		if err := bc.Cancel(group.ElionaID, "conflict"); err != nil {
+			log.Error("booking", "cancelling conflicting appointment: %v", err)
+			return
+		}
+		log.Debug("ews", "booking for %v conflicts with existing events; cancelled", group.OrganizerEmail)
+		return
+	}
 	app := ews.Appointment{
+		ElionaID:  group.ElionaID,
 		Organizer: group.OrganizerEmail,
-		Subject:   "Eliona booking",
+		Subject:   subject,
 		Start:     book.Start,
 		End:       book.End,
-		Location:  assetsEmails[0],
-		Attendees: assetsEmails,
+		AllDay:    book.AllDay,
+		TimeZone:  conf.RoomTimeZone(config, assetsEmails[0]),
+		Location:  appointmentLocation(assetsEmails, config),
+		Resources: assetsEmails,
+
+		ApprovalRooms:      conf.ApprovalRoomUPNs(config),
+		ProcessingTimeout:  conf.InvitationProcessingTimeout(config),
+		DeclineGracePeriod: time.Duration(common.Val(config.DeclineGracePeriod)) * time.Second,
+		FreeBusyStatus:     freeBusyStatus(group, config),
+		DeferResponses:     common.Val(config.ResponsePollInterval) > 0,
+
+		MaxAttendeesPerRequest: int(common.Val(config.MaxAttendeesPerRequest)),
+		ReminderMinutes:        reminderMinutes(group.OrganizerEmail, config),
+		Categories:             conf.AppointmentCategories(config),
+		IsOnlineMeeting:        common.Val(config.OnlineMeetings),
+	}
+	if recurrence, ok := recurrenceOf(group.Occurrences, app.TimeZone); ok {
+		app.Recurrence = &recurrence
 	}
-	exchangeUID, resourceEventIDs, err := ewsHelper.CreateAppointment(app)
+	exchangeUID, results, err := ewsHelper.CreateAppointment(app)
 	group.ExchangeUID = exchangeUID
+	declined := ews.DeclinedRooms(results)
+	if errors.Is(err, ews.ErrDeclined) && conf.DeclinePolicy(config) == syncmodel.DeclineKeepAccepted && len(declined) < len(results) {
+		if err := dropDeclinedRooms(ewsHelper, group, declined, config); err != nil {
+			log.Error("ews", "dropping rooms %v declining booking %v: %v", declined, group.ElionaID, err)
+			return
+		}
+		log.Debug("ews", "rooms %v declined booking %v; kept in the other rooms", declined, group.ElionaID)
+		err = nil
+		for _, result := range results {
+			if result.PendingResponse {
+				err = ews.ErrPendingResponse
+				break
+			}
+			if result.PendingApproval {
+				err = ews.ErrPendingApproval
+			}
+		}
+	}
+	pendingApproval := errors.Is(err, ews.ErrPendingApproval)
+	pendingResponse := errors.Is(err, ews.ErrPendingResponse)
 	if errors.Is(err, ews.ErrDeclined) {
-		bc := booking.NewClient(*config.BookingAppURL)
+		bc := booking.NewClient(*config.BookingAppURL, conf.BookingClock(config))
 		if err := ewsHelper.CancelEvent(group); err != nil {
 			log.Error("ews", "cancelling conflicting event: %v", err)
 			return
@@ -459,15 +2390,16 @@ func createAppointment(assetsEmails []string, group syncmodel.BookingGroup, conf
 			return
 		}
 		log.Debug("ews", "booking for %v was conflicting; cancelled", group.OrganizerEmail)
-	} else if errors.Is(err, ews.ErrNonExistentMailbox) && group.OrganizerEmail != *config.ServiceUserUPN {
+		return
+	} else if errors.Is(err, ews.ErrNonExistentMailbox) && group.OrganizerEmail != conf.WriteServiceUserUPN(config) {
 		log.Debug("ews", "booking for %v will be booked by a service user", group.OrganizerEmail)
-		group.OrganizerEmail = *config.ServiceUserUPN
+		group.OrganizerEmail = conf.WriteServiceUserUPN(config)
 		createAppointment(assetsEmails, group, config)
 		return
-	} else if err != nil {
+	} else if err != nil && !pendingApproval && !pendingResponse {
 		log.Error("ews", "creating appointment %v: %v", group.ElionaID, err)
 		log.Debug("ews", "cancelling booking %v", group.ElionaID)
-		bc := booking.NewClient(*config.BookingAppURL)
+		bc := booking.NewClient(*config.BookingAppURL, conf.BookingClock(config))
 		if err := bc.Cancel(group.ElionaID, "error"); err != nil {
 			log.Error("booking", "cancelling errored appointment: %v", err)
 			return
@@ -475,31 +2407,115 @@ func createAppointment(assetsEmails []string, group syncmodel.BookingGroup, conf
 		return
 	}
 	log.Debug("ews", "created a booking for %v", group.OrganizerEmail)
+	if subjects, ok := conf.ResourceSubject(config); ok {
+		setResourceSubjects(ewsHelper, ews.AcceptedRooms(results), group, subjects)
+	}
 
 	// For upserting, we can clear the roombookings, as we are interested just
 	// in the resource event IDs.
-	book.RoomBookings = []syncmodel.RoomBooking{}
-	for _, resourceEventID := range resourceEventIDs {
-		book.RoomBookings = append(book.RoomBookings, syncmodel.RoomBooking{
-			ExchangeIDInResourceMailbox: resourceEventID,
-		})
+	for i := range group.Occurrences {
+		occurrence := &group.Occurrences[i]
+		occurrence.RoomBookings = []syncmodel.RoomBooking{}
+		for _, result := range ews.AcceptedRooms(results) {
+			resourceEventID := result.ResourceEventID
+			if app.Recurrence != nil {
+				occurrence.InstanceIndex = i + 1
+				if i >= len(result.OccurrenceEventIDs) || result.OccurrenceEventIDs[i] == "" {
+					continue
+				}
+				resourceEventID = result.OccurrenceEventIDs[i]
+			}
+			occurrence.RoomBookings = append(occurrence.RoomBookings, syncmodel.RoomBooking{
+				ExchangeIDInResourceMailbox: resourceEventID,
+			})
+		}
+		occurrence.Location = app.Location
 	}
-	group.Occurrences[0] = book
 
 	if err := conf.UpsertBooking(group); err != nil {
 		log.Error("conf", "upserting newly created booking: %v", err)
 		return
 	}
+	if pendingResponse {
+		// Resolved by reconcilePendingResponses.
+		log.Debug("ews", "booking for %v is waiting for the rooms to respond", group.OrganizerEmail)
+		if err := conf.SetBookingGroupPendingResponse(group.ExchangeUID, *config.Id, time.Now()); err != nil {
+			log.Error("conf", "marking booking as pending response: %v", err)
+			return
+		}
+	} else if pendingApproval {
+		// Resolved by reconcilePendingApprovals on subsequent syncs.
+		log.Debug("ews", "booking for %v is waiting for approval", group.OrganizerEmail)
+		if err := conf.SetBookingGroupState(group.ExchangeUID, *config.Id, conf.BookingStatePendingApproval); err != nil {
+			log.Error("conf", "marking booking as pending approval: %v", err)
+			return
+		}
+	}
+}
+
+// setResourceSubjects replaces the subject of the event in the calendars of the
+// rooms, keeping the organizer's subject for the organizer.
+func setResourceSubjects(ewsHelper *ews.EWSHelper, rooms []ews.RoomResult, group syncmodel.BookingGroup, subjects syncmodel.SubjectFallback) {
+	book := group.Occurrences[0]
+	for _, room := range rooms {
+		subject := subjects.Compose(room.Room, group.OrganizerEmail, book.Start, book.End)
+		if err := ewsHelper.SetResourceSubject(room.Room, group.ExchangeUID, subject); err != nil {
+			log.Error("ews", "setting subject of booking %v in %v: %v", group.ElionaID, room.Room, err)
+		}
+	}
+}
+
+// dropDeclinedRooms removes the declined rooms from both the Exchange event and
+// the Eliona booking, keeping the booking in the rooms which accepted it.
+func dropDeclinedRooms(ewsHelper *ews.EWSHelper, group syncmodel.BookingGroup, declined []string, config apiserver.Configuration) error {
+	if err := ewsHelper.UpdateAppointmentAttendees(group.ExchangeUID, group.OrganizerEmail, nil, declined); err != nil {
+		return fmt.Errorf("removing declined rooms from event: %v", err)
+	}
+	assets, err := conf.GetAssetsByConfig(*config.Id)
+	if err != nil {
+		return fmt.Errorf("getting assets: %v", err)
+	}
+	isDeclined := make(map[string]bool)
+	for _, room := range declined {
+		isDeclined[strings.ToLower(room)] = true
+	}
+	bc := booking.NewClient(*config.BookingAppURL, conf.BookingClock(config))
+	for _, book := range group.Occurrences {
+		var declinedIDs []int32
+		for _, ast := range assets {
+			if !ast.AssetID.Valid || !isDeclined[strings.ToLower(ast.ProviderID)] {
+				continue
+			}
+			for _, assetID := range book.GetAssetIDs() {
+				if assetID == ast.AssetID.Int32 {
+					declinedIDs = append(declinedIDs, assetID)
+				}
+			}
+		}
+		if err := bc.RemoveRooms(book.ElionaID, declinedIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metricsHandler serves the app's metrics in the Prometheus text format.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	booking.MetricsHandler(w, r)
+	ews.WriteAddressCacheMetrics(w)
+	writeUnknownDeleteMetrics(w)
 }
 
 // listenApi starts the API server and listen for requests
 func listenApi() {
+	router := apiserver.NewRouter(
+		apiserver.NewConfigurationAPIController(apiservices.NewConfigurationAPIService()),
+		apiserver.NewMaintenanceAPIController(apiservices.NewMaintenanceAPIService(syncNow, schedules.status)),
+		apiserver.NewVersionAPIController(apiservices.NewVersionAPIService()),
+	)
+	router.HandleFunc("/metrics", metricsHandler).Methods(http.MethodGet)
 	err := http.ListenAndServe(":"+common.Getenv("API_SERVER_PORT", "3000"),
 		frontend.NewEnvironmentHandler(
-			utilshttp.NewCORSEnabledHandler(
-				apiserver.NewRouter(
-					apiserver.NewConfigurationAPIController(apiservices.NewConfigurationAPIService()),
-					apiserver.NewVersionAPIController(apiservices.NewVersionAPIService()),
-				))))
+			utilshttp.NewCORSEnabledHandler(router)))
 	log.Fatal("main", "API server: %v", err)
 }
@@ -0,0 +1,1009 @@
+package main
+
+import (
+	"errors"
+	"ews/apiserver"
+	"ews/appdb"
+	"ews/conf"
+	"ews/ews"
+	"ews/model"
+	syncmodel "ews/model/sync"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"github.com/volatiletech/null/v8"
+)
+
+func TestSyncInPagesResumesAfterInterruption(t *testing.T) {
+	assets := []appdb.Asset{
+		{ID: 1, AssetID: null.Int32From(101), ProviderID: "room1@example.com"},
+		{ID: 2, AssetID: null.Int32From(102), ProviderID: "room2@example.com"},
+	}
+	// Room 1 has three pages of changes, room 2 just one.
+	pageCount := map[int64]int{1: 3, 2: 1}
+	// Persisted sync state is the number of pages already processed.
+	persisted := map[int64]int{}
+	processed := map[int64][]int{}
+
+	fetch := func(ast appdb.Asset) (roomPage, error) {
+		state := persisted[ast.ID]
+		return roomPage{
+			asset:     ast,
+			syncState: strconv.Itoa(state + 1),
+			last:      state+1 >= pageCount[ast.ID],
+		}, nil
+	}
+	checkpoint := func(page roomPage) error {
+		state, err := strconv.Atoi(page.syncState)
+		persisted[page.asset.ID] = state
+		return err
+	}
+	errInterrupted := errors.New("interrupted")
+	rounds := 0
+	process := func(pages []roomPage) error {
+		rounds++
+		if rounds == 2 {
+			return errInterrupted
+		}
+		for _, page := range pages {
+			state, _ := strconv.Atoi(page.syncState)
+			processed[page.asset.ID] = append(processed[page.asset.ID], state)
+		}
+		return nil
+	}
+
+	if err := syncInPages(assets, fetch, process, checkpoint); !errors.Is(err, errInterrupted) {
+		t.Fatalf("expected interruption, got %v", err)
+	}
+	if persisted[1] != 1 || persisted[2] != 1 {
+		t.Fatalf("expected the first round to be checkpointed, got %v", persisted)
+	}
+
+	if err := syncInPages(assets, fetch, process, checkpoint); err != nil {
+		t.Fatalf("resuming sync: %v", err)
+	}
+	if persisted[1] != 3 {
+		t.Errorf("expected room 1 to be fully synced, got state %d", persisted[1])
+	}
+	want := []int{1, 2, 3}
+	if len(processed[1]) != len(want) {
+		t.Fatalf("expected room 1 pages %v to be processed exactly once, got %v", want, processed[1])
+	}
+	for i := range want {
+		if processed[1][i] != want[i] {
+			t.Errorf("expected room 1 pages %v to be processed exactly once, got %v", want, processed[1])
+		}
+	}
+}
+
+func TestRoomsToSyncAreScopedToConfig(t *testing.T) {
+	assets := []appdb.Asset{
+		{ID: 1, ConfigurationID: 1, AssetID: null.Int32From(101), ProviderID: "room1@first.example.com"},
+		{ID: 2, ConfigurationID: 2, AssetID: null.Int32From(102), ProviderID: "room1@second.example.com"},
+		{ID: 3, ConfigurationID: 1, ProviderID: "unassigned@first.example.com"},
+		{ID: 4, ConfigurationID: 1, AssetID: null.Int32From(104)},
+		{ID: 5, ConfigurationID: 2, AssetID: null.Int32From(105), ProviderID: "room2@second.example.com"},
+	}
+	for configID, want := range map[int64][]int64{1: {1}, 2: {2, 5}} {
+		rooms := roomsToSync(assets, configID, syncmodel.MissingRoomEmailSkip, nil)
+		if len(rooms) != len(want) {
+			t.Fatalf("config %d: expected rooms %v, got %v", configID, want, rooms)
+		}
+		for i, room := range rooms {
+			if room.ID != want[i] {
+				t.Errorf("config %d: expected rooms %v, got room %d at %d", configID, want, room.ID, i)
+			}
+		}
+	}
+}
+
+func TestRoomsToSyncWithoutProviderID(t *testing.T) {
+	assets := []appdb.Asset{
+		{ID: 1, ConfigurationID: 1, AssetID: null.Int32From(101), GlobalAssetID: "ews_root"},
+		{ID: 2, ConfigurationID: 1, AssetID: null.Int32From(102), GlobalAssetID: "ews_room_room1@example.com"},
+		{ID: 3, ConfigurationID: 1, AssetID: null.Int32From(103), GlobalAssetID: "ews_room_name_Room 3"},
+		{ID: 4, ConfigurationID: 1, AssetID: null.Int32From(104), GlobalAssetID: "ews_room_room4@example.com", ProviderID: "room4@example.com"},
+	}
+	var repaired []int64
+	repair := func(ast appdb.Asset) (appdb.Asset, error) {
+		repaired = append(repaired, ast.ID)
+		email, ok := conf.RoomEmailFromGAI(ast.GlobalAssetID)
+		if !ok {
+			return ast, errors.New("no email")
+		}
+		ast.ProviderID = email
+		return ast, nil
+	}
+
+	rooms := roomsToSync(assets, 1, syncmodel.MissingRoomEmailSkip, repair)
+	if len(rooms) != 1 || rooms[0].ID != 4 {
+		t.Errorf("expected only room 4 to be synchronized, got %v", rooms)
+	}
+	if len(repaired) != 0 {
+		t.Errorf("expected no repairs when skipping, got %v", repaired)
+	}
+
+	rooms = roomsToSync(assets, 1, syncmodel.MissingRoomEmailRepair, repair)
+	if len(rooms) != 2 || rooms[0].ID != 2 || rooms[0].ProviderID != "room1@example.com" || rooms[1].ID != 4 {
+		t.Errorf("expected repaired room 2 and room 4 to be synchronized, got %v", rooms)
+	}
+	if len(repaired) != 2 || repaired[0] != 2 || repaired[1] != 3 {
+		t.Errorf("expected rooms 2 and 3 to be repaired but not the root, got %v", repaired)
+	}
+}
+
+func TestPartitionDiscovered(t *testing.T) {
+	assets := []appdb.Asset{
+		{ID: 1, AssetID: null.Int32From(100), GlobalAssetID: "ews_root"},
+		{ID: 2, AssetID: null.Int32From(101), GlobalAssetID: "ews_room_room1@example.com", ProviderID: "room1@example.com"},
+		{ID: 3, AssetID: null.Int32From(102), GlobalAssetID: "ews_room_room2@example.com", ProviderID: "room2@example.com"},
+	}
+	rooms := []model.Room{{Email: "Room1@example.com", Name: "Room 1"}, {Email: "room3@example.com", Name: "Room 3"}}
+	discovered, missing := partitionDiscovered(assets, rooms)
+	if len(discovered) != 1 || discovered[0].ID != 2 {
+		t.Errorf("expected room 1 to be discovered, got %+v", discovered)
+	}
+	if len(missing) != 1 || missing[0].ID != 3 {
+		t.Errorf("expected room 2 to be missing, got %+v", missing)
+	}
+}
+
+func TestRenamedRooms(t *testing.T) {
+	assets := []appdb.Asset{
+		{ID: 1, AssetID: null.Int32From(100), GlobalAssetID: "ews_root", Name: "ews"},
+		{ID: 2, AssetID: null.Int32From(101), ProviderID: "room1@example.com", Name: "Meeting room 1"},
+		{ID: 3, AssetID: null.Int32From(102), ProviderID: "room2@example.com", Name: "Meeting room 2"},
+	}
+	rooms := []model.Room{
+		{Email: "Room1@example.com", Name: "Boardroom"},
+		{Email: "room2@example.com", Name: "Meeting room 2"},
+	}
+	renames := renamedRooms(assets, rooms)
+	if len(renames) != 1 {
+		t.Fatalf("expected one renamed room, got %+v", renames)
+	}
+	if from, to := renames[0].asset.Name, renames[0].room.GetName(); from != "Meeting room 1" || to != "Boardroom" {
+		t.Errorf("expected meeting room 1 renamed to boardroom, got %q to %q", from, to)
+	}
+}
+
+func TestChangedData(t *testing.T) {
+	unchanged := model.Room{Email: "room2@example.com", Department: "Facilities"}
+	digest, err := unchanged.DataDigest()
+	if err != nil {
+		t.Fatalf("digesting room data: %v", err)
+	}
+	assets := []appdb.Asset{
+		{ID: 1, AssetID: null.Int32From(101), ProviderID: "room1@example.com", DataDigest: digest},
+		{ID: 2, AssetID: null.Int32From(102), ProviderID: "room2@example.com", DataDigest: digest},
+		{ID: 3, AssetID: null.Int32From(103), ProviderID: "room3@example.com"},
+	}
+	rooms := []model.Room{
+		{Email: "room1@example.com", Capacity: common.Ptr[int32](12)},
+		unchanged,
+		{Email: "room3@example.com", PhoneNumber: "+41 44 000 00 03"},
+	}
+	changes, err := changedData(assets, rooms)
+	if err != nil {
+		t.Fatalf("comparing room data: %v", err)
+	}
+	if len(changes) != 2 || changes[0].asset.ID != 1 || changes[1].asset.ID != 3 {
+		t.Errorf("expected data of rooms 1 and 3 to change, got %+v", changes)
+	}
+
+	// A failed lookup must not blank the contact data.
+	rooms[2].ContactUnknown = true
+	changes, err = changedData(assets, rooms)
+	if err != nil {
+		t.Fatalf("comparing room data: %v", err)
+	}
+	if len(changes) != 1 || changes[0].asset.ID != 1 {
+		t.Errorf("expected room 3 with unknown contact to be left as is, got %+v", changes)
+	}
+}
+
+// A weekly series edited in Outlook from the third occurrence on as "this and
+// following occurrences": the original series is truncated to two occurrences
+// and the rest continues as a new series.
+func TestThisAndFollowingEdit(t *testing.T) {
+	week := 7 * 24 * time.Hour
+	first := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	occurrence := func(index int, start time.Time) syncmodel.BookingOccurrence {
+		return syncmodel.BookingOccurrence{InstanceIndex: index, Start: start, End: start.Add(time.Hour)}
+	}
+	var known []appdb.BookingOccurrence
+	for i := 1; i <= 5; i++ {
+		known = append(known, appdb.BookingOccurrence{
+			ID:                    int64(i),
+			ExchangeInstanceIndex: int32(i),
+			ElionaBookingID:       null.Int32From(int32(100 + i)),
+		})
+	}
+
+	truncated := reconcileOccurrences(syncmodel.BookingGroup{
+		ExchangeUID:    "original",
+		OrganizerEmail: "organizer@example.com",
+		Occurrences: []syncmodel.BookingOccurrence{
+			occurrence(1, first),
+			occurrence(2, first.Add(week)),
+		},
+	}, known)
+	continuation := syncmodel.BookingGroup{
+		ExchangeUID:    "continuation",
+		OrganizerEmail: "organizer@example.com",
+		Occurrences: []syncmodel.BookingOccurrence{
+			occurrence(1, first.Add(2*week).Add(time.Hour)),
+			occurrence(2, first.Add(3*week).Add(time.Hour)),
+			occurrence(3, first.Add(4*week).Add(time.Hour)),
+		},
+	}
+
+	// Past occurrences keep their Eliona bookings.
+	for i, want := range []int32{101, 102} {
+		if o := truncated.Occurrences[i]; o.ElionaID != want || o.Cancelled {
+			t.Errorf("occurrence %d: expected kept booking %d, got %+v", i+1, want, o)
+		}
+	}
+	// The rest of the original series is cancelled under its own indices.
+	if len(truncated.Occurrences) != 5 {
+		t.Fatalf("expected 2 kept and 3 cancelled occurrences, got %+v", truncated.Occurrences)
+	}
+	for i, o := range truncated.Occurrences[2:] {
+		if !o.Cancelled || o.InstanceIndex != i+3 || o.ElionaID != int32(103+i) {
+			t.Errorf("expected occurrence %d to be cancelled, got %+v", i+3, o)
+		}
+	}
+	if !syncmodel.IsSeriesSplit(truncated, continuation) {
+		t.Error("expected the split to be detected")
+	}
+}
+
+func TestReconcileOccurrencesKeepsGroupWithNewOccurrences(t *testing.T) {
+	known := []appdb.BookingOccurrence{
+		{ID: 1, ExchangeInstanceIndex: 1, ElionaBookingID: null.Int32From(101)},
+	}
+	group := reconcileOccurrences(syncmodel.BookingGroup{
+		Occurrences: []syncmodel.BookingOccurrence{{InstanceIndex: 1}, {InstanceIndex: 2}},
+	}, known)
+	if len(group.Occurrences) != 2 || group.Occurrences[0].ElionaID != 101 || group.Occurrences[1].ElionaID != 0 {
+		t.Errorf("expected the known occurrence to be kept and the new one to be booked, got %+v", group.Occurrences)
+	}
+}
+
+func TestSplitImports(t *testing.T) {
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	group := func(uid string, elionaID int32, createdByApp bool, offset time.Duration) syncmodel.BookingGroup {
+		return syncmodel.BookingGroup{
+			ExchangeUID:  uid,
+			ElionaID:     elionaID,
+			CreatedByApp: createdByApp,
+			Occurrences:  []syncmodel.BookingOccurrence{{Start: start.Add(offset), End: start.Add(offset + time.Hour)}},
+		}
+	}
+	groups := []syncmodel.BookingGroup{
+		group("known", 1, false, 0),
+		group("created-by-app", 0, true, 0),
+		group("overlapping", 0, false, 30*time.Minute),
+		group("free", 0, false, 3*time.Hour),
+	}
+	elionaBookings := []syncmodel.Interval{{Start: start, End: start.Add(time.Hour)}}
+
+	uids := func(groups []syncmodel.BookingGroup) (uids []string) {
+		for _, g := range groups {
+			uids = append(uids, g.ExchangeUID)
+		}
+		return uids
+	}
+	imported, conflicting := splitImports(groups, elionaBookings, syncmodel.ImportOverlapImport, syncmodel.OverlapExclusive)
+	if got := uids(imported); len(got) != 3 || got[1] != "overlapping" || len(conflicting) != 0 {
+		t.Errorf("expected overlapping booking to be imported anyway, got %v, %v", got, uids(conflicting))
+	}
+	imported, conflicting = splitImports(groups, elionaBookings, syncmodel.ImportOverlapFlag, syncmodel.OverlapExclusive)
+	if got := uids(imported); len(got) != 2 || got[0] != "known" || got[1] != "free" {
+		t.Errorf("expected known and free bookings to be imported, got %v", got)
+	}
+	if got := uids(conflicting); len(got) != 1 || got[0] != "overlapping" {
+		t.Errorf("expected overlapping booking to be flagged, got %v", got)
+	}
+}
+
+func TestSortResponses(t *testing.T) {
+	pendingSince := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	gracePeriod := 30 * time.Second
+	approvalRooms := []string{"Boardroom@example.com"}
+	for name, tc := range map[string]struct {
+		polls                       []map[string]string
+		after                       time.Duration
+		accepted, declined, pending int
+	}{
+		"late accept": {
+			polls: []map[string]string{
+				{"room1@example.com": "NoResponseReceived"},
+				{"room1@example.com": "Accept"},
+			},
+			after:    20 * time.Second,
+			accepted: 1,
+		},
+		"late decline by delegate": {
+			polls: []map[string]string{
+				{"boardroom@example.com": "NoResponseReceived", "room1@example.com": "Accept"},
+				{"boardroom@example.com": "Decline", "room1@example.com": "Accept"},
+			},
+			after:    2 * time.Hour,
+			accepted: 1,
+			declined: 1,
+		},
+		"no response within grace period": {
+			polls: []map[string]string{
+				{"room1@example.com": "NoResponseReceived"},
+			},
+			after:    time.Minute,
+			declined: 1,
+		},
+		"delegate still deciding": {
+			polls: []map[string]string{
+				{"boardroom@example.com": "NoResponseReceived"},
+			},
+			after:   2 * time.Hour,
+			pending: 1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Until the last poll, the booking keeps waiting.
+			for _, responses := range tc.polls[:len(tc.polls)-1] {
+				_, declined, pending := sortResponses(responses, approvalRooms, pendingSince, pendingSince.Add(10*time.Second), gracePeriod)
+				if len(declined) != 0 || len(pending) == 0 {
+					t.Fatalf("expected the booking to keep waiting, got declined %v, pending %v", declined, pending)
+				}
+			}
+			accepted, declined, pending := sortResponses(tc.polls[len(tc.polls)-1], approvalRooms, pendingSince, pendingSince.Add(tc.after), gracePeriod)
+			if len(accepted) != tc.accepted || len(declined) != tc.declined || len(pending) != tc.pending {
+				t.Errorf("expected %d accepted, %d declined, %d pending; got %v, %v, %v", tc.accepted, tc.declined, tc.pending, accepted, declined, pending)
+			}
+		})
+	}
+}
+
+func TestPendingResponseStateTransitions(t *testing.T) {
+	pendingSince := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	gracePeriod := 5 * time.Minute
+	type poll struct {
+		after     time.Duration
+		responses map[string]string
+		state     string
+	}
+	for name, tc := range map[string]struct {
+		policy syncmodel.DeclinePolicy
+		polls  []poll
+	}{
+		"accepted after polling started": {
+			policy: syncmodel.DeclineCancelAll,
+			polls: []poll{
+				{time.Minute, map[string]string{"room1@example.com": "NoResponseReceived"}, conf.BookingStatePendingResponse},
+				{2 * time.Minute, map[string]string{"room1@example.com": "Accept"}, conf.BookingStateConfirmed},
+			},
+		},
+		"declined after polling started": {
+			policy: syncmodel.DeclineCancelAll,
+			polls: []poll{
+				{time.Minute, map[string]string{"room1@example.com": "Accept", "room2@example.com": "NoResponseReceived"}, conf.BookingStatePendingResponse},
+				{2 * time.Minute, map[string]string{"room1@example.com": "Accept", "room2@example.com": "Decline"}, conf.BookingStateDeclined},
+			},
+		},
+		"declined by one room, kept in the other": {
+			policy: syncmodel.DeclineKeepAccepted,
+			polls: []poll{
+				{time.Minute, map[string]string{"room1@example.com": "NoResponseReceived", "room2@example.com": "NoResponseReceived"}, conf.BookingStatePendingResponse},
+				{2 * time.Minute, map[string]string{"room1@example.com": "Accept", "room2@example.com": "NoResponseReceived"}, conf.BookingStatePendingResponse},
+				{3 * time.Minute, map[string]string{"room1@example.com": "Accept", "room2@example.com": "Decline"}, conf.BookingStateConfirmed},
+			},
+		},
+		"declined by every room": {
+			policy: syncmodel.DeclineKeepAccepted,
+			polls: []poll{
+				{time.Minute, map[string]string{"room1@example.com": "NoResponseReceived"}, conf.BookingStatePendingResponse},
+				{2 * time.Minute, map[string]string{"room1@example.com": "Decline"}, conf.BookingStateDeclined},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for i, p := range tc.polls {
+				accepted, declined, pending := sortResponses(p.responses, nil, pendingSince, pendingSince.Add(p.after), gracePeriod)
+				if state := responseState(accepted, declined, pending, tc.policy); state != p.state {
+					t.Errorf("poll %d: expected state %s, got %s", i+1, p.state, state)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkerPoolBoundsConcurrency(t *testing.T) {
+	pool := newWorkerPool(2)
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.run(func() {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mu.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+			})
+		}()
+	}
+	wg.Wait()
+	if maxRunning > 2 {
+		t.Errorf("expected at most 2 functions running at once, got %d", maxRunning)
+	}
+}
+
+func TestMergeRoomGroupKeepsConflictingTimesApart(t *testing.T) {
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	group := func(assetID int32, start time.Time) syncmodel.BookingGroup {
+		return syncmodel.BookingGroup{
+			ExchangeUID: "uid",
+			Occurrences: []syncmodel.BookingOccurrence{{
+				Start:        start,
+				End:          start.Add(time.Hour),
+				RoomBookings: []syncmodel.RoomBooking{{AssetID: assetID}},
+			}},
+		}
+	}
+
+	toBook := make(map[string]syncmodel.BookingGroup)
+	mergeRoomGroup(toBook, group(1, start))
+	mergeRoomGroup(toBook, group(2, start.Add(2*time.Hour)))
+	mergeRoomGroup(toBook, group(3, start))
+	mergeRoomGroup(toBook, group(4, start.Add(2*time.Hour)))
+
+	if len(toBook) != 2 {
+		t.Fatalf("got %d groups, want 2: %v", len(toBook), toBook)
+	}
+	distinct := syncmodel.DistinctUID("uid", start.Add(2*time.Hour))
+	for key, want := range map[string][]int32{"uid": {1, 3}, distinct: {2, 4}} {
+		got := toBook[key].Occurrences[0].GetAssetIDs()
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("assets of %s: got %v, want %v", key, got, want)
+		}
+		// The group is stored under its UID.
+		if uid := toBook[key].ExchangeUID; uid != key {
+			t.Errorf("group under %s has UID %s", key, uid)
+		}
+	}
+	if !toBook[distinct].Occurrences[0].Start.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("%s starts at %v", distinct, toBook[distinct].Occurrences[0].Start)
+	}
+}
+
+func TestCopyAtOtherTimesKeepsItsGroup(t *testing.T) {
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	found := func(assetID int32, item string, start time.Time) syncmodel.BookingGroup {
+		return syncmodel.BookingGroup{
+			ExchangeUID: "uid",
+			Occurrences: []syncmodel.BookingOccurrence{{
+				Start:        start,
+				End:          start.Add(time.Hour),
+				RoomBookings: []syncmodel.RoomBooking{{AssetID: assetID, ExchangeIDInResourceMailbox: item}},
+			}},
+		}
+	}
+	distinct := syncmodel.DistinctUID("uid", start.Add(2*time.Hour))
+	// The meeting is booked, its copy in room 2 is not yet.
+	groups := map[string]appdb.BookingGroup{"uid": {ID: 1, ExchangeUID: null.StringFrom("uid"), ElionaGroupID: null.Int32From(100)}}
+	items := map[string]string{"room1-item": "uid"}
+	occurrences := map[int64][]appdb.BookingOccurrence{1: {{ID: 11, BookingGroupID: 1, ElionaBookingID: null.Int32From(101)}}}
+	lookup := bookingLookup{
+		groupByExchangeID: func(exchangeID string) (appdb.BookingGroup, error) {
+			if uid, ok := items[exchangeID]; ok {
+				return groups[uid], nil
+			}
+			return appdb.BookingGroup{}, conf.ErrNotFound
+		},
+		groupByExchangeUID: func(exchangeUID string) (appdb.BookingGroup, error) {
+			if group, ok := groups[exchangeUID]; ok {
+				return group, nil
+			}
+			return appdb.BookingGroup{}, conf.ErrNotFound
+		},
+		occurrencesByGroupID: func(groupID int64) ([]appdb.BookingOccurrence, error) {
+			return occurrences[groupID], nil
+		},
+	}
+	syncRooms := func(found ...syncmodel.BookingGroup) map[string]syncmodel.BookingGroup {
+		toBook := make(map[string]syncmodel.BookingGroup)
+		for _, group := range found {
+			group, err := assignElionaIDs(group, lookup)
+			if err != nil {
+				t.Fatalf("assigning Eliona IDs: %v", err)
+			}
+			mergeRoomGroup(toBook, group)
+		}
+		return toBook
+	}
+
+	toBook := syncRooms(found(1, "room1-item", start), found(2, "room2-item", start.Add(2*time.Hour)))
+	if toBook["uid"].ElionaID != 100 || toBook["uid"].Occurrences[0].ElionaID != 101 {
+		t.Errorf("expected the meeting to keep its booking, got %+v", toBook["uid"])
+	}
+	copied := toBook[distinct]
+	if copied.ElionaID != 0 || len(copied.Occurrences) != 1 || copied.Occurrences[0].ElionaID != 0 {
+		t.Fatalf("expected the copy to be booked anew, got %+v", copied)
+	}
+
+	// The copy is booked, and changes later on in its room only.
+	groups[distinct] = appdb.BookingGroup{ID: 2, ExchangeUID: null.StringFrom(distinct), ElionaGroupID: null.Int32From(200)}
+	items["room2-item"] = distinct
+	occurrences[2] = []appdb.BookingOccurrence{{ID: 21, BookingGroupID: 2, ElionaBookingID: null.Int32From(201)}}
+	toBook = syncRooms(found(2, "room2-item", start.Add(2*time.Hour)))
+	copied, ok := toBook[distinct]
+	if len(toBook) != 1 || !ok {
+		t.Fatalf("expected the copy to be booked under its own UID, got %v", toBook)
+	}
+	if copied.ElionaID != 200 || len(copied.Occurrences) != 1 || copied.Occurrences[0].ElionaID != 201 {
+		t.Errorf("expected the copy to keep its own booking, got %+v", copied)
+	}
+}
+
+func TestDropSelfOrganized(t *testing.T) {
+	groups := []syncmodel.BookingGroup{
+		{ExchangeUID: "meeting", OrganizerEmail: "john.doe@example.com"},
+		{ExchangeUID: "self", OrganizerEmail: "room1@example.com", SelfOrganized: true},
+	}
+	kept := dropSelfOrganized(groups, "room1@example.com", syncmodel.SelfOrganizedSkip)
+	if len(kept) != 1 || kept[0].ExchangeUID != "meeting" {
+		t.Errorf("expected only the meeting to be kept, got %+v", kept)
+	}
+	kept = dropSelfOrganized(groups, "room1@example.com", syncmodel.SelfOrganizedUnattributed)
+	if len(kept) != 2 {
+		t.Errorf("expected both events to be kept, got %+v", kept)
+	}
+}
+
+func TestApplyBlockPolicy(t *testing.T) {
+	groups := []syncmodel.BookingGroup{
+		{ExchangeUID: "meeting", OrganizerEmail: "john.doe@example.com"},
+		{ExchangeUID: "block", OrganizerEmail: "john.doe@example.com", Block: true},
+	}
+	kept := applyBlockPolicy(groups, "room1@example.com", syncmodel.BlockSkip)
+	if len(kept) != 1 || kept[0].ExchangeUID != "meeting" {
+		t.Errorf("expected only the meeting to be kept, got %+v", kept)
+	}
+	kept = applyBlockPolicy(groups, "room1@example.com", syncmodel.BlockOccupancyOnly)
+	if len(kept) != 2 || kept[0].OccupancyOnly || !kept[1].OccupancyOnly {
+		t.Errorf("expected only the block to be occupancy only, got %+v", kept)
+	}
+	kept = applyBlockPolicy(groups, "room1@example.com", syncmodel.BlockImport)
+	if len(kept) != 2 || kept[1].OccupancyOnly {
+		t.Errorf("expected both events to be imported, got %+v", kept)
+	}
+}
+
+func TestHandleUnknownDeletes(t *testing.T) {
+	before := unknownDeletes.Load()
+	page := roomPage{asset: appdb.Asset{ProviderID: "room1@example.com"}, syncState: "state"}
+	handleUnknownDeletes(&page, nil, syncmodel.UnknownDeleteResync)
+	if page.syncState != "state" || unknownDeletes.Load() != before {
+		t.Errorf("expected known deletes to change nothing, got sync state %q", page.syncState)
+	}
+	handleUnknownDeletes(&page, []string{"item1", "item2"}, syncmodel.UnknownDeleteLog)
+	if page.syncState != "state" {
+		t.Errorf("expected the sync state to be kept when logging, got %q", page.syncState)
+	}
+	if n := unknownDeletes.Load() - before; n != 2 {
+		t.Errorf("expected 2 unknown deletes counted, got %d", n)
+	}
+	handleUnknownDeletes(&page, []string{"item3"}, syncmodel.UnknownDeleteResync)
+	if page.syncState != "" {
+		t.Errorf("expected the room to be resynchronized from scratch, got sync state %q", page.syncState)
+	}
+	var metrics strings.Builder
+	writeUnknownDeleteMetrics(&metrics)
+	if want := fmt.Sprintf("ews_unknown_deletes_total %d\n", before+3); !strings.Contains(metrics.String(), want) {
+		t.Errorf("expected %q in metrics, got:\n%s", want, metrics.String())
+	}
+}
+
+func TestRoomItems(t *testing.T) {
+	series := syncmodel.BookingGroup{ExchangeUID: "series", MasterExchangeID: "master1"}
+	block := syncmodel.BookingGroup{ExchangeUID: "block", Occurrences: []syncmodel.BookingOccurrence{
+		{RoomBookings: []syncmodel.RoomBooking{{ExchangeIDInResourceMailbox: "block1"}}},
+	}}
+	single := syncmodel.BookingGroup{ExchangeUID: "single"}
+	items := roomItems(1, []syncmodel.BookingGroup{series, block, single}, []syncmodel.BookingGroup{series, single})
+	want := []appdb.RoomItem{
+		{AssetID: 1, ExchangeID: "master1", ExchangeUID: "series"},
+		{AssetID: 1, ExchangeID: "block1"},
+	}
+	if fmt.Sprint(items) != fmt.Sprint(want) {
+		t.Errorf("expected the series master and the dropped block remembered, got %+v", items)
+	}
+}
+
+func TestCancelledBookingsOfDeletedSeries(t *testing.T) {
+	occurrences := []appdb.BookingOccurrence{
+		{ID: 11, BookingGroupID: 1, ElionaBookingID: null.Int32From(101)},
+		{ID: 12, BookingGroupID: 1, ElionaBookingID: null.Int32From(102)},
+	}
+	lookup := bookingLookup{
+		groupByExchangeID: func(exchangeID string) (appdb.BookingGroup, error) {
+			// Only occurrences are stored as room bookings.
+			return appdb.BookingGroup{}, conf.ErrNotFound
+		},
+		roomItem: func(exchangeID string) (appdb.RoomItem, error) {
+			switch exchangeID {
+			case "master1":
+				return appdb.RoomItem{ExchangeID: exchangeID, ExchangeUID: "series"}, nil
+			case "block1":
+				return appdb.RoomItem{ExchangeID: exchangeID}, nil
+			}
+			return appdb.RoomItem{}, conf.ErrNotFound
+		},
+		groupByExchangeUID: func(exchangeUID string) (appdb.BookingGroup, error) {
+			if exchangeUID != "series" {
+				t.Errorf("expected the series to be resolved by its UID, got %q", exchangeUID)
+			}
+			return appdb.BookingGroup{ID: 1, ElionaGroupID: null.Int32From(100)}, nil
+		},
+		occurrencesByGroupID: func(groupID int64) ([]appdb.BookingOccurrence, error) {
+			return occurrences, nil
+		},
+		occurrenceByExchangeID: func(exchangeID string) (appdb.BookingOccurrence, error) {
+			t.Errorf("expected the whole series to be cancelled, not occurrence %s", exchangeID)
+			return appdb.BookingOccurrence{}, conf.ErrNotFound
+		},
+	}
+	cancelled, unknown, err := cancelledBookings(5, []string{"master1", "block1", "other"}, lookup)
+	if err != nil {
+		t.Fatalf("matching deletes: %v", err)
+	}
+	if len(cancelled) != 2 || cancelled[0].BookingOccurrence.ElionaID != 101 || cancelled[1].BookingOccurrence.ElionaID != 102 || cancelled[0].AssetID != 5 {
+		t.Errorf("expected both occurrences of the series cancelled in the room, got %+v", cancelled)
+	}
+	if fmt.Sprint(unknown) != "[other]" {
+		t.Errorf("expected only the item never seen to be unknown, got %v", unknown)
+	}
+}
+
+// fakeChangeFeed reports events per room; rooms missing have lost their
+// subscription.
+type fakeChangeFeed struct {
+	changed    map[string]bool
+	subscribed []string
+}
+
+func (f *fakeChangeFeed) Subscribe(roomEmail string) (string, string, error) {
+	f.subscribed = append(f.subscribed, roomEmail)
+	return "new-" + roomEmail, "wm0", nil
+}
+
+func (f *fakeChangeFeed) GetEvents(roomEmail, subscriptionID, watermark string) (bool, string, error) {
+	changed, ok := f.changed[roomEmail]
+	if !ok {
+		return false, "", ews.ErrSubscriptionLost
+	}
+	return changed, watermark + "+", nil
+}
+
+func TestRoomsWithChanges(t *testing.T) {
+	rooms := []appdb.Asset{
+		{ID: 1, ProviderID: "unchanged@example.com", SyncState: "s", SubscriptionID: "sub1", Watermark: "wm1"},
+		{ID: 2, ProviderID: "changed@example.com", SyncState: "s", SubscriptionID: "sub2", Watermark: "wm2"},
+		{ID: 3, ProviderID: "expired@example.com", SyncState: "s", SubscriptionID: "sub3", Watermark: "wm3"},
+		{ID: 4, ProviderID: "new@example.com", SyncState: "s"},
+	}
+	feed := &fakeChangeFeed{changed: map[string]bool{"unchanged@example.com": false, "changed@example.com": true}}
+	persisted := make(map[int64]pullSubscription)
+	changed, subscriptions := roomsWithChanges(feed, rooms, func(assetID int64, subscription pullSubscription) error {
+		persisted[assetID] = subscription
+		return nil
+	})
+
+	var ids []int64
+	for _, ast := range changed {
+		ids = append(ids, ast.ID)
+	}
+	if fmt.Sprint(ids) != "[2 3 4]" {
+		t.Errorf("expected rooms [2 3 4] to be synchronized, got %v", ids)
+	}
+	if want := (pullSubscription{id: "sub1", watermark: "wm1+"}); len(persisted) != 1 || persisted[1] != want {
+		t.Errorf("expected only the unchanged room's watermark persisted right away, got %+v", persisted)
+	}
+	want := map[int64]pullSubscription{
+		2: {id: "sub2", watermark: "wm2+"},
+		3: {id: "new-expired@example.com", watermark: "wm0"},
+		4: {id: "new-new@example.com", watermark: "wm0"},
+	}
+	if fmt.Sprint(subscriptions) != fmt.Sprint(want) {
+		t.Errorf("expected subscriptions %v to be persisted after syncing, got %v", want, subscriptions)
+	}
+}
+
+func TestAppointmentLocationPrefersRoomNames(t *testing.T) {
+	config := apiserver.Configuration{RoomNames: map[string]string{"Room1@example.com": "Board room"}}
+	if got := appointmentLocation([]string{"room1@example.com", "room2@example.com"}, config); got != "Board room; room2@example.com" {
+		t.Errorf("expected named rooms with the others' emails, got %q", got)
+	}
+}
+
+func TestSyncSchedulesDetectOverrun(t *testing.T) {
+	s := &syncSchedules{configs: make(map[int64]syncSchedule)}
+	interval := 20 * time.Millisecond
+	slow := func() error {
+		time.Sleep(2 * interval)
+		return nil
+	}
+	wait, err := s.run(1, interval, slow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait != overrunPause {
+		t.Errorf("expected a short pause after an overrunning sync, got %v", wait)
+	}
+	status := s.status(1)
+	if !status.Overrunning || status.Overruns != 1 || status.LastDuration < (2*interval).Seconds() {
+		t.Errorf("expected the sync to be reported overrunning, got %+v", status)
+	}
+
+	wait, err = s.run(1, interval, func() error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 || wait > interval {
+		t.Errorf("expected to wait for the rest of the interval, got %v", wait)
+	}
+	if status := s.status(1); status.Overrunning || status.Overruns != 1 {
+		t.Errorf("expected the sync to keep up again, got %+v", status)
+	}
+	if status := s.status(2); status.Overrunning {
+		t.Errorf("expected other configurations not to be overrunning, got %+v", status)
+	}
+}
+
+func TestRefreshIntervalMinimum(t *testing.T) {
+	config := apiserver.Configuration{RefreshInterval: 1}
+	if got := refreshInterval(config, 10*time.Second); got != 10*time.Second {
+		t.Errorf("expected the interval raised to the minimum, got %v", got)
+	}
+	config.RefreshInterval = 60
+	if got := refreshInterval(config, 10*time.Second); got != time.Minute {
+		t.Errorf("expected the configured interval, got %v", got)
+	}
+}
+
+func TestCredentialWarningFor(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	healthy := ews.CredentialState{Healthy: true}
+	config := apiserver.Configuration{}
+	if w := credentialWarningFor(config, healthy, now); w != noCredentialWarning {
+		t.Errorf("expected no warning without expiry, got %v", w)
+	}
+	config.ClientSecretExpiresAt = common.Ptr(now.Add(30 * 24 * time.Hour))
+	if w := credentialWarningFor(config, healthy, now); w != noCredentialWarning {
+		t.Errorf("expected no warning a month ahead of expiry, got %v", w)
+	}
+	config.ClientSecretExpiresAt = common.Ptr(now.Add(7 * 24 * time.Hour))
+	if w := credentialWarningFor(config, healthy, now); w != secretExpiring {
+		t.Errorf("expected expiry warning a week ahead, got %v", w)
+	}
+	rejected := ews.CredentialState{Reason: "rejected"}
+	if w := credentialWarningFor(config, rejected, now); w != credentialsRejected {
+		t.Errorf("expected rejected credentials to take precedence, got %v", w)
+	}
+}
+
+func TestFindDoubleBooking(t *testing.T) {
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	// The organizer booked the room in Eliona and, moments later, in Outlook.
+	tagged := []ews.TaggedEvent{{
+		ExchangeUID:    "created-for-eliona",
+		OrganizerEmail: "John.Doe@example.com",
+		ElionaID:       7,
+		Start:          start,
+		End:            start.Add(time.Hour),
+	}}
+	booked := func(organizer string, start time.Time) syncmodel.BookingGroup {
+		return syncmodel.BookingGroup{
+			ExchangeUID:    "booked-in-outlook",
+			OrganizerEmail: organizer,
+			Occurrences:    []syncmodel.BookingOccurrence{{Start: start, End: start.Add(time.Hour)}},
+		}
+	}
+
+	event, found := findDoubleBooking(booked("john.doe@example.com", start.Add(15*time.Minute)), tagged, syncmodel.OverlapExclusive)
+	if !found || event.ExchangeUID != "created-for-eliona" {
+		t.Errorf("expected the double booking to be found, got %+v, %t", event, found)
+	}
+
+	known := booked("john.doe@example.com", start)
+	known.ElionaID = 7
+	createdByApp := booked("john.doe@example.com", start)
+	createdByApp.CreatedByApp = true
+	recurring := booked("john.doe@example.com", start)
+	recurring.Occurrences[0].InstanceIndex = 1
+	for name, group := range map[string]syncmodel.BookingGroup{
+		"other organizer":  booked("jane.roe@example.com", start),
+		"back to back":     booked("john.doe@example.com", start.Add(time.Hour)),
+		"known booking":    known,
+		"created by app":   createdByApp,
+		"recurring event":  recurring,
+		"same event again": {ExchangeUID: "created-for-eliona", OrganizerEmail: "john.doe@example.com", Occurrences: booked("", start).Occurrences},
+	} {
+		if event, found := findDoubleBooking(group, tagged, syncmodel.OverlapExclusive); found {
+			t.Errorf("%s: expected no double booking, got %+v", name, event)
+		}
+	}
+}
+
+func TestConflictFlagAfterMerge(t *testing.T) {
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	tagged := []ews.TaggedEvent{{ExchangeUID: "created-for-eliona", OrganizerEmail: "john.doe@example.com", Start: start, End: start.Add(time.Hour)}}
+	elionaBookings := []syncmodel.Interval{{Start: start, End: start.Add(time.Hour)}}
+	booked := syncmodel.BookingGroup{
+		ExchangeUID:    "booked-in-outlook",
+		OrganizerEmail: "john.doe@example.com",
+		Occurrences:    []syncmodel.BookingOccurrence{{Start: start, End: start.Add(time.Hour)}},
+	}
+	flag := func(page roomPage) (conflict, update bool) {
+		var conflicting []syncmodel.BookingGroup
+		page.new, conflicting = splitImports(page.new, elionaBookings, syncmodel.ImportOverlapFlag, syncmodel.OverlapExclusive)
+		return conflictFlag(page, conflicting, syncmodel.ImportOverlapFlag)
+	}
+
+	if conflict, update := flag(roomPage{new: []syncmodel.BookingGroup{booked}}); !conflict || !update {
+		t.Errorf("expected the unmerged double booking to raise the flag, got %t, %t", conflict, update)
+	}
+
+	// Merging adopts the event for the Eliona booking.
+	if _, found := findDoubleBooking(booked, tagged, syncmodel.OverlapExclusive); !found {
+		t.Fatal("expected the double booking to be found")
+	}
+	merged := booked
+	merged.ElionaID = 7
+	merged.Occurrences = []syncmodel.BookingOccurrence{{ElionaID: 8, Start: start, End: start.Add(time.Hour)}}
+	page := roomPage{new: []syncmodel.BookingGroup{merged}}
+	if conflict, update := flag(page); conflict || !update {
+		t.Errorf("expected the merged booking to clear the flag, got %t, %t", conflict, update)
+	}
+	imported, _ := splitImports(page.new, elionaBookings, syncmodel.ImportOverlapFlag, syncmodel.OverlapExclusive)
+	if len(imported) != 1 || imported[0].ElionaID != 7 {
+		t.Errorf("expected the merged booking to update the Eliona booking, got %+v", imported)
+	}
+
+	if _, update := flag(roomPage{}); update {
+		t.Error("expected the flag to be left as it is without changes in the room")
+	}
+	if _, update := conflictFlag(roomPage{new: []syncmodel.BookingGroup{booked}}, nil, syncmodel.ImportOverlapImport); update {
+		t.Error("expected the flag to be left alone when conflicts are imported")
+	}
+}
+
+func TestRoomsInOnlineMeetings(t *testing.T) {
+	now := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	occurrence := func(assetID int32, start time.Time, online bool) syncmodel.BookingOccurrence {
+		return syncmodel.BookingOccurrence{
+			Start:        start,
+			End:          start.Add(time.Hour),
+			IsOnline:     online,
+			RoomBookings: []syncmodel.RoomBooking{{AssetID: assetID}},
+		}
+	}
+	online := roomsInOnlineMeetings([]syncmodel.BookingOccurrence{
+		occurrence(1, now.Add(-30*time.Minute), true),
+		occurrence(2, now.Add(-30*time.Minute), false),
+		// Ended just now.
+		occurrence(3, now.Add(-time.Hour), true),
+		occurrence(4, now.Add(time.Hour), true),
+	}, now, syncmodel.OverlapExclusive)
+	if len(online) != 1 || !online[1] {
+		t.Errorf("expected only room 1 in an online meeting, got %v", online)
+	}
+}
+
+func TestOrganizerAndSubject(t *testing.T) {
+	config := apiserver.Configuration{
+		ServiceUserUPN:      common.Ptr("reader@example.com"),
+		WriteServiceUserUPN: common.Ptr("writer@example.com"),
+	}
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	group := syncmodel.BookingGroup{Occurrences: []syncmodel.BookingOccurrence{{Start: start, End: start.Add(time.Hour)}}}
+	organizer, subject := organizerAndSubject([]string{"room1@example.com"}, group, config)
+	if organizer != "writer@example.com" || subject != "room1@example.com booked by writer@example.com" {
+		t.Errorf("expected booking without organizer to be booked and labelled by the write service user, got %q: %q", organizer, subject)
+	}
+
+	group.OrganizerEmail, group.Subject = "alice@example.com", "Standup"
+	organizer, subject = organizerAndSubject([]string{"room1@example.com"}, group, config)
+	if organizer != "alice@example.com" || subject != "Standup" {
+		t.Errorf("expected the organizer's own booking, got %q: %q", organizer, subject)
+	}
+}
+
+func TestRecurrenceOf(t *testing.T) {
+	occurrences := func(starts ...time.Time) []syncmodel.BookingOccurrence {
+		var result []syncmodel.BookingOccurrence
+		for _, start := range starts {
+			result = append(result, syncmodel.BookingOccurrence{Start: start, End: start.Add(time.Hour)})
+		}
+		return result
+	}
+	monday := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The last Monday before daylight saving time ends, 9:00 in Zurich.
+	beforeDST := time.Date(2024, 10, 21, 9, 0, 0, 0, zurich)
+	for _, tc := range []struct {
+		name        string
+		occurrences []syncmodel.BookingOccurrence
+		zone        *time.Location
+		want        string
+	}{
+		{"daily", occurrences(monday, monday.AddDate(0, 0, 1), monday.AddDate(0, 0, 2)), time.UTC, "{daily 1 3}"},
+		{"every other week", occurrences(monday, monday.AddDate(0, 0, 14), monday.AddDate(0, 0, 28)), time.UTC, "{weekly 2 3}"},
+		{"monthly", occurrences(monday, monday.AddDate(0, 1, 0), monday.AddDate(0, 2, 0)), time.UTC, "{monthly 1 3}"},
+		{"uneven", occurrences(monday, monday.AddDate(0, 0, 1), monday.AddDate(0, 0, 3)), time.UTC, "none"},
+		{"different times", occurrences(monday, monday.AddDate(0, 0, 1).Add(time.Hour)), time.UTC, "none"},
+		{"across daylight saving time", occurrences(beforeDST, beforeDST.AddDate(0, 0, 7), beforeDST.AddDate(0, 0, 14)), zurich, "{weekly 1 3}"},
+		{"across daylight saving time in UTC", occurrences(beforeDST, beforeDST.AddDate(0, 0, 7), beforeDST.AddDate(0, 0, 14)), time.UTC, "none"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := "none"
+			if recurrence, ok := recurrenceOf(tc.occurrences, tc.zone); ok {
+				got = fmt.Sprintf("{%s %d %d}", recurrence.Pattern, recurrence.Interval, recurrence.Count)
+			}
+			if got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestReminderMinutes(t *testing.T) {
+	minutes := int32(10)
+	config := apiserver.Configuration{
+		ServiceUserUPN:  common.Ptr("service@example.com"),
+		ReminderMinutes: &minutes,
+	}
+	if got := reminderMinutes("organizer@example.com", config); got == nil || *got != 10 {
+		t.Errorf("expected the organizer to be reminded 10 minutes ahead, got %v", got)
+	}
+	if got := reminderMinutes("Service@example.com", config); got == nil || *got != 0 {
+		t.Errorf("expected the service user not to be reminded, got %v", got)
+	}
+	config.ReminderMinutes = nil
+	if got := reminderMinutes("organizer@example.com", config); got != nil {
+		t.Errorf("expected the mailbox default, got %v", *got)
+	}
+}
+
+func TestRoomChanges(t *testing.T) {
+	responses := map[string]string{
+		"jane.doe@example.com": "Accept",
+		"room1@example.com":    "Accept",
+		"room2@example.com":    "Tentative",
+	}
+	rooms := []string{"Room1@example.com", "room2@example.com", "room3@example.com"}
+	add, remove := roomChanges(responses, rooms, []string{"Room1@example.com", "room3@example.com"})
+	if fmt.Sprint(add) != "[room3@example.com]" || fmt.Sprint(remove) != "[room2@example.com]" {
+		t.Errorf("expected room3 to be added and room2 removed, got %v and %v", add, remove)
+	}
+	add, remove = roomChanges(responses, rooms, []string{"room1@example.com", "room2@example.com"})
+	if len(add) != 0 || len(remove) != 0 {
+		t.Errorf("expected no changes to the invited rooms, got %v and %v", add, remove)
+	}
+}
@@ -31,6 +31,13 @@ type Asset struct {
 	ProviderID      string     `boil:"provider_id" json:"provider_id" toml:"provider_id" yaml:"provider_id"`
 	AssetID         null.Int32 `boil:"asset_id" json:"asset_id,omitempty" toml:"asset_id" yaml:"asset_id,omitempty"`
 	SyncState       string     `boil:"sync_state" json:"sync_state" toml:"sync_state" yaml:"sync_state"`
+	SyncedAt        null.Time  `boil:"synced_at" json:"synced_at,omitempty" toml:"synced_at" yaml:"synced_at,omitempty"`
+	SubscriptionID  string     `boil:"subscription_id" json:"subscription_id" toml:"subscription_id" yaml:"subscription_id"`
+	Watermark       string     `boil:"watermark" json:"watermark" toml:"watermark" yaml:"watermark"`
+	ExpandedUntil   null.Time  `boil:"expanded_until" json:"expanded_until,omitempty" toml:"expanded_until" yaml:"expanded_until,omitempty"`
+	ArchivedAt      null.Time  `boil:"archived_at" json:"archived_at,omitempty" toml:"archived_at" yaml:"archived_at,omitempty"`
+	Name            string     `boil:"name" json:"name" toml:"name" yaml:"name"`
+	DataDigest      string     `boil:"data_digest" json:"data_digest" toml:"data_digest" yaml:"data_digest"`
 
 	R *assetR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L assetL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -44,6 +51,13 @@ var AssetColumns = struct {
 	ProviderID      string
 	AssetID         string
 	SyncState       string
+	SyncedAt        string
+	SubscriptionID  string
+	Watermark       string
+	ExpandedUntil   string
+	ArchivedAt      string
+	Name            string
+	DataDigest      string
 }{
 	ID:              "id",
 	ConfigurationID: "configuration_id",
@@ -52,6 +66,13 @@ var AssetColumns = struct {
 	ProviderID:      "provider_id",
 	AssetID:         "asset_id",
 	SyncState:       "sync_state",
+	SyncedAt:        "synced_at",
+	SubscriptionID:  "subscription_id",
+	Watermark:       "watermark",
+	ExpandedUntil:   "expanded_until",
+	ArchivedAt:      "archived_at",
+	Name:            "name",
+	DataDigest:      "data_digest",
 }
 
 var AssetTableColumns = struct {
@@ -62,6 +83,13 @@ var AssetTableColumns = struct {
 	ProviderID      string
 	AssetID         string
 	SyncState       string
+	SyncedAt        string
+	SubscriptionID  string
+	Watermark       string
+	ExpandedUntil   string
+	ArchivedAt      string
+	Name            string
+	DataDigest      string
 }{
 	ID:              "asset.id",
 	ConfigurationID: "asset.configuration_id",
@@ -70,6 +98,13 @@ var AssetTableColumns = struct {
 	ProviderID:      "asset.provider_id",
 	AssetID:         "asset.asset_id",
 	SyncState:       "asset.sync_state",
+	SyncedAt:        "asset.synced_at",
+	SubscriptionID:  "asset.subscription_id",
+	Watermark:       "asset.watermark",
+	ExpandedUntil:   "asset.expanded_until",
+	ArchivedAt:      "asset.archived_at",
+	Name:            "asset.name",
+	DataDigest:      "asset.data_digest",
 }
 
 // Generated where
@@ -170,6 +205,13 @@ var AssetWhere = struct {
 	ProviderID      whereHelperstring
 	AssetID         whereHelpernull_Int32
 	SyncState       whereHelperstring
+	SyncedAt        whereHelpernull_Time
+	SubscriptionID  whereHelperstring
+	Watermark       whereHelperstring
+	ExpandedUntil   whereHelpernull_Time
+	ArchivedAt      whereHelpernull_Time
+	Name            whereHelperstring
+	DataDigest      whereHelperstring
 }{
 	ID:              whereHelperint64{field: "\"ews\".\"asset\".\"id\""},
 	ConfigurationID: whereHelperint64{field: "\"ews\".\"asset\".\"configuration_id\""},
@@ -178,6 +220,13 @@ var AssetWhere = struct {
 	ProviderID:      whereHelperstring{field: "\"ews\".\"asset\".\"provider_id\""},
 	AssetID:         whereHelpernull_Int32{field: "\"ews\".\"asset\".\"asset_id\""},
 	SyncState:       whereHelperstring{field: "\"ews\".\"asset\".\"sync_state\""},
+	SyncedAt:        whereHelpernull_Time{field: "\"ews\".\"asset\".\"synced_at\""},
+	SubscriptionID:  whereHelperstring{field: "\"ews\".\"asset\".\"subscription_id\""},
+	Watermark:       whereHelperstring{field: "\"ews\".\"asset\".\"watermark\""},
+	ExpandedUntil:   whereHelpernull_Time{field: "\"ews\".\"asset\".\"expanded_until\""},
+	ArchivedAt:      whereHelpernull_Time{field: "\"ews\".\"asset\".\"archived_at\""},
+	Name:            whereHelperstring{field: "\"ews\".\"asset\".\"name\""},
+	DataDigest:      whereHelperstring{field: "\"ews\".\"asset\".\"data_digest\""},
 }
 
 // AssetRels is where relationship names are stored.
@@ -208,9 +257,9 @@ func (r *assetR) GetConfiguration() *Configuration {
 type assetL struct{}
 
 var (
-	assetAllColumns            = []string{"id", "configuration_id", "project_id", "global_asset_id", "provider_id", "asset_id", "sync_state"}
-	assetColumnsWithoutDefault = []string{"project_id", "global_asset_id", "provider_id", "sync_state"}
-	assetColumnsWithDefault    = []string{"id", "configuration_id", "asset_id"}
+	assetAllColumns            = []string{"id", "configuration_id", "project_id", "global_asset_id", "provider_id", "asset_id", "sync_state", "synced_at", "subscription_id", "watermark", "expanded_until", "archived_at", "name", "data_digest"}
+	assetColumnsWithoutDefault = []string{"project_id", "global_asset_id", "provider_id", "sync_state", "expanded_until"}
+	assetColumnsWithDefault    = []string{"id", "configuration_id", "asset_id", "synced_at", "subscription_id", "watermark", "archived_at", "name", "data_digest"}
 	assetPrimaryKeyColumns     = []string{"id"}
 	assetGeneratedColumns      = []string{}
 )
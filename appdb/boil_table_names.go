@@ -5,14 +5,20 @@ package appdb
 
 var TableNames = struct {
 	Asset             string
+	AuditLog          string
 	BookingGroup      string
 	BookingOccurrence string
 	Configuration     string
+	ResolvedAddress   string
 	RoomBooking       string
+	RoomItem          string
 }{
 	Asset:             "asset",
+	AuditLog:          "audit_log",
 	BookingGroup:      "booking_group",
 	BookingOccurrence: "booking_occurrence",
 	Configuration:     "configuration",
+	ResolvedAddress:   "resolved_address",
 	RoomBooking:       "room_booking",
+	RoomItem:          "room_item",
 }
@@ -28,6 +28,10 @@ type BookingGroup struct {
 	ExchangeUID              null.String `boil:"exchange_uid" json:"exchange_uid,omitempty" toml:"exchange_uid" yaml:"exchange_uid,omitempty"`
 	ExchangeOrganizerMailbox null.String `boil:"exchange_organizer_mailbox" json:"exchange_organizer_mailbox,omitempty" toml:"exchange_organizer_mailbox" yaml:"exchange_organizer_mailbox,omitempty"`
 	ElionaGroupID            null.Int32  `boil:"eliona_group_id" json:"eliona_group_id,omitempty" toml:"eliona_group_id" yaml:"eliona_group_id,omitempty"`
+	State                    string      `boil:"state" json:"state" toml:"state" yaml:"state"`
+	ConfigurationID          null.Int64  `boil:"configuration_id" json:"configuration_id,omitempty" toml:"configuration_id" yaml:"configuration_id,omitempty"`
+	PendingSince             null.Time   `boil:"pending_since" json:"pending_since,omitempty" toml:"pending_since" yaml:"pending_since,omitempty"`
+	Subject                  string      `boil:"subject" json:"subject" toml:"subject" yaml:"subject"`
 
 	R *bookingGroupR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L bookingGroupL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -38,11 +42,19 @@ var BookingGroupColumns = struct {
 	ExchangeUID              string
 	ExchangeOrganizerMailbox string
 	ElionaGroupID            string
+	State                    string
+	ConfigurationID          string
+	PendingSince             string
+	Subject                  string
 }{
 	ID:                       "id",
 	ExchangeUID:              "exchange_uid",
 	ExchangeOrganizerMailbox: "exchange_organizer_mailbox",
 	ElionaGroupID:            "eliona_group_id",
+	State:                    "state",
+	ConfigurationID:          "configuration_id",
+	PendingSince:             "pending_since",
+	Subject:                  "subject",
 }
 
 var BookingGroupTableColumns = struct {
@@ -50,11 +62,19 @@ var BookingGroupTableColumns = struct {
 	ExchangeUID              string
 	ExchangeOrganizerMailbox string
 	ElionaGroupID            string
+	State                    string
+	ConfigurationID          string
+	PendingSince             string
+	Subject                  string
 }{
 	ID:                       "booking_group.id",
 	ExchangeUID:              "booking_group.exchange_uid",
 	ExchangeOrganizerMailbox: "booking_group.exchange_organizer_mailbox",
 	ElionaGroupID:            "booking_group.eliona_group_id",
+	State:                    "booking_group.state",
+	ConfigurationID:          "booking_group.configuration_id",
+	PendingSince:             "booking_group.pending_since",
+	Subject:                  "booking_group.subject",
 }
 
 // Generated where
@@ -109,16 +129,62 @@ func (w whereHelpernull_String) NIN(slice []string) qm.QueryMod {
 func (w whereHelpernull_String) IsNull() qm.QueryMod    { return qmhelper.WhereIsNull(w.field) }
 func (w whereHelpernull_String) IsNotNull() qm.QueryMod { return qmhelper.WhereIsNotNull(w.field) }
 
+type whereHelpernull_Int64 struct{ field string }
+
+func (w whereHelpernull_Int64) EQ(x null.Int64) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, false, x)
+}
+func (w whereHelpernull_Int64) NEQ(x null.Int64) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, true, x)
+}
+func (w whereHelpernull_Int64) LT(x null.Int64) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LT, x)
+}
+func (w whereHelpernull_Int64) LTE(x null.Int64) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LTE, x)
+}
+func (w whereHelpernull_Int64) GT(x null.Int64) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GT, x)
+}
+func (w whereHelpernull_Int64) GTE(x null.Int64) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GTE, x)
+}
+func (w whereHelpernull_Int64) IN(slice []int64) qm.QueryMod {
+	values := make([]interface{}, 0, len(slice))
+	for _, value := range slice {
+		values = append(values, value)
+	}
+	return qm.WhereIn(fmt.Sprintf("%s IN ?", w.field), values...)
+}
+func (w whereHelpernull_Int64) NIN(slice []int64) qm.QueryMod {
+	values := make([]interface{}, 0, len(slice))
+	for _, value := range slice {
+		values = append(values, value)
+	}
+	return qm.WhereNotIn(fmt.Sprintf("%s NOT IN ?", w.field), values...)
+}
+
+func (w whereHelpernull_Int64) IsNull() qm.QueryMod    { return qmhelper.WhereIsNull(w.field) }
+func (w whereHelpernull_Int64) IsNotNull() qm.QueryMod { return qmhelper.WhereIsNotNull(w.field) }
+
 var BookingGroupWhere = struct {
 	ID                       whereHelperint64
 	ExchangeUID              whereHelpernull_String
 	ExchangeOrganizerMailbox whereHelpernull_String
 	ElionaGroupID            whereHelpernull_Int32
+	State                    whereHelperstring
+	ConfigurationID          whereHelpernull_Int64
+	PendingSince             whereHelpernull_Time
+	Subject                  whereHelperstring
 }{
 	ID:                       whereHelperint64{field: "\"ews\".\"booking_group\".\"id\""},
 	ExchangeUID:              whereHelpernull_String{field: "\"ews\".\"booking_group\".\"exchange_uid\""},
 	ExchangeOrganizerMailbox: whereHelpernull_String{field: "\"ews\".\"booking_group\".\"exchange_organizer_mailbox\""},
 	ElionaGroupID:            whereHelpernull_Int32{field: "\"ews\".\"booking_group\".\"eliona_group_id\""},
+	State:                    whereHelperstring{field: "\"ews\".\"booking_group\".\"state\""},
+	ConfigurationID:          whereHelpernull_Int64{field: "\"ews\".\"booking_group\".\"configuration_id\""},
+	PendingSince:             whereHelpernull_Time{field: "\"ews\".\"booking_group\".\"pending_since\""},
+	Subject:                  whereHelperstring{field: "\"ews\".\"booking_group\".\"subject\""},
 }
 
 // BookingGroupRels is where relationship names are stored.
@@ -149,9 +215,9 @@ func (r *bookingGroupR) GetBookingOccurrences() BookingOccurrenceSlice {
 type bookingGroupL struct{}
 
 var (
-	bookingGroupAllColumns            = []string{"id", "exchange_uid", "exchange_organizer_mailbox", "eliona_group_id"}
+	bookingGroupAllColumns            = []string{"id", "exchange_uid", "exchange_organizer_mailbox", "eliona_group_id", "state", "configuration_id", "pending_since", "subject"}
 	bookingGroupColumnsWithoutDefault = []string{}
-	bookingGroupColumnsWithDefault    = []string{"id", "exchange_uid", "exchange_organizer_mailbox", "eliona_group_id"}
+	bookingGroupColumnsWithDefault    = []string{"id", "exchange_uid", "exchange_organizer_mailbox", "eliona_group_id", "state", "configuration_id", "pending_since", "subject"}
 	bookingGroupPrimaryKeyColumns     = []string{"id"}
 	bookingGroupGeneratedColumns      = []string{}
 )
@@ -28,6 +28,7 @@ type BookingOccurrence struct {
 	BookingGroupID        int64      `boil:"booking_group_id" json:"booking_group_id" toml:"booking_group_id" yaml:"booking_group_id"`
 	ExchangeInstanceIndex int32      `boil:"exchange_instance_index" json:"exchange_instance_index" toml:"exchange_instance_index" yaml:"exchange_instance_index"`
 	ElionaBookingID       null.Int32 `boil:"eliona_booking_id" json:"eliona_booking_id,omitempty" toml:"eliona_booking_id" yaml:"eliona_booking_id,omitempty"`
+	Location              string     `boil:"location" json:"location" toml:"location" yaml:"location"`
 
 	R *bookingOccurrenceR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L bookingOccurrenceL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -38,11 +39,13 @@ var BookingOccurrenceColumns = struct {
 	BookingGroupID        string
 	ExchangeInstanceIndex string
 	ElionaBookingID       string
+	Location              string
 }{
 	ID:                    "id",
 	BookingGroupID:        "booking_group_id",
 	ExchangeInstanceIndex: "exchange_instance_index",
 	ElionaBookingID:       "eliona_booking_id",
+	Location:              "location",
 }
 
 var BookingOccurrenceTableColumns = struct {
@@ -50,11 +53,13 @@ var BookingOccurrenceTableColumns = struct {
 	BookingGroupID        string
 	ExchangeInstanceIndex string
 	ElionaBookingID       string
+	Location              string
 }{
 	ID:                    "booking_occurrence.id",
 	BookingGroupID:        "booking_occurrence.booking_group_id",
 	ExchangeInstanceIndex: "booking_occurrence.exchange_instance_index",
 	ElionaBookingID:       "booking_occurrence.eliona_booking_id",
+	Location:              "booking_occurrence.location",
 }
 
 // Generated where
@@ -87,11 +92,13 @@ var BookingOccurrenceWhere = struct {
 	BookingGroupID        whereHelperint64
 	ExchangeInstanceIndex whereHelperint32
 	ElionaBookingID       whereHelpernull_Int32
+	Location              whereHelperstring
 }{
 	ID:                    whereHelperint64{field: "\"ews\".\"booking_occurrence\".\"id\""},
 	BookingGroupID:        whereHelperint64{field: "\"ews\".\"booking_occurrence\".\"booking_group_id\""},
 	ExchangeInstanceIndex: whereHelperint32{field: "\"ews\".\"booking_occurrence\".\"exchange_instance_index\""},
 	ElionaBookingID:       whereHelpernull_Int32{field: "\"ews\".\"booking_occurrence\".\"eliona_booking_id\""},
+	Location:              whereHelperstring{field: "\"ews\".\"booking_occurrence\".\"location\""},
 }
 
 // BookingOccurrenceRels is where relationship names are stored.
@@ -132,9 +139,9 @@ func (r *bookingOccurrenceR) GetRoomBookings() RoomBookingSlice {
 type bookingOccurrenceL struct{}
 
 var (
-	bookingOccurrenceAllColumns            = []string{"id", "booking_group_id", "exchange_instance_index", "eliona_booking_id"}
+	bookingOccurrenceAllColumns            = []string{"id", "booking_group_id", "exchange_instance_index", "eliona_booking_id", "location"}
 	bookingOccurrenceColumnsWithoutDefault = []string{"exchange_instance_index"}
-	bookingOccurrenceColumnsWithDefault    = []string{"id", "booking_group_id", "eliona_booking_id"}
+	bookingOccurrenceColumnsWithDefault    = []string{"id", "booking_group_id", "eliona_booking_id", "location"}
 	bookingOccurrencePrimaryKeyColumns     = []string{"id"}
 	bookingOccurrenceGeneratedColumns      = []string{}
 )
@@ -25,102 +25,347 @@ import (
 
 // Configuration is an object representing the database table.
 type Configuration struct {
-	ID              int64             `boil:"id" json:"id" toml:"id" yaml:"id"`
-	ClientID        string            `boil:"client_id" json:"client_id" toml:"client_id" yaml:"client_id"`
-	ClientSecret    string            `boil:"client_secret" json:"client_secret" toml:"client_secret" yaml:"client_secret"`
-	TenantID        string            `boil:"tenant_id" json:"tenant_id" toml:"tenant_id" yaml:"tenant_id"`
-	EwsURL          string            `boil:"ews_url" json:"ews_url" toml:"ews_url" yaml:"ews_url"`
-	Username        string            `boil:"username" json:"username" toml:"username" yaml:"username"`
-	Password        string            `boil:"password" json:"password" toml:"password" yaml:"password"`
-	ServiceUserUpn  string            `boil:"service_user_upn" json:"service_user_upn" toml:"service_user_upn" yaml:"service_user_upn"`
-	RoomListUpn     string            `boil:"room_list_upn" json:"room_list_upn" toml:"room_list_upn" yaml:"room_list_upn"`
-	BookingAppURL   string            `boil:"booking_app_url" json:"booking_app_url" toml:"booking_app_url" yaml:"booking_app_url"`
-	RefreshInterval int32             `boil:"refresh_interval" json:"refresh_interval" toml:"refresh_interval" yaml:"refresh_interval"`
-	RequestTimeout  int32             `boil:"request_timeout" json:"request_timeout" toml:"request_timeout" yaml:"request_timeout"`
-	AssetFilter     null.JSON         `boil:"asset_filter" json:"asset_filter,omitempty" toml:"asset_filter" yaml:"asset_filter,omitempty"`
-	Active          null.Bool         `boil:"active" json:"active,omitempty" toml:"active" yaml:"active,omitempty"`
-	Enable          null.Bool         `boil:"enable" json:"enable,omitempty" toml:"enable" yaml:"enable,omitempty"`
-	ProjectIds      types.StringArray `boil:"project_ids" json:"project_ids,omitempty" toml:"project_ids" yaml:"project_ids,omitempty"`
-	UserID          null.String       `boil:"user_id" json:"user_id,omitempty" toml:"user_id" yaml:"user_id,omitempty"`
+	ID                          int64             `boil:"id" json:"id" toml:"id" yaml:"id"`
+	ClientID                    string            `boil:"client_id" json:"client_id" toml:"client_id" yaml:"client_id"`
+	ClientSecret                string            `boil:"client_secret" json:"client_secret" toml:"client_secret" yaml:"client_secret"`
+	TenantID                    string            `boil:"tenant_id" json:"tenant_id" toml:"tenant_id" yaml:"tenant_id"`
+	EwsURL                      string            `boil:"ews_url" json:"ews_url" toml:"ews_url" yaml:"ews_url"`
+	Username                    string            `boil:"username" json:"username" toml:"username" yaml:"username"`
+	Password                    string            `boil:"password" json:"password" toml:"password" yaml:"password"`
+	ServiceUserUpn              string            `boil:"service_user_upn" json:"service_user_upn" toml:"service_user_upn" yaml:"service_user_upn"`
+	RoomListUpn                 string            `boil:"room_list_upn" json:"room_list_upn" toml:"room_list_upn" yaml:"room_list_upn"`
+	BookingAppURL               string            `boil:"booking_app_url" json:"booking_app_url" toml:"booking_app_url" yaml:"booking_app_url"`
+	RefreshInterval             int32             `boil:"refresh_interval" json:"refresh_interval" toml:"refresh_interval" yaml:"refresh_interval"`
+	RequestTimeout              int32             `boil:"request_timeout" json:"request_timeout" toml:"request_timeout" yaml:"request_timeout"`
+	AssetFilter                 null.JSON         `boil:"asset_filter" json:"asset_filter,omitempty" toml:"asset_filter" yaml:"asset_filter,omitempty"`
+	Active                      null.Bool         `boil:"active" json:"active,omitempty" toml:"active" yaml:"active,omitempty"`
+	Enable                      null.Bool         `boil:"enable" json:"enable,omitempty" toml:"enable" yaml:"enable,omitempty"`
+	ProjectIds                  types.StringArray `boil:"project_ids" json:"project_ids,omitempty" toml:"project_ids" yaml:"project_ids,omitempty"`
+	UserID                      null.String       `boil:"user_id" json:"user_id,omitempty" toml:"user_id" yaml:"user_id,omitempty"`
+	ApprovalRoomUpns            types.StringArray `boil:"approval_room_upns" json:"approval_room_upns,omitempty" toml:"approval_room_upns" yaml:"approval_room_upns,omitempty"`
+	RequestsPerMinute           int32             `boil:"requests_per_minute" json:"requests_per_minute" toml:"requests_per_minute" yaml:"requests_per_minute"`
+	DeclineGracePeriod          int32             `boil:"decline_grace_period" json:"decline_grace_period" toml:"decline_grace_period" yaml:"decline_grace_period"`
+	OverlapPolicy               string            `boil:"overlap_policy" json:"overlap_policy" toml:"overlap_policy" yaml:"overlap_policy"`
+	FreeBusyStatus              string            `boil:"free_busy_status" json:"free_busy_status" toml:"free_busy_status" yaml:"free_busy_status"`
+	ReadServiceUserUpn          string            `boil:"read_service_user_upn" json:"read_service_user_upn" toml:"read_service_user_upn" yaml:"read_service_user_upn"`
+	WriteServiceUserUpn         string            `boil:"write_service_user_upn" json:"write_service_user_upn" toml:"write_service_user_upn" yaml:"write_service_user_upn"`
+	DeclinePolicy               string            `boil:"decline_policy" json:"decline_policy" toml:"decline_policy" yaml:"decline_policy"`
+	WorkingHours                null.JSON         `boil:"working_hours" json:"working_hours,omitempty" toml:"working_hours" yaml:"working_hours,omitempty"`
+	RoomWorkingHours            null.JSON         `boil:"room_working_hours" json:"room_working_hours,omitempty" toml:"room_working_hours" yaml:"room_working_hours,omitempty"`
+	ReadOnly                    bool              `boil:"read_only" json:"read_only" toml:"read_only" yaml:"read_only"`
+	ImportOverlapPolicy         string            `boil:"import_overlap_policy" json:"import_overlap_policy" toml:"import_overlap_policy" yaml:"import_overlap_policy"`
+	CancelPolicy                string            `boil:"cancel_policy" json:"cancel_policy" toml:"cancel_policy" yaml:"cancel_policy"`
+	RoomNames                   null.JSON         `boil:"room_names" json:"room_names,omitempty" toml:"room_names" yaml:"room_names,omitempty"`
+	RoomNameRules               null.JSON         `boil:"room_name_rules" json:"room_name_rules,omitempty" toml:"room_name_rules" yaml:"room_name_rules,omitempty"`
+	EmptyBookingPolicy          string            `boil:"empty_booking_policy" json:"empty_booking_policy" toml:"empty_booking_policy" yaml:"empty_booking_policy"`
+	ResponsePollInterval        int32             `boil:"response_poll_interval" json:"response_poll_interval" toml:"response_poll_interval" yaml:"response_poll_interval"`
+	BookingTimeZone             string            `boil:"booking_time_zone" json:"booking_time_zone" toml:"booking_time_zone" yaml:"booking_time_zone"`
+	InvitationFailureLimit      int32             `boil:"invitation_failure_limit" json:"invitation_failure_limit" toml:"invitation_failure_limit" yaml:"invitation_failure_limit"`
+	MissingRoomEmailPolicy      string            `boil:"missing_room_email_policy" json:"missing_room_email_policy" toml:"missing_room_email_policy" yaml:"missing_room_email_policy"`
+	SubjectFallback             string            `boil:"subject_fallback" json:"subject_fallback" toml:"subject_fallback" yaml:"subject_fallback"`
+	MaxAttendeesPerRequest      int32             `boil:"max_attendees_per_request" json:"max_attendees_per_request" toml:"max_attendees_per_request" yaml:"max_attendees_per_request"`
+	RoomDiscovery               string            `boil:"room_discovery" json:"room_discovery" toml:"room_discovery" yaml:"room_discovery"`
+	RoomAddressListID           string            `boil:"room_address_list_id" json:"room_address_list_id" toml:"room_address_list_id" yaml:"room_address_list_id"`
+	RoomEmails                  types.StringArray `boil:"room_emails" json:"room_emails,omitempty" toml:"room_emails" yaml:"room_emails,omitempty"`
+	ExportImports               bool              `boil:"export_imports" json:"export_imports" toml:"export_imports" yaml:"export_imports"`
+	ReminderMinutes             null.Int32        `boil:"reminder_minutes" json:"reminder_minutes,omitempty" toml:"reminder_minutes" yaml:"reminder_minutes,omitempty"`
+	SelfOrganizedPolicy         string            `boil:"self_organized_policy" json:"self_organized_policy" toml:"self_organized_policy" yaml:"self_organized_policy"`
+	BlockPolicy                 string            `boil:"block_policy" json:"block_policy" toml:"block_policy" yaml:"block_policy"`
+	ClientSecretExpiresAt       null.Time         `boil:"client_secret_expires_at" json:"client_secret_expires_at,omitempty" toml:"client_secret_expires_at" yaml:"client_secret_expires_at,omitempty"`
+	ResourceSubject             string            `boil:"resource_subject" json:"resource_subject" toml:"resource_subject" yaml:"resource_subject"`
+	UnknownDeletePolicy         string            `boil:"unknown_delete_policy" json:"unknown_delete_policy" toml:"unknown_delete_policy" yaml:"unknown_delete_policy"`
+	ChangeDetection             string            `boil:"change_detection" json:"change_detection" toml:"change_detection" yaml:"change_detection"`
+	MirrorPrivateDetails        bool              `boil:"mirror_private_details" json:"mirror_private_details" toml:"mirror_private_details" yaml:"mirror_private_details"`
+	RecurrenceHorizonDays       int32             `boil:"recurrence_horizon_days" json:"recurrence_horizon_days" toml:"recurrence_horizon_days" yaml:"recurrence_horizon_days"`
+	InvitationProcessingTimeout int32             `boil:"invitation_processing_timeout" json:"invitation_processing_timeout" toml:"invitation_processing_timeout" yaml:"invitation_processing_timeout"`
+	CancellationBody            string            `boil:"cancellation_body" json:"cancellation_body" toml:"cancellation_body" yaml:"cancellation_body"`
+	AppointmentCategory         string            `boil:"appointment_category" json:"appointment_category" toml:"appointment_category" yaml:"appointment_category"`
+	OnlineMeetings              bool              `boil:"online_meetings" json:"online_meetings" toml:"online_meetings" yaml:"online_meetings"`
+	Cloud                       string            `boil:"cloud" json:"cloud" toml:"cloud" yaml:"cloud"`
+	ProxyURL                    string            `boil:"proxy_url" json:"proxy_url" toml:"proxy_url" yaml:"proxy_url"`
+	TLSCaCertPem                string            `boil:"tls_ca_cert_pem" json:"tls_ca_cert_pem" toml:"tls_ca_cert_pem" yaml:"tls_ca_cert_pem"`
+	InsecureSkipVerify          bool              `boil:"insecure_skip_verify" json:"insecure_skip_verify" toml:"insecure_skip_verify" yaml:"insecure_skip_verify"`
+	RoomListUpns                types.StringArray `boil:"room_list_upns" json:"room_list_upns,omitempty" toml:"room_list_upns" yaml:"room_list_upns,omitempty"`
+	ArchiveRemovedRooms         bool              `boil:"archive_removed_rooms" json:"archive_removed_rooms" toml:"archive_removed_rooms" yaml:"archive_removed_rooms"`
+	RoomCapacities              null.JSON         `boil:"room_capacities" json:"room_capacities,omitempty" toml:"room_capacities" yaml:"room_capacities,omitempty"`
+	RoomLocationPattern         string            `boil:"room_location_pattern" json:"room_location_pattern" toml:"room_location_pattern" yaml:"room_location_pattern"`
+	EquipmentEmails             types.StringArray `boil:"equipment_emails" json:"equipment_emails,omitempty" toml:"equipment_emails" yaml:"equipment_emails,omitempty"`
+	BuildingWorkingHours        null.JSON         `boil:"building_working_hours" json:"building_working_hours,omitempty" toml:"building_working_hours" yaml:"building_working_hours,omitempty"`
 
 	R *configurationR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L configurationL  `boil:"-" json:"-" toml:"-" yaml:"-"`
 }
 
 var ConfigurationColumns = struct {
-	ID              string
-	ClientID        string
-	ClientSecret    string
-	TenantID        string
-	EwsURL          string
-	Username        string
-	Password        string
-	ServiceUserUpn  string
-	RoomListUpn     string
-	BookingAppURL   string
-	RefreshInterval string
-	RequestTimeout  string
-	AssetFilter     string
-	Active          string
-	Enable          string
-	ProjectIds      string
-	UserID          string
+	ID                          string
+	ClientID                    string
+	ClientSecret                string
+	TenantID                    string
+	EwsURL                      string
+	Username                    string
+	Password                    string
+	ServiceUserUpn              string
+	RoomListUpn                 string
+	BookingAppURL               string
+	RefreshInterval             string
+	RequestTimeout              string
+	AssetFilter                 string
+	Active                      string
+	Enable                      string
+	ProjectIds                  string
+	UserID                      string
+	ApprovalRoomUpns            string
+	RequestsPerMinute           string
+	DeclineGracePeriod          string
+	OverlapPolicy               string
+	FreeBusyStatus              string
+	ReadServiceUserUpn          string
+	WriteServiceUserUpn         string
+	DeclinePolicy               string
+	WorkingHours                string
+	RoomWorkingHours            string
+	ReadOnly                    string
+	ImportOverlapPolicy         string
+	CancelPolicy                string
+	RoomNames                   string
+	RoomNameRules               string
+	EmptyBookingPolicy          string
+	ResponsePollInterval        string
+	BookingTimeZone             string
+	InvitationFailureLimit      string
+	MissingRoomEmailPolicy      string
+	SubjectFallback             string
+	MaxAttendeesPerRequest      string
+	RoomDiscovery               string
+	RoomAddressListID           string
+	RoomEmails                  string
+	ExportImports               string
+	ReminderMinutes             string
+	SelfOrganizedPolicy         string
+	BlockPolicy                 string
+	ClientSecretExpiresAt       string
+	ResourceSubject             string
+	UnknownDeletePolicy         string
+	ChangeDetection             string
+	MirrorPrivateDetails        string
+	RecurrenceHorizonDays       string
+	InvitationProcessingTimeout string
+	CancellationBody            string
+	AppointmentCategory         string
+	OnlineMeetings              string
+	Cloud                       string
+	ProxyURL                    string
+	TLSCaCertPem                string
+	InsecureSkipVerify          string
+	RoomListUpns                string
+	ArchiveRemovedRooms         string
+	RoomCapacities              string
+	RoomLocationPattern         string
+	EquipmentEmails             string
+	BuildingWorkingHours        string
 }{
-	ID:              "id",
-	ClientID:        "client_id",
-	ClientSecret:    "client_secret",
-	TenantID:        "tenant_id",
-	EwsURL:          "ews_url",
-	Username:        "username",
-	Password:        "password",
-	ServiceUserUpn:  "service_user_upn",
-	RoomListUpn:     "room_list_upn",
-	BookingAppURL:   "booking_app_url",
-	RefreshInterval: "refresh_interval",
-	RequestTimeout:  "request_timeout",
-	AssetFilter:     "asset_filter",
-	Active:          "active",
-	Enable:          "enable",
-	ProjectIds:      "project_ids",
-	UserID:          "user_id",
+	ID:                          "id",
+	ClientID:                    "client_id",
+	ClientSecret:                "client_secret",
+	TenantID:                    "tenant_id",
+	EwsURL:                      "ews_url",
+	Username:                    "username",
+	Password:                    "password",
+	ServiceUserUpn:              "service_user_upn",
+	RoomListUpn:                 "room_list_upn",
+	BookingAppURL:               "booking_app_url",
+	RefreshInterval:             "refresh_interval",
+	RequestTimeout:              "request_timeout",
+	AssetFilter:                 "asset_filter",
+	Active:                      "active",
+	Enable:                      "enable",
+	ProjectIds:                  "project_ids",
+	UserID:                      "user_id",
+	ApprovalRoomUpns:            "approval_room_upns",
+	RequestsPerMinute:           "requests_per_minute",
+	DeclineGracePeriod:          "decline_grace_period",
+	OverlapPolicy:               "overlap_policy",
+	FreeBusyStatus:              "free_busy_status",
+	ReadServiceUserUpn:          "read_service_user_upn",
+	WriteServiceUserUpn:         "write_service_user_upn",
+	DeclinePolicy:               "decline_policy",
+	WorkingHours:                "working_hours",
+	RoomWorkingHours:            "room_working_hours",
+	ReadOnly:                    "read_only",
+	ImportOverlapPolicy:         "import_overlap_policy",
+	CancelPolicy:                "cancel_policy",
+	RoomNames:                   "room_names",
+	RoomNameRules:               "room_name_rules",
+	EmptyBookingPolicy:          "empty_booking_policy",
+	ResponsePollInterval:        "response_poll_interval",
+	BookingTimeZone:             "booking_time_zone",
+	InvitationFailureLimit:      "invitation_failure_limit",
+	MissingRoomEmailPolicy:      "missing_room_email_policy",
+	SubjectFallback:             "subject_fallback",
+	MaxAttendeesPerRequest:      "max_attendees_per_request",
+	RoomDiscovery:               "room_discovery",
+	RoomAddressListID:           "room_address_list_id",
+	RoomEmails:                  "room_emails",
+	ExportImports:               "export_imports",
+	ReminderMinutes:             "reminder_minutes",
+	SelfOrganizedPolicy:         "self_organized_policy",
+	BlockPolicy:                 "block_policy",
+	ClientSecretExpiresAt:       "client_secret_expires_at",
+	ResourceSubject:             "resource_subject",
+	UnknownDeletePolicy:         "unknown_delete_policy",
+	ChangeDetection:             "change_detection",
+	MirrorPrivateDetails:        "mirror_private_details",
+	RecurrenceHorizonDays:       "recurrence_horizon_days",
+	InvitationProcessingTimeout: "invitation_processing_timeout",
+	CancellationBody:            "cancellation_body",
+	AppointmentCategory:         "appointment_category",
+	OnlineMeetings:              "online_meetings",
+	Cloud:                       "cloud",
+	ProxyURL:                    "proxy_url",
+	TLSCaCertPem:                "tls_ca_cert_pem",
+	InsecureSkipVerify:          "insecure_skip_verify",
+	RoomListUpns:                "room_list_upns",
+	ArchiveRemovedRooms:         "archive_removed_rooms",
+	RoomCapacities:              "room_capacities",
+	RoomLocationPattern:         "room_location_pattern",
+	EquipmentEmails:             "equipment_emails",
+	BuildingWorkingHours:        "building_working_hours",
 }
 
 var ConfigurationTableColumns = struct {
-	ID              string
-	ClientID        string
-	ClientSecret    string
-	TenantID        string
-	EwsURL          string
-	Username        string
-	Password        string
-	ServiceUserUpn  string
-	RoomListUpn     string
-	BookingAppURL   string
-	RefreshInterval string
-	RequestTimeout  string
-	AssetFilter     string
-	Active          string
-	Enable          string
-	ProjectIds      string
-	UserID          string
+	ID                          string
+	ClientID                    string
+	ClientSecret                string
+	TenantID                    string
+	EwsURL                      string
+	Username                    string
+	Password                    string
+	ServiceUserUpn              string
+	RoomListUpn                 string
+	BookingAppURL               string
+	RefreshInterval             string
+	RequestTimeout              string
+	AssetFilter                 string
+	Active                      string
+	Enable                      string
+	ProjectIds                  string
+	UserID                      string
+	ApprovalRoomUpns            string
+	RequestsPerMinute           string
+	DeclineGracePeriod          string
+	OverlapPolicy               string
+	FreeBusyStatus              string
+	ReadServiceUserUpn          string
+	WriteServiceUserUpn         string
+	DeclinePolicy               string
+	WorkingHours                string
+	RoomWorkingHours            string
+	ReadOnly                    string
+	ImportOverlapPolicy         string
+	CancelPolicy                string
+	RoomNames                   string
+	RoomNameRules               string
+	EmptyBookingPolicy          string
+	ResponsePollInterval        string
+	BookingTimeZone             string
+	InvitationFailureLimit      string
+	MissingRoomEmailPolicy      string
+	SubjectFallback             string
+	MaxAttendeesPerRequest      string
+	RoomDiscovery               string
+	RoomAddressListID           string
+	RoomEmails                  string
+	ExportImports               string
+	ReminderMinutes             string
+	SelfOrganizedPolicy         string
+	BlockPolicy                 string
+	ClientSecretExpiresAt       string
+	ResourceSubject             string
+	UnknownDeletePolicy         string
+	ChangeDetection             string
+	MirrorPrivateDetails        string
+	RecurrenceHorizonDays       string
+	InvitationProcessingTimeout string
+	CancellationBody            string
+	AppointmentCategory         string
+	OnlineMeetings              string
+	Cloud                       string
+	ProxyURL                    string
+	TLSCaCertPem                string
+	InsecureSkipVerify          string
+	RoomListUpns                string
+	ArchiveRemovedRooms         string
+	RoomCapacities              string
+	RoomLocationPattern         string
+	EquipmentEmails             string
+	BuildingWorkingHours        string
 }{
-	ID:              "configuration.id",
-	ClientID:        "configuration.client_id",
-	ClientSecret:    "configuration.client_secret",
-	TenantID:        "configuration.tenant_id",
-	EwsURL:          "configuration.ews_url",
-	Username:        "configuration.username",
-	Password:        "configuration.password",
-	ServiceUserUpn:  "configuration.service_user_upn",
-	RoomListUpn:     "configuration.room_list_upn",
-	BookingAppURL:   "configuration.booking_app_url",
-	RefreshInterval: "configuration.refresh_interval",
-	RequestTimeout:  "configuration.request_timeout",
-	AssetFilter:     "configuration.asset_filter",
-	Active:          "configuration.active",
-	Enable:          "configuration.enable",
-	ProjectIds:      "configuration.project_ids",
-	UserID:          "configuration.user_id",
+	ID:                          "configuration.id",
+	ClientID:                    "configuration.client_id",
+	ClientSecret:                "configuration.client_secret",
+	TenantID:                    "configuration.tenant_id",
+	EwsURL:                      "configuration.ews_url",
+	Username:                    "configuration.username",
+	Password:                    "configuration.password",
+	ServiceUserUpn:              "configuration.service_user_upn",
+	RoomListUpn:                 "configuration.room_list_upn",
+	BookingAppURL:               "configuration.booking_app_url",
+	RefreshInterval:             "configuration.refresh_interval",
+	RequestTimeout:              "configuration.request_timeout",
+	AssetFilter:                 "configuration.asset_filter",
+	Active:                      "configuration.active",
+	Enable:                      "configuration.enable",
+	ProjectIds:                  "configuration.project_ids",
+	UserID:                      "configuration.user_id",
+	ApprovalRoomUpns:            "configuration.approval_room_upns",
+	RequestsPerMinute:           "configuration.requests_per_minute",
+	DeclineGracePeriod:          "configuration.decline_grace_period",
+	OverlapPolicy:               "configuration.overlap_policy",
+	FreeBusyStatus:              "configuration.free_busy_status",
+	ReadServiceUserUpn:          "configuration.read_service_user_upn",
+	WriteServiceUserUpn:         "configuration.write_service_user_upn",
+	DeclinePolicy:               "configuration.decline_policy",
+	WorkingHours:                "configuration.working_hours",
+	RoomWorkingHours:            "configuration.room_working_hours",
+	ReadOnly:                    "configuration.read_only",
+	ImportOverlapPolicy:         "configuration.import_overlap_policy",
+	CancelPolicy:                "configuration.cancel_policy",
+	RoomNames:                   "configuration.room_names",
+	RoomNameRules:               "configuration.room_name_rules",
+	EmptyBookingPolicy:          "configuration.empty_booking_policy",
+	ResponsePollInterval:        "configuration.response_poll_interval",
+	BookingTimeZone:             "configuration.booking_time_zone",
+	InvitationFailureLimit:      "configuration.invitation_failure_limit",
+	MissingRoomEmailPolicy:      "configuration.missing_room_email_policy",
+	SubjectFallback:             "configuration.subject_fallback",
+	MaxAttendeesPerRequest:      "configuration.max_attendees_per_request",
+	RoomDiscovery:               "configuration.room_discovery",
+	RoomAddressListID:           "configuration.room_address_list_id",
+	RoomEmails:                  "configuration.room_emails",
+	ExportImports:               "configuration.export_imports",
+	ReminderMinutes:             "configuration.reminder_minutes",
+	SelfOrganizedPolicy:         "configuration.self_organized_policy",
+	BlockPolicy:                 "configuration.block_policy",
+	ClientSecretExpiresAt:       "configuration.client_secret_expires_at",
+	ResourceSubject:             "configuration.resource_subject",
+	UnknownDeletePolicy:         "configuration.unknown_delete_policy",
+	ChangeDetection:             "configuration.change_detection",
+	MirrorPrivateDetails:        "configuration.mirror_private_details",
+	RecurrenceHorizonDays:       "configuration.recurrence_horizon_days",
+	InvitationProcessingTimeout: "configuration.invitation_processing_timeout",
+	CancellationBody:            "configuration.cancellation_body",
+	AppointmentCategory:         "configuration.appointment_category",
+	OnlineMeetings:              "configuration.online_meetings",
+	Cloud:                       "configuration.cloud",
+	ProxyURL:                    "configuration.proxy_url",
+	TLSCaCertPem:                "configuration.tls_ca_cert_pem",
+	InsecureSkipVerify:          "configuration.insecure_skip_verify",
+	RoomListUpns:                "configuration.room_list_upns",
+	ArchiveRemovedRooms:         "configuration.archive_removed_rooms",
+	RoomCapacities:              "configuration.room_capacities",
+	RoomLocationPattern:         "configuration.room_location_pattern",
+	EquipmentEmails:             "configuration.equipment_emails",
+	BuildingWorkingHours:        "configuration.building_working_hours",
 }
 
 // Generated where
@@ -199,42 +444,149 @@ func (w whereHelpertypes_StringArray) IsNotNull() qm.QueryMod {
 	return qmhelper.WhereIsNotNull(w.field)
 }
 
+type whereHelperbool struct{ field string }
+
+func (w whereHelperbool) EQ(x bool) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.EQ, x) }
+func (w whereHelperbool) NEQ(x bool) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.NEQ, x) }
+func (w whereHelperbool) LT(x bool) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.LT, x) }
+func (w whereHelperbool) LTE(x bool) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.LTE, x) }
+func (w whereHelperbool) GT(x bool) qm.QueryMod  { return qmhelper.Where(w.field, qmhelper.GT, x) }
+func (w whereHelperbool) GTE(x bool) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.GTE, x) }
+
 var ConfigurationWhere = struct {
-	ID              whereHelperint64
-	ClientID        whereHelperstring
-	ClientSecret    whereHelperstring
-	TenantID        whereHelperstring
-	EwsURL          whereHelperstring
-	Username        whereHelperstring
-	Password        whereHelperstring
-	ServiceUserUpn  whereHelperstring
-	RoomListUpn     whereHelperstring
-	BookingAppURL   whereHelperstring
-	RefreshInterval whereHelperint32
-	RequestTimeout  whereHelperint32
-	AssetFilter     whereHelpernull_JSON
-	Active          whereHelpernull_Bool
-	Enable          whereHelpernull_Bool
-	ProjectIds      whereHelpertypes_StringArray
-	UserID          whereHelpernull_String
+	ID                          whereHelperint64
+	ClientID                    whereHelperstring
+	ClientSecret                whereHelperstring
+	TenantID                    whereHelperstring
+	EwsURL                      whereHelperstring
+	Username                    whereHelperstring
+	Password                    whereHelperstring
+	ServiceUserUpn              whereHelperstring
+	RoomListUpn                 whereHelperstring
+	BookingAppURL               whereHelperstring
+	RefreshInterval             whereHelperint32
+	RequestTimeout              whereHelperint32
+	AssetFilter                 whereHelpernull_JSON
+	Active                      whereHelpernull_Bool
+	Enable                      whereHelpernull_Bool
+	ProjectIds                  whereHelpertypes_StringArray
+	UserID                      whereHelpernull_String
+	ApprovalRoomUpns            whereHelpertypes_StringArray
+	RequestsPerMinute           whereHelperint32
+	DeclineGracePeriod          whereHelperint32
+	OverlapPolicy               whereHelperstring
+	FreeBusyStatus              whereHelperstring
+	ReadServiceUserUpn          whereHelperstring
+	WriteServiceUserUpn         whereHelperstring
+	DeclinePolicy               whereHelperstring
+	WorkingHours                whereHelpernull_JSON
+	RoomWorkingHours            whereHelpernull_JSON
+	ReadOnly                    whereHelperbool
+	ImportOverlapPolicy         whereHelperstring
+	CancelPolicy                whereHelperstring
+	RoomNames                   whereHelpernull_JSON
+	RoomNameRules               whereHelpernull_JSON
+	EmptyBookingPolicy          whereHelperstring
+	ResponsePollInterval        whereHelperint32
+	BookingTimeZone             whereHelperstring
+	InvitationFailureLimit      whereHelperint32
+	MissingRoomEmailPolicy      whereHelperstring
+	SubjectFallback             whereHelperstring
+	MaxAttendeesPerRequest      whereHelperint32
+	RoomDiscovery               whereHelperstring
+	RoomAddressListID           whereHelperstring
+	RoomEmails                  whereHelpertypes_StringArray
+	ExportImports               whereHelperbool
+	ReminderMinutes             whereHelpernull_Int32
+	SelfOrganizedPolicy         whereHelperstring
+	BlockPolicy                 whereHelperstring
+	ClientSecretExpiresAt       whereHelpernull_Time
+	ResourceSubject             whereHelperstring
+	UnknownDeletePolicy         whereHelperstring
+	ChangeDetection             whereHelperstring
+	MirrorPrivateDetails        whereHelperbool
+	RecurrenceHorizonDays       whereHelperint32
+	InvitationProcessingTimeout whereHelperint32
+	CancellationBody            whereHelperstring
+	AppointmentCategory         whereHelperstring
+	OnlineMeetings              whereHelperbool
+	Cloud                       whereHelperstring
+	ProxyURL                    whereHelperstring
+	TLSCaCertPem                whereHelperstring
+	InsecureSkipVerify          whereHelperbool
+	RoomListUpns                whereHelpertypes_StringArray
+	ArchiveRemovedRooms         whereHelperbool
+	RoomCapacities              whereHelpernull_JSON
+	RoomLocationPattern         whereHelperstring
+	EquipmentEmails             whereHelpertypes_StringArray
+	BuildingWorkingHours        whereHelpernull_JSON
 }{
-	ID:              whereHelperint64{field: "\"ews\".\"configuration\".\"id\""},
-	ClientID:        whereHelperstring{field: "\"ews\".\"configuration\".\"client_id\""},
-	ClientSecret:    whereHelperstring{field: "\"ews\".\"configuration\".\"client_secret\""},
-	TenantID:        whereHelperstring{field: "\"ews\".\"configuration\".\"tenant_id\""},
-	EwsURL:          whereHelperstring{field: "\"ews\".\"configuration\".\"ews_url\""},
-	Username:        whereHelperstring{field: "\"ews\".\"configuration\".\"username\""},
-	Password:        whereHelperstring{field: "\"ews\".\"configuration\".\"password\""},
-	ServiceUserUpn:  whereHelperstring{field: "\"ews\".\"configuration\".\"service_user_upn\""},
-	RoomListUpn:     whereHelperstring{field: "\"ews\".\"configuration\".\"room_list_upn\""},
-	BookingAppURL:   whereHelperstring{field: "\"ews\".\"configuration\".\"booking_app_url\""},
-	RefreshInterval: whereHelperint32{field: "\"ews\".\"configuration\".\"refresh_interval\""},
-	RequestTimeout:  whereHelperint32{field: "\"ews\".\"configuration\".\"request_timeout\""},
-	AssetFilter:     whereHelpernull_JSON{field: "\"ews\".\"configuration\".\"asset_filter\""},
-	Active:          whereHelpernull_Bool{field: "\"ews\".\"configuration\".\"active\""},
-	Enable:          whereHelpernull_Bool{field: "\"ews\".\"configuration\".\"enable\""},
-	ProjectIds:      whereHelpertypes_StringArray{field: "\"ews\".\"configuration\".\"project_ids\""},
-	UserID:          whereHelpernull_String{field: "\"ews\".\"configuration\".\"user_id\""},
+	ID:                          whereHelperint64{field: "\"ews\".\"configuration\".\"id\""},
+	ClientID:                    whereHelperstring{field: "\"ews\".\"configuration\".\"client_id\""},
+	ClientSecret:                whereHelperstring{field: "\"ews\".\"configuration\".\"client_secret\""},
+	TenantID:                    whereHelperstring{field: "\"ews\".\"configuration\".\"tenant_id\""},
+	EwsURL:                      whereHelperstring{field: "\"ews\".\"configuration\".\"ews_url\""},
+	Username:                    whereHelperstring{field: "\"ews\".\"configuration\".\"username\""},
+	Password:                    whereHelperstring{field: "\"ews\".\"configuration\".\"password\""},
+	ServiceUserUpn:              whereHelperstring{field: "\"ews\".\"configuration\".\"service_user_upn\""},
+	RoomListUpn:                 whereHelperstring{field: "\"ews\".\"configuration\".\"room_list_upn\""},
+	BookingAppURL:               whereHelperstring{field: "\"ews\".\"configuration\".\"booking_app_url\""},
+	RefreshInterval:             whereHelperint32{field: "\"ews\".\"configuration\".\"refresh_interval\""},
+	RequestTimeout:              whereHelperint32{field: "\"ews\".\"configuration\".\"request_timeout\""},
+	AssetFilter:                 whereHelpernull_JSON{field: "\"ews\".\"configuration\".\"asset_filter\""},
+	Active:                      whereHelpernull_Bool{field: "\"ews\".\"configuration\".\"active\""},
+	Enable:                      whereHelpernull_Bool{field: "\"ews\".\"configuration\".\"enable\""},
+	ProjectIds:                  whereHelpertypes_StringArray{field: "\"ews\".\"configuration\".\"project_ids\""},
+	UserID:                      whereHelpernull_String{field: "\"ews\".\"configuration\".\"user_id\""},
+	ApprovalRoomUpns:            whereHelpertypes_StringArray{field: "\"ews\".\"configuration\".\"approval_room_upns\""},
+	RequestsPerMinute:           whereHelperint32{field: "\"ews\".\"configuration\".\"requests_per_minute\""},
+	DeclineGracePeriod:          whereHelperint32{field: "\"ews\".\"configuration\".\"decline_grace_period\""},
+	OverlapPolicy:               whereHelperstring{field: "\"ews\".\"configuration\".\"overlap_policy\""},
+	FreeBusyStatus:              whereHelperstring{field: "\"ews\".\"configuration\".\"free_busy_status\""},
+	ReadServiceUserUpn:          whereHelperstring{field: "\"ews\".\"configuration\".\"read_service_user_upn\""},
+	WriteServiceUserUpn:         whereHelperstring{field: "\"ews\".\"configuration\".\"write_service_user_upn\""},
+	DeclinePolicy:               whereHelperstring{field: "\"ews\".\"configuration\".\"decline_policy\""},
+	WorkingHours:                whereHelpernull_JSON{field: "\"ews\".\"configuration\".\"working_hours\""},
+	RoomWorkingHours:            whereHelpernull_JSON{field: "\"ews\".\"configuration\".\"room_working_hours\""},
+	ReadOnly:                    whereHelperbool{field: "\"ews\".\"configuration\".\"read_only\""},
+	ImportOverlapPolicy:         whereHelperstring{field: "\"ews\".\"configuration\".\"import_overlap_policy\""},
+	CancelPolicy:                whereHelperstring{field: "\"ews\".\"configuration\".\"cancel_policy\""},
+	RoomNames:                   whereHelpernull_JSON{field: "\"ews\".\"configuration\".\"room_names\""},
+	RoomNameRules:               whereHelpernull_JSON{field: "\"ews\".\"configuration\".\"room_name_rules\""},
+	EmptyBookingPolicy:          whereHelperstring{field: "\"ews\".\"configuration\".\"empty_booking_policy\""},
+	ResponsePollInterval:        whereHelperint32{field: "\"ews\".\"configuration\".\"response_poll_interval\""},
+	BookingTimeZone:             whereHelperstring{field: "\"ews\".\"configuration\".\"booking_time_zone\""},
+	InvitationFailureLimit:      whereHelperint32{field: "\"ews\".\"configuration\".\"invitation_failure_limit\""},
+	MissingRoomEmailPolicy:      whereHelperstring{field: "\"ews\".\"configuration\".\"missing_room_email_policy\""},
+	SubjectFallback:             whereHelperstring{field: "\"ews\".\"configuration\".\"subject_fallback\""},
+	MaxAttendeesPerRequest:      whereHelperint32{field: "\"ews\".\"configuration\".\"max_attendees_per_request\""},
+	RoomDiscovery:               whereHelperstring{field: "\"ews\".\"configuration\".\"room_discovery\""},
+	RoomAddressListID:           whereHelperstring{field: "\"ews\".\"configuration\".\"room_address_list_id\""},
+	RoomEmails:                  whereHelpertypes_StringArray{field: "\"ews\".\"configuration\".\"room_emails\""},
+	ExportImports:               whereHelperbool{field: "\"ews\".\"configuration\".\"export_imports\""},
+	ReminderMinutes:             whereHelpernull_Int32{field: "\"ews\".\"configuration\".\"reminder_minutes\""},
+	SelfOrganizedPolicy:         whereHelperstring{field: "\"ews\".\"configuration\".\"self_organized_policy\""},
+	BlockPolicy:                 whereHelperstring{field: "\"ews\".\"configuration\".\"block_policy\""},
+	ClientSecretExpiresAt:       whereHelpernull_Time{field: "\"ews\".\"configuration\".\"client_secret_expires_at\""},
+	ResourceSubject:             whereHelperstring{field: "\"ews\".\"configuration\".\"resource_subject\""},
+	UnknownDeletePolicy:         whereHelperstring{field: "\"ews\".\"configuration\".\"unknown_delete_policy\""},
+	ChangeDetection:             whereHelperstring{field: "\"ews\".\"configuration\".\"change_detection\""},
+	MirrorPrivateDetails:        whereHelperbool{field: "\"ews\".\"configuration\".\"mirror_private_details\""},
+	RecurrenceHorizonDays:       whereHelperint32{field: "\"ews\".\"configuration\".\"recurrence_horizon_days\""},
+	InvitationProcessingTimeout: whereHelperint32{field: "\"ews\".\"configuration\".\"invitation_processing_timeout\""},
+	CancellationBody:            whereHelperstring{field: "\"ews\".\"configuration\".\"cancellation_body\""},
+	AppointmentCategory:         whereHelperstring{field: "\"ews\".\"configuration\".\"appointment_category\""},
+	OnlineMeetings:              whereHelperbool{field: "\"ews\".\"configuration\".\"online_meetings\""},
+	Cloud:                       whereHelperstring{field: "\"ews\".\"configuration\".\"cloud\""},
+	ProxyURL:                    whereHelperstring{field: "\"ews\".\"configuration\".\"proxy_url\""},
+	TLSCaCertPem:                whereHelperstring{field: "\"ews\".\"configuration\".\"tls_ca_cert_pem\""},
+	InsecureSkipVerify:          whereHelperbool{field: "\"ews\".\"configuration\".\"insecure_skip_verify\""},
+	RoomListUpns:                whereHelpertypes_StringArray{field: "\"ews\".\"configuration\".\"room_list_upns\""},
+	ArchiveRemovedRooms:         whereHelperbool{field: "\"ews\".\"configuration\".\"archive_removed_rooms\""},
+	RoomCapacities:              whereHelpernull_JSON{field: "\"ews\".\"configuration\".\"room_capacities\""},
+	RoomLocationPattern:         whereHelperstring{field: "\"ews\".\"configuration\".\"room_location_pattern\""},
+	EquipmentEmails:             whereHelpertypes_StringArray{field: "\"ews\".\"configuration\".\"equipment_emails\""},
+	BuildingWorkingHours:        whereHelpernull_JSON{field: "\"ews\".\"configuration\".\"building_working_hours\""},
 }
 
 // ConfigurationRels is where relationship names are stored.
@@ -265,9 +617,9 @@ func (r *configurationR) GetAssets() AssetSlice {
 type configurationL struct{}
 
 var (
-	configurationAllColumns            = []string{"id", "client_id", "client_secret", "tenant_id", "ews_url", "username", "password", "service_user_upn", "room_list_upn", "booking_app_url", "refresh_interval", "request_timeout", "asset_filter", "active", "enable", "project_ids", "user_id"}
-	configurationColumnsWithoutDefault = []string{"client_id", "client_secret", "tenant_id", "ews_url", "username", "password", "service_user_upn", "room_list_upn", "booking_app_url"}
-	configurationColumnsWithDefault    = []string{"id", "refresh_interval", "request_timeout", "asset_filter", "active", "enable", "project_ids", "user_id"}
+	configurationAllColumns            = []string{"id", "client_id", "client_secret", "tenant_id", "ews_url", "username", "password", "service_user_upn", "room_list_upn", "booking_app_url", "refresh_interval", "request_timeout", "asset_filter", "active", "enable", "project_ids", "user_id", "approval_room_upns", "requests_per_minute", "decline_grace_period", "overlap_policy", "free_busy_status", "read_service_user_upn", "write_service_user_upn", "decline_policy", "working_hours", "room_working_hours", "read_only", "import_overlap_policy", "cancel_policy", "room_names", "room_name_rules", "empty_booking_policy", "response_poll_interval", "booking_time_zone", "invitation_failure_limit", "missing_room_email_policy", "subject_fallback", "max_attendees_per_request", "room_discovery", "room_address_list_id", "room_emails", "export_imports", "reminder_minutes", "self_organized_policy", "block_policy", "client_secret_expires_at", "resource_subject", "unknown_delete_policy", "change_detection", "mirror_private_details", "recurrence_horizon_days", "invitation_processing_timeout", "cancellation_body", "appointment_category", "online_meetings", "cloud", "proxy_url", "tls_ca_cert_pem", "insecure_skip_verify", "room_list_upns", "archive_removed_rooms", "room_capacities", "room_location_pattern", "equipment_emails", "building_working_hours"}
+	configurationColumnsWithoutDefault = []string{"client_id", "client_secret", "tenant_id", "ews_url", "username", "password", "service_user_upn", "room_list_upn", "booking_app_url", "building_working_hours"}
+	configurationColumnsWithDefault    = []string{"id", "refresh_interval", "request_timeout", "asset_filter", "active", "enable", "project_ids", "user_id", "approval_room_upns", "requests_per_minute", "decline_grace_period", "overlap_policy", "free_busy_status", "read_service_user_upn", "write_service_user_upn", "decline_policy", "working_hours", "room_working_hours", "read_only", "import_overlap_policy", "cancel_policy", "room_names", "room_name_rules", "empty_booking_policy", "response_poll_interval", "booking_time_zone", "invitation_failure_limit", "missing_room_email_policy", "subject_fallback", "max_attendees_per_request", "room_discovery", "room_address_list_id", "room_emails", "export_imports", "reminder_minutes", "self_organized_policy", "block_policy", "client_secret_expires_at", "resource_subject", "unknown_delete_policy", "change_detection", "mirror_private_details", "recurrence_horizon_days", "invitation_processing_timeout", "cancellation_body", "appointment_category", "online_meetings", "cloud", "proxy_url", "tls_ca_cert_pem", "insecure_skip_verify", "room_list_upns", "archive_removed_rooms", "room_capacities", "room_location_pattern", "equipment_emails"}
 	configurationPrimaryKeyColumns     = []string{"id"}
 	configurationGeneratedColumns      = []string{}
 )
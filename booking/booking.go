@@ -11,6 +11,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/eliona-smart-building-assistant/go-utils/log"
@@ -21,11 +23,27 @@ const clientReference = "ews-app"
 
 type client struct {
 	BaseURL string
+	clock   syncmodel.BookingClock
+	// closePolicy decides how the booking listener handles close codes.
+	closePolicy map[int]CloseAction
+	// coalesceWindow is how long the booking listener holds events back to
+	// coalesce those of the same group.
+	coalesceWindow time.Duration
+	// stopErr is the error the booking listener stopped on, see StopErr.
+	stopErr error
+	// ExportImports makes the booking listener pass on the booking events
+	// caused by the app's own imports instead of ignoring them.
+	ExportImports bool
 }
 
-func NewClient(baseURL string) *client {
+// NewClient creates a client of the Booking app, converting booking times
+// with the clock.
+func NewClient(baseURL string, clock syncmodel.BookingClock) *client {
 	return &client{
-		BaseURL: baseURL,
+		BaseURL:        baseURL,
+		clock:          clock,
+		closePolicy:    closePolicy,
+		coalesceWindow: coalesceWindow,
 	}
 }
 
@@ -52,24 +70,131 @@ func (c *client) get(elionaID int32) (bookingResponse, error) {
 	}
 
 	var respBody bookingResponse
-	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-		return bookingResponse{}, fmt.Errorf("error parsing response body: %v", err)
+	if err := decodeResponse(resp.Body, &respBody, func() error { return respBody.validate() }); err != nil {
+		return bookingResponse{}, fmt.Errorf("booking %v: %v", elionaID, err)
 	}
 
 	return respBody, nil
 }
 
+// listPageSize is how many bookings are requested per page when listing.
+const listPageSize = 100
+
+type bookingPageResponse struct {
+	Bookings []bookingResponse `json:"bookings"`
+	// NextPage is the page following this one, 0 on the last page.
+	NextPage int `json:"nextPage"`
+}
+
+func (r bookingPageResponse) validate() error {
+	for _, booking := range r.Bookings {
+		if err := booking.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListBookings lists the bookings of the assets overlapping the time window,
+// fetching all pages.
+func (c *client) ListBookings(assetIDs []int32, start, end time.Time) ([]syncmodel.BookingOccurrence, error) {
+	ids := make([]string, len(assetIDs))
+	for i, id := range assetIDs {
+		ids[i] = strconv.Itoa(int(id))
+	}
+	var occurrences []syncmodel.BookingOccurrence
+	for page := 1; page != 0; {
+		v := url.Values{}
+		v.Add("assetIds", strings.Join(ids, ","))
+		v.Add("start", c.clock.ToEliona(start).Format(time.RFC3339))
+		v.Add("end", c.clock.ToEliona(end).Format(time.RFC3339))
+		v.Add("page", strconv.Itoa(page))
+		v.Add("pageSize", strconv.Itoa(listPageSize))
+		resp, err := http.Get(c.BaseURL + "/bookings?" + v.Encode())
+		if err != nil {
+			return nil, err
+		}
+		respBody, err := decodePage(resp)
+		if err != nil {
+			return nil, fmt.Errorf("listing bookings page %d: %v", page, err)
+		}
+		for _, booking := range respBody.Bookings {
+			roomBookings := make([]syncmodel.RoomBooking, len(booking.AssetIds))
+			for i, assetID := range booking.AssetIds {
+				roomBookings[i] = syncmodel.RoomBooking{AssetID: assetID}
+			}
+			bookingStart, bookingEnd := exchangeTimes(c.clock.FromEliona(booking.Start), c.clock.FromEliona(booking.End), booking.AllDay)
+			occurrences = append(occurrences, syncmodel.BookingOccurrence{
+				ElionaID:     booking.Id,
+				Start:        bookingStart,
+				End:          bookingEnd,
+				AllDay:       booking.AllDay,
+				IsOnline:     booking.IsOnline,
+				RoomBookings: roomBookings,
+			})
+		}
+		if respBody.NextPage != 0 && respBody.NextPage <= page {
+			return nil, fmt.Errorf("page %d refers back to page %d", page, respBody.NextPage)
+		}
+		page = respBody.NextPage
+	}
+	return occurrences, nil
+}
+
+func decodePage(resp *http.Response) (bookingPageResponse, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return bookingPageResponse{}, fmt.Errorf("error %v returned: failed to read response body: %v", resp.StatusCode, err)
+		}
+		return bookingPageResponse{}, fmt.Errorf("unexpected status code %d: %v", resp.StatusCode, string(bodyBytes))
+	}
+	var respBody bookingPageResponse
+	if err := decodeResponse(resp.Body, &respBody, func() error { return respBody.validate() }); err != nil {
+		return bookingPageResponse{}, err
+	}
+	return respBody, nil
+}
+
+// decodeResponse decodes the response body and checks it with validate, so
+// that fields missing in the Booking app response don't silently end up as
+// zero values in our mapping.
+func decodeResponse(body io.Reader, v any, validate func() error) error {
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("reading response body: %v", err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("error parsing response body: %v", err)
+	}
+	if err := validate(); err != nil {
+		log.Error("booking", "Invalid response body: %s", raw)
+		return fmt.Errorf("unexpected response from booking app: %v", err)
+	}
+	return nil
+}
+
 func (c *client) Book(groups map[string]syncmodel.BookingGroup) error {
 	for _, group := range groups {
 		var convertedBookings []bookingRequest
 		for _, booking := range group.Occurrences {
+			start, end := elionaTimes(booking)
 			convertedBookings = append(convertedBookings, bookingRequest{
-				BookingID:   booking.ElionaID,
-				AssetIds:    booking.GetAssetIDs(),
-				OrganizerID: group.OrganizerEmail,
-				Start:       booking.Start,
-				End:         booking.End,
-				Cancelled:   booking.Cancelled,
+				BookingID:          booking.ElionaID,
+				AssetIds:           booking.GetAssetIDs(),
+				OrganizerID:        organizerID(group),
+				Start:              c.clock.ToEliona(start),
+				End:                c.clock.ToEliona(end),
+				AllDay:             booking.AllDay,
+				Cancelled:          booking.Cancelled,
+				IsOnline:           group.IsOnline,
+				JoinURL:            group.JoinURL,
+				Categories:         group.Categories,
+				Subject:            group.Subject,
+				Location:           booking.Location,
+				Attendees:          booking.Attendees,
+				AttendeesTruncated: booking.AttendeesTruncated,
 			})
 		}
 		convertedGroup := bookingGroupRequest{
@@ -80,6 +205,7 @@ func (c *client) Book(groups map[string]syncmodel.BookingGroup) error {
 		if err != nil {
 			return err
 		}
+		imports.record(responseGroup.Id, importedBookings(convertedGroup, responseGroup), time.Now())
 		group.ElionaID = responseGroup.Id
 		for i, responseBooking := range responseGroup.Bookings {
 			// This works because the order of the bookings in response is kept
@@ -94,6 +220,37 @@ func (c *client) Book(groups map[string]syncmodel.BookingGroup) error {
 	return nil
 }
 
+// organizerID is the organizer of the group in Eliona. Rooms organizing their
+// own events are nobody to attribute them to.
+func organizerID(group syncmodel.BookingGroup) string {
+	if group.SelfOrganized {
+		return ""
+	}
+	return group.OrganizerEmail
+}
+
+// elionaTimes converts the occurrence's times to the Booking app's
+// representation. Exchange ends all-day events at the midnight following the
+// last day, while the Booking app ends them inclusively at the last second of
+// the last day.
+func elionaTimes(occurrence syncmodel.BookingOccurrence) (start, end time.Time) {
+	if !occurrence.AllDay {
+		return occurrence.Start, occurrence.End
+	}
+	return occurrence.Start, occurrence.End.Add(-time.Second)
+}
+
+// exchangeTimes converts the Booking app's times back to the syncmodel
+// representation, see elionaTimes. The inclusive end of all-day bookings is
+// accepted with any precision within the last minute of the day, so that the
+// times don't drift when round-tripping.
+func exchangeTimes(start, end time.Time, allDay bool) (time.Time, time.Time) {
+	if !allDay {
+		return start, end
+	}
+	return start.Truncate(time.Minute), end.Add(time.Minute).Truncate(time.Minute)
+}
+
 type bookingGroupRequest struct {
 	GroupID     int32            `json:"groupID,omitempty"`
 	Occurrences []bookingRequest `json:"occurrences"`
@@ -105,7 +262,16 @@ type bookingRequest struct {
 	OrganizerID string    `json:"organizerID"`
 	Start       time.Time `json:"start"`
 	End         time.Time `json:"end"`
+	AllDay      bool      `json:"allDay,omitempty"`
 	Cancelled   bool      `json:"cancelled"`
+	IsOnline    bool      `json:"isOnline,omitempty"`
+	JoinURL     string    `json:"joinURL,omitempty"`
+	Categories  []string  `json:"categories,omitempty"`
+	Subject     string    `json:"subject,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	// Attendees are the people invited besides the organizer.
+	Attendees          []string `json:"attendees,omitempty"`
+	AttendeesTruncated bool     `json:"attendeesTruncated,omitempty"`
 }
 
 type bookingGroupResponse struct {
@@ -114,12 +280,67 @@ type bookingGroupResponse struct {
 }
 
 type bookingResponse struct {
-	Id            int32     `json:"id"`
-	AssetIds      []int32   `json:"assetIds"`
-	Start         time.Time `json:"start"`
-	End           time.Time `json:"end"`
-	OrganizerID   string    `json:"organizerID"`
-	OrganizerName string    `json:"organizerName"`
+	Id                 int32     `json:"id"`
+	AssetIds           []int32   `json:"assetIds"`
+	Start              time.Time `json:"start"`
+	End                time.Time `json:"end"`
+	AllDay             bool      `json:"allDay"`
+	OrganizerID        string    `json:"organizerID"`
+	OrganizerName      string    `json:"organizerName"`
+	IsOnline           bool      `json:"isOnline"`
+	JoinURL            string    `json:"joinURL"`
+	Categories         []string  `json:"categories"`
+	Subject            string    `json:"subject"`
+	Location           string    `json:"location"`
+	Attendees          []string  `json:"attendees"`
+	AttendeesTruncated bool      `json:"attendeesTruncated"`
+}
+
+// rebook returns the request booking the assets instead of the booked ones.
+// The other fields are sent as booked, as the Booking app might clear those
+// omitted.
+func (r bookingResponse) rebook(assetIDs []int32) bookingRequest {
+	return bookingRequest{
+		BookingID:          r.Id,
+		AssetIds:           assetIDs,
+		OrganizerID:        r.OrganizerID,
+		Start:              r.Start,
+		End:                r.End,
+		AllDay:             r.AllDay,
+		IsOnline:           r.IsOnline,
+		JoinURL:            r.JoinURL,
+		Categories:         r.Categories,
+		Subject:            r.Subject,
+		Location:           r.Location,
+		Attendees:          r.Attendees,
+		AttendeesTruncated: r.AttendeesTruncated,
+	}
+}
+
+func (r bookingGroupResponse) validate(occurrences int) error {
+	if r.Id == 0 {
+		return errors.New("missing group id")
+	}
+	// Book maps the bookings to the occurrences by their order.
+	if len(r.Bookings) != occurrences {
+		return fmt.Errorf("expected %d bookings in group %v, got %d", occurrences, r.Id, len(r.Bookings))
+	}
+	for i, booking := range r.Bookings {
+		if booking.Id == 0 {
+			return fmt.Errorf("missing id of booking %d in group %v", i, r.Id)
+		}
+	}
+	return nil
+}
+
+func (r bookingResponse) validate() error {
+	if r.Id == 0 {
+		return errors.New("missing booking id")
+	}
+	if r.Start.IsZero() || r.End.IsZero() {
+		return fmt.Errorf("missing start or end of booking %v", r.Id)
+	}
+	return nil
 }
 
 func (c *client) book(bookings bookingGroupRequest) (bookingGroupResponse, error) {
@@ -145,14 +366,19 @@ func (c *client) book(bookings bookingGroupRequest) (bookingGroupResponse, error
 	}
 
 	var respBody bookingGroupResponse
-	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-		return bookingGroupResponse{}, fmt.Errorf("error parsing response body: %v", err)
+	validate := func() error {
+		return respBody.validate(len(bookings.Occurrences))
+	}
+	if err := decodeResponse(resp.Body, &respBody, validate); err != nil {
+		return bookingGroupResponse{}, err
 	}
 
 	return respBody, nil
 }
 
-func (c *client) CancelSlice(bookings []syncmodel.RoomBooking) error {
+// CancelSlice removes the rooms from their Eliona bookings. Bookings left
+// without rooms are cancelled or kept empty according to the policy.
+func (c *client) CancelSlice(bookings []syncmodel.RoomBooking, policy syncmodel.EmptyBookingPolicy) error {
 	for _, b := range bookings {
 		if b.BookingOccurrence == nil {
 			return fmt.Errorf("unifiedBooking is nil")
@@ -161,19 +387,11 @@ func (c *client) CancelSlice(bookings []syncmodel.RoomBooking) error {
 		if err != nil {
 			return fmt.Errorf("getting eliona booking for id %v: %v", b.BookingOccurrence.ElionaID, err)
 		}
-		elionaBooking.AssetIds = removeElement(elionaBooking.AssetIds, b.AssetID)
-		if len(elionaBooking.AssetIds) != 0 {
+		assetIDs := removeElement(elionaBooking.AssetIds, b.AssetID)
+		if len(assetIDs) != 0 || policy == syncmodel.EmptyBookingKeep {
 			// We don't want to cancel the whole event in Eliona when just part of the rooms are removed from the event.
 			_, err := c.book(bookingGroupRequest{
-				Occurrences: []bookingRequest{
-					{
-						BookingID:   elionaBooking.Id,
-						Start:       elionaBooking.Start,
-						End:         elionaBooking.End,
-						AssetIds:    elionaBooking.AssetIds,
-						OrganizerID: elionaBooking.OrganizerID,
-					},
-				},
+				Occurrences: []bookingRequest{elionaBooking.rebook(assetIDs)},
 			})
 			if err != nil {
 				return fmt.Errorf("updating booking %v: %v", elionaBooking.Id, err)
@@ -188,13 +406,45 @@ func (c *client) CancelSlice(bookings []syncmodel.RoomBooking) error {
 	return nil
 }
 
+// RemoveRooms removes the assets from the booking, keeping it in the rest of
+// the booked rooms.
+func (c *client) RemoveRooms(elionaID int32, assetIDs []int32) error {
+	elionaBooking, err := c.get(elionaID)
+	if err != nil {
+		return fmt.Errorf("getting eliona booking for id %v: %v", elionaID, err)
+	}
+	removed := make(map[int32]bool)
+	for _, assetID := range assetIDs {
+		removed[assetID] = true
+	}
+	var kept []int32
+	for _, assetID := range elionaBooking.AssetIds {
+		if !removed[assetID] {
+			kept = append(kept, assetID)
+		}
+	}
+	if len(kept) == 0 {
+		return fmt.Errorf("booking %v would have no rooms left", elionaID)
+	}
+	_, err = c.book(bookingGroupRequest{
+		Occurrences: []bookingRequest{elionaBooking.rebook(kept)},
+	})
+	if err != nil {
+		return fmt.Errorf("updating booking %v: %v", elionaBooking.Id, err)
+	}
+	return nil
+}
+
+// removeElement returns a new slice without the element, leaving the original
+// slice untouched.
 func removeElement(slice []int32, element int32) []int32 {
-	for i, v := range slice {
-		if v == element {
-			return append(slice[:i], slice[i+1:]...)
+	res := make([]int32, 0, len(slice))
+	for _, v := range slice {
+		if v != element {
+			res = append(res, v)
 		}
 	}
-	return slice
+	return res
 }
 
 func (c *client) Cancel(elionaID int32, reason string) error {
@@ -252,91 +502,159 @@ func (c *client) subscribeBookings(assetIDs []int) (*websocket.Conn, error) {
 type BookingGroup struct {
 	Id       int32     `json:"id,omitempty"`
 	Bookings []Booking `json:"bookings,omitempty"`
+	// ClientReference identifies the client which made the change, if the
+	// Booking app tells.
+	ClientReference string `json:"clientReference,omitempty"`
 }
 
 type Booking struct {
-	ID          int32
-	AssetIds    []int32   `json:"assetIds"`
-	OrganizerID string    `json:"organizerID"`
-	Start       time.Time `json:"start"`
-	End         time.Time `json:"end"`
-	Cancelled   bool      `json:"cancelled"`
+	ID             int32
+	AssetIds       []int32   `json:"assetIds"`
+	OrganizerID    string    `json:"organizerID"`
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	AllDay         bool      `json:"allDay"`
+	Cancelled      bool      `json:"cancelled"`
+	FreeBusyStatus string    `json:"freeBusyStatus,omitempty"`
 }
 
+const bookingsQueueSize = 100
+
+// ListenForBookings subscribes to the booking changes of the assets. When the
+// Booking app closes the subscription, it is renewed as the close policy says.
+// Events following each other quickly are coalesced per booking group.
+// The channel is closed when the context is cancelled or the policy stops
+// listening.
 func (c *client) ListenForBookings(ctx context.Context, assetIDs []int) (<-chan syncmodel.BookingGroup, error) {
 	conn, err := c.subscribeBookings(assetIDs)
 	if err != nil {
 		return nil, err
 	}
 	log.Debug("eliona-booking", "Subscribed")
-	bookingsChan := make(chan syncmodel.BookingGroup)
+	// Buffered so that the events waiting for processing are visible in the lag stats.
+	bookingsChan := make(chan syncmodel.BookingGroup, bookingsQueueSize)
 
 	go func() {
 		defer close(bookingsChan)
-		defer conn.Close()
-
+		backoff := minResubscribeBackoff
 		for {
-			message, err := func() ([]byte, error) {
-				done := make(chan struct{})
-				var message []byte
-				var err error
-				go func() {
-					defer close(done)
-					_, message, err = conn.ReadMessage()
-				}()
-
-				// Wait for message read, context cancellation, or a timeout
-				select {
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				case <-done:
-					return message, err
-				}
-			}()
+			received, err := c.readBookings(ctx, conn, bookingsChan)
+			conn.Close()
 			if errors.Is(err, context.Canceled) {
 				return
 			}
-			if err != nil {
-				log.Error("eliona-booking", "Error reading from WebSocket: %v", err)
-				return
+			if received {
+				backoff = minResubscribeBackoff
 			}
-
-			var bookingGroup BookingGroup
-			if err = json.Unmarshal(message, &bookingGroup); err != nil {
-				log.Error("eliona-booking", "Error unmarshaling bookingGroup: %v", err)
-				continue // Skip this message and continue listening
+			action := closeActionFor(err, c.closePolicy)
+			if action == CloseStop {
+				c.stopErr = err
+				return
 			}
-
-			organizer := ""
-			occurrences := make([]syncmodel.BookingOccurrence, 0, len(bookingGroup.Bookings))
-			for _, booking := range bookingGroup.Bookings {
-				roomBookings := make([]syncmodel.RoomBooking, len(booking.AssetIds))
-				for i, assetID := range booking.AssetIds {
-					roomBookings[i] = syncmodel.RoomBooking{
-						AssetID: assetID,
+			for {
+				if action == CloseBackoff {
+					log.Info("eliona-booking", "Resubscribing in %v", backoff)
+					if !sleep(ctx, backoff) {
+						return
+					}
+					backoff *= 2
+					if backoff > maxResubscribeBackoff {
+						backoff = maxResubscribeBackoff
 					}
 				}
-				occurrences = append(occurrences, syncmodel.BookingOccurrence{
-					ElionaID:     booking.ID,
-					RoomBookings: roomBookings,
-					Start:        booking.Start,
-					End:          booking.End,
-					Cancelled:    booking.Cancelled,
-				})
-				if organizer == "" {
-					organizer = booking.OrganizerID
-				} else if organizer != booking.OrganizerID {
-					log.Error("eliona-booking", "received booking group with different organizers. A: %s B: %s", organizer, booking.OrganizerID)
-					continue
+				if conn, err = c.subscribeBookings(assetIDs); err == nil {
+					break
 				}
+				log.Error("eliona-booking", "Resubscribing: %v", err)
+				action = CloseBackoff
 			}
-			bookingsChan <- syncmodel.BookingGroup{
-				ElionaID:       bookingGroup.Id,
-				Occurrences:    occurrences,
-				OrganizerEmail: organizer,
-			}
+			log.Debug("eliona-booking", "Resubscribed")
 		}
 	}()
 
-	return bookingsChan, nil
+	return coalesce(bookingsChan, c.coalesceWindow), nil
+}
+
+// StopErr returns the close error the booking listener stopped on because of
+// the close policy. It is nil if the listener ended for any other reason, and
+// is only meaningful after the bookings channel was closed.
+func (c *client) StopErr() error {
+	return c.stopErr
+}
+
+// readBookings passes the bookings received on the connection to the channel
+// until reading fails. Received reports whether any message was read.
+func (c *client) readBookings(ctx context.Context, conn *websocket.Conn, bookingsChan chan<- syncmodel.BookingGroup) (received bool, err error) {
+	for {
+		message, err := func() ([]byte, error) {
+			done := make(chan struct{})
+			var message []byte
+			var err error
+			go func() {
+				defer close(done)
+				_, message, err = conn.ReadMessage()
+			}()
+
+			// Wait for message read, context cancellation, or a timeout
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-done:
+				return message, err
+			}
+		}()
+		if err != nil {
+			return received, err
+		}
+		received = true
+		receivedAt := time.Now()
+
+		var bookingGroup BookingGroup
+		if err = json.Unmarshal(message, &bookingGroup); err != nil {
+			log.Error("eliona-booking", "Error unmarshaling bookingGroup: %v", err)
+			continue // Skip this message and continue listening
+		}
+		if !c.ExportImports && c.isEcho(bookingGroup, receivedAt) {
+			log.Debug("eliona-booking", "Ignoring booking group %v imported by the app", bookingGroup.Id)
+			continue
+		}
+
+		organizer := ""
+		freeBusyStatus := ""
+		occurrences := make([]syncmodel.BookingOccurrence, 0, len(bookingGroup.Bookings))
+		for _, booking := range bookingGroup.Bookings {
+			roomBookings := make([]syncmodel.RoomBooking, len(booking.AssetIds))
+			for i, assetID := range booking.AssetIds {
+				roomBookings[i] = syncmodel.RoomBooking{
+					AssetID: assetID,
+				}
+			}
+			start, end := exchangeTimes(c.clock.FromEliona(booking.Start), c.clock.FromEliona(booking.End), booking.AllDay)
+			occurrences = append(occurrences, syncmodel.BookingOccurrence{
+				ElionaID:     booking.ID,
+				RoomBookings: roomBookings,
+				Start:        start,
+				End:          end,
+				AllDay:       booking.AllDay,
+				Cancelled:    booking.Cancelled,
+			})
+			if booking.FreeBusyStatus != "" {
+				freeBusyStatus = booking.FreeBusyStatus
+			}
+			if organizer == "" {
+				organizer = booking.OrganizerID
+			} else if organizer != booking.OrganizerID {
+				log.Error("eliona-booking", "received booking group with different organizers. A: %s B: %s", organizer, booking.OrganizerID)
+				continue
+			}
+		}
+		Lag.Received(receivedAt)
+		bookingsChan <- syncmodel.BookingGroup{
+			ElionaID:       bookingGroup.Id,
+			Occurrences:    occurrences,
+			OrganizerEmail: organizer,
+			FreeBusyStatus: freeBusyStatus,
+			ReceivedAt:     receivedAt,
+		}
+	}
 }
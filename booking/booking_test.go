@@ -0,0 +1,303 @@
+package booking
+
+import (
+	"encoding/json"
+	syncmodel "ews/model/sync"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serve(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+}
+
+func TestGetRejectsIncompleteResponses(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body    string
+		wantErr string
+	}{
+		"valid":         {`{"id": 5, "assetIds": [1], "start": "2024-05-01T10:00:00Z", "end": "2024-05-01T11:00:00Z"}`, ""},
+		"renamed id":    {`{"bookingId": 5, "assetIds": [1], "start": "2024-05-01T10:00:00Z", "end": "2024-05-01T11:00:00Z"}`, "missing booking id"},
+		"missing times": {`{"id": 5, "assetIds": [1]}`, "missing start or end"},
+		"malformed":     {`{"id": "five"}`, "error parsing response body"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := serve(tc.body)
+			defer server.Close()
+			booking, err := NewClient(server.URL, syncmodel.BookingClock{}).get(5)
+			if tc.wantErr == "" {
+				if err != nil || booking.Id != 5 {
+					t.Errorf("expected booking 5, got %+v, %v", booking, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBookRejectsIncompleteResponses(t *testing.T) {
+	request := bookingGroupRequest{Occurrences: []bookingRequest{
+		{Start: time.Now(), End: time.Now().Add(time.Hour)},
+		{Start: time.Now().Add(24 * time.Hour), End: time.Now().Add(25 * time.Hour)},
+	}}
+	for name, tc := range map[string]struct {
+		body    string
+		wantErr string
+	}{
+		"valid":               {`{"id": 3, "bookings": [{"id": 7}, {"id": 8}]}`, ""},
+		"missing group id":    {`{"bookings": [{"id": 7}, {"id": 8}]}`, "missing group id"},
+		"missing booking":     {`{"id": 3, "bookings": [{"id": 7}]}`, "expected 2 bookings"},
+		"missing booking id":  {`{"id": 3, "bookings": [{"id": 7}, {}]}`, "missing id of booking 1"},
+		"renamed bookings":    {`{"id": 3, "items": [{"id": 7}, {"id": 8}]}`, "expected 2 bookings"},
+		"unexpected document": {`[]`, "error parsing response body"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := serve(tc.body)
+			defer server.Close()
+			group, err := NewClient(server.URL, syncmodel.BookingClock{}).book(request)
+			if tc.wantErr == "" {
+				if err != nil || group.Id != 3 || group.Bookings[1].Id != 8 {
+					t.Errorf("expected group 3, got %+v, %v", group, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRemoveElementKeepsInput(t *testing.T) {
+	input := []int32{1, 2, 3}
+	got := removeElement(input, 1)
+	if len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("expected [2 3], got %v", got)
+	}
+	if input[0] != 1 || input[1] != 2 || input[2] != 3 {
+		t.Errorf("input was modified to %v", input)
+	}
+	got[0] = 9
+	if input[1] != 2 {
+		t.Errorf("result shares the input's backing array")
+	}
+}
+
+func TestCancelSliceLastRoom(t *testing.T) {
+	for policy, want := range map[syncmodel.EmptyBookingPolicy]struct {
+		cancelled bool
+		updated   bool
+	}{
+		syncmodel.EmptyBookingCancel: {cancelled: true},
+		syncmodel.EmptyBookingKeep:   {updated: true},
+	} {
+		t.Run(string(policy), func(t *testing.T) {
+			var cancelled, updated bool
+			var updatedAssets []int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodGet:
+					w.Write([]byte(`{"id": 5, "assetIds": [1], "start": "2024-05-01T10:00:00Z", "end": "2024-05-01T11:00:00Z"}`))
+				case http.MethodPost:
+					updated = true
+					var request bookingGroupRequest
+					if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+						t.Errorf("decoding request: %v", err)
+					}
+					updatedAssets = request.Occurrences[0].AssetIds
+					w.Write([]byte(`{"id": 3, "bookings": [{"id": 5}]}`))
+				case http.MethodDelete:
+					cancelled = true
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}))
+			defer server.Close()
+
+			bookings := []syncmodel.RoomBooking{{AssetID: 1, BookingOccurrence: &syncmodel.BookingOccurrence{ElionaID: 5}}}
+			if err := NewClient(server.URL, syncmodel.BookingClock{}).CancelSlice(bookings, policy); err != nil {
+				t.Fatalf("cancelling: %v", err)
+			}
+			if cancelled != want.cancelled || updated != want.updated {
+				t.Errorf("expected cancelled %v and updated %v, got %v and %v", want.cancelled, want.updated, cancelled, updated)
+			}
+			if updated && (updatedAssets == nil || len(updatedAssets) != 0) {
+				t.Errorf("expected the booking to be kept with no assets, got %v", updatedAssets)
+			}
+		})
+	}
+}
+
+func TestRemovingRoomsKeepsBookingDetails(t *testing.T) {
+	var requests []bookingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"id": 5, "assetIds": [1, 2], "start": "2024-05-01T10:00:00Z", "end": "2024-05-01T11:00:00Z", "organizerID": "jane.doe@example.com",
+				"subject": "Board meeting", "location": "Room 1", "attendees": ["john.doe@example.com"], "categories": ["Catering"], "isOnline": true, "joinURL": "https://teams.example.com/join"}`))
+		case http.MethodPost:
+			var request bookingGroupRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Errorf("decoding request: %v", err)
+			}
+			requests = append(requests, request.Occurrences[0])
+			w.Write([]byte(`{"id": 3, "bookings": [{"id": 5}]}`))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, syncmodel.BookingClock{})
+	if err := c.RemoveRooms(5, []int32{2}); err != nil {
+		t.Fatalf("removing rooms: %v", err)
+	}
+	bookings := []syncmodel.RoomBooking{{AssetID: 2, BookingOccurrence: &syncmodel.BookingOccurrence{ElionaID: 5}}}
+	if err := c.CancelSlice(bookings, syncmodel.EmptyBookingCancel); err != nil {
+		t.Fatalf("cancelling: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected the booking to be updated twice, got %d requests", len(requests))
+	}
+	for _, request := range requests {
+		if fmt.Sprint(request.AssetIds) != "[1]" {
+			t.Errorf("expected the booking to keep room 1 only, got %v", request.AssetIds)
+		}
+		if request.Subject != "Board meeting" || request.Location != "Room 1" || fmt.Sprint(request.Attendees) != "[john.doe@example.com]" ||
+			fmt.Sprint(request.Categories) != "[Catering]" || !request.IsOnline || request.JoinURL != "https://teams.example.com/join" {
+			t.Errorf("expected the details of the booking to be kept, got %+v", request)
+		}
+	}
+}
+
+func TestAllDayRoundTrip(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tc := range map[string]struct {
+		start, end time.Time
+		lastDay    time.Time
+	}{
+		"single day": {
+			start:   time.Date(2024, 5, 1, 0, 0, 0, 0, zurich),
+			end:     time.Date(2024, 5, 2, 0, 0, 0, 0, zurich),
+			lastDay: time.Date(2024, 5, 1, 0, 0, 0, 0, zurich),
+		},
+		"multiple days over DST change": {
+			start:   time.Date(2024, 3, 29, 0, 0, 0, 0, zurich),
+			end:     time.Date(2024, 4, 2, 0, 0, 0, 0, zurich),
+			lastDay: time.Date(2024, 4, 1, 0, 0, 0, 0, zurich),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			occurrence := syncmodel.BookingOccurrence{Start: tc.start.UTC(), End: tc.end.UTC(), AllDay: true}
+			for i := 0; i < 3; i++ {
+				start, end := elionaTimes(occurrence)
+				if local := end.In(zurich); local.Day() != tc.lastDay.Day() || local.Hour() != 23 || local.Minute() != 59 {
+					t.Fatalf("expected Eliona booking to end on the last day, got %v", local)
+				}
+				// Pass the times through JSON as the Booking app does.
+				var booking Booking
+				body, _ := json.Marshal(bookingRequest{Start: start, End: end, AllDay: true})
+				if err := json.Unmarshal(body, &booking); err != nil {
+					t.Fatal(err)
+				}
+				occurrence.Start, occurrence.End = exchangeTimes(booking.Start, booking.End, booking.AllDay)
+				if !occurrence.Start.Equal(tc.start) || !occurrence.End.Equal(tc.end) {
+					t.Fatalf("round trip %d: expected %v - %v, got %v - %v", i, tc.start, tc.end, occurrence.Start, occurrence.End)
+				}
+			}
+		})
+	}
+}
+
+func TestExchangeTimesToleratesEndPrecision(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	want := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	for _, end := range []time.Time{
+		time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC),
+		time.Date(2024, 5, 1, 23, 59, 59, 0, time.UTC),
+		time.Date(2024, 5, 1, 23, 59, 59, 999000000, time.UTC),
+	} {
+		if _, got := exchangeTimes(start, end, true); !got.Equal(want) {
+			t.Errorf("exchangeTimes end %v = %v, want %v", end, got, want)
+		}
+	}
+	if _, got := exchangeTimes(start, want, false); !got.Equal(want) {
+		t.Errorf("expected times of other bookings to be kept, got %v", got)
+	}
+}
+
+func TestListBookingsFetchesAllPages(t *testing.T) {
+	pages := map[string]string{
+		"1": `{"bookings": [
+			{"id": 1, "assetIds": [10], "start": "2024-05-01T10:00:00Z", "end": "2024-05-01T11:00:00Z"},
+			{"id": 2, "assetIds": [10, 11], "start": "2024-05-02T10:00:00Z", "end": "2024-05-02T11:00:00Z", "isOnline": true}
+		], "nextPage": 2}`,
+		"2": `{"bookings": [
+			{"id": 3, "assetIds": [11], "start": "2024-05-03T00:00:00Z", "end": "2024-05-03T23:59:59Z", "allDay": true}
+		]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/bookings" || query.Get("assetIds") != "10,11" || query.Get("start") != "2024-05-01T00:00:00Z" || query.Get("end") != "2024-05-08T00:00:00Z" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		body, ok := pages[query.Get("page")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	occurrences, err := NewClient(server.URL, syncmodel.BookingClock{}).ListBookings([]int32{10, 11}, start, start.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(occurrences) != 3 {
+		t.Fatalf("expected 3 bookings, got %+v", occurrences)
+	}
+	for i, occurrence := range occurrences {
+		if occurrence.ElionaID != int32(i+1) {
+			t.Errorf("booking %d: expected id %d, got %d", i, i+1, occurrence.ElionaID)
+		}
+	}
+	if assets := occurrences[1].GetAssetIDs(); len(assets) != 2 || assets[1] != 11 {
+		t.Errorf("expected assets [10 11], got %v", assets)
+	}
+	if occurrences[0].IsOnline || !occurrences[1].IsOnline {
+		t.Errorf("expected only booking 2 to be online, got %+v", occurrences)
+	}
+	if wantEnd := time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC); !occurrences[2].AllDay || !occurrences[2].End.Equal(wantEnd) {
+		t.Errorf("expected all-day booking ending %v, got %+v", wantEnd, occurrences[2])
+	}
+}
+
+func TestListBookingsRejectsPagesReferringBack(t *testing.T) {
+	server := serve(`{"bookings": [], "nextPage": 1}`)
+	defer server.Close()
+	if _, err := NewClient(server.URL, syncmodel.BookingClock{}).ListBookings([]int32{10}, time.Now(), time.Now()); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSelfOrganizedGroupsAreUnattributed(t *testing.T) {
+	group := syncmodel.BookingGroup{OrganizerEmail: "room1@example.com", SelfOrganized: true}
+	if id := organizerID(group); id != "" {
+		t.Errorf("expected no organizer, got %q", id)
+	}
+	group.SelfOrganized = false
+	if id := organizerID(group); id != "room1@example.com" {
+		t.Errorf("expected the organizer, got %q", id)
+	}
+}
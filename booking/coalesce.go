@@ -0,0 +1,102 @@
+package booking
+
+import (
+	syncmodel "ews/model/sync"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+// DefaultCoalesceWindow is how long booking events are held back, unless
+// BOOKING_COALESCE_WINDOW overrides it.
+const DefaultCoalesceWindow = 2 * time.Second
+
+// coalesceWindow is the window used by the booking listener.
+var coalesceWindow = loadCoalesceWindow()
+
+func loadCoalesceWindow() time.Duration {
+	window, err := time.ParseDuration(common.Getenv("BOOKING_COALESCE_WINDOW", DefaultCoalesceWindow.String()))
+	if err != nil || window < 0 {
+		log.Warn("eliona-booking", "invalid BOOKING_COALESCE_WINDOW, using %v: %v", DefaultCoalesceWindow, err)
+		return DefaultCoalesceWindow
+	}
+	return window
+}
+
+type heldGroup struct {
+	group syncmodel.BookingGroup
+	due   time.Time
+}
+
+// coalesce holds each booking group back for the window. Events of a group
+// received meanwhile replace the held one, so that e.g. a booking created and
+// immediately updated is created once with the latest state. Events are passed
+// on in the order their groups were first received. A zero window passes them
+// on immediately.
+func coalesce(in <-chan syncmodel.BookingGroup, window time.Duration) <-chan syncmodel.BookingGroup {
+	if window <= 0 {
+		return in
+	}
+	out := make(chan syncmodel.BookingGroup, bookingsQueueSize)
+	go func() {
+		defer close(out)
+		var held []heldGroup
+		for {
+			var due <-chan time.Time
+			if len(held) > 0 {
+				due = time.After(time.Until(held[0].due))
+			}
+			select {
+			case group, ok := <-in:
+				if !ok {
+					for _, h := range held {
+						out <- h.group
+					}
+					return
+				}
+				held = hold(held, group, window, out)
+			case <-due:
+				out <- held[0].group
+				held = held[1:]
+			}
+		}
+	}()
+	return out
+}
+
+// hold adds the group to the held ones, replacing an earlier event of it. An
+// earlier event cancelling occurrences the latest one doesn't is passed on
+// first, so that the cancellation is not lost.
+func hold(held []heldGroup, group syncmodel.BookingGroup, window time.Duration, out chan<- syncmodel.BookingGroup) []heldGroup {
+	for i, h := range held {
+		if h.group.ElionaID != group.ElionaID {
+			continue
+		}
+		if cancelsMore(h.group, group) {
+			out <- h.group
+			held = append(held[:i], held[i+1:]...)
+			break
+		}
+		log.Debug("eliona-booking", "Coalescing booking events of group %v", group.ElionaID)
+		Lag.Processed(h.group.ReceivedAt, time.Now())
+		held[i].group = group
+		return held
+	}
+	return append(held, heldGroup{group: group, due: time.Now().Add(window)})
+}
+
+// cancelsMore reports whether the earlier event cancels an occurrence the
+// later one doesn't.
+func cancelsMore(earlier, later syncmodel.BookingGroup) bool {
+	cancelled := make(map[int32]bool, len(later.Occurrences))
+	for _, occurrence := range later.Occurrences {
+		cancelled[occurrence.ElionaID] = occurrence.Cancelled
+	}
+	for _, occurrence := range earlier.Occurrences {
+		if occurrence.Cancelled && !cancelled[occurrence.ElionaID] {
+			return true
+		}
+	}
+	return false
+}
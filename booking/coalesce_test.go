@@ -0,0 +1,88 @@
+package booking
+
+import (
+	syncmodel "ews/model/sync"
+	"testing"
+	"time"
+)
+
+func bookingEvent(groupID, occurrenceID int32, start time.Time, cancelled bool) syncmodel.BookingGroup {
+	return syncmodel.BookingGroup{
+		ElionaID: groupID,
+		Occurrences: []syncmodel.BookingOccurrence{
+			{ElionaID: occurrenceID, Start: start, End: start.Add(time.Hour), Cancelled: cancelled},
+		},
+	}
+}
+
+// coalesced sends the events within the window and collects what is passed
+// on. Closing the input passes on the held events.
+func coalesced(t *testing.T, events ...syncmodel.BookingGroup) []syncmodel.BookingGroup {
+	in := make(chan syncmodel.BookingGroup, len(events))
+	for _, event := range events {
+		in <- event
+	}
+	close(in)
+	out := coalesce(in, time.Minute)
+	var groups []syncmodel.BookingGroup
+	for {
+		select {
+		case group, ok := <-out:
+			if !ok {
+				return groups
+			}
+			groups = append(groups, group)
+		case <-time.After(5 * time.Second):
+			t.Fatal("events not passed on")
+		}
+	}
+}
+
+func TestCoalesceCreateThenUpdate(t *testing.T) {
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	groups := coalesced(t,
+		bookingEvent(1, 11, start, false),
+		bookingEvent(2, 21, start, false),
+		bookingEvent(1, 11, start.Add(time.Hour), false),
+	)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 events, got %+v", groups)
+	}
+	if groups[0].ElionaID != 1 || !groups[0].Occurrences[0].Start.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected the latest state of group 1 first, got %+v", groups[0])
+	}
+	if groups[1].ElionaID != 2 {
+		t.Errorf("expected group 2 second, got %+v", groups[1])
+	}
+}
+
+func TestCoalesceCreateThenCancel(t *testing.T) {
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	groups := coalesced(t,
+		bookingEvent(1, 11, start, false),
+		bookingEvent(1, 11, start, true),
+	)
+	if len(groups) != 1 || !groups[0].Occurrences[0].Cancelled {
+		t.Fatalf("expected only the cancellation, got %+v", groups)
+	}
+}
+
+func TestCoalesceKeepsCancellation(t *testing.T) {
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	cancel := bookingEvent(1, 11, start, true)
+	update := bookingEvent(1, 12, start.Add(24*time.Hour), false)
+	groups := coalesced(t, cancel, update)
+	if len(groups) != 2 {
+		t.Fatalf("expected the cancellation and the update, got %+v", groups)
+	}
+	if groups[0].Occurrences[0].ElionaID != 11 || !groups[0].Occurrences[0].Cancelled {
+		t.Errorf("expected the cancellation first, got %+v", groups[0])
+	}
+}
+
+func TestCoalesceWithoutWindow(t *testing.T) {
+	in := make(chan syncmodel.BookingGroup)
+	if out := coalesce(in, 0); out != (<-chan syncmodel.BookingGroup)(in) {
+		t.Error("expected events to be passed on directly")
+	}
+}
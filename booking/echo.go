@@ -0,0 +1,85 @@
+package booking
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// importEchoTTL is how long after an import the booking events echoing it are
+// recognized.
+const importEchoTTL = 10 * time.Minute
+
+// imports remembers the groups imported to the Booking app, so that the
+// booking events echoing them are recognized even if the Booking app doesn't
+// tell their origin.
+var imports = &importTracker{groups: make(map[int32]importedGroup)}
+
+type importTracker struct {
+	mu     sync.Mutex
+	groups map[int32]importedGroup
+}
+
+type importedGroup struct {
+	fingerprint string
+	at          time.Time
+}
+
+// record remembers the bookings of the group as imported at the given time.
+func (t *importTracker) record(groupID int32, bookings []Booking, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, group := range t.groups {
+		if now.Sub(group.at) > importEchoTTL {
+			delete(t.groups, id)
+		}
+	}
+	t.groups[groupID] = importedGroup{fingerprint: fingerprint(bookings), at: now}
+}
+
+// isEcho reports whether the group was imported recently with the same
+// bookings. Changes made in Eliona since differ from the import.
+func (t *importTracker) isEcho(group BookingGroup, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	imported, ok := t.groups[group.Id]
+	return ok && now.Sub(imported.at) <= importEchoTTL && imported.fingerprint == fingerprint(group.Bookings)
+}
+
+// isEcho reports whether the booking event results from the app's own import.
+func (c *client) isEcho(group BookingGroup, now time.Time) bool {
+	if group.ClientReference != "" {
+		return group.ClientReference == clientReference
+	}
+	return imports.isEcho(group, now)
+}
+
+// importedBookings are the bookings as the Booking app stored the request.
+func importedBookings(request bookingGroupRequest, response bookingGroupResponse) []Booking {
+	bookings := make([]Booking, len(request.Occurrences))
+	for i, occurrence := range request.Occurrences {
+		bookings[i] = Booking{
+			ID:        response.Bookings[i].Id,
+			AssetIds:  occurrence.AssetIds,
+			Start:     occurrence.Start,
+			End:       occurrence.End,
+			AllDay:    occurrence.AllDay,
+			Cancelled: occurrence.Cancelled,
+		}
+	}
+	return bookings
+}
+
+func fingerprint(bookings []Booking) string {
+	var b strings.Builder
+	for _, booking := range bookings {
+		assetIDs := append([]int32(nil), booking.AssetIds...)
+		sort.Slice(assetIDs, func(i, j int) bool { return assetIDs[i] < assetIDs[j] })
+		fmt.Fprintf(&b, "%d %v %s %s %t %t;", booking.ID, assetIDs,
+			booking.Start.UTC().Format(time.RFC3339Nano), booking.End.UTC().Format(time.RFC3339Nano),
+			booking.AllDay, booking.Cancelled)
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package booking
+
+import (
+	syncmodel "ews/model/sync"
+	"testing"
+	"time"
+)
+
+func TestImportEchoIsRecognized(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	start := now.Add(time.Hour)
+	request := bookingGroupRequest{Occurrences: []bookingRequest{
+		{AssetIds: []int32{2, 1}, Start: start, End: start.Add(time.Hour)},
+	}}
+	response := bookingGroupResponse{Id: 40, Bookings: []bookingResponse{{Id: 41}}}
+	imports.record(response.Id, importedBookings(request, response), now)
+	c := NewClient("", syncmodel.BookingClock{})
+
+	echo := BookingGroup{Id: 40, Bookings: []Booking{
+		{ID: 41, AssetIds: []int32{1, 2}, Start: start.In(time.FixedZone("CEST", 2*3600)), End: start.Add(time.Hour)},
+	}}
+	if !c.isEcho(echo, now.Add(time.Second)) {
+		t.Error("expected the import to be echoed")
+	}
+	if c.isEcho(echo, now.Add(importEchoTTL+time.Second)) {
+		t.Error("expected an expired import not to be echoed")
+	}
+
+	moved := echo
+	moved.Bookings = []Booking{echo.Bookings[0]}
+	moved.Bookings[0].End = start.Add(2 * time.Hour)
+	if c.isEcho(moved, now.Add(time.Second)) {
+		t.Error("expected a booking changed in Eliona not to be an echo")
+	}
+	other := BookingGroup{Id: 42, Bookings: echo.Bookings}
+	if c.isEcho(other, now.Add(time.Second)) {
+		t.Error("expected another group not to be an echo")
+	}
+}
+
+func TestImportEchoByClientReference(t *testing.T) {
+	c := NewClient("", syncmodel.BookingClock{})
+	now := time.Now()
+	if !c.isEcho(BookingGroup{Id: 50, ClientReference: clientReference}, now) {
+		t.Error("expected an event from the app to be an echo")
+	}
+	imports.record(51, nil, now)
+	if c.isEcho(BookingGroup{Id: 51, ClientReference: "eliona-frontend"}, now) {
+		t.Error("expected an event from another client not to be an echo")
+	}
+}
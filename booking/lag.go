@@ -0,0 +1,116 @@
+package booking
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LagBuckets are the upper bounds of the histogram of time between receiving
+// a booking event on the websocket and completing its processing in EWS.
+var LagBuckets = []time.Duration{
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+}
+
+// Lag tracks the booking events received from the booking websocket that are
+// waiting for processing in EWS.
+var Lag = &lagTracker{
+	bucketCounts: make([]uint64, len(LagBuckets)),
+}
+
+type lagTracker struct {
+	mu sync.Mutex
+	// pending holds the receive times of events not processed yet.
+	pending      []time.Time
+	bucketCounts []uint64
+	count        uint64
+	sum          time.Duration
+}
+
+// LagStats is a snapshot of the booking event lag.
+type LagStats struct {
+	QueueDepth       int
+	OldestPendingAge time.Duration
+	// BucketCounts are cumulative counts of processed events per LagBuckets.
+	BucketCounts []uint64
+	Count        uint64
+	Sum          time.Duration
+}
+
+// Received marks a booking event as received and waiting for processing.
+func (l *lagTracker) Received(at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append(l.pending, at)
+}
+
+// Processed marks the event received at the given time as processed and
+// records how long it took.
+func (l *lagTracker) Processed(receivedAt time.Time, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, t := range l.pending {
+		if t.Equal(receivedAt) {
+			l.pending = append(l.pending[:i], l.pending[i+1:]...)
+			break
+		}
+	}
+	lag := now.Sub(receivedAt)
+	l.count++
+	l.sum += lag
+	for i, bound := range LagBuckets {
+		if lag <= bound {
+			l.bucketCounts[i]++
+		}
+	}
+}
+
+// Stats returns the current state of the queue and the lag histogram.
+func (l *lagTracker) Stats(now time.Time) LagStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := LagStats{
+		QueueDepth:   len(l.pending),
+		BucketCounts: append([]uint64(nil), l.bucketCounts...),
+		Count:        l.count,
+		Sum:          l.sum,
+	}
+	for _, t := range l.pending {
+		if age := now.Sub(t); age > stats.OldestPendingAge {
+			stats.OldestPendingAge = age
+		}
+	}
+	return stats
+}
+
+// MetricsHandler serves the booking event lag in the Prometheus text format.
+func MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeMetrics(w, Lag.Stats(time.Now()))
+}
+
+func writeMetrics(w io.Writer, stats LagStats) {
+	fmt.Fprintln(w, "# HELP ews_booking_event_lag_seconds Time between receiving a booking event and completing its processing in EWS.")
+	fmt.Fprintln(w, "# TYPE ews_booking_event_lag_seconds histogram")
+	for i, bound := range LagBuckets {
+		fmt.Fprintf(w, "ews_booking_event_lag_seconds_bucket{le=\"%g\"} %d\n", bound.Seconds(), stats.BucketCounts[i])
+	}
+	fmt.Fprintf(w, "ews_booking_event_lag_seconds_bucket{le=\"+Inf\"} %d\n", stats.Count)
+	fmt.Fprintf(w, "ews_booking_event_lag_seconds_sum %g\n", stats.Sum.Seconds())
+	fmt.Fprintf(w, "ews_booking_event_lag_seconds_count %d\n", stats.Count)
+	fmt.Fprintln(w, "# HELP ews_booking_event_queue_depth Number of received booking events waiting for processing.")
+	fmt.Fprintln(w, "# TYPE ews_booking_event_queue_depth gauge")
+	fmt.Fprintf(w, "ews_booking_event_queue_depth %d\n", stats.QueueDepth)
+	fmt.Fprintln(w, "# HELP ews_booking_event_oldest_pending_age_seconds Age of the oldest booking event waiting for processing.")
+	fmt.Fprintln(w, "# TYPE ews_booking_event_oldest_pending_age_seconds gauge")
+	fmt.Fprintf(w, "ews_booking_event_oldest_pending_age_seconds %g\n", stats.OldestPendingAge.Seconds())
+}
@@ -0,0 +1,56 @@
+package booking
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLagTracker(t *testing.T) {
+	l := &lagTracker{bucketCounts: make([]uint64, len(LagBuckets))}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	first := now.Add(-10 * time.Second)
+	second := now.Add(-2 * time.Second)
+	l.Received(first)
+	l.Received(second)
+
+	stats := l.Stats(now)
+	if stats.QueueDepth != 2 {
+		t.Errorf("expected queue depth 2, got %d", stats.QueueDepth)
+	}
+	if stats.OldestPendingAge != 10*time.Second {
+		t.Errorf("expected oldest pending age 10s, got %v", stats.OldestPendingAge)
+	}
+
+	l.Processed(first, now)
+	stats = l.Stats(now)
+	if stats.QueueDepth != 1 || stats.OldestPendingAge != 2*time.Second {
+		t.Errorf("expected one event pending for 2s, got %d pending for %v", stats.QueueDepth, stats.OldestPendingAge)
+	}
+	if stats.Count != 1 || stats.Sum != 10*time.Second {
+		t.Errorf("expected one event with 10s lag, got %d with %v", stats.Count, stats.Sum)
+	}
+	for i, bound := range LagBuckets {
+		want := uint64(0)
+		if bound >= 10*time.Second {
+			want = 1
+		}
+		if stats.BucketCounts[i] != want {
+			t.Errorf("bucket %v: expected %d, got %d", bound, want, stats.BucketCounts[i])
+		}
+	}
+
+	var sb strings.Builder
+	writeMetrics(&sb, stats)
+	for _, line := range []string{
+		`ews_booking_event_lag_seconds_bucket{le="5"} 0`,
+		`ews_booking_event_lag_seconds_bucket{le="15"} 1`,
+		`ews_booking_event_lag_seconds_bucket{le="+Inf"} 1`,
+		`ews_booking_event_queue_depth 1`,
+		`ews_booking_event_oldest_pending_age_seconds 2`,
+	} {
+		if !strings.Contains(sb.String(), line+"\n") {
+			t.Errorf("expected metrics to contain %q, got:\n%s", line, sb.String())
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+	"github.com/gorilla/websocket"
+)
+
+// CloseAction is what the booking listener does when the subscription ends.
+type CloseAction string
+
+const (
+	// CloseReconnect resubscribes immediately.
+	CloseReconnect CloseAction = "reconnect"
+	// CloseBackoff resubscribes after a delay, doubled on every consecutive
+	// close up to maxResubscribeBackoff.
+	CloseBackoff CloseAction = "backoff"
+	// CloseStop ends listening until the subscription is restarted, e.g.
+	// after the configuration changed.
+	CloseStop CloseAction = "stop"
+)
+
+// DefaultClosePolicy maps websocket close codes to actions, unless
+// BOOKING_CLOSE_POLICY overrides them. Codes not listed, and connections lost
+// without a close frame, are backed off.
+var DefaultClosePolicy = map[int]CloseAction{
+	websocket.CloseNormalClosure:   CloseReconnect,
+	websocket.CloseGoingAway:       CloseBackoff,
+	websocket.ClosePolicyViolation: CloseStop,
+	websocket.CloseServiceRestart:  CloseBackoff,
+	websocket.CloseTryAgainLater:   CloseBackoff,
+}
+
+var (
+	minResubscribeBackoff = time.Second
+	maxResubscribeBackoff = 5 * time.Minute
+)
+
+// closePolicy is the policy used by the booking listener.
+var closePolicy = loadClosePolicy()
+
+func loadClosePolicy() map[int]CloseAction {
+	policy, err := ParseClosePolicy(common.Getenv("BOOKING_CLOSE_POLICY", ""))
+	if err != nil {
+		log.Warn("eliona-booking", "invalid BOOKING_CLOSE_POLICY, using the default: %v", err)
+		return DefaultClosePolicy
+	}
+	return policy
+}
+
+// ParseClosePolicy parses comma-separated code=action pairs, e.g.
+// "1001=reconnect,4001=stop", overriding the DefaultClosePolicy.
+func ParseClosePolicy(s string) (map[int]CloseAction, error) {
+	policy := make(map[int]CloseAction, len(DefaultClosePolicy))
+	for code, action := range DefaultClosePolicy {
+		policy[code] = action
+	}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		codeString, actionString, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not code=action", pair)
+		}
+		code, err := strconv.Atoi(strings.TrimSpace(codeString))
+		if err != nil {
+			return nil, fmt.Errorf("invalid close code %q", codeString)
+		}
+		switch action := CloseAction(strings.TrimSpace(actionString)); action {
+		case CloseReconnect, CloseBackoff, CloseStop:
+			policy[code] = action
+		default:
+			return nil, fmt.Errorf("invalid action %q for close code %d", actionString, code)
+		}
+	}
+	return policy, nil
+}
+
+// closeActionFor looks up the action for the error ending the subscription.
+func closeActionFor(err error, policy map[int]CloseAction) CloseAction {
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) {
+		log.Error("eliona-booking", "Error reading from WebSocket: %v", err)
+		return CloseBackoff
+	}
+	action, ok := policy[closeErr.Code]
+	if !ok {
+		action = CloseBackoff
+	}
+	log.Warn("eliona-booking", "Subscription closed with code %d (%s): %s", closeErr.Code, closeErr.Text, action)
+	return action
+}
+
+// sleep waits for the duration, returning false if the context ends first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
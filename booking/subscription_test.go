@@ -0,0 +1,131 @@
+package booking
+
+import (
+	"context"
+	syncmodel "ews/model/sync"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// closingServer accepts subscriptions and closes the n-th one with the n-th
+// close code, sending a booking group first. Subscriptions beyond the codes
+// stay open.
+func closingServer(t *testing.T, codes []int) (*httptest.Server, *atomic.Int32) {
+	var subscriptions atomic.Int32
+	var upgrader websocket.Upgrader
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrading: %v", err)
+			return
+		}
+		defer conn.Close()
+		var request BookingsSubscriptionRequest
+		if err := conn.ReadJSON(&request); err != nil {
+			t.Errorf("reading subscription request: %v", err)
+			return
+		}
+		n := int(subscriptions.Add(1))
+		if err := conn.WriteJSON(BookingGroup{Id: int32(n)}); err != nil {
+			t.Errorf("writing booking: %v", err)
+			return
+		}
+		if n > len(codes) {
+			// Wait for the client to go away.
+			conn.ReadMessage()
+			return
+		}
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(codes[n-1], "closing"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &subscriptions
+}
+
+func listen(t *testing.T, server *httptest.Server) (*client, <-chan syncmodel.BookingGroup) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c := NewClient(server.URL, syncmodel.BookingClock{})
+	c.closePolicy = DefaultClosePolicy
+	c.coalesceWindow = 0
+	bookings, err := c.ListenForBookings(ctx, []int{1})
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	return c, bookings
+}
+
+func receive(t *testing.T, bookings <-chan syncmodel.BookingGroup) (syncmodel.BookingGroup, bool) {
+	select {
+	case group, ok := <-bookings:
+		return group, ok
+	case <-time.After(5 * time.Second):
+		t.Fatal("no booking received")
+		return syncmodel.BookingGroup{}, false
+	}
+}
+
+func TestListenForBookingsResubscribesOnClose(t *testing.T) {
+	// Reset after the listener is done, which is when the server closed.
+	t.Cleanup(func() { minResubscribeBackoff = time.Second })
+	minResubscribeBackoff = time.Millisecond
+	server, subscriptions := closingServer(t, []int{websocket.CloseNormalClosure, websocket.CloseGoingAway, 4999})
+	c, bookings := listen(t, server)
+
+	for want := int32(1); want <= 4; want++ {
+		group, ok := receive(t, bookings)
+		if !ok {
+			t.Fatalf("listener stopped after %d subscriptions: %v", subscriptions.Load(), c.StopErr())
+		}
+		if group.ElionaID != want {
+			t.Errorf("got booking %d, want %d", group.ElionaID, want)
+		}
+	}
+}
+
+func TestListenForBookingsStopsOnPolicyViolation(t *testing.T) {
+	server, subscriptions := closingServer(t, []int{websocket.ClosePolicyViolation})
+	c, bookings := listen(t, server)
+
+	if _, ok := receive(t, bookings); !ok {
+		t.Fatal("listener stopped before the first booking")
+	}
+	if _, ok := receive(t, bookings); ok {
+		t.Fatal("listener did not stop")
+	}
+	if !websocket.IsCloseError(c.StopErr(), websocket.ClosePolicyViolation) {
+		t.Errorf("got stop error %v, want policy violation", c.StopErr())
+	}
+	if n := subscriptions.Load(); n != 1 {
+		t.Errorf("got %d subscriptions, want 1", n)
+	}
+}
+
+func TestParseClosePolicy(t *testing.T) {
+	policy, err := ParseClosePolicy(" 1001=reconnect, 4001=stop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for code, want := range map[int]CloseAction{
+		websocket.CloseNormalClosure:   CloseReconnect,
+		websocket.CloseGoingAway:       CloseReconnect,
+		websocket.ClosePolicyViolation: CloseStop,
+		4001:                           CloseStop,
+	} {
+		if policy[code] != want {
+			t.Errorf("code %d: got %q, want %q", code, policy[code], want)
+		}
+	}
+	if DefaultClosePolicy[websocket.CloseGoingAway] != CloseBackoff {
+		t.Error("parsing changed the default policy")
+	}
+	for _, invalid := range []string{"1000", "x=stop", "1000=retry"} {
+		if _, err := ParseClosePolicy(invalid); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+alter table ews.configuration add column if not exists approval_room_upns text[];
+alter table ews.configuration add column if not exists requests_per_minute integer not null default 300;
+alter table ews.configuration add column if not exists decline_grace_period integer not null default 30;
+alter table ews.configuration add column if not exists overlap_policy text not null default 'exclusive';
+alter table ews.configuration add column if not exists free_busy_status text not null default 'Busy';
+alter table ews.configuration add column if not exists read_service_user_upn text not null default '';
+alter table ews.configuration add column if not exists write_service_user_upn text not null default '';
+alter table ews.configuration add column if not exists decline_policy text not null default 'cancelAll';
+alter table ews.configuration add column if not exists working_hours json;
+alter table ews.configuration add column if not exists room_working_hours json;
+alter table ews.configuration add column if not exists read_only boolean not null default false;
+alter table ews.configuration add column if not exists import_overlap_policy text not null default 'import';
+alter table ews.configuration add column if not exists cancel_policy text not null default 'cancel';
+alter table ews.configuration add column if not exists room_names json;
+alter table ews.configuration add column if not exists room_name_rules json;
+alter table ews.configuration add column if not exists empty_booking_policy text not null default 'cancel';
+alter table ews.configuration add column if not exists response_poll_interval integer not null default 0;
+alter table ews.configuration add column if not exists booking_time_zone text not null default '';
+alter table ews.configuration add column if not exists invitation_failure_limit integer not null default 0;
+
+alter table ews.booking_group add column if not exists state text not null default 'confirmed';
+alter table ews.booking_group add column if not exists configuration_id bigint;
+alter table ews.booking_group add column if not exists pending_since timestamp with time zone;
+
+create table if not exists ews.audit_log
+-- Mutation performed by the app in Exchange, kept for compliance.
+(
+	id               bigserial primary key,
+	configuration_id bigint      not null,
+	created_at       timestamptz not null default now(),
+	action           text        not null, -- 'create', 'cancel', 'cancelOccurrence', 'updateAttendees', 'declineAsResource', 'resourceSubject' or 'reschedule'
+	organizer        text        not null default '',
+	rooms            text[],
+	exchange_uid     text        not null default '',
+	start_time       timestamptz,
+	end_time         timestamptz,
+	correlation_id   text        not null default '', -- Identifies the booking event the mutation was performed for; sent to Exchange as client-request-id.
+	error            text -- Null if the mutation succeeded.
+);
+
+create index if not exists audit_log_configuration_id_created_at on ews.audit_log (configuration_id, created_at);
+alter table ews.configuration add column if not exists missing_room_email_policy text not null default 'skip';
+alter table ews.configuration add column if not exists subject_fallback text not null default '';
+alter table ews.configuration add column if not exists max_attendees_per_request integer not null default 0;
+alter table ews.configuration add column if not exists room_discovery text not null default 'roomList';
+alter table ews.configuration add column if not exists room_address_list_id text not null default '';
+alter table ews.configuration add column if not exists room_emails text[];
+alter table ews.configuration add column if not exists export_imports boolean not null default false;
+alter table ews.configuration add column if not exists reminder_minutes integer;
+alter table ews.configuration add column if not exists self_organized_policy text not null default 'unattributed';
+alter table ews.configuration add column if not exists block_policy text not null default 'import';
+alter table ews.configuration add column if not exists client_secret_expires_at timestamp with time zone;
+alter table ews.configuration add column if not exists resource_subject text not null default '';
+alter table ews.asset add column if not exists synced_at timestamp with time zone;
+alter table ews.configuration add column if not exists unknown_delete_policy text not null default 'log';
+alter table ews.asset add column if not exists subscription_id text not null default '';
+alter table ews.asset add column if not exists watermark text not null default '';
+alter table ews.configuration add column if not exists change_detection text not null default 'polling';
+alter table ews.booking_group add column if not exists subject text not null default '';
+alter table ews.booking_occurrence add column if not exists location text not null default '';
+alter table ews.configuration add column if not exists mirror_private_details boolean not null default false;
+alter table ews.configuration add column if not exists recurrence_horizon_days integer not null default 365;
+alter table ews.asset add column if not exists expanded_until timestamp with time zone;
+alter table ews.configuration add column if not exists invitation_processing_timeout integer not null default 15;
+alter table ews.configuration add column if not exists cancellation_body text not null default '';
+alter table ews.configuration add column if not exists appointment_category text not null default '';
+alter table ews.configuration add column if not exists online_meetings boolean not null default false;
+alter table ews.configuration add column if not exists cloud text not null default 'commercial';
+alter table ews.configuration add column if not exists proxy_url text not null default '';
+alter table ews.configuration add column if not exists tls_ca_cert_pem text not null default '';
+alter table ews.configuration add column if not exists insecure_skip_verify boolean not null default false;
+alter table ews.configuration add column if not exists room_list_upns text[];
+alter table ews.configuration add column if not exists archive_removed_rooms boolean not null default false;
+alter table ews.asset add column if not exists archived_at timestamp with time zone;
+alter table ews.asset add column if not exists name text not null default '';
+alter table ews.configuration add column if not exists room_capacities json;
+alter table ews.asset add column if not exists data_digest text not null default '';
+alter table ews.configuration add column if not exists room_location_pattern text not null default '';
+alter table ews.configuration add column if not exists equipment_emails text[];
+
+create table if not exists ews.resolved_address
+-- Distinguished name resolved to an SMTP address, caching ResolveNames across restarts.
+(
+	id               bigserial   primary key,
+	configuration_id bigint      not null,
+	dn               text        not null,
+	smtp             text        not null,
+	resolved_at      timestamptz not null default now(), -- Addresses resolved longer ago than their time to live are resolved again.
+	unique (configuration_id, dn)
+);
+
+create table if not exists ews.room_item
+-- Item in a room's calendar without room booking of its own, so that its deletion isn't mistaken for one of an unknown item.
+(
+	id           bigserial primary key,
+	asset_id     bigint not null,
+	exchange_id  text   not null unique, -- Always from the resource's perspective
+	exchange_uid text   not null default '' -- UID of the series the item is the recurring master of; empty for items skipped by policy.
+);
+alter table ews.configuration add column if not exists building_working_hours json;
@@ -17,6 +17,7 @@ package conf
 
 import (
 	"context"
+	"crypto/x509"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -24,6 +25,9 @@ import (
 	"ews/appdb"
 	syncmodel "ews/model/sync"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/eliona-smart-building-assistant/go-eliona/frontend"
 	"github.com/eliona-smart-building-assistant/go-utils/common"
@@ -36,10 +40,45 @@ import (
 var ErrBadRequest = errors.New("bad request")
 var ErrNotFound = errors.New("not found")
 
+// FieldError rejects a configuration because of one of its fields.
+type FieldError struct {
+	// Field is the JSON name of the offending field.
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Is makes field errors bad requests.
+func (e *FieldError) Is(target error) bool {
+	return target == ErrBadRequest
+}
+
+func fieldError(field, format string, args ...any) error {
+	return &FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// States of a booking group. Groups imported from Exchange are confirmed, or
+// occupancy only.
+const (
+	BookingStateConfirmed = "confirmed"
+	// BookingStatePendingApproval marks bookings of rooms waiting for a delegate to respond.
+	BookingStatePendingApproval = "pending_approval"
+	// BookingStatePendingResponse marks bookings created without waiting for
+	// the rooms to process the invitation.
+	BookingStatePendingResponse = "pending_response"
+	BookingStateDeclined        = "declined"
+	// BookingStateOccupancyOnly marks imported bookings whose changes in
+	// Eliona are not written back to Exchange.
+	BookingStateOccupancyOnly = "occupancy_only"
+)
+
 func InsertConfig(ctx context.Context, config apiserver.Configuration) (apiserver.Configuration, error) {
 	dbConfig, err := dbConfigFromApiConfig(ctx, config)
 	if err != nil {
-		return apiserver.Configuration{}, fmt.Errorf("creating DB config from API config: %v", err)
+		return apiserver.Configuration{}, fmt.Errorf("creating DB config from API config: %w", err)
 	}
 	if err := dbConfig.InsertG(ctx, boil.Infer()); err != nil {
 		return apiserver.Configuration{}, fmt.Errorf("inserting DB config: %v", err)
@@ -50,7 +89,7 @@ func InsertConfig(ctx context.Context, config apiserver.Configuration) (apiserve
 func UpsertConfig(ctx context.Context, config apiserver.Configuration) (apiserver.Configuration, error) {
 	dbConfig, err := dbConfigFromApiConfig(ctx, config)
 	if err != nil {
-		return apiserver.Configuration{}, fmt.Errorf("creating DB config from API config: %v", err)
+		return apiserver.Configuration{}, fmt.Errorf("creating DB config from API config: %w", err)
 	}
 	if err := dbConfig.UpsertG(ctx, true, []string{"id"}, boil.Blacklist("id"), boil.Infer()); err != nil {
 		return apiserver.Configuration{}, fmt.Errorf("inserting DB config: %v", err)
@@ -68,6 +107,7 @@ func GetConfig(ctx context.Context, configID int64) (*apiserver.Configuration, e
 	if err != nil {
 		return nil, fmt.Errorf("fetching config from database: %v", err)
 	}
+	encryptStoredSecrets(ctx, dbConfig)
 	apiConfig, err := apiConfigFromDbConfig(dbConfig)
 	if err != nil {
 		return nil, fmt.Errorf("creating API config from DB config: %v", err)
@@ -75,15 +115,51 @@ func GetConfig(ctx context.Context, configID int64) (*apiserver.Configuration, e
 	return &apiConfig, nil
 }
 
+// WithTx runs fn in a transaction. The transaction is committed if fn
+// succeeds, and rolled back otherwise.
+func WithTx(ctx context.Context, fn func(tx boil.ContextExecutor) error) error {
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %v", err)
+	}
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Error("conf", "rolling back transaction: %v", rbErr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %v", err)
+	}
+	return nil
+}
+
 func DeleteConfig(ctx context.Context, configID int64) error {
+	return WithTx(ctx, func(tx boil.ContextExecutor) error {
+		return DeleteConfigTx(ctx, tx, configID)
+	})
+}
+
+// DeleteConfigTx deletes the config and its assets using the given executor.
+func DeleteConfigTx(ctx context.Context, exec boil.ContextExecutor, configID int64) error {
+	if _, err := appdb.RoomItems(
+		qm.Where(appdb.RoomItemColumns.AssetID+" in (select id from ews.asset where configuration_id = ?)", configID),
+	).DeleteAll(ctx, exec); err != nil {
+		return fmt.Errorf("deleting room items from database: %v", err)
+	}
 	if _, err := appdb.Assets(
 		appdb.AssetWhere.ConfigurationID.EQ(configID),
-	).DeleteAllG(ctx); err != nil {
+	).DeleteAll(ctx, exec); err != nil {
 		return fmt.Errorf("deleting assets from database: %v", err)
 	}
+	if _, err := appdb.ResolvedAddresses(
+		appdb.ResolvedAddressWhere.ConfigurationID.EQ(configID),
+	).DeleteAll(ctx, exec); err != nil {
+		return fmt.Errorf("deleting resolved addresses from database: %v", err)
+	}
 	count, err := appdb.Configurations(
 		appdb.ConfigurationWhere.ID.EQ(configID),
-	).DeleteAllG(ctx)
+	).DeleteAll(ctx, exec)
 	if err != nil {
 		return fmt.Errorf("deleting config from database: %v", err)
 	}
@@ -97,18 +173,26 @@ func DeleteConfig(ctx context.Context, configID int64) error {
 }
 
 func dbConfigFromApiConfig(ctx context.Context, apiConfig apiserver.Configuration) (dbConfig appdb.Configuration, err error) {
-	if !((apiConfig.ClientId != nil && apiConfig.ClientSecret != nil && apiConfig.TenantId != nil) || (apiConfig.EwsURL != nil && apiConfig.Username != nil && apiConfig.Password != nil)) {
-		return appdb.Configuration{}, fmt.Errorf("configure either OAuth or NTLM credentials")
+	if err := ValidateConnection(apiConfig); err != nil {
+		return appdb.Configuration{}, err
 	}
 	if apiConfig.ClientId != nil {
 		dbConfig.ClientID = *apiConfig.ClientId
 	}
 	if apiConfig.ClientSecret != nil {
-		dbConfig.ClientSecret = *apiConfig.ClientSecret
+		if dbConfig.ClientSecret, err = encryptSecret(*apiConfig.ClientSecret); err != nil {
+			return appdb.Configuration{}, fmt.Errorf("encrypting client secret: %v", err)
+		}
 	}
+	dbConfig.ClientSecretExpiresAt = null.TimeFromPtr(apiConfig.ClientSecretExpiresAt)
 	if apiConfig.TenantId != nil {
 		dbConfig.TenantID = *apiConfig.TenantId
 	}
+	cloud, err := syncmodel.ParseCloud(common.Val(apiConfig.Cloud))
+	if err != nil {
+		return appdb.Configuration{}, fieldError("cloud", "%v", err)
+	}
+	dbConfig.Cloud = string(cloud)
 
 	if apiConfig.EwsURL != nil {
 		dbConfig.EwsURL = *apiConfig.EwsURL
@@ -117,28 +201,239 @@ func dbConfigFromApiConfig(ctx context.Context, apiConfig apiserver.Configuratio
 		dbConfig.Username = *apiConfig.Username
 	}
 	if apiConfig.Password != nil {
-		dbConfig.Password = *apiConfig.Password
+		if dbConfig.Password, err = encryptSecret(*apiConfig.Password); err != nil {
+			return appdb.Configuration{}, fmt.Errorf("encrypting password: %v", err)
+		}
 	}
 
-	if apiConfig.ServiceUserUPN == nil {
-		return appdb.Configuration{}, fmt.Errorf("config is missing ServiceUserUPN")
+	dbConfig.ServiceUserUpn = common.Val(apiConfig.ServiceUserUPN)
+	dbConfig.ReadServiceUserUpn = common.Val(apiConfig.ReadServiceUserUPN)
+	dbConfig.WriteServiceUserUpn = common.Val(apiConfig.WriteServiceUserUPN)
+	roomDiscovery, err := syncmodel.ParseRoomDiscovery(common.Val(apiConfig.RoomDiscovery))
+	if err != nil {
+		return appdb.Configuration{}, fieldError("roomDiscovery", "%v", err)
 	}
-	dbConfig.ServiceUserUpn = *apiConfig.ServiceUserUPN
-	if apiConfig.RoomListUPN == nil {
-		return appdb.Configuration{}, fmt.Errorf("config is missing RoomListUPN")
+	dbConfig.RoomDiscovery = string(roomDiscovery)
+	dbConfig.RoomAddressListID = common.Val(apiConfig.RoomAddressListID)
+	if apiConfig.RoomEmails != nil {
+		dbConfig.RoomEmails = *apiConfig.RoomEmails
 	}
-	dbConfig.RoomListUpn = *apiConfig.RoomListUPN
-	if apiConfig.BookingAppURL == nil {
-		return appdb.Configuration{}, fmt.Errorf("config is missing BookingAppURL")
+	if apiConfig.EquipmentEmails != nil {
+		dbConfig.EquipmentEmails = *apiConfig.EquipmentEmails
+	}
+	switch roomDiscovery {
+	case syncmodel.RoomDiscoveryRoomList:
+		if apiConfig.RoomListUPN == nil && apiConfig.RoomListUPNs == nil {
+			return appdb.Configuration{}, fieldError("roomListUPNs", "required for roomList discovery")
+		}
+	case syncmodel.RoomDiscoveryAddressList:
+		if dbConfig.RoomAddressListID == "" {
+			return appdb.Configuration{}, fieldError("roomAddressListID", "required for addressList discovery")
+		} else if !validGUID(dbConfig.RoomAddressListID) {
+			return appdb.Configuration{}, fieldError("roomAddressListID", "not a GUID")
+		}
+	case syncmodel.RoomDiscoveryStatic:
+		if len(dbConfig.RoomEmails) == 0 {
+			return appdb.Configuration{}, fieldError("roomEmails", "required for static discovery")
+		}
+	}
+	dbConfig.RoomListUpns = RoomListUPNs(apiConfig)
+	if len(dbConfig.RoomListUpns) > 0 {
+		dbConfig.RoomListUpn = dbConfig.RoomListUpns[0]
 	}
 	dbConfig.BookingAppURL = *apiConfig.BookingAppURL
 
 	dbConfig.ID = null.Int64FromPtr(apiConfig.Id).Int64
 	dbConfig.Enable = null.BoolFromPtr(apiConfig.Enable)
+	dbConfig.ReadOnly = common.Val(apiConfig.ReadOnly)
+	dbConfig.ExportImports = common.Val(apiConfig.ExportImports)
+	dbConfig.ArchiveRemovedRooms = common.Val(apiConfig.ArchiveRemovedRooms)
+	dbConfig.MirrorPrivateDetails = common.Val(apiConfig.MirrorPrivateDetails)
 	dbConfig.RefreshInterval = apiConfig.RefreshInterval
 	if apiConfig.RequestTimeout != nil {
 		dbConfig.RequestTimeout = *apiConfig.RequestTimeout
 	}
+	// The proxy URL may carry the credentials of the proxy.
+	if dbConfig.ProxyURL, err = encryptSecret(common.Val(apiConfig.ProxyURL)); err != nil {
+		return appdb.Configuration{}, fmt.Errorf("encrypting proxy URL: %v", err)
+	}
+	dbConfig.TLSCaCertPem = common.Val(apiConfig.TLSCACertPEM)
+	dbConfig.InsecureSkipVerify = common.Val(apiConfig.InsecureSkipVerify)
+	if apiConfig.RequestsPerMinute != nil {
+		dbConfig.RequestsPerMinute = *apiConfig.RequestsPerMinute
+	}
+	if apiConfig.DeclineGracePeriod != nil {
+		dbConfig.DeclineGracePeriod = *apiConfig.DeclineGracePeriod
+	}
+	dbConfig.InvitationProcessingTimeout = DefaultInvitationProcessingTimeout
+	if apiConfig.InvitationProcessingTimeout != nil {
+		if *apiConfig.InvitationProcessingTimeout < 0 {
+			return appdb.Configuration{}, fieldError("invitationProcessingTimeout", "%d is negative", *apiConfig.InvitationProcessingTimeout)
+		}
+		dbConfig.InvitationProcessingTimeout = *apiConfig.InvitationProcessingTimeout
+	}
+	if apiConfig.ResponsePollInterval != nil {
+		if *apiConfig.ResponsePollInterval < 0 {
+			return appdb.Configuration{}, fieldError("responsePollInterval", "%d is negative", *apiConfig.ResponsePollInterval)
+		}
+		dbConfig.ResponsePollInterval = *apiConfig.ResponsePollInterval
+	}
+	if apiConfig.MaxAttendeesPerRequest != nil {
+		if *apiConfig.MaxAttendeesPerRequest < 0 {
+			return appdb.Configuration{}, fieldError("maxAttendeesPerRequest", "%d is negative", *apiConfig.MaxAttendeesPerRequest)
+		}
+		dbConfig.MaxAttendeesPerRequest = *apiConfig.MaxAttendeesPerRequest
+	}
+	dbConfig.RecurrenceHorizonDays = DefaultRecurrenceHorizonDays
+	if apiConfig.RecurrenceHorizonDays != nil {
+		if *apiConfig.RecurrenceHorizonDays <= 0 {
+			return appdb.Configuration{}, fieldError("recurrenceHorizonDays", "%d is not positive", *apiConfig.RecurrenceHorizonDays)
+		}
+		dbConfig.RecurrenceHorizonDays = *apiConfig.RecurrenceHorizonDays
+	}
+	if apiConfig.ReminderMinutes != nil && *apiConfig.ReminderMinutes < 0 {
+		return appdb.Configuration{}, fieldError("reminderMinutes", "%d is negative", *apiConfig.ReminderMinutes)
+	}
+	dbConfig.ReminderMinutes = null.Int32FromPtr(apiConfig.ReminderMinutes)
+	if apiConfig.InvitationFailureLimit != nil {
+		if *apiConfig.InvitationFailureLimit < 0 {
+			return appdb.Configuration{}, fieldError("invitationFailureLimit", "%d is negative", *apiConfig.InvitationFailureLimit)
+		}
+		dbConfig.InvitationFailureLimit = *apiConfig.InvitationFailureLimit
+	}
+	dbConfig.OverlapPolicy = string(syncmodel.OverlapExclusive)
+	if apiConfig.OverlapPolicy != nil && *apiConfig.OverlapPolicy != "" {
+		policy := syncmodel.OverlapPolicy(*apiConfig.OverlapPolicy)
+		if policy != syncmodel.OverlapExclusive && policy != syncmodel.OverlapInclusive {
+			return appdb.Configuration{}, fieldError("overlapPolicy", "unknown policy %q", policy)
+		}
+		dbConfig.OverlapPolicy = string(policy)
+	}
+	dbConfig.FreeBusyStatus, err = syncmodel.ParseFreeBusyStatus(common.Val(apiConfig.FreeBusyStatus))
+	if err != nil {
+		return appdb.Configuration{}, fieldError("freeBusyStatus", "%v", err)
+	}
+	declinePolicy, err := syncmodel.ParseDeclinePolicy(common.Val(apiConfig.DeclinePolicy))
+	if err != nil {
+		return appdb.Configuration{}, fieldError("declinePolicy", "%v", err)
+	}
+	dbConfig.DeclinePolicy = string(declinePolicy)
+	importOverlapPolicy, err := syncmodel.ParseImportOverlapPolicy(common.Val(apiConfig.ImportOverlapPolicy))
+	if err != nil {
+		return appdb.Configuration{}, fieldError("importOverlapPolicy", "%v", err)
+	}
+	dbConfig.ImportOverlapPolicy = string(importOverlapPolicy)
+	cancelPolicy, err := syncmodel.ParseCancelPolicy(common.Val(apiConfig.CancelPolicy))
+	if err != nil {
+		return appdb.Configuration{}, fieldError("cancelPolicy", "%v", err)
+	}
+	dbConfig.CancelPolicy = string(cancelPolicy)
+	emptyBookingPolicy, err := syncmodel.ParseEmptyBookingPolicy(common.Val(apiConfig.EmptyBookingPolicy))
+	if err != nil {
+		return appdb.Configuration{}, fieldError("emptyBookingPolicy", "%v", err)
+	}
+	dbConfig.EmptyBookingPolicy = string(emptyBookingPolicy)
+	missingRoomEmailPolicy, err := syncmodel.ParseMissingRoomEmailPolicy(common.Val(apiConfig.MissingRoomEmailPolicy))
+	if err != nil {
+		return appdb.Configuration{}, fieldError("missingRoomEmailPolicy", "%v", err)
+	}
+	dbConfig.MissingRoomEmailPolicy = string(missingRoomEmailPolicy)
+	selfOrganizedPolicy, err := syncmodel.ParseSelfOrganizedPolicy(common.Val(apiConfig.SelfOrganizedPolicy))
+	if err != nil {
+		return appdb.Configuration{}, fieldError("selfOrganizedPolicy", "%v", err)
+	}
+	dbConfig.SelfOrganizedPolicy = string(selfOrganizedPolicy)
+	blockPolicy, err := syncmodel.ParseBlockPolicy(common.Val(apiConfig.BlockPolicy))
+	if err != nil {
+		return appdb.Configuration{}, fieldError("blockPolicy", "%v", err)
+	}
+	dbConfig.BlockPolicy = string(blockPolicy)
+	unknownDeletePolicy, err := syncmodel.ParseUnknownDeletePolicy(common.Val(apiConfig.UnknownDeletePolicy))
+	if err != nil {
+		return appdb.Configuration{}, fieldError("unknownDeletePolicy", "%v", err)
+	}
+	dbConfig.UnknownDeletePolicy = string(unknownDeletePolicy)
+	changeDetection, err := syncmodel.ParseChangeDetection(common.Val(apiConfig.ChangeDetection))
+	if err != nil {
+		return appdb.Configuration{}, fieldError("changeDetection", "%v", err)
+	}
+	dbConfig.ChangeDetection = string(changeDetection)
+	if _, err := syncmodel.ParseBookingClock(common.Val(apiConfig.BookingTimeZone)); err != nil {
+		return appdb.Configuration{}, fieldError("bookingTimeZone", "%v", err)
+	}
+	dbConfig.BookingTimeZone = common.Val(apiConfig.BookingTimeZone)
+	dbConfig.SubjectFallback = common.Val(apiConfig.SubjectFallback)
+	dbConfig.ResourceSubject = common.Val(apiConfig.ResourceSubject)
+	dbConfig.CancellationBody = common.Val(apiConfig.CancellationBody)
+	dbConfig.AppointmentCategory = strings.TrimSpace(common.Val(apiConfig.AppointmentCategory))
+	dbConfig.OnlineMeetings = common.Val(apiConfig.OnlineMeetings)
+	if apiConfig.WorkingHours != nil {
+		if _, err := parseWorkingHours(*apiConfig.WorkingHours); err != nil {
+			return appdb.Configuration{}, fieldError("workingHours", "%v", err)
+		}
+		wh, err := json.Marshal(apiConfig.WorkingHours)
+		if err != nil {
+			return appdb.Configuration{}, fmt.Errorf("marshalling workingHours: %v", err)
+		}
+		dbConfig.WorkingHours = null.JSONFrom(wh)
+	}
+	if apiConfig.RoomNames != nil {
+		rn, err := json.Marshal(apiConfig.RoomNames)
+		if err != nil {
+			return appdb.Configuration{}, fmt.Errorf("marshalling roomNames: %v", err)
+		}
+		dbConfig.RoomNames = null.JSONFrom(rn)
+	}
+	if apiConfig.RoomCapacities != nil {
+		for room, capacity := range apiConfig.RoomCapacities {
+			if capacity < 0 {
+				return appdb.Configuration{}, fieldError("roomCapacities", "capacity %d of %s is negative", capacity, room)
+			}
+		}
+		rc, err := json.Marshal(apiConfig.RoomCapacities)
+		if err != nil {
+			return appdb.Configuration{}, fmt.Errorf("marshalling roomCapacities: %v", err)
+		}
+		dbConfig.RoomCapacities = null.JSONFrom(rc)
+	}
+	if _, err := syncmodel.ParseRoomLocationPattern(common.Val(apiConfig.RoomLocationPattern)); err != nil {
+		return appdb.Configuration{}, fieldError("roomLocationPattern", "%v", err)
+	}
+	dbConfig.RoomLocationPattern = common.Val(apiConfig.RoomLocationPattern)
+	if apiConfig.RoomNameRules != nil {
+		if _, err := parseRoomNameRules(apiConfig.RoomNameRules); err != nil {
+			return appdb.Configuration{}, fieldError("roomNameRules", "%v", err)
+		}
+		rnr, err := json.Marshal(apiConfig.RoomNameRules)
+		if err != nil {
+			return appdb.Configuration{}, fmt.Errorf("marshalling roomNameRules: %v", err)
+		}
+		dbConfig.RoomNameRules = null.JSONFrom(rnr)
+	}
+	if apiConfig.RoomWorkingHours != nil {
+		for room, hours := range apiConfig.RoomWorkingHours {
+			if _, err := parseWorkingHours(hours); err != nil {
+				return appdb.Configuration{}, fieldError("roomWorkingHours", "%s: %v", room, err)
+			}
+		}
+		rwh, err := json.Marshal(apiConfig.RoomWorkingHours)
+		if err != nil {
+			return appdb.Configuration{}, fmt.Errorf("marshalling roomWorkingHours: %v", err)
+		}
+		dbConfig.RoomWorkingHours = null.JSONFrom(rwh)
+	}
+	if apiConfig.BuildingWorkingHours != nil {
+		for building, hours := range apiConfig.BuildingWorkingHours {
+			if _, err := parseWorkingHours(hours); err != nil {
+				return appdb.Configuration{}, fieldError("buildingWorkingHours", "%s: %v", building, err)
+			}
+		}
+		bwh, err := json.Marshal(apiConfig.BuildingWorkingHours)
+		if err != nil {
+			return appdb.Configuration{}, fmt.Errorf("marshalling buildingWorkingHours: %v", err)
+		}
+		dbConfig.BuildingWorkingHours = null.JSONFrom(bwh)
+	}
 	af, err := json.Marshal(apiConfig.AssetFilter)
 	if err != nil {
 		return appdb.Configuration{}, fmt.Errorf("marshalling assetFilter: %v", err)
@@ -148,6 +443,9 @@ func dbConfigFromApiConfig(ctx context.Context, apiConfig apiserver.Configuratio
 	if apiConfig.ProjectIDs != nil {
 		dbConfig.ProjectIds = *apiConfig.ProjectIDs
 	}
+	if apiConfig.ApprovalRoomUPNs != nil {
+		dbConfig.ApprovalRoomUpns = *apiConfig.ApprovalRoomUPNs
+	}
 
 	env := frontend.GetEnvironment(ctx)
 	if env != nil {
@@ -158,22 +456,122 @@ func dbConfigFromApiConfig(ctx context.Context, apiConfig apiserver.Configuratio
 }
 
 func apiConfigFromDbConfig(dbConfig *appdb.Configuration) (apiConfig apiserver.Configuration, err error) {
+	clientSecret, err := decryptSecret(dbConfig.ClientSecret)
+	if err != nil {
+		return apiserver.Configuration{}, fmt.Errorf("client secret: %v", err)
+	}
+	password, err := decryptSecret(dbConfig.Password)
+	if err != nil {
+		return apiserver.Configuration{}, fmt.Errorf("password: %v", err)
+	}
+	proxyURL, err := decryptSecret(dbConfig.ProxyURL)
+	if err != nil {
+		return apiserver.Configuration{}, fmt.Errorf("proxy URL: %v", err)
+	}
 	apiConfig.ClientId = &dbConfig.ClientID
-	apiConfig.ClientSecret = &dbConfig.ClientSecret
+	apiConfig.ClientSecret = &clientSecret
+	apiConfig.ClientSecretExpiresAt = dbConfig.ClientSecretExpiresAt.Ptr()
 	apiConfig.TenantId = &dbConfig.TenantID
+	apiConfig.Cloud = &dbConfig.Cloud
 
 	apiConfig.EwsURL = &dbConfig.EwsURL
 	apiConfig.Username = &dbConfig.Username
-	apiConfig.Password = &dbConfig.Password
+	apiConfig.Password = &password
 
 	apiConfig.ServiceUserUPN = &dbConfig.ServiceUserUpn
-	apiConfig.RoomListUPN = &dbConfig.RoomListUpn
+	apiConfig.ReadServiceUserUPN = &dbConfig.ReadServiceUserUpn
+	apiConfig.WriteServiceUserUPN = &dbConfig.WriteServiceUserUpn
+	// Rows stored before multiple room lists were supported only have the
+	// single one.
+	apiConfig.RoomListUPNs = common.Ptr(RoomListUPNs(apiserver.Configuration{
+		RoomListUPN:  &dbConfig.RoomListUpn,
+		RoomListUPNs: common.Ptr[[]string](dbConfig.RoomListUpns),
+	}))
+	apiConfig.RoomDiscovery = &dbConfig.RoomDiscovery
+	apiConfig.RoomAddressListID = &dbConfig.RoomAddressListID
+	apiConfig.RoomEmails = common.Ptr[[]string](dbConfig.RoomEmails)
+	apiConfig.EquipmentEmails = common.Ptr[[]string](dbConfig.EquipmentEmails)
 	apiConfig.BookingAppURL = &dbConfig.BookingAppURL
 
 	apiConfig.Id = &dbConfig.ID
 	apiConfig.Enable = dbConfig.Enable.Ptr()
+	apiConfig.ReadOnly = &dbConfig.ReadOnly
+	apiConfig.ExportImports = &dbConfig.ExportImports
+	apiConfig.ArchiveRemovedRooms = &dbConfig.ArchiveRemovedRooms
+	apiConfig.MirrorPrivateDetails = &dbConfig.MirrorPrivateDetails
 	apiConfig.RefreshInterval = dbConfig.RefreshInterval
 	apiConfig.RequestTimeout = &dbConfig.RequestTimeout
+	apiConfig.ProxyURL = &proxyURL
+	apiConfig.TLSCACertPEM = &dbConfig.TLSCaCertPem
+	apiConfig.InsecureSkipVerify = &dbConfig.InsecureSkipVerify
+	apiConfig.RequestsPerMinute = &dbConfig.RequestsPerMinute
+	apiConfig.DeclineGracePeriod = &dbConfig.DeclineGracePeriod
+	apiConfig.InvitationProcessingTimeout = &dbConfig.InvitationProcessingTimeout
+	apiConfig.ResponsePollInterval = &dbConfig.ResponsePollInterval
+	apiConfig.InvitationFailureLimit = &dbConfig.InvitationFailureLimit
+	apiConfig.MaxAttendeesPerRequest = &dbConfig.MaxAttendeesPerRequest
+	apiConfig.RecurrenceHorizonDays = &dbConfig.RecurrenceHorizonDays
+	apiConfig.ReminderMinutes = dbConfig.ReminderMinutes.Ptr()
+	apiConfig.OverlapPolicy = &dbConfig.OverlapPolicy
+	apiConfig.FreeBusyStatus = &dbConfig.FreeBusyStatus
+	apiConfig.DeclinePolicy = &dbConfig.DeclinePolicy
+	apiConfig.ImportOverlapPolicy = &dbConfig.ImportOverlapPolicy
+	apiConfig.CancelPolicy = &dbConfig.CancelPolicy
+	apiConfig.EmptyBookingPolicy = &dbConfig.EmptyBookingPolicy
+	apiConfig.MissingRoomEmailPolicy = &dbConfig.MissingRoomEmailPolicy
+	apiConfig.SelfOrganizedPolicy = &dbConfig.SelfOrganizedPolicy
+	apiConfig.BlockPolicy = &dbConfig.BlockPolicy
+	apiConfig.UnknownDeletePolicy = &dbConfig.UnknownDeletePolicy
+	apiConfig.ChangeDetection = &dbConfig.ChangeDetection
+	apiConfig.BookingTimeZone = &dbConfig.BookingTimeZone
+	apiConfig.SubjectFallback = &dbConfig.SubjectFallback
+	apiConfig.ResourceSubject = &dbConfig.ResourceSubject
+	apiConfig.CancellationBody = &dbConfig.CancellationBody
+	apiConfig.AppointmentCategory = &dbConfig.AppointmentCategory
+	apiConfig.OnlineMeetings = &dbConfig.OnlineMeetings
+	if dbConfig.WorkingHours.Valid {
+		var wh apiserver.WorkingHours
+		if err := json.Unmarshal(dbConfig.WorkingHours.JSON, &wh); err != nil {
+			return apiserver.Configuration{}, fmt.Errorf("unmarshalling workingHours: %v", err)
+		}
+		apiConfig.WorkingHours = &wh
+	}
+	if dbConfig.RoomNames.Valid {
+		var rn map[string]string
+		if err := json.Unmarshal(dbConfig.RoomNames.JSON, &rn); err != nil {
+			return apiserver.Configuration{}, fmt.Errorf("unmarshalling roomNames: %v", err)
+		}
+		apiConfig.RoomNames = rn
+	}
+	apiConfig.RoomLocationPattern = &dbConfig.RoomLocationPattern
+	if dbConfig.RoomCapacities.Valid {
+		var rc map[string]int32
+		if err := json.Unmarshal(dbConfig.RoomCapacities.JSON, &rc); err != nil {
+			return apiserver.Configuration{}, fmt.Errorf("unmarshalling roomCapacities: %v", err)
+		}
+		apiConfig.RoomCapacities = rc
+	}
+	if dbConfig.RoomNameRules.Valid {
+		var rnr []apiserver.RoomNameRule
+		if err := json.Unmarshal(dbConfig.RoomNameRules.JSON, &rnr); err != nil {
+			return apiserver.Configuration{}, fmt.Errorf("unmarshalling roomNameRules: %v", err)
+		}
+		apiConfig.RoomNameRules = rnr
+	}
+	if dbConfig.RoomWorkingHours.Valid {
+		var rwh map[string]apiserver.WorkingHours
+		if err := json.Unmarshal(dbConfig.RoomWorkingHours.JSON, &rwh); err != nil {
+			return apiserver.Configuration{}, fmt.Errorf("unmarshalling roomWorkingHours: %v", err)
+		}
+		apiConfig.RoomWorkingHours = rwh
+	}
+	if dbConfig.BuildingWorkingHours.Valid {
+		var bwh map[string]apiserver.WorkingHours
+		if err := json.Unmarshal(dbConfig.BuildingWorkingHours.JSON, &bwh); err != nil {
+			return apiserver.Configuration{}, fmt.Errorf("unmarshalling buildingWorkingHours: %v", err)
+		}
+		apiConfig.BuildingWorkingHours = bwh
+	}
 	if dbConfig.AssetFilter.Valid {
 		var af [][]apiserver.FilterRule
 		if err := json.Unmarshal(dbConfig.AssetFilter.JSON, &af); err != nil {
@@ -184,6 +582,7 @@ func apiConfigFromDbConfig(dbConfig *appdb.Configuration) (apiConfig apiserver.C
 	apiConfig.Active = dbConfig.Active.Ptr()
 	apiConfig.ProjectIDs = common.Ptr[[]string](dbConfig.ProjectIds)
 	apiConfig.UserId = dbConfig.UserID.Ptr()
+	apiConfig.ApprovalRoomUPNs = common.Ptr[[]string](dbConfig.ApprovalRoomUpns)
 	return apiConfig, nil
 }
 
@@ -194,6 +593,7 @@ func GetConfigs(ctx context.Context) ([]apiserver.Configuration, error) {
 	}
 	var apiConfigs []apiserver.Configuration
 	for _, dbConfig := range dbConfigs {
+		encryptStoredSecrets(ctx, dbConfig)
 		ac, err := apiConfigFromDbConfig(dbConfig)
 		if err != nil {
 			return nil, fmt.Errorf("creating API config from DB config: %v", err)
@@ -218,10 +618,482 @@ func ProjIds(config apiserver.Configuration) []string {
 	return *config.ProjectIDs
 }
 
+func ApprovalRoomUPNs(config apiserver.Configuration) []string {
+	if config.ApprovalRoomUPNs == nil {
+		return []string{}
+	}
+	return *config.ApprovalRoomUPNs
+}
+
+// RoomDiscovery returns where the configuration's rooms are discovered,
+// defaulting to the room list.
+func RoomDiscovery(config apiserver.Configuration) syncmodel.RoomDiscovery {
+	discovery, err := syncmodel.ParseRoomDiscovery(common.Val(config.RoomDiscovery))
+	if err != nil {
+		return syncmodel.RoomDiscoveryRoomList
+	}
+	return discovery
+}
+
+// Cloud returns the configured cloud of the tenant, defaulting to commercial.
+func Cloud(config apiserver.Configuration) syncmodel.Cloud {
+	cloud, err := syncmodel.ParseCloud(common.Val(config.Cloud))
+	if err != nil {
+		return syncmodel.CloudCommercial
+	}
+	return cloud
+}
+
+// OverlapPolicy returns the configured overlap policy, defaulting to exclusive.
+func OverlapPolicy(config apiserver.Configuration) syncmodel.OverlapPolicy {
+	if config.OverlapPolicy == nil || *config.OverlapPolicy == "" {
+		return syncmodel.OverlapExclusive
+	}
+	return syncmodel.OverlapPolicy(*config.OverlapPolicy)
+}
+
+// DeclinePolicy returns the configured decline policy, defaulting to cancelling
+// the whole booking.
+func DeclinePolicy(config apiserver.Configuration) syncmodel.DeclinePolicy {
+	policy, err := syncmodel.ParseDeclinePolicy(common.Val(config.DeclinePolicy))
+	if err != nil {
+		return syncmodel.DeclineCancelAll
+	}
+	return policy
+}
+
+// CancelPolicy returns how bookings cancelled in Eliona free their rooms,
+// defaulting to cancelling the whole event.
+func CancelPolicy(config apiserver.Configuration) syncmodel.CancelPolicy {
+	policy, err := syncmodel.ParseCancelPolicy(common.Val(config.CancelPolicy))
+	if err != nil {
+		return syncmodel.CancelEvent
+	}
+	return policy
+}
+
+// EmptyBookingPolicy returns what happens to Eliona bookings left without
+// rooms, defaulting to cancelling them.
+func EmptyBookingPolicy(config apiserver.Configuration) syncmodel.EmptyBookingPolicy {
+	policy, err := syncmodel.ParseEmptyBookingPolicy(common.Val(config.EmptyBookingPolicy))
+	if err != nil {
+		return syncmodel.EmptyBookingCancel
+	}
+	return policy
+}
+
+// MissingRoomEmailPolicy returns what happens to rooms whose asset has no
+// email address, defaulting to skipping them.
+func MissingRoomEmailPolicy(config apiserver.Configuration) syncmodel.MissingRoomEmailPolicy {
+	policy, err := syncmodel.ParseMissingRoomEmailPolicy(common.Val(config.MissingRoomEmailPolicy))
+	if err != nil {
+		return syncmodel.MissingRoomEmailSkip
+	}
+	return policy
+}
+
+// SelfOrganizedPolicy returns what happens to events organized by their room,
+// defaulting to importing them without an organizer.
+func SelfOrganizedPolicy(config apiserver.Configuration) syncmodel.SelfOrganizedPolicy {
+	policy, err := syncmodel.ParseSelfOrganizedPolicy(common.Val(config.SelfOrganizedPolicy))
+	if err != nil {
+		return syncmodel.SelfOrganizedUnattributed
+	}
+	return policy
+}
+
+// BlockPolicy returns what happens to events nobody is invited to, defaulting
+// to importing them.
+func BlockPolicy(config apiserver.Configuration) syncmodel.BlockPolicy {
+	policy, err := syncmodel.ParseBlockPolicy(common.Val(config.BlockPolicy))
+	if err != nil {
+		return syncmodel.BlockImport
+	}
+	return policy
+}
+
+// UnknownDeletePolicy returns what happens when the deletion of an unknown item
+// is reported, defaulting to logging it.
+func UnknownDeletePolicy(config apiserver.Configuration) syncmodel.UnknownDeletePolicy {
+	policy, err := syncmodel.ParseUnknownDeletePolicy(common.Val(config.UnknownDeletePolicy))
+	if err != nil {
+		return syncmodel.UnknownDeleteLog
+	}
+	return policy
+}
+
+// ChangeDetection returns how changes in rooms' calendars are detected,
+// defaulting to polling.
+func ChangeDetection(config apiserver.Configuration) syncmodel.ChangeDetection {
+	detection, err := syncmodel.ParseChangeDetection(common.Val(config.ChangeDetection))
+	if err != nil {
+		return syncmodel.ChangeDetectionPolling
+	}
+	return detection
+}
+
+// BookingClock returns the conversion of the Booking app's times, defaulting
+// to absolute times.
+func BookingClock(config apiserver.Configuration) syncmodel.BookingClock {
+	clock, err := syncmodel.ParseBookingClock(common.Val(config.BookingTimeZone))
+	if err != nil {
+		log.Error("conf", "config %d: %v; using absolute times", common.Val(config.Id), err)
+		return syncmodel.BookingClock{}
+	}
+	return clock
+}
+
+// SubjectFallback returns how subjects of bookings without one are composed.
+// Times are shown in the project's time zone, if configured.
+func SubjectFallback(config apiserver.Configuration) syncmodel.SubjectFallback {
+	return syncmodel.SubjectFallback{
+		Template:  common.Val(config.SubjectFallback),
+		Location:  BookingClock(config).Location,
+		RoomNames: config.RoomNames,
+	}
+}
+
+// ResourceSubject returns how the subjects shown in the rooms' calendars for
+// bookings from Eliona are composed, and false if rooms show the organizer's
+// subject.
+func ResourceSubject(config apiserver.Configuration) (syncmodel.SubjectFallback, bool) {
+	subjects := SubjectFallback(config)
+	subjects.Template = common.Val(config.ResourceSubject)
+	return subjects, subjects.Template != ""
+}
+
+// DefaultCancellationBody is sent to the attendees of cancelled events of
+// configurations without a cancellation body.
+const DefaultCancellationBody = "Cancelled via Eliona"
+
+// CancellationBody returns how the message sent to the attendees of events
+// cancelled from Eliona is composed.
+func CancellationBody(config apiserver.Configuration) syncmodel.SubjectFallback {
+	body := SubjectFallback(config)
+	body.Template = common.Val(config.CancellationBody)
+	if body.Template == "" {
+		body.Template = DefaultCancellationBody
+	}
+	return body
+}
+
+// AppointmentCategories returns the categories of events created for bookings
+// from Eliona.
+func AppointmentCategories(config apiserver.Configuration) []string {
+	if category := common.Val(config.AppointmentCategory); category != "" {
+		return []string{category}
+	}
+	return nil
+}
+
+// ImportOverlapPolicy returns the configured handling of imported bookings
+// overlapping Eliona bookings, defaulting to importing them anyway.
+func ImportOverlapPolicy(config apiserver.Configuration) syncmodel.ImportOverlapPolicy {
+	policy, err := syncmodel.ParseImportOverlapPolicy(common.Val(config.ImportOverlapPolicy))
+	if err != nil {
+		return syncmodel.ImportOverlapImport
+	}
+	return policy
+}
+
+// RoomDisplayNamer returns a function naming the configuration's rooms in
+// Eliona, see syncmodel.RoomDisplayName.
+func RoomDisplayNamer(config apiserver.Configuration) (func(email, name string) string, error) {
+	rules, err := parseRoomNameRules(config.RoomNameRules)
+	if err != nil {
+		return nil, err
+	}
+	return func(email, name string) string {
+		return syncmodel.RoomDisplayName(email, name, config.RoomNames, rules)
+	}, nil
+}
+
+func parseRoomNameRules(apiRules []apiserver.RoomNameRule) ([]syncmodel.RoomNameRule, error) {
+	rules := make([]syncmodel.RoomNameRule, 0, len(apiRules))
+	for _, r := range apiRules {
+		rule, err := syncmodel.ParseRoomNameRule(r.Pattern, r.Replacement)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// RoomListUPNs returns the room lists to discover rooms in. The deprecated
+// single room list is added to them. Blank entries and duplicates are
+// dropped.
+func RoomListUPNs(config apiserver.Configuration) []string {
+	upns := []string{}
+	for _, upn := range append(common.Val(config.RoomListUPNs), common.Val(config.RoomListUPN)) {
+		upn = strings.TrimSpace(upn)
+		if upn == "" || containsFold(upns, upn) {
+			continue
+		}
+		upns = append(upns, upn)
+	}
+	return upns
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RoomLocationPattern returns the pattern locating rooms in buildings and
+// floors.
+func RoomLocationPattern(config apiserver.Configuration) (syncmodel.RoomLocationPattern, error) {
+	return syncmodel.ParseRoomLocationPattern(common.Val(config.RoomLocationPattern))
+}
+
+// RoomCapacity returns the number of people the room seats, or nil if it is
+// unknown.
+func RoomCapacity(config apiserver.Configuration, roomEmail string) *int32 {
+	for room, capacity := range config.RoomCapacities {
+		if strings.EqualFold(room, roomEmail) {
+			return common.Ptr(capacity)
+		}
+	}
+	return nil
+}
+
+// RoomWorkingHours returns working hours of the room located in the building,
+// evaluated in the room's time zone. Room overrides take precedence over
+// building overrides, which take precedence over the configuration's working
+// hours applying to all of its rooms. The building is empty if unknown.
+func RoomWorkingHours(config apiserver.Configuration, roomEmail, building string) (syncmodel.WorkingHours, error) {
+	for room, hours := range config.RoomWorkingHours {
+		if strings.EqualFold(room, roomEmail) {
+			return parseWorkingHours(hours)
+		}
+	}
+	if hours, ok := config.BuildingWorkingHours[building]; ok && building != "" {
+		return parseWorkingHours(hours)
+	}
+	return parseWorkingHours(common.Val(config.WorkingHours))
+}
+
+// RoomTimeZone returns the time zone wall-clock times of bookings in the room
+// are kept in: the booking time zone if configured, otherwise the time zone
+// of the room's working hours.
+func RoomTimeZone(config apiserver.Configuration, roomEmail string) *time.Location {
+	if clock := BookingClock(config); clock.Location != nil {
+		return clock.Location
+	}
+	hours, err := RoomWorkingHours(config, roomEmail, "")
+	if err != nil {
+		return time.UTC
+	}
+	return hours.Location
+}
+
+func parseWorkingHours(hours apiserver.WorkingHours) (syncmodel.WorkingHours, error) {
+	return syncmodel.ParseWorkingHours(hours.Start, hours.End, hours.TimeZone, hours.Days)
+}
+
+// ReadServiceUserUPN returns the service user for importing rooms and
+// calendars, defaulting to the general service user.
+func ReadServiceUserUPN(config apiserver.Configuration) string {
+	if filled(config.ReadServiceUserUPN) {
+		return *config.ReadServiceUserUPN
+	}
+	return common.Val(config.ServiceUserUPN)
+}
+
+// WriteServiceUserUPN returns the service user for creating and cancelling
+// bookings, defaulting to the general service user.
+func WriteServiceUserUPN(config apiserver.Configuration) string {
+	if filled(config.WriteServiceUserUPN) {
+		return *config.WriteServiceUserUPN
+	}
+	return common.Val(config.ServiceUserUPN)
+}
+
+func filled(s *string) bool {
+	return s != nil && *s != ""
+}
+
+// ValidateConnection checks that exactly one complete set of credentials is
+// configured and that the addresses needed to connect are well-formed.
+func ValidateConnection(config apiserver.Configuration) error {
+	oauth := []configField{{"clientId", config.ClientId}, {"clientSecret", config.ClientSecret}, {"tenantId", config.TenantId}}
+	// The EWS URL is autodiscovered if not set.
+	ntlm := []configField{{"username", config.Username}, {"password", config.Password}}
+	oauthSet, oauthMissing := partitionFilled(oauth)
+	ntlmSet, ntlmMissing := partitionFilled(ntlm)
+	// The EWS URL is ignored with OAuth, so only the NTLM account conflicts.
+	ntlmAccount := filled(config.Username) || filled(config.Password)
+	switch {
+	case len(oauthSet) > 0 && ntlmAccount:
+		field := "username"
+		if !filled(config.Username) {
+			field = "password"
+		}
+		return fieldError(field, "NTLM credentials can't be combined with OAuth credentials (%s)", strings.Join(oauthSet, ", "))
+	case len(oauthSet) > 0:
+		if len(oauthMissing) > 0 {
+			return fieldError(oauthMissing[0], "required for OAuth together with %s", strings.Join(oauthSet, ", "))
+		}
+	case len(ntlmSet) > 0:
+		if len(ntlmMissing) > 0 {
+			return fieldError(ntlmMissing[0], "required for NTLM together with %s", strings.Join(ntlmSet, ", "))
+		}
+	default:
+		return fieldError("clientId", "configure either OAuth (clientId, clientSecret, tenantId) or NTLM (username, password) credentials")
+	}
+	if filled(config.EwsURL) && !validURL(*config.EwsURL) {
+		return fieldError("ewsURL", "%q is not an http(s) URL", *config.EwsURL)
+	}
+	// The proxy URL isn't repeated, as it may contain a password.
+	if filled(config.ProxyURL) && !validURL(*config.ProxyURL) {
+		return fieldError("proxyURL", "not an http(s) URL")
+	}
+	if filled(config.TLSCACertPEM) && !x509.NewCertPool().AppendCertsFromPEM([]byte(*config.TLSCACertPEM)) {
+		return fieldError("tlsCACertPEM", "no PEM encoded certificates found")
+	}
+
+	// The general service user is only needed if not both specific ones are
+	// set.
+	if !filled(config.ServiceUserUPN) && (!filled(config.ReadServiceUserUPN) || !filled(config.WriteServiceUserUPN)) {
+		return fieldError("serviceUserUPN", "required unless both readServiceUserUPN and writeServiceUserUPN are set")
+	}
+	// Service users are impersonated, which works by SID as well.
+	for _, user := range []configField{
+		{"serviceUserUPN", config.ServiceUserUPN},
+		{"readServiceUserUPN", config.ReadServiceUserUPN},
+		{"writeServiceUserUPN", config.WriteServiceUserUPN},
+	} {
+		if filled(user.value) && !validUPN(*user.value) && !syncmodel.IsSID(*user.value) {
+			return fieldError(user.name, "%q is neither an email address nor a SID", *user.value)
+		}
+	}
+	if filled(config.RoomListUPN) && !validUPN(*config.RoomListUPN) {
+		return fieldError("roomListUPN", "%q is not an email address", *config.RoomListUPN)
+	}
+	for _, upn := range common.Val(config.RoomListUPNs) {
+		if !validUPN(upn) {
+			return fieldError("roomListUPNs", "%q is not an email address", upn)
+		}
+	}
+	for _, email := range common.Val(config.EquipmentEmails) {
+		if !validUPN(email) {
+			return fieldError("equipmentEmails", "%q is not an email address", email)
+		}
+	}
+	if !filled(config.BookingAppURL) {
+		return fieldError("bookingAppURL", "required")
+	}
+	if !validURL(*config.BookingAppURL) {
+		return fieldError("bookingAppURL", "%q is not an http(s) URL", *config.BookingAppURL)
+	}
+	return nil
+}
+
+type configField struct {
+	name  string
+	value *string
+}
+
+// partitionFilled returns the names of the filled and of the missing fields.
+func partitionFilled(fields []configField) (set, missing []string) {
+	for _, field := range fields {
+		if filled(field.value) {
+			set = append(set, field.name)
+		} else {
+			missing = append(missing, field.name)
+		}
+	}
+	return set, missing
+}
+
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validUPN checks that the UPN has the form of an email address.
+func validUPN(upn string) bool {
+	local, domain, found := strings.Cut(upn, "@")
+	return found && local != "" && domain != "" && !strings.ContainsAny(upn, " \t")
+}
+
+// validGUID checks that the ID has the form of a GUID, e.g.
+// 1c2e6f4a-8b3d-4e5f-9a0b-7c6d5e4f3a2b.
+func validGUID(id string) bool {
+	parts := strings.Split(id, "-")
+	if len(parts) != 5 {
+		return false
+	}
+	for i, length := range []int{8, 4, 4, 4, 12} {
+		if len(parts[i]) != length || strings.Trim(parts[i], "0123456789abcdefABCDEF") != "" {
+			return false
+		}
+	}
+	return true
+}
+
 func IsConfigActive(config apiserver.Configuration) bool {
 	return config.Active == nil || *config.Active
 }
 
+// IsReadOnly reports whether the configuration must not write to Exchange.
+func IsReadOnly(config apiserver.Configuration) bool {
+	return common.Val(config.ReadOnly)
+}
+
+// ReminderMinutes returns the minutes before bookings from Eliona start at
+// which their organizers are reminded, nil for the default of the mailbox.
+func ReminderMinutes(config apiserver.Configuration) *int {
+	if config.ReminderMinutes == nil {
+		return nil
+	}
+	minutes := int(*config.ReminderMinutes)
+	return &minutes
+}
+
+// DefaultInvitationProcessingTimeout is the invitation processing timeout in
+// seconds of configurations without one.
+const DefaultInvitationProcessingTimeout = 15
+
+// InvitationProcessingTimeout returns how long the rooms are polled quickly
+// for having processed an invitation.
+func InvitationProcessingTimeout(config apiserver.Configuration) time.Duration {
+	if config.InvitationProcessingTimeout == nil {
+		return DefaultInvitationProcessingTimeout * time.Second
+	}
+	return time.Duration(*config.InvitationProcessingTimeout) * time.Second
+}
+
+// DefaultRecurrenceHorizonDays is the recurrence horizon of configurations
+// without one.
+const DefaultRecurrenceHorizonDays = 365
+
+// RecurrenceHorizon returns how far ahead occurrences of recurring events are
+// synchronized.
+func RecurrenceHorizon(config apiserver.Configuration) time.Duration {
+	days := common.Val(config.RecurrenceHorizonDays)
+	if days <= 0 {
+		days = DefaultRecurrenceHorizonDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ExportsImports reports whether booking events caused by the app's own
+// imports from Exchange are handled like bookings made in Eliona.
+func ExportsImports(config apiserver.Configuration) bool {
+	return common.Val(config.ExportImports)
+}
+
+// ArchivesRemovedRooms reports whether assets of rooms which are no longer
+// discovered are archived.
+func ArchivesRemovedRooms(config apiserver.Configuration) bool {
+	return common.Val(config.ArchiveRemovedRooms)
+}
+
 func IsConfigEnabled(config apiserver.Configuration) bool {
 	return config.Enable == nil || *config.Enable
 }
@@ -232,16 +1104,65 @@ func SetAllConfigsInactive(ctx context.Context) (int64, error) {
 	})
 }
 
-func InsertAsset(ctx context.Context, config apiserver.Configuration, projId string, globalAssetID string, assetId int32, providerId string) error {
+func InsertAsset(ctx context.Context, config apiserver.Configuration, projId string, globalAssetID string, assetId int32, providerId string, name string) error {
 	var dbAsset appdb.Asset
 	dbAsset.ConfigurationID = null.Int64FromPtr(config.Id).Int64
 	dbAsset.ProjectID = projId
 	dbAsset.GlobalAssetID = globalAssetID
 	dbAsset.AssetID = null.Int32From(assetId)
 	dbAsset.ProviderID = providerId
+	dbAsset.Name = name
 	return dbAsset.InsertG(ctx, boil.Infer())
 }
 
+// Global asset IDs of the app's assets, as built by the asset types in the
+// model package.
+const (
+	roomGAIPrefix       = "ews_room_"
+	roomByNameGAIPrefix = "ews_room_name_"
+	equipmentGAIPrefix  = "ews_equipment_"
+)
+
+// IsResourceAsset tells whether the asset is a room or equipment, which have
+// a calendar. The root asset and the building and floor assets grouping rooms
+// have no email address.
+func IsResourceAsset(asset appdb.Asset) bool {
+	return strings.HasPrefix(asset.GlobalAssetID, roomGAIPrefix) || strings.HasPrefix(asset.GlobalAssetID, equipmentGAIPrefix)
+}
+
+// RoomEmailFromGAI derives the email address of the room or equipment from
+// the global asset ID of its asset. Rooms created by name have no email to
+// derive.
+func RoomEmailFromGAI(globalAssetID string) (string, bool) {
+	var email string
+	switch {
+	case strings.HasPrefix(globalAssetID, roomByNameGAIPrefix):
+		return "", false
+	case strings.HasPrefix(globalAssetID, roomGAIPrefix):
+		email = strings.TrimPrefix(globalAssetID, roomGAIPrefix)
+	case strings.HasPrefix(globalAssetID, equipmentGAIPrefix):
+		email = strings.TrimPrefix(globalAssetID, equipmentGAIPrefix)
+	}
+	if email == "" {
+		return "", false
+	}
+	return email, true
+}
+
+// RepairAssetProviderID stores the email address derived from the room
+// asset's global asset ID as its provider ID and returns the repaired asset.
+func RepairAssetProviderID(asset appdb.Asset) (appdb.Asset, error) {
+	email, ok := RoomEmailFromGAI(asset.GlobalAssetID)
+	if !ok {
+		return asset, fmt.Errorf("no email in global asset ID %q of asset %d", asset.GlobalAssetID, asset.ID)
+	}
+	asset.ProviderID = email
+	if _, err := asset.UpdateG(context.Background(), boil.Whitelist(appdb.AssetColumns.ProviderID)); err != nil {
+		return asset, fmt.Errorf("updating provider ID of asset %d: %v", asset.ID, err)
+	}
+	return asset, nil
+}
+
 func GetAssetId(ctx context.Context, config apiserver.Configuration, projId string, globalAssetID string) (*int32, error) {
 	dbAsset, err := appdb.Assets(
 		appdb.AssetWhere.ConfigurationID.EQ(null.Int64FromPtr(config.Id).Int64),
@@ -284,8 +1205,74 @@ func GetAssets() ([]appdb.Asset, error) {
 	return assetsSlice, nil
 }
 
-func GetWatchedAssetIDs() ([]int, error) {
-	assets, err := GetAssets()
+// GetAssetsByConfig returns the assets belonging to the configuration, except
+// for the archived ones.
+func GetAssetsByConfig(configID int64) ([]appdb.Asset, error) {
+	assets, err := appdb.Assets(
+		appdb.AssetWhere.ConfigurationID.EQ(configID),
+		appdb.AssetWhere.ArchivedAt.IsNull(),
+	).AllG(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("fetching assets of config %d: %v", configID, err)
+	}
+	assetsSlice := make([]appdb.Asset, 0, len(assets))
+	for _, asset := range assets {
+		assetsSlice = append(assetsSlice, *asset)
+	}
+	return assetsSlice, nil
+}
+
+// GetArchivedAssetsByConfig returns the archived assets of the configuration.
+func GetArchivedAssetsByConfig(configID int64) ([]appdb.Asset, error) {
+	assets, err := appdb.Assets(
+		appdb.AssetWhere.ConfigurationID.EQ(configID),
+		appdb.AssetWhere.ArchivedAt.IsNotNull(),
+	).AllG(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("fetching archived assets of config %d: %v", configID, err)
+	}
+	assetsSlice := make([]appdb.Asset, 0, len(assets))
+	for _, asset := range assets {
+		assetsSlice = append(assetsSlice, *asset)
+	}
+	return assetsSlice, nil
+}
+
+// SetAssetArchived archives the asset of a room which is no longer discovered,
+// or restores it once the room is discovered again.
+func SetAssetArchived(asset appdb.Asset, archived bool) error {
+	asset.ArchivedAt = null.Time{}
+	if archived {
+		asset.ArchivedAt = null.TimeFrom(time.Now())
+	}
+	if _, err := asset.UpdateG(context.Background(), boil.Whitelist(appdb.AssetColumns.ArchivedAt)); err != nil {
+		return fmt.Errorf("updating archival of asset %d: %v", asset.ID, err)
+	}
+	return nil
+}
+
+// SetAssetName stores the name the asset was given in Eliona.
+func SetAssetName(asset appdb.Asset, name string) error {
+	asset.Name = name
+	if _, err := asset.UpdateG(context.Background(), boil.Whitelist(appdb.AssetColumns.Name)); err != nil {
+		return fmt.Errorf("updating name of asset %d: %v", asset.ID, err)
+	}
+	return nil
+}
+
+// SetAssetDataDigest stores the digest of the data last set for the asset.
+func SetAssetDataDigest(asset appdb.Asset, digest string) error {
+	asset.DataDigest = digest
+	if _, err := asset.UpdateG(context.Background(), boil.Whitelist(appdb.AssetColumns.DataDigest)); err != nil {
+		return fmt.Errorf("updating data digest of asset %d: %v", asset.ID, err)
+	}
+	return nil
+}
+
+// GetWatchedAssetIDs returns Eliona asset IDs of the configuration's rooms,
+// whose bookings the configuration synchronizes to Exchange.
+func GetWatchedAssetIDs(configID int64) ([]int, error) {
+	assets, err := GetAssetsByConfig(configID)
 	if err != nil {
 		return nil, err
 	}
@@ -317,11 +1304,45 @@ func GetSyncState(assetID int64) (string, error) {
 	return dbConfig.SyncState, nil
 }
 
+// SetEwsURL stores the EWS URL found by autodiscovery in the configuration.
+func SetEwsURL(configID int64, ewsURL string) error {
+	_, err := appdb.Configurations(
+		appdb.ConfigurationWhere.ID.EQ(configID),
+	).UpdateAllG(context.Background(), appdb.M{
+		appdb.ConfigurationColumns.EwsURL: ewsURL,
+	})
+	return err
+}
+
 func PersistSyncState(assetID int64, syncState string) error {
 	_, err := appdb.Assets(
 		appdb.AssetWhere.ID.EQ(assetID),
 	).UpdateAllG(context.Background(), appdb.M{
 		appdb.AssetColumns.SyncState: syncState,
+		appdb.AssetColumns.SyncedAt:  time.Now(),
+	})
+	return err
+}
+
+// PersistSubscription stores the room's pull subscription and the watermark
+// up to which its events are synchronized.
+func PersistSubscription(assetID int64, subscriptionID, watermark string) error {
+	_, err := appdb.Assets(
+		appdb.AssetWhere.ID.EQ(assetID),
+	).UpdateAllG(context.Background(), appdb.M{
+		appdb.AssetColumns.SubscriptionID: subscriptionID,
+		appdb.AssetColumns.Watermark:      watermark,
+	})
+	return err
+}
+
+// PersistExpandedUntil stores up to when occurrences of the room's recurring
+// events are synchronized.
+func PersistExpandedUntil(assetID int64, until time.Time) error {
+	_, err := appdb.Assets(
+		appdb.AssetWhere.ID.EQ(assetID),
+	).UpdateAllG(context.Background(), appdb.M{
+		appdb.AssetColumns.ExpandedUntil: until,
 	})
 	return err
 }
@@ -340,6 +1361,79 @@ func GetBookingGroupByExchangeID(exchangeID string) (appdb.BookingGroup, error)
 	return *booking, nil
 }
 
+// GetRoomItem returns the item of a room's calendar that has no room booking.
+func GetRoomItem(exchangeID string) (appdb.RoomItem, error) {
+	item, err := appdb.RoomItems(
+		appdb.RoomItemWhere.ExchangeID.EQ(exchangeID),
+	).OneG(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		return appdb.RoomItem{}, ErrNotFound
+	} else if err != nil {
+		return appdb.RoomItem{}, fmt.Errorf("fetching room item from database: %v", err)
+	}
+	return *item, nil
+}
+
+// AddRoomItem remembers the item of a room's calendar, replacing the UID of
+// the series it is the master of.
+func AddRoomItem(item appdb.RoomItem) error {
+	if err := item.UpsertG(
+		context.Background(), true,
+		[]string{appdb.RoomItemColumns.ExchangeID},
+		boil.Whitelist(appdb.RoomItemColumns.ExchangeUID),
+		boil.Infer()); err != nil {
+		return fmt.Errorf("upserting room item: %v", err)
+	}
+	return nil
+}
+
+// DeleteRoomItem forgets the item once its deletion was handled.
+func DeleteRoomItem(exchangeID string) error {
+	if _, err := appdb.RoomItems(
+		appdb.RoomItemWhere.ExchangeID.EQ(exchangeID),
+	).DeleteAllG(context.Background()); err != nil {
+		return fmt.Errorf("deleting room item: %v", err)
+	}
+	return nil
+}
+
+// CountBookingGroupsByState counts the booking groups the configuration
+// created in Exchange by their state.
+func CountBookingGroupsByState(configID int64) (map[string]int64, error) {
+	var rows []struct {
+		State string `boil:"state"`
+		Count int64  `boil:"count"`
+	}
+	err := appdb.BookingGroups(
+		qm.Select(appdb.BookingGroupColumns.State, "count(*) as count"),
+		appdb.BookingGroupWhere.ConfigurationID.EQ(null.Int64From(configID)),
+		qm.GroupBy(appdb.BookingGroupColumns.State),
+	).BindG(context.Background(), &rows)
+	if err != nil {
+		return nil, fmt.Errorf("counting booking groups: %v", err)
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.State] = row.Count
+	}
+	return counts, nil
+}
+
+// GetBookingOccurrenceByExchangeID returns the occurrence the event in a
+// room's mailbox belongs to.
+func GetBookingOccurrenceByExchangeID(exchangeID string) (appdb.BookingOccurrence, error) {
+	occurrence, err := appdb.BookingOccurrences(
+		qm.InnerJoin("ews.room_booking rb on rb.booking_occurrence_id = ews.booking_occurrence.id"),
+		qm.Where("rb.exchange_id = ?", exchangeID),
+	).OneG(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		return appdb.BookingOccurrence{}, ErrNotFound
+	} else if err != nil {
+		return appdb.BookingOccurrence{}, fmt.Errorf("fetching occurrence from database: %v", err)
+	}
+	return *occurrence, nil
+}
+
 func GetBookingGroupByExchangeUID(exchangeUID string) (appdb.BookingGroup, error) {
 	booking, err := appdb.BookingGroups(
 		appdb.BookingGroupWhere.ExchangeUID.EQ(null.StringFrom(exchangeUID)),
@@ -421,39 +1515,65 @@ func GetBookingOccurrencesByGroupIDWithoutExceptions(groupID int64, exceptIDs []
 
 func UpsertBooking(modelGroup syncmodel.BookingGroup) error {
 	ctx := context.Background()
+	return WithTx(ctx, func(tx boil.ContextExecutor) error {
+		return UpsertBookingTx(ctx, tx, modelGroup)
+	})
+}
 
+// UpsertBookingTx upserts the group with all its occurrences and room bookings
+// using the given executor.
+func UpsertBookingTx(ctx context.Context, exec boil.ContextExecutor, modelGroup syncmodel.BookingGroup) error {
 	dbGroup := appdb.BookingGroup{
 		ExchangeUID:              null.StringFrom(modelGroup.ExchangeUID),
 		ExchangeOrganizerMailbox: null.StringFrom(modelGroup.OrganizerEmail),
 		ElionaGroupID:            null.Int32From(modelGroup.ElionaID),
+		Subject:                  modelGroup.Subject,
 	}
 
-	if err := dbGroup.UpsertG(
-		ctx, true,
+	if err := dbGroup.Upsert(
+		ctx, exec, true,
 		[]string{appdb.BookingGroupColumns.ExchangeUID},
-		boil.Whitelist(appdb.BookingGroupColumns.ElionaGroupID),
+		boil.Whitelist(appdb.BookingGroupColumns.ElionaGroupID, appdb.BookingGroupColumns.Subject),
 		boil.Infer(),
 	); err != nil {
 		return fmt.Errorf("upserting group: %v", err)
 	}
-	if err := dbGroup.ReloadG(ctx); err != nil {
+	if err := dbGroup.Reload(ctx, exec); err != nil {
 		return fmt.Errorf("reloading group: %v", err)
 	}
+	if modelGroup.OccupancyOnly && dbGroup.State != BookingStateOccupancyOnly {
+		dbGroup.State = BookingStateOccupancyOnly
+		if _, err := dbGroup.Update(ctx, exec, boil.Whitelist(appdb.BookingGroupColumns.State)); err != nil {
+			return fmt.Errorf("marking group occupancy only: %v", err)
+		}
+	}
 
 	for _, occurrence := range modelGroup.Occurrences {
+		if occurrence.Cancelled {
+			// Cancelled occurrences are no longer part of the group, e.g. after
+			// the series was truncated.
+			if _, err := appdb.BookingOccurrences(
+				appdb.BookingOccurrenceWhere.BookingGroupID.EQ(dbGroup.ID),
+				appdb.BookingOccurrenceWhere.ExchangeInstanceIndex.EQ(int32(occurrence.InstanceIndex)),
+			).DeleteAll(ctx, exec); err != nil {
+				return fmt.Errorf("deleting cancelled occurrence: %v", err)
+			}
+			continue
+		}
 		bookingOccurrence := appdb.BookingOccurrence{
 			BookingGroupID:        dbGroup.ID,
 			ExchangeInstanceIndex: int32(occurrence.InstanceIndex),
 			ElionaBookingID:       null.Int32From(occurrence.ElionaID),
+			Location:              occurrence.Location,
 		}
-		if err := bookingOccurrence.UpsertG(
-			ctx, true,
+		if err := bookingOccurrence.Upsert(
+			ctx, exec, true,
 			[]string{appdb.BookingOccurrenceColumns.BookingGroupID, appdb.BookingOccurrenceColumns.ExchangeInstanceIndex},
-			boil.Whitelist(appdb.BookingOccurrenceColumns.ElionaBookingID),
+			boil.Whitelist(appdb.BookingOccurrenceColumns.ElionaBookingID, appdb.BookingOccurrenceColumns.Location),
 			boil.Infer()); err != nil {
 			return fmt.Errorf("upserting occurrence: %v", err)
 		}
-		if err := bookingOccurrence.ReloadG(ctx); err != nil {
+		if err := bookingOccurrence.Reload(ctx, exec); err != nil {
 			return fmt.Errorf("reloading occurrence: %v", err)
 		}
 		for _, specificEvent := range occurrence.RoomBookings {
@@ -462,8 +1582,8 @@ func UpsertBooking(modelGroup syncmodel.BookingGroup) error {
 				ExchangeID:          null.StringFrom(specificEvent.ExchangeIDInResourceMailbox),
 			}
 			// Just a hacky way to do "ON CONFLICT DO NOTHING"
-			if err := roomBooking.UpsertG(
-				ctx, true,
+			if err := roomBooking.Upsert(
+				ctx, exec, true,
 				[]string{appdb.RoomBookingColumns.ExchangeID},
 				boil.Whitelist(appdb.RoomBookingColumns.ExchangeID),
 				boil.Infer()); err != nil {
@@ -473,3 +1593,205 @@ func UpsertBooking(modelGroup syncmodel.BookingGroup) error {
 	}
 	return nil
 }
+
+func SetBookingGroupState(exchangeUID string, configID int64, state string) error {
+	_, err := appdb.BookingGroups(
+		appdb.BookingGroupWhere.ExchangeUID.EQ(null.StringFrom(exchangeUID)),
+	).UpdateAllG(context.Background(), appdb.M{
+		appdb.BookingGroupColumns.State:           state,
+		appdb.BookingGroupColumns.ConfigurationID: configID,
+	})
+	if err != nil {
+		return fmt.Errorf("updating state of group %s: %v", exchangeUID, err)
+	}
+	return nil
+}
+
+// AdoptExchangeEvent points the stored group to another event in Exchange,
+// which then stands for the group's Eliona booking. The room bookings of the
+// previous event are forgotten; those of the adopted event are stored when
+// upserting it. The adopted event is confirmed, being in the rooms already.
+func AdoptExchangeEvent(groupID int64, exchangeUID, organizer string) error {
+	ctx := context.Background()
+	return WithTx(ctx, func(tx boil.ContextExecutor) error {
+		return AdoptExchangeEventTx(ctx, tx, groupID, exchangeUID, organizer)
+	})
+}
+
+// AdoptExchangeEventTx adopts the event for the group using the given
+// executor.
+func AdoptExchangeEventTx(ctx context.Context, tx boil.ContextExecutor, groupID int64, exchangeUID, organizer string) error {
+	occurrences, err := appdb.BookingOccurrences(
+		appdb.BookingOccurrenceWhere.BookingGroupID.EQ(groupID),
+	).All(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("fetching occurrences of group %d: %v", groupID, err)
+	}
+	occurrenceIDs := make([]int64, len(occurrences))
+	for i, occurrence := range occurrences {
+		occurrenceIDs[i] = occurrence.ID
+	}
+	if _, err := appdb.RoomBookings(
+		appdb.RoomBookingWhere.BookingOccurrenceID.IN(occurrenceIDs),
+	).DeleteAll(ctx, tx); err != nil {
+		return fmt.Errorf("deleting room bookings of group %d: %v", groupID, err)
+	}
+	if _, err := appdb.BookingGroups(
+		appdb.BookingGroupWhere.ID.EQ(groupID),
+	).UpdateAll(ctx, tx, appdb.M{
+		appdb.BookingGroupColumns.ExchangeUID:              exchangeUID,
+		appdb.BookingGroupColumns.ExchangeOrganizerMailbox: organizer,
+		appdb.BookingGroupColumns.State:                    BookingStateConfirmed,
+	}); err != nil {
+		return fmt.Errorf("updating group %d: %v", groupID, err)
+	}
+	return nil
+}
+
+// SetBookingGroupPendingResponse marks the group as waiting for the rooms'
+// responses since the given time.
+func SetBookingGroupPendingResponse(exchangeUID string, configID int64, since time.Time) error {
+	_, err := appdb.BookingGroups(
+		appdb.BookingGroupWhere.ExchangeUID.EQ(null.StringFrom(exchangeUID)),
+	).UpdateAllG(context.Background(), appdb.M{
+		appdb.BookingGroupColumns.State:           BookingStatePendingResponse,
+		appdb.BookingGroupColumns.ConfigurationID: configID,
+		appdb.BookingGroupColumns.PendingSince:    null.TimeFrom(since),
+	})
+	if err != nil {
+		return fmt.Errorf("marking group %s as pending response: %v", exchangeUID, err)
+	}
+	return nil
+}
+
+func GetBookingGroupsByState(configID int64, state string) ([]appdb.BookingGroup, error) {
+	bookings, err := appdb.BookingGroups(
+		appdb.BookingGroupWhere.ConfigurationID.EQ(null.Int64From(configID)),
+		appdb.BookingGroupWhere.State.EQ(state),
+	).AllG(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("fetching groups in state %s from database: %v", state, err)
+	}
+	var result []appdb.BookingGroup
+	for _, booking := range bookings {
+		result = append(result, *booking)
+	}
+	return result, nil
+}
+
+func AddRoomBooking(occurrenceID int64, exchangeID string) error {
+	roomBooking := appdb.RoomBooking{
+		BookingOccurrenceID: occurrenceID,
+		ExchangeID:          null.StringFrom(exchangeID),
+	}
+	// Just a hacky way to do "ON CONFLICT DO NOTHING"
+	if err := roomBooking.UpsertG(
+		context.Background(), true,
+		[]string{appdb.RoomBookingColumns.ExchangeID},
+		boil.Whitelist(appdb.RoomBookingColumns.ExchangeID),
+		boil.Infer()); err != nil {
+		return fmt.Errorf("upserting room booking: %v", err)
+	}
+	return nil
+}
+
+// RemoveRoomBooking forgets the room booking of the item of a room removed
+// from its event. The item is remembered instead, so that the room deleting it
+// is not mistaken for the deletion of an unknown item.
+func RemoveRoomBooking(assetID int64, exchangeID string) error {
+	ctx := context.Background()
+	return WithTx(ctx, func(tx boil.ContextExecutor) error {
+		return RemoveRoomBookingTx(ctx, tx, assetID, exchangeID)
+	})
+}
+
+// RemoveRoomBookingTx is RemoveRoomBooking using the given executor.
+func RemoveRoomBookingTx(ctx context.Context, exec boil.ContextExecutor, assetID int64, exchangeID string) error {
+	if _, err := appdb.RoomBookings(
+		appdb.RoomBookingWhere.ExchangeID.EQ(null.StringFrom(exchangeID)),
+	).DeleteAll(ctx, exec); err != nil {
+		return fmt.Errorf("deleting room booking: %v", err)
+	}
+	item := appdb.RoomItem{AssetID: assetID, ExchangeID: exchangeID}
+	if err := item.Upsert(
+		ctx, exec, true,
+		[]string{appdb.RoomItemColumns.ExchangeID},
+		boil.Whitelist(appdb.RoomItemColumns.ExchangeUID),
+		boil.Infer()); err != nil {
+		return fmt.Errorf("upserting room item: %v", err)
+	}
+	return nil
+}
+
+// GetResolvedAddresses returns the newest addresses resolved for the
+// configuration since the given time, at most limit of them.
+func GetResolvedAddresses(configID int64, since time.Time, limit int) ([]appdb.ResolvedAddress, error) {
+	addresses, err := appdb.ResolvedAddresses(
+		appdb.ResolvedAddressWhere.ConfigurationID.EQ(configID),
+		appdb.ResolvedAddressWhere.ResolvedAt.GT(since),
+		qm.OrderBy(appdb.ResolvedAddressColumns.ResolvedAt+" desc"),
+		qm.Limit(limit),
+	).AllG(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("fetching resolved addresses from database: %v", err)
+	}
+	var result []appdb.ResolvedAddress
+	for _, address := range addresses {
+		result = append(result, *address)
+	}
+	return result, nil
+}
+
+// UpsertResolvedAddress stores the SMTP address the distinguished name
+// resolved to, replacing the one resolved before.
+func UpsertResolvedAddress(address appdb.ResolvedAddress) error {
+	if err := address.UpsertG(
+		context.Background(), true,
+		[]string{appdb.ResolvedAddressColumns.ConfigurationID, appdb.ResolvedAddressColumns.Dn},
+		boil.Whitelist(appdb.ResolvedAddressColumns.Smtp, appdb.ResolvedAddressColumns.ResolvedAt),
+		boil.Infer()); err != nil {
+		return fmt.Errorf("upserting resolved address: %v", err)
+	}
+	return nil
+}
+
+// InsertAuditLog records a mutation the app performed in Exchange.
+func InsertAuditLog(entry appdb.AuditLog) error {
+	if err := entry.InsertG(context.Background(), boil.Infer()); err != nil {
+		return fmt.Errorf("inserting audit log entry: %v", err)
+	}
+	return nil
+}
+
+// maxAuditLogEntries limits the number of audit log entries returned at once.
+const maxAuditLogEntries = 1000
+
+// GetAuditLog returns the newest audit log entries. Zero values of the
+// arguments don't filter.
+func GetAuditLog(configID int64, room string, from, to time.Time) ([]appdb.AuditLog, error) {
+	mods := []qm.QueryMod{
+		qm.OrderBy(appdb.AuditLogColumns.CreatedAt + " desc"),
+		qm.Limit(maxAuditLogEntries),
+	}
+	if configID != 0 {
+		mods = append(mods, appdb.AuditLogWhere.ConfigurationID.EQ(configID))
+	}
+	if room != "" {
+		mods = append(mods, qm.Where("? = any("+appdb.AuditLogColumns.Rooms+")", strings.ToLower(room)))
+	}
+	if !from.IsZero() {
+		mods = append(mods, appdb.AuditLogWhere.CreatedAt.GTE(from))
+	}
+	if !to.IsZero() {
+		mods = append(mods, appdb.AuditLogWhere.CreatedAt.LT(to))
+	}
+	entries, err := appdb.AuditLogs(mods...).AllG(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("fetching audit log from database: %v", err)
+	}
+	var result []appdb.AuditLog
+	for _, entry := range entries {
+		result = append(result, *entry)
+	}
+	return result, nil
+}
@@ -0,0 +1,157 @@
+package conf
+
+import (
+	"context"
+	"ews/appdb"
+	syncmodel "ews/model/sync"
+	"testing"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/db"
+	"github.com/volatiletech/null/v8"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+)
+
+// testTx returns a transaction on the database of CONNECTION_STRING, rolled
+// back after the test. Tests using it are skipped without an initialized
+// database.
+func testTx(t *testing.T) (context.Context, boil.ContextTransactor) {
+	t.Helper()
+	if db.ConnectionString() == "" {
+		t.Skip("CONNECTION_STRING is not set")
+	}
+	ctx := context.Background()
+	database := db.Database("ews-test")
+	var table *string
+	if err := database.QueryRowContext(ctx, "select to_regclass('ews.booking_group')::text").Scan(&table); err != nil || table == nil {
+		t.Skipf("schema ews is not initialized: %v", err)
+	}
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("beginning transaction: %v", err)
+	}
+	t.Cleanup(func() { _ = tx.Rollback() })
+	return ctx, tx
+}
+
+func TestUpsertBookingKeepsCopiesAtOtherTimesApart(t *testing.T) {
+	ctx, tx := testTx(t)
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	group := func(uid string, elionaID int32, start time.Time) syncmodel.BookingGroup {
+		return syncmodel.BookingGroup{
+			ExchangeUID:    uid,
+			ElionaID:       elionaID,
+			OrganizerEmail: "organizer@example.com",
+			Occurrences:    []syncmodel.BookingOccurrence{{Start: start, End: start.Add(time.Hour)}},
+		}
+	}
+	const uid = "040000008200E00074C5B7101A82E00800000000TEST970"
+	copied := syncmodel.DistinctUID(uid, start.Add(2*time.Hour))
+	if err := UpsertBookingTx(ctx, tx, group(uid, 970001, start)); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpsertBookingTx(ctx, tx, group(copied, 970002, start.Add(2*time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := appdb.BookingGroups(appdb.BookingGroupWhere.ExchangeUID.IN([]string{uid, copied})).All(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected both copies to be stored, got %d groups", len(groups))
+	}
+	for _, g := range groups {
+		if want := map[string]int32{uid: 970001, copied: 970002}[g.ExchangeUID.String]; g.ElionaGroupID.Int32 != want {
+			t.Errorf("group %s has Eliona ID %d, want %d", g.ExchangeUID.String, g.ElionaGroupID.Int32, want)
+		}
+	}
+}
+
+func TestAdoptExchangeEventKeepsElionaBooking(t *testing.T) {
+	ctx, tx := testTx(t)
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	const uid = "040000008200E00074C5B7101A82E00800000000TEST976"
+	const adopted = "040000008200E00074C5B7101A82E00800000000TEST976B"
+	if err := UpsertBookingTx(ctx, tx, syncmodel.BookingGroup{
+		ExchangeUID:    uid,
+		ElionaID:       976001,
+		OrganizerEmail: "app@example.com",
+		Occurrences: []syncmodel.BookingOccurrence{{
+			ElionaID:     976002,
+			Start:        start,
+			End:          start.Add(time.Hour),
+			RoomBookings: []syncmodel.RoomBooking{{ExchangeIDInResourceMailbox: "room-event"}},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	group, err := appdb.BookingGroups(appdb.BookingGroupWhere.ExchangeUID.EQ(null.StringFrom(uid))).One(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AdoptExchangeEventTx(ctx, tx, group.ID, adopted, "organizer@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Reload(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if group.ExchangeUID.String != adopted || group.ExchangeOrganizerMailbox.String != "organizer@example.com" {
+		t.Errorf("expected the group to point to the adopted event, got %s by %s", group.ExchangeUID.String, group.ExchangeOrganizerMailbox.String)
+	}
+	if group.ElionaGroupID.Int32 != 976001 || group.State != BookingStateConfirmed {
+		t.Errorf("expected the confirmed Eliona booking to be kept, got %d in state %s", group.ElionaGroupID.Int32, group.State)
+	}
+	occurrences, err := appdb.BookingOccurrences(appdb.BookingOccurrenceWhere.BookingGroupID.EQ(group.ID)).All(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(occurrences) != 1 || occurrences[0].ElionaBookingID.Int32 != 976002 {
+		t.Fatalf("expected the Eliona occurrence to be kept, got %+v", occurrences)
+	}
+	count, err := appdb.RoomBookings(appdb.RoomBookingWhere.BookingOccurrenceID.EQ(occurrences[0].ID)).Count(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected the room bookings of the replaced event to be forgotten, got %d", count)
+	}
+}
+
+func TestRemoveRoomBookingKeepsItemKnown(t *testing.T) {
+	ctx, tx := testTx(t)
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	const uid = "040000008200E00074C5B7101A82E00800000000TEST932"
+	if err := UpsertBookingTx(ctx, tx, syncmodel.BookingGroup{
+		ExchangeUID:    uid,
+		ElionaID:       932001,
+		OrganizerEmail: "organizer@example.com",
+		Occurrences: []syncmodel.BookingOccurrence{{
+			ElionaID:     932002,
+			Start:        start,
+			End:          start.Add(time.Hour),
+			RoomBookings: []syncmodel.RoomBooking{{ExchangeIDInResourceMailbox: "room1-event-932"}, {ExchangeIDInResourceMailbox: "room2-event-932"}},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveRoomBookingTx(ctx, tx, 932, "room2-event-932"); err != nil {
+		t.Fatal(err)
+	}
+	roomBookings, err := appdb.RoomBookings(appdb.RoomBookingWhere.ExchangeID.IN([]string{"room1-event-932", "room2-event-932"})).All(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roomBookings) != 1 || roomBookings[0].ExchangeID.String != "room1-event-932" {
+		t.Errorf("expected only the room booking of the removed room to be forgotten, got %+v", roomBookings)
+	}
+	item, err := appdb.RoomItems(appdb.RoomItemWhere.ExchangeID.EQ("room2-event-932")).One(ctx, tx)
+	if err != nil {
+		t.Fatalf("expected the item of the removed room to be remembered: %v", err)
+	}
+	if item.AssetID != 932 || item.ExchangeUID != "" {
+		t.Errorf("expected the item to be remembered as never booked, got %+v", item)
+	}
+}
@@ -22,22 +22,71 @@ create table if not exists ews.configuration
 
 	client_id            text not null,
 	client_secret        text not null,
+	client_secret_expires_at timestamp with time zone, -- Users are warned ahead of the expiry of the client secret.
 	tenant_id            text not null,
+	cloud                text not null default 'commercial', -- Microsoft 365 cloud of the tenant: 'commercial', 'gccHigh', 'dod' or 'china'.
 
 	ews_url              text not null,
 	username             text not null,
 	password             text not null,
+	tls_ca_cert_pem      text not null default '', -- CA certificates trusted besides the system's for NTLM, PEM encoded.
+	insecure_skip_verify boolean not null default false, -- Accepts any certificate of Exchange Server for NTLM; insecure.
 
 	service_user_upn     text not null,
-	room_list_upn        text not null,
+	read_service_user_upn  text not null default '', -- Overrides service_user_upn for importing calendars.
+	write_service_user_upn text not null default '', -- Overrides service_user_upn for creating bookings.
+	room_list_upn        text not null, -- First of room_list_upns, for older versions of the app.
+	room_list_upns       text[], -- Room lists containing the rooms, for 'roomList' discovery.
 	booking_app_url      text not null,
 	refresh_interval     integer not null default 60,
 	request_timeout      integer not null default 120,
+	proxy_url            text not null default '', -- Proxy of requests to Exchange, optionally with basic auth credentials; empty uses HTTPS_PROXY.
 	asset_filter         json,
 	active               boolean default false,
 	enable               boolean default false,
 	project_ids          text[],
-	user_id              text
+	user_id              text,
+	approval_room_upns   text[], -- Rooms requiring delegate approval; these don't accept invitations immediately.
+	requests_per_minute  integer not null default 300,
+	decline_grace_period integer not null default 30, -- Seconds to wait for a room to process an invitation before considering it declined.
+	invitation_processing_timeout integer not null default 15, -- Seconds the rooms are polled every second for the invitation they processed, before checking less often.
+	overlap_policy       text    not null default 'exclusive', -- Whether back-to-back bookings overlap ('inclusive') or not ('exclusive').
+	free_busy_status     text    not null default 'Busy', -- Default free/busy status of bookings created from Eliona.
+	decline_policy       text    not null default 'cancelAll', -- Whether to cancel ('cancelAll') or keep ('keepAccepted') multi-room bookings declined by some rooms.
+	working_hours        json, -- Working hours of the configuration's rooms for utilization.
+	room_working_hours   json, -- Working hours overrides keyed by room email.
+	building_working_hours json, -- Working hours overrides keyed by building name.
+	read_only            boolean not null default false, -- Import only, never write to Exchange; for validating a migration.
+	import_overlap_policy text   not null default 'import', -- Whether to import ('import') or flag ('flag') Exchange bookings overlapping Eliona bookings.
+	cancel_policy        text    not null default 'cancel', -- How bookings cancelled in Eliona free their rooms: 'cancel', 'removeRooms' or 'declineAsRoom'.
+	room_names           json, -- Names of rooms in Eliona keyed by room email, overriding room_name_rules.
+	room_name_rules      json, -- Regex replacements turning Exchange room names into names in Eliona.
+	room_capacities      json, -- Number of people rooms seat, keyed by room email.
+	room_location_pattern text   not null default '', -- Regex with groups 'building' and 'floor' parsing rooms' office locations; empty keeps rooms directly under the root asset.
+	empty_booking_policy text    not null default 'cancel', -- Whether to cancel ('cancel') or keep ('keepEmpty') Eliona bookings left without rooms.
+	response_poll_interval integer not null default 0, -- Seconds between checks of room responses to pending bookings; 0 waits for the responses when booking.
+	booking_time_zone    text    not null default '', -- Time zone of wall-clock times sent by the Booking app as UTC; empty if they are absolute.
+	invitation_failure_limit integer not null default 0, -- Consecutive invitation delivery failures after which events are created without invitations; 0 never stops sending them.
+	missing_room_email_policy text not null default 'skip', -- Whether rooms whose asset lost its email are skipped ('skip') or get it back from their global asset ID ('repair').
+	subject_fallback     text    not null default '', -- Template composing subjects of bookings without one from {room}, {organizer}, {start} and {end}; empty for the default.
+	resource_subject     text    not null default '', -- Template composing the subjects shown in rooms' calendars for bookings from Eliona; empty shows the organizer's subject.
+	cancellation_body    text    not null default '', -- Template composing the message sent to attendees of events cancelled from Eliona; empty sends 'Cancelled via Eliona'.
+	appointment_category text    not null default '', -- Outlook category of events created for bookings from Eliona; empty sets none.
+	online_meetings      boolean not null default false, -- Events created for bookings from Eliona are online meetings, e.g. Teams.
+	max_attendees_per_request integer not null default 0, -- Attendees sent in one request when creating an event, the rest is added in batches; 0 for the default.
+	room_discovery       text    not null default 'roomList', -- Where rooms are discovered: the room list ('roomList'), an address list ('addressList') or room_emails ('static').
+	room_address_list_id text    not null default '', -- ID of the address list containing the rooms, for 'addressList' discovery.
+	room_emails          text[], -- Email addresses of the rooms, for 'static' discovery.
+	equipment_emails     text[], -- Email addresses of equipment mailboxes, e.g. projectors or cars, booked like rooms.
+	archive_removed_rooms boolean not null default false, -- Archive assets of rooms no longer discovered, so that they are no longer synchronized.
+	export_imports       boolean not null default false, -- Handle booking events caused by the app's own imports like bookings made in Eliona.
+	self_organized_policy text   not null default 'unattributed', -- Whether events organized by their room are imported without organizer ('unattributed') or not at all ('skip').
+	block_policy         text    not null default 'import', -- Whether events nobody is invited to are imported ('import'), imported without writing back their changes ('occupancyOnly') or not at all ('skip').
+	unknown_delete_policy text   not null default 'log', -- Whether deletions of items unknown to the app are only logged ('log') or the room is synchronized from scratch ('resync').
+	mirror_private_details boolean not null default false, -- Import the details of events marked private or confidential rather than only blocking the room.
+	change_detection     text    not null default 'polling', -- Whether rooms' calendars are synchronized every time ('polling') or only once a pull subscription reports changes ('pullSubscription').
+	recurrence_horizon_days integer not null default 365, -- Days ahead up to which occurrences of recurring events are synchronized.
+	reminder_minutes     integer -- Minutes before bookings from Eliona their organizers are reminded; 0 disables, null keeps the mailbox default.
 );
 
 create table if not exists ews.asset
@@ -47,8 +96,15 @@ create table if not exists ews.asset
 	project_id       text      not null,
 	global_asset_id  text      not null,
 	provider_id      text      not null,
+	name             text      not null default '', -- Name the asset was last given in Eliona; empty if created before names were tracked.
+	data_digest      text      not null default '', -- Digest of the data last set for the asset, to detect changed attributes; empty if unknown.
 	asset_id         integer,
-	sync_state       text      not null
+	sync_state       text      not null,
+	synced_at        timestamp with time zone, -- When the sync state was last persisted.
+	subscription_id  text      not null default '', -- Pull subscription to the room's calendar, for 'pullSubscription' change detection.
+	watermark        text      not null default '', -- Watermark up to which the subscription's events are synchronized.
+	expanded_until   timestamp with time zone, -- Up to when occurrences of the room's recurring events are synchronized; null until the room is synchronized.
+	archived_at      timestamp with time zone -- When the room was no longer discovered; null while it is.
 );
 
 create table if not exists ews.booking_group
@@ -57,7 +113,11 @@ create table if not exists ews.booking_group
 	id                         bigserial primary key,
 	exchange_uid               text unique, -- Unique identifier regardless of perspective; one event might be present in multiple mailboxes (i.e. more invited rooms)
 	exchange_organizer_mailbox text,
-	eliona_group_id            int unique
+	eliona_group_id            int unique,
+	state                      text not null default 'confirmed', -- 'pending_approval' until all rooms requiring approval respond, 'pending_response' until all rooms process the invitation, 'occupancy_only' if changes in Eliona are not written back
+	configuration_id           bigint, -- Configuration that created the booking in Exchange. Null for bookings imported from Exchange.
+	pending_since              timestamp with time zone, -- When the booking started waiting for the rooms' responses.
+	subject                    text not null default '' -- Subject of the event as booked in Eliona.
 );
 
 create table if not exists ews.booking_occurrence
@@ -67,6 +127,7 @@ create table if not exists ews.booking_occurrence
 	booking_group_id        bigserial not null references ews.booking_group(id) ON DELETE CASCADE,
 	exchange_instance_index int not null, -- Number in series that is used to address recurring events in series. 0 if not recurring.
 	eliona_booking_id       int unique,
+	location                text not null default '', -- Location of the occurrence as typed by the organizer.
 	UNIQUE (booking_group_id, exchange_instance_index)
 );
 
@@ -78,5 +139,43 @@ create table if not exists ews.room_booking
 	exchange_id           text unique -- Always from the resource's perspective
 );
 
+create table if not exists ews.room_item
+-- Item in a room's calendar without room booking of its own, so that its deletion isn't mistaken for one of an unknown item.
+(
+	id           bigserial primary key,
+	asset_id     bigint not null,
+	exchange_id  text   not null unique, -- Always from the resource's perspective
+	exchange_uid text   not null default '' -- UID of the series the item is the recurring master of; empty for items skipped by policy.
+);
+
+create table if not exists ews.audit_log
+-- Mutation performed by the app in Exchange, kept for compliance.
+(
+	id               bigserial primary key,
+	configuration_id bigint      not null,
+	created_at       timestamptz not null default now(),
+	action           text        not null, -- 'create', 'cancel', 'cancelOccurrence', 'updateAttendees', 'declineAsResource', 'resourceSubject' or 'reschedule'
+	organizer        text        not null default '',
+	rooms            text[],
+	exchange_uid     text        not null default '',
+	start_time       timestamptz,
+	end_time         timestamptz,
+	correlation_id   text        not null default '', -- Identifies the booking event the mutation was performed for; sent to Exchange as client-request-id.
+	error            text -- Null if the mutation succeeded.
+);
+
+create index if not exists audit_log_configuration_id_created_at on ews.audit_log (configuration_id, created_at);
+
+create table if not exists ews.resolved_address
+-- Distinguished name resolved to an SMTP address, caching ResolveNames across restarts.
+(
+	id               bigserial   primary key,
+	configuration_id bigint      not null,
+	dn               text        not null,
+	smtp             text        not null,
+	resolved_at      timestamptz not null default now(), -- Addresses resolved longer ago than their time to live are resolved again.
+	unique (configuration_id, dn)
+);
+
 -- Makes the new objects available for all other init steps
 commit;
@@ -0,0 +1,134 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package conf
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"ews/appdb"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+// encryptedPrefix marks credentials encrypted by encryptSecret. Credentials
+// stored without it are plaintext, as stored before encryption was enabled.
+const encryptedPrefix = "enc:v1:"
+
+var noSecretKeyWarning sync.Once
+
+// secretCipher returns the cipher keyed by APP_SECRET_KEY, nil if no key is
+// set.
+func secretCipher() (cipher.AEAD, error) {
+	secret := common.Getenv("APP_SECRET_KEY", "")
+	if secret == "" {
+		noSecretKeyWarning.Do(func() {
+			log.Warn("conf", "APP_SECRET_KEY is not set, credentials are stored in plaintext")
+		})
+		return nil, nil
+	}
+	// Any passphrase is turned into a key of the length of AES-256.
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptSecret encrypts the credential for storing it, keeping it as it is
+// if no key is set.
+func encryptSecret(plaintext string) (string, error) {
+	aead, err := secretCipher()
+	if err != nil || aead == nil || plaintext == "" {
+		return plaintext, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generating nonce: %v", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret decrypts the stored credential. Plaintext credentials are
+// returned as they are.
+func decryptSecret(stored string) (string, error) {
+	encoded, encrypted := strings.CutPrefix(stored, encryptedPrefix)
+	if !encrypted {
+		return stored, nil
+	}
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	if aead == nil {
+		return "", errors.New("credentials are encrypted, but APP_SECRET_KEY is not set")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted credentials")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("decrypting credentials: wrong APP_SECRET_KEY")
+	}
+	return string(plaintext), nil
+}
+
+// encryptStoredSecrets encrypts the plaintext credentials of a configuration
+// stored before a key was set. Failures are logged only, as the plaintext
+// credentials keep working.
+func encryptStoredSecrets(ctx context.Context, dbConfig *appdb.Configuration) {
+	updates := appdb.M{}
+	for column, stored := range map[string]*string{
+		appdb.ConfigurationColumns.ClientSecret: &dbConfig.ClientSecret,
+		appdb.ConfigurationColumns.Password:     &dbConfig.Password,
+		appdb.ConfigurationColumns.ProxyURL:     &dbConfig.ProxyURL,
+	} {
+		if *stored == "" || strings.HasPrefix(*stored, encryptedPrefix) {
+			continue
+		}
+		encrypted, err := encryptSecret(*stored)
+		if err != nil {
+			log.Error("conf", "encrypting stored credentials of config %d: %v", dbConfig.ID, err)
+			return
+		}
+		if encrypted == *stored {
+			// No key is set.
+			return
+		}
+		updates[column] = encrypted
+	}
+	if len(updates) == 0 {
+		return
+	}
+	if _, err := appdb.Configurations(
+		appdb.ConfigurationWhere.ID.EQ(dbConfig.ID),
+	).UpdateAllG(ctx, updates); err != nil {
+		log.Error("conf", "storing encrypted credentials of config %d: %v", dbConfig.ID, err)
+		return
+	}
+	log.Info("conf", "encrypted the stored credentials of config %d", dbConfig.ID)
+}
@@ -0,0 +1,88 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"ews/apiserver"
+	"ews/conf"
+	"ews/ews"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// diagnose checks the connection to Exchange of a stored configuration or of
+// credentials given as flags, and writes a report. It returns the exit code
+// of the app, which is non-zero if any check failed.
+func diagnose(args []string, out io.Writer) int {
+	config, err := diagnosedConfig(args, out)
+	if err != nil {
+		fmt.Fprintf(out, "diagnose: %v\n", err)
+		return 2
+	}
+	steps := ews.Diagnose(config)
+	writeDiagnosticReport(out, steps)
+	for _, step := range steps {
+		if step.Err != nil {
+			return 1
+		}
+	}
+	return 0
+}
+
+func diagnosedConfig(args []string, out io.Writer) (apiserver.Configuration, error) {
+	flags := flag.NewFlagSet("diagnose", flag.ContinueOnError)
+	flags.SetOutput(out)
+	configID := flags.Int64("config", 0, "ID of the stored configuration to check")
+	var config apiserver.Configuration
+	config.ClientId = flags.String("client-id", "", "OAuth client ID")
+	config.ClientSecret = flags.String("client-secret", "", "OAuth client secret")
+	config.TenantId = flags.String("tenant-id", "", "OAuth tenant ID")
+	config.EwsURL = flags.String("ews-url", "", "EWS URL for NTLM")
+	config.Username = flags.String("username", "", "username for NTLM")
+	config.Password = flags.String("password", "", "password for NTLM")
+	config.ServiceUserUPN = flags.String("service-user", "", "UPN of the impersonated service user")
+	config.RoomListUPN = flags.String("room-list", "", "UPN of the room list")
+	if err := flags.Parse(args); err != nil {
+		return apiserver.Configuration{}, err
+	}
+	if *configID == 0 {
+		return config, nil
+	}
+	stored, err := conf.GetConfig(context.Background(), *configID)
+	if errors.Is(err, conf.ErrBadRequest) {
+		return apiserver.Configuration{}, fmt.Errorf("configuration %d not found", *configID)
+	}
+	if err != nil {
+		return apiserver.Configuration{}, err
+	}
+	return *stored, nil
+}
+
+func writeDiagnosticReport(w io.Writer, steps []ews.DiagnosticStep) {
+	for _, step := range steps {
+		switch {
+		case step.Skipped:
+			fmt.Fprintf(w, "[SKIP] %s\n", step.Name)
+		case step.Err != nil:
+			fmt.Fprintf(w, "[FAIL] %s: %v\n", step.Name, step.Err)
+		default:
+			fmt.Fprintf(w, "[ OK ] %s: %s\n", step.Name, step.Detail)
+		}
+	}
+}
@@ -18,6 +18,8 @@ package eliona
 import (
 	"ews/apiserver"
 	"fmt"
+	"strings"
+	"time"
 
 	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v2"
 	"github.com/eliona-smart-building-assistant/go-eliona/asset"
@@ -45,6 +47,33 @@ func CreateAssets(config apiserver.Configuration, root asset.Root) (totalCreated
 	return totalCreated, nil
 }
 
+// NotifyBookingConflict notifies the configuration's user about a booking made
+// in Exchange which was not imported as it overlaps an Eliona booking.
+func NotifyBookingConflict(config apiserver.Configuration, roomEmail string, organizer string, start time.Time) error {
+	if config.UserId == nil {
+		log.Warn("eliona", "userID for config %v is nil", *config.Id)
+		return nil
+	}
+	for _, projectId := range *config.ProjectIDs {
+		_, _, err := client.NewClient().CommunicationAPI.
+			PostNotification(client.AuthenticationContext()).
+			Notification(
+				api.Notification{
+					User:      *config.UserId,
+					ProjectId: *api.NewNullableString(&projectId),
+					Message: *api.NewNullableTranslation(&api.Translation{
+						De: api.PtrString(fmt.Sprintf("Die Buchung von %s am %s im Raum %s überschneidet sich mit einer Eliona-Buchung und wurde nicht importiert.", organizer, start.Format(time.RFC3339), roomEmail)),
+						En: api.PtrString(fmt.Sprintf("The booking by %s at %s in room %s overlaps an Eliona booking and was not imported.", organizer, start.Format(time.RFC3339), roomEmail)),
+					}),
+				}).
+			Execute()
+		if err != nil {
+			return fmt.Errorf("posting booking conflict notification: %v", err)
+		}
+	}
+	return nil
+}
+
 func notifyUser(userId string, projectId string, assetsCreated int) error {
 	receipt, _, err := client.NewClient().CommunicationAPI.
 		PostNotification(client.AuthenticationContext()).
@@ -64,3 +93,54 @@ func notifyUser(userId string, projectId string, assetsCreated int) error {
 	}
 	return nil
 }
+
+// NotifySecretExpiry warns the configuration's user that its client secret
+// expires soon, or has expired.
+func NotifySecretExpiry(config apiserver.Configuration, expiresAt time.Time) error {
+	date := expiresAt.Format("2006-01-02")
+	return notifyConfigUser(config,
+		fmt.Sprintf("Das Client Secret der Microsoft Exchange App-Konfiguration %d läuft am %s ab. Bitte erneuern Sie es, damit die Synchronisation nicht unterbrochen wird.", *config.Id, date),
+		fmt.Sprintf("The client secret of Microsoft Exchange App configuration %d expires on %s. Please renew it before synchronization stops.", *config.Id, date))
+}
+
+// NotifyCredentialsUnhealthy tells the configuration's user that Exchange no
+// longer accepts its credentials.
+func NotifyCredentialsUnhealthy(config apiserver.Configuration, reason string) error {
+	return notifyConfigUser(config,
+		fmt.Sprintf("Die Zugangsdaten der Microsoft Exchange App-Konfiguration %d sind wahrscheinlich abgelaufen: %s", *config.Id, reason),
+		fmt.Sprintf("The credentials of Microsoft Exchange App configuration %d have likely expired: %s", *config.Id, reason))
+}
+
+// NotifyRoomsArchived tells the configuration's user which rooms were archived
+// as they are no longer discovered in Exchange.
+func NotifyRoomsArchived(config apiserver.Configuration, roomEmails []string) error {
+	rooms := strings.Join(roomEmails, ", ")
+	return notifyConfigUser(config,
+		fmt.Sprintf("Microsoft Exchange App hat %d Räume archiviert, die in Exchange nicht mehr gefunden werden: %s", len(roomEmails), rooms),
+		fmt.Sprintf("Microsoft Exchange App archived %d rooms no longer found in Exchange: %s", len(roomEmails), rooms))
+}
+
+func notifyConfigUser(config apiserver.Configuration, de, en string) error {
+	if config.UserId == nil {
+		log.Warn("eliona", "userID for config %v is nil", *config.Id)
+		return nil
+	}
+	for _, projectId := range *config.ProjectIDs {
+		_, _, err := client.NewClient().CommunicationAPI.
+			PostNotification(client.AuthenticationContext()).
+			Notification(
+				api.Notification{
+					User:      *config.UserId,
+					ProjectId: *api.NewNullableString(&projectId),
+					Message: *api.NewNullableTranslation(&api.Translation{
+						De: api.PtrString(de),
+						En: api.PtrString(en),
+					}),
+				}).
+			Execute()
+		if err != nil {
+			return fmt.Errorf("posting notification: %v", err)
+		}
+	}
+	return nil
+}
@@ -7,7 +7,9 @@ import (
 	"ews/model"
 	"fmt"
 
+	api "github.com/eliona-smart-building-assistant/go-eliona-api-client/v2"
 	"github.com/eliona-smart-building-assistant/go-eliona/asset"
+	"github.com/eliona-smart-building-assistant/go-eliona/client"
 	"github.com/eliona-smart-building-assistant/go-utils/log"
 )
 
@@ -40,3 +42,80 @@ func UpsertAssetData(config apiserver.Configuration, assets []model.Room) error
 	}
 	return nil
 }
+
+// RenameAsset changes the name of the asset in Eliona, keeping its other
+// properties.
+func RenameAsset(assetID int32, name string) error {
+	ast, _, err := client.NewClient().AssetsAPI.
+		GetAssetById(client.AuthenticationContext(), assetID).
+		Execute()
+	if err != nil {
+		return fmt.Errorf("getting asset %d: %v", assetID, err)
+	}
+	ast.Name = *api.NewNullableString(&name)
+	if _, err := asset.UpsertAsset(*ast); err != nil {
+		return fmt.Errorf("renaming asset %d: %v", assetID, err)
+	}
+	return nil
+}
+
+type roomConflictData struct {
+	BookingConflict int8 `eliona:"booking_conflict" subtype:"input"`
+}
+
+// UpsertBookingConflict sets whether a booking made in Exchange conflicts with
+// an Eliona booking in the room.
+func UpsertBookingConflict(assetID int32, conflict bool) error {
+	data := roomConflictData{}
+	if conflict {
+		data.BookingConflict = 1
+	}
+	if err := asset.UpsertAssetDataIfAssetExists(asset.Data{
+		AssetId:         assetID,
+		Data:            data,
+		ClientReference: ClientReference,
+	}); err != nil {
+		return fmt.Errorf("upserting booking conflict data: %v", err)
+	}
+	return nil
+}
+
+type roomBookableData struct {
+	Bookable int8 `eliona:"bookable" subtype:"property"`
+}
+
+// UpsertBookable sets whether the room can be booked in Eliona.
+func UpsertBookable(assetID int32, bookable bool) error {
+	data := roomBookableData{}
+	if bookable {
+		data.Bookable = 1
+	}
+	if err := asset.UpsertAssetDataIfAssetExists(asset.Data{
+		AssetId:         assetID,
+		Data:            data,
+		ClientReference: ClientReference,
+	}); err != nil {
+		return fmt.Errorf("upserting bookable data: %v", err)
+	}
+	return nil
+}
+
+type roomMeetingData struct {
+	OnlineMeeting int8 `eliona:"online_meeting" subtype:"input"`
+}
+
+// UpsertOnlineMeeting sets whether the meeting currently running in the room is hybrid.
+func UpsertOnlineMeeting(assetID int32, online bool) error {
+	data := roomMeetingData{}
+	if online {
+		data.OnlineMeeting = 1
+	}
+	if err := asset.UpsertAssetDataIfAssetExists(asset.Data{
+		AssetId:         assetID,
+		Data:            data,
+		ClientReference: ClientReference,
+	}); err != nil {
+		return fmt.Errorf("upserting online meeting data: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"container/list"
+	"ews/apiserver"
+	"ews/appdb"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+// DefaultAddressCacheSize is how many resolved addresses are kept per
+// configuration unless ADDRESS_CACHE_SIZE says otherwise.
+const DefaultAddressCacheSize = 1000
+
+// addressTTL is how long resolved addresses are used before being resolved
+// again, so that stale mappings eventually refresh.
+const addressTTL = 7 * 24 * time.Hour
+
+// addressCache maps distinguished names to SMTP addresses. Beyond its size,
+// the least recently used addresses are evicted.
+//
+// Once PersistAddresses is called, caches of stored configurations write
+// resolved addresses through to the database and are restored from it, so
+// that they survive restarts.
+type addressCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// order holds the addressEntry values, most recently used first.
+	order *list.List
+
+	configID int64
+	// store persists resolved addresses; nil keeps them in memory only.
+	store AddressStorer
+}
+
+type addressEntry struct {
+	name       string
+	smtp       string
+	resolvedAt time.Time
+}
+
+// Counters of all address caches since the app started.
+var addressCacheHits, addressCacheMisses, addressCacheEvictions atomic.Uint64
+
+func newAddressCache(size int) *addressCache {
+	return &addressCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+var addressCachesMu sync.Mutex
+var addressCaches = make(map[int64]*addressCache)
+
+// AddressLoader returns the newest addresses of a configuration resolved
+// since the given time, at most limit of them.
+type AddressLoader func(configID int64, since time.Time, limit int) ([]appdb.ResolvedAddress, error)
+
+// AddressStorer durably records a resolved address.
+type AddressStorer func(address appdb.ResolvedAddress) error
+
+var loadAddresses AddressLoader
+var storeAddress AddressStorer
+
+// PersistAddresses backs the address caches created from now on by durable
+// storage, restoring each from the addresses resolved before.
+func PersistAddresses(load AddressLoader, store AddressStorer) {
+	addressCachesMu.Lock()
+	defer addressCachesMu.Unlock()
+	loadAddresses, storeAddress = load, store
+}
+
+// addressCacheFor returns the address cache shared by all helpers of a
+// configuration. Helpers are created per operation, so it must outlive them.
+func addressCacheFor(config apiserver.Configuration) *addressCache {
+	if config.Id == nil {
+		return newAddressCache(addressCacheSize())
+	}
+	addressCachesMu.Lock()
+	defer addressCachesMu.Unlock()
+	cache, ok := addressCaches[*config.Id]
+	if !ok {
+		cache = newAddressCache(addressCacheSize())
+		cache.configID = *config.Id
+		cache.store = storeAddress
+		if loadAddresses != nil {
+			cache.restore(loadAddresses)
+		}
+		addressCaches[*config.Id] = cache
+	}
+	return cache
+}
+
+// restore fills the cache with the addresses resolved before the app
+// restarted. The oldest are added first, so that they are evicted first.
+func (c *addressCache) restore(load AddressLoader) {
+	addresses, err := load(c.configID, time.Now().Add(-addressTTL), c.size)
+	if err != nil {
+		// Addresses are resolved again as needed.
+		log.Warn("ews", "restoring address cache of config %d: %v", c.configID, err)
+		return
+	}
+	for i := len(addresses) - 1; i >= 0; i-- {
+		c.add(addresses[i].Dn, addresses[i].Smtp, addresses[i].ResolvedAt)
+	}
+}
+
+func addressCacheSize() int {
+	size, err := strconv.Atoi(common.Getenv("ADDRESS_CACHE_SIZE", strconv.Itoa(DefaultAddressCacheSize)))
+	if err != nil || size < 1 {
+		log.Warn("ews", "invalid ADDRESS_CACHE_SIZE; using %d", DefaultAddressCacheSize)
+		return DefaultAddressCacheSize
+	}
+	return size
+}
+
+func (c *addressCache) get(name string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[name]
+	if !ok {
+		addressCacheMisses.Add(1)
+		return "", false
+	}
+	entry := element.Value.(addressEntry)
+	if time.Since(entry.resolvedAt) >= addressTTL {
+		c.order.Remove(element)
+		delete(c.entries, name)
+		addressCacheMisses.Add(1)
+		return "", false
+	}
+	addressCacheHits.Add(1)
+	c.order.MoveToFront(element)
+	return entry.smtp, true
+}
+
+// put caches the address just resolved, writing it through to the store.
+func (c *addressCache) put(name, smtp string) {
+	resolvedAt := time.Now()
+	c.add(name, smtp, resolvedAt)
+	if c.store == nil {
+		return
+	}
+	if err := c.store(appdb.ResolvedAddress{ConfigurationID: c.configID, Dn: name, Smtp: smtp, ResolvedAt: resolvedAt}); err != nil {
+		// The address stays cached in memory until the app restarts.
+		log.Warn("ews", "storing resolved address of %s: %v", name, err)
+	}
+}
+
+func (c *addressCache) add(name, smtp string, resolvedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := addressEntry{name: name, smtp: smtp, resolvedAt: resolvedAt}
+	if element, ok := c.entries[name]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[name] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(addressEntry).name)
+		addressCacheEvictions.Add(1)
+	}
+}
+
+func (c *addressCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// AddressCacheStats is a snapshot of the effectiveness of the caches of
+// resolved addresses.
+type AddressCacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// Size is the number of addresses cached for all configurations.
+	Size int
+}
+
+// AddressCache returns the statistics of the address caches.
+func AddressCache() AddressCacheStats {
+	stats := AddressCacheStats{
+		Hits:      addressCacheHits.Load(),
+		Misses:    addressCacheMisses.Load(),
+		Evictions: addressCacheEvictions.Load(),
+	}
+	addressCachesMu.Lock()
+	defer addressCachesMu.Unlock()
+	for _, cache := range addressCaches {
+		stats.Size += cache.len()
+	}
+	return stats
+}
+
+// WriteAddressCacheMetrics writes the address cache statistics in the
+// Prometheus text format.
+func WriteAddressCacheMetrics(w io.Writer) {
+	stats := AddressCache()
+	fmt.Fprintln(w, "# HELP ews_address_cache_hits_total Distinguished names resolved from the address cache.")
+	fmt.Fprintln(w, "# TYPE ews_address_cache_hits_total counter")
+	fmt.Fprintf(w, "ews_address_cache_hits_total %d\n", stats.Hits)
+	fmt.Fprintln(w, "# HELP ews_address_cache_misses_total Distinguished names resolved by a ResolveNames request.")
+	fmt.Fprintln(w, "# TYPE ews_address_cache_misses_total counter")
+	fmt.Fprintf(w, "ews_address_cache_misses_total %d\n", stats.Misses)
+	fmt.Fprintln(w, "# HELP ews_address_cache_evictions_total Addresses evicted from full address caches.")
+	fmt.Fprintln(w, "# TYPE ews_address_cache_evictions_total counter")
+	fmt.Fprintf(w, "ews_address_cache_evictions_total %d\n", stats.Evictions)
+	fmt.Fprintln(w, "# HELP ews_address_cache_size Addresses cached for all configurations.")
+	fmt.Fprintln(w, "# TYPE ews_address_cache_size gauge")
+	fmt.Fprintf(w, "ews_address_cache_size %d\n", stats.Size)
+}
@@ -0,0 +1,95 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"crypto/rand"
+	"ews/appdb"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+	"github.com/volatiletech/null/v8"
+)
+
+// Actions recorded in the audit log.
+const (
+	AuditCreate            = "create"
+	AuditCancel            = "cancel"
+	AuditCancelOccurrence  = "cancelOccurrence"
+	AuditUpdateAttendees   = "updateAttendees"
+	AuditDeclineAsResource = "declineAsResource"
+	AuditResourceSubject   = "resourceSubject"
+	AuditReschedule        = "reschedule"
+)
+
+// Auditor durably records mutations performed in Exchange.
+type Auditor func(entry appdb.AuditLog) error
+
+// SetAuditor replaces the auditor recording the helper's mutations.
+func (h *EWSHelper) SetAuditor(auditor Auditor) {
+	h.auditor = auditor
+}
+
+// SetCorrelationID sets the ID of the booking event the helper acts upon. It
+// is recorded in the audit log and sent to Exchange as client-request-id.
+func (h *EWSHelper) SetCorrelationID(correlationID string) {
+	h.correlationID = correlationID
+}
+
+// NewCorrelationID returns a random ID in the GUID format Exchange expects for
+// client-request-id.
+func NewCorrelationID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Error("ews", "generating correlation ID: %v", err)
+		return ""
+	}
+	b[6] = b[6]&0x0f | 0x40 // Version 4
+	b[8] = b[8]&0x3f | 0x80 // Variant RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// audit records the mutation with its result. Failing to record it doesn't
+// fail the mutation.
+func (h *EWSHelper) audit(action, organizer string, rooms []string, exchangeUID string, start, end time.Time, err error) {
+	if h.auditor == nil {
+		return
+	}
+	entry := appdb.AuditLog{
+		ConfigurationID: h.configID,
+		Action:          action,
+		Organizer:       organizer,
+		ExchangeUID:     exchangeUID,
+		CorrelationID:   h.correlationID,
+	}
+	for _, room := range rooms {
+		entry.Rooms = append(entry.Rooms, strings.ToLower(room))
+	}
+	if !start.IsZero() {
+		entry.StartTime = null.TimeFrom(start)
+	}
+	if !end.IsZero() {
+		entry.EndTime = null.TimeFrom(end)
+	}
+	if err != nil {
+		entry.Error = null.StringFrom(err.Error())
+	}
+	if err := h.auditor(entry); err != nil {
+		log.Error("ews", "recording %s of event %s in audit log: %v", action, exchangeUID, err)
+	}
+}
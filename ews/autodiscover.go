@@ -0,0 +1,212 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// autodiscoverURLs are the endpoints asked for the EWS URL of a mailbox in
+// the domain, SOAP Autodiscover first and the legacy POX Autodiscover as a
+// fallback.
+func autodiscoverURLs(domain string) []string {
+	return []string{
+		fmt.Sprintf("https://autodiscover.%s/autodiscover/autodiscover.svc", domain),
+		fmt.Sprintf("https://autodiscover.%s/autodiscover/autodiscover.xml", domain),
+	}
+}
+
+// Autodiscover finds the EWS URL of the mailbox, authenticating with the
+// username and password. The internal URL is preferred over the external one,
+// as on-premises servers are typically reached from within the network.
+func Autodiscover(client *http.Client, email, username, password string) (string, error) {
+	_, domain, found := strings.Cut(email, "@")
+	if !found || domain == "" {
+		return "", fmt.Errorf("%q is not an email address", email)
+	}
+	return autodiscover(client, autodiscoverURLs(domain), email, username, password)
+}
+
+func autodiscover(client *http.Client, urls []string, email, username, password string) (string, error) {
+	var errs []string
+	for _, url := range urls {
+		var ewsURL string
+		var err error
+		if strings.HasSuffix(url, ".svc") {
+			ewsURL, err = soapAutodiscover(client, url, email, username, password)
+		} else {
+			ewsURL, err = poxAutodiscover(client, url, email, username, password)
+		}
+		if err == nil {
+			return ewsURL, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", url, err))
+	}
+	return "", fmt.Errorf("autodiscovering EWS URL of %s: %s", email, strings.Join(errs, "; "))
+}
+
+type getUserSettingsEnvelope struct {
+	Body struct {
+		GetUserSettingsResponseMessage struct {
+			Response struct {
+				ErrorCode     string `xml:"ErrorCode"`
+				ErrorMessage  string `xml:"ErrorMessage"`
+				UserResponses struct {
+					UserResponse struct {
+						ErrorCode    string `xml:"ErrorCode"`
+						ErrorMessage string `xml:"ErrorMessage"`
+						UserSettings struct {
+							UserSetting []struct {
+								Name  string `xml:"Name"`
+								Value string `xml:"Value"`
+							} `xml:"UserSetting"`
+						} `xml:"UserSettings"`
+					} `xml:"UserResponse"`
+				} `xml:"UserResponses"`
+			} `xml:"Response"`
+		} `xml:"GetUserSettingsResponseMessage"`
+	} `xml:"Body"`
+}
+
+func soapAutodiscover(client *http.Client, url, email, username, password string) (string, error) {
+	requestXML := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:a="http://schemas.microsoft.com/exchange/2010/Autodiscover"
+               xmlns:wsa="http://www.w3.org/2005/08/addressing"
+               xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+    <soap:Header>
+        <a:RequestedServerVersion>Exchange2013</a:RequestedServerVersion>
+        <wsa:Action>http://schemas.microsoft.com/exchange/2010/Autodiscover/Autodiscover/GetUserSettings</wsa:Action>
+        <wsa:To>%s</wsa:To>
+    </soap:Header>
+    <soap:Body>
+        <a:GetUserSettingsRequestMessage>
+            <a:Request>
+                <a:Users>
+                    <a:User>
+                        <a:Mailbox>%s</a:Mailbox>
+                    </a:User>
+                </a:Users>
+                <a:RequestedSettings>
+                    <a:Setting>InternalEwsUrl</a:Setting>
+                    <a:Setting>ExternalEwsUrl</a:Setting>
+                </a:RequestedSettings>
+            </a:Request>
+        </a:GetUserSettingsRequestMessage>
+    </soap:Body>
+</soap:Envelope>`, html.EscapeString(url), html.EscapeString(email))
+	responseXML, err := postAutodiscover(client, url, requestXML, username, password)
+	if err != nil {
+		return "", err
+	}
+	var env getUserSettingsEnvelope
+	if err := xml.Unmarshal(responseXML, &env); err != nil {
+		return "", fmt.Errorf("unmarshaling XML: %v", err)
+	}
+	response := env.Body.GetUserSettingsResponseMessage.Response
+	if response.ErrorCode != "NoError" {
+		return "", fmt.Errorf("%s - %s", response.ErrorCode, response.ErrorMessage)
+	}
+	user := response.UserResponses.UserResponse
+	if user.ErrorCode != "NoError" {
+		return "", fmt.Errorf("%s - %s", user.ErrorCode, user.ErrorMessage)
+	}
+	settings := make(map[string]string)
+	for _, setting := range user.UserSettings.UserSetting {
+		settings[setting.Name] = setting.Value
+	}
+	return preferInternal(settings["InternalEwsUrl"], settings["ExternalEwsUrl"])
+}
+
+type poxAutodiscoverResponse struct {
+	Response struct {
+		Error struct {
+			ErrorCode string `xml:"ErrorCode"`
+			Message   string `xml:"Message"`
+		} `xml:"Error"`
+		Account struct {
+			Protocol []struct {
+				Type   string `xml:"Type"`
+				EwsUrl string `xml:"EwsUrl"`
+			} `xml:"Protocol"`
+		} `xml:"Account"`
+	} `xml:"Response"`
+}
+
+func poxAutodiscover(client *http.Client, url, email, username, password string) (string, error) {
+	requestXML := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<Autodiscover xmlns="http://schemas.microsoft.com/exchange/autodiscover/outlook/requestschema/2006">
+    <Request>
+        <EMailAddress>%s</EMailAddress>
+        <AcceptableResponseSchema>http://schemas.microsoft.com/exchange/autodiscover/outlook/responseschema/2006a</AcceptableResponseSchema>
+    </Request>
+</Autodiscover>`, html.EscapeString(email))
+	responseXML, err := postAutodiscover(client, url, requestXML, username, password)
+	if err != nil {
+		return "", err
+	}
+	var response poxAutodiscoverResponse
+	if err := xml.Unmarshal(responseXML, &response); err != nil {
+		return "", fmt.Errorf("unmarshaling XML: %v", err)
+	}
+	if e := response.Response.Error; e.ErrorCode != "" {
+		return "", fmt.Errorf("%s - %s", e.ErrorCode, e.Message)
+	}
+	// EXCH is the protocol used within the network, EXPR from outside.
+	urls := make(map[string]string)
+	for _, protocol := range response.Response.Account.Protocol {
+		urls[protocol.Type] = protocol.EwsUrl
+	}
+	return preferInternal(urls["EXCH"], urls["EXPR"])
+}
+
+func preferInternal(internal, external string) (string, error) {
+	if internal != "" {
+		return internal, nil
+	}
+	if external != "" {
+		return external, nil
+	}
+	return "", errors.New("no EWS URL in response")
+}
+
+func postAutodiscover(client *http.Client, url, requestXML, username, password string) ([]byte, error) {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(requestXML))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	request.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	request.SetBasicAuth(username, password) // Needed for NTLM
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", response.StatusCode)
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	return body, nil
+}
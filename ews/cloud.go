@@ -0,0 +1,57 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	syncmodel "ews/model/sync"
+	"fmt"
+)
+
+// cloudEndpoints are the hosts of a Microsoft 365 cloud.
+type cloudEndpoints struct {
+	// login is the Entra ID authority issuing the tokens.
+	login string
+	// outlook serves EWS and is the audience of the tokens.
+	outlook string
+}
+
+var clouds = map[syncmodel.Cloud]cloudEndpoints{
+	syncmodel.CloudCommercial: {login: "login.microsoftonline.com", outlook: "outlook.office365.com"},
+	syncmodel.CloudGCCHigh:    {login: "login.microsoftonline.us", outlook: "outlook.office365.us"},
+	syncmodel.CloudDoD:        {login: "login.microsoftonline.us", outlook: "webmail.apps.mil"},
+	syncmodel.CloudChina:      {login: "login.chinacloudapi.cn", outlook: "partner.outlook.cn"},
+}
+
+// cloudEndpointsOf returns the endpoints of the cloud, the commercial cloud's
+// for unknown clouds.
+func cloudEndpointsOf(cloud syncmodel.Cloud) cloudEndpoints {
+	if endpoints, ok := clouds[cloud]; ok {
+		return endpoints
+	}
+	return clouds[syncmodel.CloudCommercial]
+}
+
+func (e cloudEndpoints) tokenURL(tenantID string) string {
+	return fmt.Sprintf("https://%s/%s/oauth2/v2.0/token", e.login, tenantID)
+}
+
+func (e cloudEndpoints) scope() string {
+	return fmt.Sprintf("https://%s/.default", e.outlook)
+}
+
+func (e cloudEndpoints) ewsURL() string {
+	return fmt.Sprintf("https://%s/EWS/Exchange.asmx", e.outlook)
+}
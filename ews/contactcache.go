@@ -0,0 +1,99 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"ews/apiserver"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contactTTL is how long resolved contact data of rooms is reused before
+// being looked up again, so that changes in the directory eventually show.
+const contactTTL = 24 * time.Hour
+
+// contactCache maps email addresses of rooms to their contact data. Rooms are
+// looked up on every discovery, while their directory entries rarely change.
+type contactCache struct {
+	mu      sync.Mutex
+	entries map[string]contactEntry
+}
+
+type contactEntry struct {
+	contact Contact
+	// missing is set for addresses without directory entry, so that they are
+	// not looked up on every discovery either.
+	missing    bool
+	resolvedAt time.Time
+}
+
+func newContactCache() *contactCache {
+	return &contactCache{entries: make(map[string]contactEntry)}
+}
+
+var contactCachesMu sync.Mutex
+var contactCaches = make(map[int64]*contactCache)
+
+// contactCacheFor returns the contact cache shared by all helpers of a
+// configuration.
+func contactCacheFor(config apiserver.Configuration) *contactCache {
+	if config.Id == nil {
+		return newContactCache()
+	}
+	contactCachesMu.Lock()
+	defer contactCachesMu.Unlock()
+	cache, ok := contactCaches[*config.Id]
+	if !ok {
+		cache = newContactCache()
+		contactCaches[*config.Id] = cache
+	}
+	return cache
+}
+
+// get returns the cached entry of the email address, unless it is unknown or
+// expired. Helpers built without a cache always miss.
+func (c *contactCache) get(email string, now time.Time) (contactEntry, bool) {
+	if c == nil {
+		return contactEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[strings.ToLower(email)]
+	if !ok || now.Sub(entry.resolvedAt) >= contactTTL {
+		return contactEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *contactCache) put(email string, contact Contact, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[strings.ToLower(email)] = contactEntry{contact: contact, resolvedAt: now}
+}
+
+// putMissing remembers that the email address has no directory entry.
+func (c *contactCache) putMissing(email string, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[strings.ToLower(email)] = contactEntry{missing: true, resolvedAt: now}
+}
@@ -0,0 +1,135 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"ews/apiserver"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+// unauthorizedLimit is the number of consecutive 401 responses after which
+// the credentials are considered expired. Single ones happen during the NTLM
+// handshake or when the server has a hiccup.
+const unauthorizedLimit = 3
+
+// CredentialState tells whether Exchange accepts the credentials of a
+// configuration.
+type CredentialState struct {
+	Healthy bool
+	// Reason explains why the credentials are unhealthy.
+	Reason string
+	// Since is when the credentials last became healthy or unhealthy.
+	Since time.Time
+}
+
+// credentialHealth follows the responses to the requests of a configuration.
+type credentialHealth struct {
+	mu           sync.Mutex
+	configID     int64
+	state        CredentialState
+	unauthorized int
+}
+
+var credentialsMu sync.Mutex
+var credentials = make(map[int64]*credentialHealth)
+
+// credentialsFor returns the credential health shared by all helpers of a
+// configuration.
+func credentialsFor(config apiserver.Configuration) *credentialHealth {
+	if config.Id == nil {
+		return nil
+	}
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	health, ok := credentials[*config.Id]
+	if !ok {
+		health = &credentialHealth{configID: *config.Id, state: CredentialState{Healthy: true, Since: time.Now()}}
+		credentials[*config.Id] = health
+	}
+	return health
+}
+
+// Credentials returns the credential state of each configuration that sent
+// requests since the app started.
+func Credentials() map[int64]CredentialState {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	states := make(map[int64]CredentialState, len(credentials))
+	for id, health := range credentials {
+		health.mu.Lock()
+		states[id] = health.state
+		health.mu.Unlock()
+	}
+	return states
+}
+
+// CredentialsOf returns the credential state of the configuration, healthy if
+// it sent no requests yet.
+func CredentialsOf(configID int64) CredentialState {
+	state, ok := Credentials()[configID]
+	if !ok {
+		return CredentialState{Healthy: true}
+	}
+	return state
+}
+
+// accepted notes that Exchange accepted the credentials.
+func (c *credentialHealth) accepted(now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unauthorized = 0
+	if !c.state.Healthy {
+		log.Info("ews", "credentials of configuration %d accepted again", c.configID)
+		c.state = CredentialState{Healthy: true, Since: now}
+	}
+}
+
+// rejected notes a 401 response. Credentials are unhealthy once the limit of
+// consecutive rejections is reached.
+func (c *credentialHealth) rejected(now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.unauthorized++
+	n := c.unauthorized
+	c.mu.Unlock()
+	if n >= unauthorizedLimit {
+		c.fail(fmt.Sprintf("Exchange rejected the credentials %d times in a row; the password has likely expired", n), now)
+	}
+}
+
+// fail marks the credentials as unhealthy.
+func (c *credentialHealth) fail(reason string, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state.Healthy {
+		log.Warn("ews", "credentials of configuration %d unhealthy: %s", c.configID, reason)
+		c.state.Since = now
+	}
+	c.state.Healthy = false
+	c.state.Reason = reason
+}
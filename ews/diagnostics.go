@@ -0,0 +1,213 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"encoding/xml"
+	"errors"
+	"ews/apiserver"
+	"ews/conf"
+	syncmodel "ews/model/sync"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// DiagnosticStep is the outcome of one step of Diagnose.
+type DiagnosticStep struct {
+	Name string
+	// Detail describes what was found, if the step succeeded.
+	Detail string
+	// Err is nil if the step succeeded.
+	Err error
+	// Skipped marks steps not run because an earlier step failed.
+	Skipped bool
+}
+
+const dialTimeout = 10 * time.Second
+
+// Diagnose checks step by step whether the configuration can connect to
+// Exchange, authenticate, impersonate its service users and discover its
+// rooms. Steps following a failed one are skipped, as their errors would only
+// repeat the cause.
+func Diagnose(config apiserver.Configuration) []DiagnosticStep {
+	var steps []DiagnosticStep
+	failed := false
+	run := func(name string, check func() (string, error)) {
+		if failed {
+			steps = append(steps, DiagnosticStep{Name: name, Skipped: true})
+			return
+		}
+		detail, err := check()
+		failed = err != nil
+		steps = append(steps, DiagnosticStep{Name: name, Detail: detail, Err: err})
+	}
+
+	run("credentials", func() (string, error) {
+		switch {
+		case filled(config.ClientId) && filled(config.ClientSecret) && filled(config.TenantId):
+			return fmt.Sprintf("OAuth for tenant %s", *config.TenantId), nil
+		case filled(config.Username) && filled(config.Password):
+			return fmt.Sprintf("NTLM as %s", *config.Username), nil
+		}
+		return "", errors.New("either client ID, client secret and tenant ID, or username and password must be set")
+	})
+	var h *EWSHelper
+	run("connectivity", func() (string, error) {
+		h = NewEWSHelper(config, conf.ReadServiceUserUPN(config))
+		if h.ewsURLErr != nil {
+			return "", fmt.Errorf("autodiscovering EWS URL: %w", h.ewsURLErr)
+		}
+		if filled(config.ProxyURL) {
+			detail, err := dial(*config.ProxyURL)
+			if err != nil {
+				return "", fmt.Errorf("proxy: %w", err)
+			}
+			return detail + " (proxy)", nil
+		}
+		return dial(h.EwsURL)
+	})
+	run("authentication", func() (string, error) {
+		if _, err := h.sendRequest(serverTimeZonesRequest); err != nil {
+			return "", err
+		}
+		return "EWS accepted the credentials", nil
+	})
+	users := []string{conf.ReadServiceUserUPN(config)}
+	if write := conf.WriteServiceUserUPN(config); write != users[0] {
+		users = append(users, write)
+	}
+	for _, user := range users {
+		user := user
+		run("impersonation of "+user, func() (string, error) {
+			return h.checkImpersonation(user)
+		})
+	}
+	run("room discovery", func() (string, error) {
+		discovery := conf.RoomDiscovery(config)
+		if discovery == syncmodel.RoomDiscoveryRoomList && len(conf.RoomListUPNs(config)) == 0 {
+			return "", errors.New("no room list configured")
+		}
+		root, err := h.GetAssets(config)
+		if err != nil {
+			return "", err
+		}
+		if len(root.Rooms) == 0 {
+			return "", fmt.Errorf("no rooms found by %s discovery", discovery)
+		}
+		return fmt.Sprintf("%d rooms found by %s discovery", len(root.Rooms), discovery), nil
+	})
+	return steps
+}
+
+// TestConnection checks that Exchange accepts the configuration's credentials
+// and the impersonation of its read service user. The check leaves no trace
+// in the credential health or caches of a stored configuration.
+func TestConnection(config apiserver.Configuration) error {
+	config.Id = nil
+	user := conf.ReadServiceUserUPN(config)
+	h := NewEWSHelper(config, user)
+	if h.ewsURLErr != nil {
+		return fmt.Errorf("autodiscovering EWS URL: %w", h.ewsURLErr)
+	}
+	if _, err := h.checkImpersonation(user); err != nil {
+		return fmt.Errorf("impersonating %s: %w", user, err)
+	}
+	return nil
+}
+
+// dial opens and closes a TCP connection to the host of the EWS or proxy URL.
+func dial(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", errors.New("invalid URL")
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	address := net.JoinHostPort(u.Hostname(), port)
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return "", fmt.Errorf("connecting to %s: %v", address, err)
+	}
+	conn.Close()
+	return fmt.Sprintf("reached %s", address), nil
+}
+
+// serverTimeZonesRequest needs no mailbox, so any SOAP answer to it proves
+// the credentials were accepted.
+const serverTimeZonesRequest = `
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"
+                  xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types"
+                  xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
+    <soapenv:Header>
+        <t:RequestServerVersion Version="Exchange2013_SP1"/>
+    </soapenv:Header>
+    <soapenv:Body>
+        <m:GetServerTimeZones ReturnFullTimeZoneData="false"/>
+    </soapenv:Body>
+</soapenv:Envelope>
+`
+
+type getFolderEnvelope struct {
+	Body struct {
+		GetFolderResponse struct {
+			ResponseMessages struct {
+				GetFolderResponseMessage struct {
+					ResponseClass string `xml:"ResponseClass,attr"`
+					ResponseCode  string `xml:"ResponseCode"`
+					MessageText   string `xml:"MessageText"`
+				} `xml:"GetFolderResponseMessage"`
+			} `xml:"ResponseMessages"`
+		} `xml:"GetFolderResponse"`
+	} `xml:"Body"`
+}
+
+// checkImpersonation opens the user's calendar impersonating the user.
+func (h *EWSHelper) checkImpersonation(user string) (string, error) {
+	if user == "" {
+		return "", errors.New("no service user configured")
+	}
+	requestXML, err := marshalRequest(impersonate(IdentityPrincipalName, user), getFolderRequest{
+		FolderShape: itemShape{BaseShape: "IdOnly"},
+		FolderID:    calendarOf(""),
+	})
+	if err != nil {
+		return "", err
+	}
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return "", err
+	}
+	var fault soapFault
+	if err := xml.Unmarshal(responseXML, &fault); err == nil && fault.Body.Fault.FaultCode != "" {
+		return "", fmt.Errorf("SOAP fault: %w", faultError(fault))
+	}
+	var env getFolderEnvelope
+	if err := xml.Unmarshal(responseXML, &env); err != nil {
+		return "", fmt.Errorf("unmarshaling XML: %v", err)
+	}
+	message := env.Body.GetFolderResponse.ResponseMessages.GetFolderResponseMessage
+	if err := parseResponseCode(message.ResponseClass, message.ResponseCode, message.MessageText); err != nil {
+		return "", fmt.Errorf("opening calendar: %w", err)
+	}
+	return "calendar opened", nil
+}
@@ -0,0 +1,83 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"errors"
+	"strings"
+)
+
+// EWSError is an error reported by Exchange, either as a SOAP fault or in a
+// response message. Match it against the sentinels with errors.Is, which
+// compares the response codes only.
+type EWSError struct {
+	ResponseCode string
+	Message      string
+}
+
+func (e *EWSError) Error() string {
+	if e.Message == "" {
+		return e.ResponseCode
+	}
+	return e.ResponseCode + " - " + e.Message
+}
+
+func (e *EWSError) Is(target error) bool {
+	if target == errConflict {
+		return e.ResponseCode == "ErrorIrresolvableConflict" || e.ResponseCode == "ErrorStaleObject"
+	}
+	t, ok := target.(*EWSError)
+	return ok && t.ResponseCode == e.ResponseCode
+}
+
+var (
+	ErrNonExistentMailbox = &EWSError{
+		ResponseCode: "ErrorNonExistentMailbox",
+		Message:      "the SMTP address has no mailbox associated with it within this Exchange server",
+	}
+	ErrOccurrenceOutOfRange = &EWSError{ResponseCode: "ErrorCalendarOccurrenceIndexIsOutOfRecurrenceRange"}
+	ErrOccurrenceDeleted    = &EWSError{ResponseCode: "ErrorCalendarOccurrenceIsDeletedFromRecurrence"}
+	ErrItemNotFound         = &EWSError{ResponseCode: "ErrorItemNotFound"}
+	ErrAccessDenied         = &EWSError{ResponseCode: "ErrorAccessDenied"}
+	ErrImpersonationDenied  = &EWSError{ResponseCode: "ErrorImpersonateUserDenied"}
+	ErrTimeoutExpired       = &EWSError{ResponseCode: "ErrorTimeoutExpired"}
+)
+
+// errConflict matches the response codes of updates conflicting with a
+// change made in the meantime.
+var errConflict = errors.New("item has been changed in the meantime")
+
+// parseResponseCode returns the error of a response message, nil if it
+// succeeded.
+func parseResponseCode(responseClass, responseCode, message string) error {
+	if responseClass == "Success" {
+		return nil
+	}
+	return &EWSError{ResponseCode: responseCode, Message: message}
+}
+
+// faultError returns the error of a SOAP fault. Faults without details are
+// identified by their fault code, e.g. a:ErrorNonExistentMailbox.
+func faultError(fault soapFault) error {
+	code, message := fault.Body.Fault.Detail.ResponseCode, fault.Body.Fault.Detail.Message
+	if code == "" {
+		code = fault.Body.Fault.FaultCode[strings.LastIndex(fault.Body.Fault.FaultCode, ":")+1:]
+	}
+	if message == "" {
+		message = fault.Body.Fault.FaultString
+	}
+	return &EWSError{ResponseCode: code, Message: message}
+}
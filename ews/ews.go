@@ -23,21 +23,29 @@ import (
 	"encoding/xml"
 	"errors"
 	"ews/apiserver"
+	"ews/conf"
 	"ews/model"
 	syncmodel "ews/model/sync"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/Azure/go-ntlmssp"
+	"github.com/eliona-smart-building-assistant/go-utils/common"
 	"github.com/eliona-smart-building-assistant/go-utils/log"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
 var ErrDeclined = errors.New("resource has declined invitation")
-var ErrNonExistentMailbox = errors.New("the SMTP address has no mailbox associated with it within this Exchange server")
+var ErrPendingApproval = errors.New("resource requiring approval has not responded yet")
+var ErrPendingResponse = errors.New("resource has not processed the invitation yet")
+var ErrReadOnly = errors.New("configuration is read-only")
 
 var errNotFound = errors.New("entity not found")
 
@@ -47,77 +55,324 @@ type EWSHelper struct {
 	username     string
 	password     string
 	serviceUser  string
-	addressCache map[string]string
+	addressCache *addressCache
+	contactCache *contactCache
+
+	// logSOAP enables logging of full request and response bodies at debug level.
+	logSOAP bool
+	// privacyMode redacts meeting contents (subjects, bodies, locations) from logged bodies.
+	privacyMode bool
+	// mirrorPrivateDetails keeps the details of private and confidential
+	// items, see redacts.
+	mirrorPrivateDetails bool
+	// readOnly refuses all writes to Exchange, logging them instead.
+	readOnly bool
+	// recurrenceHorizon bounds how far ahead recurring events are expanded.
+	recurrenceHorizon time.Duration
+
+	limiter RateLimiter
+	// ewsURLErr is why the EWS URL couldn't be autodiscovered.
+	ewsURLErr error
+	// invitations tracks delivery of meeting invitations of the configuration.
+	invitations *invitationDelivery
+	// credentials follows whether Exchange accepts the configuration's
+	// credentials.
+	credentials *credentialHealth
+	// subjects labels events without a subject.
+	subjects syncmodel.SubjectFallback
+	// cancellationBody composes the message sent to attendees of cancelled
+	// events, DefaultCancellationBody if the template is empty.
+	cancellationBody syncmodel.SubjectFallback
+
+	configID      int64
+	correlationID string
+	auditor       Auditor
 }
 
-// NewEWSHelper creates a new instance of EWSHelper with OAuth or NTLM authentication based on the provided configuration
+// DefaultRequestTimeout bounds requests to Exchange of configurations without
+// a request timeout.
+const DefaultRequestTimeout = 30 * time.Second
+
+// requestTimeout returns how long a request to Exchange may take, including
+// reading the response.
+func requestTimeout(config apiserver.Configuration) time.Duration {
+	if config.RequestTimeout != nil && *config.RequestTimeout > 0 {
+		return time.Duration(*config.RequestTimeout) * time.Second
+	}
+	return DefaultRequestTimeout
+}
+
+// NewEWSHelper returns an EWSHelper with OAuth or NTLM authentication based on
+// the provided configuration. Helpers of stored configurations are reused
+// until the configuration changes.
 func NewEWSHelper(config apiserver.Configuration, impersonationUser string) *EWSHelper {
+	if config.Id == nil {
+		return newEWSHelper(config, impersonationUser)
+	}
+	return cachedHelperFor(config, impersonationUser)
+}
+
+func newEWSHelper(config apiserver.Configuration, impersonationUser string) *EWSHelper {
 	var httpClient *http.Client
 	var ewsURL string
+	var ewsURLErr error
 	var username, password string
+	timeout := requestTimeout(config)
 
 	if filled(config.ClientId) && filled(config.ClientSecret) && filled(config.TenantId) {
 		// Use OAuth
+		endpoints := cloudEndpointsOf(conf.Cloud(config))
 		oauth2Config := clientcredentials.Config{
 			ClientID:     *config.ClientId,
 			ClientSecret: *config.ClientSecret,
-			TokenURL:     fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", *config.TenantId),
-			Scopes:       []string{"https://outlook.office365.com/.default"},
+			TokenURL:     endpoints.tokenURL(*config.TenantId),
+			Scopes:       []string{endpoints.scope()},
+		}
+		// Token requests are bounded by the same timeout and go through the
+		// same proxy.
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{
+			Transport: transportFor(config, ""),
+			Timeout:   timeout,
+		})
+		// Keep the transport adding the tokens, only the client gets the timeout.
+		httpClient = &http.Client{
+			Transport: oauth2Config.Client(ctx).Transport,
+			Timeout:   timeout,
 		}
-		httpClient = oauth2Config.Client(context.Background())
-		ewsURL = "https://outlook.office365.com/EWS/Exchange.asmx"
-	} else if filled(config.Username) && filled(config.Password) && filled(config.EwsURL) {
+		ewsURL = endpoints.ewsURL()
+	} else if filled(config.Username) && filled(config.Password) {
 		// Use NTLM
 		httpClient = &http.Client{
 			Transport: ntlmssp.Negotiator{
-				RoundTripper: &http.Transport{},
+				RoundTripper: transportFor(config, *config.Username),
 			},
+			Timeout: timeout,
 		}
-		ewsURL = *config.EwsURL
 		username = *config.Username
 		password = *config.Password
+		ewsURL = common.Val(config.EwsURL)
+		if ewsURL == "" {
+			ewsURL, ewsURLErr = discoverEwsURL(config, httpClient)
+		}
 	} else {
 		panic("Invalid configuration: either OAuth or NTLM credentials must be provided")
 	}
 
 	return &EWSHelper{
-		Client:       httpClient,
-		EwsURL:       ewsURL,
-		username:     username,
-		password:     password,
-		serviceUser:  impersonationUser,
-		addressCache: make(map[string]string),
+		Client:               httpClient,
+		EwsURL:               ewsURL,
+		ewsURLErr:            ewsURLErr,
+		username:             username,
+		password:             password,
+		serviceUser:          impersonationUser,
+		addressCache:         addressCacheFor(config),
+		contactCache:         contactCacheFor(config),
+		logSOAP:              common.Getenv("EWS_LOG_SOAP", "false") == "true",
+		privacyMode:          common.Getenv("PRIVACY_MODE", "false") == "true",
+		mirrorPrivateDetails: common.Val(config.MirrorPrivateDetails),
+		readOnly:             common.Val(config.ReadOnly),
+		recurrenceHorizon:    conf.RecurrenceHorizon(config),
+		limiter:              limiterFor(config),
+		invitations:          deliveryFor(config),
+		credentials:          credentialsFor(config),
+		subjects:             conf.SubjectFallback(config),
+		cancellationBody:     conf.CancellationBody(config),
+		configID:             common.Val(config.Id),
+		auditor:              conf.InsertAuditLog,
+	}
+}
+
+// discoverEwsURL autodiscovers the EWS URL from the service user's mailbox and
+// stores it in the configuration, so that it is discovered only once.
+func discoverEwsURL(config apiserver.Configuration, client *http.Client) (string, error) {
+	ewsURL, err := Autodiscover(client, conf.ReadServiceUserUPN(config), *config.Username, *config.Password)
+	if err != nil {
+		log.Error("ews", "no EWS URL configured and %v", err)
+		return "", err
 	}
+	log.Info("ews", "autodiscovered EWS URL %s", ewsURL)
+	if config.Id != nil {
+		if err := conf.SetEwsURL(*config.Id, ewsURL); err != nil {
+			log.Error("conf", "storing autodiscovered EWS URL of config %d: %v", *config.Id, err)
+		}
+	}
+	return ewsURL, nil
+}
+
+// SetRateLimiter replaces the limiter shared by the configuration's helpers.
+func (h *EWSHelper) SetRateLimiter(limiter RateLimiter) {
+	h.limiter = limiter
+}
+
+// refuseWrite logs the write that would have happened if the helper is
+// read-only.
+func (h *EWSHelper) refuseWrite(format string, args ...any) bool {
+	if !h.readOnly {
+		return false
+	}
+	log.Info("ews", "read-only configuration; would have "+format, args...)
+	return true
 }
 
 func filled(s *string) bool {
 	return s != nil && *s != ""
 }
 
-// sendRequest sends an HTTP request with the specified XML body and returns the response body
+// sendRequest sends an HTTP request with the specified XML body and returns the response body.
+// Requests throttled by Exchange are retried after the back-off it asks for.
 func (h *EWSHelper) sendRequest(xmlBody string) ([]byte, error) {
+	for attempt := 1; ; attempt++ {
+		response, responseBody, err := h.send(xmlBody)
+		if err != nil {
+			return nil, err
+		}
+		if backOff, throttled := throttling(response, responseBody); throttled {
+			if attempt > maxThrottleRetries {
+				return nil, fmt.Errorf("%w: giving up after %d retries", ErrThrottled, maxThrottleRetries)
+			}
+			log.Warn("ews", "throttled by Exchange (status %d); retrying in %v", response.StatusCode, backOff)
+			throttleSleep(backOff)
+			continue
+		}
+		if err := checkSOAPResponse(response, responseBody); err != nil {
+			return nil, err
+		}
+		return responseBody, nil
+	}
+}
+
+// send sends the request once, returning the response with its body read.
+func (h *EWSHelper) send(xmlBody string) (*http.Response, []byte, error) {
+	if h.ewsURLErr != nil {
+		return nil, nil, fmt.Errorf("no EWS URL: %w", h.ewsURLErr)
+	}
+	if h.limiter != nil {
+		if err := h.limiter.Wait(context.Background()); err != nil {
+			return nil, nil, fmt.Errorf("waiting for rate limiter: %w", err)
+		}
+	}
+
 	request, err := http.NewRequest("POST", h.EwsURL, bytes.NewBufferString(xmlBody))
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return nil, nil, fmt.Errorf("creating request: %w", err)
 	}
 
+	h.logBody("request", []byte(xmlBody))
+
 	request.Header.Add("Content-Type", "text/xml; charset=utf-8")
+	if h.correlationID != "" {
+		request.Header.Add("client-request-id", h.correlationID)
+		request.Header.Add("return-client-request-id", "true")
+	}
 	if h.username != "" && h.password != "" {
 		request.SetBasicAuth(h.username, h.password) // Needed for NTLM
 	}
 
 	response, err := h.Client.Do(request)
+	var tokenErr *oauth2.RetrieveError
+	if errors.As(err, &tokenErr) {
+		h.credentials.fail(fmt.Sprintf("acquiring OAuth token failed: %v", tokenErr), time.Now())
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return nil, nil, fmt.Errorf("sending request: no response within %v: %w", h.Client.Timeout, err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("sending request: %w", err)
+		return nil, nil, fmt.Errorf("sending request: %w", err)
 	}
 	defer response.Body.Close()
+	if response.StatusCode == http.StatusUnauthorized {
+		h.credentials.rejected(time.Now())
+	} else {
+		h.credentials.accepted(time.Now())
+	}
 
 	responseBody, err := io.ReadAll(response.Body)
 	if err != nil {
-		return nil, fmt.Errorf("reading response body: %w", err)
+		return nil, nil, fmt.Errorf("reading response body: %w", err)
+	}
+	h.logBody("response", responseBody)
+	return response, responseBody, nil
+}
+
+// ErrNotSOAP is returned when the server answers with something else than a
+// SOAP envelope, typically an HTML page of a proxy or authentication gateway.
+var ErrNotSOAP = errors.New("response is not a SOAP envelope")
+
+// maxSnippetLength is how many characters of a non-SOAP response are shown.
+const maxSnippetLength = 200
+
+// checkSOAPResponse makes sure the response is a SOAP envelope. Unmarshalling
+// anything else would silently produce empty results.
+func checkSOAPResponse(response *http.Response, body []byte) error {
+	contentType := response.Header.Get("Content-Type")
+	if !strings.Contains(strings.ToLower(contentType), "html") && rootElement(body) == "Envelope" {
+		return nil
+	}
+	snippet, _ := truncate(strings.TrimSpace(string(body)), maxSnippetLength)
+	return fmt.Errorf("%w (status %d, content type %q): %s", ErrNotSOAP, response.StatusCode, contentType, snippet)
+}
+
+// rootElement returns the local name of the first element in the document, or
+// an empty string if there is none.
+func rootElement(body []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local
+		}
+	}
+}
+
+// Elements whose content must never appear in logs.
+var credentialElements = []string{"Password", "ClientSecret", "Token", "BinarySecret", "Credentials"}
+
+// Elements describing the meeting itself, redacted in privacy mode.
+var privateElements = []string{"Subject", "Body", "NewBodyContent", "Location"}
+
+func elementRegexps(names []string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, 0, len(names))
+	for _, name := range names {
+		// Element with any (or no) namespace prefix and any attributes.
+		res = append(res, regexp.MustCompile(`(<(?:\w+:)?`+name+`(?:\s[^>]*)?>)[^<]*(</(?:\w+:)?`+name+`>)`))
+	}
+	return res
+}
+
+var credentialRegexps = elementRegexps(credentialElements)
+var privateRegexps = elementRegexps(privateElements)
+
+// logBody logs the SOAP body if enabled. Only bodies are logged, never the
+// headers, so the Authorization header (OAuth token or NTLM credentials) can't
+// leak this way.
+func (h *EWSHelper) logBody(direction string, body []byte) {
+	if !h.logSOAP || log.Lev() < log.DebugLevel {
+		return
+	}
+	log.Debug("ews-soap", "%s %s:\n%s", direction, h.EwsURL, redactBody(body, h.privacyMode))
+}
+
+func redactBody(body []byte, privacyMode bool) []byte {
+	for _, re := range credentialRegexps {
+		body = re.ReplaceAll(body, []byte("${1}[REDACTED]${2}"))
+	}
+	if privacyMode {
+		for _, re := range privateRegexps {
+			body = re.ReplaceAll(body, []byte("${1}[REDACTED]${2}"))
+		}
 	}
+	return body
+}
 
-	return responseBody, nil
+// Redact removes credentials and meeting details from SOAP fragments quoted
+// in s, e.g. by errors stored in the audit log.
+func Redact(s string) string {
+	return string(redactBody([]byte(s), true))
 }
 
 type soapFault struct {
@@ -163,49 +418,102 @@ type roomId struct {
 	// MailboxType  string `xml:"MailboxType"`
 }
 
+// discoveredRoom is a room found by one of the room discovery methods.
+type discoveredRoom struct {
+	Name  string
+	Email string
+}
+
+// GetAssets discovers the configuration's rooms, and its equipment. All
+// discovery methods result in the same asset tree.
 func (h *EWSHelper) GetAssets(config apiserver.Configuration) (model.Root, error) {
-	// We might fetch also all room lists and include them into asset tree, but
-	// one room might belong to multiple room lists, which would make full
-	// Eliona mapping impossible. So let's give the user opprotunity to specify
-	// one room list to be synced from Exchange to Eliona.
-	requestXML := fmt.Sprintf(`
-<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"
-                  xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types"
-                  xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
-    <soapenv:Header>
-        <t:RequestServerVersion Version="Exchange2013_SP1"/>
-        <t:ExchangeImpersonation>
-            <t:ConnectingSID>
-                <t:PrincipalName>%s</t:PrincipalName>
-            </t:ConnectingSID>
-        </t:ExchangeImpersonation>
-    </soapenv:Header>
-    <soapenv:Body>
-        <m:GetRooms>
-            <m:RoomList>
-                <t:EmailAddress>%s</t:EmailAddress>
-           </m:RoomList>
-        </m:GetRooms>
-    </soapenv:Body>
-</soapenv:Envelope>
-`, h.serviceUser, *config.RoomListUPN)
-	responseXML, err := h.sendRequest(requestXML)
+	var rooms []discoveredRoom
+	var source string
+	var err error
+	switch conf.RoomDiscovery(config) {
+	case syncmodel.RoomDiscoveryAddressList:
+		source = "address list " + common.Val(config.RoomAddressListID)
+		rooms, err = h.addressListRooms(common.Val(config.RoomAddressListID))
+	case syncmodel.RoomDiscoveryStatic:
+		source = "configured room emails"
+		rooms = staticRooms(common.Val(config.RoomEmails))
+	default:
+		upns := conf.RoomListUPNs(config)
+		source = "room lists " + strings.Join(upns, ", ")
+		rooms, err = h.roomListsRooms(upns)
+	}
 	if err != nil {
-		return model.Root{}, fmt.Errorf("requesting rooms: %v", err)
+		return model.Root{}, err
 	}
 
-	var env roomsEnvelope
-	if err := xml.Unmarshal(responseXML, &env); err != nil {
-		return model.Root{}, fmt.Errorf("unmarshaling XML: %v", err)
+	displayName, err := conf.RoomDisplayNamer(config)
+	if err != nil {
+		return model.Root{}, fmt.Errorf("naming rooms: %v", err)
 	}
-
-	xmlRooms := env.Body.GetRoomsResponse.Rooms.Rooms
-	modelRooms := make([]model.Room, 0, len(xmlRooms))
-	for _, room := range xmlRooms {
+	locations, err := conf.RoomLocationPattern(config)
+	if err != nil {
+		return model.Root{}, fmt.Errorf("locating rooms: %v", err)
+	}
+	modelRooms := make([]model.Room, 0, len(rooms))
+	for _, room := range rooms {
+		if room.Email == "" {
+			// Orphaned objects in the room list have no mailbox. There is
+			// nothing to book on them and they cannot be identified.
+			log.Warn("ews", "skipping room '%s' without email address in %s", room.Name, source)
+			continue
+		}
+		name := room.Name
+		if name == "" {
+			name = room.Email
+		}
+		contact, err := h.ResolveContact(room.Email)
+		if err != nil {
+			// The room is still usable, just without contact data and not
+			// grouped.
+			log.Warn("ews", "resolving contact data of room %s: %v", room.Email, err)
+		}
+		// Unlike a missing directory entry, a failed lookup says nothing
+		// about the room's contact data.
+		contactUnknown := err != nil && !errors.Is(err, ErrNonExistentMailbox)
+		var building, floor string
+		if locations.Enabled() {
+			building, floor = locations.Locate(contact.Office)
+		}
+		modelRooms = append(modelRooms, model.Room{
+			Email:       room.Email,
+			Name:        name,
+			PhoneNumber: contact.PhoneNumber,
+			Office:      contact.Office,
+			Department:  contact.Department,
+			DisplayName: displayName(room.Email, name),
+			Capacity:    conf.RoomCapacity(config, room.Email),
+			Building:    building,
+			Floor:       floor,
+			Config:      config,
+
+			ContactUnknown: contactUnknown,
+		})
+	}
+	for _, equipment := range staticRooms(common.Val(config.EquipmentEmails)) {
+		if containsRoom(modelRooms, equipment.Email) {
+			log.Warn("ews", "skipping equipment %s discovered as room", equipment.Email)
+			continue
+		}
+		contact, err := h.ResolveContact(equipment.Email)
+		if err != nil {
+			log.Warn("ews", "resolving contact data of equipment %s: %v", equipment.Email, err)
+		}
 		modelRooms = append(modelRooms, model.Room{
-			Email:  room.Id.EmailAddress,
-			Name:   room.Id.Name,
-			Config: config,
+			Email:       equipment.Email,
+			Name:        equipment.Name,
+			PhoneNumber: contact.PhoneNumber,
+			Office:      contact.Office,
+			Department:  contact.Department,
+			DisplayName: displayName(equipment.Email, equipment.Name),
+			Equipment:   true,
+			Config:      config,
+
+			ContactUnknown: err != nil && !errors.Is(err, ErrNonExistentMailbox),
 		})
 	}
 	return model.Root{
@@ -214,6 +522,261 @@ func (h *EWSHelper) GetAssets(config apiserver.Configuration) (model.Root, error
 	}, nil
 }
 
+func containsRoom(rooms []model.Room, email string) bool {
+	for _, room := range rooms {
+		if strings.EqualFold(room.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contact is the contact data of a mailbox's directory entry.
+type Contact struct {
+	PhoneNumber string
+	Office      string
+	Department  string
+}
+
+// ResolveContact returns the contact data of the mailbox from its directory
+// entry, ErrNonExistentMailbox if it has none. Both are cached per
+// configuration for a day, failed lookups are not.
+func (h *EWSHelper) ResolveContact(email string) (Contact, error) {
+	if entry, found := h.contactCache.get(email, time.Now()); found {
+		if entry.missing {
+			return Contact{}, ErrNonExistentMailbox
+		}
+		return entry.contact, nil
+	}
+	requestXML, err := marshalRequest(impersonate(IdentityPrincipalName, h.serviceUser), resolveNamesRequest{
+		ReturnFullContactData: true,
+		SearchScope:           "ActiveDirectory",
+		UnresolvedEntry:       email,
+	})
+	if err != nil {
+		return Contact{}, err
+	}
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return Contact{}, fmt.Errorf("resolving contact: %v", err)
+	}
+	var fault soapFault
+	if err := xml.Unmarshal(responseXML, &fault); err == nil && fault.Body.Fault.FaultCode != "" {
+		return Contact{}, fmt.Errorf("SOAP fault: %w", faultError(fault))
+	}
+	var resp resolveNamesResponse
+	if err := xml.Unmarshal(responseXML, &resp); err != nil {
+		return Contact{}, fmt.Errorf("unmarshaling XML: %v", err)
+	}
+	for _, message := range resp.Body.ResolveNamesResponse.ResponseMessages.ResolveNamesResponseMessage {
+		// Names without any match are reported as error, though that is
+		// just as definite as a list of other matches.
+		if message.ResponseCode == "ErrorNameResolutionNoResults" {
+			continue
+		}
+		if err := parseResponseCode(message.ResponseClass, message.ResponseCode, message.MessageText); err != nil {
+			return Contact{}, fmt.Errorf("resolving contact: %w", err)
+		}
+		for _, resolution := range message.ResolutionSet.Resolution {
+			if !strings.EqualFold(resolution.Mailbox.EmailAddress, email) {
+				continue
+			}
+			contact := Contact{
+				Office:     resolution.Contact.OfficeLocation,
+				Department: resolution.Contact.Department,
+			}
+			for _, phone := range resolution.Contact.PhoneNumbers.Entry {
+				// The business phone is the room's own, other numbers are
+				// only used if it has none.
+				if phone.Number != "" && (phone.Key == "BusinessPhone" || contact.PhoneNumber == "") {
+					contact.PhoneNumber = phone.Number
+				}
+			}
+			h.contactCache.put(email, contact, time.Now())
+			return contact, nil
+		}
+	}
+	h.contactCache.putMissing(email, time.Now())
+	return Contact{}, ErrNonExistentMailbox
+}
+
+// roomListsRooms returns the rooms of the room lists. Rooms belonging to
+// more than one of the lists are returned once, as each room maps to one
+// asset in Eliona.
+func (h *EWSHelper) roomListsRooms(roomListUPNs []string) ([]discoveredRoom, error) {
+	var rooms []discoveredRoom
+	var seen []string
+	for _, roomListUPN := range roomListUPNs {
+		listRooms, err := h.roomListRooms(roomListUPN)
+		if err != nil {
+			return nil, fmt.Errorf("room list %s: %w", roomListUPN, err)
+		}
+		for _, room := range listRooms {
+			if room.Email != "" {
+				if containsFold(seen, room.Email) {
+					continue
+				}
+				seen = append(seen, room.Email)
+			}
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms, nil
+}
+
+// roomListRooms returns the rooms of the room list.
+func (h *EWSHelper) roomListRooms(roomListUPN string) ([]discoveredRoom, error) {
+	requestXML, err := marshalRequest(impersonate(IdentityPrincipalName, h.serviceUser), getRoomsRequest{RoomList: roomListUPN})
+	if err != nil {
+		return nil, err
+	}
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return nil, fmt.Errorf("requesting rooms: %v", err)
+	}
+
+	var env roomsEnvelope
+	if err := xml.Unmarshal(responseXML, &env); err != nil {
+		return nil, fmt.Errorf("unmarshaling XML: %v", err)
+	}
+	var rooms []discoveredRoom
+	for _, room := range env.Body.GetRoomsResponse.Rooms.Rooms {
+		rooms = append(rooms, discoveredRoom{Name: room.Id.Name, Email: room.Id.EmailAddress})
+	}
+	return rooms, nil
+}
+
+// RoomList is a room list defined in Exchange.
+type RoomList struct {
+	Name  string
+	Email string
+}
+
+type roomListsEnvelope struct {
+	Body struct {
+		GetRoomListsResponse struct {
+			ResponseClass string `xml:"ResponseClass,attr"`
+			ResponseCode  string `xml:"ResponseCode"`
+			MessageText   string `xml:"MessageText"`
+			RoomLists     struct {
+				Addresses []struct {
+					Name         string `xml:"Name"`
+					EmailAddress string `xml:"EmailAddress"`
+				} `xml:"Address"`
+			} `xml:"RoomLists"`
+		} `xml:"GetRoomListsResponse"`
+	} `xml:"Body"`
+}
+
+// GetRoomLists returns the room lists defined in the organization, none if
+// the organization defines no room lists.
+func (h *EWSHelper) GetRoomLists() ([]RoomList, error) {
+	requestXML, err := marshalRequest(impersonate(IdentityPrincipalName, h.serviceUser), getRoomListsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return nil, fmt.Errorf("requesting room lists: %v", err)
+	}
+	var fault soapFault
+	if err := xml.Unmarshal(responseXML, &fault); err == nil && fault.Body.Fault.FaultCode != "" {
+		return nil, fmt.Errorf("SOAP fault: %w", faultError(fault))
+	}
+	var env roomListsEnvelope
+	if err := xml.Unmarshal(responseXML, &env); err != nil {
+		return nil, fmt.Errorf("unmarshaling XML: %v", err)
+	}
+	response := env.Body.GetRoomListsResponse
+	if err := parseResponseCode(response.ResponseClass, response.ResponseCode, response.MessageText); err != nil {
+		return nil, fmt.Errorf("getting room lists: %w", err)
+	}
+	roomLists := []RoomList{}
+	for _, address := range response.RoomLists.Addresses {
+		roomLists = append(roomLists, RoomList{Name: address.Name, Email: address.EmailAddress})
+	}
+	return roomLists, nil
+}
+
+// addressListPageSize is the number of rooms requested at once from an
+// address list.
+const addressListPageSize = 100
+
+type findPeopleEnvelope struct {
+	Body struct {
+		FindPeopleResponse struct {
+			ResponseClass string `xml:"ResponseClass,attr"`
+			ResponseCode  string `xml:"ResponseCode"`
+			MessageText   string `xml:"MessageText"`
+			People        struct {
+				Persona []struct {
+					DisplayName  string `xml:"DisplayName"`
+					EmailAddress struct {
+						EmailAddress string `xml:"EmailAddress"`
+					} `xml:"EmailAddress"`
+				} `xml:"Persona"`
+			} `xml:"People"`
+			TotalNumberOfPeopleInView int `xml:"TotalNumberOfPeopleInView"`
+		} `xml:"FindPeopleResponse"`
+	} `xml:"Body"`
+}
+
+// addressListRooms returns the rooms of the address list, page by page.
+func (h *EWSHelper) addressListRooms(addressListID string) ([]discoveredRoom, error) {
+	var rooms []discoveredRoom
+	for offset := 0; ; offset += addressListPageSize {
+		requestXML, err := marshalRequest(impersonate(IdentityPrincipalName, h.serviceUser), findPeopleRequest{
+			PersonaShape: itemShape{
+				BaseShape:            "IdOnly",
+				AdditionalProperties: &additionalProperties{FieldURIs: fieldURIs("persona:DisplayName", "persona:EmailAddress")},
+			},
+			IndexedPageItemView: indexedPageItemView{BasePoint: "Beginning", MaxEntriesReturned: addressListPageSize, Offset: offset},
+			AddressListID:       requestItemID{ID: addressListID},
+		})
+		if err != nil {
+			return nil, err
+		}
+		responseXML, err := h.sendRequest(requestXML)
+		if err != nil {
+			return nil, fmt.Errorf("requesting rooms of address list: %v", err)
+		}
+		var fault soapFault
+		if err := xml.Unmarshal(responseXML, &fault); err == nil && fault.Body.Fault.FaultCode != "" {
+			return nil, fmt.Errorf("SOAP fault: %w", faultError(fault))
+		}
+		var env findPeopleEnvelope
+		if err := xml.Unmarshal(responseXML, &env); err != nil {
+			return nil, fmt.Errorf("unmarshaling XML: %v", err)
+		}
+		response := env.Body.FindPeopleResponse
+		if err := parseResponseCode(response.ResponseClass, response.ResponseCode, response.MessageText); err != nil {
+			return nil, fmt.Errorf("finding rooms in address list %s: %w", addressListID, err)
+		}
+		for _, persona := range response.People.Persona {
+			rooms = append(rooms, discoveredRoom{Name: persona.DisplayName, Email: persona.EmailAddress.EmailAddress})
+		}
+		if len(response.People.Persona) == 0 || offset+len(response.People.Persona) >= response.TotalNumberOfPeopleInView {
+			return rooms, nil
+		}
+	}
+}
+
+// staticRooms returns the configured rooms, named by their email addresses
+// unless roomNames names them. Duplicates and blank entries are dropped.
+func staticRooms(emails []string) []discoveredRoom {
+	rooms := make([]discoveredRoom, 0, len(emails))
+	var seen []string
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		if email == "" || containsFold(seen, email) {
+			continue
+		}
+		seen = append(seen, email)
+		rooms = append(rooms, discoveredRoom{Name: email, Email: email})
+	}
+	return rooms
+}
+
 type roomEventsEnvelope struct {
 	XMLName xml.Name       `xml:"Envelope"`
 	Body    roomEventsBody `xml:"Body"`
@@ -240,27 +803,143 @@ type syncFolderItemsResponseMessage struct {
 type changes struct {
 	Create []createOrUpdate `xml:"Create"`
 	Update []createOrUpdate `xml:"Update"`
-	Delete []delete         `xml:"Delete"`
+	Delete []deleted        `xml:"Delete"`
 }
 
 type createOrUpdate struct {
 	CalendarItem *calendarItem `xml:"CalendarItem"`
 }
 
-type delete struct {
+type deleted struct {
 	ItemId itemId `xml:"ItemId"`
 }
 
+// exchangeTime is a time returned by Exchange, normalized to UTC. Requests ask
+// for times in UTC, so times without an offset are taken as UTC as well.
+type exchangeTime struct {
+	time.Time
+}
+
+func (t *exchangeTime) UnmarshalText(text []byte) error {
+	parsed, err := time.Parse(time.RFC3339, string(text))
+	if err != nil {
+		var floatingErr error
+		if parsed, floatingErr = time.Parse("2006-01-02T15:04:05", string(text)); floatingErr != nil {
+			return err
+		}
+	}
+	t.Time = parsed.UTC()
+	return nil
+}
+
 type calendarItem struct {
 	ItemId           itemId `xml:"ItemId"`
 	UID              string `xml:"UID"`
 	InstanceIndex    int
-	Subject          string    `xml:"Subject"`
-	DateTimeReceived string    `xml:"DateTimeReceived"`
-	Start            time.Time `xml:"Start"`
-	End              time.Time `xml:"End"`
+	Subject          string       `xml:"Subject"`
+	DateTimeReceived string       `xml:"DateTimeReceived"`
+	Start            exchangeTime `xml:"Start"`
+	End              exchangeTime `xml:"End"`
+	Location         string       `xml:"Location"`
+	Sensitivity      string       `xml:"Sensitivity"`
+	// StartTimeZone is the Windows time zone the item was scheduled in.
+	StartTimeZone struct {
+		ID string `xml:"Id,attr"`
+	} `xml:"StartTimeZone"`
 	Organizer        organizer `xml:"Organizer"`
 	CalendarItemType string    `xml:"CalendarItemType"`
+	IsAllDayEvent    bool      `xml:"IsAllDayEvent"`
+	// Not exposed by all servers; missing elements are treated as not online.
+	IsOnlineMeeting      bool     `xml:"IsOnlineMeeting"`
+	JoinOnlineMeetingUrl string   `xml:"JoinOnlineMeetingUrl"`
+	Categories           []string `xml:"Categories>String"`
+	// Eliona ID the app tagged the event with, zero for events not created
+	// by the app.
+	ElionaTag struct {
+		Value int32 `xml:"Value"`
+	} `xml:"ExtendedProperty"`
+	// IsMeeting and the attendees tell meetings from blocks. Nil if the
+	// server didn't say.
+	IsMeeting *bool `xml:"IsMeeting"`
+	eventAttendees
+	// OriginalStart is the start of an occurrence according to the pattern
+	// of its series.
+	OriginalStart exchangeTime `xml:"OriginalStart"`
+	recurrenceExceptions
+}
+
+// recurrenceExceptions are the occurrences of a recurring master deviating
+// from the pattern of its series.
+type recurrenceExceptions struct {
+	ModifiedOccurrences []modifiedOccurrence `xml:"ModifiedOccurrences>Occurrence"`
+	DeletedOccurrences  []struct {
+		Start exchangeTime `xml:"Start"`
+	} `xml:"DeletedOccurrences>DeletedOccurrence"`
+}
+
+type modifiedOccurrence struct {
+	ItemId        itemId       `xml:"ItemId"`
+	Start         exchangeTime `xml:"Start"`
+	End           exchangeTime `xml:"End"`
+	OriginalStart exchangeTime `xml:"OriginalStart"`
+}
+
+// modified returns the modification of the occurrence, matched by its ID or
+// its original start.
+func (e recurrenceExceptions) modified(occurrence calendarItem) (modifiedOccurrence, bool) {
+	for _, modified := range e.ModifiedOccurrences {
+		if modified.ItemId.Id == occurrence.ItemId.Id ||
+			!occurrence.OriginalStart.IsZero() && modified.OriginalStart.Equal(occurrence.OriginalStart.Time) {
+			return modified, true
+		}
+	}
+	return modifiedOccurrence{}, false
+}
+
+// deleted reports whether the unmodified occurrence was deleted from its
+// series.
+func (e recurrenceExceptions) deleted(occurrence calendarItem) bool {
+	for _, deleted := range e.DeletedOccurrences {
+		if deleted.Start.Equal(occurrence.Start.Time) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply reconciles the expanded occurrences with the exceptions: modified
+// occurrences take their actual times, deleted ones are dropped and each
+// occurrence is kept once.
+func (e recurrenceExceptions) apply(occurrences []calendarItem) []calendarItem {
+	var reconciled []calendarItem
+	seen := make(map[string]bool)
+	for _, occurrence := range occurrences {
+		if seen[occurrence.ItemId.Id] {
+			continue
+		}
+		seen[occurrence.ItemId.Id] = true
+		if modified, ok := e.modified(occurrence); ok {
+			occurrence.Start, occurrence.End = modified.Start, modified.End
+		} else if e.deleted(occurrence) {
+			continue
+		}
+		reconciled = append(reconciled, occurrence)
+	}
+	return reconciled
+}
+
+func (item calendarItem) isOnline() bool {
+	return item.IsOnlineMeeting || item.JoinOnlineMeetingUrl != ""
+}
+
+// isBlock reports whether the item is an appointment nobody is invited to,
+// like a block put directly in the room's calendar. Copies of meetings in a
+// room's calendar have at least the room as an attendee.
+func (item calendarItem) isBlock() bool {
+	if item.IsMeeting != nil && *item.IsMeeting {
+		return false
+	}
+	return len(item.RequiredAttendees.Attendee)+len(item.OptionalAttendees.Attendee)+len(item.Resources.Attendee) == 0
 }
 
 type itemId struct {
@@ -277,100 +956,82 @@ type mailbox struct {
 	EmailAddress string `xml:"EmailAddress"` // This might be either email address, or Legacy DN.
 }
 
-func (h *EWSHelper) GetRoomAppointments(assetID int32, roomEmail string, syncState string) (new []syncmodel.BookingGroup, updated []syncmodel.BookingGroup, cancelled []string, newSyncState string, err error) {
+// GetRoomAppointments gets a page of changes in the room's calendar since the
+// sync state. Last is false if there are more changes to be fetched.
+func (h *EWSHelper) GetRoomAppointments(assetID int32, roomEmail string, syncState string) (new []syncmodel.BookingGroup, updated []syncmodel.BookingGroup, cancelled []string, newSyncState string, last bool, err error) {
 	// Every synchronization, we will get a list of Create, Update and Delete events (and some cruft
 	// amongst it). When there is no SyncState, we will get only Create events for all events
-	// present on server. If that happens to be a lot of events, these will be created over time by
-	// chunks of MaxChangesReturned until IncludesLastItemInRange will be true.
-	requestXML := fmt.Sprintf(`
-<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types" xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
-    <soap:Header>
-        <t:RequestServerVersion Version="Exchange2013_SP1"/>
-        <t:ExchangeImpersonation>
-            <t:ConnectingSID>
-                <t:SmtpAddress>%s</t:SmtpAddress>
-            </t:ConnectingSID>
-        </t:ExchangeImpersonation>
-    </soap:Header>
-    <soap:Body>
-        <m:SyncFolderItems>
-            <m:ItemShape>
-                <t:BaseShape>IdOnly</t:BaseShape>
-                <t:AdditionalProperties>
-                    <t:FieldURI FieldURI="calendar:UID"/>
-                    <t:FieldURI FieldURI="item:Subject"/>
-                    <t:FieldURI FieldURI="item:DateTimeReceived"/>
-                    <t:FieldURI FieldURI="calendar:Start"/>
-                    <t:FieldURI FieldURI="calendar:End"/>
-                    <t:FieldURI FieldURI="calendar:Organizer"/>
-                    <t:FieldURI FieldURI="calendar:CalendarItemType"/>
-                </t:AdditionalProperties>
-            </m:ItemShape>
-            <m:SyncFolderId>
-                <t:DistinguishedFolderId Id="calendar">
-                    <t:Mailbox>
-                        <t:EmailAddress>%s</t:EmailAddress>
-                    </t:Mailbox>
-                </t:DistinguishedFolderId>
-            </m:SyncFolderId>
-            <m:SyncState>%s</m:SyncState>
-            <m:MaxChangesReturned>256</m:MaxChangesReturned>
-        </m:SyncFolderItems>
-    </soap:Body>
-</soap:Envelope>`, roomEmail, roomEmail, syncState)
+	// present on server. If that happens to be a lot of events, these are returned in pages of
+	// MaxChangesReturned until IncludesLastItemInRange is true.
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, roomEmail), syncFolderItemsRequest{
+		ItemShape: itemShape{
+			BaseShape: "IdOnly",
+			AdditionalProperties: &additionalProperties{
+				FieldURIs:         fieldURIs(bookedItemFields...),
+				ExtendedFieldURIs: []extendedFieldURI{elionaIDField},
+			},
+		},
+		SyncFolderID:       calendarOf(roomEmail),
+		SyncState:          syncState,
+		MaxChangesReturned: 256,
+	})
+	if err != nil {
+		return nil, nil, nil, syncState, false, err
+	}
 	responseXML, err := h.sendRequest(requestXML)
 	if err != nil {
-		return nil, nil, nil, syncState, fmt.Errorf("getting room %v appointments: %v", roomEmail, err)
+		return nil, nil, nil, syncState, false, fmt.Errorf("getting room %v appointments: %w", roomEmail, err)
 	}
 
 	// First, try to unmarshal into SOAPFault to see if there was an error.
 	var soapFault soapFault
 	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
-		return nil, nil, nil, syncState, fmt.Errorf("SOAP fault: %s - %s", soapFault.Body.Fault.Detail.ResponseCode, soapFault.Body.Fault.Detail.Message)
+		return nil, nil, nil, syncState, false, fmt.Errorf("SOAP fault: %w", faultError(soapFault))
 	}
 
 	var env roomEventsEnvelope
 	if err := xml.Unmarshal(responseXML, &env); err != nil {
-		return nil, nil, nil, syncState, fmt.Errorf("unmarshaling XML: %v", err)
+		return nil, nil, nil, syncState, false, fmt.Errorf("unmarshaling XML: %v", err)
 	}
 	changes := env.Body.SyncFolderItemsResponse.ResponseMessages.SyncFolderItemsResponseMessage.Changes
+
+	// On the initial import, the occurrences of all series of the page are
+	// listed at once rather than fetched one by one.
+	var listed map[string][]calendarItem
+	if syncState == "" {
+		var seriesStart time.Time
+		for _, change := range changes.Create {
+			if change.checkItem() == nil && change.CalendarItem.CalendarItemType == "RecurringMaster" &&
+				(seriesStart.IsZero() || change.CalendarItem.Start.Before(seriesStart)) {
+				seriesStart = change.CalendarItem.Start.Time
+			}
+		}
+		if !seriesStart.IsZero() {
+			if listed, err = h.listOccurrences(roomEmail, seriesStart, h.horizon()); err != nil {
+				// The series are expanded one by one instead.
+				log.Warn("ews", "listing occurrences of room %v: %v", roomEmail, err)
+				listed = nil
+			}
+		}
+	}
 	for _, change := range changes.Create {
 		if err := change.checkItem(); err != nil {
 			log.Debug("ews", "skipped creating calendar item: %v", err)
 			continue
 		}
 		item := change.CalendarItem
-		organizerEmail, err := h.resolveDN(item.Organizer.Mailbox.EmailAddress)
-		if err != nil {
-			return nil, nil, nil, syncState, fmt.Errorf("resolving distinguished name '%s': %v", item.Organizer.Mailbox.EmailAddress, err)
-		}
-
 		items := []calendarItem{*item}
 		if change.CalendarItem.CalendarItemType == "RecurringMaster" {
-			recurringItems, err := h.expandRecurrence(item.ItemId.Id, roomEmail)
+			recurringItems, err := h.seriesOccurrences(*item, roomEmail, listed)
 			if err != nil {
-				return nil, nil, nil, syncState, fmt.Errorf("expanding recurrence for event %v: %v", item.ItemId.Id, err)
+				return nil, nil, nil, syncState, false, fmt.Errorf("expanding recurrence for event %v: %v", item.ItemId.Id, err)
 			}
 			items = recurringItems // RecurringMaster is a redundant occurence, only the "Occurence"s should be booked
 		}
-
-		group := syncmodel.BookingGroup{
-			ExchangeUID:    item.UID,
-			OrganizerEmail: organizerEmail,
-		}
-		for _, item := range items {
-			group.Occurrences = append(group.Occurrences, syncmodel.BookingOccurrence{
-				InstanceIndex: item.InstanceIndex,
-				Start:         item.Start,
-				End:           item.End,
-				Cancelled:     false,
-				RoomBookings: []syncmodel.RoomBooking{{
-					ExchangeIDInResourceMailbox: item.ItemId.Id,
-					AssetID:                     assetID,
-				}},
-			})
+		group, err := h.bookingGroup(assetID, roomEmail, *item, items)
+		if err != nil {
+			return nil, nil, nil, syncState, false, err
 		}
-
 		new = append(new, group)
 	}
 
@@ -380,452 +1041,1859 @@ func (h *EWSHelper) GetRoomAppointments(assetID int32, roomEmail string, syncSta
 			continue
 		}
 		item := change.CalendarItem
-		organizerEmail, err := h.resolveDN(item.Organizer.Mailbox.EmailAddress)
-		if err != nil {
-			return nil, nil, nil, syncState, fmt.Errorf("resolving distinguished name '%s': %v", item.Organizer.Mailbox.EmailAddress, err)
-		}
-
 		items := []calendarItem{*item}
 		if change.CalendarItem.CalendarItemType == "RecurringMaster" {
-			recurringItems, err := h.expandRecurrence(item.ItemId.Id, roomEmail)
+			recurringItems, err := h.expandRecurrence(item.ItemId.Id, roomEmail, 0, h.horizon())
 			if err != nil {
-				return nil, nil, nil, syncState, fmt.Errorf("expanding recurrence for event %v: %v", item.ItemId.Id, err)
+				return nil, nil, nil, syncState, false, fmt.Errorf("expanding recurrence for event %v: %v", item.ItemId.Id, err)
 			}
 			items = recurringItems // RecurringMaster is a redundant occurence, only the "Occurence"s should be booked
 		}
-
-		group := syncmodel.BookingGroup{
-			ExchangeUID:    item.UID,
-			OrganizerEmail: organizerEmail,
-		}
-		for _, item := range items {
-			group.Occurrences = append(group.Occurrences, syncmodel.BookingOccurrence{
-				InstanceIndex: item.InstanceIndex,
-				Start:         item.Start,
-				End:           item.End,
-				Cancelled:     false,
-				RoomBookings: []syncmodel.RoomBooking{{
-					ExchangeIDInResourceMailbox: item.ItemId.Id,
-					AssetID:                     assetID,
-				}},
-			})
+		group, err := h.bookingGroup(assetID, roomEmail, *item, items)
+		if err != nil {
+			return nil, nil, nil, syncState, false, err
 		}
 		updated = append(updated, group)
 	}
-
-	for _, change := range changes.Delete {
-		cancelled = append(cancelled, change.ItemId.Id)
+
+	for _, change := range changes.Delete {
+		cancelled = append(cancelled, change.ItemId.Id)
+	}
+
+	newSyncState = env.Body.SyncFolderItemsResponse.ResponseMessages.SyncFolderItemsResponseMessage.SyncState
+	last = env.Body.SyncFolderItemsResponse.ResponseMessages.SyncFolderItemsResponseMessage.IncludesLastItemInRange
+
+	return new, updated, cancelled, newSyncState, last, nil
+}
+
+// bookedItemFields are the properties of calendar items booked in Eliona.
+var bookedItemFields = []string{"calendar:UID", "item:Subject", "item:Sensitivity", "item:DateTimeReceived", "calendar:Start", "calendar:End",
+	"calendar:StartTimeZone", "calendar:Location", "calendar:Organizer", "calendar:CalendarItemType", "calendar:IsAllDayEvent", "calendar:IsOnlineMeeting",
+	"calendar:JoinOnlineMeetingUrl", "item:Categories", "calendar:IsMeeting", "calendar:RequiredAttendees",
+	"calendar:OptionalAttendees", "calendar:Resources"}
+
+// bookingGroup returns the group booking the item's occurrences in the room.
+// The item is the recurring master of a series, or the occurrence itself.
+func (h *EWSHelper) bookingGroup(assetID int32, roomEmail string, item calendarItem, occurrences []calendarItem) (syncmodel.BookingGroup, error) {
+	organizerEmail, err := h.resolveDN(item.Organizer.Mailbox.EmailAddress)
+	if err != nil {
+		return syncmodel.BookingGroup{}, fmt.Errorf("resolving distinguished name '%s': %v", item.Organizer.Mailbox.EmailAddress, err)
+	}
+
+	group := syncmodel.BookingGroup{
+		ExchangeUID:    item.UID,
+		OrganizerEmail: organizerEmail,
+		IsOnline:       item.isOnline(),
+		CreatedByApp:   item.ElionaTag.Value != 0,
+		SelfOrganized:  strings.EqualFold(organizerEmail, roomEmail),
+		Block:          item.isBlock(),
+	}
+	if item.CalendarItemType == "RecurringMaster" {
+		group.MasterExchangeID = item.ItemId.Id
+	}
+	redacted := h.redacts(item)
+	subject := item.Subject
+	if redacted {
+		subject = ""
+	} else {
+		group.JoinURL = item.JoinOnlineMeetingUrl
+		group.Categories = item.Categories
+	}
+	group.Subject = h.subjects.Subject(subject, roomEmail, organizerEmail, item.Start.Time, item.End.Time)
+	var attendees []string
+	var attendeesTruncated bool
+	if !redacted {
+		attendees, attendeesTruncated = h.attendeeEmails(item, roomEmail)
+	}
+	for _, occurrence := range occurrences {
+		location := occurrence.Location
+		if redacted {
+			location = ""
+		}
+		group.Occurrences = append(group.Occurrences, syncmodel.BookingOccurrence{
+			InstanceIndex: occurrence.InstanceIndex,
+			Start:         occurrence.Start.Time,
+			End:           occurrence.End.Time,
+			Cancelled:     false,
+			AllDay:        occurrence.IsAllDayEvent,
+			TimeZone:      occurrence.StartTimeZone.ID,
+			Location:      location,
+			// Occurrences share the attendees of their series.
+			Attendees:          attendees,
+			AttendeesTruncated: attendeesTruncated,
+			RoomBookings: []syncmodel.RoomBooking{{
+				ExchangeIDInResourceMailbox: occurrence.ItemId.Id,
+				AssetID:                     assetID,
+			}},
+		})
+	}
+	return group, nil
+}
+
+func (cr createOrUpdate) checkItem() error {
+	// Sometimes we can get information about non-calendarItems as well, like:
+	//
+	// <t:Create>
+	// 	<t:Message>
+	// 		<t:ItemId Id="AAMkADA0YjRlMDBiLTI5ZWQtNDhiYS1iYTRhLTU1NDcxMDA1YjlhZQBGAAAAAAD7L6rZT1EWT4zA7nKhCR2gBwA2JJ7TGEwETLI+6ZT89YaJAAAAAAENAAA2JJ7TGEwETLI+6ZT89YaJAAAGDBN1AAA=" ChangeKey="CQAAABYAAAA2JJ7TGEwETLI+6ZT89YaJAAAGC9oG" />
+	// 		<t:Subject>Let's go for lunch</t:Subject>
+	// 		<t:DateTimeReceived>2023-03-22T13:04:13Z</t:DateTimeReceived>
+	// 	</t:Message>
+	// </t:Create>
+	//
+	// Such entry has no value and we cannot process it.
+	if cr.CalendarItem == nil {
+		return fmt.Errorf("not a calendar item")
+	}
+	if cr.CalendarItem.Start.IsZero() || cr.CalendarItem.End.IsZero() {
+		return fmt.Errorf("item %v has no start or end", cr.CalendarItem.ItemId.Id)
+	}
+	if cr.CalendarItem.Organizer.Mailbox.EmailAddress == "" {
+		return fmt.Errorf("item %v has no organizer", cr.CalendarItem.ItemId.Id)
+	}
+	return nil
+}
+
+// horizon is the time up to which recurring events are expanded.
+func (h *EWSHelper) horizon() time.Time {
+	horizon := h.recurrenceHorizon
+	if horizon == 0 {
+		horizon = conf.DefaultRecurrenceHorizonDays * 24 * time.Hour
+	}
+	return time.Now().Add(horizon)
+}
+
+// occurrenceBatchSize is the number of occurrences fetched per request.
+const occurrenceBatchSize = 50
+
+// expandRecurrence returns the occurrences of the recurring event following
+// the instance index after and starting until the time, in chronological
+// order, each with its instance index in the series. Series without an end
+// would be expanded forever otherwise.
+func (h *EWSHelper) expandRecurrence(eventID, roomEmail string, after int, until time.Time) ([]calendarItem, error) {
+	exceptions, err := h.getRecurrenceExceptions(eventID, roomEmail)
+	if err != nil {
+		return nil, err
+	}
+	var items []calendarItem
+	instanceIndex := after
+
+expansion:
+	for {
+		indices := make([]int, occurrenceBatchSize)
+		for i := range indices {
+			instanceIndex++ // Starts with 1
+			indices[i] = instanceIndex
+		}
+		occurrences, err := h.getOccurrences(eventID, roomEmail, indices)
+		if err != nil {
+			return nil, err
+		}
+		for _, occurrence := range occurrences {
+			if errors.Is(occurrence.err, ErrOccurrenceOutOfRange) {
+				// End of loop.
+				break expansion
+			}
+			if errors.Is(occurrence.err, ErrOccurrenceDeleted) {
+				// This occurence was deleted, skip it.
+				continue
+			}
+			if occurrence.item.Start.After(until) {
+				break expansion
+			}
+			items = append(items, occurrence.item)
+		}
+	}
+
+	items = exceptions.apply(items)
+
+	// Occurrences moved in Outlook keep their instance index, so the index
+	// order isn't necessarily chronological.
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Start.Before(items[j].Start.Time)
+	})
+	return items, nil
+}
+
+// getRecurrenceExceptions gets the modified and deleted occurrences of the
+// recurring event.
+func (h *EWSHelper) getRecurrenceExceptions(eventID, roomEmail string) (recurrenceExceptions, error) {
+	fetched, err := h.getCalendarItems(roomEmail, getItemRequest{
+		ItemShape: itemShape{
+			BaseShape:            "IdOnly",
+			AdditionalProperties: &additionalProperties{FieldURIs: fieldURIs("calendar:ModifiedOccurrences", "calendar:DeletedOccurrences")},
+		},
+		ItemIDs: []requestItemID{{ID: eventID}},
+	})
+	if err != nil {
+		return recurrenceExceptions{}, fmt.Errorf("getting exceptions of recurring event %v: %v", eventID, err)
+	}
+	if len(fetched) != 1 {
+		return recurrenceExceptions{}, fmt.Errorf("getting exceptions of recurring event %v: got %d items", eventID, len(fetched))
+	}
+	return fetched[0].item.recurrenceExceptions, nil
+}
+
+// fetchedItem is an item as got by GetItem. Errors of occurrences that are
+// deleted or out of the recurrence range are kept rather than failing.
+type fetchedItem struct {
+	item calendarItem
+	err  error
+}
+
+// getOccurrences gets the occurrences of the recurring event with the instance
+// indices in one request, in the order of the indices.
+func (h *EWSHelper) getOccurrences(eventID, roomEmail string, instanceIndices []int) ([]fetchedItem, error) {
+	ids := make([]occurrenceItemID, len(instanceIndices))
+	for i, instanceIndex := range instanceIndices {
+		ids[i] = occurrenceItemID{RecurringMasterID: eventID, InstanceIndex: instanceIndex}
+	}
+	occurrences, err := h.getCalendarItems(roomEmail, getItemRequest{
+		ItemShape: itemShape{
+			BaseShape: "IdOnly",
+			AdditionalProperties: &additionalProperties{
+				FieldURIs: fieldURIs("calendar:UID", "item:Subject", "item:DateTimeReceived", "calendar:Start", "calendar:End", "calendar:OriginalStart",
+					"calendar:StartTimeZone", "calendar:Location", "calendar:Organizer", "calendar:CalendarItemType", "calendar:IsAllDayEvent", "calendar:IsOnlineMeeting",
+					"calendar:JoinOnlineMeetingUrl"),
+			},
+		},
+		OccurrenceItemIDs: ids,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("expanding recurrence: %v", err)
+	}
+	if len(occurrences) != len(instanceIndices) {
+		return nil, fmt.Errorf("expanding recurrence: expected %d occurrences, got %d", len(instanceIndices), len(occurrences))
+	}
+	for i := range occurrences {
+		occurrences[i].item.InstanceIndex = instanceIndices[i]
+	}
+	return occurrences, nil
+}
+
+// getCalendarItems gets the calendar items from the mailbox, one for each ID
+// requested.
+func (h *EWSHelper) getCalendarItems(mailbox string, request getItemRequest) ([]fetchedItem, error) {
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, mailbox), request)
+	if err != nil {
+		return nil, err
+	}
+
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return nil, err
+	}
+	// First, try to unmarshal into SOAPFault to see if there was an error.
+	var soapFault soapFault
+	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
+		return nil, fmt.Errorf("SOAP fault: %w", faultError(soapFault))
+	}
+
+	var response struct {
+		Body struct {
+			GetItemResponse struct {
+				ResponseMessages struct {
+					GetItemResponseMessage []struct {
+						ResponseClass string `xml:"ResponseClass,attr"`
+						ResponseCode  string `xml:"ResponseCode"`
+						Items         struct {
+							CalendarItem calendarItem `xml:"CalendarItem"`
+						} `xml:"Items"`
+					} `xml:"GetItemResponseMessage"`
+				} `xml:"ResponseMessages"`
+			} `xml:"GetItemResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(responseXML, &response); err != nil {
+		return nil, fmt.Errorf("unmarshaling XML: %v", err)
+	}
+
+	var items []fetchedItem
+	for _, rm := range response.Body.GetItemResponse.ResponseMessages.GetItemResponseMessage {
+		err := parseResponseCode(rm.ResponseClass, rm.ResponseCode, "")
+		if errors.Is(err, ErrOccurrenceOutOfRange) || errors.Is(err, ErrOccurrenceDeleted) {
+			items = append(items, fetchedItem{err: err})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("GetItem failed: %w", err)
+		}
+		items = append(items, fetchedItem{item: rm.Items.CalendarItem})
+	}
+	return items, nil
+}
+
+// calendarViewPageSize is the number of occurrences listed per request.
+const calendarViewPageSize = 500
+
+// maxCalendarViewRange is the longest interval a calendar view may span.
+// Exchange rejects longer ones with ErrorCalendarViewRangeTooBig.
+const maxCalendarViewRange = 2 * 365 * 24 * time.Hour
+
+// listOccurrences lists the occurrences in the room's calendar between start
+// and end by the UID of their series. Recurring masters aren't listed. Long
+// intervals are listed in windows Exchange accepts.
+func (h *EWSHelper) listOccurrences(roomEmail string, start, end time.Time) (map[string][]calendarItem, error) {
+	occurrences := make(map[string][]calendarItem)
+	seen := make(map[string]bool)
+	for start.Before(end) {
+		viewEnd := end
+		if viewEnd.Sub(start) > maxCalendarViewRange {
+			viewEnd = start.Add(maxCalendarViewRange)
+		}
+		requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, roomEmail), findItemRequest{
+			Traversal: "Shallow",
+			ItemShape: itemShape{
+				BaseShape: "IdOnly",
+				AdditionalProperties: &additionalProperties{
+					FieldURIs: fieldURIs("calendar:UID", "calendar:Start", "calendar:End", "calendar:StartTimeZone", "calendar:Location",
+						"calendar:CalendarItemType", "calendar:IsAllDayEvent"),
+				},
+			},
+			CalendarView:   &calendarView{MaxEntriesReturned: calendarViewPageSize, StartDate: syncmodel.EWSTime(start), EndDate: syncmodel.EWSTime(viewEnd)},
+			ParentFolderID: calendarOf(roomEmail),
+		})
+		if err != nil {
+			return nil, err
+		}
+		responseXML, err := h.sendRequest(requestXML)
+		if err != nil {
+			return nil, fmt.Errorf("listing occurrences in room %v: %v", roomEmail, err)
+		}
+		var soapFault soapFault
+		if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
+			return nil, fmt.Errorf("SOAP fault: %w", faultError(soapFault))
+		}
+
+		var response struct {
+			Body struct {
+				FindItemResponse struct {
+					ResponseMessages struct {
+						FindItemResponseMessage struct {
+							ResponseClass string `xml:"ResponseClass,attr"`
+							ResponseCode  string `xml:"ResponseCode"`
+							RootFolder    struct {
+								IncludesLastItemInRange bool `xml:"IncludesLastItemInRange,attr"`
+								Items                   struct {
+									CalendarItem []calendarItem `xml:"CalendarItem"`
+								} `xml:"Items"`
+							} `xml:"RootFolder"`
+						} `xml:"FindItemResponseMessage"`
+					} `xml:"ResponseMessages"`
+				} `xml:"FindItemResponse"`
+			} `xml:"Body"`
+		}
+		if err := xml.Unmarshal(responseXML, &response); err != nil {
+			return nil, fmt.Errorf("unmarshaling XML: %v", err)
+		}
+		rm := response.Body.FindItemResponse.ResponseMessages.FindItemResponseMessage
+		if err := parseResponseCode(rm.ResponseClass, rm.ResponseCode, ""); err != nil {
+			return nil, fmt.Errorf("FindItem failed: %w", err)
+		}
+
+		items := rm.RootFolder.Items.CalendarItem
+		for _, item := range items {
+			// Pages overlap by the items spanning their boundary.
+			if seen[item.ItemId.Id] || item.UID == "" {
+				continue
+			}
+			seen[item.ItemId.Id] = true
+			if item.CalendarItemType == "Occurrence" || item.CalendarItemType == "Exception" {
+				occurrences[item.UID] = append(occurrences[item.UID], item)
+			}
+		}
+		if rm.RootFolder.IncludesLastItemInRange || len(items) == 0 || !items[len(items)-1].Start.After(start) {
+			// On to the next window, if any.
+			start = viewEnd
+			continue
+		}
+		start = items[len(items)-1].Start.Time
+	}
+	return occurrences, nil
+}
+
+// seriesOccurrences returns the occurrences of the recurring event in
+// chronological order, each with its instance index in the series. Listed
+// occurrences are numbered in their order, checking the last one's index with
+// a single request. Series with moved or deleted occurrences, or without
+// listed ones, are expanded occurrence by occurrence instead.
+func (h *EWSHelper) seriesOccurrences(master calendarItem, roomEmail string, listed map[string][]calendarItem) ([]calendarItem, error) {
+	occurrences := listed[master.UID]
+	if len(occurrences) == 0 {
+		return h.expandRecurrence(master.ItemId.Id, roomEmail, 0, h.horizon())
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].Start.Before(occurrences[j].Start.Time)
+	})
+	for i := range occurrences {
+		if occurrences[i].CalendarItemType == "Exception" {
+			return h.expandRecurrence(master.ItemId.Id, roomEmail, 0, h.horizon())
+		}
+		occurrences[i].InstanceIndex = i + 1
+	}
+	// Occurrences deleted before the last one would shift its index.
+	last := occurrences[len(occurrences)-1]
+	fetched, err := h.getOccurrences(master.ItemId.Id, roomEmail, []int{last.InstanceIndex})
+	if err != nil {
+		return nil, err
+	}
+	if fetched[0].err != nil || !fetched[0].item.Start.Equal(last.Start.Time) {
+		log.Debug("ews", "expanding recurrence of event %v occurrence by occurrence, as its listed occurrences aren't numbered consecutively", master.ItemId.Id)
+		return h.expandRecurrence(master.ItemId.Id, roomEmail, 0, h.horizon())
+	}
+	return occurrences, nil
+}
+
+// SeriesOccurrence is an occurrence of a recurring event, as found in a room's
+// calendar.
+type SeriesOccurrence struct {
+	ItemID      string
+	ExchangeUID string
+}
+
+// FindSeriesOccurrences returns an occurrence between from and until of each
+// recurring event in the room's calendar having some.
+func (h *EWSHelper) FindSeriesOccurrences(roomEmail string, from, until time.Time) ([]SeriesOccurrence, error) {
+	listed, err := h.listOccurrences(roomEmail, from, until)
+	if err != nil {
+		return nil, err
+	}
+	var series []SeriesOccurrence
+	for uid, occurrences := range listed {
+		series = append(series, SeriesOccurrence{ItemID: occurrences[0].ItemId.Id, ExchangeUID: uid})
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].ExchangeUID < series[j].ExchangeUID
+	})
+	return series, nil
+}
+
+// ExtendSeries returns the group booking the occurrences of the recurring
+// event in the room following the instance index after and starting until the
+// time. Occurrences up to after are not fetched again.
+func (h *EWSHelper) ExtendSeries(assetID int32, roomEmail, occurrenceID string, after int, until time.Time) (syncmodel.BookingGroup, error) {
+	fetched, err := h.getCalendarItems(roomEmail, getItemRequest{
+		ItemShape: itemShape{
+			BaseShape: "IdOnly",
+			AdditionalProperties: &additionalProperties{
+				FieldURIs:         fieldURIs(bookedItemFields...),
+				ExtendedFieldURIs: []extendedFieldURI{elionaIDField},
+			},
+		},
+		RecurringMasterItemID: &recurringMasterItemID{OccurrenceID: occurrenceID},
+	})
+	if err != nil {
+		return syncmodel.BookingGroup{}, fmt.Errorf("getting recurring master of %v: %v", occurrenceID, err)
+	}
+	if len(fetched) != 1 {
+		return syncmodel.BookingGroup{}, fmt.Errorf("getting recurring master of %v: got %d items", occurrenceID, len(fetched))
+	}
+	master := fetched[0].item
+	if err := (createOrUpdate{CalendarItem: &master}).checkItem(); err != nil {
+		return syncmodel.BookingGroup{}, err
+	}
+	occurrences, err := h.expandRecurrence(master.ItemId.Id, roomEmail, after, until)
+	if err != nil {
+		return syncmodel.BookingGroup{}, fmt.Errorf("expanding recurrence for event %v: %v", master.ItemId.Id, err)
+	}
+	return h.bookingGroup(assetID, roomEmail, master, occurrences)
+}
+
+// FindConflictingEvents returns IDs of events in the room's calendar that
+// overlap the given interval under the policy.
+func (h *EWSHelper) FindConflictingEvents(roomEmail string, start, end time.Time, policy syncmodel.OverlapPolicy) ([]string, error) {
+	// CalendarView omits events merely touching the interval, so it is widened
+	// to let the policy decide about them.
+	events, err := h.FindEvents(roomEmail, start.Add(-time.Minute), end.Add(time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	var conflicting []string
+	for _, event := range events {
+		if syncmodel.Overlaps(start, end, event.Start, event.End, policy) {
+			conflicting = append(conflicting, event.ItemID)
+		}
+	}
+	return conflicting, nil
+}
+
+// CalendarEvent is an event as found in a room's calendar.
+type CalendarEvent struct {
+	ItemID string
+	Start  time.Time
+	End    time.Time
+}
+
+// FindEvents returns events in the room's calendar intersecting the interval,
+// with recurring events expanded. Cancelled events are skipped.
+func (h *EWSHelper) FindEvents(roomEmail string, start, end time.Time) ([]CalendarEvent, error) {
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, roomEmail), findItemRequest{
+		Traversal: "Shallow",
+		ItemShape: itemShape{
+			BaseShape:            "IdOnly",
+			AdditionalProperties: &additionalProperties{FieldURIs: fieldURIs("calendar:Start", "calendar:End", "calendar:IsCancelled")},
+		},
+		CalendarView:   &calendarView{StartDate: syncmodel.EWSTime(start), EndDate: syncmodel.EWSTime(end)},
+		ParentFolderID: calendarOf(roomEmail),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return nil, fmt.Errorf("finding events of room %v: %v", roomEmail, err)
+	}
+	var soapFault soapFault
+	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
+		return nil, fmt.Errorf("SOAP fault: %w", faultError(soapFault))
+	}
+
+	var response struct {
+		Body struct {
+			FindItemResponse struct {
+				ResponseMessages struct {
+					FindItemResponseMessage struct {
+						ResponseClass string `xml:"ResponseClass,attr"`
+						ResponseCode  string `xml:"ResponseCode"`
+						RootFolder    struct {
+							Items struct {
+								CalendarItem []struct {
+									ItemId      itemId       `xml:"ItemId"`
+									Start       exchangeTime `xml:"Start"`
+									End         exchangeTime `xml:"End"`
+									IsCancelled bool         `xml:"IsCancelled"`
+								} `xml:"CalendarItem"`
+							} `xml:"Items"`
+						} `xml:"RootFolder"`
+					} `xml:"FindItemResponseMessage"`
+				} `xml:"ResponseMessages"`
+			} `xml:"FindItemResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(responseXML, &response); err != nil {
+		return nil, fmt.Errorf("unmarshaling XML: %v", err)
+	}
+	rm := response.Body.FindItemResponse.ResponseMessages.FindItemResponseMessage
+	if err := parseResponseCode(rm.ResponseClass, rm.ResponseCode, ""); err != nil {
+		return nil, fmt.Errorf("FindItem failed: %w", err)
+	}
+
+	var events []CalendarEvent
+	for _, item := range rm.RootFolder.Items.CalendarItem {
+		if item.IsCancelled {
+			continue
+		}
+		events = append(events, CalendarEvent{
+			ItemID: item.ItemId.Id,
+			Start:  item.Start.Time,
+			End:    item.End.Time,
+		})
+	}
+	return events, nil
+}
+
+// Events created by the app are tagged with the Eliona booking ID in a custom
+// extended property, so that they can be recognized later.
+const (
+	elionaIDPropertySetID = "8c5d3b2e-4f61-4a3c-9e27-5b1f0d6a7c94"
+	elionaIDPropertyName  = "Eliona-id"
+)
+
+// TaggedEvent is an event created by the app, as found in a room's calendar.
+type TaggedEvent struct {
+	ItemID         string
+	ExchangeUID    string
+	OrganizerEmail string
+	ElionaID       int32
+	Start          time.Time
+	End            time.Time
+}
+
+// FindTaggedEvents lists events in the room's calendar that were created by
+// the app.
+func (h *EWSHelper) FindTaggedEvents(roomEmail string) ([]TaggedEvent, error) {
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, roomEmail), findItemRequest{
+		Traversal: "Shallow",
+		ItemShape: itemShape{
+			BaseShape: "IdOnly",
+			AdditionalProperties: &additionalProperties{
+				FieldURIs:         fieldURIs("calendar:UID", "calendar:Start", "calendar:End", "calendar:Organizer"),
+				ExtendedFieldURIs: []extendedFieldURI{elionaIDField},
+			},
+		},
+		Restriction:    &restriction{Exists: &elionaIDField},
+		ParentFolderID: calendarOf(roomEmail),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return nil, fmt.Errorf("finding tagged events of room %v: %v", roomEmail, err)
+	}
+	var soapFault soapFault
+	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
+		return nil, fmt.Errorf("SOAP fault: %w", faultError(soapFault))
+	}
+
+	var response struct {
+		Body struct {
+			FindItemResponse struct {
+				ResponseMessages struct {
+					FindItemResponseMessage struct {
+						ResponseClass string `xml:"ResponseClass,attr"`
+						ResponseCode  string `xml:"ResponseCode"`
+						RootFolder    struct {
+							Items struct {
+								CalendarItem []struct {
+									ItemId           itemId       `xml:"ItemId"`
+									UID              string       `xml:"UID"`
+									Start            exchangeTime `xml:"Start"`
+									End              exchangeTime `xml:"End"`
+									Organizer        organizer    `xml:"Organizer"`
+									ExtendedProperty struct {
+										Value int32 `xml:"Value"`
+									} `xml:"ExtendedProperty"`
+								} `xml:"CalendarItem"`
+							} `xml:"Items"`
+						} `xml:"RootFolder"`
+					} `xml:"FindItemResponseMessage"`
+				} `xml:"ResponseMessages"`
+			} `xml:"FindItemResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(responseXML, &response); err != nil {
+		return nil, fmt.Errorf("unmarshaling XML: %v", err)
+	}
+	rm := response.Body.FindItemResponse.ResponseMessages.FindItemResponseMessage
+	if err := parseResponseCode(rm.ResponseClass, rm.ResponseCode, ""); err != nil {
+		return nil, fmt.Errorf("FindItem failed: %w", err)
+	}
+
+	var events []TaggedEvent
+	for _, item := range rm.RootFolder.Items.CalendarItem {
+		organizerEmail, err := h.resolveDN(item.Organizer.Mailbox.EmailAddress)
+		if err != nil {
+			return nil, fmt.Errorf("resolving distinguished name '%s': %v", item.Organizer.Mailbox.EmailAddress, err)
+		}
+		events = append(events, TaggedEvent{
+			ItemID:         item.ItemId.Id,
+			ExchangeUID:    item.UID,
+			OrganizerEmail: organizerEmail,
+			ElionaID:       item.ExtendedProperty.Value,
+			Start:          item.Start.Time,
+			End:            item.End.Time,
+		})
+	}
+	return events, nil
+}
+
+type Appointment struct {
+	// ElionaID is the ID of the Eliona booking the event is tagged with.
+	ElionaID  int32
+	Organizer string
+	Subject   string
+	Start     time.Time
+	End       time.Time
+	// AllDay events start and end at midnight.
+	AllDay bool
+	// TimeZone keeps the wall-clock times of recurring and all-day events
+	// across daylight saving time changes. Nil means UTC.
+	TimeZone *time.Location
+	Location string
+	// Attendees are the people invited besides the organizer.
+	Attendees []string
+	// Resources are the rooms invited. The appointment waits for their
+	// responses.
+	Resources []string
+	// ApprovalRooms are resources which need a delegate to approve the
+	// booking.
+	ApprovalRooms []string
+	// ProcessingTimeout is how long the resources are polled every second
+	// for having processed the invitation.
+	ProcessingTimeout time.Duration
+	// DeclineGracePeriod is how long to wait for a resource to process the
+	// invitation after the processing timeout before considering it declined.
+	DeclineGracePeriod time.Duration
+	// FreeBusyStatus is shown in attendees' calendars. Defaults to Busy.
+	FreeBusyStatus string
+	// DeferResponses creates the appointment without waiting for the rooms
+	// to process the invitation. Rooms which haven't yet are reported as
+	// pending response.
+	DeferResponses bool
+	// MaxAttendeesPerRequest limits the attendees invited when creating the
+	// event, the rest is added in batches. Zero means
+	// DefaultMaxAttendeesPerRequest.
+	MaxAttendeesPerRequest int
+	// ReminderMinutes before the start the organizer is reminded. Zero
+	// disables the reminder, nil keeps the default of the mailbox.
+	ReminderMinutes *int
+	// Recurrence makes the appointment a recurring series, nil for single
+	// events.
+	Recurrence *Recurrence
+	// Categories the event is shown with in Outlook.
+	Categories []string
+	// IsOnlineMeeting asks Exchange to add an online meeting, e.g. Teams,
+	// using the organizer's default provider.
+	IsOnlineMeeting bool
+
+	// sendInvitations is the SendMeetingInvitations mode, defaults to
+	// SendToAllAndSaveCopy.
+	sendInvitations string
+}
+
+func (a Appointment) requiresApproval(attendee string) bool {
+	return containsFold(a.ApprovalRooms, attendee)
+}
+
+// RoomResult is the response of a room to the appointment's invitation.
+type RoomResult struct {
+	Room string
+	// ResourceEventID is the ID of the event in the room's mailbox, empty
+	// unless the room accepted the invitation.
+	ResourceEventID string
+	// OccurrenceEventIDs are the IDs of the occurrences of a recurring
+	// event in the room's mailbox, the one with instance index i at i-1.
+	// Occurrences missing in the room's mailbox have an empty ID.
+	OccurrenceEventIDs []string
+	Declined           bool
+	PendingApproval    bool
+	PendingResponse    bool
+}
+
+// AcceptedRooms returns results of the rooms which accepted the invitation.
+func AcceptedRooms(results []RoomResult) []RoomResult {
+	var accepted []RoomResult
+	for _, result := range results {
+		if !result.Declined && !result.PendingApproval && !result.PendingResponse {
+			accepted = append(accepted, result)
+		}
+	}
+	return accepted
+}
+
+// DeclinedRooms returns email addresses of the rooms which declined the invitation.
+func DeclinedRooms(results []RoomResult) []string {
+	var declined []string
+	for _, result := range results {
+		if result.Declined {
+			declined = append(declined, result.Room)
+		}
+	}
+	return declined
+}
+
+// CreateAppointment creates the appointment and collects responses of all
+// invited rooms. ErrDeclined is returned if any of the rooms declined, otherwise
+// ErrPendingApproval if any of them waits for a delegate. The results are
+// returned in both cases so that the caller can keep the accepted rooms. With
+// DeferResponses, ErrPendingResponse is returned if any room hasn't processed
+// the invitation yet. Without invitations, the results are of the rooms already
+// having the event.
+func (h *EWSHelper) CreateAppointment(appointment Appointment) (exchangeUID string, results []RoomResult, err error) {
+	if h.refuseWrite("created appointment for booking %d in %v", appointment.ElionaID, appointment.Resources) {
+		return "", nil, ErrReadOnly
+	}
+	appointment = appointment.withLimitedFieldLengths()
+	defer func() {
+		h.audit(AuditCreate, appointment.Organizer, appointment.Resources, exchangeUID, appointment.Start, appointment.End, err)
+	}()
+	appointment.sendInvitations = h.invitations.sendMeetingInvitations()
+	// Huge room lists might exceed the server's request size limit, so the
+	// event is created with the first batch only.
+	batches := attendeeBatches(appointment.Resources, appointment.MaxAttendeesPerRequest)
+	first := appointment
+	first.Resources = batches[0]
+	requestXML, err := createAppointmentRequest(first)
+	if err != nil {
+		return "", nil, err
+	}
+
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return "", nil, fmt.Errorf("requesting create appointment: %w", err)
+	}
+
+	organizerEventID, invitationsSent, err := parseCreateItemResponse(responseXML)
+	if err != nil {
+		return "", nil, err
+	}
+
+	exchangeUID, err = h.getUIDFromItemId(appointment.Organizer, organizerEventID)
+	if err != nil {
+		return "", nil, fmt.Errorf("getting UID from ItemID: %v", err)
+	}
+	if len(batches) > 1 {
+		log.Info("ews", "adding %d rooms of booking %d in %d batches", len(appointment.Resources), appointment.ElionaID, len(batches))
+	}
+	// Invite only the added attendees, the others already got the invitation.
+	addInvitations := sendToChangedAndSaveCopy
+	if appointment.sendInvitations == sendToNone {
+		addInvitations = sendToNone
+	}
+	for _, batch := range batches[1:] {
+		if err := h.updateAppointmentAttendees(exchangeUID, appointment.Organizer, batch, nil, addInvitations); err != nil {
+			// Don't leave an event behind that misses some of the rooms.
+			if err := h.CancelEvent(syncmodel.BookingGroup{ExchangeUID: exchangeUID, OrganizerEmail: appointment.Organizer}); err != nil {
+				log.Error("ews", "cancelling event %s with missing attendees: %v", exchangeUID, err)
+			}
+			return exchangeUID, nil, fmt.Errorf("adding attendees: %w", err)
+		}
+	}
+
+	if appointment.sendInvitations == sendToNone {
+		// The rooms have nothing to respond to.
+		log.Info("ews", "created event %s for booking %d without sending invitations", exchangeUID, appointment.ElionaID)
+		return exchangeUID, h.existingRoomEvents(appointment, exchangeUID), nil
+	}
+	h.invitations.record(invitationsSent)
+	if !invitationsSent {
+		// The event exists, so the booking is kept, but the rooms won't get
+		// the invitation to respond to.
+		log.Warn("ews", "created event %s for booking %d, but the invitations were not delivered", exchangeUID, appointment.ElionaID)
+		return exchangeUID, h.existingRoomEvents(appointment, exchangeUID), nil
+	}
+
+	// The server takes some time to process the invitation. Sometimes it's
+	// instant, sometimes 2 seconds aren't enough.
+	processedBy := pollNow().Add(appointment.ProcessingTimeout)
+	if appointment.DeferResponses {
+		processedBy = pollNow()
+	}
+	declined, pending, pendingResponse := false, false, false
+	for _, attendee := range appointment.Resources {
+		gracePeriod := appointment.DeclineGracePeriod
+		if appointment.requiresApproval(attendee) || appointment.DeferResponses {
+			// No point in waiting, a human needs to respond or the responses
+			// are checked later.
+			gracePeriod = 0
+		}
+		result := RoomResult{Room: attendee}
+		resourceEventID, err := h.waitForResourceEvent(appointment.Organizer, attendee, exchangeUID, processedBy, gracePeriod)
+		if errors.Is(err, errNotFound) && appointment.DeferResponses {
+			result.PendingResponse = true
+			pendingResponse = true
+		} else if errors.Is(err, errNotFound) && appointment.requiresApproval(attendee) {
+			// The delegate has not approved the booking yet.
+			result.PendingApproval = true
+			pending = true
+		} else if errors.Is(err, errNotFound) {
+			// The resource has probably declined the invitation without
+			// letting the organizer know.
+			log.Debug("ews", "resource %s did not process invitation %s within grace period", attendee, exchangeUID)
+			result.Declined = true
+			declined = true
+		} else if errors.Is(err, ErrDeclined) {
+			result.Declined = true
+			declined = true
+		} else if err != nil {
+			return exchangeUID, nil, fmt.Errorf("finding resource event ID: %v", err)
+		} else {
+			result.ResourceEventID = resourceEventID
+		}
+		if result.ResourceEventID != "" && appointment.Recurrence != nil {
+			// The series exists by now, so it is kept even if its
+			// occurrences cannot be listed.
+			if result.OccurrenceEventIDs, err = h.occurrenceEventIDs(resourceEventID, attendee, appointment); err != nil {
+				log.Warn("ews", "finding occurrences of %s in %s: %v", exchangeUID, attendee, err)
+			}
+		}
+		results = append(results, result)
+	}
+	if declined {
+		return exchangeUID, results, ErrDeclined
+	}
+	if pendingResponse {
+		return exchangeUID, results, ErrPendingResponse
+	}
+	if pending {
+		return exchangeUID, results, ErrPendingApproval
+	}
+
+	return exchangeUID, results, nil
+}
+
+// occurrenceEventIDs returns the IDs of the occurrences of the recurring
+// appointment in the room's mailbox, in the order of their instance index.
+// Occurrences the room has deleted or declined have an empty ID.
+func (h *EWSHelper) occurrenceEventIDs(resourceEventID, roomEmail string, appointment Appointment) ([]string, error) {
+	occurrences, err := h.expandRecurrence(resourceEventID, roomEmail, 0, appointment.Recurrence.end(appointment.Start))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, occurrence := range occurrences {
+		for len(ids) < occurrence.InstanceIndex-1 {
+			ids = append(ids, "")
+		}
+		ids = append(ids, occurrence.ItemId.Id)
+	}
+	return ids, nil
+}
+
+// DefaultMaxAttendeesPerRequest keeps CreateItem requests of bookings with
+// many rooms well below the default request size limit of Exchange.
+const DefaultMaxAttendeesPerRequest = 100
+
+// attendeeBatches splits the attendees into batches of at most size
+// attendees, DefaultMaxAttendeesPerRequest if size is zero. There is always
+// at least one batch.
+func attendeeBatches(attendees []string, size int) [][]string {
+	if size <= 0 {
+		size = DefaultMaxAttendeesPerRequest
+	}
+	batches := [][]string{}
+	for len(attendees) > size {
+		batches = append(batches, attendees[:size])
+		attendees = attendees[size:]
+	}
+	return append(batches, attendees)
+}
+
+// Maximum lengths of appointment fields accepted by Exchange, in characters.
+const (
+	maxSubjectLength  = 255
+	maxLocationLength = 255
+)
+
+// withLimitedFieldLengths truncates fields exceeding Exchange's limits, which
+// would otherwise make CreateItem fail without a clear reason.
+func (a Appointment) withLimitedFieldLengths() Appointment {
+	if subject, truncated := truncate(a.Subject, maxSubjectLength); truncated {
+		log.Warn("ews", "subject of booking %d is longer than %d characters; truncated", a.ElionaID, maxSubjectLength)
+		a.Subject = subject
+	}
+	if location, truncated := truncate(a.Location, maxLocationLength); truncated {
+		log.Warn("ews", "location of booking %d is longer than %d characters; truncated", a.ElionaID, maxLocationLength)
+		a.Location = location
+	}
+	return a
+}
+
+// truncate shortens s to at most max characters, ending it with an ellipsis.
+func truncate(s string, max int) (string, bool) {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s, false
+	}
+	return string(runes[:max-1]) + "…", true
+}
+
+// createAppointmentRequest renders the CreateItem request for the appointment.
+func createAppointmentRequest(appointment Appointment) (string, error) {
+	freeBusyStatus, err := syncmodel.ParseFreeBusyStatus(appointment.FreeBusyStatus)
+	if err != nil {
+		return "", err
+	}
+	sendInvitations := appointment.sendInvitations
+	if sendInvitations == "" {
+		sendInvitations = sendToAllAndSaveCopy
+	}
+	item := newCalendarItem{
+		Subject:              appointment.Subject,
+		ExtendedProperty:     extendedProperty{Field: elionaIDField, Value: fmt.Sprint(appointment.ElionaID)},
+		Start:                syncmodel.EWSTime(appointment.Start),
+		End:                  syncmodel.EWSTime(appointment.End),
+		IsAllDayEvent:        appointment.AllDay,
+		LegacyFreeBusyStatus: freeBusyStatus,
+		Location:             appointment.Location,
+	}
+	if len(appointment.Categories) > 0 {
+		item.Categories = &itemCategories{Strings: appointment.Categories}
+	}
+	if minutes := appointment.ReminderMinutes; minutes != nil {
+		reminderIsSet := *minutes != 0
+		item.ReminderIsSet = &reminderIsSet
+		if reminderIsSet {
+			item.ReminderMinutesBeforeStart = minutes
+		}
+	}
+	for _, email := range appointment.Attendees {
+		item.RequiredAttendees = append(item.RequiredAttendees, requestAttendee{Mailbox: requestMailbox{EmailAddress: email}})
+	}
+	for _, email := range appointment.Resources {
+		item.Resources = append(item.Resources, requestAttendee{Mailbox: requestMailbox{EmailAddress: email}})
+	}
+	zone := time.UTC
+	if appointment.TimeZone != nil {
+		zone = appointment.TimeZone
+		if id, ok := windowsTimeZone(zone); ok {
+			item.StartTimeZone = &requestTimeZone{ID: id}
+			item.EndTimeZone = &requestTimeZone{ID: id}
+		} else {
+			log.Warn("ews", "time zone %s of booking %d is unknown to Exchange; using UTC", zone, appointment.ElionaID)
+			zone = time.UTC
+		}
+	}
+	if appointment.Recurrence != nil {
+		if item.Recurrence, err = appointment.Recurrence.request(appointment.Start.In(zone)); err != nil {
+			return "", err
+		}
+	}
+	if appointment.IsOnlineMeeting {
+		item.IsOnlineMeeting = &appointment.IsOnlineMeeting
+	}
+	return marshalRequest(impersonate(IdentitySmtpAddress, appointment.Organizer), createItemRequest{
+		SendMeetingInvitations: sendInvitations,
+		SavedItemFolderID:      calendarOf(""),
+		CalendarItem:           item,
+	})
+}
+
+const (
+	// processingPollInterval is how often resources are polled until the
+	// processing timeout, declinePollInterval afterwards.
+	processingPollInterval = time.Second
+	declinePollInterval    = 5 * time.Second
+)
+
+// pollNow and pollSleep time the polls of resources. Replaced in tests.
+var (
+	pollNow   = time.Now
+	pollSleep = time.Sleep
+)
+
+// existingRoomEvents returns the results of the rooms already having the event
+// in their mailbox when they got no invitation to respond to. The mailboxes
+// are looked up once, there is no response to wait for.
+func (h *EWSHelper) existingRoomEvents(appointment Appointment, exchangeUID string) []RoomResult {
+	var results []RoomResult
+	for _, room := range appointment.Resources {
+		resourceEventID, _, err := h.findEventUIDInMailbox(room, exchangeUID)
+		if errors.Is(err, errNotFound) {
+			continue
+		} else if err != nil {
+			log.Warn("ews", "finding event %s in %s: %v", exchangeUID, room, err)
+			continue
+		}
+		result := RoomResult{Room: room, ResourceEventID: resourceEventID}
+		if appointment.Recurrence != nil {
+			if result.OccurrenceEventIDs, err = h.occurrenceEventIDs(resourceEventID, room, appointment); err != nil {
+				log.Warn("ews", "finding occurrences of %s in %s: %v", exchangeUID, room, err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// waitForResourceEvent looks up the event in the resource's mailbox. Slow
+// servers might not have processed the invitation yet, so it keeps polling
+// every second until processedBy, and less often until the grace period
+// after it passes, unless the resource has explicitly declined.
+func (h *EWSHelper) waitForResourceEvent(organizer, resource, exchangeUID string, processedBy time.Time, gracePeriod time.Duration) (string, error) {
+	deadline := processedBy.Add(gracePeriod)
+	for {
+		resourceEventID, _, err := h.findEventUIDInMailbox(resource, exchangeUID)
+		if !errors.Is(err, errNotFound) {
+			return resourceEventID, err
+		}
+		responses, err := h.GetAttendeeResponses(organizer, exchangeUID)
+		if err != nil {
+			return "", fmt.Errorf("getting attendee responses: %v", err)
+		}
+		if responses[strings.ToLower(resource)] == "Decline" {
+			return "", ErrDeclined
+		}
+		now := pollNow()
+		interval := processingPollInterval
+		if !now.Before(processedBy) {
+			interval = declinePollInterval
+		}
+		if now.Add(interval).After(deadline) {
+			return "", errNotFound
+		}
+		pollSleep(interval)
+	}
+}
+
+// parseCreateItemResponse returns the ID of the created item and whether the
+// meeting invitations were sent. Exchange might create the item but fail to
+// deliver the invitations, reporting it as a warning or an error next to the
+// item.
+func parseCreateItemResponse(responseXML []byte) (itemID string, invitationsSent bool, err error) {
+	// First, try to unmarshal into SOAPFault to see if there was an error.
+	var soapFault soapFault
+	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
+		return "", false, fmt.Errorf("SOAP fault: %w", faultError(soapFault))
+	}
+
+	var env appointmentCreated
+	if err := xml.Unmarshal(responseXML, &env); err != nil {
+		return "", false, fmt.Errorf("unmarshaling XML: %v", err)
+	}
+	message := env.Body.CreateItemResponse.ResponseMessages.CreateItemResponseMessage
+	itemID = message.Items.CalendarItem.ItemId.ID
+	if itemID == "" {
+		return "", false, fmt.Errorf("item not created: %w", &EWSError{ResponseCode: message.ResponseCode, Message: message.MessageText})
+	}
+	if message.ResponseClass != "Success" {
+		log.Warn("ews", "item %s created with %s: %s - %s", itemID, message.ResponseClass, message.ResponseCode, message.MessageText)
+		return itemID, false, nil
+	}
+	return itemID, true, nil
+}
+
+type appointmentCreated struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Body    struct {
+		CreateItemResponse struct {
+			ResponseMessages struct {
+				CreateItemResponseMessage struct {
+					ResponseClass string `xml:"ResponseClass,attr"`
+					ResponseCode  string `xml:"ResponseCode"`
+					MessageText   string `xml:"MessageText"`
+					Items         struct {
+						CalendarItem struct {
+							ItemId struct {
+								ID        string `xml:"Id,attr"`
+								ChangeKey string `xml:"ChangeKey,attr"`
+							} `xml:"ItemId"`
+						} `xml:"CalendarItem"`
+					} `xml:"Items"`
+				} `xml:"CreateItemResponseMessage"`
+			} `xml:"ResponseMessages"`
+		} `xml:"CreateItemResponse"`
+	} `xml:"Body"`
+}
+
+func (h *EWSHelper) CancelEvent(event syncmodel.BookingGroup) (err error) {
+	if h.refuseWrite("cancelled event %s", event.ExchangeUID) {
+		return ErrReadOnly
+	}
+	defer func() {
+		h.audit(AuditCancel, event.OrganizerEmail, nil, event.ExchangeUID, time.Time{}, time.Time{}, err)
+	}()
+	// Find the organizer's eventId and changeKey using the UID
+	eventID, changeKey, err := h.findEventUIDInMailbox(event.OrganizerEmail, event.ExchangeUID)
+	if err != nil {
+		return fmt.Errorf("finding organizer event ID: %v", err)
+	}
+	body := h.cancellationMessage(event.OrganizerEmail, eventID)
+
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, event.OrganizerEmail), respondRequest{
+		MessageDisposition: "SendAndSaveCopy",
+		CancelCalendarItem: &cancelCalendarItem{
+			ReferenceItemID: requestItemID{ID: eventID, ChangeKey: changeKey},
+			NewBodyContent:  bodyContent{BodyType: "HTML", Content: body},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return fmt.Errorf("requesting cancel event: %w", err)
+	}
+
+	// First, try to unmarshal into SOAPFault to see if there was an error.
+	var soapFault soapFault
+	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
+		return fmt.Errorf("SOAP fault: %w", faultError(soapFault))
+	}
+
+	var response struct {
+		XMLName xml.Name `xml:"Envelope"`
+		Body    struct {
+			CreateItemResponse struct {
+				ResponseMessages struct {
+					CreateItemResponseMessage struct {
+						ResponseClass string `xml:"ResponseClass,attr"`
+						ResponseCode  string `xml:"ResponseCode"`
+					} `xml:"CreateItemResponseMessage"`
+				} `xml:"ResponseMessages"`
+			} `xml:"CreateItemResponse"`
+		} `xml:"Body"`
+	}
+
+	if err := xml.Unmarshal(responseXML, &response); err != nil {
+		return fmt.Errorf("unmarshalling XML: %v", err)
+	}
+
+	responseClass := response.Body.CreateItemResponse.ResponseMessages.CreateItemResponseMessage.ResponseClass
+	responseCode := response.Body.CreateItemResponse.ResponseMessages.CreateItemResponseMessage.ResponseCode
+
+	if err := parseResponseCode(responseClass, responseCode, ""); err != nil {
+		return fmt.Errorf("cancelling event resulted in %w. Response: %s", err, string(responseXML))
+	}
+
+	return nil
+}
+
+// cancellationMessage composes the message sent to the attendees of the
+// organizer's cancelled event. The event is only read if the template needs
+// its details; if it can't be read, the default message is sent rather than
+// keeping the event from being cancelled.
+func (h *EWSHelper) cancellationMessage(organizer, eventID string) string {
+	body := h.cancellationBody
+	if body.Template == "" {
+		body.Template = conf.DefaultCancellationBody
+	}
+	if !strings.Contains(body.Template, "{") {
+		return body.Template
+	}
+	fetched, err := h.getCalendarItems(organizer, getItemRequest{
+		ItemShape: itemShape{
+			BaseShape:            "IdOnly",
+			AdditionalProperties: &additionalProperties{FieldURIs: fieldURIs("calendar:Start", "calendar:End", "calendar:Location")},
+		},
+		ItemIDs: []requestItemID{{ID: eventID}},
+	})
+	if err == nil && len(fetched) != 1 {
+		err = fmt.Errorf("got %d items", len(fetched))
+	} else if err == nil {
+		err = fetched[0].err
+	}
+	if err != nil {
+		log.Warn("ews", "getting details of cancelled event %s, sending the default message: %v", eventID, err)
+		return conf.DefaultCancellationBody
+	}
+	item := fetched[0].item
+	return body.Compose(item.Location, organizer, item.Start.Time, item.End.Time)
+}
+
+func (h *EWSHelper) CancelOccurrence(group syncmodel.BookingGroup, occurrence syncmodel.BookingOccurrence) (err error) {
+	if h.refuseWrite("cancelled occurrence %d of event %s", occurrence.InstanceIndex, group.ExchangeUID) {
+		return ErrReadOnly
+	}
+	defer func() {
+		h.audit(AuditCancelOccurrence, group.OrganizerEmail, nil, group.ExchangeUID, occurrence.Start, occurrence.End, err)
+	}()
+	// Find the organizer's eventId using the UID
+	eventID, _, err := h.findEventUIDInMailbox(group.OrganizerEmail, group.ExchangeUID)
+	if err != nil {
+		return fmt.Errorf("finding organizer event ID: %v", err)
+	}
+
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, group.OrganizerEmail), deleteItemRequest{
+		DeleteType:               "MoveToDeletedItems",
+		SendMeetingCancellations: "SendToAllAndSaveCopy",
+		OccurrenceItemIDs:        []occurrenceItemID{{RecurringMasterID: eventID, InstanceIndex: occurrence.InstanceIndex}},
+	})
+	if err != nil {
+		return err
+	}
+
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return fmt.Errorf("requesting cancel event: %w", err)
+	}
+
+	// First, try to unmarshal into SOAPFault to see if there was an error.
+	var soapFault soapFault
+	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
+		return fmt.Errorf("SOAP fault: %w", faultError(soapFault))
+	}
+
+	var response struct {
+		XMLName xml.Name `xml:"Envelope"`
+		Body    struct {
+			CreateItemResponse struct {
+				ResponseMessages struct {
+					CreateItemResponseMessage struct {
+						ResponseClass string `xml:"ResponseClass,attr"`
+						ResponseCode  string `xml:"ResponseCode"`
+					} `xml:"CreateItemResponseMessage"`
+				} `xml:"ResponseMessages"`
+			} `xml:"CreateItemResponse"`
+		} `xml:"Body"`
+	}
+
+	if err := xml.Unmarshal(responseXML, &response); err != nil {
+		return fmt.Errorf("unmarshalling XML: %v", err)
+	}
+
+	responseClass := response.Body.CreateItemResponse.ResponseMessages.CreateItemResponseMessage.ResponseClass
+	responseCode := response.Body.CreateItemResponse.ResponseMessages.CreateItemResponseMessage.ResponseCode
+
+	if err := parseResponseCode(responseClass, responseCode, ""); err != nil {
+		return fmt.Errorf("cancelling event resulted in %w. Response: %s", err, string(responseXML))
+	}
+
+	return nil
+}
+
+// DeclineAsResource declines the event on behalf of the resource, freeing it
+// up. The decline is not sent, so the organizer and other attendees are not
+// notified and the event stays unchanged for them.
+func (h *EWSHelper) DeclineAsResource(resourceEmail, exchangeUID string) (err error) {
+	if h.refuseWrite("declined event %s as %s", exchangeUID, resourceEmail) {
+		return ErrReadOnly
+	}
+	defer func() {
+		h.audit(AuditDeclineAsResource, "", []string{resourceEmail}, exchangeUID, time.Time{}, time.Time{}, err)
+	}()
+	itemID, changeKey, err := h.findEventUIDInMailbox(resourceEmail, exchangeUID)
+	if err != nil {
+		return fmt.Errorf("finding resource event ID: %w", err)
+	}
+	return h.declineItem(resourceEmail, itemID, changeKey)
+}
+
+// DeclineOccurrenceAsResource declines a single occurrence of the recurring
+// event on behalf of the resource, like DeclineAsResource does for the event.
+func (h *EWSHelper) DeclineOccurrenceAsResource(resourceEmail, exchangeUID string, instanceIndex int) (err error) {
+	if h.refuseWrite("declined occurrence %d of event %s as %s", instanceIndex, exchangeUID, resourceEmail) {
+		return ErrReadOnly
+	}
+	defer func() {
+		h.audit(AuditDeclineAsResource, "", []string{resourceEmail}, exchangeUID, time.Time{}, time.Time{}, err)
+	}()
+	masterID, _, err := h.findEventUIDInMailbox(resourceEmail, exchangeUID)
+	if err != nil {
+		return fmt.Errorf("finding resource event ID: %w", err)
+	}
+	occurrences, err := h.getOccurrences(masterID, resourceEmail, []int{instanceIndex})
+	if err != nil {
+		return fmt.Errorf("finding resource occurrence: %v", err)
+	} else if occurrences[0].err != nil {
+		return fmt.Errorf("finding resource occurrence: %w", occurrences[0].err)
+	}
+	occurrence := occurrences[0].item.ItemId
+	return h.declineItem(resourceEmail, occurrence.Id, occurrence.ChangeKey)
+}
+
+func (h *EWSHelper) declineItem(resourceEmail, itemID, changeKey string) error {
+	requestXML, err := declineItemRequest(resourceEmail, itemID, changeKey)
+	if err != nil {
+		return err
+	}
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return fmt.Errorf("requesting decline event: %w", err)
+	}
+
+	var soapFault soapFault
+	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
+		return fmt.Errorf("SOAP fault: %w", faultError(soapFault))
+	}
+
+	var response struct {
+		XMLName xml.Name `xml:"Envelope"`
+		Body    struct {
+			CreateItemResponse struct {
+				ResponseMessages struct {
+					CreateItemResponseMessage struct {
+						ResponseClass string `xml:"ResponseClass,attr"`
+						ResponseCode  string `xml:"ResponseCode"`
+					} `xml:"CreateItemResponseMessage"`
+				} `xml:"ResponseMessages"`
+			} `xml:"CreateItemResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(responseXML, &response); err != nil {
+		return fmt.Errorf("unmarshalling XML: %v", err)
 	}
 
-	newSyncState = env.Body.SyncFolderItemsResponse.ResponseMessages.SyncFolderItemsResponseMessage.SyncState
+	rm := response.Body.CreateItemResponse.ResponseMessages.CreateItemResponseMessage
+	if err := parseResponseCode(rm.ResponseClass, rm.ResponseCode, ""); err != nil {
+		return fmt.Errorf("declining event resulted in %w. Response: %s", err, string(responseXML))
+	}
+	return nil
+}
 
-	return new, updated, cancelled, newSyncState, nil
+// declineItemRequest declines the item in the resource's calendar, saving the
+// response instead of sending it to the organizer.
+func declineItemRequest(resourceEmail, itemID, changeKey string) (string, error) {
+	return marshalRequest(impersonate(IdentitySmtpAddress, resourceEmail), respondRequest{
+		MessageDisposition: "SaveOnly",
+		DeclineItem:        &declineItem{ReferenceItemID: requestItemID{ID: itemID, ChangeKey: changeKey}},
+	})
 }
 
-func (cr createOrUpdate) checkItem() error {
-	// Sometimes we can get information about non-calendarItems as well, like:
-	//
-	// <t:Create>
-	// 	<t:Message>
-	// 		<t:ItemId Id="AAMkADA0YjRlMDBiLTI5ZWQtNDhiYS1iYTRhLTU1NDcxMDA1YjlhZQBGAAAAAAD7L6rZT1EWT4zA7nKhCR2gBwA2JJ7TGEwETLI+6ZT89YaJAAAAAAENAAA2JJ7TGEwETLI+6ZT89YaJAAAGDBN1AAA=" ChangeKey="CQAAABYAAAA2JJ7TGEwETLI+6ZT89YaJAAAGC9oG" />
-	// 		<t:Subject>Let's go for lunch</t:Subject>
-	// 		<t:DateTimeReceived>2023-03-22T13:04:13Z</t:DateTimeReceived>
-	// 	</t:Message>
-	// </t:Create>
-	//
-	// Such entry has no value and we cannot process it.
-	if cr.CalendarItem == nil {
-		return fmt.Errorf("not a calendar item")
+// SetResourceSubject replaces the subject of the event's copy in the
+// resource's calendar, e.g. to hide the organizer's subject from those seeing
+// the room's calendar. The organizer's and attendees' copies are unchanged.
+func (h *EWSHelper) SetResourceSubject(resourceEmail, exchangeUID, subject string) (err error) {
+	if h.refuseWrite("set subject of event %s as %s", exchangeUID, resourceEmail) {
+		return ErrReadOnly
 	}
-	if cr.CalendarItem.Start.IsZero() || cr.CalendarItem.End.IsZero() {
-		return fmt.Errorf("item %v has no start or end", cr.CalendarItem.ItemId.Id)
+	defer func() {
+		h.audit(AuditResourceSubject, "", []string{resourceEmail}, exchangeUID, time.Time{}, time.Time{}, err)
+	}()
+	itemID, changeKey, err := h.findEventUIDInMailbox(resourceEmail, exchangeUID)
+	if err != nil {
+		return fmt.Errorf("finding resource event ID: %w", err)
 	}
-	if cr.CalendarItem.Organizer.Mailbox.EmailAddress == "" {
-		return fmt.Errorf("item %v has no organizer", cr.CalendarItem.ItemId.Id)
+
+	requestXML, err := resourceSubjectRequest(resourceEmail, itemID, changeKey, subject)
+	if err != nil {
+		return err
+	}
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return fmt.Errorf("requesting subject update: %w", err)
+	}
+	var soapFault soapFault
+	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
+		return fmt.Errorf("SOAP fault: %w", faultError(soapFault))
+	}
+
+	var response struct {
+		Body struct {
+			UpdateItemResponse struct {
+				ResponseMessages struct {
+					UpdateItemResponseMessage struct {
+						ResponseClass string `xml:"ResponseClass,attr"`
+						ResponseCode  string `xml:"ResponseCode"`
+					} `xml:"UpdateItemResponseMessage"`
+				} `xml:"ResponseMessages"`
+			} `xml:"UpdateItemResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(responseXML, &response); err != nil {
+		return fmt.Errorf("unmarshalling XML: %v", err)
+	}
+	rm := response.Body.UpdateItemResponse.ResponseMessages.UpdateItemResponseMessage
+	if err := parseResponseCode(rm.ResponseClass, rm.ResponseCode, ""); err != nil {
+		return fmt.Errorf("updating subject resulted in %w", err)
 	}
 	return nil
 }
 
-func (h *EWSHelper) expandRecurrence(eventID, roomEmail string) ([]calendarItem, error) {
-	var items []calendarItem
-	instanceIndex := 0
+// resourceSubjectRequest sets the subject of the item in the resource's
+// calendar without notifying anyone.
+func resourceSubjectRequest(resourceEmail, itemID, changeKey, subject string) (string, error) {
+	return marshalRequest(impersonate(IdentitySmtpAddress, resourceEmail), updateItemRequest{
+		ConflictResolution:                    "AlwaysOverwrite",
+		MessageDisposition:                    "SaveOnly",
+		SendMeetingInvitationsOrCancellations: "SendToNone",
+		ItemChange: itemChange{
+			ItemID:  &requestItemID{ID: itemID, ChangeKey: changeKey},
+			Updates: []fieldUpdate{setItemField("item:Subject", updatedCalendarItem{Subject: &subject})},
+		},
+	})
+}
 
-	for {
-		instanceIndex++ // Starts with 1
-		requestXML := fmt.Sprintf(`
-<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types" xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
-    <soap:Header>
-        <t:RequestServerVersion Version="Exchange2013_SP1"/>
-        <t:ExchangeImpersonation>
-            <t:ConnectingSID>
-                <t:SmtpAddress>%s</t:SmtpAddress>
-            </t:ConnectingSID>
-        </t:ExchangeImpersonation>
-    </soap:Header>
-    <soap:Body>
-        <m:GetItem>
-            <m:ItemShape>
-                <t:BaseShape>IdOnly</t:BaseShape>
-                <t:AdditionalProperties>
-                    <t:FieldURI FieldURI="calendar:UID"/>
-                    <t:FieldURI FieldURI="item:Subject"/>
-                    <t:FieldURI FieldURI="item:DateTimeReceived"/>
-                    <t:FieldURI FieldURI="calendar:Start"/>
-                    <t:FieldURI FieldURI="calendar:End"/>
-                    <t:FieldURI FieldURI="calendar:Organizer"/>
-                    <t:FieldURI FieldURI="calendar:CalendarItemType"/>
-                </t:AdditionalProperties>
-            </m:ItemShape>
-            <m:ItemIds>
-                <t:OccurrenceItemId RecurringMasterId="%s" InstanceIndex="%d" />
-            </m:ItemIds>
-        </m:GetItem>
-    </soap:Body>
-</soap:Envelope>`, roomEmail, eventID, instanceIndex)
+type attendees struct {
+	Attendee []eventAttendee `xml:"Attendee"`
+}
 
-		responseXML, err := h.sendRequest(requestXML)
-		if err != nil {
-			return nil, fmt.Errorf("expanding recurrence: %v", err)
-		}
-		// First, try to unmarshal into SOAPFault to see if there was an error.
-		var soapFault soapFault
-		if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
-			return nil, fmt.Errorf("SOAP fault: %s - %s", soapFault.Body.Fault.Detail.ResponseCode, soapFault.Body.Fault.Detail.Message)
-		}
+type eventAttendee struct {
+	Mailbox      mailbox `xml:"Mailbox"`
+	ResponseType string  `xml:"ResponseType"`
+}
 
-		var response struct {
-			Body struct {
-				GetItemResponse struct {
-					ResponseMessages struct {
-						GetItemResponseMessage struct {
-							ResponseClass string `xml:"ResponseClass,attr"`
-							ResponseCode  string `xml:"ResponseCode"`
-							Items         struct {
-								CalendarItem calendarItem `xml:"CalendarItem"`
-							} `xml:"Items"`
-						} `xml:"GetItemResponseMessage"`
-					} `xml:"ResponseMessages"`
-				} `xml:"GetItemResponse"`
-			} `xml:"Body"`
-		}
-		if err := xml.Unmarshal(responseXML, &response); err != nil {
-			return nil, fmt.Errorf("unmarshaling XML: %v", err)
-		}
+type eventAttendees struct {
+	RequiredAttendees attendees `xml:"RequiredAttendees"`
+	OptionalAttendees attendees `xml:"OptionalAttendees"`
+	Resources         attendees `xml:"Resources"`
+}
 
-		rm := response.Body.GetItemResponse.ResponseMessages.GetItemResponseMessage
-		if rm.ResponseCode == "ErrorCalendarOccurrenceIndexIsOutOfRecurrenceRange" {
-			// End of loop.
-			break
-		}
-		if rm.ResponseCode == "ErrorCalendarOccurrenceIsDeletedFromRecurrence" {
-			// This occurence was deleted, skip it.
+// redacts reports whether the item's details, i.e. its subject, location,
+// attendees, categories and join URL, are kept from Eliona. That's all items
+// in privacy mode, and items their organizer marked private or confidential
+// unless the configuration mirrors their details. The booking itself is kept,
+// so that the room shows as busy.
+func (h *EWSHelper) redacts(item calendarItem) bool {
+	if h.privacyMode {
+		return true
+	}
+	return !h.mirrorPrivateDetails && (item.Sensitivity == "Private" || item.Sensitivity == "Confidential")
+}
+
+// maxSyncedAttendees limits the attendees of an event resolved and forwarded
+// to the Booking app, as each Legacy DN might take a request to resolve.
+const maxSyncedAttendees = 250
+
+// attendeeEmails returns the SMTP addresses of the event's required and
+// optional attendees besides the room, and whether the list was cut at
+// maxSyncedAttendees. Attendees whose Legacy DN can't be resolved are left
+// out.
+func (h *EWSHelper) attendeeEmails(item calendarItem, roomEmail string) (emails []string, truncated bool) {
+	all := append(append([]eventAttendee{}, item.RequiredAttendees.Attendee...), item.OptionalAttendees.Attendee...)
+	if len(all) > maxSyncedAttendees {
+		all = all[:maxSyncedAttendees]
+		truncated = true
+	}
+	for _, attendee := range all {
+		email, err := h.resolveDN(attendee.Mailbox.EmailAddress)
+		if err != nil {
+			log.Debug("ews", "skipped attendee '%s' of event %v: %v", attendee.Mailbox.EmailAddress, item.UID, err)
 			continue
 		}
-		if rm.ResponseClass != "Success" {
-			return nil, fmt.Errorf("GetItem failed: %s", rm.ResponseCode)
+		if !strings.EqualFold(email, roomEmail) {
+			emails = append(emails, email)
 		}
-
-		item := rm.Items.CalendarItem
-		item.InstanceIndex = instanceIndex
-
-		items = append(items, item)
 	}
-
-	return items, nil
+	return emails, truncated
 }
 
-type Appointment struct {
-	Organizer string
-	Subject   string
-	Start     time.Time
-	End       time.Time
-	Location  string
-	Attendees []string
+// GetAttendeeResponses returns the response type (Accept, Decline, Tentative,
+// NoResponseReceived, ...) of each attendee of the event as seen by the
+// organizer. The map is keyed by lower-cased email addresses.
+func (h *EWSHelper) GetAttendeeResponses(organizer, exchangeUID string) (map[string]string, error) {
+	eventID, _, err := h.findEventUIDInMailbox(organizer, exchangeUID)
+	if err != nil {
+		return nil, fmt.Errorf("finding organizer event ID: %w", err)
+	}
+	item, err := h.getAttendees(organizer, eventID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make(map[string]string)
+	for _, list := range []attendees{item.RequiredAttendees, item.OptionalAttendees, item.Resources} {
+		for _, attendee := range list.Attendee {
+			responses[strings.ToLower(attendee.Mailbox.EmailAddress)] = attendee.ResponseType
+		}
+	}
+	return responses, nil
 }
 
-func (h *EWSHelper) CreateAppointment(appointment Appointment) (exchangeUID string, resourceEventIDs []string, err error) {
-	requestXML := fmt.Sprintf(`
-<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"
-                  xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types"
-                  xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
-    <soapenv:Header>
-        <t:RequestServerVersion Version="Exchange2013_SP1"/>
-        <t:ExchangeImpersonation>
-            <t:ConnectingSID>
-                <t:SmtpAddress>%s</t:SmtpAddress>
-            </t:ConnectingSID>
-        </t:ExchangeImpersonation>
-    </soapenv:Header>
-    <soapenv:Body>
-        <m:CreateItem SendMeetingInvitations="SendToAllAndSaveCopy">
-            <m:SavedItemFolderId>
-                <t:DistinguishedFolderId Id="calendar"/>
-            </m:SavedItemFolderId>
-            <m:Items>
-                <t:CalendarItem>
-                    <t:Subject>%s</t:Subject>
-                    <t:Start>%s</t:Start>
-                    <t:End>%s</t:End>
-                    <t:IsAllDayEvent>false</t:IsAllDayEvent>
-                    <t:LegacyFreeBusyStatus>Busy</t:LegacyFreeBusyStatus>
-                    <t:Location>%s</t:Location>
-                    <t:RequiredAttendees>%s</t:RequiredAttendees>
-                </t:CalendarItem>
-            </m:Items>
-        </m:CreateItem>
-    </soapenv:Body>
-</soapenv:Envelope>`,
-		appointment.Organizer,
-		appointment.Subject,
-		appointment.Start.Format(time.RFC3339),
-		appointment.End.Format(time.RFC3339),
-		appointment.Location,
-		formatAttendees(appointment.Attendees),
-	)
+func (h *EWSHelper) getAttendees(organizer, eventID string, instanceIndex int) (eventAttendees, error) {
+	request := getItemRequest{
+		ItemShape: itemShape{
+			BaseShape:            "IdOnly",
+			AdditionalProperties: &additionalProperties{FieldURIs: fieldURIs("calendar:RequiredAttendees", "calendar:OptionalAttendees", "calendar:Resources")},
+		},
+	}
+	if instanceIndex > 0 {
+		request.OccurrenceItemIDs = []occurrenceItemID{{RecurringMasterID: eventID, InstanceIndex: instanceIndex}}
+	} else {
+		request.ItemIDs = []requestItemID{{ID: eventID}}
+	}
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, organizer), request)
+	if err != nil {
+		return eventAttendees{}, err
+	}
 
 	responseXML, err := h.sendRequest(requestXML)
 	if err != nil {
-		return "", nil, fmt.Errorf("requesting create appointment: %w", err)
+		return eventAttendees{}, fmt.Errorf("requesting attendees: %v", err)
 	}
-
 	// First, try to unmarshal into SOAPFault to see if there was an error.
 	var soapFault soapFault
 	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
-		if soapFault.Body.Fault.Detail.ResponseCode == "ErrorNonExistentMailbox" {
-			return "", nil, ErrNonExistentMailbox
-		}
-		return "", nil, fmt.Errorf("SOAP fault: %s - %s", soapFault.Body.Fault.Detail.ResponseCode, soapFault.Body.Fault.Detail.Message)
+		return eventAttendees{}, fmt.Errorf("SOAP fault: %w", faultError(soapFault))
 	}
 
-	var env appointmentCreated
-	if err := xml.Unmarshal(responseXML, &env); err != nil {
-		return "", nil, fmt.Errorf("unmarshaling XML: %v", err)
+	var response struct {
+		Body struct {
+			GetItemResponse struct {
+				ResponseMessages struct {
+					GetItemResponseMessage struct {
+						ResponseClass string `xml:"ResponseClass,attr"`
+						ResponseCode  string `xml:"ResponseCode"`
+						Items         struct {
+							CalendarItem eventAttendees `xml:"CalendarItem"`
+						} `xml:"Items"`
+					} `xml:"GetItemResponseMessage"`
+				} `xml:"ResponseMessages"`
+			} `xml:"GetItemResponse"`
+		} `xml:"Body"`
 	}
-
-	organizerEventID := env.Body.CreateItemResponse.ResponseMessages.CreateItemResponseMessage.Items.CalendarItem.ItemId.ID
-
-	exchangeUID, err = h.getUIDFromItemId(appointment.Organizer, organizerEventID)
-	if err != nil {
-		return "", nil, fmt.Errorf("getting UID from ItemID: %v", err)
+	if err := xml.Unmarshal(responseXML, &response); err != nil {
+		return eventAttendees{}, fmt.Errorf("unmarshaling XML: %v", err)
 	}
-
-	// Let's give the server some time to process the invitation. Sometimes it's
-	// instant, sometimes 2 seconds aren't enough. This should be long enough
-	// time.
-	time.Sleep(15 * time.Second)
-	for _, attendee := range appointment.Attendees {
-		resourceEventID, _, err := h.findEventUIDInMailbox(attendee, exchangeUID)
-		if errors.Is(err, errNotFound) {
-			// The resource has probably declined the invitation.
-			return exchangeUID, nil, ErrDeclined
-		} else if err != nil {
-			return exchangeUID, nil, fmt.Errorf("finding resource event ID: %v", err)
-		}
-		resourceEventIDs = append(resourceEventIDs, resourceEventID)
+	rm := response.Body.GetItemResponse.ResponseMessages.GetItemResponseMessage
+	if err := parseResponseCode(rm.ResponseClass, rm.ResponseCode, ""); err != nil {
+		return eventAttendees{}, fmt.Errorf("GetItem failed: %w", err)
 	}
+	return rm.Items.CalendarItem, nil
+}
 
-	return exchangeUID, resourceEventIDs, nil
+// UpdateAppointmentAttendees adds and removes attendees (typically rooms) of
+// an existing event without recreating it. In case the event was changed in
+// the meantime, the update is retried with a fresh ChangeKey.
+func (h *EWSHelper) UpdateAppointmentAttendees(exchangeUID, organizer string, add, remove []string) (err error) {
+	if h.refuseWrite("added rooms %v to and removed rooms %v from event %s", add, remove, exchangeUID) {
+		return ErrReadOnly
+	}
+	defer func() {
+		h.audit(AuditUpdateAttendees, organizer, append(append([]string{}, add...), remove...), exchangeUID, time.Time{}, time.Time{}, err)
+	}()
+	return h.updateAppointmentAttendees(exchangeUID, organizer, add, remove, sendToChangedAndSaveCopy)
 }
 
-func formatAttendees(attendees []string) string {
-	var attendeeXML strings.Builder
-	for _, email := range attendees {
-		attendeeXML.WriteString(fmt.Sprintf(`
-            <t:Attendee>
-                <t:Mailbox>
-                    <t:EmailAddress>%s</t:EmailAddress>
-                </t:Mailbox>
-            </t:Attendee>`, email))
+// RemoveOccurrenceAttendees removes attendees (typically rooms) from a single
+// occurrence of the recurring event, keeping them in the other occurrences.
+func (h *EWSHelper) RemoveOccurrenceAttendees(exchangeUID, organizer string, instanceIndex int, remove []string) (err error) {
+	if h.refuseWrite("removed rooms %v from occurrence %d of event %s", remove, instanceIndex, exchangeUID) {
+		return ErrReadOnly
+	}
+	defer func() {
+		h.audit(AuditUpdateAttendees, organizer, remove, exchangeUID, time.Time{}, time.Time{}, err)
+	}()
+	eventID, _, err := h.findEventUIDInMailbox(organizer, exchangeUID)
+	if err != nil {
+		return fmt.Errorf("finding organizer event ID: %w", err)
+	}
+	current, err := h.getAttendees(organizer, eventID, instanceIndex)
+	if err != nil {
+		return fmt.Errorf("getting current attendees: %v", err)
 	}
-	return attendeeXML.String()
+	item := itemChange{OccurrenceItemID: &occurrenceItemID{RecurringMasterID: eventID, InstanceIndex: instanceIndex}}
+	return h.updateAttendees(organizer, item, current, nil, remove, sendToChangedAndSaveCopy)
 }
 
-type appointmentCreated struct {
-	XMLName xml.Name `xml:"Envelope"`
-	Body    struct {
-		CreateItemResponse struct {
-			ResponseMessages struct {
-				CreateItemResponseMessage struct {
-					ResponseClass string `xml:"ResponseClass,attr"`
-					ResponseCode  string `xml:"ResponseCode"`
-					Items         struct {
-						CalendarItem struct {
-							ItemId struct {
-								ID        string `xml:"Id,attr"`
-								ChangeKey string `xml:"ChangeKey,attr"`
-							} `xml:"ItemId"`
-						} `xml:"CalendarItem"`
-					} `xml:"Items"`
-				} `xml:"CreateItemResponseMessage"`
-			} `xml:"ResponseMessages"`
-		} `xml:"CreateItemResponse"`
-	} `xml:"Body"`
+// updateAppointmentAttendees updates the attendees, retrying on conflicts.
+// sendInvitations is the SendMeetingInvitationsOrCancellations mode.
+func (h *EWSHelper) updateAppointmentAttendees(exchangeUID, organizer string, add, remove []string, sendInvitations string) error {
+	const attempts = 3
+	for attempt := 1; ; attempt++ {
+		eventID, changeKey, err := h.findEventUIDInMailbox(organizer, exchangeUID)
+		if err != nil {
+			return fmt.Errorf("finding organizer event ID: %w", err)
+		}
+		current, err := h.getAttendees(organizer, eventID, 0)
+		if err != nil {
+			return fmt.Errorf("getting current attendees: %v", err)
+		}
+		item := itemChange{ItemID: &requestItemID{ID: eventID, ChangeKey: changeKey}}
+		err = h.updateAttendees(organizer, item, current, add, remove, sendInvitations)
+		if errors.Is(err, errConflict) && attempt < attempts {
+			log.Debug("ews", "event %s changed while updating attendees, retrying", exchangeUID)
+			continue
+		}
+		return err
+	}
 }
 
-func (h *EWSHelper) CancelEvent(event syncmodel.BookingGroup) error {
-	// Find the organizer's eventId and changeKey using the UID
-	eventID, changeKey, err := h.findEventUIDInMailbox(event.OrganizerEmail, event.ExchangeUID)
+// updateAttendees adds rooms as resources and removes them from the resources
+// and the required attendees, where events created before rooms were invited
+// as resources have them. The item change names the item or occurrence to update.
+func (h *EWSHelper) updateAttendees(organizer string, item itemChange, current eventAttendees, add, remove []string, sendInvitations string) error {
+	var updates []fieldUpdate
+	for _, field := range []struct {
+		name    string
+		current attendees
+		add     []string
+	}{
+		{"RequiredAttendees", current.RequiredAttendees, nil},
+		{"Resources", current.Resources, add},
+	} {
+		if update, ok := attendeesUpdate(field.name, field.current, field.add, remove); ok {
+			updates = append(updates, update)
+		}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	item.Updates = updates
+
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, organizer), updateItemRequest{
+		ConflictResolution:                    "NeverOverwrite",
+		MessageDisposition:                    "SaveOnly",
+		SendMeetingInvitationsOrCancellations: sendInvitations,
+		ItemChange:                            item,
+	})
 	if err != nil {
-		return fmt.Errorf("finding organizer event ID: %v", err)
+		return err
 	}
 
-	requestXML := fmt.Sprintf(`
-<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types" xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
-    <soap:Header>
-        <t:RequestServerVersion Version="Exchange2013_SP1"/>
-        <t:ExchangeImpersonation>
-            <t:ConnectingSID>
-                <t:SmtpAddress>%s</t:SmtpAddress>
-            </t:ConnectingSID>
-        </t:ExchangeImpersonation>
-    </soap:Header>
-    <soap:Body>
-    <m:CreateItem MessageDisposition="SendAndSaveCopy">
-      <m:Items>
-        <t:CancelCalendarItem>
-          <t:ReferenceItemId Id="%s" ChangeKey="%s" />
-          <t:NewBodyContent BodyType="HTML">Cancelled via Eliona</t:NewBodyContent>
-        </t:CancelCalendarItem>
-      </m:Items>
-    </m:CreateItem>
-  </soap:Body>
-</soap:Envelope>`, event.OrganizerEmail, eventID, changeKey)
-
 	responseXML, err := h.sendRequest(requestXML)
 	if err != nil {
-		return fmt.Errorf("requesting cancel event: %w", err)
+		return fmt.Errorf("requesting attendees update: %w", err)
 	}
-
 	// First, try to unmarshal into SOAPFault to see if there was an error.
 	var soapFault soapFault
 	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
-		if soapFault.Body.Fault.FaultCode == "ErrorNonExistentMailbox" {
-			return ErrNonExistentMailbox
-		}
-		return fmt.Errorf("SOAP fault: %s - %s", soapFault.Body.Fault.Detail.ResponseCode, soapFault.Body.Fault.Detail.Message)
+		return fmt.Errorf("SOAP fault: %w", faultError(soapFault))
 	}
 
 	var response struct {
-		XMLName xml.Name `xml:"Envelope"`
-		Body    struct {
-			CreateItemResponse struct {
+		Body struct {
+			UpdateItemResponse struct {
 				ResponseMessages struct {
-					CreateItemResponseMessage struct {
+					UpdateItemResponseMessage struct {
 						ResponseClass string `xml:"ResponseClass,attr"`
 						ResponseCode  string `xml:"ResponseCode"`
-					} `xml:"CreateItemResponseMessage"`
+					} `xml:"UpdateItemResponseMessage"`
 				} `xml:"ResponseMessages"`
-			} `xml:"CreateItemResponse"`
+			} `xml:"UpdateItemResponse"`
 		} `xml:"Body"`
 	}
-
 	if err := xml.Unmarshal(responseXML, &response); err != nil {
 		return fmt.Errorf("unmarshalling XML: %v", err)
 	}
+	rm := response.Body.UpdateItemResponse.ResponseMessages.UpdateItemResponseMessage
+	if err := parseResponseCode(rm.ResponseClass, rm.ResponseCode, ""); err != nil {
+		return fmt.Errorf("updating attendees resulted in %w", err)
+	}
+	return nil
+}
 
-	responseClass := response.Body.CreateItemResponse.ResponseMessages.CreateItemResponseMessage.ResponseClass
-	responseCode := response.Body.CreateItemResponse.ResponseMessages.CreateItemResponseMessage.ResponseCode
+// UpdateAppointment moves the event with the given UID to the new times and
+// sends the update to all attendees. Nothing is sent if the event already
+// takes place at these times.
+func (h *EWSHelper) UpdateAppointment(exchangeUID, organizer string, start, end time.Time) (err error) {
+	if h.refuseWrite("moved event %s to %v - %v", exchangeUID, start, end) {
+		return ErrReadOnly
+	}
+	return h.moveEvent(exchangeUID, organizer, 0, start, end)
+}
 
-	if responseClass != "Success" || responseCode != "NoError" {
-		return fmt.Errorf("cancelling event resulted in %s - %s. Response: %s", responseClass, responseCode, string(responseXML))
+// UpdateOccurrence moves a single occurrence of the recurring event with the
+// given UID to the new times, like UpdateAppointment does with whole events.
+func (h *EWSHelper) UpdateOccurrence(exchangeUID, organizer string, instanceIndex int, start, end time.Time) (err error) {
+	if h.refuseWrite("moved occurrence %d of event %s to %v - %v", instanceIndex, exchangeUID, start, end) {
+		return ErrReadOnly
 	}
+	return h.moveEvent(exchangeUID, organizer, instanceIndex, start, end)
+}
 
-	return nil
+// moveEvent moves the event, or its occurrence with the instance index if
+// positive, unless it already takes place at the times.
+func (h *EWSHelper) moveEvent(exchangeUID, organizer string, instanceIndex int, start, end time.Time) (err error) {
+	moved := false
+	defer func() {
+		if moved || err != nil {
+			h.audit(AuditReschedule, organizer, nil, exchangeUID, start, end, err)
+		}
+	}()
+	const attempts = 3
+	for attempt := 1; ; attempt++ {
+		eventID, changeKey, err := h.findEventUIDInMailbox(organizer, exchangeUID)
+		if err != nil {
+			return fmt.Errorf("finding organizer event ID: %w", err)
+		}
+		request := getItemRequest{
+			ItemShape: itemShape{
+				BaseShape:            "IdOnly",
+				AdditionalProperties: &additionalProperties{FieldURIs: fieldURIs("calendar:Start", "calendar:End")},
+			},
+		}
+		if instanceIndex > 0 {
+			request.OccurrenceItemIDs = []occurrenceItemID{{RecurringMasterID: eventID, InstanceIndex: instanceIndex}}
+		} else {
+			request.ItemIDs = []requestItemID{{ID: eventID}}
+		}
+		fetched, err := h.getCalendarItems(organizer, request)
+		if err != nil {
+			return fmt.Errorf("getting current times: %v", err)
+		}
+		if len(fetched) != 1 {
+			return fmt.Errorf("getting current times: got %d items", len(fetched))
+		}
+		if fetched[0].err != nil {
+			return fmt.Errorf("getting current times: %w", fetched[0].err)
+		}
+		current := fetched[0].item
+		if current.Start.Equal(start) && current.End.Equal(end) {
+			return nil
+		}
+		if instanceIndex > 0 {
+			// The occurrence is an item of its own.
+			eventID, changeKey = current.ItemId.Id, current.ItemId.ChangeKey
+		}
+		err = h.rescheduleAppointment(organizer, eventID, changeKey, start, end)
+		if errors.Is(err, errConflict) && attempt < attempts {
+			log.Debug("ews", "event %s changed while moving it, retrying", exchangeUID)
+			continue
+		}
+		moved = err == nil
+		return err
+	}
 }
 
-func (h *EWSHelper) CancelOccurrence(group syncmodel.BookingGroup, occurrence syncmodel.BookingOccurrence) error {
-	// Find the organizer's eventId using the UID
-	eventID, _, err := h.findEventUIDInMailbox(group.OrganizerEmail, group.ExchangeUID)
+func (h *EWSHelper) rescheduleAppointment(organizer, eventID, changeKey string, start, end time.Time) error {
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, organizer), updateItemRequest{
+		ConflictResolution:                    "NeverOverwrite",
+		MessageDisposition:                    "SaveOnly",
+		SendMeetingInvitationsOrCancellations: sendToAllAndSaveCopy,
+		ItemChange: itemChange{
+			ItemID: &requestItemID{ID: eventID, ChangeKey: changeKey},
+			Updates: []fieldUpdate{
+				setItemField("calendar:Start", updatedCalendarItem{Start: syncmodel.EWSTime(start)}),
+				setItemField("calendar:End", updatedCalendarItem{End: syncmodel.EWSTime(end)}),
+			},
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("finding organizer event ID: %v", err)
+		return err
 	}
 
-	requestXML := fmt.Sprintf(`
-<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types" xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
-  <soap:Header>
-      <t:RequestServerVersion Version="Exchange2013_SP1"/>
-      <t:ExchangeImpersonation>
-          <t:ConnectingSID>
-              <t:SmtpAddress>%s</t:SmtpAddress>
-          </t:ConnectingSID>
-      </t:ExchangeImpersonation>
-  </soap:Header>
-  <soap:Body>
-    <m:DeleteItem DeleteType="MoveToDeletedItems" SendMeetingCancellations="SendToAllAndSaveCopy">
-      <m:ItemIds>
-        <t:OccurrenceItemId RecurringMasterId="%s" InstanceIndex="%d" />
-      </m:ItemIds>
-    </m:DeleteItem>
-  </soap:Body>
-</soap:Envelope>`, group.OrganizerEmail, eventID, occurrence.InstanceIndex)
-
 	responseXML, err := h.sendRequest(requestXML)
 	if err != nil {
-		return fmt.Errorf("requesting cancel event: %w", err)
+		return fmt.Errorf("requesting times update: %w", err)
 	}
-
-	// First, try to unmarshal into SOAPFault to see if there was an error.
 	var soapFault soapFault
 	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
-		if soapFault.Body.Fault.FaultCode == "ErrorNonExistentMailbox" {
-			return ErrNonExistentMailbox
-		}
-		return fmt.Errorf("SOAP fault: %s - %s", soapFault.Body.Fault.Detail.ResponseCode, soapFault.Body.Fault.Detail.Message)
+		return fmt.Errorf("SOAP fault: %w", faultError(soapFault))
 	}
 
 	var response struct {
-		XMLName xml.Name `xml:"Envelope"`
-		Body    struct {
-			CreateItemResponse struct {
+		Body struct {
+			UpdateItemResponse struct {
 				ResponseMessages struct {
-					CreateItemResponseMessage struct {
+					UpdateItemResponseMessage struct {
 						ResponseClass string `xml:"ResponseClass,attr"`
 						ResponseCode  string `xml:"ResponseCode"`
-					} `xml:"CreateItemResponseMessage"`
+					} `xml:"UpdateItemResponseMessage"`
 				} `xml:"ResponseMessages"`
-			} `xml:"CreateItemResponse"`
+			} `xml:"UpdateItemResponse"`
 		} `xml:"Body"`
 	}
-
 	if err := xml.Unmarshal(responseXML, &response); err != nil {
 		return fmt.Errorf("unmarshalling XML: %v", err)
 	}
+	rm := response.Body.UpdateItemResponse.ResponseMessages.UpdateItemResponseMessage
+	if err := parseResponseCode(rm.ResponseClass, rm.ResponseCode, ""); err != nil {
+		return fmt.Errorf("updating times resulted in %w", err)
+	}
+	return nil
+}
 
-	responseClass := response.Body.CreateItemResponse.ResponseMessages.CreateItemResponseMessage.ResponseClass
-	responseCode := response.Body.CreateItemResponse.ResponseMessages.CreateItemResponseMessage.ResponseCode
+// attendeesUpdate returns the update of the attendee collection field adding
+// and removing the attendees, false if nothing changes.
+func attendeesUpdate(field string, current attendees, add, remove []string) (fieldUpdate, bool) {
+	var remaining []string
+	removed := false
+	for _, attendee := range current.Attendee {
+		if containsFold(remove, attendee.Mailbox.EmailAddress) {
+			removed = true
+			continue
+		}
+		remaining = append(remaining, attendee.Mailbox.EmailAddress)
+	}
 
-	if responseClass != "Success" || responseCode != "NoError" {
-		return fmt.Errorf("cancelling event resulted in %s - %s. Response: %s", responseClass, responseCode, string(responseXML))
+	// A single attendee can't be deleted from the list, so when removing, the
+	// whole list is replaced. Appending is enough otherwise.
+	uri := "calendar:" + field
+	switch {
+	case removed && len(remaining)+len(add) == 0:
+		return deleteItemField(uri), true
+	case removed:
+		return setItemField(uri, attendeesField(field, append(remaining, add...))), true
+	case len(add) > 0:
+		return appendToItemField(uri, attendeesField(field, add)), true
+	default:
+		return fieldUpdate{}, false
 	}
+}
 
-	return nil
+// attendeesField is the value of the attendee collection field.
+func attendeesField(field string, emails []string) updatedCalendarItem {
+	if field == "Resources" {
+		return updatedCalendarItem{Resources: attendeesOf(emails)}
+	}
+	return updatedCalendarItem{RequiredAttendees: attendeesOf(emails)}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
 }
 
 func (h *EWSHelper) getUIDFromItemId(itemMailbox string, itemId string) (string, error) {
-	requestXML := fmt.Sprintf(`
-<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
-    <soap:Header>
-        <t:RequestServerVersion Version="Exchange2013_SP1"/>
-        <t:ExchangeImpersonation>
-            <t:ConnectingSID>
-                <t:SmtpAddress>%s</t:SmtpAddress>
-            </t:ConnectingSID>
-        </t:ExchangeImpersonation>
-    </soap:Header>
-    <soap:Body>
-        <GetItem xmlns="http://schemas.microsoft.com/exchange/services/2006/messages">
-            <ItemShape>
-                <t:BaseShape>IdOnly</t:BaseShape>
-                <t:AdditionalProperties>
-                    <t:FieldURI FieldURI="calendar:UID"/>
-                </t:AdditionalProperties>
-            </ItemShape>
-            <ItemIds>
-                <t:ItemId Id="%s"/>
-            </ItemIds>
-        </GetItem>
-    </soap:Body>
-</soap:Envelope>`, itemMailbox, itemId)
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, itemMailbox), getItemRequest{
+		ItemShape: itemShape{
+			BaseShape:            "IdOnly",
+			AdditionalProperties: &additionalProperties{FieldURIs: fieldURIs("calendar:UID")},
+		},
+		ItemIDs: []requestItemID{{ID: itemId}},
+	})
+	if err != nil {
+		return "", err
+	}
 
 	respBody, err := h.sendRequest(requestXML)
 	if err != nil {
@@ -862,13 +2930,19 @@ func (h *EWSHelper) getUIDFromItemId(itemMailbox string, itemId string) (string,
 }
 
 func getObjectIdStringFromUid(id string) (string, error) {
-	buf, err := hex.DecodeString(id)
+	buf, err := hex.DecodeString(syncmodel.BaseUID(id))
 	if err != nil {
 		return "", err
 	}
 	return base64.StdEncoding.EncodeToString(buf), nil
 }
 
+// FindEventID returns the ID of the event specified by UID in the mailbox.
+func (h *EWSHelper) FindEventID(mailbox, uid string) (string, error) {
+	itemID, _, err := h.findEventUIDInMailbox(mailbox, uid)
+	return itemID, err
+}
+
 // findEventUIDInMailbox finds the event specified by UID in the specified mailbox
 // and returns it's itemID and changeKey.
 // Inspired by article [1].
@@ -897,39 +2971,18 @@ func (h *EWSHelper) findEventUIDInMailbox(mailbox, uid string) (itemID string, c
 		return "", "", fmt.Errorf("error converting UID: %v", err)
 	}
 
-	requestXML := fmt.Sprintf(`
-<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types" xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
-    <soap:Header>
-        <t:RequestServerVersion Version="Exchange2013_SP1"/>
-        <t:ExchangeImpersonation>
-            <t:ConnectingSID>
-                <t:SmtpAddress>%s</t:SmtpAddress>
-            </t:ConnectingSID>
-        </t:ExchangeImpersonation>
-    </soap:Header>
-    <soap:Body>
-      <m:FindItem Traversal="Shallow">
-        <m:ItemShape>
-          <t:BaseShape>AllProperties</t:BaseShape>
-        </m:ItemShape>
-        <m:Restriction>
-          <t:IsEqualTo>
-            <t:ExtendedFieldURI PropertySetId="6ED8DA90-450B-101B-98DA-00AA003F1305" PropertyId="3" PropertyType="Binary"/>
-            <t:FieldURIOrConstant>
-              <t:Constant Value="%s"/>
-            </t:FieldURIOrConstant>
-          </t:IsEqualTo>
-        </m:Restriction>
-        <m:ParentFolderIds>
-          <t:DistinguishedFolderId Id="calendar">
-            <t:Mailbox>
-              <t:EmailAddress>%s</t:EmailAddress>
-            </t:Mailbox>
-          </t:DistinguishedFolderId>
-        </m:ParentFolderIds>
-      </m:FindItem>
-    </soap:Body>
-</soap:Envelope>`, mailbox, globalObjectID, mailbox)
+	// The GlobalObjectId is the binary MAPI property 3 of the meeting property set.
+	uidEqual := &isEqualTo{Field: extendedFieldURI{PropertySetID: "6ED8DA90-450B-101B-98DA-00AA003F1305", PropertyID: "3", PropertyType: "Binary"}}
+	uidEqual.Constant.Value = globalObjectID
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, mailbox), findItemRequest{
+		Traversal:      "Shallow",
+		ItemShape:      itemShape{BaseShape: "AllProperties"},
+		Restriction:    &restriction{IsEqualTo: uidEqual},
+		ParentFolderID: calendarOf(mailbox),
+	})
+	if err != nil {
+		return "", "", err
+	}
 
 	respBody, err := h.sendRequest(requestXML)
 	if err != nil {
@@ -971,33 +3024,21 @@ func (h *EWSHelper) findEventUIDInMailbox(mailbox, uid string) (itemID string, c
 
 // resolveDN translates the distinguished name to a SMTP one.
 func (h *EWSHelper) resolveDN(name string) (string, error) {
-	if smtp, found := h.addressCache[name]; found {
-		return smtp, nil
-	}
 	// Docs say the reply might contain SMTP address sometimes. No need to resolve that.
 	if isSMTPAddress(name) {
-		h.addressCache[name] = name
 		return name, nil
 	}
-	requestXML := fmt.Sprintf(`
-<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"
-                  xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types"
-                  xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
-    <soapenv:Header>
-        <t:RequestServerVersion Version="Exchange2013_SP1"/>
-        <t:ExchangeImpersonation>
-            <t:ConnectingSID>
-                <t:PrincipalName>%s</t:PrincipalName>
-            </t:ConnectingSID>
-        </t:ExchangeImpersonation>
-    </soapenv:Header>
-    <soapenv:Body>
-        <m:ResolveNames ReturnFullContactData="true" SearchScope="ActiveDirectory">
-            <m:UnresolvedEntry>%s</m:UnresolvedEntry>
-        </m:ResolveNames>
-    </soapenv:Body>
-</soapenv:Envelope>
-`, h.serviceUser, name)
+	if smtp, found := h.addressCache.get(name); found {
+		return smtp, nil
+	}
+	requestXML, err := marshalRequest(impersonate(IdentityPrincipalName, h.serviceUser), resolveNamesRequest{
+		ReturnFullContactData: true,
+		SearchScope:           "ActiveDirectory",
+		UnresolvedEntry:       name,
+	})
+	if err != nil {
+		return "", err
+	}
 
 	responseXML, err := h.sendRequest(requestXML)
 	if err != nil {
@@ -1020,7 +3061,7 @@ func (h *EWSHelper) resolveDN(name string) (string, error) {
 	}
 
 	smtpAddress := resolutionMessages[0].Mailbox.EmailAddress
-	h.addressCache[name] = smtpAddress
+	h.addressCache.put(name, smtpAddress)
 	return smtpAddress, nil
 }
 
@@ -1035,6 +3076,9 @@ type resolveNamesResponse struct {
 		ResolveNamesResponse struct {
 			ResponseMessages struct {
 				ResolveNamesResponseMessage []struct {
+					ResponseClass string `xml:"ResponseClass,attr"`
+					MessageText   string `xml:"MessageText"`
+					ResponseCode  string `xml:"ResponseCode"`
 					ResolutionSet struct {
 						TotalItemsInView        string `xml:"TotalItemsInView,attr"`
 						IncludesLastItemInRange string `xml:"IncludesLastItemInRange,attr"`
@@ -1042,6 +3086,16 @@ type resolveNamesResponse struct {
 							Mailbox struct {
 								EmailAddress string `xml:"EmailAddress"`
 							} `xml:"Mailbox"`
+							Contact struct {
+								OfficeLocation string `xml:"OfficeLocation"`
+								Department     string `xml:"Department"`
+								PhoneNumbers   struct {
+									Entry []struct {
+										Key    string `xml:"Key,attr"`
+										Number string `xml:",chardata"`
+									} `xml:"Entry"`
+								} `xml:"PhoneNumbers"`
+							} `xml:"Contact"`
 						} `xml:"Resolution"`
 					} `xml:"ResolutionSet"`
 				} `xml:"ResolveNamesResponseMessage"`
@@ -0,0 +1,3333 @@
+package ews
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"ews/apiserver"
+	"ews/appdb"
+	syncmodel "ews/model/sync"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Azure/go-ntlmssp"
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"golang.org/x/oauth2"
+)
+
+// newTestHelper returns a helper talking to a test server responding with the given body.
+func newTestHelper(t *testing.T, response string) *EWSHelper {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+	}
+}
+
+const getRoomsWithMalformedRoom = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <m:GetRoomsResponse ResponseClass="Success" xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
+      <m:ResponseCode>NoError</m:ResponseCode>
+      <m:Rooms>
+        <t:Room>
+          <t:Id>
+            <t:Name>Meeting room 1</t:Name>
+            <t:EmailAddress>room1@example.com</t:EmailAddress>
+          </t:Id>
+        </t:Room>
+        <t:Room>
+          <t:Id>
+            <t:Name>Orphaned room</t:Name>
+            <t:EmailAddress></t:EmailAddress>
+          </t:Id>
+        </t:Room>
+        <t:Room>
+          <t:Id>
+            <t:EmailAddress>room2@example.com</t:EmailAddress>
+          </t:Id>
+        </t:Room>
+      </m:Rooms>
+    </m:GetRoomsResponse>
+  </s:Body>
+</s:Envelope>`
+
+func TestGetAssetsSkipsRoomsWithoutEmail(t *testing.T) {
+	h := newTestHelper(t, getRoomsWithMalformedRoom)
+	roomList := "rooms@example.com"
+	root, err := h.GetAssets(apiserver.Configuration{RoomListUPN: &roomList})
+	if err != nil {
+		t.Fatalf("getting assets: %v", err)
+	}
+	if len(root.Rooms) != 2 {
+		t.Fatalf("expected 2 rooms, got %d: %+v", len(root.Rooms), root.Rooms)
+	}
+	gais := make(map[string]bool)
+	for _, room := range root.Rooms {
+		if room.Email == "" {
+			t.Errorf("room %q has no email", room.Name)
+		}
+		if room.Name == "" {
+			t.Errorf("room %q has no name", room.Email)
+		}
+		if gais[room.GetGAI()] {
+			t.Errorf("duplicate GAI %q", room.GetGAI())
+		}
+		gais[room.GetGAI()] = true
+	}
+	if root.Rooms[1].Name != "room2@example.com" {
+		t.Errorf("expected name to fall back to email, got %q", root.Rooms[1].Name)
+	}
+}
+
+func TestGetAssetsDeduplicatesRoomsOfRoomLists(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "GetRooms") {
+			requests++
+		}
+		fmt.Fprint(w, getRoomsWithMalformedRoom)
+	}))
+	defer server.Close()
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "service@example.com"}
+	root, err := h.GetAssets(apiserver.Configuration{
+		RoomListUPN:  common.Ptr("first.floor@example.com"),
+		RoomListUPNs: &[]string{"second.floor@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("getting assets: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected both room lists to be requested, got %d requests", requests)
+	}
+	if len(root.Rooms) != 2 {
+		t.Errorf("expected rooms in both lists once, got %+v", root.Rooms)
+	}
+}
+
+func TestGetAssetsAppliesRoomNames(t *testing.T) {
+	h := newTestHelper(t, getRoomsWithMalformedRoom)
+	roomList := "rooms@example.com"
+	root, err := h.GetAssets(apiserver.Configuration{
+		RoomListUPN:   &roomList,
+		RoomNameRules: []apiserver.RoomNameRule{{Pattern: `^Meeting room (\d+)$`, Replacement: "Room $1"}},
+		RoomNames:     map[string]string{"room2@example.com": "Boardroom"},
+	})
+	if err != nil {
+		t.Fatalf("getting assets: %v", err)
+	}
+	if got := root.Rooms[0].GetName(); got != "Room 1" {
+		t.Errorf("expected rule to rename room 1, got %q", got)
+	}
+	if got := root.Rooms[1].GetName(); got != "Boardroom" {
+		t.Errorf("expected room 2 to be named explicitly, got %q", got)
+	}
+	if root.Rooms[0].Name != "Meeting room 1" || root.Rooms[0].GetGAI() != "ews_room_room1@example.com" {
+		t.Errorf("expected Exchange name and GAI to stay, got %q, %q", root.Rooms[0].Name, root.Rooms[0].GetGAI())
+	}
+}
+
+func TestGetAssetsSetsCapacities(t *testing.T) {
+	h := newTestHelper(t, getRoomsWithMalformedRoom)
+	root, err := h.GetAssets(apiserver.Configuration{
+		RoomListUPN:    common.Ptr("rooms@example.com"),
+		RoomCapacities: map[string]int32{"Room1@example.com": 12},
+	})
+	if err != nil {
+		t.Fatalf("getting assets: %v", err)
+	}
+	if c := root.Rooms[0].Capacity; c == nil || *c != 12 {
+		t.Errorf("expected room 1 to seat 12, got %v", c)
+	}
+	if c := root.Rooms[1].Capacity; c != nil {
+		t.Errorf("expected capacity of room 2 to be unknown, got %d", *c)
+	}
+}
+
+func TestGetAssetsGroupsRoomsByLocation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "ResolveNames") {
+			fmt.Fprint(w, getRoomsWithMalformedRoom)
+			return
+		}
+		email, office := "room2@example.com", ""
+		if strings.Contains(string(body), "room1@example.com") {
+			email, office = "room1@example.com", "Building A, Floor 2"
+		}
+		fmt.Fprint(w, soapResponse(`<m:ResolveNamesResponse><m:ResponseMessages>
+      <m:ResolveNamesResponseMessage ResponseClass="Success"><m:ResolutionSet TotalItemsInView="1" IncludesLastItemInRange="true">
+        <t:Resolution>
+          <t:Mailbox><t:EmailAddress>`+email+`</t:EmailAddress></t:Mailbox>
+          <t:Contact><t:OfficeLocation>`+office+`</t:OfficeLocation></t:Contact>
+        </t:Resolution>
+      </m:ResolutionSet></m:ResolveNamesResponseMessage>
+    </m:ResponseMessages></m:ResolveNamesResponse>`))
+	}))
+	defer server.Close()
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "service@example.com"}
+	root, err := h.GetAssets(apiserver.Configuration{
+		RoomListUPN:         common.Ptr("rooms@example.com"),
+		RoomLocationPattern: common.Ptr(`^(?P<building>[^,]+), Floor (?P<floor>\d+)$`),
+	})
+	if err != nil {
+		t.Fatalf("getting assets: %v", err)
+	}
+	children := root.GetLocationalChildren()
+	if len(children) != 2 || children[0].GetAssetType() != "ews_building" || children[1].GetGAI() != "ews_room_room2@example.com" {
+		t.Fatalf("expected building A and room 2 under the root, got %+v", children)
+	}
+	floors := children[0].GetLocationalChildren()
+	if len(floors) != 1 || floors[0].GetGAI() != "ews_floor_Building A_2" {
+		t.Fatalf("expected floor 2 in building A, got %+v", floors)
+	}
+	if rooms := floors[0].GetLocationalChildren(); len(rooms) != 1 || rooms[0].GetGAI() != "ews_room_room1@example.com" {
+		t.Errorf("expected room 1 on floor 2, got %+v", rooms)
+	}
+	if functional := root.GetFunctionalChildren(); len(functional) != 1 {
+		t.Errorf("expected only room 2 to be created as functional child, got %+v", functional)
+	}
+}
+
+func TestResolveContact(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, soapResponse(`<m:ResolveNamesResponse><m:ResponseMessages>
+      <m:ResolveNamesResponseMessage ResponseClass="Success"><m:ResolutionSet TotalItemsInView="1" IncludesLastItemInRange="true">
+        <t:Resolution>
+          <t:Mailbox><t:EmailAddress>room1@example.com</t:EmailAddress></t:Mailbox>
+          <t:Contact>
+            <t:Department>Facilities</t:Department>
+            <t:OfficeLocation>Building A, Floor 2</t:OfficeLocation>
+            <t:PhoneNumbers>
+              <t:Entry Key="MobilePhone">+41 79 000 00 01</t:Entry>
+              <t:Entry Key="BusinessPhone">+41 44 000 00 01</t:Entry>
+            </t:PhoneNumbers>
+          </t:Contact>
+        </t:Resolution>
+      </m:ResolutionSet></m:ResolveNamesResponseMessage>
+    </m:ResponseMessages></m:ResolveNamesResponse>`))
+	}))
+	defer server.Close()
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "service@example.com", contactCache: newContactCache()}
+	contact, err := h.ResolveContact("Room1@example.com")
+	if err != nil {
+		t.Fatalf("resolving contact: %v", err)
+	}
+	expected := Contact{PhoneNumber: "+41 44 000 00 01", Office: "Building A, Floor 2", Department: "Facilities"}
+	if contact != expected {
+		t.Errorf("expected %+v, got %+v", expected, contact)
+	}
+	if cached, err := h.ResolveContact("room1@example.com"); err != nil || cached != expected || requests != 1 {
+		t.Errorf("expected contact resolved once and then cached, got %+v (%v) after %d requests", cached, err, requests)
+	}
+	if _, err := h.ResolveContact("room2@example.com"); !errors.Is(err, ErrNonExistentMailbox) {
+		t.Errorf("expected unknown room to be reported as non-existent, got %v", err)
+	}
+	if _, err := h.ResolveContact("room2@example.com"); !errors.Is(err, ErrNonExistentMailbox) || requests != 2 {
+		t.Errorf("expected unknown room to be cached as non-existent, got %v after %d requests", err, requests)
+	}
+}
+
+const internalServerErrorFault = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <s:Fault>
+      <faultcode xmlns:a="http://schemas.microsoft.com/exchange/services/2006/types">a:ErrorInternalServerError</faultcode>
+      <faultstring xml:lang="en-US">An internal server error occurred. The operation failed.</faultstring>
+    </s:Fault>
+  </s:Body>
+</s:Envelope>`
+
+func TestResolveContactFailure(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, internalServerErrorFault)
+	}))
+	defer server.Close()
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "service@example.com", contactCache: newContactCache()}
+	for i := 0; i < 2; i++ {
+		_, err := h.ResolveContact("room1@example.com")
+		if err == nil || errors.Is(err, ErrNonExistentMailbox) || !strings.Contains(err.Error(), "ErrorInternalServerError") {
+			t.Errorf("expected the fault to be reported, got %v", err)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("expected failed lookups not to be cached, got %d requests", requests)
+	}
+}
+
+func TestGetAssetsDefersRoomsWithUnknownLocation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "ResolveNames") {
+			fmt.Fprint(w, getRoomsWithMalformedRoom)
+			return
+		}
+		if strings.Contains(string(body), "room1@example.com") {
+			fmt.Fprint(w, internalServerErrorFault)
+			return
+		}
+		fmt.Fprint(w, soapResponse(`<m:ResolveNamesResponse><m:ResponseMessages>
+      <m:ResolveNamesResponseMessage ResponseClass="Error">
+        <m:MessageText>No results were found.</m:MessageText>
+        <m:ResponseCode>ErrorNameResolutionNoResults</m:ResponseCode>
+      </m:ResolveNamesResponseMessage>
+    </m:ResponseMessages></m:ResolveNamesResponse>`))
+	}))
+	defer server.Close()
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "service@example.com"}
+	root, err := h.GetAssets(apiserver.Configuration{
+		RoomListUPN:         common.Ptr("rooms@example.com"),
+		RoomLocationPattern: common.Ptr(`^(?P<building>[^,]+), Floor (?P<floor>\d+)$`),
+	})
+	if err != nil {
+		t.Fatalf("getting assets: %v", err)
+	}
+	if len(root.Rooms) != 2 || !root.Rooms[0].ContactUnknown || root.Rooms[1].ContactUnknown {
+		t.Fatalf("expected only the contact of room 1 to be unknown, got %+v", root.Rooms)
+	}
+	// Room 1 would end up under the root, where it would stay.
+	children := root.GetLocationalChildren()
+	if len(children) != 1 || children[0].GetGAI() != "ews_room_room2@example.com" {
+		t.Errorf("expected only room 2 without directory entry under the root, got %+v", children)
+	}
+	if functional := root.GetFunctionalChildren(); len(functional) != 1 {
+		t.Errorf("expected room 1 not to be created yet, got %+v", functional)
+	}
+}
+
+func TestContactCacheExpires(t *testing.T) {
+	cache := newContactCache()
+	resolved := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	cache.put("room1@example.com", Contact{Office: "Building A"}, resolved)
+	if _, found := cache.get("ROOM1@example.com", resolved.Add(contactTTL-time.Minute)); !found {
+		t.Errorf("expected contact cached regardless of case")
+	}
+	if _, found := cache.get("room1@example.com", resolved.Add(contactTTL)); found {
+		t.Errorf("expected contact to expire after %v", contactTTL)
+	}
+}
+
+func TestGetAssetsIncludesEquipment(t *testing.T) {
+	h := newTestHelper(t, getRoomsWithMalformedRoom)
+	root, err := h.GetAssets(apiserver.Configuration{
+		RoomListUPN:     common.Ptr("rooms@example.com"),
+		EquipmentEmails: &[]string{"projector@example.com", "Room1@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("getting assets: %v", err)
+	}
+	if len(root.Rooms) != 3 {
+		t.Fatalf("expected 2 rooms and the projector, got %+v", root.Rooms)
+	}
+	projector := root.Rooms[2]
+	if projector.GetAssetType() != "ews_equipment" || projector.GetGAI() != "ews_equipment_projector@example.com" {
+		t.Errorf("expected projector as equipment, got %q (%s)", projector.GetGAI(), projector.GetAssetType())
+	}
+	if root.Rooms[0].Equipment {
+		t.Error("expected room 1 to stay a room")
+	}
+}
+
+func TestGetAssetsFromStaticRoomList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only the rooms' contact data is looked up.
+		if body, _ := io.ReadAll(r.Body); !strings.Contains(string(body), "ResolveNames") {
+			t.Errorf("expected static discovery not to look up rooms, got %s", body)
+		}
+	}))
+	defer server.Close()
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "service@example.com"}
+	root, err := h.GetAssets(apiserver.Configuration{
+		RoomDiscovery: common.Ptr(string(syncmodel.RoomDiscoveryStatic)),
+		RoomEmails:    &[]string{"room1@example.com", " ", "room2@example.com", "Room1@example.com"},
+		RoomNames:     map[string]string{"room2@example.com": "Boardroom"},
+	})
+	if err != nil {
+		t.Fatalf("getting assets: %v", err)
+	}
+	if len(root.Rooms) != 2 {
+		t.Fatalf("expected 2 rooms without blanks and duplicates, got %d", len(root.Rooms))
+	}
+	if root.Rooms[0].Email != "room1@example.com" || root.Rooms[0].GetName() != "room1@example.com" || root.Rooms[0].GetGAI() != "ews_room_room1@example.com" {
+		t.Errorf("expected room 1 named by its email, got %+v", root.Rooms[0])
+	}
+	if got := root.Rooms[1].GetName(); got != "Boardroom" {
+		t.Errorf("expected room 2 to be named explicitly, got %q", got)
+	}
+}
+
+const findPeopleResponse = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <FindPeopleResponse ResponseClass="Success" xmlns="http://schemas.microsoft.com/exchange/services/2006/messages" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
+      <ResponseCode>NoError</ResponseCode>
+      <People>
+        <t:Persona>
+          <t:DisplayName>Meeting room 1</t:DisplayName>
+          <t:EmailAddress>
+            <t:EmailAddress>room1@example.com</t:EmailAddress>
+          </t:EmailAddress>
+        </t:Persona>
+      </People>
+      <TotalNumberOfPeopleInView>1</TotalNumberOfPeopleInView>
+    </FindPeopleResponse>
+  </s:Body>
+</s:Envelope>`
+
+func TestGetAssetsFromAddressList(t *testing.T) {
+	h := newTestHelper(t, findPeopleResponse)
+	root, err := h.GetAssets(apiserver.Configuration{
+		RoomDiscovery:     common.Ptr(string(syncmodel.RoomDiscoveryAddressList)),
+		RoomAddressListID: common.Ptr("list-id"),
+	})
+	if err != nil {
+		t.Fatalf("getting assets: %v", err)
+	}
+	if len(root.Rooms) != 1 || root.Rooms[0].Email != "room1@example.com" || root.Rooms[0].Name != "Meeting room 1" {
+		t.Errorf("expected meeting room 1, got %+v", root.Rooms)
+	}
+}
+
+func roomListsResponse(addresses string) string {
+	return soapResponse(`<m:GetRoomListsResponse ResponseClass="Success">
+      <m:ResponseCode>NoError</m:ResponseCode>
+      <m:RoomLists>` + addresses + `</m:RoomLists>
+    </m:GetRoomListsResponse>`)
+}
+
+func TestGetRoomLists(t *testing.T) {
+	h := newTestHelper(t, roomListsResponse(`
+        <t:Address>
+          <t:Name>Building A</t:Name>
+          <t:EmailAddress>building-a@example.com</t:EmailAddress>
+          <t:RoutingType>SMTP</t:RoutingType>
+          <t:MailboxType>PublicDL</t:MailboxType>
+        </t:Address>`))
+	roomLists, err := h.GetRoomLists()
+	if err != nil {
+		t.Fatalf("getting room lists: %v", err)
+	}
+	if len(roomLists) != 1 || roomLists[0] != (RoomList{Name: "Building A", Email: "building-a@example.com"}) {
+		t.Errorf("expected room list of building A, got %+v", roomLists)
+	}
+
+	h = newTestHelper(t, roomListsResponse(""))
+	roomLists, err = h.GetRoomLists()
+	if err != nil || roomLists == nil || len(roomLists) != 0 {
+		t.Errorf("expected no room lists, got %+v, %v", roomLists, err)
+	}
+}
+
+type countingLimiter struct {
+	waits int
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits++
+	return nil
+}
+
+func TestSendRequestWaitsForRateLimiter(t *testing.T) {
+	h := newTestHelper(t, getRoomsWithMalformedRoom)
+	limiter := &countingLimiter{}
+	h.SetRateLimiter(limiter)
+	roomList := "rooms@example.com"
+	if _, err := h.GetAssets(apiserver.Configuration{RoomListUPN: &roomList}); err != nil {
+		t.Fatalf("getting assets: %v", err)
+	}
+	// One for the room list and one for each room's contact data.
+	if limiter.waits != 3 {
+		t.Errorf("expected 3 waits for the limiter, got %d", limiter.waits)
+	}
+}
+
+func TestLimiterIsSharedPerConfig(t *testing.T) {
+	id := int64(4711)
+	requestsPerMinute := int32(60)
+	config := apiserver.Configuration{Id: &id, RequestsPerMinute: &requestsPerMinute}
+	if limiterFor(config) != limiterFor(config) {
+		t.Error("expected helpers of the same config to share the limiter")
+	}
+	otherID := int64(4712)
+	if limiterFor(config) == limiterFor(apiserver.Configuration{Id: &otherID}) {
+		t.Error("expected helpers of different configs to have own limiters")
+	}
+}
+
+const findItemBackToBack = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <m:FindItemResponse xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
+      <m:ResponseMessages>
+        <m:FindItemResponseMessage ResponseClass="Success">
+          <m:ResponseCode>NoError</m:ResponseCode>
+          <m:RootFolder TotalItemsInView="3" IncludesLastItemInRange="true">
+            <t:Items>
+              <t:CalendarItem>
+                <t:ItemId Id="before" ChangeKey="a"/>
+                <t:Start>2024-05-06T09:00:00Z</t:Start>
+                <t:End>2024-05-06T10:00:00Z</t:End>
+                <t:IsCancelled>false</t:IsCancelled>
+              </t:CalendarItem>
+              <t:CalendarItem>
+                <t:ItemId Id="after" ChangeKey="b"/>
+                <t:Start>2024-05-06T11:00:00Z</t:Start>
+                <t:End>2024-05-06T12:00:00Z</t:End>
+                <t:IsCancelled>false</t:IsCancelled>
+              </t:CalendarItem>
+              <t:CalendarItem>
+                <t:ItemId Id="cancelled" ChangeKey="c"/>
+                <t:Start>2024-05-06T10:00:00Z</t:Start>
+                <t:End>2024-05-06T11:00:00Z</t:End>
+                <t:IsCancelled>true</t:IsCancelled>
+              </t:CalendarItem>
+            </t:Items>
+          </m:RootFolder>
+        </m:FindItemResponseMessage>
+      </m:ResponseMessages>
+    </m:FindItemResponse>
+  </s:Body>
+</s:Envelope>`
+
+func TestFindConflictingEventsAtBoundaries(t *testing.T) {
+	h := newTestHelper(t, findItemBackToBack)
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 5, 6, 11, 0, 0, 0, time.UTC)
+
+	conflicting, err := h.FindConflictingEvents("room1@example.com", start, end, syncmodel.OverlapExclusive)
+	if err != nil {
+		t.Fatalf("finding conflicts: %v", err)
+	}
+	if len(conflicting) != 0 {
+		t.Errorf("expected back-to-back events not to conflict, got %v", conflicting)
+	}
+
+	conflicting, err = h.FindConflictingEvents("room1@example.com", start, end, syncmodel.OverlapInclusive)
+	if err != nil {
+		t.Fatalf("finding conflicts: %v", err)
+	}
+	if len(conflicting) != 2 || conflicting[0] != "before" || conflicting[1] != "after" {
+		t.Errorf("expected back-to-back events to conflict, got %v", conflicting)
+	}
+}
+
+const findItemTagged = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <m:FindItemResponse xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
+      <m:ResponseMessages>
+        <m:FindItemResponseMessage ResponseClass="Success">
+          <m:ResponseCode>NoError</m:ResponseCode>
+          <m:RootFolder TotalItemsInView="1" IncludesLastItemInRange="true">
+            <t:Items>
+              <t:CalendarItem>
+                <t:ItemId Id="tagged" ChangeKey="a"/>
+                <t:UID>040000008200E00074C5B7101A82E008</t:UID>
+                <t:Start>2024-05-06T09:00:00Z</t:Start>
+                <t:End>2024-05-06T10:00:00Z</t:End>
+                <t:Organizer>
+                  <t:Mailbox>
+                    <t:Name>John Doe</t:Name>
+                    <t:EmailAddress>john.doe@example.com</t:EmailAddress>
+                  </t:Mailbox>
+                </t:Organizer>
+                <t:ExtendedProperty>
+                  <t:ExtendedFieldURI PropertySetId="8c5d3b2e-4f61-4a3c-9e27-5b1f0d6a7c94" PropertyName="Eliona-id" PropertyType="Integer"/>
+                  <t:Value>4711</t:Value>
+                </t:ExtendedProperty>
+              </t:CalendarItem>
+            </t:Items>
+          </m:RootFolder>
+        </m:FindItemResponseMessage>
+      </m:ResponseMessages>
+    </m:FindItemResponse>
+  </s:Body>
+</s:Envelope>`
+
+func TestFindTaggedEvents(t *testing.T) {
+	h := newTestHelper(t, findItemTagged)
+	events, err := h.FindTaggedEvents("room1@example.com")
+	if err != nil {
+		t.Fatalf("finding tagged events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	event := events[0]
+	if event.ElionaID != 4711 {
+		t.Errorf("expected Eliona ID 4711, got %d", event.ElionaID)
+	}
+	if event.OrganizerEmail != "john.doe@example.com" {
+		t.Errorf("unexpected organizer %q", event.OrganizerEmail)
+	}
+	if event.ExchangeUID != "040000008200E00074C5B7101A82E008" {
+		t.Errorf("unexpected UID %q", event.ExchangeUID)
+	}
+}
+
+const syncFolderItemsTagged = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <m:SyncFolderItemsResponse xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
+      <m:ResponseMessages>
+        <m:SyncFolderItemsResponseMessage ResponseClass="Success">
+          <m:ResponseCode>NoError</m:ResponseCode>
+          <m:SyncState>state</m:SyncState>
+          <m:IncludesLastItemInRange>true</m:IncludesLastItemInRange>
+          <m:Changes>
+            <t:Create>
+              <t:CalendarItem>
+                <t:ItemId Id="tagged" ChangeKey="a"/>
+                <t:UID>created-by-app</t:UID>
+                <t:Start>2024-05-06T09:00:00Z</t:Start>
+                <t:End>2024-05-06T10:00:00Z</t:End>
+                <t:Organizer>
+                  <t:Mailbox>
+                    <t:EmailAddress>john.doe@example.com</t:EmailAddress>
+                  </t:Mailbox>
+                </t:Organizer>
+                <t:CalendarItemType>Single</t:CalendarItemType>
+                <t:ExtendedProperty>
+                  <t:ExtendedFieldURI PropertySetId="8c5d3b2e-4f61-4a3c-9e27-5b1f0d6a7c94" PropertyName="Eliona-id" PropertyType="Integer"/>
+                  <t:Value>4711</t:Value>
+                </t:ExtendedProperty>
+              </t:CalendarItem>
+            </t:Create>
+            <t:Create>
+              <t:CalendarItem>
+                <t:ItemId Id="external" ChangeKey="b"/>
+                <t:UID>created-in-outlook</t:UID>
+                <t:Start>2024-05-06T11:00:00Z</t:Start>
+                <t:End>2024-05-06T12:00:00Z</t:End>
+                <t:Organizer>
+                  <t:Mailbox>
+                    <t:EmailAddress>jane.doe@example.com</t:EmailAddress>
+                  </t:Mailbox>
+                </t:Organizer>
+                <t:CalendarItemType>Single</t:CalendarItemType>
+              </t:CalendarItem>
+            </t:Create>
+          </m:Changes>
+        </m:SyncFolderItemsResponseMessage>
+      </m:ResponseMessages>
+    </m:SyncFolderItemsResponse>
+  </s:Body>
+</s:Envelope>`
+
+func TestGetRoomAppointmentsRecognizesEventsCreatedByApp(t *testing.T) {
+	h := newTestHelper(t, syncFolderItemsTagged)
+	new, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	if len(new) != 2 {
+		t.Fatalf("expected 2 new events, got %d", len(new))
+	}
+	if !new[0].CreatedByApp {
+		t.Error("expected tagged event to be recognized as created by the app")
+	}
+	if new[1].CreatedByApp {
+		t.Error("expected event created in Outlook not to be recognized as created by the app")
+	}
+}
+
+// syncFolderItemsAcrossDST has a booking from 01:30 CET to 03:30 CEST in
+// Europe/Berlin, spanning the switch to summer time, once with offsets and once
+// floating in the requested UTC time zone context.
+const syncFolderItemsAcrossDST = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <m:SyncFolderItemsResponse xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
+      <m:ResponseMessages>
+        <m:SyncFolderItemsResponseMessage ResponseClass="Success">
+          <m:ResponseCode>NoError</m:ResponseCode>
+          <m:SyncState>state</m:SyncState>
+          <m:IncludesLastItemInRange>true</m:IncludesLastItemInRange>
+          <m:Changes>
+            <t:Create>
+              <t:CalendarItem>
+                <t:ItemId Id="with-offsets" ChangeKey="a"/>
+                <t:UID>uid1</t:UID>
+                <t:Start>2024-03-31T01:30:00+01:00</t:Start>
+                <t:End>2024-03-31T03:30:00+02:00</t:End>
+                <t:StartTimeZone Id="W. Europe Standard Time" Name="(UTC+01:00) Amsterdam, Berlin, Bern, Rome, Stockholm, Vienna"/>
+                <t:Organizer><t:Mailbox><t:EmailAddress>john.doe@example.com</t:EmailAddress></t:Mailbox></t:Organizer>
+                <t:CalendarItemType>Single</t:CalendarItemType>
+              </t:CalendarItem>
+            </t:Create>
+            <t:Create>
+              <t:CalendarItem>
+                <t:ItemId Id="floating" ChangeKey="b"/>
+                <t:UID>uid2</t:UID>
+                <t:Start>2024-03-31T00:30:00</t:Start>
+                <t:End>2024-03-31T01:30:00</t:End>
+                <t:Organizer><t:Mailbox><t:EmailAddress>john.doe@example.com</t:EmailAddress></t:Mailbox></t:Organizer>
+                <t:CalendarItemType>Single</t:CalendarItemType>
+              </t:CalendarItem>
+            </t:Create>
+          </m:Changes>
+        </m:SyncFolderItemsResponseMessage>
+      </m:ResponseMessages>
+    </m:SyncFolderItemsResponse>
+  </s:Body>
+</s:Envelope>`
+
+func TestGetRoomAppointmentsAcrossDSTTransition(t *testing.T) {
+	h := newTestHelper(t, syncFolderItemsAcrossDST)
+	new, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	if len(new) != 2 {
+		t.Fatalf("expected 2 new events, got %d", len(new))
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 3, 31, 1, 30, 0, 0, berlin)
+	end := time.Date(2024, 3, 31, 3, 30, 0, 0, berlin)
+	for _, group := range new {
+		occurrence := group.Occurrences[0]
+		if !occurrence.Start.Equal(start) || !occurrence.End.Equal(end) {
+			t.Errorf("%s: expected %v - %v, got %v - %v", group.ExchangeUID, start, end, occurrence.Start, occurrence.End)
+		}
+		if occurrence.Start.Location() != time.UTC || occurrence.End.Location() != time.UTC {
+			t.Errorf("%s: expected times in UTC, got %v - %v", group.ExchangeUID, occurrence.Start, occurrence.End)
+		}
+		// The wall clock advances by two hours, the booking lasts one.
+		if d := occurrence.End.Sub(occurrence.Start); d != time.Hour {
+			t.Errorf("%s: expected the booking to last an hour, got %v", group.ExchangeUID, d)
+		}
+	}
+	if tz := new[0].Occurrences[0].TimeZone; tz != "W. Europe Standard Time" {
+		t.Errorf("expected the Exchange time zone to be preserved, got %q", tz)
+	}
+}
+
+func TestAllDayEventsRoundTrip(t *testing.T) {
+	h := newTestHelper(t, strings.Replace(syncFolderItemsTagged,
+		"<t:CalendarItemType>Single</t:CalendarItemType>",
+		"<t:CalendarItemType>Single</t:CalendarItemType>\n                <t:IsAllDayEvent>true</t:IsAllDayEvent>", 1))
+	new, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	if !new[0].Occurrences[0].AllDay || new[1].Occurrences[0].AllDay {
+		t.Errorf("expected only the first event to be all-day, got %+v and %+v", new[0].Occurrences[0], new[1].Occurrences[0])
+	}
+
+	request, err := createAppointmentRequest(Appointment{
+		Organizer: "john.doe@example.com",
+		Start:     time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC),
+		End:       time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC),
+		AllDay:    true,
+		Resources: []string{"room1@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(request, "<t:IsAllDayEvent>true</t:IsAllDayEvent>") {
+		t.Errorf("expected all-day appointment to be created as such, got %s", request)
+	}
+}
+
+func TestGetRoomAppointmentsCapturesAttendeesAndLocation(t *testing.T) {
+	h := newTestHelper(t, strings.Replace(syncFolderItemsTagged,
+		"<t:CalendarItemType>Single</t:CalendarItemType>",
+		`<t:CalendarItemType>Single</t:CalendarItemType>
+                <t:RequiredAttendees>
+                  <t:Attendee><t:Mailbox><t:EmailAddress>jane.doe@example.com</t:EmailAddress></t:Mailbox></t:Attendee>
+                  <t:Attendee><t:Mailbox><t:EmailAddress>Room1@example.com</t:EmailAddress></t:Mailbox></t:Attendee>
+                </t:RequiredAttendees>
+                <t:OptionalAttendees>
+                  <t:Attendee><t:Mailbox><t:EmailAddress>bob@example.com</t:EmailAddress></t:Mailbox></t:Attendee>
+                </t:OptionalAttendees>
+                <t:Location>Board room, 3rd floor</t:Location>`, 1))
+	new, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	occurrence := new[0].Occurrences[0]
+	if fmt.Sprint(occurrence.Attendees) != "[jane.doe@example.com bob@example.com]" || occurrence.AttendeesTruncated {
+		t.Errorf("expected the attendees besides the room, got %v (truncated %t)", occurrence.Attendees, occurrence.AttendeesTruncated)
+	}
+	if occurrence.Location != "Board room, 3rd floor" {
+		t.Errorf("expected the location typed by the organizer, got %q", occurrence.Location)
+	}
+
+	h.privacyMode = true
+	new, _, _, _, _, err = h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	if attendees := new[0].Occurrences[0].Attendees; len(attendees) != 0 {
+		t.Errorf("expected no attendees in privacy mode, got %v", attendees)
+	}
+	if location := new[0].Occurrences[0].Location; location != "" {
+		t.Errorf("expected no location in privacy mode, got %q", location)
+	}
+}
+
+func TestPrivateEventsAreBookedWithoutDetails(t *testing.T) {
+	response := strings.Replace(syncFolderItemsTagged,
+		"<t:CalendarItemType>Single</t:CalendarItemType>",
+		`<t:CalendarItemType>Single</t:CalendarItemType>
+                <t:Subject>Salary review</t:Subject>
+                <t:Sensitivity>Private</t:Sensitivity>
+                <t:Location>HR office</t:Location>
+                <t:RequiredAttendees>
+                  <t:Attendee><t:Mailbox><t:EmailAddress>jane.doe@example.com</t:EmailAddress></t:Mailbox></t:Attendee>
+                </t:RequiredAttendees>`, 1)
+	h := newTestHelper(t, response)
+	new, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	if len(new) != 2 {
+		t.Fatalf("expected the private event to be booked, got %d events", len(new))
+	}
+	private := new[0]
+	if private.Subject != "room1@example.com booked by john.doe@example.com" {
+		t.Errorf("expected the fallback subject, got %q", private.Subject)
+	}
+	if occurrence := private.Occurrences[0]; occurrence.Location != "" || len(occurrence.Attendees) != 0 {
+		t.Errorf("expected no details of the private event, got %+v", occurrence)
+	}
+
+	h.mirrorPrivateDetails = true
+	new, _, _, _, _, err = h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	if private := new[0]; private.Subject != "Salary review" || private.Occurrences[0].Location != "HR office" || len(private.Occurrences[0].Attendees) != 1 {
+		t.Errorf("expected the details to be mirrored, got %+v", private)
+	}
+}
+
+func TestAttendeeEmailsAreTruncated(t *testing.T) {
+	var item calendarItem
+	for i := 0; i <= maxSyncedAttendees; i++ {
+		item.RequiredAttendees.Attendee = append(item.RequiredAttendees.Attendee, eventAttendee{Mailbox: mailbox{EmailAddress: fmt.Sprintf("user%d@example.com", i)}})
+	}
+	h := &EWSHelper{addressCache: newAddressCache(DefaultAddressCacheSize)}
+	emails, truncated := h.attendeeEmails(item, "room1@example.com")
+	if len(emails) != maxSyncedAttendees || !truncated {
+		t.Errorf("expected %d attendees and the list marked truncated, got %d (truncated %t)", maxSyncedAttendees, len(emails), truncated)
+	}
+}
+
+func TestCreateAppointmentRequestFreeBusyStatus(t *testing.T) {
+	appointment := Appointment{
+		Organizer: "john.doe@example.com",
+		Subject:   "Eliona booking",
+		Start:     time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC),
+		End:       time.Date(2024, 5, 6, 11, 0, 0, 0, time.UTC),
+		Location:  "room1@example.com",
+		Resources: []string{"room1@example.com"},
+	}
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{"", "Busy"},
+		{syncmodel.FreeBusyStatusTentative, "Tentative"},
+		{syncmodel.FreeBusyStatusFree, "Free"},
+	}
+	for _, tt := range tests {
+		appointment.FreeBusyStatus = tt.status
+		request, err := createAppointmentRequest(appointment)
+		if err != nil {
+			t.Fatalf("rendering request for %q: %v", tt.status, err)
+		}
+		if want := "<t:LegacyFreeBusyStatus>" + tt.want + "</t:LegacyFreeBusyStatus>"; !strings.Contains(request, want) {
+			t.Errorf("status %q: expected request to contain %s", tt.status, want)
+		}
+	}
+
+	appointment.FreeBusyStatus = "Maybe"
+	if _, err := createAppointmentRequest(appointment); err == nil {
+		t.Error("expected invalid status to be rejected")
+	}
+}
+
+func TestCreateAppointmentRequestReminder(t *testing.T) {
+	fifteen, zero := 15, 0
+	tests := []struct {
+		minutes *int
+		want    string
+	}{
+		{nil, ""},
+		{&zero, "<t:ReminderIsSet>false</t:ReminderIsSet>"},
+		{&fifteen, "<t:ReminderIsSet>true</t:ReminderIsSet><t:ReminderMinutesBeforeStart>15</t:ReminderMinutesBeforeStart>"},
+	}
+	for _, tt := range tests {
+		request, err := createAppointmentRequest(Appointment{
+			Organizer:       "john.doe@example.com",
+			Subject:         "Meeting",
+			Resources:       []string{"room1@example.com"},
+			ReminderMinutes: tt.minutes,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.want == "" {
+			if strings.Contains(request, "Reminder") {
+				t.Errorf("expected no reminder elements, got %s", request)
+			}
+			continue
+		}
+		// The schema orders reminders after the subject, before extended properties.
+		if want := "<t:Subject>Meeting</t:Subject>" + tt.want + "<t:ExtendedProperty>"; !strings.Contains(request, want) {
+			t.Errorf("expected request to contain %s, got %s", want, request)
+		}
+		if err := xml.Unmarshal([]byte(request), new(struct{})); err != nil {
+			t.Errorf("rendered malformed XML: %v", err)
+		}
+	}
+}
+
+func TestCreateAppointmentRequestCategories(t *testing.T) {
+	request, err := createAppointmentRequest(Appointment{
+		Organizer:  "john.doe@example.com",
+		Subject:    "Meeting",
+		Resources:  []string{"room1@example.com"},
+		Categories: []string{"Eliona & Co"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The schema orders categories after the subject, before reminders.
+	if want := "<t:Subject>Meeting</t:Subject><t:Categories><t:String>Eliona &amp; Co</t:String></t:Categories><t:ExtendedProperty>"; !strings.Contains(request, want) {
+		t.Errorf("expected request to contain %s, got %s", want, request)
+	}
+
+	request, err = createAppointmentRequest(Appointment{Organizer: "john.doe@example.com", Resources: []string{"room1@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(request, "Categories") {
+		t.Errorf("expected no categories, got %s", request)
+	}
+}
+
+func TestCreateAppointmentRequestOnlineMeeting(t *testing.T) {
+	request, err := createAppointmentRequest(Appointment{
+		Organizer:       "john.doe@example.com",
+		Resources:       []string{"room1@example.com"},
+		IsOnlineMeeting: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "</t:Resources><t:IsOnlineMeeting>true</t:IsOnlineMeeting></t:CalendarItem>"; !strings.Contains(request, want) {
+		t.Errorf("expected request to contain %s, got %s", want, request)
+	}
+
+	request, err = createAppointmentRequest(Appointment{Organizer: "john.doe@example.com", Resources: []string{"room1@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(request, "IsOnlineMeeting") {
+		t.Errorf("expected no online meeting, got %s", request)
+	}
+}
+
+func TestCreateAppointmentRequestInTimeZone(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Still Sunday in UTC.
+	start := time.Date(2024, 5, 6, 0, 30, 0, 0, zurich)
+	request, err := createAppointmentRequest(Appointment{
+		Organizer:  "john.doe@example.com",
+		Start:      start,
+		End:        start.Add(time.Hour),
+		TimeZone:   zurich,
+		Resources:  []string{"room1@example.com"},
+		Recurrence: &Recurrence{Pattern: RecurWeekly, Interval: 1, Count: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<t:Start>2024-05-05T22:30:00Z</t:Start>",
+		"<t:DaysOfWeek>Monday</t:DaysOfWeek>",
+		"<t:StartDate>2024-05-06</t:StartDate>",
+		`</t:Recurrence><t:StartTimeZone Id="W. Europe Standard Time"></t:StartTimeZone><t:EndTimeZone Id="W. Europe Standard Time"></t:EndTimeZone></t:CalendarItem>`,
+	} {
+		if !strings.Contains(request, want) {
+			t.Errorf("expected request to contain %s, got %s", want, request)
+		}
+	}
+
+	// All-day events span the days in the time zone, not in UTC.
+	midnight := time.Date(2024, 5, 6, 0, 0, 0, 0, zurich)
+	request, err = createAppointmentRequest(Appointment{
+		Organizer: "john.doe@example.com",
+		Start:     midnight,
+		End:       midnight.AddDate(0, 0, 1),
+		AllDay:    true,
+		TimeZone:  zurich,
+		Resources: []string{"room1@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<t:StartTimeZone Id="W. Europe Standard Time">`; !strings.Contains(request, want) {
+		t.Errorf("expected request to contain %s, got %s", want, request)
+	}
+}
+
+func TestCreateAppointmentRequestEscapesValues(t *testing.T) {
+	request, err := createAppointmentRequest(Appointment{
+		Organizer: `o'brien@example.com`,
+		Subject:   "A & B <test>",
+		Location:  `Room "Alpha" & <Beta>`,
+		Attendees: []string{`o'brien@example.com`},
+		Resources: []string{"room1@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct {
+		Subject   string   `xml:"Body>CreateItem>Items>CalendarItem>Subject"`
+		Location  string   `xml:"Body>CreateItem>Items>CalendarItem>Location"`
+		Attendees []string `xml:"Body>CreateItem>Items>CalendarItem>RequiredAttendees>Attendee>Mailbox>EmailAddress"`
+		Resources []string `xml:"Body>CreateItem>Items>CalendarItem>Resources>Attendee>Mailbox>EmailAddress"`
+	}
+	if err := xml.Unmarshal([]byte(request), &parsed); err != nil {
+		t.Fatalf("rendered malformed XML: %v", err)
+	}
+	if parsed.Subject != "A & B <test>" {
+		t.Errorf("expected subject to round-trip, got %q", parsed.Subject)
+	}
+	if parsed.Location != `Room "Alpha" & <Beta>` {
+		t.Errorf("expected location to round-trip, got %q", parsed.Location)
+	}
+	if fmt.Sprint(parsed.Attendees) != `[o'brien@example.com]` || fmt.Sprint(parsed.Resources) != `[room1@example.com]` {
+		t.Errorf("expected people as required attendees and rooms as resources, got %v and %v", parsed.Attendees, parsed.Resources)
+	}
+}
+
+func TestMarshalRequest(t *testing.T) {
+	uidEqual := &isEqualTo{Field: extendedFieldURI{PropertySetID: "set", PropertyID: "3", PropertyType: "Binary"}}
+	uidEqual.Constant.Value = `a"b`
+	request, err := marshalRequest(impersonate(IdentitySmtpAddress, "S-1-5-21-1"), findItemRequest{
+		Traversal:      "Shallow",
+		ItemShape:      itemShape{BaseShape: "IdOnly", AdditionalProperties: &additionalProperties{FieldURIs: fieldURIs("calendar:UID")}},
+		Restriction:    &restriction{IsEqualTo: uidEqual},
+		ParentFolderID: calendarOf("room&1@example.com"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<soap:Header><t:RequestServerVersion Version="Exchange2013_SP1"></t:RequestServerVersion><t:ExchangeImpersonation><t:ConnectingSID><t:SID>S-1-5-21-1</t:SID></t:ConnectingSID></t:ExchangeImpersonation><t:TimeZoneContext><t:TimeZoneDefinition Id="UTC"></t:TimeZoneDefinition></t:TimeZoneContext></soap:Header>`,
+		`<soap:Body><m:FindItem Traversal="Shallow"><m:ItemShape><t:BaseShape>IdOnly</t:BaseShape><t:AdditionalProperties><t:FieldURI FieldURI="calendar:UID"></t:FieldURI></t:AdditionalProperties></m:ItemShape>`,
+		`<m:Restriction><t:IsEqualTo><t:ExtendedFieldURI PropertySetId="set" PropertyId="3" PropertyType="Binary"></t:ExtendedFieldURI><t:FieldURIOrConstant><t:Constant Value="a&#34;b"></t:Constant></t:FieldURIOrConstant></t:IsEqualTo></m:Restriction>`,
+		`<m:ParentFolderIds><t:DistinguishedFolderId Id="calendar"><t:Mailbox><t:EmailAddress>room&amp;1@example.com</t:EmailAddress></t:Mailbox></t:DistinguishedFolderId></m:ParentFolderIds></m:FindItem></soap:Body>`,
+	} {
+		if !strings.Contains(request, want) {
+			t.Errorf("expected request to contain %s, got %s", want, request)
+		}
+	}
+	if strings.Contains(request, "CalendarView") {
+		t.Errorf("expected no calendar view without one set, got %s", request)
+	}
+}
+
+func TestCreateAppointmentRequestInProjectTimeZone(t *testing.T) {
+	clock, err := syncmodel.ParseBookingClock("Europe/Zurich")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Booking app sends 10:00-11:00 Zurich time labelled as UTC.
+	appointment := Appointment{
+		Organizer: "john.doe@example.com",
+		Start:     clock.FromEliona(time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)),
+		End:       clock.FromEliona(time.Date(2024, 5, 6, 11, 0, 0, 0, time.UTC)),
+		Location:  "room1@example.com",
+		Resources: []string{"room1@example.com"},
+	}
+	request, err := createAppointmentRequest(appointment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<t:Start>2024-05-06T08:00:00Z</t:Start>", "<t:End>2024-05-06T09:00:00Z</t:End>"} {
+		if !strings.Contains(request, want) {
+			t.Errorf("expected request to contain %s", want)
+		}
+	}
+}
+
+func TestRoomResults(t *testing.T) {
+	results := []RoomResult{
+		{Room: "room1@example.com", ResourceEventID: "AAMk1"},
+		{Room: "room2@example.com", Declined: true},
+		{Room: "room3@example.com", PendingApproval: true},
+	}
+	accepted := AcceptedRooms(results)
+	if len(accepted) != 1 || accepted[0].ResourceEventID != "AAMk1" {
+		t.Errorf("expected only room1 to be accepted, got %+v", accepted)
+	}
+	declined := DeclinedRooms(results)
+	if len(declined) != 1 || declined[0] != "room2@example.com" {
+		t.Errorf("expected only room2 to be declined, got %v", declined)
+	}
+}
+
+func TestAppointmentFieldLengthLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		length    int
+		truncated bool
+	}{
+		{"below limit", maxSubjectLength - 1, false},
+		{"at limit", maxSubjectLength, false},
+		{"over limit", maxSubjectLength + 1, true},
+		{"far over limit", 10 * maxSubjectLength, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Multi-byte characters must be counted as single characters.
+			value := strings.Repeat("ä", tt.length)
+			a := Appointment{Subject: value, Location: value}.withLimitedFieldLengths()
+			for field, got := range map[string]string{"subject": a.Subject, "location": a.Location} {
+				n := len([]rune(got))
+				if tt.truncated {
+					if n != maxSubjectLength || !strings.HasSuffix(got, "…") {
+						t.Errorf("%s: expected truncation to %d characters with ellipsis, got %d", field, maxSubjectLength, n)
+					}
+				} else if got != value {
+					t.Errorf("%s: expected value to be kept, got %d characters", field, n)
+				}
+			}
+		})
+	}
+}
+
+func TestReadOnlyHelperRefusesWrites(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+		readOnly:     true,
+	}
+	group := syncmodel.BookingGroup{ExchangeUID: "uid", OrganizerEmail: "organizer@example.com"}
+
+	if _, _, err := h.CreateAppointment(Appointment{Organizer: "organizer@example.com", Resources: []string{"room1@example.com"}}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("create: expected ErrReadOnly, got %v", err)
+	}
+	if err := h.CancelEvent(group); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("cancel: expected ErrReadOnly, got %v", err)
+	}
+	if err := h.CancelOccurrence(group, syncmodel.BookingOccurrence{InstanceIndex: 1}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("cancel occurrence: expected ErrReadOnly, got %v", err)
+	}
+	if err := h.UpdateAppointmentAttendees("uid", "organizer@example.com", []string{"room2@example.com"}, nil); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("update attendees: expected ErrReadOnly, got %v", err)
+	}
+	if err := h.DeclineAsResource("room1@example.com", "uid"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("decline as resource: expected ErrReadOnly, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests to be sent to Exchange, got %d", requests)
+	}
+}
+
+func TestDeclineItemRequestIsNotSent(t *testing.T) {
+	request, err := declineItemRequest("room1@example.com", "item", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<t:SmtpAddress>room1@example.com</t:SmtpAddress>`,
+		`<m:CreateItem MessageDisposition="SaveOnly">`,
+		`<t:DeclineItem>`,
+		`<t:ReferenceItemId Id="item" ChangeKey="key"></t:ReferenceItemId>`,
+	} {
+		if !strings.Contains(request, want) {
+			t.Errorf("expected request to contain %s", want)
+		}
+	}
+}
+
+func TestAttendeesUpdate(t *testing.T) {
+	current := attendees{Attendee: []eventAttendee{
+		{Mailbox: mailbox{EmailAddress: "room1@example.com"}},
+		{Mailbox: mailbox{EmailAddress: "room3@example.com"}},
+	}}
+	for _, tc := range []struct {
+		name        string
+		add, remove []string
+		want        string
+	}{
+		{"append", []string{"room&2@example.com"}, nil, `<t:AppendToItemField><t:FieldURI FieldURI="calendar:Resources"></t:FieldURI><t:CalendarItem><t:Resources><t:Attendee><t:Mailbox><t:EmailAddress>room&amp;2@example.com</t:EmailAddress></t:Mailbox></t:Attendee></t:Resources></t:CalendarItem></t:AppendToItemField>`},
+		{"replace", []string{"room2@example.com"}, []string{"ROOM1@example.com"}, `<t:SetItemField><t:FieldURI FieldURI="calendar:Resources"></t:FieldURI><t:CalendarItem><t:Resources><t:Attendee><t:Mailbox><t:EmailAddress>room3@example.com</t:EmailAddress></t:Mailbox></t:Attendee><t:Attendee><t:Mailbox><t:EmailAddress>room2@example.com</t:EmailAddress></t:Mailbox></t:Attendee></t:Resources></t:CalendarItem></t:SetItemField>`},
+		{"delete", nil, []string{"room1@example.com", "room3@example.com"}, `<t:DeleteItemField><t:FieldURI FieldURI="calendar:Resources"></t:FieldURI></t:DeleteItemField>`},
+	} {
+		update, ok := attendeesUpdate("Resources", current, tc.add, tc.remove)
+		if !ok {
+			t.Fatalf("%s: expected an update", tc.name)
+		}
+		request, err := marshalRequest(impersonate(IdentitySmtpAddress, "organizer@example.com"), updateItemRequest{
+			ItemChange: itemChange{ItemID: &requestItemID{ID: "item"}, Updates: []fieldUpdate{update}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(request, `<t:Updates>`+tc.want+`</t:Updates>`) {
+			t.Errorf("%s: expected update %s, got %s", tc.name, tc.want, request)
+		}
+	}
+	if _, ok := attendeesUpdate("Resources", current, nil, []string{"room2@example.com"}); ok {
+		t.Error("expected no update removing an absent room")
+	}
+}
+
+func TestImpersonationHeader(t *testing.T) {
+	for _, tc := range []struct {
+		preferred IdentityType
+		user      string
+		want      string
+	}{
+		{IdentityPrincipalName, "service@example.com", `<t:PrincipalName>service@example.com</t:PrincipalName>`},
+		{IdentitySmtpAddress, "room1@example.com", `<t:SmtpAddress>room1@example.com</t:SmtpAddress>`},
+		{IdentitySmtpAddress, "S-1-5-21-1004336348-1177238915-682003330-512", `<t:SID>S-1-5-21-1004336348-1177238915-682003330-512</t:SID>`},
+		{IdentityPrincipalName, "S-1-5-21-1004", `<t:SID>S-1-5-21-1004</t:SID>`},
+		{IdentitySmtpAddress, "s-1@example.com", `<t:SmtpAddress>s-1@example.com</t:SmtpAddress>`},
+		{IdentitySmtpAddress, "o'brien&co@example.com", `<t:SmtpAddress>o&#39;brien&amp;co@example.com</t:SmtpAddress>`},
+	} {
+		request, err := marshalRequest(impersonate(tc.preferred, tc.user), getRoomListsRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `<t:ConnectingSID>` + tc.want + `</t:ConnectingSID>`; !strings.Contains(request, want) {
+			t.Errorf("%s: expected %s, got %s", tc.user, want, request)
+		}
+	}
+}
+
+func TestImpersonationBySIDIsSent(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		_, _ = w.Write([]byte(getFolderResponse("Success", "NoError")))
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL}
+	if _, err := h.checkImpersonation("S-1-5-21-1004"); err != nil {
+		t.Fatalf("checking impersonation: %v", err)
+	}
+	if !strings.Contains(body, `<t:SID>S-1-5-21-1004</t:SID>`) || strings.Contains(body, "PrincipalName") {
+		t.Errorf("expected impersonation by SID, got %s", body)
+	}
+}
+
+func TestMutationsAreAudited(t *testing.T) {
+	var requestIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestIDs = append(requestIDs, r.Header.Get("client-request-id"))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+	var entries []appdb.AuditLog
+	h := &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+		configID:     7,
+	}
+	h.SetCorrelationID("b3f7c2a4-8a51-4c37-9d1e-2f6e5a4b3c21")
+	h.SetAuditor(func(entry appdb.AuditLog) error {
+		entries = append(entries, entry)
+		return errors.New("database is down")
+	})
+
+	err := h.UpdateAppointmentAttendees("040000008200E00074C5B7101A82E008", "organizer@example.com", []string{"Room2@example.com"}, nil)
+	if err == nil {
+		t.Fatal("expected update to fail on an empty response")
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected the mutation to be audited despite failing to record it, got %d entries", len(entries))
+	}
+	entry := entries[0]
+	if entry.Action != AuditUpdateAttendees || entry.ConfigurationID != 7 || entry.Organizer != "organizer@example.com" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if len(entry.Rooms) != 1 || entry.Rooms[0] != "room2@example.com" {
+		t.Errorf("expected lower-cased room, got %v", entry.Rooms)
+	}
+	if entry.CorrelationID != "b3f7c2a4-8a51-4c37-9d1e-2f6e5a4b3c21" || !entry.Error.Valid {
+		t.Errorf("expected correlated failure, got %+v", entry)
+	}
+	if len(requestIDs) == 0 || requestIDs[0] != "b3f7c2a4-8a51-4c37-9d1e-2f6e5a4b3c21" {
+		t.Errorf("expected correlation ID to be sent as client-request-id, got %v", requestIDs)
+	}
+}
+
+func TestUpdateAppointmentAttendeesMovesRoomsToResources(t *testing.T) {
+	var update string
+	var recreated bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		if strings.Contains(request, "<m:CreateItem") || strings.Contains(request, "CancelCalendarItem") {
+			recreated = true
+		}
+		switch {
+		case strings.Contains(request, "<m:UpdateItem"):
+			update = request
+			_, _ = w.Write([]byte(soapResponse(`<m:UpdateItemResponse><m:ResponseMessages><m:UpdateItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode></m:UpdateItemResponseMessage></m:ResponseMessages></m:UpdateItemResponse>`)))
+		case strings.Contains(request, "<m:FindItem"):
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items><t:CalendarItem><t:ItemId Id="item1" ChangeKey="ck"/></t:CalendarItem></t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		default:
+			// An event created before rooms were invited as resources.
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items><t:CalendarItem><t:RequiredAttendees>
+				<t:Attendee><t:Mailbox><t:EmailAddress>jane.doe@example.com</t:EmailAddress></t:Mailbox></t:Attendee>
+				<t:Attendee><t:Mailbox><t:EmailAddress>room1@example.com</t:EmailAddress></t:Mailbox></t:Attendee>
+			</t:RequiredAttendees></t:CalendarItem></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		}
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "service@example.com"}
+
+	if err := h.UpdateAppointmentAttendees("040000008200E00074C5B7101A82E008", "organizer@example.com", []string{"room2@example.com"}, []string{"room1@example.com"}); err != nil {
+		t.Fatalf("updating attendees: %v", err)
+	}
+	if recreated {
+		t.Error("expected the rooms to be updated in place, not the event to be recreated")
+	}
+	var parsed struct {
+		ItemID struct {
+			ID string `xml:"Id,attr"`
+		} `xml:"Body>UpdateItem>ItemChanges>ItemChange>ItemId"`
+		Set    []string `xml:"Body>UpdateItem>ItemChanges>ItemChange>Updates>SetItemField>CalendarItem>RequiredAttendees>Attendee>Mailbox>EmailAddress"`
+		Append []string `xml:"Body>UpdateItem>ItemChanges>ItemChange>Updates>AppendToItemField>CalendarItem>Resources>Attendee>Mailbox>EmailAddress"`
+	}
+	if err := xml.Unmarshal([]byte(update), &parsed); err != nil {
+		t.Fatalf("rendered malformed XML: %v", err)
+	}
+	if parsed.ItemID.ID != "item1" {
+		t.Errorf("expected the organizer's event to be updated, got %q", parsed.ItemID.ID)
+	}
+	if fmt.Sprint(parsed.Set) != "[jane.doe@example.com]" || fmt.Sprint(parsed.Append) != "[room2@example.com]" {
+		t.Errorf("expected the removed room to leave the required attendees and the added one to be a resource, got %v and %v", parsed.Set, parsed.Append)
+	}
+}
+
+func TestCancelledOccurrenceFreesRoomsInThatOccurrence(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		requests = append(requests, request)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<m:UpdateItem"):
+			_, _ = w.Write([]byte(soapResponse(`<m:UpdateItemResponse><m:ResponseMessages><m:UpdateItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode></m:UpdateItemResponseMessage></m:ResponseMessages></m:UpdateItemResponse>`)))
+		case strings.Contains(request, "<m:CreateItem"):
+			_, _ = w.Write([]byte(soapResponse(`<m:CreateItemResponse><m:ResponseMessages><m:CreateItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode></m:CreateItemResponseMessage></m:ResponseMessages></m:CreateItemResponse>`)))
+		case strings.Contains(request, "<m:FindItem"):
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items><t:CalendarItem><t:ItemId Id="master" ChangeKey="ck"/></t:CalendarItem></t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		default:
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items><t:CalendarItem><t:ItemId Id="occurrence3" ChangeKey="ck3"/><t:Resources>
+				<t:Attendee><t:Mailbox><t:EmailAddress>room1@example.com</t:EmailAddress></t:Mailbox></t:Attendee>
+				<t:Attendee><t:Mailbox><t:EmailAddress>room2@example.com</t:EmailAddress></t:Mailbox></t:Attendee>
+			</t:Resources></t:CalendarItem></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		}
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "service@example.com"}
+	const uid = "040000008200E00074C5B7101A82E008"
+	occurrence := `<t:OccurrenceItemId RecurringMasterId="master" InstanceIndex="3"></t:OccurrenceItemId>`
+
+	if err := h.RemoveOccurrenceAttendees(uid, "organizer@example.com", 3, []string{"room1@example.com"}); err != nil {
+		t.Fatalf("removing rooms: %v", err)
+	}
+	update := requests[len(requests)-1]
+	if !strings.Contains(update, "<t:ItemChange>"+occurrence) || strings.Contains(update, "<t:ItemChange><t:ItemId") {
+		t.Errorf("expected only the occurrence to be updated, got %s", update)
+	}
+	if !strings.Contains(update, "room2@example.com") || strings.Contains(update, "room1@example.com") {
+		t.Errorf("expected the room to be removed from the occurrence, got %s", update)
+	}
+
+	requests = nil
+	if err := h.DeclineOccurrenceAsResource("room1@example.com", uid, 3); err != nil {
+		t.Fatalf("declining occurrence: %v", err)
+	}
+	if !strings.Contains(requests[1], occurrence) {
+		t.Errorf("expected the room's occurrence to be looked up, got %s", requests[1])
+	}
+	if decline := requests[len(requests)-1]; !strings.Contains(decline, `<t:ReferenceItemId Id="occurrence3" ChangeKey="ck3">`) {
+		t.Errorf("expected the occurrence to be declined, got %s", decline)
+	}
+}
+
+const htmlLoginPage = `<!DOCTYPE html>
+<html>
+<head><title>Sign in</title></head>
+<body><form action="/login" method="post"><input name="username"><input type="password" name="password"></form></body>
+</html>`
+
+func TestNonSOAPSnippetKeepsCharactersWhole(t *testing.T) {
+	response := &http.Response{StatusCode: http.StatusBadGateway, Header: http.Header{"Content-Type": {"text/html"}}}
+	// 'ü' takes two bytes, so a byte limit would split one of them.
+	err := checkSOAPResponse(response, []byte(strings.Repeat("ü", maxSnippetLength+1)))
+	if !errors.Is(err, ErrNotSOAP) {
+		t.Fatalf("expected ErrNotSOAP, got %v", err)
+	}
+	if !utf8.ValidString(err.Error()) || !strings.HasSuffix(err.Error(), strings.Repeat("ü", maxSnippetLength-1)+"…") {
+		t.Errorf("expected the snippet truncated to %d characters, got %q", maxSnippetLength, err)
+	}
+}
+
+func TestHTMLResponseIsRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(htmlLoginPage))
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+	}
+
+	new, updated, cancelled, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if !errors.Is(err, ErrNotSOAP) {
+		t.Fatalf("expected ErrNotSOAP, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Sign in") {
+		t.Errorf("expected the error to contain a snippet of the body, got %v", err)
+	}
+	if len(new) != 0 || len(updated) != 0 || len(cancelled) != 0 {
+		t.Errorf("expected no changes, got %d new, %d updated, %d cancelled", len(new), len(updated), len(cancelled))
+	}
+}
+
+func TestHTMLResponseWithXMLContentTypeIsRejected(t *testing.T) {
+	h := newTestHelper(t, htmlLoginPage)
+	if _, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", ""); !errors.Is(err, ErrNotSOAP) {
+		t.Fatalf("expected ErrNotSOAP, got %v", err)
+	}
+}
+
+func createItemResponse(responseClass, responseCode, itemID string) string {
+	item := ""
+	if itemID != "" {
+		item = `<t:CalendarItem><t:ItemId Id="` + itemID + `" ChangeKey="ck"/></t:CalendarItem>`
+	}
+	return `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <m:CreateItemResponse xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
+      <m:ResponseMessages>
+        <m:CreateItemResponseMessage ResponseClass="` + responseClass + `">
+          <m:MessageText>Message submission was blocked.</m:MessageText>
+          <m:ResponseCode>` + responseCode + `</m:ResponseCode>
+          <m:Items>` + item + `</m:Items>
+        </m:CreateItemResponseMessage>
+      </m:ResponseMessages>
+    </m:CreateItemResponse>
+  </s:Body>
+</s:Envelope>`
+}
+
+func TestParseCreateItemResponse(t *testing.T) {
+	for name, tc := range map[string]struct {
+		response        string
+		wantItem        string
+		invitationsSent bool
+		wantErr         bool
+	}{
+		"created":                   {createItemResponse("Success", "NoError", "item1"), "item1", true, false},
+		"created, invites warning":  {createItemResponse("Warning", "ErrorMessageSubmissionBlocked", "item1"), "item1", false, false},
+		"created, invites not sent": {createItemResponse("Error", "ErrorMessageSubmissionBlocked", "item1"), "item1", false, false},
+		"not created":               {createItemResponse("Error", "ErrorSendAsDenied", ""), "", false, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			itemID, invitationsSent, err := parseCreateItemResponse([]byte(tc.response))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if itemID != tc.wantItem || invitationsSent != tc.invitationsSent {
+				t.Errorf("expected item %q with invitations sent %v, got %q, %v", tc.wantItem, tc.invitationsSent, itemID, invitationsSent)
+			}
+		})
+	}
+	if _, _, err := parseCreateItemResponse([]byte(createItemResponse("Error", "ErrorNonExistentMailbox", ""))); !errors.Is(err, ErrNonExistentMailbox) {
+		t.Errorf("expected ErrNonExistentMailbox, got %v", err)
+	}
+}
+
+func TestResponseCodesMatchSentinels(t *testing.T) {
+	if err := parseResponseCode("Success", "NoError", ""); err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+	err := fmt.Errorf("GetItem failed: %w", parseResponseCode("Error", "ErrorAccessDenied", "Access is denied."))
+	if !errors.Is(err, ErrAccessDenied) || errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected only ErrAccessDenied to match, got %v", err)
+	}
+	var ewsErr *EWSError
+	if !errors.As(err, &ewsErr) || ewsErr.Message != "Access is denied." {
+		t.Errorf("expected the message to be kept, got %v", err)
+	}
+	for _, code := range []string{"ErrorIrresolvableConflict", "ErrorStaleObject"} {
+		if err := parseResponseCode("Error", code, ""); !errors.Is(err, errConflict) {
+			t.Errorf("expected %s to be a conflict", code)
+		}
+	}
+
+	var fault soapFault
+	fault.Body.Fault.FaultCode = "a:ErrorNonExistentMailbox"
+	fault.Body.Fault.FaultString = "The SMTP address has no mailbox associated with it."
+	if err := faultError(fault); !errors.Is(err, ErrNonExistentMailbox) {
+		t.Errorf("expected a fault without details to be identified by its code, got %v", err)
+	}
+}
+
+func TestInvitationsDowngradeAfterRepeatedFailures(t *testing.T) {
+	id := int64(4713)
+	limit := int32(2)
+	config := apiserver.Configuration{Id: &id, InvitationFailureLimit: &limit}
+	delivery := deliveryFor(config)
+	if delivery != deliveryFor(config) {
+		t.Fatal("expected helpers of the same config to share the delivery state")
+	}
+	delivery.record(false)
+	delivery.record(true)
+	delivery.record(false)
+	if got := delivery.sendMeetingInvitations(); got != sendToAllAndSaveCopy {
+		t.Fatalf("expected invitations after non-consecutive failures, got %s", got)
+	}
+	delivery.record(false)
+	if got := delivery.sendMeetingInvitations(); got != sendToNone {
+		t.Fatalf("expected no invitations after %d failures, got %s", limit, got)
+	}
+	request, err := createAppointmentRequest(Appointment{sendInvitations: delivery.sendMeetingInvitations()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(request, `SendMeetingInvitations="SendToNone"`) {
+		t.Error("expected the request not to send invitations")
+	}
+
+	unlimited := apiserver.Configuration{Id: &id}
+	if got := deliveryFor(unlimited).sendMeetingInvitations(); got != sendToAllAndSaveCopy {
+		t.Errorf("expected changed configuration to send invitations again, got %s", got)
+	}
+}
+
+func TestCreateAppointmentWithoutInvitationsKeepsRoomEvents(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<m:CreateItem"):
+			_, _ = w.Write([]byte(createItemResponse("Warning", "ErrorMessageSubmissionBlocked", "item1")))
+		case strings.Contains(request, "calendar:UID"):
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:Items><t:CalendarItem><t:UID>` + uid + `</t:UID></t:CalendarItem></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		case strings.Contains(request, "<m:FindItem") && strings.Contains(request, "room1@example.com"):
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items><t:CalendarItem><t:ItemId Id="room1-item" ChangeKey="ck"/></t:CalendarItem></t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		default:
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items></t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		}
+	}))
+	defer server.Close()
+	h := &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+		invitations:  &invitationDelivery{},
+	}
+
+	exchangeUID, results, err := h.CreateAppointment(Appointment{
+		ElionaID:  1,
+		Organizer: "organizer@example.com",
+		Start:     time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC),
+		End:       time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC),
+		Resources: []string{"room1@example.com", "room2@example.com"},
+	})
+	if err != nil || exchangeUID != uid {
+		t.Fatalf("expected the event to be kept, got %q, %v", exchangeUID, err)
+	}
+	accepted := AcceptedRooms(results)
+	if len(accepted) != 1 || accepted[0].Room != "room1@example.com" || accepted[0].ResourceEventID != "room1-item" {
+		t.Errorf("expected the item of the room having the event, got %+v", results)
+	}
+}
+
+const syncFolderItemsCategories = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <m:SyncFolderItemsResponse xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
+      <m:ResponseMessages>
+        <m:SyncFolderItemsResponseMessage ResponseClass="Success">
+          <m:ResponseCode>NoError</m:ResponseCode>
+          <m:SyncState>state</m:SyncState>
+          <m:IncludesLastItemInRange>true</m:IncludesLastItemInRange>
+          <m:Changes>
+            <t:Create>
+              <t:CalendarItem>
+                <t:ItemId Id="categorized" ChangeKey="a"/>
+                <t:UID>categorized</t:UID>
+                <t:Start>2024-05-06T09:00:00Z</t:Start>
+                <t:End>2024-05-06T10:00:00Z</t:End>
+                <t:Organizer>
+                  <t:Mailbox>
+                    <t:EmailAddress>john.doe@example.com</t:EmailAddress>
+                  </t:Mailbox>
+                </t:Organizer>
+                <t:CalendarItemType>Single</t:CalendarItemType>
+                <t:Categories>
+                  <t:String>Catering</t:String>
+                  <t:String>Board meeting</t:String>
+                  <t:String>Red category</t:String>
+                </t:Categories>
+              </t:CalendarItem>
+            </t:Create>
+            <t:Create>
+              <t:CalendarItem>
+                <t:ItemId Id="plain" ChangeKey="b"/>
+                <t:UID>plain</t:UID>
+                <t:Start>2024-05-06T11:00:00Z</t:Start>
+                <t:End>2024-05-06T12:00:00Z</t:End>
+                <t:Organizer>
+                  <t:Mailbox>
+                    <t:EmailAddress>jane.doe@example.com</t:EmailAddress>
+                  </t:Mailbox>
+                </t:Organizer>
+                <t:CalendarItemType>Single</t:CalendarItemType>
+              </t:CalendarItem>
+            </t:Create>
+          </m:Changes>
+        </m:SyncFolderItemsResponseMessage>
+      </m:ResponseMessages>
+    </m:SyncFolderItemsResponse>
+  </s:Body>
+</s:Envelope>`
+
+func TestGetRoomAppointmentsReadsCategories(t *testing.T) {
+	h := newTestHelper(t, syncFolderItemsCategories)
+	new, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	if len(new) != 2 {
+		t.Fatalf("expected 2 new events, got %d", len(new))
+	}
+	want := []string{"Catering", "Board meeting", "Red category"}
+	if strings.Join(new[0].Categories, ",") != strings.Join(want, ",") {
+		t.Errorf("expected categories %v, got %v", want, new[0].Categories)
+	}
+	if len(new[1].Categories) != 0 {
+		t.Errorf("expected no categories, got %v", new[1].Categories)
+	}
+
+	h.privacyMode = true
+	new, _, _, _, _, err = h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	if len(new[0].Categories) != 0 {
+		t.Errorf("expected categories to be suppressed in privacy mode, got %v", new[0].Categories)
+	}
+}
+
+const syncFolderItemsSubjects = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <m:SyncFolderItemsResponse xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
+      <m:ResponseMessages>
+        <m:SyncFolderItemsResponseMessage ResponseClass="Success">
+          <m:ResponseCode>NoError</m:ResponseCode>
+          <m:SyncState>state</m:SyncState>
+          <m:IncludesLastItemInRange>true</m:IncludesLastItemInRange>
+          <m:Changes>
+            <t:Create>
+              <t:CalendarItem>
+                <t:ItemId Id="titled" ChangeKey="a"/>
+                <t:UID>titled</t:UID>
+                <t:Subject>Weekly</t:Subject>
+                <t:Start>2024-05-06T09:00:00Z</t:Start>
+                <t:End>2024-05-06T10:00:00Z</t:End>
+                <t:Organizer>
+                  <t:Mailbox>
+                    <t:EmailAddress>john.doe@example.com</t:EmailAddress>
+                  </t:Mailbox>
+                </t:Organizer>
+                <t:CalendarItemType>Single</t:CalendarItemType>
+              </t:CalendarItem>
+            </t:Create>
+            <t:Create>
+              <t:CalendarItem>
+                <t:ItemId Id="empty" ChangeKey="a"/>
+                <t:UID>empty</t:UID>
+                <t:Start>2024-05-06T11:00:00Z</t:Start>
+                <t:End>2024-05-06T12:00:00Z</t:End>
+                <t:Organizer>
+                  <t:Mailbox>
+                    <t:EmailAddress>john.doe@example.com</t:EmailAddress>
+                  </t:Mailbox>
+                </t:Organizer>
+                <t:CalendarItemType>Single</t:CalendarItemType>
+              </t:CalendarItem>
+            </t:Create>
+            <t:Create>
+              <t:CalendarItem>
+                <t:ItemId Id="blank" ChangeKey="a"/>
+                <t:UID>blank</t:UID>
+                <t:Subject>  &#9; </t:Subject>
+                <t:Start>2024-05-06T13:00:00Z</t:Start>
+                <t:End>2024-05-06T14:00:00Z</t:End>
+                <t:Organizer>
+                  <t:Mailbox>
+                    <t:EmailAddress>john.doe@example.com</t:EmailAddress>
+                  </t:Mailbox>
+                </t:Organizer>
+                <t:CalendarItemType>Single</t:CalendarItemType>
+              </t:CalendarItem>
+            </t:Create>
+          </m:Changes>
+        </m:SyncFolderItemsResponseMessage>
+      </m:ResponseMessages>
+    </m:SyncFolderItemsResponse>
+  </s:Body>
+</s:Envelope>`
+
+func TestGetRoomAppointmentsSubjectFallback(t *testing.T) {
+	h := newTestHelper(t, syncFolderItemsSubjects)
+	h.subjects = syncmodel.SubjectFallback{Template: "{room}, {organizer}, {start}"}
+	new, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	want := []string{
+		"Weekly",
+		"room1@example.com, john.doe@example.com, 2024-05-06 11:00",
+		"room1@example.com, john.doe@example.com, 2024-05-06 13:00",
+	}
+	if len(new) != len(want) {
+		t.Fatalf("expected %d new events, got %d", len(want), len(new))
+	}
+	for i, group := range new {
+		if group.Subject != want[i] {
+			t.Errorf("event %s: expected subject %q, got %q", group.ExchangeUID, want[i], group.Subject)
+		}
+	}
+
+	h.privacyMode = true
+	new, _, _, _, _, err = h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	if want := "room1@example.com, john.doe@example.com, 2024-05-06 09:00"; new[0].Subject != want {
+		t.Errorf("expected subject to be composed in privacy mode, got %q", new[0].Subject)
+	}
+}
+
+func TestGetRoomAppointmentsSelfOrganized(t *testing.T) {
+	// The first event was booked in the room's calendar by the room itself.
+	response := strings.Replace(syncFolderItemsSubjects, "john.doe@example.com", "Room1@example.com", 1)
+	h := newTestHelper(t, response)
+	new, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	for i, group := range new {
+		if want := i == 0; group.SelfOrganized != want {
+			t.Errorf("event %s: expected self-organized %t, organized by %s", group.ExchangeUID, want, group.OrganizerEmail)
+		}
+	}
+}
+
+func TestGetRoomAppointmentsRecognizesBlocks(t *testing.T) {
+	// The first event has an organizer, but nobody is invited to it. The
+	// others are meetings with the room as a resource or marked as meetings.
+	response := strings.Replace(syncFolderItemsSubjects, `<t:UID>empty</t:UID>`, `<t:UID>empty</t:UID>
+                <t:Resources>
+                  <t:Attendee>
+                    <t:Mailbox>
+                      <t:EmailAddress>room1@example.com</t:EmailAddress>
+                    </t:Mailbox>
+                  </t:Attendee>
+                </t:Resources>`, 1)
+	response = strings.Replace(response, `<t:UID>blank</t:UID>`, `<t:UID>blank</t:UID>
+                <t:IsMeeting>true</t:IsMeeting>`, 1)
+	h := newTestHelper(t, response)
+	new, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	if len(new) != 3 {
+		t.Fatalf("expected 3 new events, got %d", len(new))
+	}
+	for i, group := range new {
+		if want := i == 0; group.Block != want {
+			t.Errorf("event %s: expected block %t", group.ExchangeUID, want)
+		}
+	}
+}
+
+func TestCredentialsUnhealthyAfterRepeatedRejections(t *testing.T) {
+	status := http.StatusUnauthorized
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, credentials: credentialsFor(apiserver.Configuration{Id: common.Ptr(int64(-978))})}
+
+	for i := 1; i <= unauthorizedLimit; i++ {
+		if !CredentialsOf(-978).Healthy {
+			t.Fatalf("expected credentials to be healthy after %d rejections", i-1)
+		}
+		if _, err := h.sendRequest(serverTimeZonesRequest); err == nil {
+			t.Fatal("expected the rejected request to fail")
+		}
+	}
+	if state := CredentialsOf(-978); state.Healthy || state.Reason == "" {
+		t.Fatalf("expected credentials to be unhealthy with a reason, got %+v", state)
+	}
+
+	status = http.StatusOK
+	h.sendRequest(serverTimeZonesRequest)
+	if !CredentialsOf(-978).Healthy {
+		t.Error("expected accepted credentials to be healthy again")
+	}
+}
+
+func getFolderResponse(class, code string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <m:GetFolderResponse xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
+      <m:ResponseMessages>
+        <m:GetFolderResponseMessage ResponseClass="%s">
+          <m:MessageText>The impersonation of user is denied.</m:MessageText>
+          <m:ResponseCode>%s</m:ResponseCode>
+        </m:GetFolderResponseMessage>
+      </m:ResponseMessages>
+    </m:GetFolderResponse>
+  </s:Body>
+</s:Envelope>`, class, code)
+}
+
+func TestCheckImpersonation(t *testing.T) {
+	h := newTestHelper(t, getFolderResponse("Success", "NoError"))
+	if _, err := h.checkImpersonation("service@example.com"); err != nil {
+		t.Errorf("expected impersonation to succeed, got %v", err)
+	}
+	h = newTestHelper(t, getFolderResponse("Error", "ErrorImpersonateUserDenied"))
+	_, err := h.checkImpersonation("service@example.com")
+	if err == nil || !strings.Contains(err.Error(), "ErrorImpersonateUserDenied") {
+		t.Errorf("expected impersonation to be denied, got %v", err)
+	}
+}
+
+func TestDiagnoseSkipsStepsAfterFailure(t *testing.T) {
+	steps := Diagnose(apiserver.Configuration{ServiceUserUPN: common.Ptr("service@example.com")})
+	if len(steps) == 0 || steps[0].Err == nil {
+		t.Fatalf("expected missing credentials to fail, got %+v", steps)
+	}
+	for _, step := range steps[1:] {
+		if !step.Skipped {
+			t.Errorf("expected step %q to be skipped", step.Name)
+		}
+	}
+}
+
+func TestConnectionReportsImpersonationDenied(t *testing.T) {
+	response := getFolderResponse("Error", "ErrorImpersonateUserDenied")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, response)
+	}))
+	defer server.Close()
+	config := apiserver.Configuration{
+		Id:             common.Ptr(int64(7)),
+		EwsURL:         common.Ptr(server.URL),
+		Username:       common.Ptr("user"),
+		Password:       common.Ptr("password"),
+		ServiceUserUPN: common.Ptr("service@example.com"),
+	}
+	err := TestConnection(config)
+	if !errors.Is(err, ErrImpersonationDenied) {
+		t.Errorf("expected impersonation to be denied, got %v", err)
+	}
+	response = getFolderResponse("Success", "NoError")
+	if err := TestConnection(config); err != nil {
+		t.Errorf("expected connection test to succeed, got %v", err)
+	}
+}
+
+// soapResponse wraps the body element in a SOAP envelope.
+func soapResponse(body string) string {
+	return `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
+  <s:Body>` + body + `</s:Body>
+</s:Envelope>`
+}
+
+func TestCreateAppointmentAddsLargeAttendeeListsInBatches(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	var mu sync.Mutex
+	var created int
+	var added []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<m:CreateItem"):
+			created = strings.Count(request, "<t:Attendee>")
+			_, _ = w.Write([]byte(createItemResponse("Success", "NoError", "item1")))
+		case strings.Contains(request, "<m:UpdateItem"):
+			if !strings.Contains(request, `SendMeetingInvitationsOrCancellations="SendToChangedAndSaveCopy"`) {
+				t.Error("expected only the added attendees to be invited")
+			}
+			added = append(added, strings.Count(request, "<t:Attendee>"))
+			_, _ = w.Write([]byte(soapResponse(`<m:UpdateItemResponse><m:ResponseMessages><m:UpdateItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode></m:UpdateItemResponseMessage></m:ResponseMessages></m:UpdateItemResponse>`)))
+		case strings.Contains(request, "<m:FindItem"):
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items><t:CalendarItem><t:ItemId Id="item1" ChangeKey="ck"/></t:CalendarItem></t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		case strings.Contains(request, "calendar:UID"):
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:Items><t:CalendarItem><t:UID>` + uid + `</t:UID></t:CalendarItem></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		default:
+			// Attendees of the event, appended to in batches anyway.
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items><t:CalendarItem/></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		}
+	}))
+	defer server.Close()
+	h := &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+		invitations:  &invitationDelivery{},
+	}
+
+	rooms := make([]string, 250)
+	for i := range rooms {
+		rooms[i] = fmt.Sprintf("room%d@example.com", i)
+	}
+	exchangeUID, results, err := h.CreateAppointment(Appointment{
+		ElionaID:       1,
+		Organizer:      "organizer@example.com",
+		Start:          time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC),
+		End:            time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC),
+		Location:       rooms[0],
+		Resources:      rooms,
+		DeferResponses: true,
+
+		MaxAttendeesPerRequest: 100,
+	})
+	if err != nil {
+		t.Fatalf("creating appointment: %v", err)
+	}
+	if exchangeUID != uid {
+		t.Errorf("expected UID %s, got %s", uid, exchangeUID)
+	}
+	if created != 100 || len(added) != 2 || added[0] != 100 || added[1] != 50 {
+		t.Errorf("expected 100 attendees at creation and batches of 100 and 50, got %d and %v", created, added)
+	}
+	if len(results) != len(rooms) || len(AcceptedRooms(results)) != len(rooms) {
+		t.Errorf("expected responses of all %d rooms, got %d", len(rooms), len(results))
+	}
+}
+
+func TestSetResourceSubjectUpdatesOnlyResourceCopy(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	var updates []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<m:UpdateItem"):
+			updates = append(updates, request)
+			_, _ = w.Write([]byte(soapResponse(`<m:UpdateItemResponse><m:ResponseMessages><m:UpdateItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode></m:UpdateItemResponseMessage></m:ResponseMessages></m:UpdateItemResponse>`)))
+		case strings.Contains(request, "<t:SmtpAddress>room1@example.com</t:SmtpAddress>"):
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items><t:CalendarItem><t:ItemId Id="room-copy" ChangeKey="ck"/></t:CalendarItem></t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		default:
+			t.Errorf("unexpected request %s", request)
+		}
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "organizer@example.com"}
+
+	subjects := syncmodel.SubjectFallback{Template: "Booked by {organizer}"}
+	subject := subjects.Compose("room1@example.com", "o'brien@example.com", time.Time{}, time.Time{})
+	if err := h.SetResourceSubject("room1@example.com", uid, subject); err != nil {
+		t.Fatalf("setting resource subject: %v", err)
+	}
+	if len(updates) != 1 {
+		t.Fatalf("expected a single update, got %d", len(updates))
+	}
+	for _, want := range []string{
+		`<t:SmtpAddress>room1@example.com</t:SmtpAddress>`,
+		`<t:ItemId Id="room-copy" ChangeKey="ck"></t:ItemId>`,
+		`<t:Subject>Booked by o&#39;brien@example.com</t:Subject>`,
+		`SendMeetingInvitationsOrCancellations="SendToNone"`,
+	} {
+		if !strings.Contains(updates[0], want) {
+			t.Errorf("expected update to contain %s", want)
+		}
+	}
+	if strings.Contains(updates[0], "organizer@example.com") {
+		t.Error("expected the organizer's copy to be left unchanged")
+	}
+}
+
+func TestUpdateAppointmentMovesOrganizerEvent(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	var updates []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<m:UpdateItem"):
+			updates = append(updates, request)
+			_, _ = w.Write([]byte(soapResponse(`<m:UpdateItemResponse><m:ResponseMessages><m:UpdateItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode></m:UpdateItemResponseMessage></m:ResponseMessages></m:UpdateItemResponse>`)))
+		case strings.Contains(request, "<m:FindItem"):
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items><t:CalendarItem><t:ItemId Id="item1" ChangeKey="ck"/></t:CalendarItem></t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		case strings.Contains(request, "<m:GetItem"):
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items><t:CalendarItem><t:ItemId Id="item1" ChangeKey="ck"/><t:Start>2024-05-06T09:00:00Z</t:Start><t:End>2024-05-06T10:00:00Z</t:End></t:CalendarItem></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		default:
+			t.Errorf("unexpected request %s", request)
+		}
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "service@example.com"}
+
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	if err := h.UpdateAppointment(uid, "organizer@example.com", start, start.Add(time.Hour)); err != nil {
+		t.Fatalf("updating unchanged appointment: %v", err)
+	}
+	if len(updates) != 0 {
+		t.Fatalf("expected no update for unchanged times, got %d", len(updates))
+	}
+	if err := h.UpdateAppointment(uid, "organizer@example.com", start.Add(time.Hour), start.Add(2*time.Hour)); err != nil {
+		t.Fatalf("updating appointment: %v", err)
+	}
+	if len(updates) != 1 {
+		t.Fatalf("expected a single update, got %d", len(updates))
+	}
+	for _, want := range []string{
+		`<t:SmtpAddress>organizer@example.com</t:SmtpAddress>`,
+		`<t:ItemId Id="item1" ChangeKey="ck"></t:ItemId>`,
+		`<t:Start>2024-05-06T10:00:00Z</t:Start>`,
+		`<t:End>2024-05-06T11:00:00Z</t:End>`,
+		`SendMeetingInvitationsOrCancellations="SendToAllAndSaveCopy"`,
+	} {
+		if !strings.Contains(updates[0], want) {
+			t.Errorf("expected update to contain %s", want)
+		}
+	}
+}
+
+func TestUpdateOccurrenceMovesOnlyThatOccurrence(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	var lookups, updates []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<m:UpdateItem"):
+			updates = append(updates, request)
+			_, _ = w.Write([]byte(soapResponse(`<m:UpdateItemResponse><m:ResponseMessages><m:UpdateItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode></m:UpdateItemResponseMessage></m:ResponseMessages></m:UpdateItemResponse>`)))
+		case strings.Contains(request, "<m:FindItem"):
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items><t:CalendarItem><t:ItemId Id="master1" ChangeKey="ck"/></t:CalendarItem></t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		case strings.Contains(request, "<m:GetItem"):
+			lookups = append(lookups, request)
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items><t:CalendarItem><t:ItemId Id="occurrence3" ChangeKey="ck3"/><t:Start>2024-05-20T09:00:00Z</t:Start><t:End>2024-05-20T10:00:00Z</t:End></t:CalendarItem></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		default:
+			t.Errorf("unexpected request %s", request)
+		}
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "service@example.com"}
+
+	start := time.Date(2024, 5, 20, 11, 0, 0, 0, time.UTC)
+	if err := h.UpdateOccurrence(uid, "organizer@example.com", 3, start, start.Add(time.Hour)); err != nil {
+		t.Fatalf("updating occurrence: %v", err)
+	}
+	if len(lookups) != 1 || !strings.Contains(lookups[0], `RecurringMasterId="master1" InstanceIndex="3"`) {
+		t.Errorf("expected the occurrence to be looked up by its instance index, got %v", lookups)
+	}
+	if len(updates) != 1 {
+		t.Fatalf("expected a single update, got %d", len(updates))
+	}
+	for _, want := range []string{
+		`<t:ItemId Id="occurrence3" ChangeKey="ck3"></t:ItemId>`,
+		`<t:Start>2024-05-20T11:00:00Z</t:Start>`,
+		`<t:End>2024-05-20T12:00:00Z</t:End>`,
+	} {
+		if !strings.Contains(updates[0], want) {
+			t.Errorf("expected update to contain %s", want)
+		}
+	}
+}
+
+func TestWaitForResourceEventPollsUntilProcessed(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	found := `<t:CalendarItem><t:ItemId Id="item1" ChangeKey="ck"/></t:CalendarItem>`
+	var roomLookups, processedAfter int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<m:FindItem"):
+			items := found
+			if strings.Contains(request, "<t:SmtpAddress>room1@example.com</t:SmtpAddress>") {
+				roomLookups++
+				if processedAfter == 0 || roomLookups < processedAfter {
+					items = ""
+				}
+			}
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items>` + items + `</t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		default:
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items><t:CalendarItem/></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		}
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "service@example.com"}
+
+	now := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	var sleeps []time.Duration
+	pollNow = func() time.Time { return now }
+	pollSleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+	}
+	t.Cleanup(func() {
+		pollNow = time.Now
+		pollSleep = time.Sleep
+	})
+
+	processedAfter = 3
+	eventID, err := h.waitForResourceEvent("organizer@example.com", "room1@example.com", uid, now.Add(15*time.Second), 30*time.Second)
+	if err != nil || eventID != "item1" {
+		t.Fatalf("expected the room's event once processed, got %q, %v", eventID, err)
+	}
+	if fmt.Sprint(sleeps) != "[1s 1s]" {
+		t.Errorf("expected to poll every second, slept %v", sleeps)
+	}
+
+	roomLookups, processedAfter, sleeps = 0, 0, nil
+	if _, err := h.waitForResourceEvent("organizer@example.com", "room1@example.com", uid, now.Add(3*time.Second), 10*time.Second); !errors.Is(err, errNotFound) {
+		t.Fatalf("expected the room not to process the invitation, got %v", err)
+	}
+	if fmt.Sprint(sleeps) != "[1s 1s 1s 5s 5s]" {
+		t.Errorf("expected to poll every second until processed by, less often during the grace period, slept %v", sleeps)
+	}
+}
+
+// roomProcessingHelper returns a helper whose server has the rooms in
+// processedAfter find the event only from the given lookup on, never for 0.
+// Other rooms and the organizer have the event right away.
+func roomProcessingHelper(t *testing.T, uid string, processedAfter map[string]int) *EWSHelper {
+	found := `<t:CalendarItem><t:ItemId Id="item1" ChangeKey="ck"/></t:CalendarItem>`
+	lookups := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<m:CreateItem"):
+			_, _ = w.Write([]byte(createItemResponse("Success", "NoError", "item1")))
+		case strings.Contains(request, "calendar:UID"):
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:Items><t:CalendarItem><t:UID>` + uid + `</t:UID></t:CalendarItem></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		case strings.Contains(request, "<m:FindItem"):
+			items := found
+			for room, after := range processedAfter {
+				if strings.Contains(request, "<t:SmtpAddress>"+room+"</t:SmtpAddress>") {
+					lookups[room]++
+					if after == 0 || lookups[room] < after {
+						items = ""
+					}
+				}
+			}
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items>` + items + `</t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		default:
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items><t:CalendarItem/></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+		invitations:  &invitationDelivery{},
+	}
+}
+
+func TestApprovalRoomKeepsBookingPending(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	h := roomProcessingHelper(t, uid, map[string]int{"room1@example.com": 0})
+	now := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	var sleeps []time.Duration
+	pollNow = func() time.Time { return now }
+	pollSleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+	}
+	t.Cleanup(func() {
+		pollNow = time.Now
+		pollSleep = time.Sleep
+	})
+
+	_, results, err := h.CreateAppointment(Appointment{
+		ElionaID:           1,
+		Organizer:          "organizer@example.com",
+		Start:              time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC),
+		End:                time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC),
+		Resources:          []string{"room1@example.com", "room2@example.com"},
+		ApprovalRooms:      []string{"room1@example.com"},
+		DeclineGracePeriod: time.Minute,
+	})
+	if !errors.Is(err, ErrPendingApproval) {
+		t.Fatalf("expected the booking to wait for approval, got %v", err)
+	}
+	if len(results) != 2 || !results[0].PendingApproval || results[0].Declined {
+		t.Errorf("expected the approval room to stay pending instead of declining, got %+v", results)
+	}
+	if results[1].ResourceEventID != "item1" {
+		t.Errorf("expected the other room to accept, got %+v", results[1])
+	}
+	if len(sleeps) != 0 {
+		t.Errorf("expected not to wait for the delegate, slept %v", sleeps)
+	}
+}
+
+func TestMissingResponseDeclinesAfterGracePeriod(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	now := start
+	pollNow = func() time.Time { return now }
+	pollSleep = func(d time.Duration) { now = now.Add(d) }
+	t.Cleanup(func() {
+		pollNow = time.Now
+		pollSleep = time.Sleep
+	})
+	appointment := Appointment{
+		ElionaID:           1,
+		Organizer:          "organizer@example.com",
+		Start:              time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC),
+		End:                time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC),
+		Resources:          []string{"room1@example.com"},
+		ProcessingTimeout:  2 * time.Second,
+		DeclineGracePeriod: 20 * time.Second,
+	}
+
+	// The room processes the invitation after the processing timeout, but
+	// within the grace period.
+	h := roomProcessingHelper(t, uid, map[string]int{"room1@example.com": 5})
+	_, results, err := h.CreateAppointment(appointment)
+	if err != nil || len(results) != 1 || results[0].Declined || results[0].ResourceEventID != "item1" {
+		t.Fatalf("expected the room to accept within the grace period, got %+v, %v", results, err)
+	}
+	if elapsed := now.Sub(start); elapsed <= appointment.ProcessingTimeout {
+		t.Errorf("expected to keep polling after the processing timeout, waited %v", elapsed)
+	}
+
+	now = start
+	h = roomProcessingHelper(t, uid, map[string]int{"room1@example.com": 0})
+	_, results, err = h.CreateAppointment(appointment)
+	if !errors.Is(err, ErrDeclined) || len(results) != 1 || !results[0].Declined {
+		t.Fatalf("expected the missing response to count as a decline, got %+v, %v", results, err)
+	}
+	if elapsed := now.Sub(start); elapsed != appointment.ProcessingTimeout+appointment.DeclineGracePeriod {
+		t.Errorf("expected to decline once the grace period passed, waited %v", elapsed)
+	}
+}
+
+func TestCancelEventComposesCancellationBody(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	var cancellation string
+	var detailsFail bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<t:CancelCalendarItem"):
+			cancellation = request
+			_, _ = w.Write([]byte(createItemResponse("Success", "NoError", "")))
+		case strings.Contains(request, "<m:FindItem"):
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items><t:CalendarItem><t:ItemId Id="item1" ChangeKey="ck"/></t:CalendarItem></t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		case detailsFail:
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Error"><m:ResponseCode>ErrorAccessDenied</m:ResponseCode></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		default:
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items><t:CalendarItem><t:ItemId Id="item1" ChangeKey="ck"/><t:Start>2024-05-06T09:00:00Z</t:Start><t:End>2024-05-06T10:00:00Z</t:End><t:Location>Board &amp; Room</t:Location></t:CalendarItem></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		}
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "organizer@example.com"}
+
+	group := syncmodel.BookingGroup{ExchangeUID: uid, OrganizerEmail: "organizer@example.com"}
+	if err := h.CancelEvent(group); err != nil {
+		t.Fatalf("cancelling event: %v", err)
+	}
+	if !strings.Contains(cancellation, `<t:NewBodyContent BodyType="HTML">Cancelled via Eliona</t:NewBodyContent>`) {
+		t.Errorf("expected the default cancellation body, got %s", cancellation)
+	}
+
+	h.cancellationBody = syncmodel.SubjectFallback{Template: "{room} at {start} was cancelled by {organizer}"}
+	if err := h.CancelEvent(group); err != nil {
+		t.Fatalf("cancelling event: %v", err)
+	}
+	if !strings.Contains(cancellation, `<t:NewBodyContent BodyType="HTML">Board &amp; Room at 2024-05-06 09:00 was cancelled by organizer@example.com</t:NewBodyContent>`) {
+		t.Errorf("expected the composed cancellation body, got %s", cancellation)
+	}
+
+	// The event is cancelled even if its details can't be read.
+	detailsFail = true
+	cancellation = ""
+	if err := h.CancelEvent(group); err != nil {
+		t.Fatalf("cancelling event: %v", err)
+	}
+	if !strings.Contains(cancellation, `<t:NewBodyContent BodyType="HTML">Cancelled via Eliona</t:NewBodyContent>`) {
+		t.Errorf("expected the default cancellation body, got %s", cancellation)
+	}
+}
+
+// occurrencesResponse answers a GetItem request for occurrences with the
+// message for each requested instance index. Requests for the exceptions of
+// the series get none.
+func occurrencesResponse(request string, message func(index int) string) string {
+	if !strings.Contains(request, "<t:OccurrenceItemId") {
+		return soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items><t:CalendarItem><t:ItemId Id="master" ChangeKey="a"/></t:CalendarItem></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)
+	}
+	var messages strings.Builder
+	for _, match := range regexp.MustCompile(`InstanceIndex="(\d+)"`).FindAllStringSubmatch(request, -1) {
+		index, _ := strconv.Atoi(match[1])
+		messages.WriteString(message(index))
+	}
+	return soapResponse(`<m:GetItemResponse><m:ResponseMessages>` + messages.String() + `</m:ResponseMessages></m:GetItemResponse>`)
+}
+
+func occurrenceMessage(index int, start, end string) string {
+	return fmt.Sprintf(`<m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items><t:CalendarItem><t:ItemId Id="occurrence%d" ChangeKey="a"/><t:Start>%s</t:Start><t:End>%s</t:End></t:CalendarItem></m:Items></m:GetItemResponseMessage>`, index, start, end)
+}
+
+const (
+	outOfRecurrenceRange  = `<m:GetItemResponseMessage ResponseClass="Error"><m:ResponseCode>ErrorCalendarOccurrenceIndexIsOutOfRecurrenceRange</m:ResponseCode></m:GetItemResponseMessage>`
+	deletedFromRecurrence = `<m:GetItemResponseMessage ResponseClass="Error"><m:ResponseCode>ErrorCalendarOccurrenceIsDeletedFromRecurrence</m:ResponseCode></m:GetItemResponseMessage>`
+)
+
+func TestExpandRecurrenceInBatches(t *testing.T) {
+	first := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "<t:OccurrenceItemId") {
+			requests++
+		}
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		// A daily series of 60 occurrences, the 10th of which was deleted.
+		_, _ = w.Write([]byte(occurrencesResponse(string(body), func(index int) string {
+			switch {
+			case index > 60:
+				return outOfRecurrenceRange
+			case index == 10:
+				return deletedFromRecurrence
+			}
+			start := first.AddDate(0, 0, index-1)
+			return occurrenceMessage(index, start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339))
+		})))
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+	}
+
+	items, err := h.expandRecurrence("master", "room1@example.com", 0, first.AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatalf("expanding recurrence: %v", err)
+	}
+	if len(items) != 59 {
+		t.Fatalf("expected 59 occurrences, got %d", len(items))
+	}
+	if items[9].InstanceIndex != 11 || items[58].InstanceIndex != 60 {
+		t.Errorf("expected the deleted occurrence to be skipped, got indices %d and %d", items[9].InstanceIndex, items[58].InstanceIndex)
+	}
+	if requests != 2 {
+		t.Errorf("expected the occurrences to be fetched in 2 requests, got %d", requests)
+	}
+}
+
+func TestMovedOccurrenceKeepsInstanceIndex(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	// The second occurrence of the weekly series was moved before the first.
+	starts := map[string]string{
+		"1": "2024-05-06T09:00:00Z",
+		"2": "2024-05-03T09:00:00Z",
+		"3": "2024-05-20T09:00:00Z",
+	}
+	instanceIndex := regexp.MustCompile(`InstanceIndex="(\d+)"`)
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<m:SyncFolderItems"):
+			_, _ = w.Write([]byte(soapResponse(`<m:SyncFolderItemsResponse><m:ResponseMessages><m:SyncFolderItemsResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:SyncState>state</m:SyncState><m:IncludesLastItemInRange>true</m:IncludesLastItemInRange><m:Changes><t:Create><t:CalendarItem><t:ItemId Id="master" ChangeKey="a"/><t:UID>` + uid + `</t:UID><t:Subject>Weekly</t:Subject><t:Start>2024-05-06T09:00:00Z</t:Start><t:End>2024-05-06T10:00:00Z</t:End><t:Organizer><t:Mailbox><t:EmailAddress>john.doe@example.com</t:EmailAddress></t:Mailbox></t:Organizer><t:CalendarItemType>RecurringMaster</t:CalendarItemType></t:CalendarItem></t:Create></m:Changes></m:SyncFolderItemsResponseMessage></m:ResponseMessages></m:SyncFolderItemsResponse>`)))
+		case strings.Contains(request, "<m:GetItem"):
+			_, _ = w.Write([]byte(occurrencesResponse(request, func(index int) string {
+				start, ok := starts[strconv.Itoa(index)]
+				if !ok {
+					return outOfRecurrenceRange
+				}
+				return occurrenceMessage(index, start, strings.Replace(start, "T09", "T10", 1))
+			})))
+		case strings.Contains(request, "<m:FindItem"):
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items><t:CalendarItem><t:ItemId Id="organizer-master" ChangeKey="ck"/></t:CalendarItem></t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		case strings.Contains(request, "<m:DeleteItem"):
+			deleted = instanceIndex.FindStringSubmatch(request)[1]
+			_, _ = w.Write([]byte(soapResponse(`<m:CreateItemResponse><m:ResponseMessages><m:CreateItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode></m:CreateItemResponseMessage></m:ResponseMessages></m:CreateItemResponse>`)))
+		default:
+			t.Errorf("unexpected request %s", request)
+		}
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+	}
+
+	new, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	if len(new) != 1 || len(new[0].Occurrences) != 3 {
+		t.Fatalf("expected a series of 3 occurrences, got %+v", new)
+	}
+	group := new[0]
+	for i, want := range []int{2, 1, 3} {
+		occurrence := group.Occurrences[i]
+		if occurrence.InstanceIndex != want {
+			t.Errorf("occurrence %d: expected instance index %d, got %d", i, want, occurrence.InstanceIndex)
+		}
+		if i > 0 && !group.Occurrences[i-1].Start.Before(occurrence.Start) {
+			t.Errorf("occurrence %d at %v is not after its predecessor", i, occurrence.Start)
+		}
+	}
+
+	// Cancelling the earliest occurrence cancels the moved one in Exchange.
+	if err := h.CancelOccurrence(group, group.Occurrences[0]); err != nil {
+		t.Fatalf("cancelling occurrence: %v", err)
+	}
+	if deleted != "2" {
+		t.Errorf("expected occurrence with instance index 2 to be cancelled, got %q", deleted)
+	}
+}
+
+func TestInitialImportListsOccurrences(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	starts := []string{"2024-05-06T09:00:00Z", "2024-05-13T09:00:00Z", "2024-05-20T09:00:00Z"}
+	var occurrences strings.Builder
+	for i, start := range starts {
+		fmt.Fprintf(&occurrences, `<t:CalendarItem><t:ItemId Id="occurrence%d" ChangeKey="a"/><t:UID>%s</t:UID><t:Start>%s</t:Start><t:End>%s</t:End><t:CalendarItemType>Occurrence</t:CalendarItemType></t:CalendarItem>`,
+			i+1, uid, start, strings.Replace(start, "T09", "T10", 1))
+	}
+	var fetched []int
+	var views int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<m:SyncFolderItems"):
+			_, _ = w.Write([]byte(soapResponse(`<m:SyncFolderItemsResponse><m:ResponseMessages><m:SyncFolderItemsResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:SyncState>state</m:SyncState><m:IncludesLastItemInRange>true</m:IncludesLastItemInRange><m:Changes><t:Create><t:CalendarItem><t:ItemId Id="master" ChangeKey="a"/><t:UID>` + uid + `</t:UID><t:Subject>Weekly</t:Subject><t:Start>2024-05-06T09:00:00Z</t:Start><t:End>2024-05-06T10:00:00Z</t:End><t:Organizer><t:Mailbox><t:EmailAddress>john.doe@example.com</t:EmailAddress></t:Mailbox></t:Organizer><t:CalendarItemType>RecurringMaster</t:CalendarItemType></t:CalendarItem></t:Create></m:Changes></m:SyncFolderItemsResponseMessage></m:ResponseMessages></m:SyncFolderItemsResponse>`)))
+		case strings.Contains(request, "<m:FindItem"):
+			// Later windows follow up to the horizon.
+			if views++; views == 1 && !strings.Contains(request, `StartDate="2024-05-06T09:00:00Z"`) {
+				t.Errorf("expected the calendar view to start with the series, got %s", request)
+			}
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:RootFolder IncludesLastItemInRange="true"><t:Items>` + occurrences.String() + `</t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		case strings.Contains(request, "<m:GetItem"):
+			_, _ = w.Write([]byte(occurrencesResponse(request, func(index int) string {
+				fetched = append(fetched, index)
+				return occurrenceMessage(index, "2024-05-20T09:00:00Z", "2024-05-20T10:00:00Z")
+			})))
+		default:
+			t.Errorf("unexpected request %s", request)
+		}
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+	}
+
+	new, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("getting appointments: %v", err)
+	}
+	if len(new) != 1 || len(new[0].Occurrences) != 3 {
+		t.Fatalf("expected a series of 3 occurrences, got %+v", new)
+	}
+	for i, occurrence := range new[0].Occurrences {
+		if occurrence.InstanceIndex != i+1 || occurrence.RoomBookings[0].ExchangeIDInResourceMailbox != fmt.Sprintf("occurrence%d", i+1) {
+			t.Errorf("occurrence %d: unexpected %+v", i, occurrence)
+		}
+	}
+	if fmt.Sprint(fetched) != "[3]" {
+		t.Errorf("expected only the last occurrence to be fetched, got %v", fetched)
+	}
+}
+
+func TestInitialImportExpandsSeriesIfListingFails(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	starts := map[int]string{1: "2024-05-06T09:00:00Z", 2: "2024-05-13T09:00:00Z", 3: "2024-05-20T09:00:00Z"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<m:SyncFolderItems"):
+			_, _ = w.Write([]byte(soapResponse(`<m:SyncFolderItemsResponse><m:ResponseMessages><m:SyncFolderItemsResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:SyncState>state</m:SyncState><m:IncludesLastItemInRange>true</m:IncludesLastItemInRange><m:Changes><t:Create><t:CalendarItem><t:ItemId Id="master" ChangeKey="a"/><t:UID>` + uid + `</t:UID><t:Subject>Weekly</t:Subject><t:Start>2024-05-06T09:00:00Z</t:Start><t:End>2024-05-06T10:00:00Z</t:End><t:Organizer><t:Mailbox><t:EmailAddress>john.doe@example.com</t:EmailAddress></t:Mailbox></t:Organizer><t:CalendarItemType>RecurringMaster</t:CalendarItemType></t:CalendarItem></t:Create></m:Changes></m:SyncFolderItemsResponseMessage></m:ResponseMessages></m:SyncFolderItemsResponse>`)))
+		case strings.Contains(request, "<m:FindItem"):
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Error"><m:ResponseCode>ErrorCalendarViewRangeTooBig</m:ResponseCode></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		case strings.Contains(request, "<m:GetItem"):
+			_, _ = w.Write([]byte(occurrencesResponse(request, func(index int) string {
+				start, ok := starts[index]
+				if !ok {
+					return outOfRecurrenceRange
+				}
+				return occurrenceMessage(index, start, strings.Replace(start, "T09", "T10", 1))
+			})))
+		default:
+			t.Errorf("unexpected request %s", request)
+		}
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+	}
+
+	new, _, _, _, _, err := h.GetRoomAppointments(1, "room1@example.com", "")
+	if err != nil {
+		t.Fatalf("expected the import to go on, got %v", err)
+	}
+	if len(new) != 1 || len(new[0].Occurrences) != 3 {
+		t.Fatalf("expected the series expanded to 3 occurrences, got %+v", new)
+	}
+}
+
+func TestListOccurrencesInWindowsExchangeAccepts(t *testing.T) {
+	start := time.Date(2019, 1, 7, 9, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dates := regexp.MustCompile(`StartDate="([^"]+)" EndDate="([^"]+)"`)
+	var windows [][2]time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		match := dates.FindStringSubmatch(string(body))
+		if match == nil {
+			t.Fatalf("expected a calendar view, got %s", body)
+		}
+		viewStart, _ := time.Parse(time.RFC3339, match[1])
+		viewEnd, _ := time.Parse(time.RFC3339, match[2])
+		windows = append(windows, [2]time.Time{viewStart, viewEnd})
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		item := fmt.Sprintf(`<t:CalendarItem><t:ItemId Id="occurrence%d" ChangeKey="a"/><t:UID>uid</t:UID><t:Start>%s</t:Start><t:End>%s</t:End><t:CalendarItemType>Occurrence</t:CalendarItemType></t:CalendarItem>`,
+			len(windows), viewStart.Format(time.RFC3339), viewStart.Add(time.Hour).Format(time.RFC3339))
+		_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:RootFolder IncludesLastItemInRange="true"><t:Items>` + item + `</t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{Client: server.Client(), EwsURL: server.URL, serviceUser: "service@example.com"}
+
+	listed, err := h.listOccurrences("room1@example.com", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 3 || !windows[0][0].Equal(start) || !windows[len(windows)-1][1].Equal(end) {
+		t.Fatalf("expected the interval listed in 3 windows, got %v", windows)
+	}
+	for i, window := range windows {
+		if window[1].Sub(window[0]) > maxCalendarViewRange {
+			t.Errorf("window %d spans %v, longer than Exchange accepts", i, window[1].Sub(window[0]))
+		}
+		if i > 0 && !window[0].Equal(windows[i-1][1]) {
+			t.Errorf("window %d starts at %v, not where the previous one ended", i, window[0])
+		}
+	}
+	if len(listed["uid"]) != 3 {
+		t.Errorf("expected the occurrences of all windows, got %+v", listed)
+	}
+}
+
+func TestModifiedOccurrenceKeepsItsTimes(t *testing.T) {
+	first := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	// The third occurrence of the weekly series was moved from Monday to
+	// Wednesday, the server still returning it at its original time.
+	moved := first.AddDate(0, 0, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		if strings.Contains(request, `<t:ItemId Id="master"`) {
+			if !strings.Contains(request, `FieldURI="calendar:ModifiedOccurrences"`) {
+				t.Errorf("expected the modified occurrences to be requested, got %s", request)
+			}
+			_, _ = w.Write([]byte(soapResponse(fmt.Sprintf(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items><t:CalendarItem><t:ItemId Id="master" ChangeKey="a"/><t:ModifiedOccurrences><t:Occurrence><t:ItemId Id="occurrence3" ChangeKey="b"/><t:Start>%s</t:Start><t:End>%s</t:End><t:OriginalStart>%s</t:OriginalStart></t:Occurrence></t:ModifiedOccurrences></t:CalendarItem></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`,
+				moved.Format(time.RFC3339), moved.Add(time.Hour).Format(time.RFC3339), first.AddDate(0, 0, 14).Format(time.RFC3339)))))
+			return
+		}
+		_, _ = w.Write([]byte(occurrencesResponse(request, func(index int) string {
+			if index > 4 {
+				return outOfRecurrenceRange
+			}
+			start := first.AddDate(0, 0, 7*(index-1))
+			return occurrenceMessage(index, start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339))
+		})))
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+	}
+
+	items, err := h.expandRecurrence("master", "room1@example.com", 0, first.AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatalf("expanding recurrence: %v", err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("expected 4 occurrences, got %d", len(items))
+	}
+	third := items[2]
+	if third.InstanceIndex != 3 || !third.Start.Equal(moved) || !third.End.Equal(moved.Add(time.Hour)) {
+		t.Errorf("expected the third occurrence on Wednesday, got %d at %v-%v", third.InstanceIndex, third.Start, third.End)
+	}
+}
+
+func TestExtendSeriesFetchesFollowingOccurrences(t *testing.T) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	first := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+	var fetched []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, `<t:RecurringMasterItemId OccurrenceId="occurrence"`):
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items><t:CalendarItem><t:ItemId Id="master" ChangeKey="a"/><t:UID>` + uid + `</t:UID><t:Subject>Weekly</t:Subject><t:Start>2030-01-07T09:00:00Z</t:Start><t:End>2030-01-07T10:00:00Z</t:End><t:Organizer><t:Mailbox><t:EmailAddress>john.doe@example.com</t:EmailAddress></t:Mailbox></t:Organizer><t:CalendarItemType>RecurringMaster</t:CalendarItemType></t:CalendarItem></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		case strings.Contains(request, "<m:GetItem"):
+			// A weekly series without an end.
+			_, _ = w.Write([]byte(occurrencesResponse(request, func(index int) string {
+				fetched = append(fetched, index)
+				start := first.AddDate(0, 0, 7*(index-1))
+				return occurrenceMessage(index, start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339))
+			})))
+		default:
+			t.Errorf("unexpected request %s", request)
+		}
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+	}
+
+	group, err := h.ExtendSeries(1, "room1@example.com", "occurrence", 2, first.AddDate(0, 0, 29))
+	if err != nil {
+		t.Fatalf("extending series: %v", err)
+	}
+	if group.ExchangeUID != uid || group.Subject != "Weekly" {
+		t.Errorf("expected the group of the series, got %+v", group)
+	}
+	var indices []int
+	for _, occurrence := range group.Occurrences {
+		indices = append(indices, occurrence.InstanceIndex)
+	}
+	if fmt.Sprint(indices) != "[3 4 5]" {
+		t.Errorf("expected the occurrences up to the horizon following the known ones, got %v", indices)
+	}
+	if len(fetched) != occurrenceBatchSize || fetched[0] != 3 {
+		t.Errorf("expected a single batch following the known occurrences, fetched %v", fetched)
+	}
+}
+
+func TestAttendeeBatches(t *testing.T) {
+	for _, tc := range []struct {
+		attendees int
+		size      int
+		want      []int
+	}{
+		{0, 10, []int{0}},
+		{10, 10, []int{10}},
+		{11, 10, []int{10, 1}},
+		{250, 0, []int{100, 100, 50}},
+	} {
+		attendees := make([]string, tc.attendees)
+		batches := attendeeBatches(attendees, tc.size)
+		got := make([]int, len(batches))
+		for i, batch := range batches {
+			got[i] = len(batch)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%d attendees in batches of %d: expected %v, got %v", tc.attendees, tc.size, tc.want, got)
+		}
+	}
+}
+
+func TestAddressCachePersistsAddresses(t *testing.T) {
+	var stored []appdb.ResolvedAddress
+	cache := newAddressCache(2)
+	cache.configID = 7
+	cache.store = func(address appdb.ResolvedAddress) error {
+		stored = append(stored, address)
+		return nil
+	}
+	cache.restore(func(configID int64, since time.Time, limit int) ([]appdb.ResolvedAddress, error) {
+		if configID != 7 || limit != 2 || time.Since(since) < addressTTL {
+			t.Errorf("expected addresses of config 7 resolved within the TTL, limited to the cache size; got %d, %v, %d", configID, since, limit)
+		}
+		return []appdb.ResolvedAddress{
+			{Dn: "/o=example/cn=new", Smtp: "new@example.com", ResolvedAt: time.Now().Add(-time.Hour)},
+			{Dn: "/o=example/cn=stale", Smtp: "stale@example.com", ResolvedAt: time.Now().Add(-addressTTL)},
+		}, nil
+	})
+	if smtp, ok := cache.get("/o=example/cn=new"); !ok || smtp != "new@example.com" {
+		t.Errorf("expected restored address, got %q", smtp)
+	}
+	if _, ok := cache.get("/o=example/cn=stale"); ok {
+		t.Error("expected address resolved longer ago than the TTL to be resolved again")
+	}
+	cache.put("/o=example/cn=a", "a@example.com")
+	if len(stored) != 1 || stored[0].ConfigurationID != 7 || stored[0].Dn != "/o=example/cn=a" || stored[0].Smtp != "a@example.com" || stored[0].ResolvedAt.IsZero() {
+		t.Errorf("expected resolved address written through, got %+v", stored)
+	}
+}
+
+func TestAddressCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	before := AddressCache()
+	cache := newAddressCache(2)
+	cache.put("/o=example/cn=a", "a@example.com")
+	cache.put("/o=example/cn=b", "b@example.com")
+	if _, ok := cache.get("/o=example/cn=a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	cache.put("/o=example/cn=c", "c@example.com")
+	if _, ok := cache.get("/o=example/cn=b"); ok {
+		t.Error("expected least recently used b to be evicted")
+	}
+	for _, name := range []string{"/o=example/cn=a", "/o=example/cn=c"} {
+		if _, ok := cache.get(name); !ok {
+			t.Errorf("expected %s to be cached", name)
+		}
+	}
+	if cache.len() != 2 {
+		t.Errorf("expected 2 cached addresses, got %d", cache.len())
+	}
+
+	after := AddressCache()
+	if hits := after.Hits - before.Hits; hits != 3 {
+		t.Errorf("expected 3 hits, got %d", hits)
+	}
+	if misses := after.Misses - before.Misses; misses != 1 {
+		t.Errorf("expected 1 miss, got %d", misses)
+	}
+	if evictions := after.Evictions - before.Evictions; evictions != 1 {
+		t.Errorf("expected 1 eviction, got %d", evictions)
+	}
+}
+
+func TestRedactRemovesCredentialsAndSubjects(t *testing.T) {
+	err := `creating event resulted in Error. Response: <t:Subject>Salary review</t:Subject><t:Password>hunter2</t:Password><t:Start>2024-05-01T10:00:00Z</t:Start>`
+	redacted := Redact(err)
+	for _, secret := range []string{"Salary review", "hunter2"} {
+		if strings.Contains(redacted, secret) {
+			t.Errorf("expected %q to be redacted, got %s", secret, redacted)
+		}
+	}
+	if !strings.Contains(redacted, "2024-05-01T10:00:00Z") {
+		t.Errorf("expected the start to be kept, got %s", redacted)
+	}
+}
+
+func TestRequestTimeoutIsApplied(t *testing.T) {
+	oauth := apiserver.Configuration{
+		ClientId:       common.Ptr("client"),
+		ClientSecret:   common.Ptr("secret"),
+		TenantId:       common.Ptr("tenant"),
+		RequestTimeout: common.Ptr(int32(5)),
+	}
+	h := NewEWSHelper(oauth, "service@example.com")
+	if h.Client.Timeout != 5*time.Second {
+		t.Errorf("expected a timeout of 5s, got %v", h.Client.Timeout)
+	}
+	if _, ok := h.Client.Transport.(*oauth2.Transport); !ok {
+		t.Errorf("expected the OAuth transport to be kept, got %T", h.Client.Transport)
+	}
+
+	ntlm := apiserver.Configuration{
+		EwsURL:   common.Ptr("https://mail.example.com/EWS/Exchange.asmx"),
+		Username: common.Ptr("user"),
+		Password: common.Ptr("password"),
+	}
+	if h := NewEWSHelper(ntlm, "service@example.com"); h.Client.Timeout != DefaultRequestTimeout {
+		t.Errorf("expected the default timeout, got %v", h.Client.Timeout)
+	}
+}
+
+func TestCloudSelectsEndpoints(t *testing.T) {
+	config := apiserver.Configuration{
+		ClientId:     common.Ptr("client"),
+		ClientSecret: common.Ptr("secret"),
+		TenantId:     common.Ptr("tenant"),
+	}
+	if h := NewEWSHelper(config, "service@example.com"); h.EwsURL != "https://outlook.office365.com/EWS/Exchange.asmx" {
+		t.Errorf("expected the commercial EWS URL by default, got %s", h.EwsURL)
+	}
+	config.Cloud = common.Ptr(string(syncmodel.CloudGCCHigh))
+	if h := NewEWSHelper(config, "service@example.com"); h.EwsURL != "https://outlook.office365.us/EWS/Exchange.asmx" {
+		t.Errorf("expected the GCC High EWS URL, got %s", h.EwsURL)
+	}
+
+	china := cloudEndpointsOf(syncmodel.CloudChina)
+	if got, want := china.tokenURL("tenant"), "https://login.chinacloudapi.cn/tenant/oauth2/v2.0/token"; got != want {
+		t.Errorf("expected token URL %s, got %s", want, got)
+	}
+	if got, want := china.scope(), "https://partner.outlook.cn/.default"; got != want {
+		t.Errorf("expected scope %s, got %s", want, got)
+	}
+}
+
+func TestRequestsGoThroughConfiguredProxy(t *testing.T) {
+	var requested, proxyAuth string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		proxyAuth = r.Header.Get("Proxy-Authorization")
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyURL.User = url.UserPassword("user", "pass")
+
+	config := apiserver.Configuration{
+		EwsURL:   common.Ptr("http://mail.example.com/EWS/Exchange.asmx"),
+		Username: common.Ptr("user"),
+		Password: common.Ptr("password"),
+		ProxyURL: common.Ptr(proxyURL.String()),
+	}
+	h := NewEWSHelper(config, "service@example.com")
+	resp, err := h.Client.Get(h.EwsURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if requested != "http://mail.example.com/EWS/Exchange.asmx" {
+		t.Errorf("expected the request to go through the proxy, got %q", requested)
+	}
+	if want := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass")); proxyAuth != want {
+		t.Errorf("expected proxy authorization %q, got %q", want, proxyAuth)
+	}
+}
+
+func TestNTLMTrustsConfiguredCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	ntlm := func() apiserver.Configuration {
+		return apiserver.Configuration{
+			EwsURL:   common.Ptr(server.URL),
+			Username: common.Ptr("user"),
+			Password: common.Ptr("password"),
+		}
+	}
+	caCertPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	for name, tc := range map[string]struct {
+		configure func(*apiserver.Configuration)
+		wantErr   bool
+	}{
+		"system CAs":     {func(*apiserver.Configuration) {}, true},
+		"configured CA":  {func(c *apiserver.Configuration) { c.TLSCACertPEM = &caCertPEM }, false},
+		"skipped verify": {func(c *apiserver.Configuration) { c.InsecureSkipVerify = common.Ptr(true) }, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := ntlm()
+			tc.configure(&config)
+			resp, err := NewEWSHelper(config, "service@example.com").Client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHelpersShareTransports(t *testing.T) {
+	ntlm := func(username string) apiserver.Configuration {
+		return apiserver.Configuration{
+			EwsURL:   common.Ptr("https://mail.example.com/EWS/Exchange.asmx"),
+			Username: common.Ptr(username),
+			Password: common.Ptr("password"),
+		}
+	}
+	transport := func(h *EWSHelper) http.RoundTripper {
+		return h.Client.Transport.(ntlmssp.Negotiator).RoundTripper
+	}
+	first := NewEWSHelper(ntlm("user"), "organizer1@example.com")
+	second := NewEWSHelper(ntlm("user"), "organizer2@example.com")
+	if transport(first) != transport(second) {
+		t.Error("expected helpers of the same account to share the transport")
+	}
+	if transport(first).(*http.Transport).MaxIdleConnsPerHost != maxIdleConnsPerHost {
+		t.Errorf("expected %d idle connections per host, got %d", maxIdleConnsPerHost, transport(first).(*http.Transport).MaxIdleConnsPerHost)
+	}
+	if other := NewEWSHelper(ntlm("other"), "organizer1@example.com"); transport(other) == transport(first) {
+		t.Error("expected NTLM accounts not to share authenticated connections")
+	}
+	proxied := ntlm("user")
+	proxied.ProxyURL = common.Ptr("http://proxy.example.com:3128")
+	if h := NewEWSHelper(proxied, "organizer1@example.com"); transport(h) == transport(first) {
+		t.Error("expected a proxied transport of its own")
+	}
+}
+
+func TestHungServerTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	client := server.Client()
+	client.Timeout = 50 * time.Millisecond
+	h := &EWSHelper{Client: client, EwsURL: server.URL}
+
+	_, err := h.sendRequest(serverTimeZonesRequest)
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+}
+
+func TestHelpersAreReusedUntilConfigChanges(t *testing.T) {
+	config := apiserver.Configuration{
+		Id:       common.Ptr(int64(-1004)),
+		EwsURL:   common.Ptr("https://mail.example.com/EWS/Exchange.asmx"),
+		Username: common.Ptr("user"),
+		Password: common.Ptr("password"),
+	}
+	reader := NewEWSHelper(config, "reader@example.com")
+	organizer := NewEWSHelper(config, "organizer@example.com")
+	if reader.Client != organizer.Client {
+		t.Error("expected the helpers to share the HTTP client")
+	}
+	if reader.serviceUser != "reader@example.com" || organizer.serviceUser != "organizer@example.com" {
+		t.Errorf("expected each helper to impersonate its user, got %s and %s", reader.serviceUser, organizer.serviceUser)
+	}
+	organizer.SetCorrelationID("correlation")
+	if reader.correlationID != "" {
+		t.Error("expected the correlation ID to stay with its helper")
+	}
+
+	config.Password = common.Ptr("changed")
+	changed := NewEWSHelper(config, "reader@example.com")
+	if changed.Client == reader.Client || changed.password != "changed" {
+		t.Error("expected a new helper after the configuration changed")
+	}
+
+	ForgetHelper(*config.Id)
+	if NewEWSHelper(config, "reader@example.com").Client == changed.Client {
+		t.Error("expected a new helper after the cached one was dropped")
+	}
+}
+
+func TestFailedAutodiscoveryIsRetriedAfterBackoff(t *testing.T) {
+	config := apiserver.Configuration{
+		Id:       common.Ptr(int64(-1007)),
+		Username: common.Ptr("user"),
+		Password: common.Ptr("password"),
+		// Fails autodiscovery without any request.
+		ServiceUserUPN: common.Ptr("service"),
+	}
+	defer ForgetHelper(*config.Id)
+	now := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	failed := helperTemplate(config, now)
+	if failed.ewsURLErr == nil {
+		t.Fatal("expected autodiscovery to fail")
+	}
+	if again := helperTemplate(config, now.Add(minDiscoveryBackoff-time.Second)); again != failed {
+		t.Error("expected the failure to be reused during its backoff")
+	}
+	retried := helperTemplate(config, now.Add(minDiscoveryBackoff))
+	if retried == failed {
+		t.Fatal("expected autodiscovery to be retried after the backoff")
+	}
+	if cached := helpers[*config.Id]; cached.failures != 2 || !cached.retryAt.Equal(now.Add(3*minDiscoveryBackoff)) {
+		t.Errorf("expected the backoff to double, got retry at %v after %d failures", cached.retryAt, cached.failures)
+	}
+
+	ForgetHelper(*config.Id)
+	if _, ok := helpers[*config.Id]; ok {
+		t.Error("expected the helper to be dropped")
+	}
+}
+
+const serverBusyFault = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <s:Fault>
+      <faultcode xmlns:a="http://schemas.microsoft.com/exchange/services/2006/types">a:ErrorServerBusy</faultcode>
+      <faultstring xml:lang="en-US">The server cannot service this request right now. Try again later.</faultstring>
+      <detail>
+        <e:ResponseCode xmlns:e="http://schemas.microsoft.com/exchange/services/2006/errors">ErrorServerBusy</e:ResponseCode>
+        <e:Message xmlns:e="http://schemas.microsoft.com/exchange/services/2006/errors">The server cannot service this request right now. Try again later.</e:Message>
+        <t:MessageXml xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
+          <t:Value Name="BackOffMilliseconds">1500</t:Value>
+        </t:MessageXml>
+      </detail>
+    </s:Fault>
+  </s:Body>
+</s:Envelope>`
+
+// throttledServer answers with the given throttling responses before
+// answering normally.
+func throttledServer(t *testing.T, throttled ...func(w http.ResponseWriter)) (*EWSHelper, *[]time.Duration) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= len(throttled) {
+			throttled[requests-1](w)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		_, _ = w.Write([]byte(getRoomsWithMalformedRoom))
+	}))
+	t.Cleanup(server.Close)
+	var backOffs []time.Duration
+	throttleSleep = func(d time.Duration) { backOffs = append(backOffs, d) }
+	t.Cleanup(func() { throttleSleep = time.Sleep })
+	return &EWSHelper{Client: server.Client(), EwsURL: server.URL, addressCache: newAddressCache(DefaultAddressCacheSize)}, &backOffs
+}
+
+func TestThrottledRequestsAreRetried(t *testing.T) {
+	h, backOffs := throttledServer(t,
+		func(w http.ResponseWriter) {
+			w.Header().Set("x-ms-diagnostics", `2000003;reason="Too many requests";BackOffMilliseconds=2000`)
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+		func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(serverBusyFault))
+		},
+		func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+	)
+	if _, err := h.sendRequest(serverTimeZonesRequest); err != nil {
+		t.Fatalf("expected the request to succeed after the back-offs, got %v", err)
+	}
+	want := []time.Duration{2 * time.Second, 1500 * time.Millisecond, defaultThrottleBackOff}
+	if fmt.Sprint(*backOffs) != fmt.Sprint(want) {
+		t.Errorf("expected back-offs %v, got %v", want, *backOffs)
+	}
+}
+
+func TestThrottlingGivesUp(t *testing.T) {
+	busy := func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	h, backOffs := throttledServer(t, busy, busy, busy, busy)
+	if _, err := h.sendRequest(serverTimeZonesRequest); !errors.Is(err, ErrThrottled) {
+		t.Fatalf("expected ErrThrottled, got %v", err)
+	}
+	if len(*backOffs) != maxThrottleRetries || (*backOffs)[0] != time.Second {
+		t.Errorf("expected %d back-offs of 1s, got %v", maxThrottleRetries, *backOffs)
+	}
+}
+
+func TestAutodiscoverFallsBackToPOX(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".svc") {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?>
+<Autodiscover xmlns="http://schemas.microsoft.com/exchange/autodiscover/responseschema/2006">
+  <Response xmlns="http://schemas.microsoft.com/exchange/autodiscover/outlook/responseschema/2006a">
+    <Account>
+      <Protocol><Type>EXPR</Type><EwsUrl>https://mail.example.com/EWS/Exchange.asmx</EwsUrl></Protocol>
+      <Protocol><Type>EXCH</Type><EwsUrl>https://exch01.example.local/EWS/Exchange.asmx</EwsUrl></Protocol>
+    </Account>
+  </Response>
+</Autodiscover>`)
+	}))
+	defer server.Close()
+
+	urls := []string{server.URL + "/autodiscover/autodiscover.svc", server.URL + "/autodiscover/autodiscover.xml"}
+	ewsURL, err := autodiscover(server.Client(), urls, "room@example.com", "user", "password")
+	if err != nil {
+		t.Fatalf("autodiscover: %v", err)
+	}
+	if ewsURL != "https://exch01.example.local/EWS/Exchange.asmx" {
+		t.Errorf("expected the internal EWS URL, got %s", ewsURL)
+	}
+}
+
+func TestAutodiscoverSOAP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "user" || password != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <GetUserSettingsResponseMessage xmlns="http://schemas.microsoft.com/exchange/2010/Autodiscover">
+      <Response>
+        <ErrorCode>NoError</ErrorCode>
+        <UserResponses>
+          <UserResponse>
+            <ErrorCode>NoError</ErrorCode>
+            <UserSettings>
+              <UserSetting><Name>ExternalEwsUrl</Name><Value>https://mail.example.com/EWS/Exchange.asmx</Value></UserSetting>
+            </UserSettings>
+          </UserResponse>
+        </UserResponses>
+      </Response>
+    </GetUserSettingsResponseMessage>
+  </s:Body>
+</s:Envelope>`)
+	}))
+	defer server.Close()
+
+	urls := []string{server.URL + "/autodiscover/autodiscover.svc"}
+	ewsURL, err := autodiscover(server.Client(), urls, "room@example.com", "user", "password")
+	if err != nil {
+		t.Fatalf("autodiscover: %v", err)
+	}
+	if ewsURL != "https://mail.example.com/EWS/Exchange.asmx" {
+		t.Errorf("expected the external EWS URL without an internal one, got %s", ewsURL)
+	}
+	if _, err := autodiscover(server.Client(), urls, "room@example.com", "user", "wrong"); err == nil {
+		t.Error("expected rejected credentials to fail autodiscovery")
+	}
+}
+
+const getEventsResponse = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <m:GetEventsResponse xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types">
+      <m:ResponseMessages>
+        <m:GetEventsResponseMessage ResponseClass="Success">
+          <m:ResponseCode>NoError</m:ResponseCode>
+          <m:Notification>
+            <t:SubscriptionId>sub1</t:SubscriptionId>
+            <t:PreviousWatermark>wm1</t:PreviousWatermark>
+            <t:MoreEvents>false</t:MoreEvents>
+            %s
+          </m:Notification>
+        </m:GetEventsResponseMessage>
+      </m:ResponseMessages>
+    </m:GetEventsResponse>
+  </s:Body>
+</s:Envelope>`
+
+func TestGetEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		events    string
+		changed   bool
+		watermark string
+	}{
+		{"status only", `<t:StatusEvent><t:Watermark>wm2</t:Watermark></t:StatusEvent>`, false, "wm2"},
+		{"item created", `<t:CreatedEvent><t:Watermark>wm2</t:Watermark><t:ItemId Id="item1"/></t:CreatedEvent>
+            <t:ModifiedEvent><t:Watermark>wm3</t:Watermark><t:ItemId Id="item1"/></t:ModifiedEvent>`, true, "wm3"},
+	}
+	for _, tt := range tests {
+		h := newTestHelper(t, fmt.Sprintf(getEventsResponse, tt.events))
+		changed, watermark, err := h.GetEvents("room1@example.com", "sub1", "wm1")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if changed != tt.changed || watermark != tt.watermark {
+			t.Errorf("%s: expected changed %t at %s, got %t at %s", tt.name, tt.changed, tt.watermark, changed, watermark)
+		}
+	}
+}
+
+func TestGetEventsOfExpiredSubscription(t *testing.T) {
+	h := newTestHelper(t, `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <m:GetEventsResponse xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
+      <m:ResponseMessages>
+        <m:GetEventsResponseMessage ResponseClass="Error">
+          <m:MessageText>The specified subscription was not found.</m:MessageText>
+          <m:ResponseCode>ErrorSubscriptionNotFound</m:ResponseCode>
+        </m:GetEventsResponseMessage>
+      </m:ResponseMessages>
+    </m:GetEventsResponse>
+  </s:Body>
+</s:Envelope>`)
+	if _, _, err := h.GetEvents("room1@example.com", "sub1", "wm1"); !errors.Is(err, ErrSubscriptionLost) {
+		t.Errorf("expected ErrSubscriptionLost, got %v", err)
+	}
+}
+
+// createWeeklyAppointment books a weekly series of 3 occurrences in a room
+// whose mailbox answers for the occurrences with occurrence, returning the
+// CreateItem request.
+func createWeeklyAppointment(t *testing.T, occurrence func(index int, start time.Time) string) (string, []RoomResult, error) {
+	const uid = "040000008200E00074C5B7101A82E008"
+	first := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	var created string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := string(body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		switch {
+		case strings.Contains(request, "<m:CreateItem"):
+			created = request
+			_, _ = w.Write([]byte(createItemResponse("Success", "NoError", "master")))
+		case strings.Contains(request, "<m:FindItem"):
+			_, _ = w.Write([]byte(soapResponse(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:RootFolder><t:Items><t:CalendarItem><t:ItemId Id="room-master" ChangeKey="ck"/></t:CalendarItem></t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>`)))
+		case strings.Contains(request, "<t:OccurrenceItemId") || !strings.Contains(request, "calendar:UID"):
+			_, _ = w.Write([]byte(occurrencesResponse(request, func(index int) string {
+				if index > 3 {
+					return outOfRecurrenceRange
+				}
+				return occurrence(index, first.AddDate(0, 0, 7*(index-1)))
+			})))
+		default:
+			_, _ = w.Write([]byte(soapResponse(`<m:GetItemResponse><m:ResponseMessages><m:GetItemResponseMessage ResponseClass="Success"><m:Items><t:CalendarItem><t:UID>` + uid + `</t:UID></t:CalendarItem></m:Items></m:GetItemResponseMessage></m:ResponseMessages></m:GetItemResponse>`)))
+		}
+	}))
+	t.Cleanup(server.Close)
+	h := &EWSHelper{
+		Client:       server.Client(),
+		EwsURL:       server.URL,
+		serviceUser:  "service@example.com",
+		addressCache: newAddressCache(DefaultAddressCacheSize),
+		invitations:  &invitationDelivery{},
+	}
+
+	_, results, err := h.CreateAppointment(Appointment{
+		ElionaID:   1,
+		Organizer:  "organizer@example.com",
+		Start:      first,
+		End:        first.Add(time.Hour),
+		Location:   "room1@example.com",
+		Resources:  []string{"room1@example.com"},
+		Recurrence: &Recurrence{Pattern: RecurWeekly, Interval: 1, Count: 3},
+	})
+	return created, results, err
+}
+
+func TestCreateRecurringAppointment(t *testing.T) {
+	created, results, err := createWeeklyAppointment(t, func(index int, start time.Time) string {
+		return occurrenceMessage(index, start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339))
+	})
+	if err != nil {
+		t.Fatalf("creating appointment: %v", err)
+	}
+	for _, want := range []string{
+		`<t:Recurrence><t:WeeklyRecurrence><t:Interval>1</t:Interval><t:DaysOfWeek>Monday</t:DaysOfWeek></t:WeeklyRecurrence>`,
+		`<t:NumberedRecurrence><t:StartDate>2024-05-06</t:StartDate><t:NumberOfOccurrences>3</t:NumberOfOccurrences></t:NumberedRecurrence></t:Recurrence>`,
+	} {
+		if !strings.Contains(created, want) {
+			t.Errorf("expected the request to contain %s", want)
+		}
+	}
+	if len(results) != 1 || results[0].ResourceEventID != "room-master" || fmt.Sprint(results[0].OccurrenceEventIDs) != "[occurrence1 occurrence2 occurrence3]" {
+		t.Errorf("expected the room's occurrences, got %+v", results)
+	}
+}
+
+func TestCreateRecurringAppointmentWithDeletedOccurrence(t *testing.T) {
+	_, results, err := createWeeklyAppointment(t, func(index int, start time.Time) string {
+		if index == 2 {
+			return deletedFromRecurrence
+		}
+		return occurrenceMessage(index, start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339))
+	})
+	if err != nil {
+		t.Fatalf("creating appointment: %v", err)
+	}
+	if len(results) != 1 || results[0].ResourceEventID != "room-master" || fmt.Sprint(results[0].OccurrenceEventIDs) != "[occurrence1  occurrence3]" {
+		t.Errorf("expected no ID for the deleted occurrence, got %+v", results)
+	}
+}
+
+func TestCreateRecurringAppointmentKeepsSeriesWithoutOccurrences(t *testing.T) {
+	_, results, err := createWeeklyAppointment(t, func(int, time.Time) string {
+		return `<m:GetItemResponseMessage ResponseClass="Error"><m:ResponseCode>ErrorInternalServerError</m:ResponseCode></m:GetItemResponseMessage>`
+	})
+	if err != nil {
+		t.Fatalf("expected the series to be kept, got %v", err)
+	}
+	if len(results) != 1 || results[0].ResourceEventID != "room-master" || len(results[0].OccurrenceEventIDs) != 0 {
+		t.Errorf("expected the room's series without occurrences, got %+v", results)
+	}
+}
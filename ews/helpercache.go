@@ -0,0 +1,118 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"encoding/json"
+	"ews/apiserver"
+	"sync"
+	"time"
+)
+
+// Backoffs before autodiscovering the EWS URL of a configuration again after
+// it failed, doubling with every failure in a row.
+const (
+	minDiscoveryBackoff = time.Minute
+	maxDiscoveryBackoff = time.Hour
+)
+
+// cachedHelper is the helper of a configuration reused across operations.
+type cachedHelper struct {
+	// fingerprint of the configuration the helper was built from.
+	fingerprint string
+	// built is closed once helper is set. Building may autodiscover the EWS
+	// URL, which takes a while, so it's done outside of helpersMu and only
+	// once for all operations needing the helper.
+	built  chan struct{}
+	helper *EWSHelper
+	// failures counts the autodiscoveries in a row which failed, until
+	// retryAt.
+	failures int
+	retryAt  time.Time
+}
+
+var helpersMu sync.Mutex
+var helpers = make(map[int64]*cachedHelper)
+
+// cachedHelperFor returns a copy of the helper cached for the configuration,
+// impersonating the user. The helper is built again once the configuration
+// changes. Copies share the HTTP client, so that OAuth tokens are reused
+// until they expire instead of being acquired by every sync, while whatever
+// is set per operation, like the correlation ID, stays with the copy.
+func cachedHelperFor(config apiserver.Configuration, impersonationUser string) *EWSHelper {
+	helper := *helperTemplate(config, time.Now())
+	helper.serviceUser = impersonationUser
+	return &helper
+}
+
+func helperTemplate(config apiserver.Configuration, now time.Time) *EWSHelper {
+	// Marshalling the plain configuration struct can't fail.
+	fingerprint, _ := json.Marshal(config)
+	helpersMu.Lock()
+	cached, ok := helpers[*config.Id]
+	if !ok || cached.fingerprint != string(fingerprint) || cached.expired(now) {
+		previous := cached
+		cached = &cachedHelper{fingerprint: string(fingerprint), built: make(chan struct{})}
+		helpers[*config.Id] = cached
+		helpersMu.Unlock()
+		cached.build(config, previous, now)
+	} else {
+		helpersMu.Unlock()
+	}
+	<-cached.built
+	return cached.helper
+}
+
+// build builds the helper. A failed autodiscovery is retried with the next
+// helper after a backoff, which grows if the helper it replaces failed too.
+func (c *cachedHelper) build(config apiserver.Configuration, previous *cachedHelper, now time.Time) {
+	defer close(c.built)
+	c.helper = newEWSHelper(config, "")
+	if c.helper.ewsURLErr == nil {
+		return
+	}
+	c.failures = 1
+	if previous != nil && previous.fingerprint == c.fingerprint {
+		c.failures = previous.failures + 1
+	}
+	backoff := minDiscoveryBackoff
+	for i := 1; i < c.failures && backoff < maxDiscoveryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxDiscoveryBackoff {
+		backoff = maxDiscoveryBackoff
+	}
+	c.retryAt = now.Add(backoff)
+}
+
+// expired reports whether the helper failed to autodiscover the EWS URL and
+// its backoff passed. Helpers still being built are waited for instead.
+func (c *cachedHelper) expired(now time.Time) bool {
+	select {
+	case <-c.built:
+		return c.failures > 0 && !now.Before(c.retryAt)
+	default:
+		return false
+	}
+}
+
+// ForgetHelper drops the helper cached for the configuration, e.g. once it is
+// changed or deleted, so that it doesn't keep its credentials in memory.
+func ForgetHelper(configID int64) {
+	helpersMu.Lock()
+	defer helpersMu.Unlock()
+	delete(helpers, configID)
+}
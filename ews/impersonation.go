@@ -0,0 +1,47 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	syncmodel "ews/model/sync"
+)
+
+// IdentityType is the element identifying the impersonated user in the
+// ConnectingSID of a request.
+type IdentityType string
+
+const (
+	IdentityPrincipalName IdentityType = "PrincipalName"
+	IdentitySmtpAddress   IdentityType = "SmtpAddress"
+	// IdentitySID identifies the user by its security identifier, for
+	// environments where only the SID is known, e.g. from directory sync.
+	IdentitySID IdentityType = "SID"
+)
+
+// Impersonation is the user a request is sent on behalf of.
+type Impersonation struct {
+	Type IdentityType
+	ID   string
+}
+
+// impersonate identifies the user the way the operation prefers, unless the
+// user is given as a SID, which can only be impersonated by SID.
+func impersonate(preferred IdentityType, user string) Impersonation {
+	if syncmodel.IsSID(user) {
+		return Impersonation{Type: IdentitySID, ID: user}
+	}
+	return Impersonation{Type: preferred, ID: user}
+}
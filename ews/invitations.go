@@ -0,0 +1,101 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"ews/apiserver"
+	"sync"
+
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+// Values of CreateItem's SendMeetingInvitations and UpdateItem's
+// SendMeetingInvitationsOrCancellations attributes.
+const (
+	sendToAllAndSaveCopy     = "SendToAllAndSaveCopy"
+	sendToChangedAndSaveCopy = "SendToChangedAndSaveCopy"
+	sendToNone               = "SendToNone"
+)
+
+// invitationDelivery counts consecutive failures to deliver meeting
+// invitations of a configuration. Once the limit is reached, events are
+// created without sending invitations.
+type invitationDelivery struct {
+	mu       sync.Mutex
+	limit    int32
+	failures int32
+}
+
+var deliveriesMu sync.Mutex
+var deliveries = make(map[int64]*invitationDelivery)
+
+// deliveryFor returns the delivery state shared by all helpers of a
+// configuration. Helpers are created per operation, so it must outlive them.
+func deliveryFor(config apiserver.Configuration) *invitationDelivery {
+	limit := int32(0)
+	if config.InvitationFailureLimit != nil && *config.InvitationFailureLimit > 0 {
+		limit = *config.InvitationFailureLimit
+	}
+	if config.Id == nil {
+		return &invitationDelivery{limit: limit}
+	}
+
+	deliveriesMu.Lock()
+	defer deliveriesMu.Unlock()
+	delivery, ok := deliveries[*config.Id]
+	if !ok {
+		delivery = &invitationDelivery{}
+		deliveries[*config.Id] = delivery
+	}
+	delivery.mu.Lock()
+	defer delivery.mu.Unlock()
+	if delivery.limit != limit {
+		// Configuration has changed, give the invitations another chance.
+		delivery.limit = limit
+		delivery.failures = 0
+	}
+	return delivery
+}
+
+// sendMeetingInvitations returns how invitations of new events are sent.
+func (d *invitationDelivery) sendMeetingInvitations() string {
+	if d == nil {
+		return sendToAllAndSaveCopy
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.limit > 0 && d.failures >= d.limit {
+		return sendToNone
+	}
+	return sendToAllAndSaveCopy
+}
+
+// record notes whether the invitations of a new event were delivered.
+func (d *invitationDelivery) record(delivered bool) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if delivered {
+		d.failures = 0
+		return
+	}
+	d.failures++
+	if d.limit > 0 && d.failures == d.limit {
+		log.Warn("ews", "delivering meeting invitations failed %d times in a row; creating events without sending invitations", d.failures)
+	}
+}
@@ -0,0 +1,69 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"context"
+	"ews/apiserver"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultRequestsPerMinute stays well below the default EWS throttling
+// policy of Exchange Online, leaving room for other clients of the same
+// service account.
+const DefaultRequestsPerMinute = 300
+
+// RateLimiter blocks until a request may be sent.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+var limitersMu sync.Mutex
+var limiters = make(map[int64]*rate.Limiter)
+
+// limiterFor returns the token bucket shared by all helpers of a configuration.
+// Helpers are created per operation, so the bucket must outlive them.
+func limiterFor(config apiserver.Configuration) RateLimiter {
+	requestsPerMinute := DefaultRequestsPerMinute
+	if config.RequestsPerMinute != nil && *config.RequestsPerMinute > 0 {
+		requestsPerMinute = int(*config.RequestsPerMinute)
+	}
+	limit := rate.Limit(float64(requestsPerMinute) / 60)
+	// Allow short bursts of a few seconds' worth of requests.
+	burst := requestsPerMinute / 20
+	if burst < 1 {
+		burst = 1
+	}
+
+	if config.Id == nil {
+		return rate.NewLimiter(limit, burst)
+	}
+
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	limiter, ok := limiters[*config.Id]
+	if !ok {
+		limiter = rate.NewLimiter(limit, burst)
+		limiters[*config.Id] = limiter
+	} else if limiter.Limit() != limit || limiter.Burst() != burst {
+		// Configuration has changed.
+		limiter.SetLimit(limit)
+		limiter.SetBurst(burst)
+	}
+	return limiter
+}
@@ -0,0 +1,80 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"fmt"
+	"time"
+)
+
+// RecurrencePattern is the unit a recurring appointment repeats in.
+type RecurrencePattern string
+
+const (
+	RecurDaily   RecurrencePattern = "daily"
+	RecurWeekly  RecurrencePattern = "weekly"
+	RecurMonthly RecurrencePattern = "monthly"
+)
+
+// Recurrence makes an appointment repeat, starting with the occurrence at the
+// appointment's start. Weekly occurrences fall on the weekday of the start,
+// monthly ones on its day of the month, both in UTC like the start.
+type Recurrence struct {
+	Pattern RecurrencePattern
+	// Interval between occurrences in units of the pattern, e.g. 2 for
+	// every other week.
+	Interval int
+	// Count of occurrences. Zero repeats until the date of Until.
+	Count int
+	Until time.Time
+}
+
+// end returns a time after the start of the last occurrence.
+func (r Recurrence) end(start time.Time) time.Time {
+	if r.Count == 0 {
+		return r.Until.AddDate(0, 0, 1)
+	}
+	// No pattern repeats less often than monthly.
+	return start.AddDate(0, r.Count*r.Interval+1, 0)
+}
+
+// request returns the recurrence element of an item starting at start, with
+// days counted in the time zone of start.
+func (r Recurrence) request(start time.Time) (*requestRecurrence, error) {
+	if r.Interval <= 0 {
+		return nil, fmt.Errorf("invalid recurrence interval %d", r.Interval)
+	}
+	var recurrence requestRecurrence
+	switch r.Pattern {
+	case RecurDaily:
+		recurrence.Daily = &intervalRecurrence{Interval: r.Interval}
+	case RecurWeekly:
+		recurrence.Weekly = &weeklyRecurrence{Interval: r.Interval, DaysOfWeek: start.Weekday().String()}
+	case RecurMonthly:
+		recurrence.Monthly = &monthlyRecurrence{Interval: r.Interval, DayOfMonth: start.Day()}
+	default:
+		return nil, fmt.Errorf("unsupported recurrence pattern %q", r.Pattern)
+	}
+	startDate := start.Format(time.DateOnly)
+	if r.Count > 0 {
+		recurrence.Numbered = &numberedRecurrence{StartDate: startDate, NumberOfOccurrences: r.Count}
+	} else if !r.Until.IsZero() {
+		recurrence.EndDate = &endDateRecurrence{StartDate: startDate, EndDate: r.Until.In(start.Location()).Format(time.DateOnly)}
+	} else {
+		return nil, fmt.Errorf("recurrence without count or end")
+	}
+	return &recurrence, nil
+}
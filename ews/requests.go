@@ -0,0 +1,407 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// Outbound requests are marshaled from the structs below. Elements are named
+// with the prefixes the envelope declares, as encoding/xml can't choose
+// prefixes for namespaces itself.
+
+type requestEnvelope struct {
+	XMLName    xml.Name      `xml:"soap:Envelope"`
+	SOAPNS     string        `xml:"xmlns:soap,attr"`
+	TypesNS    string        `xml:"xmlns:t,attr"`
+	MessagesNS string        `xml:"xmlns:m,attr"`
+	Header     requestHeader `xml:"soap:Header"`
+	Operation  any           `xml:"soap:Body>operation"`
+}
+
+type requestHeader struct {
+	ServerVersion struct {
+		Version string `xml:"Version,attr"`
+	} `xml:"t:RequestServerVersion"`
+	Impersonation impersonationIdentity `xml:"t:ExchangeImpersonation>t:ConnectingSID>identity"`
+	// TimeZoneContext makes Exchange return times in UTC rather than in the
+	// time zone of the mailbox.
+	TimeZoneContext struct {
+		ID string `xml:"Id,attr"`
+	} `xml:"t:TimeZoneContext>t:TimeZoneDefinition"`
+}
+
+// impersonationIdentity is named after its IdentityType.
+type impersonationIdentity struct {
+	XMLName xml.Name
+	ID      string `xml:",chardata"`
+}
+
+// marshalRequest renders the SOAP envelope of the operation sent on behalf
+// of the impersonated user. The operation names itself by its XMLName.
+func marshalRequest(impersonation Impersonation, operation any) (string, error) {
+	env := requestEnvelope{
+		SOAPNS:     "http://schemas.xmlsoap.org/soap/envelope/",
+		TypesNS:    "http://schemas.microsoft.com/exchange/services/2006/types",
+		MessagesNS: "http://schemas.microsoft.com/exchange/services/2006/messages",
+		Operation:  operation,
+	}
+	env.Header.ServerVersion.Version = "Exchange2013_SP1"
+	env.Header.TimeZoneContext.ID = "UTC"
+	env.Header.Impersonation = impersonationIdentity{
+		XMLName: xml.Name{Local: "t:" + string(impersonation.Type)},
+		ID:      impersonation.ID,
+	}
+	requestXML, err := xml.Marshal(env)
+	if err != nil {
+		return "", fmt.Errorf("marshaling request: %v", err)
+	}
+	return string(requestXML), nil
+}
+
+type itemShape struct {
+	BaseShape            string                `xml:"t:BaseShape"`
+	AdditionalProperties *additionalProperties `xml:"t:AdditionalProperties"`
+}
+
+type additionalProperties struct {
+	FieldURIs         []fieldURI         `xml:"t:FieldURI"`
+	ExtendedFieldURIs []extendedFieldURI `xml:"t:ExtendedFieldURI"`
+}
+
+type fieldURI struct {
+	FieldURI string `xml:"FieldURI,attr"`
+}
+
+// fieldURIs lists the properties by their URIs.
+func fieldURIs(uris ...string) []fieldURI {
+	fields := make([]fieldURI, len(uris))
+	for i, uri := range uris {
+		fields[i] = fieldURI{FieldURI: uri}
+	}
+	return fields
+}
+
+type extendedFieldURI struct {
+	PropertySetID string `xml:"PropertySetId,attr"`
+	PropertyName  string `xml:"PropertyName,attr,omitempty"`
+	PropertyID    string `xml:"PropertyId,attr,omitempty"`
+	PropertyType  string `xml:"PropertyType,attr"`
+}
+
+// elionaIDField is the extended property tagging events created by the app.
+var elionaIDField = extendedFieldURI{PropertySetID: elionaIDPropertySetID, PropertyName: elionaIDPropertyName, PropertyType: "Integer"}
+
+type distinguishedFolderID struct {
+	ID      string          `xml:"Id,attr"`
+	Mailbox *requestMailbox `xml:"t:Mailbox"`
+}
+
+type requestMailbox struct {
+	EmailAddress string `xml:"t:EmailAddress"`
+}
+
+// calendarOf is the calendar folder of the mailbox, or of the impersonated
+// user if the mailbox is empty.
+func calendarOf(mailbox string) distinguishedFolderID {
+	folder := distinguishedFolderID{ID: "calendar"}
+	if mailbox != "" {
+		folder.Mailbox = &requestMailbox{EmailAddress: mailbox}
+	}
+	return folder
+}
+
+type syncFolderItemsRequest struct {
+	XMLName            xml.Name              `xml:"m:SyncFolderItems"`
+	ItemShape          itemShape             `xml:"m:ItemShape"`
+	SyncFolderID       distinguishedFolderID `xml:"m:SyncFolderId>t:DistinguishedFolderId"`
+	SyncState          string                `xml:"m:SyncState"`
+	MaxChangesReturned int                   `xml:"m:MaxChangesReturned"`
+}
+
+// getItemRequest gets items by their ID, occurrences by their index, or the
+// recurring master of an occurrence.
+type getItemRequest struct {
+	XMLName               xml.Name               `xml:"m:GetItem"`
+	ItemShape             itemShape              `xml:"m:ItemShape"`
+	ItemIDs               []requestItemID        `xml:"m:ItemIds>t:ItemId"`
+	OccurrenceItemIDs     []occurrenceItemID     `xml:"m:ItemIds>t:OccurrenceItemId"`
+	RecurringMasterItemID *recurringMasterItemID `xml:"m:ItemIds>t:RecurringMasterItemId"`
+}
+
+type requestItemID struct {
+	ID        string `xml:"Id,attr"`
+	ChangeKey string `xml:"ChangeKey,attr,omitempty"`
+}
+
+type occurrenceItemID struct {
+	RecurringMasterID string `xml:"RecurringMasterId,attr"`
+	InstanceIndex     int    `xml:"InstanceIndex,attr"`
+}
+
+type recurringMasterItemID struct {
+	OccurrenceID string `xml:"OccurrenceId,attr"`
+}
+
+type findItemRequest struct {
+	XMLName        xml.Name              `xml:"m:FindItem"`
+	Traversal      string                `xml:"Traversal,attr"`
+	ItemShape      itemShape             `xml:"m:ItemShape"`
+	CalendarView   *calendarView         `xml:"m:CalendarView"`
+	Restriction    *restriction          `xml:"m:Restriction"`
+	ParentFolderID distinguishedFolderID `xml:"m:ParentFolderIds>t:DistinguishedFolderId"`
+}
+
+type calendarView struct {
+	MaxEntriesReturned int    `xml:"MaxEntriesReturned,attr,omitempty"`
+	StartDate          string `xml:"StartDate,attr"`
+	EndDate            string `xml:"EndDate,attr"`
+}
+
+// restriction holds one of the supported search expressions.
+type restriction struct {
+	Exists    *extendedFieldURI `xml:"t:Exists>t:ExtendedFieldURI"`
+	IsEqualTo *isEqualTo        `xml:"t:IsEqualTo"`
+}
+
+type isEqualTo struct {
+	Field    extendedFieldURI `xml:"t:ExtendedFieldURI"`
+	Constant struct {
+		Value string `xml:"Value,attr"`
+	} `xml:"t:FieldURIOrConstant>t:Constant"`
+}
+
+type resolveNamesRequest struct {
+	XMLName               xml.Name `xml:"m:ResolveNames"`
+	ReturnFullContactData bool     `xml:"ReturnFullContactData,attr"`
+	SearchScope           string   `xml:"SearchScope,attr"`
+	UnresolvedEntry       string   `xml:"m:UnresolvedEntry"`
+}
+
+type createItemRequest struct {
+	XMLName                xml.Name              `xml:"m:CreateItem"`
+	SendMeetingInvitations string                `xml:"SendMeetingInvitations,attr"`
+	SavedItemFolderID      distinguishedFolderID `xml:"m:SavedItemFolderId>t:DistinguishedFolderId"`
+	CalendarItem           newCalendarItem       `xml:"m:Items>t:CalendarItem"`
+}
+
+// newCalendarItem lists its elements in the order the schema requires.
+type newCalendarItem struct {
+	Subject                    string             `xml:"t:Subject"`
+	Categories                 *itemCategories    `xml:"t:Categories"`
+	ReminderIsSet              *bool              `xml:"t:ReminderIsSet"`
+	ReminderMinutesBeforeStart *int               `xml:"t:ReminderMinutesBeforeStart"`
+	ExtendedProperty           extendedProperty   `xml:"t:ExtendedProperty"`
+	Start                      string             `xml:"t:Start"`
+	End                        string             `xml:"t:End"`
+	IsAllDayEvent              bool               `xml:"t:IsAllDayEvent"`
+	LegacyFreeBusyStatus       string             `xml:"t:LegacyFreeBusyStatus"`
+	Location                   string             `xml:"t:Location"`
+	RequiredAttendees          []requestAttendee  `xml:"t:RequiredAttendees>t:Attendee"`
+	Resources                  []requestAttendee  `xml:"t:Resources>t:Attendee"`
+	Recurrence                 *requestRecurrence `xml:"t:Recurrence"`
+	StartTimeZone              *requestTimeZone   `xml:"t:StartTimeZone"`
+	EndTimeZone                *requestTimeZone   `xml:"t:EndTimeZone"`
+	IsOnlineMeeting            *bool              `xml:"t:IsOnlineMeeting"`
+}
+
+// requestTimeZone is a Windows time zone the item's wall-clock times, and
+// thereby its recurrence and all-day span, are kept in.
+type requestTimeZone struct {
+	ID string `xml:"Id,attr"`
+}
+
+// requestRecurrence has one of the patterns and one of the ranges.
+type requestRecurrence struct {
+	Daily    *intervalRecurrence `xml:"t:DailyRecurrence"`
+	Weekly   *weeklyRecurrence   `xml:"t:WeeklyRecurrence"`
+	Monthly  *monthlyRecurrence  `xml:"t:AbsoluteMonthlyRecurrence"`
+	Numbered *numberedRecurrence `xml:"t:NumberedRecurrence"`
+	EndDate  *endDateRecurrence  `xml:"t:EndDateRecurrence"`
+}
+
+type intervalRecurrence struct {
+	Interval int `xml:"t:Interval"`
+}
+
+type weeklyRecurrence struct {
+	Interval   int    `xml:"t:Interval"`
+	DaysOfWeek string `xml:"t:DaysOfWeek"`
+}
+
+type monthlyRecurrence struct {
+	Interval   int `xml:"t:Interval"`
+	DayOfMonth int `xml:"t:DayOfMonth"`
+}
+
+type numberedRecurrence struct {
+	StartDate           string `xml:"t:StartDate"`
+	NumberOfOccurrences int    `xml:"t:NumberOfOccurrences"`
+}
+
+type endDateRecurrence struct {
+	StartDate string `xml:"t:StartDate"`
+	EndDate   string `xml:"t:EndDate"`
+}
+
+// itemCategories is omitted without categories, which an empty slice in a
+// nested path isn't.
+type itemCategories struct {
+	Strings []string `xml:"t:String"`
+}
+
+type requestAttendee struct {
+	Mailbox requestMailbox `xml:"t:Mailbox"`
+}
+
+type extendedProperty struct {
+	Field extendedFieldURI `xml:"t:ExtendedFieldURI"`
+	Value string           `xml:"t:Value"`
+}
+
+type subscribeRequest struct {
+	XMLName          xml.Name                `xml:"m:Subscribe"`
+	PullSubscription pullSubscriptionRequest `xml:"m:PullSubscriptionRequest"`
+}
+
+type pullSubscriptionRequest struct {
+	FolderID   distinguishedFolderID `xml:"t:FolderIds>t:DistinguishedFolderId"`
+	EventTypes []string              `xml:"t:EventTypes>t:EventType"`
+	Timeout    int                   `xml:"t:Timeout"`
+}
+
+type getEventsRequest struct {
+	XMLName        xml.Name `xml:"m:GetEvents"`
+	SubscriptionID string   `xml:"m:SubscriptionId"`
+	Watermark      string   `xml:"m:Watermark"`
+}
+
+type getFolderRequest struct {
+	XMLName     xml.Name              `xml:"m:GetFolder"`
+	FolderShape itemShape             `xml:"m:FolderShape"`
+	FolderID    distinguishedFolderID `xml:"m:FolderIds>t:DistinguishedFolderId"`
+}
+
+type getRoomListsRequest struct {
+	XMLName xml.Name `xml:"m:GetRoomLists"`
+}
+
+type getRoomsRequest struct {
+	XMLName  xml.Name `xml:"m:GetRooms"`
+	RoomList string   `xml:"m:RoomList>t:EmailAddress"`
+}
+
+type findPeopleRequest struct {
+	XMLName             xml.Name            `xml:"m:FindPeople"`
+	PersonaShape        itemShape           `xml:"m:PersonaShape"`
+	IndexedPageItemView indexedPageItemView `xml:"m:IndexedPageItemView"`
+	AddressListID       requestItemID       `xml:"m:ParentFolderId>t:AddressListId"`
+}
+
+type indexedPageItemView struct {
+	BasePoint          string `xml:"BasePoint,attr"`
+	MaxEntriesReturned int    `xml:"MaxEntriesReturned,attr"`
+	Offset             int    `xml:"Offset,attr"`
+}
+
+// respondRequest creates a response to an existing item, like cancelling a
+// meeting as its organizer or declining it as an attendee.
+type respondRequest struct {
+	XMLName            xml.Name            `xml:"m:CreateItem"`
+	MessageDisposition string              `xml:"MessageDisposition,attr"`
+	CancelCalendarItem *cancelCalendarItem `xml:"m:Items>t:CancelCalendarItem"`
+	DeclineItem        *declineItem        `xml:"m:Items>t:DeclineItem"`
+}
+
+type cancelCalendarItem struct {
+	ReferenceItemID requestItemID `xml:"t:ReferenceItemId"`
+	NewBodyContent  bodyContent   `xml:"t:NewBodyContent"`
+}
+
+type declineItem struct {
+	ReferenceItemID requestItemID `xml:"t:ReferenceItemId"`
+}
+
+type bodyContent struct {
+	BodyType string `xml:"BodyType,attr"`
+	Content  string `xml:",chardata"`
+}
+
+type deleteItemRequest struct {
+	XMLName                  xml.Name           `xml:"m:DeleteItem"`
+	DeleteType               string             `xml:"DeleteType,attr"`
+	SendMeetingCancellations string             `xml:"SendMeetingCancellations,attr"`
+	OccurrenceItemIDs        []occurrenceItemID `xml:"m:ItemIds>t:OccurrenceItemId"`
+}
+
+type updateItemRequest struct {
+	XMLName                               xml.Name   `xml:"m:UpdateItem"`
+	ConflictResolution                    string     `xml:"ConflictResolution,attr"`
+	MessageDisposition                    string     `xml:"MessageDisposition,attr"`
+	SendMeetingInvitationsOrCancellations string     `xml:"SendMeetingInvitationsOrCancellations,attr"`
+	ItemChange                            itemChange `xml:"m:ItemChanges>t:ItemChange"`
+}
+
+// itemChange changes either the item or a single occurrence of it.
+type itemChange struct {
+	ItemID           *requestItemID    `xml:"t:ItemId"`
+	OccurrenceItemID *occurrenceItemID `xml:"t:OccurrenceItemId"`
+	Updates          []fieldUpdate     `xml:"t:Updates>update"`
+}
+
+// fieldUpdate is named after its kind, see setItemField, appendToItemField
+// and deleteItemField.
+type fieldUpdate struct {
+	XMLName      xml.Name
+	FieldURI     fieldURI             `xml:"t:FieldURI"`
+	CalendarItem *updatedCalendarItem `xml:"t:CalendarItem"`
+}
+
+// updatedCalendarItem holds the value of the updated field, in the order the
+// schema requires.
+type updatedCalendarItem struct {
+	Subject           *string       `xml:"t:Subject"`
+	Start             string        `xml:"t:Start,omitempty"`
+	End               string        `xml:"t:End,omitempty"`
+	RequiredAttendees *attendeeList `xml:"t:RequiredAttendees"`
+	Resources         *attendeeList `xml:"t:Resources"`
+}
+
+type attendeeList struct {
+	Attendees []requestAttendee `xml:"t:Attendee"`
+}
+
+// attendeesOf lists the attendees by their email addresses.
+func attendeesOf(emails []string) *attendeeList {
+	list := &attendeeList{}
+	for _, email := range emails {
+		list.Attendees = append(list.Attendees, requestAttendee{Mailbox: requestMailbox{EmailAddress: email}})
+	}
+	return list
+}
+
+func setItemField(uri string, value updatedCalendarItem) fieldUpdate {
+	return fieldUpdate{XMLName: xml.Name{Local: "t:SetItemField"}, FieldURI: fieldURI{FieldURI: uri}, CalendarItem: &value}
+}
+
+func appendToItemField(uri string, value updatedCalendarItem) fieldUpdate {
+	return fieldUpdate{XMLName: xml.Name{Local: "t:AppendToItemField"}, FieldURI: fieldURI{FieldURI: uri}, CalendarItem: &value}
+}
+
+func deleteItemField(uri string) fieldUpdate {
+	return fieldUpdate{XMLName: xml.Name{Local: "t:DeleteItemField"}, FieldURI: fieldURI{FieldURI: uri}}
+}
@@ -0,0 +1,177 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+)
+
+// ErrSubscriptionLost is returned by GetEvents once Exchange no longer knows
+// the pull subscription, typically because it expired. Events since the
+// watermark are lost with it, so the room has to be subscribed again and
+// synchronized.
+var ErrSubscriptionLost = errors.New("pull subscription lost")
+
+// pullSubscriptionTimeout is the number of minutes, the most Exchange allows,
+// after which a subscription nobody gets events from expires.
+const pullSubscriptionTimeout = 1440
+
+// maxEventPages limits the GetEvents requests following MoreEvents at once.
+const maxEventPages = 50
+
+// lostSubscriptionCodes are the response codes of subscriptions whose events
+// can't be got anymore.
+var lostSubscriptionCodes = map[string]bool{
+	"ErrorSubscriptionNotFound": true,
+	"ErrorExpiredSubscription":  true,
+	"ErrorInvalidSubscription":  true,
+	"ErrorInvalidWatermark":     true,
+	"ErrorReadEventsFailed":     true,
+}
+
+// Subscribe creates a pull subscription to the room's calendar, returning its
+// ID and the watermark its events start after.
+func (h *EWSHelper) Subscribe(roomEmail string) (subscriptionID string, watermark string, err error) {
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, roomEmail), subscribeRequest{
+		PullSubscription: pullSubscriptionRequest{
+			FolderID:   calendarOf(roomEmail),
+			EventTypes: []string{"CopiedEvent", "CreatedEvent", "DeletedEvent", "ModifiedEvent", "MovedEvent"},
+			Timeout:    pullSubscriptionTimeout,
+		},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return "", "", fmt.Errorf("subscribing to room %v: %w", roomEmail, err)
+	}
+	var soapFault soapFault
+	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
+		return "", "", fmt.Errorf("SOAP fault: %w", faultError(soapFault))
+	}
+
+	var response struct {
+		Body struct {
+			SubscribeResponse struct {
+				ResponseMessages struct {
+					SubscribeResponseMessage struct {
+						ResponseClass  string `xml:"ResponseClass,attr"`
+						ResponseCode   string `xml:"ResponseCode"`
+						MessageText    string `xml:"MessageText"`
+						SubscriptionId string `xml:"SubscriptionId"`
+						Watermark      string `xml:"Watermark"`
+					} `xml:"SubscribeResponseMessage"`
+				} `xml:"ResponseMessages"`
+			} `xml:"SubscribeResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(responseXML, &response); err != nil {
+		return "", "", fmt.Errorf("unmarshaling XML: %v", err)
+	}
+	rm := response.Body.SubscribeResponse.ResponseMessages.SubscribeResponseMessage
+	if err := parseResponseCode(rm.ResponseClass, rm.ResponseCode, rm.MessageText); err != nil {
+		return "", "", fmt.Errorf("Subscribe failed: %w", err)
+	}
+	return rm.SubscriptionId, rm.Watermark, nil
+}
+
+// GetEvents gets the events of the room's pull subscription after the
+// watermark, reporting whether any item in the calendar changed and the
+// watermark to continue with.
+func (h *EWSHelper) GetEvents(roomEmail, subscriptionID, watermark string) (changed bool, newWatermark string, err error) {
+	for page := 0; page < maxEventPages; page++ {
+		pageChanged, more, next, err := h.getEventsPage(roomEmail, subscriptionID, watermark)
+		if err != nil {
+			return false, watermark, err
+		}
+		changed = changed || pageChanged
+		watermark = next
+		if !more {
+			break
+		}
+	}
+	return changed, watermark, nil
+}
+
+func (h *EWSHelper) getEventsPage(roomEmail, subscriptionID, watermark string) (changed bool, more bool, newWatermark string, err error) {
+	requestXML, err := marshalRequest(impersonate(IdentitySmtpAddress, roomEmail), getEventsRequest{
+		SubscriptionID: subscriptionID,
+		Watermark:      watermark,
+	})
+	if err != nil {
+		return false, false, "", err
+	}
+	responseXML, err := h.sendRequest(requestXML)
+	if err != nil {
+		return false, false, "", fmt.Errorf("getting events of room %v: %w", roomEmail, err)
+	}
+	var soapFault soapFault
+	if err := xml.Unmarshal(responseXML, &soapFault); err == nil && soapFault.Body.Fault.FaultCode != "" {
+		if lostSubscriptionCodes[soapFault.Body.Fault.Detail.ResponseCode] {
+			return false, false, "", fmt.Errorf("%w: %s", ErrSubscriptionLost, soapFault.Body.Fault.Detail.ResponseCode)
+		}
+		return false, false, "", fmt.Errorf("SOAP fault: %w", faultError(soapFault))
+	}
+
+	var response struct {
+		Body struct {
+			GetEventsResponse struct {
+				ResponseMessages struct {
+					GetEventsResponseMessage struct {
+						ResponseClass string `xml:"ResponseClass,attr"`
+						ResponseCode  string `xml:"ResponseCode"`
+						MessageText   string `xml:"MessageText"`
+						Notification  struct {
+							MoreEvents bool `xml:"MoreEvents"`
+							// Events are named by their type, all carrying
+							// the watermark after them.
+							Events []struct {
+								XMLName   xml.Name
+								Watermark string `xml:"Watermark"`
+							} `xml:",any"`
+						} `xml:"Notification"`
+					} `xml:"GetEventsResponseMessage"`
+				} `xml:"ResponseMessages"`
+			} `xml:"GetEventsResponse"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(responseXML, &response); err != nil {
+		return false, false, "", fmt.Errorf("unmarshaling XML: %v", err)
+	}
+	rm := response.Body.GetEventsResponse.ResponseMessages.GetEventsResponseMessage
+	if lostSubscriptionCodes[rm.ResponseCode] {
+		return false, false, "", fmt.Errorf("%w: %s", ErrSubscriptionLost, rm.ResponseCode)
+	}
+	if err := parseResponseCode(rm.ResponseClass, rm.ResponseCode, rm.MessageText); err != nil {
+		return false, false, "", fmt.Errorf("GetEvents failed: %w", err)
+	}
+	newWatermark = watermark
+	for _, event := range rm.Notification.Events {
+		if event.Watermark == "" {
+			// Not an event, like the subscription ID or previous watermark.
+			continue
+		}
+		newWatermark = event.Watermark
+		// Status events only keep the subscription alive.
+		if event.XMLName.Local != "StatusEvent" {
+			changed = true
+		}
+	}
+	return changed, rm.Notification.MoreEvents, newWatermark, nil
+}
@@ -0,0 +1,83 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ErrThrottled is returned when Exchange keeps throttling a request.
+var ErrThrottled = errors.New("throttled by Exchange")
+
+const (
+	// maxThrottleRetries is how often a throttled request is retried.
+	maxThrottleRetries = 3
+	// defaultThrottleBackOff is waited if Exchange doesn't say how long.
+	defaultThrottleBackOff = 30 * time.Second
+	// maxThrottleBackOff caps the back-off Exchange asks for, so that a sync
+	// isn't blocked for long by a single request.
+	maxThrottleBackOff = 5 * time.Minute
+)
+
+// throttleSleep waits for the back-off. Replaced in tests.
+var throttleSleep = time.Sleep
+
+// backOffRegexp finds the back-off in milliseconds, both in the MessageXml of
+// a ServerBusy error (<t:Value Name="BackOffMilliseconds">5000</t:Value>) and
+// in the x-ms-diagnostics header (BackOffMilliseconds=5000).
+var backOffRegexp = regexp.MustCompile(`(?i)BackOffMilliseconds"?\s*>?\s*[:=]?\s*"?(\d+)`)
+
+// serverBusyRegexp finds ErrorServerBusy as a fault code or response code,
+// not e.g. in a subject.
+var serverBusyRegexp = regexp.MustCompile(`[>:]ErrorServerBusy<`)
+
+// throttling reports whether Exchange throttled the request, i.e. answered
+// with 429 Too Many Requests, 503 Service Unavailable with a Retry-After
+// header, or an ErrorServerBusy error, and how long to back off.
+func throttling(response *http.Response, body []byte) (time.Duration, bool) {
+	retryAfter := response.Header.Get("Retry-After")
+	throttled := response.StatusCode == http.StatusTooManyRequests ||
+		response.StatusCode == http.StatusServiceUnavailable && retryAfter != "" ||
+		serverBusyRegexp.Match(body)
+	if !throttled {
+		return 0, false
+	}
+
+	backOff := defaultThrottleBackOff
+	if match := backOffRegexp.FindSubmatch(body); match != nil {
+		backOff = milliseconds(string(match[1]), backOff)
+	} else if match := backOffRegexp.FindStringSubmatch(response.Header.Get("x-ms-diagnostics")); match != nil {
+		backOff = milliseconds(match[1], backOff)
+	} else if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		backOff = time.Duration(seconds) * time.Second
+	}
+	if backOff > maxThrottleBackOff {
+		backOff = maxThrottleBackOff
+	}
+	return backOff, true
+}
+
+func milliseconds(s string, fallback time.Duration) time.Duration {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return time.Duration(ms) * time.Millisecond
+}
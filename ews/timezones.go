@@ -0,0 +1,83 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import "time"
+
+// windowsTimeZones maps IANA time zones to the Windows time zone IDs Exchange
+// knows, following the CLDR mapping of the zones' territories.
+var windowsTimeZones = map[string]string{
+	"UTC":                 "UTC",
+	"Etc/UTC":             "UTC",
+	"Europe/London":       "GMT Standard Time",
+	"Europe/Dublin":       "GMT Standard Time",
+	"Europe/Lisbon":       "GMT Standard Time",
+	"Europe/Zurich":       "W. Europe Standard Time",
+	"Europe/Berlin":       "W. Europe Standard Time",
+	"Europe/Vienna":       "W. Europe Standard Time",
+	"Europe/Rome":         "W. Europe Standard Time",
+	"Europe/Amsterdam":    "W. Europe Standard Time",
+	"Europe/Stockholm":    "W. Europe Standard Time",
+	"Europe/Oslo":         "W. Europe Standard Time",
+	"Europe/Luxembourg":   "W. Europe Standard Time",
+	"Europe/Vaduz":        "W. Europe Standard Time",
+	"Europe/Monaco":       "W. Europe Standard Time",
+	"Europe/Paris":        "Romance Standard Time",
+	"Europe/Brussels":     "Romance Standard Time",
+	"Europe/Madrid":       "Romance Standard Time",
+	"Europe/Copenhagen":   "Romance Standard Time",
+	"Europe/Prague":       "Central Europe Standard Time",
+	"Europe/Budapest":     "Central Europe Standard Time",
+	"Europe/Bratislava":   "Central Europe Standard Time",
+	"Europe/Ljubljana":    "Central Europe Standard Time",
+	"Europe/Belgrade":     "Central Europe Standard Time",
+	"Europe/Warsaw":       "Central European Standard Time",
+	"Europe/Zagreb":       "Central European Standard Time",
+	"Europe/Sarajevo":     "Central European Standard Time",
+	"Europe/Helsinki":     "FLE Standard Time",
+	"Europe/Kiev":         "FLE Standard Time",
+	"Europe/Kyiv":         "FLE Standard Time",
+	"Europe/Riga":         "FLE Standard Time",
+	"Europe/Tallinn":      "FLE Standard Time",
+	"Europe/Vilnius":      "FLE Standard Time",
+	"Europe/Sofia":        "FLE Standard Time",
+	"Europe/Athens":       "GTB Standard Time",
+	"Europe/Bucharest":    "GTB Standard Time",
+	"Europe/Istanbul":     "Turkey Standard Time",
+	"Europe/Moscow":       "Russian Standard Time",
+	"America/New_York":    "Eastern Standard Time",
+	"America/Toronto":     "Eastern Standard Time",
+	"America/Chicago":     "Central Standard Time",
+	"America/Denver":      "Mountain Standard Time",
+	"America/Phoenix":     "US Mountain Standard Time",
+	"America/Los_Angeles": "Pacific Standard Time",
+	"America/Sao_Paulo":   "E. South America Standard Time",
+	"Asia/Dubai":          "Arabian Standard Time",
+	"Asia/Kolkata":        "India Standard Time",
+	"Asia/Singapore":      "Singapore Standard Time",
+	"Asia/Shanghai":       "China Standard Time",
+	"Asia/Hong_Kong":      "China Standard Time",
+	"Asia/Tokyo":          "Tokyo Standard Time",
+	"Australia/Sydney":    "AUS Eastern Standard Time",
+	"Australia/Melbourne": "AUS Eastern Standard Time",
+	"Pacific/Auckland":    "New Zealand Standard Time",
+}
+
+// windowsTimeZone returns the Windows ID of the time zone, if it is known.
+func windowsTimeZone(location *time.Location) (string, bool) {
+	id, ok := windowsTimeZones[location.String()]
+	return id, ok
+}
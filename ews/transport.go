@@ -0,0 +1,120 @@
+//  This file is part of the eliona project.
+//  Copyright © 2022 LEICOM iTEC AG. All Rights Reserved.
+//  ______ _ _
+// |  ____| (_)
+// | |__  | |_  ___  _ __   __ _
+// |  __| | | |/ _ \| '_ \ / _` |
+// | |____| | | (_) | | | | (_| |
+// |______|_|_|\___/|_| |_|\__,_|
+//
+//  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING
+//  BUT NOT LIMITED  TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+//  NON INFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM,
+//  DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ews
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"ews/apiserver"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/eliona-smart-building-assistant/go-utils/common"
+	"github.com/eliona-smart-building-assistant/go-utils/log"
+)
+
+const (
+	// maxIdleConnsPerHost keeps enough connections to Exchange open for a
+	// burst of bookings, the default of two is exhausted by a few.
+	maxIdleConnsPerHost = 16
+	idleConnTimeout     = 90 * time.Second
+)
+
+// transportKey identifies the settings of a transport. Helpers with the same
+// settings share a transport and so its connections.
+type transportKey struct {
+	proxyURL string
+	// ntlmUser keeps the connections of NTLM accounts apart, as NTLM
+	// authenticates the connection rather than each request.
+	ntlmUser           string
+	tlsCACertPEM       string
+	insecureSkipVerify bool
+}
+
+var transportsMu sync.Mutex
+var transports = make(map[transportKey]*http.Transport)
+
+// transportFor returns the transport shared by the helpers of configurations
+// connecting the same way. The TLS settings apply to NTLM accounts only,
+// OAuth connects to Exchange Online.
+func transportFor(config apiserver.Configuration, ntlmUser string) *http.Transport {
+	key := transportKey{proxyURL: common.Val(config.ProxyURL), ntlmUser: ntlmUser}
+	if ntlmUser != "" {
+		key.tlsCACertPEM = common.Val(config.TLSCACertPEM)
+		key.insecureSkipVerify = common.Val(config.InsecureSkipVerify)
+	}
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	if transport, ok := transports[key]; ok {
+		return transport
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFor(config)
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	transport.IdleConnTimeout = idleConnTimeout
+	if ntlmUser != "" {
+		transport.TLSClientConfig = tlsConfigFor(config)
+		// NTLM authenticates HTTP/1.1 connections only.
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	transports[key] = transport
+	return transport
+}
+
+// proxyFor returns how requests to Exchange find their proxy: the configured
+// one, else the one of the environment (HTTPS_PROXY, NO_PROXY). Credentials in
+// the proxy URL are used for basic authentication.
+func proxyFor(config apiserver.Configuration) func(*http.Request) (*url.URL, error) {
+	if !filled(config.ProxyURL) {
+		return http.ProxyFromEnvironment
+	}
+	proxyURL, err := url.Parse(*config.ProxyURL)
+	if err != nil {
+		// The error would repeat the URL, including its password.
+		return func(*http.Request) (*url.URL, error) {
+			return nil, errors.New("invalid proxy URL")
+		}
+	}
+	return http.ProxyURL(proxyURL)
+}
+
+// tlsConfigFor returns the TLS configuration of NTLM connections trusting the
+// configured CA certificates besides the system's, nil for the defaults.
+func tlsConfigFor(config apiserver.Configuration) *tls.Config {
+	if !filled(config.TLSCACertPEM) && !common.Val(config.InsecureSkipVerify) {
+		return nil
+	}
+	tlsConfig := &tls.Config{}
+	if filled(config.TLSCACertPEM) {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(*config.TLSCACertPEM)) {
+			log.Error("ews", "no certificates found in the CA certificates of configuration %d", common.Val(config.Id))
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if common.Val(config.InsecureSkipVerify) {
+		log.Warn("ews", "TLS certificates of Exchange are not verified for configuration %d", common.Val(config.Id))
+		tlsConfig.InsecureSkipVerify = true
+	}
+	return tlsConfig
+}
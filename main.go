@@ -16,6 +16,7 @@
 package main
 
 import (
+	"os"
 	"time"
 
 	"github.com/eliona-smart-building-assistant/go-eliona/app"
@@ -41,6 +42,13 @@ func main() {
 		boil.DebugWriter = log.GetWriter(log.TraceLevel, "database")
 	}
 
+	// Check the connection to Exchange instead of starting the app.
+	if len(os.Args) > 1 && os.Args[1] == "diagnose" {
+		code := diagnose(os.Args[2:], os.Stdout)
+		database.Close()
+		os.Exit(code)
+	}
+
 	// Necessary to close used init resources, because db.Pool() is used in this app.
 	defer db.ClosePool()
 
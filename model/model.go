@@ -17,6 +17,9 @@ package model
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"ews/apiserver"
 	"ews/conf"
 	"fmt"
@@ -30,8 +33,27 @@ type Room struct {
 	Email    string `eliona:"email,filterable" subtype:"info"`
 	Name     string `eliona:"name,filterable"`
 	Bookable int8   `eliona:"bookable" subtype:"property"`
+	// Capacity is the number of people the room seats, nil if unknown.
+	Capacity *int32 `eliona:"capacity,filterable" subtype:"property"`
+	// PhoneNumber, Office and Department are taken from the directory entry
+	// of the room's mailbox.
+	PhoneNumber string `eliona:"phone_number,filterable" subtype:"info"`
+	Office      string `eliona:"office,filterable" subtype:"info"`
+	Department  string `eliona:"department,filterable" subtype:"info"`
 
-	Config apiserver.Configuration
+	// DisplayName is the name of the asset in Eliona, if it differs from the
+	// name in Exchange.
+	DisplayName string
+	// Building and Floor locate the room, if known.
+	Building string
+	Floor    string
+	// Equipment is set for equipment mailboxes like projectors, which are
+	// booked like rooms but are assets of their own type.
+	Equipment bool
+	// ContactUnknown is set if looking up the room's directory entry failed,
+	// so that its contact data and location are not known.
+	ContactUnknown bool
+	Config         apiserver.Configuration
 }
 
 func (r *Room) AdheresToFilter(filter [][]apiserver.FilterRule) (bool, error) {
@@ -48,18 +70,43 @@ func (r *Room) AdheresToFilter(filter [][]apiserver.FilterRule) (bool, error) {
 }
 
 func (r *Room) GetName() string {
+	if r.DisplayName != "" {
+		return r.DisplayName
+	}
 	return r.Name
 }
 
+// DataDigest summarizes the asset data of the room, so that changes of any
+// attribute can be detected without reading the data back from Eliona.
+func (r *Room) DataDigest() (string, error) {
+	data, err := json.Marshal(asset.SplitBySubtype(r))
+	if err != nil {
+		return "", fmt.Errorf("marshalling asset data: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
 func (r *Room) GetDescription() string {
+	if r.Equipment {
+		return "Equipment resource managed in Microsoft Exchange server"
+	}
 	return "Room resource managed in Microsoft Exchange server"
 }
 
 func (r *Room) GetAssetType() string {
+	if r.Equipment {
+		return "ews_equipment"
+	}
 	return "ews_room"
 }
 
 func (r *Room) GetGAI() string {
+	if r.Email == "" {
+		// Rooms without email are skipped during discovery. Should one get
+		// here anyway, don't let it share the bare asset type GAI with others.
+		return r.GetAssetType() + "_name_" + r.Name
+	}
 	return r.GetAssetType() + "_" + r.Email
 }
 
@@ -68,7 +115,7 @@ func (r *Room) GetAssetID(projectID string) (*int32, error) {
 }
 
 func (r *Room) SetAssetID(assetID int32, projectID string) error {
-	if err := conf.InsertAsset(context.Background(), r.Config, projectID, r.GetGAI(), assetID, r.Email); err != nil {
+	if err := conf.InsertAsset(context.Background(), r.Config, projectID, r.GetGAI(), assetID, r.Email, r.GetName()); err != nil {
 		return fmt.Errorf("inserting asset to config db: %v", err)
 	}
 	return nil
@@ -109,28 +156,174 @@ func (r *Root) GetAssetID(projectID string) (*int32, error) {
 }
 
 func (r *Root) SetAssetID(assetID int32, projectID string) error {
-	if err := conf.InsertAsset(context.Background(), r.Config, projectID, r.GetGAI(), assetID, ""); err != nil {
+	if err := conf.InsertAsset(context.Background(), r.Config, projectID, r.GetGAI(), assetID, "", r.GetName()); err != nil {
 		return fmt.Errorf("inserting asset to config db: %v", err)
 	}
 	return nil
 }
 
+// GetLocationalChildren nests located rooms under their building and floor.
+// Rooms without location are children of the root.
 func (r *Root) GetLocationalChildren() []asset.LocationalNode {
-	locationalChildren := make([]asset.LocationalNode, 0, len(r.Rooms))
+	var locationalChildren []asset.LocationalNode
+	var buildings []*Building
 	for i := range r.Rooms {
-		locationalChildren = append(locationalChildren, &r.Rooms[i])
+		room := &r.Rooms[i]
+		if r.locationPending(room) {
+			continue
+		}
+		if room.Building == "" {
+			locationalChildren = append(locationalChildren, room)
+			continue
+		}
+		var building *Building
+		for _, b := range buildings {
+			if b.Name == room.Building {
+				building = b
+			}
+		}
+		if building == nil {
+			building = &Building{Name: room.Building, Config: r.Config}
+			buildings = append(buildings, building)
+			locationalChildren = append(locationalChildren, building)
+		}
+		building.add(room)
 	}
 	return locationalChildren
 }
 
+// GetFunctionalChildren returns the rooms without location. Located rooms are
+// left to GetLocationalChildren, as assets are created under the first parent
+// they are found under. Their functional parent becomes the root then, as it
+// is for all nodes of the locational tree.
 func (r *Root) GetFunctionalChildren() []asset.FunctionalNode {
-	functionalChildren := make([]asset.FunctionalNode, 0, len(r.Rooms))
+	var functionalChildren []asset.FunctionalNode
 	for i := range r.Rooms {
-		functionalChildren = append(functionalChildren, &r.Rooms[i])
+		if r.Rooms[i].Building == "" && !r.locationPending(&r.Rooms[i]) {
+			functionalChildren = append(functionalChildren, &r.Rooms[i])
+		}
 	}
 	return functionalChildren
 }
 
+// locationPending reports whether the room is left out of the asset tree, as
+// it would be located by its contact data, which could not be looked up.
+// Rooms are created under the first parent they are found under, so they
+// are created once their location is known.
+func (r *Root) locationPending(room *Room) bool {
+	if !room.ContactUnknown {
+		return false
+	}
+	locations, err := conf.RoomLocationPattern(r.Config)
+	return err == nil && locations.Enabled()
+}
+
+// Building groups the rooms located in it.
+type Building struct {
+	Name   string
+	Floors []*Floor
+	// Rooms located in the building, but on no known floor.
+	Rooms []*Room
+
+	Config apiserver.Configuration
+}
+
+func (b *Building) add(room *Room) {
+	if room.Floor == "" {
+		b.Rooms = append(b.Rooms, room)
+		return
+	}
+	for _, floor := range b.Floors {
+		if floor.Name == room.Floor {
+			floor.Rooms = append(floor.Rooms, room)
+			return
+		}
+	}
+	b.Floors = append(b.Floors, &Floor{Building: b.Name, Name: room.Floor, Rooms: []*Room{room}, Config: b.Config})
+}
+
+func (b *Building) GetName() string {
+	return b.Name
+}
+
+func (b *Building) GetDescription() string {
+	return "Building containing Microsoft Exchange rooms"
+}
+
+func (b *Building) GetAssetType() string {
+	return "ews_building"
+}
+
+func (b *Building) GetGAI() string {
+	return b.GetAssetType() + "_" + b.Name
+}
+
+func (b *Building) GetAssetID(projectID string) (*int32, error) {
+	return conf.GetAssetId(context.Background(), b.Config, projectID, b.GetGAI())
+}
+
+func (b *Building) SetAssetID(assetID int32, projectID string) error {
+	if err := conf.InsertAsset(context.Background(), b.Config, projectID, b.GetGAI(), assetID, "", b.GetName()); err != nil {
+		return fmt.Errorf("inserting asset to config db: %v", err)
+	}
+	return nil
+}
+
+func (b *Building) GetLocationalChildren() []asset.LocationalNode {
+	locationalChildren := make([]asset.LocationalNode, 0, len(b.Floors)+len(b.Rooms))
+	for _, floor := range b.Floors {
+		locationalChildren = append(locationalChildren, floor)
+	}
+	for _, room := range b.Rooms {
+		locationalChildren = append(locationalChildren, room)
+	}
+	return locationalChildren
+}
+
+// Floor groups the rooms located on it.
+type Floor struct {
+	Building string
+	Name     string
+	Rooms    []*Room
+
+	Config apiserver.Configuration
+}
+
+func (f *Floor) GetName() string {
+	return f.Name
+}
+
+func (f *Floor) GetDescription() string {
+	return "Floor containing Microsoft Exchange rooms"
+}
+
+func (f *Floor) GetAssetType() string {
+	return "ews_floor"
+}
+
+func (f *Floor) GetGAI() string {
+	return f.GetAssetType() + "_" + f.Building + "_" + f.Name
+}
+
+func (f *Floor) GetAssetID(projectID string) (*int32, error) {
+	return conf.GetAssetId(context.Background(), f.Config, projectID, f.GetGAI())
+}
+
+func (f *Floor) SetAssetID(assetID int32, projectID string) error {
+	if err := conf.InsertAsset(context.Background(), f.Config, projectID, f.GetGAI(), assetID, "", f.GetName()); err != nil {
+		return fmt.Errorf("inserting asset to config db: %v", err)
+	}
+	return nil
+}
+
+func (f *Floor) GetLocationalChildren() []asset.LocationalNode {
+	locationalChildren := make([]asset.LocationalNode, 0, len(f.Rooms))
+	for _, room := range f.Rooms {
+		locationalChildren = append(locationalChildren, room)
+	}
+	return locationalChildren
+}
+
 //
 
 func apiFilterToCommonFilter(input [][]apiserver.FilterRule) [][]common.FilterRule {
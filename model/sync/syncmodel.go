@@ -1,21 +1,77 @@
 package syncmodel
 
-import "time"
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
 
 type BookingGroup struct {
-	ElionaID       int32
-	ExchangeUID    string
-	OrganizerEmail string
+	ElionaID    int32
+	ExchangeUID string
+	// MasterExchangeID is the item ID of the series' recurring master in
+	// the room's mailbox the group was found in; empty for single events.
+	// Deletions of whole series are reported with it.
+	MasterExchangeID string
+	OrganizerEmail   string
+	// Subject of the event, composed by SubjectFallback if the event has none.
+	Subject string
+	// IsOnline marks hybrid meetings having an online (e.g. Teams) part.
+	IsOnline bool
+	JoinURL  string
+	// Categories of the event in Exchange, empty in privacy mode.
+	Categories []string
+	// FreeBusyStatus requested by the Eliona booking, empty for default.
+	FreeBusyStatus string
 	Occurrences    []BookingOccurrence
+	// ReceivedAt is when the group was received from the booking websocket.
+	ReceivedAt time.Time
+	// CorrelationID identifies the handling of a booking event in logs and
+	// the audit log.
+	CorrelationID string
+	// CreatedByApp marks events found in Exchange which the app created for
+	// an Eliona booking.
+	CreatedByApp bool
+	// SelfOrganized marks events organized by the room they were found in.
+	// They are booked in Eliona without an organizer.
+	SelfOrganized bool
+	// Block marks events nobody is invited to, e.g. put directly in the
+	// room's calendar to block it.
+	Block bool
+	// OccupancyOnly marks imported events whose changes in Eliona are not
+	// written back to Exchange.
+	OccupancyOnly bool
 }
 
 type BookingOccurrence struct {
-	ElionaID      int32
+	ElionaID int32
+	// InstanceIndex identifies the occurrence within its series in Exchange,
+	// starting with 1; 0 for single events. It is not the chronological
+	// position, as moved occurrences keep their index.
 	InstanceIndex int
 	Start         time.Time
 	End           time.Time
 	Cancelled     bool
-	RoomBookings  []RoomBooking
+	// AllDay occurrences span whole days from midnight to midnight, End
+	// being the midnight after the last day as in Exchange.
+	AllDay bool
+	// TimeZone is the Windows time zone ID the occurrence was scheduled in
+	// Exchange, empty if unknown. Start and End are UTC regardless.
+	TimeZone string
+	// Location of the occurrence as typed by the organizer, e.g. a room's
+	// name rather than its email. Empty in privacy mode.
+	Location string
+	// Attendees are the email addresses of the people invited besides the
+	// room. Empty in privacy mode.
+	Attendees []string
+	// AttendeesTruncated marks attendee lists too large to be synced whole.
+	AttendeesTruncated bool
+	// IsOnline marks occurrences of hybrid meetings as listed by the Booking
+	// app, which knows no groups. See BookingGroup.IsOnline otherwise.
+	IsOnline     bool
+	RoomBookings []RoomBooking
 }
 
 type RoomBooking struct {
@@ -31,3 +87,734 @@ func (ub BookingOccurrence) GetAssetIDs() []int32 {
 	}
 	return assetIDs
 }
+
+// OverlapPolicy defines whether bookings touching at their boundaries (one
+// ending at 10:00, other starting at 10:00) overlap.
+type OverlapPolicy string
+
+const (
+	// OverlapExclusive treats back-to-back bookings as not overlapping.
+	OverlapExclusive OverlapPolicy = "exclusive"
+	// OverlapInclusive treats back-to-back bookings as overlapping.
+	OverlapInclusive OverlapPolicy = "inclusive"
+)
+
+// Overlaps reports whether intervals a and b overlap under the policy.
+func Overlaps(aStart, aEnd, bStart, bEnd time.Time, policy OverlapPolicy) bool {
+	if policy == OverlapInclusive {
+		return !aStart.After(bEnd) && !bStart.After(aEnd)
+	}
+	return aStart.Before(bEnd) && bStart.Before(aEnd)
+}
+
+// OverlapsWith reports whether the occurrences overlap under the policy.
+func (ub BookingOccurrence) OverlapsWith(other BookingOccurrence, policy OverlapPolicy) bool {
+	return Overlaps(ub.Start, ub.End, other.Start, other.End, policy)
+}
+
+// IsOngoing reports whether the occurrence takes place at the given time. The
+// end of the occurrence belongs to it only under the inclusive policy.
+func (ub BookingOccurrence) IsOngoing(t time.Time, policy OverlapPolicy) bool {
+	if ub.Cancelled || t.Before(ub.Start) {
+		return false
+	}
+	if policy == OverlapInclusive {
+		return !t.After(ub.End)
+	}
+	return t.Before(ub.End)
+}
+
+// Free/busy statuses of an appointment as accepted by Exchange.
+const (
+	FreeBusyStatusFree             = "Free"
+	FreeBusyStatusTentative        = "Tentative"
+	FreeBusyStatusBusy             = "Busy"
+	FreeBusyStatusOOF              = "OOF"
+	FreeBusyStatusWorkingElsewhere = "WorkingElsewhere"
+)
+
+// ParseFreeBusyStatus validates the free/busy status. Empty status defaults to
+// Busy.
+func ParseFreeBusyStatus(status string) (string, error) {
+	switch status {
+	case "":
+		return FreeBusyStatusBusy, nil
+	case FreeBusyStatusFree, FreeBusyStatusTentative, FreeBusyStatusBusy, FreeBusyStatusOOF, FreeBusyStatusWorkingElsewhere:
+		return status, nil
+	}
+	return "", fmt.Errorf("invalid free/busy status %q", status)
+}
+
+// DeclinePolicy defines what happens to a multi-room booking when some of the
+// rooms decline it.
+type DeclinePolicy string
+
+const (
+	// DeclineCancelAll cancels the whole booking.
+	DeclineCancelAll DeclinePolicy = "cancelAll"
+	// DeclineKeepAccepted keeps the booking in the rooms which accepted it.
+	DeclineKeepAccepted DeclinePolicy = "keepAccepted"
+)
+
+// ParseDeclinePolicy validates the decline policy. Empty policy defaults to
+// DeclineCancelAll.
+func ParseDeclinePolicy(policy string) (DeclinePolicy, error) {
+	switch DeclinePolicy(policy) {
+	case "":
+		return DeclineCancelAll, nil
+	case DeclineCancelAll, DeclineKeepAccepted:
+		return DeclinePolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid decline policy %q", policy)
+}
+
+// CancelPolicy defines how a booking cancelled in Eliona frees its rooms in
+// Exchange.
+type CancelPolicy string
+
+const (
+	// CancelEvent cancels the whole event, notifying all attendees.
+	CancelEvent CancelPolicy = "cancel"
+	// CancelRemoveRooms removes the rooms from the event, which stays in
+	// place for the other attendees.
+	CancelRemoveRooms CancelPolicy = "removeRooms"
+	// CancelDeclineAsRoom declines the event on the rooms' behalf without
+	// notifying anyone, leaving it up to the organizer what to do.
+	CancelDeclineAsRoom CancelPolicy = "declineAsRoom"
+)
+
+// ParseCancelPolicy validates the cancel policy. Empty policy defaults to
+// CancelEvent.
+func ParseCancelPolicy(policy string) (CancelPolicy, error) {
+	switch CancelPolicy(policy) {
+	case "":
+		return CancelEvent, nil
+	case CancelEvent, CancelRemoveRooms, CancelDeclineAsRoom:
+		return CancelPolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid cancel policy %q", policy)
+}
+
+// EmptyBookingPolicy defines what happens to an Eliona booking when the last
+// of its rooms is removed from the event in Exchange.
+type EmptyBookingPolicy string
+
+const (
+	// EmptyBookingCancel cancels the Eliona booking.
+	EmptyBookingCancel EmptyBookingPolicy = "cancel"
+	// EmptyBookingKeep keeps the booking without rooms, e.g. for bookings of
+	// other assets or to reassign it to another room.
+	EmptyBookingKeep EmptyBookingPolicy = "keepEmpty"
+)
+
+// ParseEmptyBookingPolicy validates the empty booking policy. Empty policy
+// defaults to EmptyBookingCancel.
+func ParseEmptyBookingPolicy(policy string) (EmptyBookingPolicy, error) {
+	switch EmptyBookingPolicy(policy) {
+	case "":
+		return EmptyBookingCancel, nil
+	case EmptyBookingCancel, EmptyBookingKeep:
+		return EmptyBookingPolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid empty booking policy %q", policy)
+}
+
+// RoomDiscovery defines where the rooms of a configuration are discovered.
+type RoomDiscovery string
+
+const (
+	// RoomDiscoveryRoomList discovers the rooms of the room list.
+	RoomDiscoveryRoomList RoomDiscovery = "roomList"
+	// RoomDiscoveryAddressList discovers the rooms of an address list, e.g.
+	// one maintained for an address book container not exposed as room list.
+	RoomDiscoveryAddressList RoomDiscovery = "addressList"
+	// RoomDiscoveryStatic uses a configured list of room email addresses.
+	RoomDiscoveryStatic RoomDiscovery = "static"
+)
+
+// ParseRoomDiscovery validates the room discovery. Empty discovery defaults
+// to RoomDiscoveryRoomList.
+func ParseRoomDiscovery(discovery string) (RoomDiscovery, error) {
+	switch RoomDiscovery(discovery) {
+	case "":
+		return RoomDiscoveryRoomList, nil
+	case RoomDiscoveryRoomList, RoomDiscoveryAddressList, RoomDiscoveryStatic:
+		return RoomDiscovery(discovery), nil
+	}
+	return "", fmt.Errorf("invalid room discovery %q", discovery)
+}
+
+// Cloud is the Microsoft 365 cloud hosting the tenant of an OAuth
+// configuration.
+type Cloud string
+
+const (
+	// CloudCommercial is the worldwide Microsoft 365 cloud.
+	CloudCommercial Cloud = "commercial"
+	// CloudGCCHigh is the US Government Community Cloud High.
+	CloudGCCHigh Cloud = "gccHigh"
+	// CloudDoD is the US Department of Defense cloud.
+	CloudDoD Cloud = "dod"
+	// CloudChina is the cloud operated by 21Vianet in China.
+	CloudChina Cloud = "china"
+)
+
+var sidRegexp = regexp.MustCompile(`^S-1-\d+(-\d+)+$`)
+
+// IsSID reports whether the user is given by its security identifier, e.g.
+// S-1-5-21-1004336348-1177238915-682003330-512, rather than by an address.
+func IsSID(user string) bool {
+	return sidRegexp.MatchString(user)
+}
+
+// ParseCloud validates the cloud. Empty cloud defaults to CloudCommercial.
+func ParseCloud(cloud string) (Cloud, error) {
+	switch Cloud(cloud) {
+	case "":
+		return CloudCommercial, nil
+	case CloudCommercial, CloudGCCHigh, CloudDoD, CloudChina:
+		return Cloud(cloud), nil
+	}
+	return "", fmt.Errorf("invalid cloud %q", cloud)
+}
+
+// MissingRoomEmailPolicy defines what happens to a room whose asset has no
+// email address stored, so its calendar can't be synchronized.
+type MissingRoomEmailPolicy string
+
+const (
+	// MissingRoomEmailSkip skips the room and logs it.
+	MissingRoomEmailSkip MissingRoomEmailPolicy = "skip"
+	// MissingRoomEmailRepair derives the email from the room's global asset
+	// ID and stores it, skipping only rooms it can't be derived for.
+	MissingRoomEmailRepair MissingRoomEmailPolicy = "repair"
+)
+
+// ParseMissingRoomEmailPolicy validates the missing room email policy. Empty
+// policy defaults to MissingRoomEmailSkip.
+func ParseMissingRoomEmailPolicy(policy string) (MissingRoomEmailPolicy, error) {
+	switch MissingRoomEmailPolicy(policy) {
+	case "":
+		return MissingRoomEmailSkip, nil
+	case MissingRoomEmailSkip, MissingRoomEmailRepair:
+		return MissingRoomEmailPolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid missing room email policy %q", policy)
+}
+
+// SelfOrganizedPolicy defines what happens to events organized by the room
+// they are found in, e.g. booked directly in the room's calendar.
+type SelfOrganizedPolicy string
+
+const (
+	// SelfOrganizedUnattributed imports the event without an organizer.
+	SelfOrganizedUnattributed SelfOrganizedPolicy = "unattributed"
+	// SelfOrganizedSkip doesn't import the event.
+	SelfOrganizedSkip SelfOrganizedPolicy = "skip"
+)
+
+// ParseSelfOrganizedPolicy validates the self-organized event policy. Empty
+// policy defaults to SelfOrganizedUnattributed.
+func ParseSelfOrganizedPolicy(policy string) (SelfOrganizedPolicy, error) {
+	switch SelfOrganizedPolicy(policy) {
+	case "":
+		return SelfOrganizedUnattributed, nil
+	case SelfOrganizedUnattributed, SelfOrganizedSkip:
+		return SelfOrganizedPolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid self-organized policy %q", policy)
+}
+
+// BlockPolicy defines what happens to events nobody is invited to, e.g. put
+// directly in the room's calendar to block it.
+type BlockPolicy string
+
+const (
+	// BlockImport imports blocks like any other event.
+	BlockImport BlockPolicy = "import"
+	// BlockOccupancyOnly imports blocks, but doesn't write their changes in
+	// Eliona back to Exchange.
+	BlockOccupancyOnly BlockPolicy = "occupancyOnly"
+	// BlockSkip doesn't import blocks.
+	BlockSkip BlockPolicy = "skip"
+)
+
+// ParseBlockPolicy validates the block policy. Empty policy defaults to
+// BlockImport.
+func ParseBlockPolicy(policy string) (BlockPolicy, error) {
+	switch BlockPolicy(policy) {
+	case "":
+		return BlockImport, nil
+	case BlockImport, BlockOccupancyOnly, BlockSkip:
+		return BlockPolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid block policy %q", policy)
+}
+
+// UnknownDeletePolicy defines what happens when Exchange reports the deletion
+// of an item in a room's calendar the app doesn't know, meaning its creation
+// was likely missed.
+type UnknownDeletePolicy string
+
+const (
+	// UnknownDeleteLog only logs and counts unknown deletes.
+	UnknownDeleteLog UnknownDeletePolicy = "log"
+	// UnknownDeleteResync additionally resynchronizes the room from scratch,
+	// importing the events missed.
+	UnknownDeleteResync UnknownDeletePolicy = "resync"
+)
+
+// ParseUnknownDeletePolicy validates the unknown delete policy. Empty policy
+// defaults to UnknownDeleteLog.
+func ParseUnknownDeletePolicy(policy string) (UnknownDeletePolicy, error) {
+	switch UnknownDeletePolicy(policy) {
+	case "":
+		return UnknownDeleteLog, nil
+	case UnknownDeleteLog, UnknownDeleteResync:
+		return UnknownDeletePolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid unknown delete policy %q", policy)
+}
+
+// ChangeDetection defines how changes in rooms' calendars are detected.
+type ChangeDetection string
+
+const (
+	// ChangeDetectionPolling synchronizes every room's calendar each time.
+	ChangeDetectionPolling ChangeDetection = "polling"
+	// ChangeDetectionPullSubscription asks a pull subscription to each room's
+	// calendar for events first and synchronizes only calendars that changed.
+	ChangeDetectionPullSubscription ChangeDetection = "pullSubscription"
+)
+
+// ParseChangeDetection validates the change detection. Empty change detection
+// defaults to ChangeDetectionPolling.
+func ParseChangeDetection(detection string) (ChangeDetection, error) {
+	switch ChangeDetection(detection) {
+	case "":
+		return ChangeDetectionPolling, nil
+	case ChangeDetectionPolling, ChangeDetectionPullSubscription:
+		return ChangeDetection(detection), nil
+	}
+	return "", fmt.Errorf("invalid change detection %q", detection)
+}
+
+// BookingClock converts between the times of the Booking app and the absolute
+// times used in Exchange. All conversions of booking times go through it.
+type BookingClock struct {
+	// Location the Booking app's times are wall-clock times in, labelled as
+	// UTC. Nil if the Booking app sends absolute times.
+	Location *time.Location
+}
+
+// ParseBookingClock returns the clock of the time zone. Empty time zone means
+// the Booking app sends absolute times.
+func ParseBookingClock(timeZone string) (BookingClock, error) {
+	if timeZone == "" {
+		return BookingClock{}, nil
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return BookingClock{}, fmt.Errorf("invalid booking time zone %q: %v", timeZone, err)
+	}
+	return BookingClock{Location: location}, nil
+}
+
+// FromEliona converts a time received from the Booking app to absolute time.
+func (c BookingClock) FromEliona(t time.Time) time.Time {
+	if c.Location == nil {
+		return t
+	}
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), c.Location)
+}
+
+// ToEliona converts an absolute time to the Booking app's representation.
+func (c BookingClock) ToEliona(t time.Time) time.Time {
+	if c.Location == nil {
+		return t
+	}
+	t = t.In(c.Location)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// EWSTime formats an absolute time for EWS requests.
+func EWSTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ImportOverlapPolicy defines what happens to a booking imported from Exchange
+// which overlaps a booking created from Eliona in the same room.
+type ImportOverlapPolicy string
+
+const (
+	// ImportOverlapImport imports the booking anyway, showing both bookings.
+	ImportOverlapImport ImportOverlapPolicy = "import"
+	// ImportOverlapFlag doesn't import the booking and flags the conflict.
+	ImportOverlapFlag ImportOverlapPolicy = "flag"
+)
+
+// ParseImportOverlapPolicy validates the import overlap policy. Empty policy
+// defaults to ImportOverlapImport.
+func ParseImportOverlapPolicy(policy string) (ImportOverlapPolicy, error) {
+	switch ImportOverlapPolicy(policy) {
+	case "":
+		return ImportOverlapImport, nil
+	case ImportOverlapImport, ImportOverlapFlag:
+		return ImportOverlapPolicy(policy), nil
+	}
+	return "", fmt.Errorf("invalid import overlap policy %q", policy)
+}
+
+// FirstOverlap returns the first occurrence of the group overlapping any of the
+// booked intervals. Found is false if there is none.
+func (g BookingGroup) FirstOverlap(booked []Interval, policy OverlapPolicy) (occurrence BookingOccurrence, found bool) {
+	for _, occurrence := range g.Occurrences {
+		if occurrence.Cancelled {
+			continue
+		}
+		for _, interval := range booked {
+			if Overlaps(occurrence.Start, occurrence.End, interval.Start, interval.End, policy) {
+				return occurrence, true
+			}
+		}
+	}
+	return BookingOccurrence{}, false
+}
+
+// RoomNameRule renames rooms whose Exchange name matches the pattern.
+type RoomNameRule struct {
+	Pattern *regexp.Regexp
+	// Replacement may refer to submatches of the pattern, e.g. "Room $1".
+	Replacement string
+}
+
+// ParseRoomNameRule compiles the rule's pattern.
+func ParseRoomNameRule(pattern, replacement string) (RoomNameRule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return RoomNameRule{}, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	return RoomNameRule{Pattern: re, Replacement: replacement}, nil
+}
+
+// RoomDisplayName returns the name the room is shown with in Eliona. A name
+// set for the room's email takes precedence, otherwise the rules are applied
+// to the Exchange name in order. Rules resulting in an empty name are ignored.
+func RoomDisplayName(email, name string, names map[string]string, rules []RoomNameRule) string {
+	for room, displayName := range names {
+		if strings.EqualFold(room, email) && displayName != "" {
+			return displayName
+		}
+	}
+	displayName := name
+	for _, rule := range rules {
+		if renamed := rule.Pattern.ReplaceAllString(displayName, rule.Replacement); renamed != "" {
+			displayName = renamed
+		}
+	}
+	return displayName
+}
+
+// RoomLocationPattern parses the office location of rooms, e.g.
+// "Building A, Floor 2", into their building and floor. The zero value
+// locates no rooms.
+type RoomLocationPattern struct {
+	re *regexp.Regexp
+}
+
+// ParseRoomLocationPattern compiles the pattern, which must name a group
+// "building" and may name a group "floor". An empty pattern locates no rooms.
+func ParseRoomLocationPattern(pattern string) (RoomLocationPattern, error) {
+	if pattern == "" {
+		return RoomLocationPattern{}, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return RoomLocationPattern{}, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	if re.SubexpIndex("building") < 0 {
+		return RoomLocationPattern{}, fmt.Errorf("pattern %q has no group named building", pattern)
+	}
+	return RoomLocationPattern{re: re}, nil
+}
+
+// Enabled tells whether the pattern locates rooms at all.
+func (p RoomLocationPattern) Enabled() bool {
+	return p.re != nil
+}
+
+// Locate returns the building and floor in the office location. Both are
+// empty if the location doesn't match, and the floor is empty if the pattern
+// has none.
+func (p RoomLocationPattern) Locate(office string) (building, floor string) {
+	if p.re == nil {
+		return "", ""
+	}
+	match := p.re.FindStringSubmatch(office)
+	if match == nil {
+		return "", ""
+	}
+	building = strings.TrimSpace(match[p.re.SubexpIndex("building")])
+	if i := p.re.SubexpIndex("floor"); i >= 0 && building != "" {
+		floor = strings.TrimSpace(match[i])
+	}
+	return building, floor
+}
+
+// DefaultSubjectTemplate composes subjects of bookings without one unless
+// the configuration sets another template.
+const DefaultSubjectTemplate = "{room} booked by {organizer}"
+
+// SubjectFallback labels bookings whose subject is empty or redacted, so
+// every booking has a meaningful label. It's not applied to subjects set by
+// the user.
+type SubjectFallback struct {
+	// Template with the placeholders {room}, {organizer}, {start} and {end}.
+	Template string
+	// Location the times are shown in, UTC if nil.
+	Location *time.Location
+	// RoomNames override the room's email in {room}, see RoomDisplayName.
+	RoomNames map[string]string
+}
+
+// Subject returns the subject, or the subject composed from the event's
+// fields if the subject is blank.
+func (f SubjectFallback) Subject(subject, roomEmail, organizer string, start, end time.Time) string {
+	if strings.TrimSpace(subject) != "" {
+		return subject
+	}
+	return f.Compose(roomEmail, organizer, start, end)
+}
+
+// Compose returns the subject composed from the event's fields, regardless of
+// its own subject.
+func (f SubjectFallback) Compose(roomEmail, organizer string, start, end time.Time) string {
+	template := f.Template
+	if template == "" {
+		template = DefaultSubjectTemplate
+	}
+	location := f.Location
+	if location == nil {
+		location = time.UTC
+	}
+	const timeLayout = "2006-01-02 15:04"
+	return strings.TrimSpace(strings.NewReplacer(
+		"{room}", RoomDisplayName(roomEmail, roomEmail, f.RoomNames, nil),
+		"{organizer}", organizer,
+		"{start}", start.In(location).Format(timeLayout),
+		"{end}", end.In(location).Format(timeLayout),
+	).Replace(template))
+}
+
+// Interval is a span of time, e.g. a booked event.
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// WorkingHours is the daily window in which a room is expected to be used,
+// evaluated in the room's local time zone.
+type WorkingHours struct {
+	Location *time.Location
+	// Start and End are wall clock times as offsets from local midnight.
+	Start time.Duration
+	End   time.Duration
+	Days  []time.Weekday
+}
+
+// Defaults of working hours not configured otherwise.
+const (
+	DefaultWorkingHoursStart = "08:00"
+	DefaultWorkingHoursEnd   = "18:00"
+)
+
+// DefaultWorkingDays are Monday to Friday.
+var DefaultWorkingDays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+// ParseWorkingHours validates the working hours. Start and end are in the
+// "15:04" format, time zone is an IANA name and days are numbered from Sunday
+// (0) to Saturday (6). Empty values fall back to 08:00-18:00 UTC on weekdays.
+func ParseWorkingHours(start, end, timeZone string, days []int32) (WorkingHours, error) {
+	if start == "" {
+		start = DefaultWorkingHoursStart
+	}
+	if end == "" {
+		end = DefaultWorkingHoursEnd
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return WorkingHours{}, fmt.Errorf("invalid time zone %q: %v", timeZone, err)
+	}
+	wh := WorkingHours{Location: loc, Days: DefaultWorkingDays}
+	if wh.Start, err = parseClock(start); err != nil {
+		return WorkingHours{}, err
+	}
+	if wh.End, err = parseClock(end); err != nil {
+		return WorkingHours{}, err
+	}
+	if wh.End <= wh.Start {
+		return WorkingHours{}, fmt.Errorf("working hours end %s is not after start %s", end, start)
+	}
+	if len(days) > 0 {
+		wh.Days = nil
+		for _, day := range days {
+			if day < 0 || day > 6 {
+				return WorkingHours{}, fmt.Errorf("invalid working day %d", day)
+			}
+			wh.Days = append(wh.Days, time.Weekday(day))
+		}
+	}
+	return wh, nil
+}
+
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w WorkingHours) isWorkingDay(day time.Weekday) bool {
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Windows returns the working windows between from and to. Each window is
+// built from the local wall clock of its day, so days with a DST transition
+// inside the working hours are shorter or longer accordingly.
+func (w WorkingHours) Windows(from, to time.Time) []Interval {
+	var windows []Interval
+	local := from.In(w.Location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, w.Location)
+	for day.Before(to) {
+		if w.isWorkingDay(day.Weekday()) {
+			start := wallClock(day, w.Start, w.Location)
+			end := wallClock(day, w.End, w.Location)
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			if start.Before(end) {
+				windows = append(windows, Interval{Start: start, End: end})
+			}
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, w.Location)
+	}
+	return windows
+}
+
+func wallClock(day time.Time, offset time.Duration, loc *time.Location) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, loc)
+}
+
+// Utilization sums the time the room was booked within the working hours
+// between from and to, and the total working time available. Overlapping
+// bookings are counted once.
+func Utilization(booked []Interval, hours WorkingHours, from, to time.Time) (bookedTime, availableTime time.Duration) {
+	merged := mergeIntervals(booked)
+	for _, window := range hours.Windows(from, to) {
+		availableTime += window.End.Sub(window.Start)
+		for _, b := range merged {
+			start, end := b.Start, b.End
+			if start.Before(window.Start) {
+				start = window.Start
+			}
+			if end.After(window.End) {
+				end = window.End
+			}
+			if start.Before(end) {
+				bookedTime += end.Sub(start)
+			}
+		}
+	}
+	return bookedTime, availableTime
+}
+
+func mergeIntervals(intervals []Interval) []Interval {
+	sorted := append([]Interval(nil), intervals...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+	var merged []Interval
+	for _, interval := range sorted {
+		if n := len(merged); n > 0 && !interval.Start.After(merged[n-1].End) {
+			if interval.End.After(merged[n-1].End) {
+				merged[n-1].End = interval.End
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// DistinctUID keeps a copy of the meeting with the UID at different times apart
+// from it, e.g. one copied by a misbehaving client. The start of its first
+// occurrence tells the copies apart, so that they are stored separately.
+func DistinctUID(uid string, start time.Time) string {
+	return uid + "#" + start.UTC().Format("20060102T150405Z")
+}
+
+// BaseUID returns the UID of the meeting in Exchange, without the
+// discriminator added by DistinctUID.
+func BaseUID(uid string) string {
+	base, _, _ := strings.Cut(uid, "#")
+	return base
+}
+
+// SameTimes reports whether both groups have the same occurrences at the same
+// times, as the copies of a meeting found in the mailboxes of its rooms do.
+func SameTimes(a, b BookingGroup) bool {
+	if len(a.Occurrences) != len(b.Occurrences) {
+		return false
+	}
+	for i := range a.Occurrences {
+		if !a.Occurrences[i].Start.Equal(b.Occurrences[i].Start) || !a.Occurrences[i].End.Equal(b.Occurrences[i].End) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSeriesSplit reports whether next continues the truncated series prev, as
+// when "this and following occurrences" of a series are edited in Outlook. The
+// continuation is a new series by the same organizer starting after the last
+// remaining occurrence of the truncated one.
+func IsSeriesSplit(prev, next BookingGroup) bool {
+	if prev.ExchangeUID == next.ExchangeUID || !strings.EqualFold(prev.OrganizerEmail, next.OrganizerEmail) {
+		return false
+	}
+	if len(next.Occurrences) == 0 || next.Occurrences[0].InstanceIndex == 0 {
+		// Not a series.
+		return false
+	}
+	truncated := false
+	var lastRemaining time.Time
+	for _, occurrence := range prev.Occurrences {
+		if occurrence.Cancelled {
+			truncated = true
+			continue
+		}
+		if occurrence.InstanceIndex == 0 {
+			return false
+		}
+		if occurrence.End.After(lastRemaining) {
+			lastRemaining = occurrence.End
+		}
+	}
+	if !truncated || lastRemaining.IsZero() {
+		return false
+	}
+	for _, occurrence := range next.Occurrences {
+		if occurrence.Start.Before(lastRemaining) {
+			return false
+		}
+	}
+	return true
+}
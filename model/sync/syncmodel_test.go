@@ -0,0 +1,378 @@
+package syncmodel
+
+import (
+	"testing"
+	"time"
+)
+
+func at(hour, minute int) time.Time {
+	return time.Date(2024, 5, 6, hour, minute, 0, 0, time.UTC)
+}
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		name                       string
+		aStart, aEnd, bStart, bEnd time.Time
+		exclusive, inclusive       bool
+	}{
+		{"back to back", at(9, 0), at(10, 0), at(10, 0), at(11, 0), false, true},
+		{"back to back reversed", at(10, 0), at(11, 0), at(9, 0), at(10, 0), false, true},
+		{"overlapping by a minute", at(9, 0), at(10, 1), at(10, 0), at(11, 0), true, true},
+		{"gap between", at(9, 0), at(9, 59), at(10, 0), at(11, 0), false, false},
+		{"contained", at(9, 0), at(12, 0), at(10, 0), at(11, 0), true, true},
+		{"identical", at(10, 0), at(11, 0), at(10, 0), at(11, 0), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Overlaps(tt.aStart, tt.aEnd, tt.bStart, tt.bEnd, OverlapExclusive); got != tt.exclusive {
+				t.Errorf("exclusive: got %v, want %v", got, tt.exclusive)
+			}
+			if got := Overlaps(tt.aStart, tt.aEnd, tt.bStart, tt.bEnd, OverlapInclusive); got != tt.inclusive {
+				t.Errorf("inclusive: got %v, want %v", got, tt.inclusive)
+			}
+		})
+	}
+}
+
+func TestIsOngoingAtBoundaries(t *testing.T) {
+	occurrence := BookingOccurrence{Start: at(9, 0), End: at(10, 0)}
+	next := BookingOccurrence{Start: at(10, 0), End: at(11, 0)}
+
+	if !occurrence.IsOngoing(at(9, 0), OverlapExclusive) {
+		t.Error("occurrence should be ongoing at its start")
+	}
+	if occurrence.IsOngoing(at(10, 0), OverlapExclusive) {
+		t.Error("occurrence should not be ongoing at its end under exclusive policy")
+	}
+	if !occurrence.IsOngoing(at(10, 0), OverlapInclusive) {
+		t.Error("occurrence should be ongoing at its end under inclusive policy")
+	}
+	if occurrence.OverlapsWith(next, OverlapExclusive) {
+		t.Error("back-to-back occurrences should not conflict under exclusive policy")
+	}
+	occurrence.Cancelled = true
+	if occurrence.IsOngoing(at(9, 30), OverlapInclusive) {
+		t.Error("cancelled occurrence should never be ongoing")
+	}
+}
+
+func TestParseDeclinePolicy(t *testing.T) {
+	for policy, want := range map[string]DeclinePolicy{
+		"":             DeclineCancelAll,
+		"cancelAll":    DeclineCancelAll,
+		"keepAccepted": DeclineKeepAccepted,
+	} {
+		got, err := ParseDeclinePolicy(policy)
+		if err != nil || got != want {
+			t.Errorf("ParseDeclinePolicy(%q) = %q, %v; want %q", policy, got, err, want)
+		}
+	}
+	if _, err := ParseDeclinePolicy("keepSome"); err == nil {
+		t.Error("expected invalid policy to be rejected")
+	}
+}
+
+func TestParseCancelPolicy(t *testing.T) {
+	for policy, want := range map[string]CancelPolicy{
+		"":              CancelEvent,
+		"cancel":        CancelEvent,
+		"removeRooms":   CancelRemoveRooms,
+		"declineAsRoom": CancelDeclineAsRoom,
+	} {
+		got, err := ParseCancelPolicy(policy)
+		if err != nil || got != want {
+			t.Errorf("ParseCancelPolicy(%q) = %q, %v; want %q", policy, got, err, want)
+		}
+	}
+	if _, err := ParseCancelPolicy("release"); err == nil {
+		t.Error("expected invalid policy to be rejected")
+	}
+}
+
+func TestParseCloud(t *testing.T) {
+	for cloud, want := range map[string]Cloud{
+		"":           CloudCommercial,
+		"commercial": CloudCommercial,
+		"gccHigh":    CloudGCCHigh,
+		"dod":        CloudDoD,
+		"china":      CloudChina,
+	} {
+		got, err := ParseCloud(cloud)
+		if err != nil || got != want {
+			t.Errorf("ParseCloud(%q) = %q, %v; want %q", cloud, got, err, want)
+		}
+	}
+	if _, err := ParseCloud("germany"); err == nil {
+		t.Error("expected invalid cloud to be rejected")
+	}
+}
+
+func TestBookingClock(t *testing.T) {
+	clock, err := ParseBookingClock("Europe/Zurich")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		eliona time.Time
+		want   string
+	}{
+		// Summer time, UTC+2.
+		{time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC), "2024-05-06T08:00:00Z"},
+		// Winter time, UTC+1.
+		{time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC), "2024-01-15T09:00:00Z"},
+		// Wall-clock midnight falls on the previous day in UTC.
+		{time.Date(2024, 5, 6, 0, 30, 0, 0, time.UTC), "2024-05-05T22:30:00Z"},
+	} {
+		absolute := clock.FromEliona(tc.eliona)
+		if got := EWSTime(absolute); got != tc.want {
+			t.Errorf("EWSTime(FromEliona(%v)) = %s, want %s", tc.eliona, got, tc.want)
+		}
+		if back := clock.ToEliona(absolute); !back.Equal(tc.eliona) {
+			t.Errorf("ToEliona(%v) = %v, want %v", absolute, back, tc.eliona)
+		}
+	}
+
+	absolute, err := ParseBookingClock("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	instant := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	if got := absolute.FromEliona(instant); !got.Equal(instant) {
+		t.Errorf("expected absolute times to be kept, got %v", got)
+	}
+	if got := absolute.ToEliona(instant); !got.Equal(instant) {
+		t.Errorf("expected absolute times to be kept, got %v", got)
+	}
+	if _, err := ParseBookingClock("Mars/Olympus"); err == nil {
+		t.Error("expected invalid time zone to be rejected")
+	}
+}
+
+func TestParseEmptyBookingPolicy(t *testing.T) {
+	for policy, want := range map[string]EmptyBookingPolicy{
+		"":          EmptyBookingCancel,
+		"cancel":    EmptyBookingCancel,
+		"keepEmpty": EmptyBookingKeep,
+	} {
+		got, err := ParseEmptyBookingPolicy(policy)
+		if err != nil || got != want {
+			t.Errorf("ParseEmptyBookingPolicy(%q) = %q, %v; want %q", policy, got, err, want)
+		}
+	}
+	if _, err := ParseEmptyBookingPolicy("keep"); err == nil {
+		t.Error("expected invalid policy to be rejected")
+	}
+}
+
+func TestSubjectFallback(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 6, 7, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	for _, tc := range []struct {
+		fallback SubjectFallback
+		subject  string
+		want     string
+	}{
+		{SubjectFallback{}, "Weekly", "Weekly"},
+		{SubjectFallback{}, "", "room1@example.com booked by john@example.com"},
+		{SubjectFallback{}, " \t\n", "room1@example.com booked by john@example.com"},
+		{SubjectFallback{RoomNames: map[string]string{"Room1@example.com": "Boardroom"}}, "", "Boardroom booked by john@example.com"},
+		{SubjectFallback{Template: "{organizer}, {start}-{end}", Location: zurich}, "  ", "john@example.com, 2024-05-06 09:00-2024-05-06 10:00"},
+	} {
+		if got := tc.fallback.Subject(tc.subject, "room1@example.com", "john@example.com", start, end); got != tc.want {
+			t.Errorf("subject %q with %+v: expected %q, got %q", tc.subject, tc.fallback, tc.want, got)
+		}
+	}
+	if got := (SubjectFallback{Template: "{organizer}"}).Compose("room1@example.com", "john@example.com", start, end); got != "john@example.com" {
+		t.Errorf("expected the subject to be composed regardless of the event's, got %q", got)
+	}
+}
+
+func TestRoomLocationPattern(t *testing.T) {
+	pattern, err := ParseRoomLocationPattern(`^(?P<building>[^,]+)(?:, Floor (?P<floor>\d+))?$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		office, building, floor string
+	}{
+		{"Building A, Floor 2", "Building A", "2"},
+		{"Building B", "Building B", ""},
+		{"", "", ""},
+	} {
+		if building, floor := pattern.Locate(tc.office); building != tc.building || floor != tc.floor {
+			t.Errorf("Locate(%q) = %q, %q; want %q, %q", tc.office, building, floor, tc.building, tc.floor)
+		}
+	}
+	if pattern, err := ParseRoomLocationPattern(""); err != nil || pattern.Enabled() {
+		t.Errorf("expected empty pattern to locate no rooms, got %v", err)
+	}
+	if _, err := ParseRoomLocationPattern(`^(?P<floor>\d+)$`); err == nil {
+		t.Error("expected pattern without building to be rejected")
+	}
+}
+
+func TestRoomDisplayName(t *testing.T) {
+	var rules []RoomNameRule
+	for _, r := range [][2]string{
+		{`^RM-B(\d+)-0*(\d+)$`, "Building $1, Room $2"},
+		{`^Building 2, `, ""},
+		{`^.*$`, ""},
+	} {
+		rule, err := ParseRoomNameRule(r[0], r[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, rule)
+	}
+	names := map[string]string{"Boardroom@example.com": "Boardroom"}
+
+	for _, tc := range []struct {
+		email, name, want string
+	}{
+		{"rm-b1-014@example.com", "RM-B1-014", "Building 1, Room 14"},
+		{"rm-b2-003@example.com", "RM-B2-003", "Room 3"},
+		{"boardroom@example.com", "RM-B1-001", "Boardroom"},
+		{"cafeteria@example.com", "Cafeteria", "Cafeteria"},
+	} {
+		if got := RoomDisplayName(tc.email, tc.name, names, rules); got != tc.want {
+			t.Errorf("RoomDisplayName(%q, %q) = %q; want %q", tc.email, tc.name, got, tc.want)
+		}
+	}
+	if _, err := ParseRoomNameRule("(", ""); err == nil {
+		t.Error("expected invalid pattern to be rejected")
+	}
+}
+
+func TestUtilizationAcrossDSTTransition(t *testing.T) {
+	// Night shift room: clocks in Zurich jump from 02:00 to 03:00 on 31 March 2024.
+	hours, err := ParseWorkingHours("00:00", "06:00", "Europe/Zurich", []int32{0, 1, 2, 3, 4, 5, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zurich := hours.Location
+	from := time.Date(2024, 3, 30, 0, 0, 0, 0, zurich)
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, zurich)
+
+	windows := hours.Windows(from, to)
+	if len(windows) != 2 {
+		t.Fatalf("expected 2 windows, got %v", windows)
+	}
+	if d := windows[0].End.Sub(windows[0].Start); d != 6*time.Hour {
+		t.Errorf("expected 6h window before the transition, got %v", d)
+	}
+	if d := windows[1].End.Sub(windows[1].Start); d != 5*time.Hour {
+		t.Errorf("expected 5h window on the transition day, got %v", d)
+	}
+
+	booked := []Interval{
+		// 01:00-04:00 local on the transition day lasts just 2 hours.
+		{Start: time.Date(2024, 3, 31, 1, 0, 0, 0, zurich), End: time.Date(2024, 3, 31, 4, 0, 0, 0, zurich)},
+		// Overlapping booking must not be counted twice.
+		{Start: time.Date(2024, 3, 31, 3, 0, 0, 0, zurich), End: time.Date(2024, 3, 31, 4, 0, 0, 0, zurich)},
+		// Outside of working hours.
+		{Start: time.Date(2024, 3, 30, 20, 0, 0, 0, zurich), End: time.Date(2024, 3, 30, 22, 0, 0, 0, zurich)},
+		// Partly within working hours.
+		{Start: time.Date(2024, 3, 30, 5, 0, 0, 0, zurich), End: time.Date(2024, 3, 30, 7, 0, 0, 0, zurich)},
+	}
+	bookedTime, available := Utilization(booked, hours, from, to)
+	if available != 11*time.Hour {
+		t.Errorf("expected 11h available, got %v", available)
+	}
+	if bookedTime != 3*time.Hour {
+		t.Errorf("expected 3h booked, got %v", bookedTime)
+	}
+}
+
+func TestWorkingHoursInRoomTimeZone(t *testing.T) {
+	// 16:00-20:00 on Friday in New York is already Saturday in Tokyo.
+	from := time.Date(2024, 5, 3, 20, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC)
+	newYork, err := ParseWorkingHours("", "", "America/New_York", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo, err := ParseWorkingHours("", "", "Asia/Tokyo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, available := Utilization(nil, newYork, from, to); available != 2*time.Hour {
+		t.Errorf("expected 2h available in New York, got %v", available)
+	}
+	if _, available := Utilization(nil, tokyo, from, to); available != 0 {
+		t.Errorf("expected no working hours in Tokyo, got %v", available)
+	}
+}
+
+func TestParseWorkingHoursRejectsInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		start, end, tz string
+		days           []int32
+	}{
+		{"18:00", "08:00", "UTC", nil},
+		{"8am", "18:00", "UTC", nil},
+		{"08:00", "18:00", "Mars/Olympus", nil},
+		{"08:00", "18:00", "UTC", []int32{7}},
+	} {
+		if _, err := ParseWorkingHours(tt.start, tt.end, tt.tz, tt.days); err == nil {
+			t.Errorf("expected %+v to be rejected", tt)
+		}
+	}
+}
+
+func TestIsSeriesSplit(t *testing.T) {
+	truncated := BookingGroup{
+		ExchangeUID:    "original",
+		OrganizerEmail: "organizer@example.com",
+		Occurrences: []BookingOccurrence{
+			{InstanceIndex: 1, Start: at(9, 0), End: at(10, 0)},
+			{InstanceIndex: 2, Cancelled: true},
+		},
+	}
+	continuation := BookingGroup{
+		ExchangeUID:    "continuation",
+		OrganizerEmail: "Organizer@example.com",
+		Occurrences:    []BookingOccurrence{{InstanceIndex: 1, Start: at(11, 0), End: at(12, 0)}},
+	}
+	if !IsSeriesSplit(truncated, continuation) {
+		t.Error("expected continuation of truncated series to be a split")
+	}
+
+	otherOrganizer := continuation
+	otherOrganizer.OrganizerEmail = "someone@example.com"
+	if IsSeriesSplit(truncated, otherOrganizer) {
+		t.Error("series of another organizer is not a split")
+	}
+	overlapping := continuation
+	overlapping.Occurrences = []BookingOccurrence{{InstanceIndex: 1, Start: at(9, 30), End: at(10, 30)}}
+	if IsSeriesSplit(truncated, overlapping) {
+		t.Error("series starting before the truncated one ends is not a split")
+	}
+	untouched := truncated
+	untouched.Occurrences = truncated.Occurrences[:1]
+	if IsSeriesSplit(untouched, continuation) {
+		t.Error("series which was not truncated is not split")
+	}
+}
+
+func TestFirstOverlap(t *testing.T) {
+	group := BookingGroup{Occurrences: []BookingOccurrence{
+		{InstanceIndex: 1, Start: at(9, 0), End: at(10, 0), Cancelled: true},
+		{InstanceIndex: 2, Start: at(11, 0), End: at(12, 0)},
+	}}
+	elionaBookings := []Interval{{Start: at(9, 30), End: at(10, 30)}, {Start: at(12, 0), End: at(13, 0)}}
+
+	if _, found := group.FirstOverlap(elionaBookings, OverlapExclusive); found {
+		t.Error("cancelled and back-to-back occurrences should not overlap")
+	}
+	occurrence, found := group.FirstOverlap(elionaBookings, OverlapInclusive)
+	if !found || occurrence.InstanceIndex != 2 {
+		t.Errorf("expected back-to-back occurrence 2 to overlap inclusively, got %+v, %v", occurrence, found)
+	}
+	if _, err := ParseImportOverlapPolicy("ignore"); err == nil {
+		t.Error("expected invalid policy to be rejected")
+	}
+}